@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/argocd"
+)
+
+var argocdRepoRoot string
+
+// argocdCmd represents the argocd command
+var argocdCmd = &cobra.Command{
+	Use:   "argocd <application.yaml>",
+	Short: "Fuzz the Helm sources of ArgoCD Applications",
+	Long: `Run one fuzzing session per Helm source of the ArgoCD Applications in a
+manifest, rendering each the way ArgoCD would. Charts from Helm repositories
+and OCI registries are downloaded for the session; Git sources (path instead of
+chart) are read from --repo-root, a local checkout of their repository.
+
+The source's valueFiles, values, valuesObject, parameters and fileParameters
+are merged in ArgoCD's order and override every input, so only the values the
+Application leaves open are fuzzed. Inputs render as helm.releaseName, or the
+Application's name, in the destination namespace. --values and --set override
+the Application's values in turn. Value files are relative to the chart, and
+$ref/ value files of multi-source Applications to --repo-root.
+
+Each source writes its files to its own subdirectory of --output when there
+are several.`,
+	Args: cobra.ExactArgs(1),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return []string{"yaml", "yml"}, cobra.ShellCompDirectiveFilterFileExt
+	},
+	RunE: runArgoCD,
+}
+
+func init() {
+	rootCmd.AddCommand(argocdCmd)
+	addSessionFlags(argocdCmd)
+
+	argocdCmd.Flags().StringVar(&argocdRepoRoot, "repo-root", ".", "Local checkout of the Git repository that Git sources and $ref/ value files are read from")
+	argocdCmd.MarkFlagDirname("repo-root")
+}
+
+func runArgoCD(cmd *cobra.Command, args []string) error {
+	targets, err := argocd.Load(args[0])
+	if err != nil {
+		return err
+	}
+
+	dir, err := os.MkdirTemp("", "helm-fuzz-argocd-")
+	if err != nil {
+		return infraError(fmt.Errorf("failed to create chart directory: %w", err))
+	}
+	defer os.RemoveAll(dir)
+
+	var runs []*chartRun
+	for i, t := range targets {
+		// Each remote chart gets its own directory, as several may share a name
+		chartDir := filepath.Join(dir, strconv.Itoa(i))
+		if err := os.Mkdir(chartDir, 0755); err != nil {
+			return infraError(fmt.Errorf("failed to create chart directory: %w", err))
+		}
+		chartPath, err := t.Chart(chartDir, argocdRepoRoot)
+		if err != nil {
+			return infraError(err)
+		}
+		values, err := t.Values(chartPath, argocdRepoRoot)
+		if err != nil {
+			return err
+		}
+		runs = append(runs, &chartRun{
+			chartPath:   chartPath,
+			name:        argocdRunName(targets, t),
+			values:      values,
+			releaseName: t.ReleaseName,
+			namespace:   t.Namespace,
+		})
+	}
+
+	if err := runSessions(cmd, runs, "application"); err != nil {
+		return err
+	}
+	return sessionsResult(runs)
+}
+
+// argocdRunName names a source's progress row and output subdirectory,
+// adding the source index for Applications with several Helm sources
+func argocdRunName(targets []argocd.Target, t argocd.Target) string {
+	for _, other := range targets {
+		if other.Application == t.Application && other.Index != t.Index {
+			return fmt.Sprintf("%s-%d", t.Application, t.Index)
+		}
+	}
+	return t.Application
+}
@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/baseline"
+	"github.com/kasuboski/helm-fuzzer/pkg/report"
+	"github.com/kasuboski/helm-fuzzer/pkg/runner"
+)
+
+var (
+	baselineFile   string
+	baselineFormat string
+)
+
+// baselineCmd represents the baseline command
+var baselineCmd = &cobra.Command{
+	Use:   "baseline",
+	Short: "Accept a chart's known findings and fail only on new ones",
+	Long: `Record the findings of earlier sessions as a chart's baseline, then compare
+later sessions against it. Only findings whose fingerprint is not in the baseline
+fail, so fuzzing can gate a legacy chart's CI before its existing crashes are
+fixed. The baseline is .helmfuzz-baseline.json in the chart directory unless
+--file is given; commit it with the chart. fuzz and matrix take the same file
+with --baseline.`,
+}
+
+var baselineRecordCmd = &cobra.Command{
+	Use:               "record <chart-path> <output-dir>...",
+	Short:             "Record the findings of sessions as the baseline",
+	Args:              cobra.MinimumNArgs(2),
+	ValidArgsFunction: completeCharts(1, cobra.ShellCompDirectiveFilterDirs),
+	RunE:              runBaselineRecord,
+}
+
+var baselineCompareCmd = &cobra.Command{
+	Use:   "compare <chart-path> <output-dir>...",
+	Short: "Compare the findings of sessions against the baseline",
+	Long: `List the findings of the sessions as new or known, and the baseline entries
+none of them found again. Exits with code 1 if any finding is new.`,
+	Args:              cobra.MinimumNArgs(2),
+	ValidArgsFunction: completeCharts(1, cobra.ShellCompDirectiveFilterDirs),
+	RunE:              runBaselineCompare,
+}
+
+func init() {
+	rootCmd.AddCommand(baselineCmd)
+	baselineCmd.AddCommand(baselineRecordCmd, baselineCompareCmd)
+
+	baselineCmd.PersistentFlags().StringVar(&baselineFile, "file", "", "Baseline file (default: "+baseline.FileName+" in the chart directory)")
+	baselineCmd.MarkPersistentFlagFilename("file", "json")
+	baselineCompareCmd.Flags().StringVarP(&baselineFormat, "format", "o", "text", "Output format: text or json")
+}
+
+// baselineTarget resolves the chart's name and the baseline file
+func baselineTarget(arg string) (string, string, error) {
+	chartPath, err := filepath.Abs(arg)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to resolve chart path: %w", err)
+	}
+	path := baselineFile
+	if path == "" {
+		path = filepath.Join(chartPath, baseline.FileName)
+	}
+	return filepath.Base(chartPath), path, nil
+}
+
+// runFindings collects the findings of every run
+func runFindings(runs []report.Run) []report.JSONFinding {
+	var findings []report.JSONFinding
+	for _, run := range runs {
+		findings = append(findings, run.Report.Findings...)
+	}
+	return findings
+}
+
+func runBaselineRecord(cmd *cobra.Command, args []string) error {
+	chartName, path, err := baselineTarget(args[0])
+	if err != nil {
+		return err
+	}
+	runs, err := chartRuns(cmd, chartName, args[1:])
+	if err != nil {
+		return err
+	}
+
+	b := baseline.New(chartName, runFindings(runs))
+	if err := b.Save(path); err != nil {
+		return infraError(err)
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Recorded %d finding(s) in %s\n", len(b.Findings), path)
+	return nil
+}
+
+func runBaselineCompare(cmd *cobra.Command, args []string) error {
+	if baselineFormat != "text" && baselineFormat != "json" {
+		return fmt.Errorf("invalid format %q: must be text or json", baselineFormat)
+	}
+	chartName, path, err := baselineTarget(args[0])
+	if err != nil {
+		return err
+	}
+	b, err := baseline.Load(path)
+	if err != nil {
+		return err
+	}
+	runs, err := chartRuns(cmd, chartName, args[1:])
+	if err != nil {
+		return err
+	}
+
+	c := b.Compare(runFindings(runs))
+	if baselineFormat == "json" {
+		enc := json.NewEncoder(cmd.OutOrStdout())
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(c); err != nil {
+			return err
+		}
+	} else {
+		writeComparison(cmd.OutOrStdout(), chartName, c)
+	}
+
+	if len(c.New) > 0 {
+		return findingsError(fmt.Errorf("%d finding(s) not in the baseline", len(c.New)))
+	}
+	return nil
+}
+
+// writeComparison prints each group of a comparison of chart's findings as a table
+func writeComparison(out io.Writer, chart string, c *baseline.Comparison) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	section := func(title string, n int) {
+		fmt.Fprintf(w, "%s (%d)\n", title, n)
+	}
+	row := func(id, category, location, reason string) {
+		reason, _, _ = strings.Cut(reason, "\n")
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\n", id, category, location, reason)
+	}
+
+	section("New", len(c.New))
+	for _, f := range c.New {
+		row(runner.FindingID(chart, runner.Fingerprint(f.Reason)), f.Category, f.Location(), f.Reason)
+	}
+	section("Known", len(c.Known))
+	for _, f := range c.Known {
+		row(runner.FindingID(chart, runner.Fingerprint(f.Reason)), f.Category, f.Location(), f.Reason)
+	}
+	section("Resolved", len(c.Resolved))
+	for _, e := range c.Resolved {
+		row(e.ID, e.Category, e.Location, e.Reason)
+	}
+	w.Flush()
+}
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/bench"
+	"github.com/kasuboski/helm-fuzzer/pkg/fuzz"
+)
+
+var (
+	benchIterations int
+	benchWarmup     int
+	benchSlowest    int
+	benchFormat     string
+	benchMaxP95     time.Duration
+)
+
+// benchCmd represents the bench command
+var benchCmd = &cobra.Command{
+	Use:   "bench <chart-path>",
+	Short: "Measure render latency across generated inputs",
+	Long: `Render the chart with the inputs a fuzzing session would generate, one at a
+time, and report the latency distribution (p50/p95/p99) and the slowest inputs
+with their values. Inputs follow the iteration index, so runs against two
+versions of a chart time the same inputs. With --max-p95 the command fails when
+the 95th percentile exceeds the limit, catching template performance
+regressions in CI.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeCharts(1, cobra.ShellCompDirectiveNoFileComp),
+	RunE:              runBench,
+}
+
+func init() {
+	rootCmd.AddCommand(benchCmd)
+
+	benchCmd.Flags().IntVar(&benchIterations, "iterations", 200, "Number of timed renders")
+	benchCmd.Flags().IntVar(&benchWarmup, "warmup", 3, "Untimed renders before timing starts")
+	benchCmd.Flags().IntVar(&benchSlowest, "slowest", 5, "Number of slowest inputs to show")
+	benchCmd.Flags().StringVarP(&benchFormat, "format", "o", "text", "Output format: "+strings.Join(bench.Formats, ", "))
+	benchCmd.Flags().DurationVar(&benchMaxP95, "max-p95", 0, "Fail if the 95th percentile render takes longer than this (e.g. 50ms)")
+}
+
+func runBench(cmd *cobra.Command, args []string) error {
+	chartPath, err := filepath.Abs(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to resolve chart path: %w", err)
+	}
+	if _, err := os.Stat(chartPath); os.IsNotExist(err) {
+		return fmt.Errorf("chart path does not exist: %s", chartPath)
+	}
+	if !slices.Contains(bench.Formats, benchFormat) {
+		return fmt.Errorf("invalid format %q: must be one of %s", benchFormat, strings.Join(bench.Formats, ", "))
+	}
+	if benchIterations <= 0 {
+		return fmt.Errorf("--iterations must be positive")
+	}
+
+	cfg, err := loadConfig(chartPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	session, err := fuzz.NewWithOptions(chartPath, fuzz.Options{Config: cfg})
+	if err != nil {
+		var configErr *fuzz.ConfigError
+		if errors.As(err, &configErr) {
+			return err
+		}
+		return infraError(err)
+	}
+
+	// An interrupted benchmark still reports what it measured
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	warmup := benchWarmup
+	if warmup == 0 {
+		warmup = -1
+	}
+	summary, err := bench.Run(ctx, chartPath, session, bench.Options{
+		Iterations: benchIterations,
+		Warmup:     warmup,
+		Slowest:    benchSlowest,
+	})
+	if err != nil {
+		return infraError(err)
+	}
+	if err := bench.Write(cmd.OutOrStdout(), benchFormat, summary); err != nil {
+		return err
+	}
+
+	if benchMaxP95 > 0 && summary.P95 > benchMaxP95 {
+		return findingsError(fmt.Errorf("p95 render time %s exceeds --max-p95 %s", summary.P95.Round(time.Microsecond), benchMaxP95))
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/report"
+)
+
+var compareFormat string
+
+// compareCmd represents the compare command
+var compareCmd = &cobra.Command{
+	Use:   "compare <before> <after>",
+	Short: "Track findings across sessions as new, persisting or resolved",
+	Long: `Compare the findings of two sets of sessions, such as the last release's and
+this one's, each given as a report.json or an output directory holding one or
+per-chart subdirectories of them. Findings are matched by chart and fingerprint
+and listed as new, persisting or resolved, with the counts before and after in
+total and per category. Charts only the earlier sessions fuzzed are named, not
+counted as resolved. Exits with code 1 if any finding is new.`,
+	Args: cobra.ExactArgs(2),
+	RunE: runCompare,
+}
+
+func init() {
+	rootCmd.AddCommand(compareCmd)
+
+	compareCmd.Flags().StringVarP(&compareFormat, "format", "o", "text", "Output format: text or json")
+}
+
+func runCompare(cmd *cobra.Command, args []string) error {
+	if compareFormat != "text" && compareFormat != "json" {
+		return fmt.Errorf("invalid format %q: must be text or json", compareFormat)
+	}
+	before, err := report.LoadRuns(args[:1])
+	if err != nil {
+		return err
+	}
+	after, err := report.LoadRuns(args[1:])
+	if err != nil {
+		return err
+	}
+
+	l := report.CompareRuns(before, after)
+	if compareFormat == "json" {
+		enc := json.NewEncoder(cmd.OutOrStdout())
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(l); err != nil {
+			return err
+		}
+	} else {
+		writeLifecycle(cmd.OutOrStdout(), l)
+	}
+
+	if len(l.New) > 0 {
+		return findingsError(fmt.Errorf("%d new finding(s)", len(l.New)))
+	}
+	return nil
+}
+
+// writeLifecycle prints the trend of a lifecycle and each group of its findings as a table
+func writeLifecycle(out io.Writer, l *report.Lifecycle) {
+	t := l.Trend
+	fmt.Fprintf(out, "Findings: %d before, %d after (%+d)\n\n", t.Before, t.After, t.Change)
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	group := func(title string, findings []report.LifecycleFinding) {
+		fmt.Fprintf(w, "%s (%d)\n", title, len(findings))
+		for _, f := range findings {
+			reason, _, _ := strings.Cut(f.Reason, "\n")
+			fmt.Fprintf(w, "  %s\t%s\t%s\t%s\n", f.ID, f.Category, f.Location, reason)
+		}
+	}
+	group("New", l.New)
+	group("Persisting", l.Persisting)
+	group("Resolved", l.Resolved)
+	if len(t.Categories) > 0 {
+		fmt.Fprintln(w, "Categories")
+		for _, c := range t.Categories {
+			fmt.Fprintf(w, "  %s\t%d\t%d\t%+d\n", c.Name, c.Before, c.After, c.After-c.Before)
+		}
+	}
+	w.Flush()
+
+	if len(l.Unfuzzed) > 0 {
+		fmt.Fprintf(out, "\nNot fuzzed again, so left out: %s\n", strings.Join(l.Unfuzzed, ", "))
+	}
+}
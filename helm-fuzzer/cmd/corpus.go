@@ -0,0 +1,259 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/config"
+	"github.com/kasuboski/helm-fuzzer/pkg/corpus"
+	"github.com/kasuboski/helm-fuzzer/pkg/coverage"
+	"github.com/kasuboski/helm-fuzzer/pkg/runner"
+	"github.com/kasuboski/helm-fuzzer/pkg/schema"
+)
+
+var (
+	corpusDirFlag  string
+	corpusDryRun   bool
+	corpusFeatures bool
+)
+
+// corpusCmd represents the corpus command
+var corpusCmd = &cobra.Command{
+	Use:   "corpus",
+	Short: "Manage a chart's seed corpus",
+	Long: `Manage the corpus of values files replayed as seeds before generated input.
+The corpus directory is the corpusDir in the chart's .helmfuzz.yaml unless --dir
+is given.`,
+}
+
+var corpusAddCmd = &cobra.Command{
+	Use:               "add <chart-path> <values-file>...",
+	Short:             "Add values files to the corpus",
+	Args:              cobra.MinimumNArgs(2),
+	ValidArgsFunction: completeCharts(1, cobra.ShellCompDirectiveDefault),
+	RunE:              runCorpusAdd,
+}
+
+var corpusListCmd = &cobra.Command{
+	Use:               "list <chart-path>",
+	Short:             "List corpus entries with the coverage and crashes each contributes",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeCharts(1, cobra.ShellCompDirectiveNoFileComp),
+	RunE:              runCorpusList,
+}
+
+var corpusMinimizeCmd = &cobra.Command{
+	Use:   "minimize <chart-path>",
+	Short: "Remove corpus entries that add no coverage or crashes",
+	Long: `Render every corpus entry and remove the entries whose value paths, enum
+values, rendered templates and crash fingerprints are all covered by the entries
+that remain.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeCharts(1, cobra.ShellCompDirectiveNoFileComp),
+	RunE:              runCorpusMinimize,
+}
+
+func init() {
+	rootCmd.AddCommand(corpusCmd)
+	corpusCmd.AddCommand(corpusAddCmd, corpusListCmd, corpusMinimizeCmd)
+
+	corpusCmd.PersistentFlags().StringVar(&corpusDirFlag, "dir", "", "Corpus directory (default: corpusDir from .helmfuzz.yaml)")
+	corpusListCmd.Flags().BoolVar(&corpusFeatures, "features", false, "List the features each entry covers")
+	corpusMinimizeCmd.Flags().BoolVar(&corpusDryRun, "dry-run", false, "Only print the entries that would be removed")
+}
+
+// corpusTarget resolves the chart path, its config and the corpus directory
+func corpusTarget(arg string) (string, *config.Config, string, error) {
+	chartPath, err := filepath.Abs(arg)
+	if err != nil {
+		return "", nil, "", fmt.Errorf("failed to resolve chart path: %w", err)
+	}
+	if _, err := os.Stat(chartPath); os.IsNotExist(err) {
+		return "", nil, "", fmt.Errorf("chart path does not exist: %s", chartPath)
+	}
+
+	cfg, err := loadConfig(chartPath)
+	if err != nil {
+		return "", nil, "", fmt.Errorf("failed to load config: %w", err)
+	}
+
+	dir := corpusDirFlag
+	if dir == "" {
+		dir = cfg.ResolveCorpusDir(chartPath)
+	}
+	if dir == "" {
+		return "", nil, "", fmt.Errorf("no corpus directory: set corpusDir in %s or pass --dir", filepath.Join(chartPath, ".helmfuzz.yaml"))
+	}
+	return chartPath, cfg, dir, nil
+}
+
+func runCorpusAdd(cmd *cobra.Command, args []string) error {
+	_, _, dir, err := corpusTarget(args[0])
+	if err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	for _, src := range args[1:] {
+		name, added, err := corpus.Add(dir, src)
+		if err != nil {
+			return err
+		}
+		if added {
+			fmt.Fprintf(out, "Added %s as %s\n", src, filepath.Join(dir, name))
+		} else {
+			fmt.Fprintf(out, "Skipped %s: same values as %s\n", src, name)
+		}
+	}
+	return nil
+}
+
+// entryResult is what rendering a corpus entry exercised
+type entryResult struct {
+	entry    corpus.Entry
+	coverage coverage.Summary
+	// features are the entry's coverage features plus crash:<fingerprint> if it crashed
+	features []string
+	reason   string
+}
+
+// analyzeCorpus renders every entry, returning each entry's result and the
+// coverage of the whole corpus
+func analyzeCorpus(chartPath string, cfg *config.Config, entries []corpus.Entry) ([]entryResult, coverage.Summary, error) {
+	sch, err := schema.NewEngine(cfg).DetectSchema(chartPath)
+	if err != nil {
+		return nil, coverage.Summary{}, infraError(fmt.Errorf("failed to detect schema: %w", err))
+	}
+	r, err := runner.New(chartPath)
+	if err != nil {
+		return nil, coverage.Summary{}, infraError(fmt.Errorf("failed to create runner: %w", err))
+	}
+	templates, err := r.Templates()
+	if err != nil {
+		return nil, coverage.Summary{}, infraError(fmt.Errorf("failed to list templates: %w", err))
+	}
+	oracle := runner.NewOracleWithConfig(cfg.IgnoreErrors, cfg.UninterestingPatterns)
+
+	total := coverage.New(sch, templates)
+	results := make([]entryResult, 0, len(entries))
+	for _, entry := range entries {
+		result := r.Run(entry.Values)
+		tracker := coverage.New(sch, templates)
+		tracker.Record(entry.Values, result.Templates)
+		total.Record(entry.Values, result.Templates)
+
+		er := entryResult{entry: entry, coverage: tracker.Summary(), features: tracker.Features()}
+		if oracle.IsCrash(result) && oracle.IsInteresting(result) {
+			er.reason = oracle.GetCrashReason(result)
+			er.features = append(er.features, "crash:"+runner.Fingerprint(er.reason))
+		}
+		results = append(results, er)
+	}
+	return results, total.Summary(), nil
+}
+
+func runCorpusList(cmd *cobra.Command, args []string) error {
+	chartPath, cfg, dir, err := corpusTarget(args[0])
+	if err != nil {
+		return err
+	}
+	entries, err := corpus.Entries(dir)
+	if err != nil {
+		return err
+	}
+	out := cmd.OutOrStdout()
+	if len(entries) == 0 {
+		fmt.Fprintf(out, "Corpus %s is empty\n", dir)
+		return nil
+	}
+
+	results, total, err := analyzeCorpus(chartPath, cfg, entries)
+	if err != nil {
+		return err
+	}
+
+	// Features no other entry covers show what removing an entry would lose
+	owners := make(map[string]int)
+	for _, r := range results {
+		for _, f := range r.features {
+			owners[f]++
+		}
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ENTRY\tPATHS\tENUMS\tTEMPLATES\tUNIQUE\tRESULT")
+	for _, r := range results {
+		unique := 0
+		for _, f := range r.features {
+			if owners[f] == 1 {
+				unique++
+			}
+		}
+		status := "ok"
+		if r.reason != "" {
+			status = fmt.Sprintf("crash %s (%s)", runner.Fingerprint(r.reason)[:12], runner.CategorizeReason(r.reason))
+		}
+		c := r.coverage
+		fmt.Fprintf(tw, "%s\t%d/%d\t%d/%d\t%d/%d\t%d\t%s\n", r.entry.Name,
+			c.PathsSet, c.Paths, c.EnumValuesChosen, c.EnumValues, c.TemplatesRendered, c.Templates, unique, status)
+		if corpusFeatures {
+			for _, f := range r.features {
+				fmt.Fprintf(tw, "  %s\t\t\t\t\t\n", f)
+			}
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "\n%d entries cover %d/%d value paths, %d/%d enum values, %d/%d templates\n", len(results),
+		total.PathsSet, total.Paths, total.EnumValuesChosen, total.EnumValues, total.TemplatesRendered, total.Templates)
+	return nil
+}
+
+func runCorpusMinimize(cmd *cobra.Command, args []string) error {
+	chartPath, cfg, dir, err := corpusTarget(args[0])
+	if err != nil {
+		return err
+	}
+	entries, err := corpus.Entries(dir)
+	if err != nil {
+		return err
+	}
+
+	results, _, err := analyzeCorpus(chartPath, cfg, entries)
+	if err != nil {
+		return err
+	}
+	features := make(map[string][]string, len(results))
+	for _, r := range results {
+		features[r.entry.Name] = r.features
+	}
+	keep := make(map[string]bool)
+	for _, name := range corpus.Minimize(features) {
+		keep[name] = true
+	}
+
+	out := cmd.OutOrStdout()
+	removed := 0
+	for _, entry := range entries {
+		if keep[entry.Name] {
+			continue
+		}
+		removed++
+		if corpusDryRun {
+			fmt.Fprintf(out, "Would remove %s\n", entry.Path)
+			continue
+		}
+		if err := os.Remove(entry.Path); err != nil {
+			return infraError(fmt.Errorf("failed to remove corpus entry: %w", err))
+		}
+		fmt.Fprintf(out, "Removed %s\n", entry.Path)
+	}
+	fmt.Fprintf(out, "Kept %d of %d entries\n", len(entries)-removed, len(entries))
+	return nil
+}
@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"helm.sh/helm/v3/pkg/chart/loader"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/coverage"
+	"github.com/kasuboski/helm-fuzzer/pkg/fuzz"
+	"github.com/kasuboski/helm-fuzzer/pkg/runner"
+)
+
+var (
+	coverageIterations int
+	coverageFormat     string
+	coverageMin        float64
+)
+
+// coverageCmd represents the coverage command
+var coverageCmd = &cobra.Command{
+	Use:   "coverage <chart-path>",
+	Short: "Report which template files, defines and branches generated inputs reach",
+	Long: `Render the chart with the inputs a fuzzing session would generate, seeds and
+corpus entries first, using templates instrumented to record each template file,
+define, and if/else, with and range branch as it executes. The report lists the
+regions no input reached, which point at values the schema or corpus never
+produces. Output that passes through functions such as b64enc or fromYaml hides
+what produced it, so coverage is a lower bound. With --min the command fails
+when less than that percentage of regions executed.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeCharts(1, cobra.ShellCompDirectiveNoFileComp),
+	RunE:              runCoverage,
+}
+
+func init() {
+	rootCmd.AddCommand(coverageCmd)
+
+	coverageCmd.Flags().IntVar(&coverageIterations, "iterations", 200, "Number of inputs to render")
+	coverageCmd.Flags().StringVarP(&coverageFormat, "format", "o", "text", "Output format: text or json")
+	coverageCmd.Flags().Float64Var(&coverageMin, "min", 0, "Fail if fewer than this percentage of regions executed")
+}
+
+// coverageReport is the region coverage of a chart across renders
+type coverageReport struct {
+	Chart   string `json:"chart"`
+	Renders int    `json:"renders"`
+	// Failed counts renders that errored; they record no coverage
+	Failed int `json:"failed"`
+	coverage.RegionSummary
+}
+
+func runCoverage(cmd *cobra.Command, args []string) error {
+	chartPath, err := filepath.Abs(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to resolve chart path: %w", err)
+	}
+	if _, err := os.Stat(chartPath); os.IsNotExist(err) {
+		return fmt.Errorf("chart path does not exist: %s", chartPath)
+	}
+	if coverageFormat != "text" && coverageFormat != "json" {
+		return fmt.Errorf("invalid format %q: must be text or json", coverageFormat)
+	}
+	if coverageIterations <= 0 {
+		return fmt.Errorf("--iterations must be positive")
+	}
+
+	cfg, err := loadConfig(chartPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	session, err := fuzz.NewWithOptions(chartPath, fuzz.Options{Config: cfg})
+	if err != nil {
+		var configErr *fuzz.ConfigError
+		if errors.As(err, &configErr) {
+			return err
+		}
+		return infraError(err)
+	}
+
+	c, err := loader.Load(chartPath)
+	if err != nil {
+		return infraError(fmt.Errorf("failed to load chart: %w", err))
+	}
+	instrumentation := coverage.Instrument(c)
+
+	runners := make([]*runner.Runner, 0, len(cfg.KubeVersions))
+	for _, kubeVersion := range cfg.KubeVersions {
+		r, err := runner.NewWithKubeVersion(chartPath, kubeVersion)
+		if err != nil {
+			return infraError(fmt.Errorf("failed to create runner: %w", err))
+		}
+		runners = append(runners, r)
+	}
+
+	// An interrupted sweep still reports what it reached
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	tracker := coverage.NewRegionTracker(instrumentation)
+	report := coverageReport{Chart: filepath.Base(chartPath)}
+	for i := 0; i < coverageIterations && ctx.Err() == nil; i++ {
+		// Rendering modifies the chart, so each render instruments a fresh copy
+		c, err := loader.Load(chartPath)
+		if err != nil {
+			return infraError(fmt.Errorf("failed to load chart: %w", err))
+		}
+		instrumentation.Apply(c)
+
+		report.Renders++
+		rendered, err := runners[i%len(runners)].RenderChart(c, session.Input(i))
+		if err != nil {
+			report.Failed++
+			continue
+		}
+		tracker.Record(instrumentation.Executed(rendered))
+	}
+	report.RegionSummary = tracker.Summary()
+
+	if coverageFormat == "json" {
+		enc := json.NewEncoder(cmd.OutOrStdout())
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			return err
+		}
+	} else {
+		writeCoverage(cmd.OutOrStdout(), &report)
+	}
+
+	if percent := coverage.Percent(report.Executed, report.Regions); coverageMin > 0 && percent < coverageMin {
+		return findingsError(fmt.Errorf("%.1f%% of template regions executed, below --min %.1f%%", percent, coverageMin))
+	}
+	return nil
+}
+
+// writeCoverage prints coverage per kind of region and the unexecuted regions by template
+func writeCoverage(out io.Writer, r *coverageReport) {
+	fmt.Fprintf(out, "Executed %d of %d template region(s) (%.1f%%) across %d render(s), %d failed\n\n",
+		r.Executed, r.Regions, coverage.Percent(r.Executed, r.Regions), r.Renders, r.Failed)
+
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "KIND\tEXECUTED\tREGIONS\tCOVERAGE")
+	for _, k := range r.Kinds {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.0f%%\n", k.Kind, k.Executed, k.Regions, coverage.Percent(k.Executed, k.Regions))
+	}
+	tw.Flush()
+
+	if len(r.Unexecuted) == 0 {
+		return
+	}
+	fmt.Fprintln(out, "\nUnexecuted regions:")
+	template := ""
+	for _, region := range r.Unexecuted {
+		if region.Template != template {
+			template = region.Template
+			fmt.Fprintf(out, "  %s\n", template)
+		}
+		label := region.Kind
+		if region.Name != "" {
+			label += " " + region.Name
+		}
+		fmt.Fprintf(out, "    %4d  %s\n", region.Line, label)
+	}
+}
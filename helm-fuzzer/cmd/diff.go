@@ -0,0 +1,246 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"helm.sh/helm/v3/pkg/registry"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/config"
+	"github.com/kasuboski/helm-fuzzer/pkg/corpus"
+	"github.com/kasuboski/helm-fuzzer/pkg/generator"
+	"github.com/kasuboski/helm-fuzzer/pkg/runner"
+	"github.com/kasuboski/helm-fuzzer/pkg/schema"
+)
+
+var (
+	diffIterations      int
+	diffTimeout         string
+	diffOutput          string
+	diffNoOutputChanges bool
+)
+
+// maxDiffChanges caps the changed fields printed per output divergence
+const maxDiffChanges = 5
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+	Use:   "diff <old-chart> <new-chart-path>",
+	Short: "Find inputs two versions of a chart handle differently",
+	Long: `Render the same inputs with two versions of a chart and report where they
+diverge: inputs the new chart crashes on (regressions), inputs it no longer crashes
+on, inputs that crash with a different error, and inputs whose rendered manifests
+differ. Inputs are generated from the new chart's schema after replaying its seeds.
+Each distinct divergence is reported once and its input saved to --output.
+The command fails when regressions are found.
+
+The old chart may be a chart directory, a packaged .tgz, or an OCI reference
+such as oci://ghcr.io/org/charts/app:1.2.0, pulled with helm's registry
+credentials, so a pull request can be checked against the last release.`,
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeCharts(2, cobra.ShellCompDirectiveNoFileComp),
+	RunE:              runDiff,
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+
+	diffCmd.Flags().IntVar(&diffIterations, "iterations", 0, "Number of inputs to compare (default: iterations from the new chart's config)")
+	diffCmd.Flags().StringVar(&diffTimeout, "timeout", "5m", "Timeout for the comparison (e.g., 5m, 1h)")
+	diffCmd.Flags().StringVar(&diffOutput, "output", ".", "Output directory for divergent inputs")
+	diffCmd.Flags().BoolVar(&diffNoOutputChanges, "no-output-changes", false, "Only report crash differences, not changed manifests")
+}
+
+// divergenceGroup is a distinct divergence and how often it occurred
+type divergenceGroup struct {
+	*runner.Divergence
+	iteration int
+	count     int
+	file      string
+}
+
+func runDiff(cmd *cobra.Command, args []string) error {
+	newPath, err := filepath.Abs(args[1])
+	if err != nil {
+		return fmt.Errorf("failed to resolve chart path: %w", err)
+	}
+	if _, err := os.Stat(newPath); os.IsNotExist(err) {
+		return fmt.Errorf("chart path does not exist: %s", newPath)
+	}
+
+	// A packaged or pulled old chart is unpacked into a temporary directory
+	oldDir, err := os.MkdirTemp("", "helm-fuzz-old-chart-")
+	if err != nil {
+		return infraError(fmt.Errorf("failed to create chart directory: %w", err))
+	}
+	defer os.RemoveAll(oldDir)
+	oldRef := args[0]
+	if !registry.IsOCI(oldRef) {
+		if oldRef, err = filepath.Abs(oldRef); err != nil {
+			return fmt.Errorf("failed to resolve chart path: %w", err)
+		}
+	}
+	oldPath, err := runner.OpenChart(oldDir, oldRef)
+	if err != nil {
+		if registry.IsOCI(oldRef) {
+			return infraError(err)
+		}
+		return err
+	}
+
+	timeout, err := time.ParseDuration(diffTimeout)
+	if err != nil {
+		return fmt.Errorf("invalid timeout: %w", err)
+	}
+
+	// The new chart's config and schema drive input generation and crash detection
+	cfg, err := loadConfig(newPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if diffIterations > 0 {
+		cfg.Iterations = diffIterations
+	}
+	input, err := comparisonInputs(cfg, newPath)
+	if err != nil {
+		return err
+	}
+	oracle := runner.NewOracleWithConfig(cfg.IgnoreErrors, cfg.UninterestingPatterns)
+	minimizer := runner.NewMinimizer(diffOutput)
+
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "Comparing %s -> %s over %d inputs\n", oldRef, newPath, cfg.Iterations)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	groups := make(map[string]*divergenceGroup)
+	var order []string
+	compared := 0
+	for i := 0; i < cfg.Iterations && ctx.Err() == nil; i++ {
+		values := input(i)
+
+		// Both charts render each input against the same Kubernetes version
+		kubeVersion := cfg.KubeVersions[i%len(cfg.KubeVersions)]
+		oldRunner, err := runner.NewWithKubeVersion(oldPath, kubeVersion)
+		if err != nil {
+			return infraError(fmt.Errorf("failed to create runner: %w", err))
+		}
+		newRunner, err := runner.NewWithKubeVersion(newPath, kubeVersion)
+		if err != nil {
+			return infraError(fmt.Errorf("failed to create runner: %w", err))
+		}
+		oldResult, newResult := oldRunner.Run(values), newRunner.Run(values)
+		compared++
+
+		d := runner.Diverge(oracle, oldResult, newResult)
+		if d == nil || (diffNoOutputChanges && d.Kind == runner.DivergenceOutput) {
+			continue
+		}
+		if g, ok := groups[d.Key()]; ok {
+			g.count++
+			continue
+		}
+
+		g := &divergenceGroup{Divergence: d, iteration: i + 1, count: 1}
+		file, err := minimizer.SaveReproduction(newResult, describeDivergence(d))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to save divergent input: %v\n", err)
+		}
+		g.file = file
+		groups[d.Key()] = g
+		order = append(order, d.Key())
+	}
+
+	regressions := 0
+	for _, key := range order {
+		g := groups[key]
+		if g.Kind == runner.DivergenceRegression {
+			regressions++
+		}
+		writeDivergence(cmd, g)
+	}
+
+	fmt.Fprintf(out, "\nCompared %d inputs: %d distinct divergence(s), %d regression(s)\n", compared, len(order), regressions)
+	if regressions > 0 {
+		return findingsError(fmt.Errorf("new chart regressed on %d input class(es)", regressions))
+	}
+	return nil
+}
+
+// comparisonInputs returns the inputs a comparison renders, by index: the
+// chart defaults, then the chart's seeds and corpus, then generated inputs
+func comparisonInputs(cfg *config.Config, chartPath string) (func(int) map[string]interface{}, error) {
+	sch, err := schema.NewEngine(cfg).DetectSchema(chartPath)
+	if err != nil {
+		return nil, infraError(fmt.Errorf("failed to detect schema: %w", err))
+	}
+	gen := generator.NewWithOptions(sch, generator.Options{
+		MaxDepth:           cfg.MaxDepth,
+		MaxTotalValuesSize: cfg.MaxTotalValuesSize,
+		MaxKeysPerObject:   cfg.MaxKeysPerObject,
+	})
+
+	seeds := append([]map[string]interface{}{{}}, cfg.Seeds...)
+	if corpusDir := cfg.ResolveCorpusDir(chartPath); corpusDir != "" {
+		entries, err := corpus.Load(corpusDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load corpus: %w", err)
+		}
+		seeds = append(seeds, entries...)
+	}
+
+	return func(i int) map[string]interface{} {
+		if i < len(seeds) {
+			return seeds[i]
+		}
+		return gen.Generate().Example(generator.Seed(cfg.Seed, generator.StreamValues, i))
+	}, nil
+}
+
+// describeDivergence summarizes a divergence in one line for repro file headers
+func describeDivergence(d *runner.Divergence) string {
+	switch d.Kind {
+	case runner.DivergenceRegression:
+		return d.Kind + ": " + firstLine(d.NewReason)
+	case runner.DivergenceFixed:
+		return d.Kind + ": " + firstLine(d.OldReason)
+	case runner.DivergenceChangedError:
+		return d.Kind + ": " + firstLine(d.NewReason)
+	default:
+		return fmt.Sprintf("%s: %d change(s)", d.Kind, len(d.Changes))
+	}
+}
+
+// writeDivergence prints a divergence group
+func writeDivergence(cmd *cobra.Command, g *divergenceGroup) {
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "\n[%s] first at input %d, %d input(s)\n", g.Kind, g.iteration, g.count)
+	if g.OldReason != "" {
+		fmt.Fprintf(out, "   old: %s\n", firstLine(g.OldReason))
+	}
+	if g.NewReason != "" {
+		fmt.Fprintf(out, "   new: %s\n", firstLine(g.NewReason))
+	}
+	for i, change := range g.Changes {
+		if i == maxDiffChanges {
+			fmt.Fprintf(out, "   ... (%d more)\n", len(g.Changes)-maxDiffChanges)
+			break
+		}
+		fmt.Fprintf(out, "   %s\n", change)
+	}
+	if g.file != "" {
+		fmt.Fprintf(out, "   input: %s\n", g.file)
+	}
+}
+
+// firstLine returns the first line of a multi-line string
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
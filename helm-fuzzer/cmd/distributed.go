@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/distributed"
+	"github.com/kasuboski/helm-fuzzer/pkg/report"
+	"github.com/kasuboski/helm-fuzzer/pkg/runner"
+)
+
+var (
+	coordinateAddr      string
+	coordinateShardSize int
+	leaseTimeout        time.Duration
+	workerName          string
+	workerConcurrency   int
+)
+
+// coordinateCmd represents the coordinate command
+var coordinateCmd = &cobra.Command{
+	Use:   "coordinate <chart-path>",
+	Short: "Shard a fuzzing session across worker processes",
+	Long: `Serve a chart to helm-fuzz work processes on this or other machines and hand
+out its iterations in shards. Workers send back their findings, which are
+deduplicated and saved here as one session, so a large chart can be fuzzed
+deeply overnight. A shard whose worker goes quiet for --lease-timeout is handed
+to another worker. Inputs follow the iteration index, so the session finds what
+a single process running the same iterations would.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeCharts(1, cobra.ShellCompDirectiveNoFileComp),
+	RunE:              runCoordinate,
+}
+
+// workCmd represents the work command
+var workCmd = &cobra.Command{
+	Use:   "work <coordinator-url>",
+	Short: "Fuzz shards handed out by a coordinator",
+	Long: `Fetch the chart and config from a helm-fuzz coordinate process and fuzz the
+shards it hands out until the session is done.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runWork,
+}
+
+func init() {
+	rootCmd.AddCommand(coordinateCmd)
+	rootCmd.AddCommand(workCmd)
+
+	coordinateCmd.Flags().StringVar(&coordinateAddr, "addr", ":9091", "Address workers connect to")
+	coordinateCmd.Flags().IntVar(&coordinateShardSize, "shard-size", 100, "Iterations per shard")
+	coordinateCmd.Flags().DurationVar(&leaseTimeout, "lease-timeout", 10*time.Minute, "Time a worker has to finish a shard before it is reassigned")
+	coordinateCmd.Flags().IntVar(&iterations, "iterations", 0, "Number of iterations (overrides config)")
+	coordinateCmd.Flags().Uint64Var(&seed, "seed", 0, "Session seed every worker derives its shards' inputs from (overrides config)")
+	coordinateCmd.Flags().StringVar(&timeoutStr, "timeout", "8h", "Stop the session after this long, keeping what completed shards found")
+	coordinateCmd.Flags().StringVar(&outputDir, "output", ".", "Output directory for reproduction files and reports")
+	coordinateCmd.MarkFlagDirname("output")
+	coordinateCmd.Flags().StringArrayVar(&reports, "report", nil, "Write a report at the end of the session as format=path (html, json, junit or markdown); repeatable")
+
+	workCmd.Flags().StringVar(&workerName, "name", "", "Name in the coordinator's logs (default: the hostname)")
+	workCmd.Flags().IntVar(&workerConcurrency, "workers", 0, "Concurrent renders on this machine (default: the config's workers)")
+}
+
+func runCoordinate(cmd *cobra.Command, args []string) error {
+	if err := runner.NeedsNetwork("coordinating workers"); err != nil {
+		return err
+	}
+	chartPath, err := filepath.Abs(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to resolve chart path: %w", err)
+	}
+	timeout, err := time.ParseDuration(timeoutStr)
+	if err != nil {
+		return fmt.Errorf("invalid timeout: %w", err)
+	}
+	var specs []report.Spec
+	for _, arg := range reports {
+		spec, err := report.ParseSpec(arg)
+		if err != nil {
+			return err
+		}
+		specs = append(specs, spec)
+	}
+
+	cfg, err := loadConfig(chartPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if iterations > 0 {
+		cfg.Iterations = iterations
+	}
+	if seed != 0 {
+		cfg.Seed = seed
+	}
+
+	logger := slog.New(slog.NewTextHandler(cmd.ErrOrStderr(), nil))
+	coordinator, err := distributed.NewCoordinator(chartPath, distributed.CoordinatorOptions{
+		Config:       cfg,
+		OutputDir:    outputDir,
+		ShardSize:    coordinateShardSize,
+		LeaseTimeout: leaseTimeout,
+		Logger:       logger,
+	})
+	if err != nil {
+		return infraError(err)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	addr, err := coordinator.Start(ctx, coordinateAddr)
+	if err != nil {
+		return infraError(err)
+	}
+	_, shards := coordinator.Progress()
+	logger.Info("coordinating", "chart", filepath.Base(chartPath), "iterations", cfg.Iterations, "shards", shards, "seed", cfg.Seed, "addr", addr.String())
+
+	select {
+	case <-coordinator.Done():
+		logger.Info("all shards completed")
+		// Keep answering long enough for polling workers to hear the session is done
+		time.Sleep(3 * time.Second)
+	case <-time.After(timeout):
+		completed, total := coordinator.Progress()
+		logger.Warn("timeout reached", "shards", fmt.Sprintf("%d/%d", completed, total))
+	case <-ctx.Done():
+		logger.Warn("interrupted")
+	}
+
+	session := coordinator.Session()
+	session.ChartPath = chartPath
+	session.ToolVersion = version
+	session.Config = cfg
+	specs = append(specs, report.Spec{Format: "json", Path: filepath.Join(outputDir, report.JSONFileName)})
+	for _, spec := range specs {
+		if err := report.WriteFile(spec, session); err != nil {
+			return infraError(err)
+		}
+		logger.Info("wrote report", "format", spec.Format, "path", spec.Path)
+	}
+
+	flaky := session.FlakyCount()
+	fmt.Fprintf(cmd.OutOrStdout(), "%d iterations, %d crashes, %d unique, %d flaky\n", session.Iterations, session.Crashes, len(session.Findings), flaky)
+	if len(session.Findings) > flaky {
+		return findingsError(fmt.Errorf("fuzzing found crashes"))
+	}
+	return nil
+}
+
+func runWork(cmd *cobra.Command, args []string) error {
+	if err := runner.NeedsNetwork("working for a coordinator"); err != nil {
+		return err
+	}
+	logger := slog.New(slog.NewTextHandler(cmd.ErrOrStderr(), nil))
+	worker := distributed.NewWorker(args[0], distributed.WorkerOptions{
+		Name:    workerName,
+		Workers: workerConcurrency,
+		Logger:  logger,
+	})
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	leases, err := worker.Run(ctx)
+	if err != nil {
+		return infraError(err)
+	}
+	logger.Info("worker finished", "shards", leases)
+	return nil
+}
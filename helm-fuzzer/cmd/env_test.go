@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestEnvName(t *testing.T) {
+	if got := envName("artifacts-dir"); got != "HELMFUZZ_ARTIFACTS_DIR" {
+		t.Errorf("expected HELMFUZZ_ARTIFACTS_DIR, got %s", got)
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	newCmd := func() (*cobra.Command, *string, *int, *bool, *[]string) {
+		cmd := &cobra.Command{Use: "fuzz"}
+		dir := cmd.Flags().String("artifacts-dir", "", "")
+		iterations := cmd.Flags().Int("iterations", 0, "")
+		ci := cmd.Flags().Bool("ci", false, "")
+		reports := cmd.Flags().StringArray("report", nil, "")
+		return cmd, dir, iterations, ci, reports
+	}
+
+	t.Setenv("HELMFUZZ_ARTIFACTS_DIR", "/artifacts")
+	t.Setenv("HELMFUZZ_ITERATIONS", "100")
+	t.Setenv("HELMFUZZ_CI", "true")
+	t.Setenv("HELMFUZZ_REPORT", "html=report.html")
+
+	cmd, dir, iterations, ci, reports := newCmd()
+	// A flag on the command line wins over its variable
+	if err := cmd.ParseFlags([]string{"--iterations", "5"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyEnv(cmd); err != nil {
+		t.Fatalf("applyEnv failed: %v", err)
+	}
+	if *dir != "/artifacts" || !*ci {
+		t.Errorf("expected the flags set from the environment, got artifacts-dir %q ci %t", *dir, *ci)
+	}
+	if *iterations != 5 {
+		t.Errorf("expected --iterations to win over HELMFUZZ_ITERATIONS, got %d", *iterations)
+	}
+	// A repeatable flag takes its variable as one value
+	if len(*reports) != 1 || (*reports)[0] != "html=report.html" {
+		t.Errorf("expected one report from HELMFUZZ_REPORT, got %v", *reports)
+	}
+	if !cmd.Flags().Changed("artifacts-dir") {
+		t.Error("expected a flag set from the environment to count as given")
+	}
+
+	t.Setenv("HELMFUZZ_ITERATIONS", "many")
+	cmd, _, _, _, _ = newCmd()
+	if err := applyEnv(cmd); err == nil || !strings.Contains(err.Error(), "invalid HELMFUZZ_ITERATIONS") {
+		t.Errorf("expected an error naming the variable, got %v", err)
+	}
+}
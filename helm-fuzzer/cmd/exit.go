@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"errors"
+)
+
+// Exit codes distinguish findings from a broken setup or environment
+const (
+	// ExitClean means the command completed and found nothing
+	ExitClean = 0
+	// ExitFindings means the chart has interesting crashes or regressions
+	ExitFindings = 1
+	// ExitUsage means invalid arguments, flags or configuration
+	ExitUsage = 2
+	// ExitInfrastructure means the fuzzer could not do its job: the chart
+	// failed to load, Helm failed to initialize or results could not be written
+	ExitInfrastructure = 3
+)
+
+// exitError carries the exit code for an error returned by a command
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+
+func (e *exitError) Unwrap() error { return e.err }
+
+// findingsError marks an error as reporting findings
+func findingsError(err error) error {
+	return &exitError{code: ExitFindings, err: err}
+}
+
+// infraError marks an error as an infrastructure failure
+func infraError(err error) error {
+	return &exitError{code: ExitInfrastructure, err: err}
+}
+
+// ExitCode returns the process exit code for an error returned by Execute.
+// Errors that are not marked otherwise are usage or configuration errors.
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitClean
+	}
+	var e *exitError
+	if errors.As(err, &e) {
+		return e.code
+	}
+	return ExitUsage
+}
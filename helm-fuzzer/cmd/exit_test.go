@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestExitCode(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"no error", nil, ExitClean},
+		{"findings", findingsError(errors.New("fuzzing found crashes")), ExitFindings},
+		{"usage", errors.New("unknown flag: --bogus"), ExitUsage},
+		{"infrastructure", infraError(errors.New("failed to load chart")), ExitInfrastructure},
+		{"wrapped", fmt.Errorf("chart app: %w", infraError(errors.New("failed to load chart"))), ExitInfrastructure},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExitCode(tt.err); got != tt.want {
+				t.Errorf("ExitCode(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSessionsResult(t *testing.T) {
+	loadErr := infraError(errors.New("failed to load chart"))
+	configErr := errors.New("invalid .helmfuzz.yaml")
+
+	for _, tt := range []struct {
+		name string
+		runs []*chartRun
+		want int
+		// message is part of the error expected, if any
+		message string
+	}{
+		{
+			name: "clean",
+			runs: []*chartRun{{name: "api"}, {name: "worker"}},
+			want: ExitClean,
+		},
+		{
+			name:    "findings",
+			runs:    []*chartRun{{name: "api", crashFound: true}, {name: "worker"}},
+			want:    ExitFindings,
+			message: "fuzzing found crashes",
+		},
+		{
+			name:    "findings and an infrastructure failure",
+			runs:    []*chartRun{{name: "api", crashFound: true}, {name: "worker", err: loadErr}},
+			want:    ExitInfrastructure,
+			message: "fuzzing failed for 1 of 2 runs: worker",
+		},
+		{
+			name:    "findings and a usage error",
+			runs:    []*chartRun{{name: "api", crashFound: true}, {name: "worker", err: configErr}},
+			want:    ExitUsage,
+			message: "fuzzing failed for 1 of 2 runs: worker",
+		},
+		{
+			name:    "usage error and an infrastructure failure",
+			runs:    []*chartRun{{name: "api", err: configErr}, {name: "worker", err: loadErr}},
+			want:    ExitInfrastructure,
+			message: "fuzzing failed for 2 of 2 runs: api, worker",
+		},
+		{
+			name:    "single run that fails",
+			runs:    []*chartRun{{name: "api", err: loadErr}},
+			want:    ExitInfrastructure,
+			message: "failed to load chart",
+		},
+		{
+			name:    "single run with a usage error",
+			runs:    []*chartRun{{name: "api", err: configErr}},
+			want:    ExitUsage,
+			message: "invalid .helmfuzz.yaml",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			err := sessionsResult(tt.runs)
+			if got := ExitCode(err); got != tt.want {
+				t.Errorf("expected exit code %d, got %d (%v)", tt.want, got, err)
+			}
+			if tt.message != "" && (err == nil || !strings.Contains(err.Error(), tt.message)) {
+				t.Errorf("expected an error containing %q, got %v", tt.message, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/explain"
+	"github.com/kasuboski/helm-fuzzer/pkg/runner"
+)
+
+var explainFormat string
+
+// explainCmd represents the explain command
+var explainCmd = &cobra.Command{
+	Use:   "explain [chart-path] <repro-file>",
+	Short: "Explain which values make a reproduction crash and where",
+	Long: `Render a crashing values file against the chart, then remove its values one
+subtree or key at a time, keeping each removal that still crashes with the same
+fingerprint. Removed values fall back to the chart defaults, so what remains is
+what the crash needs. The explanation names those values, any value the failing
+expression expected to be set, and the template location, e.g.
+
+  crash requires ingress.enabled=true and ingress.hosts[0].host unset at templates/ingress.yaml:23
+
+The chart defaults to the working directory.`,
+	Args:              cobra.RangeArgs(1, 2),
+	ValidArgsFunction: completeCharts(1, cobra.ShellCompDirectiveDefault),
+	RunE:              runExplain,
+}
+
+func init() {
+	rootCmd.AddCommand(explainCmd)
+
+	explainCmd.Flags().StringVarP(&explainFormat, "format", "o", "text", "Output format: "+strings.Join(explain.Formats, ", "))
+}
+
+func runExplain(cmd *cobra.Command, args []string) error {
+	chartArg, reproFile := ".", args[0]
+	if len(args) == 2 {
+		chartArg, reproFile = args[0], args[1]
+	}
+	chartPath, err := filepath.Abs(chartArg)
+	if err != nil {
+		return fmt.Errorf("failed to resolve chart path: %w", err)
+	}
+	if _, err := os.Stat(filepath.Join(chartPath, "Chart.yaml")); os.IsNotExist(err) {
+		return fmt.Errorf("no chart found at %s", chartPath)
+	}
+	if !slices.Contains(explain.Formats, explainFormat) {
+		return fmt.Errorf("invalid format %q: must be one of %s", explainFormat, strings.Join(explain.Formats, ", "))
+	}
+
+	values, _, err := runner.LoadReproduction(reproFile)
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig(chartPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	oracle := runner.NewOracleWithConfig(cfg.IgnoreErrors, cfg.UninterestingPatterns)
+	replay, err := replayer(chartPath, cfg, oracle, nil)
+	if err != nil {
+		return err
+	}
+	r, err := runner.New(chartPath)
+	if err != nil {
+		return infraError(fmt.Errorf("failed to create runner: %w", err))
+	}
+	defaults, err := r.DefaultValues()
+	if err != nil {
+		return infraError(err)
+	}
+
+	explanation, err := explain.Explain(values, defaults, explain.ReplayFunc(replay))
+	if err != nil {
+		return fmt.Errorf("%s: %w", reproFile, err)
+	}
+	if attr := runner.Attribute(explanation.Reason); attr != nil {
+		// The snippet is a nicety; the explanation stands without it
+		explanation.Snippet, _ = r.Snippet(attr, explanation.Minimal, 3)
+	}
+	return explain.Write(cmd.OutOrStdout(), explainFormat, explanation)
+}
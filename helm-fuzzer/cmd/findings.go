@@ -0,0 +1,245 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"helm.sh/helm/v3/pkg/chartutil"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/findingsdb"
+)
+
+var (
+	findingsDB       string
+	findingsChart    string
+	findingsStatus   string
+	findingsSeverity string
+	findingsCategory string
+	findingsSince    time.Duration
+	findingsFormat   string
+)
+
+// findingsCmd represents the findings command
+var findingsCmd = &cobra.Command{
+	Use:   "findings",
+	Short: "Track the crash history of charts across sessions",
+	Long: `The findings database remembers every crash fuzz has found: its ID and
+fingerprint, when it was first and last seen and in which chart versions, its severity, and
+whether it has been fixed. fuzz --findings-db adds each session's findings to
+it; a fixed finding found again is reopened. The database is a SQLite file,
+` + findingsdb.FileName + ` in the working directory unless --db is given, so it
+can be kept in CI caches, shared by sessions running at once, and queried with
+sqlite3 beyond what these commands offer.
+
+Severity follows the crash category: panics are critical, nil pointers and type
+mismatches high, template and parse errors medium, anything else low.`,
+}
+
+var findingsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List findings, most severe and most recently seen first",
+	Args:  cobra.NoArgs,
+	RunE:  runFindingsList,
+}
+
+var findingsQueryCmd = &cobra.Command{
+	Use:   "query <id|fingerprint>",
+	Short: "Show the history of a finding",
+	Long: `Show everything recorded about the finding with the given ID, as shown by list
+and in reports, or the findings whose fingerprint starts with the given prefix.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runFindingsQuery,
+}
+
+var findingsMarkFixedCmd = &cobra.Command{
+	Use:   "mark-fixed <id|fingerprint>...",
+	Short: "Mark findings as fixed",
+	Long: `Mark the findings with the given IDs, fingerprints or unique fingerprint
+prefixes as fixed. A fixed finding that a later session finds again is
+reopened.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runFindingsMarkFixed,
+}
+
+func init() {
+	rootCmd.AddCommand(findingsCmd)
+	findingsCmd.AddCommand(findingsListCmd, findingsQueryCmd, findingsMarkFixedCmd)
+
+	findingsCmd.PersistentFlags().StringVar(&findingsDB, "db", findingsdb.FileName, "Findings database file")
+	findingsCmd.MarkPersistentFlagFilename("db", "db")
+	findingsCmd.PersistentFlags().StringVar(&findingsChart, "chart", "", "Only findings of this chart (its directory name)")
+	findingsListCmd.Flags().StringVar(&findingsStatus, "status", "", "Only findings with this status: open or fixed")
+	findingsListCmd.Flags().StringVar(&findingsSeverity, "severity", "", "Only findings with this severity: critical, high, medium or low")
+	findingsListCmd.Flags().StringVar(&findingsCategory, "category", "", "Only findings in this crash category (e.g. \"nil pointer\")")
+	findingsListCmd.Flags().DurationVar(&findingsSince, "since", 0, "Only findings seen within this long (e.g. 168h)")
+	findingsListCmd.Flags().StringVarP(&findingsFormat, "format", "o", "text", "Output format: text or json")
+	findingsQueryCmd.Flags().StringVarP(&findingsFormat, "format", "o", "text", "Output format: text or json")
+}
+
+func runFindingsList(cmd *cobra.Command, args []string) error {
+	if findingsFormat != "text" && findingsFormat != "json" {
+		return fmt.Errorf("invalid format %q: must be text or json", findingsFormat)
+	}
+	if findingsStatus != "" && findingsStatus != findingsdb.StatusOpen && findingsStatus != findingsdb.StatusFixed {
+		return fmt.Errorf("invalid status %q: must be open or fixed", findingsStatus)
+	}
+	if findingsSeverity != "" && !slices.Contains(findingsdb.Severities, findingsSeverity) {
+		return fmt.Errorf("invalid severity %q: must be critical, high, medium or low", findingsSeverity)
+	}
+	db, err := findingsdb.Open(findingsDB)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	filter := findingsdb.Filter{
+		Chart:    findingsChart,
+		Status:   findingsStatus,
+		Severity: findingsSeverity,
+		Category: findingsCategory,
+	}
+	if findingsSince > 0 {
+		filter.Since = time.Now().Add(-findingsSince)
+	}
+	records, err := db.List(filter)
+	if err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	if findingsFormat == "json" {
+		return writeFindingsJSON(out, records)
+	}
+	if len(records) == 0 {
+		fmt.Fprintln(out, "No findings")
+		return nil
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSEVERITY\tSTATUS\tLAST SEEN\tSESSIONS\tREASON")
+	for _, r := range records {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\n", r.ID, r.Severity, r.Status,
+			r.LastSeen.Local().Format(time.DateTime), r.Sessions, firstLine(r.Reason))
+	}
+	return w.Flush()
+}
+
+func runFindingsQuery(cmd *cobra.Command, args []string) error {
+	if findingsFormat != "text" && findingsFormat != "json" {
+		return fmt.Errorf("invalid format %q: must be text or json", findingsFormat)
+	}
+	db, err := findingsdb.Open(findingsDB)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	records, err := db.Lookup(findingsChart, args[0])
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return fmt.Errorf("no finding matches %q", args[0])
+	}
+
+	out := cmd.OutOrStdout()
+	if findingsFormat == "json" {
+		return writeFindingsJSON(out, records)
+	}
+	for i, r := range records {
+		if i > 0 {
+			fmt.Fprintln(out)
+		}
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "ID:\t%s\n", r.ID)
+		fmt.Fprintf(w, "Fingerprint:\t%s\n", r.Fingerprint)
+		fmt.Fprintf(w, "Chart:\t%s\n", r.Chart)
+		fmt.Fprintf(w, "Status:\t%s\n", r.Status)
+		fmt.Fprintf(w, "Severity:\t%s (%s)\n", r.Severity, r.Category)
+		if r.Location != "" {
+			fmt.Fprintf(w, "Location:\t%s\n", r.Location)
+		}
+		fmt.Fprintf(w, "First seen:\t%s%s\n", r.FirstSeen.Local().Format(time.DateTime), inVersion(r.FirstVersion))
+		fmt.Fprintf(w, "Last seen:\t%s%s\n", r.LastSeen.Local().Format(time.DateTime), inVersion(r.LastVersion))
+		fmt.Fprintf(w, "Sessions:\t%d\n", r.Sessions)
+		if r.FixedAt != nil {
+			fmt.Fprintf(w, "Fixed:\t%s\n", r.FixedAt.Local().Format(time.DateTime))
+		}
+		if r.Reopened > 0 {
+			fmt.Fprintf(w, "Reopened:\t%d time(s)\n", r.Reopened)
+		}
+		if r.ReproFile != "" {
+			fmt.Fprintf(w, "Reproduction:\t%s\n", r.ReproFile)
+		}
+		w.Flush()
+		fmt.Fprintf(out, "Reason:\n  %s\n", r.Reason)
+	}
+	return nil
+}
+
+func runFindingsMarkFixed(cmd *cobra.Command, args []string) error {
+	db, err := findingsdb.Open(findingsDB)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	now := time.Now().UTC()
+	for _, prefix := range args {
+		r, err := db.MarkFixed(findingsChart, prefix, now)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Marked %s as fixed\n", r.ID)
+	}
+	return nil
+}
+
+// writeFindingsJSON prints records as a JSON array
+func writeFindingsJSON(out io.Writer, records []*findingsdb.Record) error {
+	if records == nil {
+		records = []*findingsdb.Record{}
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(records)
+}
+
+// inVersion describes the chart version a finding was seen in, if known
+func inVersion(version string) string {
+	if version == "" {
+		return ""
+	}
+	return " in version " + version
+}
+
+// recordFindings adds the findings of the finished runs to the findings database
+func recordFindings(cmd *cobra.Command, path string, runs []*chartRun) error {
+	db, err := findingsdb.Open(path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	added, reopened := 0, 0
+	for _, run := range runs {
+		if run.session == nil {
+			continue
+		}
+		// The chart version is only context; a chart without one is still recorded
+		version := ""
+		if metadata, err := chartutil.LoadChartfile(filepath.Join(run.chartPath, chartutil.ChartfileName)); err == nil {
+			version = metadata.Version
+		}
+		a, r, err := db.RecordSession(filepath.Base(run.chartPath), version, run.session.Findings, run.session.StartTime.UTC())
+		if err != nil {
+			return err
+		}
+		added += a
+		reopened += r
+	}
+	fmt.Fprintf(cmd.ErrOrStderr(), "Findings database %s: %d new, %d reopened\n", path, added, reopened)
+	return nil
+}
@@ -1,60 +1,293 @@
 package cmd
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"io/fs"
+	"log/slog"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/cli/values"
+	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/postrender"
 
+	"github.com/kasuboski/helm-fuzzer/pkg/baseline"
 	"github.com/kasuboski/helm-fuzzer/pkg/config"
-	"github.com/kasuboski/helm-fuzzer/pkg/generator"
+	"github.com/kasuboski/helm-fuzzer/pkg/fuzz"
+	"github.com/kasuboski/helm-fuzzer/pkg/metrics"
+	"github.com/kasuboski/helm-fuzzer/pkg/notify"
+	"github.com/kasuboski/helm-fuzzer/pkg/report"
+	"github.com/kasuboski/helm-fuzzer/pkg/retention"
 	"github.com/kasuboski/helm-fuzzer/pkg/runner"
-	"github.com/kasuboski/helm-fuzzer/pkg/schema"
+	"github.com/kasuboski/helm-fuzzer/pkg/tracing"
 	"github.com/kasuboski/helm-fuzzer/pkg/tui"
 )
 
 var (
-	ciMode     bool
-	timeoutStr string
-	iterations int
-	outputDir  string
+	ciMode      bool
+	plainMode   bool
+	logFormat   string
+	timeoutStr  string
+	iterations  int
+	outputDir   string
+	reports     []string
+	annotate    bool
+	verbose     bool
+	quiet       bool
+	metricsAddr string
+	noEmoji     bool
+	recursive   bool
+	failFast    bool
+	throttle    string
+	onCrash     string
+	seed        uint64
+	resume      bool
+	guided      bool
+	saveCorpus  bool
+	culprits    bool
+	raceRenders int
+	tagCombos   bool
+
+	continuous     bool
+	reportInterval time.Duration
+	riskWeighted   bool
+	builtIns       bool
+	useEnvtest     bool
+	upgrades       bool
+
+	artifactsDir     string
+	dependencyUpdate bool
+	valueOpts        values.Options
+	sessionBaseline  string
+	sessionFindings  string
 )
 
+// stateSaveInterval is how often a running session saves its state for --resume
+const stateSaveInterval = 10 * time.Second
+
 // fuzzCmd represents the fuzz command
 var fuzzCmd = &cobra.Command{
-	Use:   "fuzz <chart-path>",
+	Use:   "fuzz <chart-path>...",
 	Short: "Run fuzzing on a Helm chart",
 	Long: `Run property-based fuzzing on a Helm chart by generating randomized
 valid inputs and testing template rendering. This helps discover edge cases
 that cause crashes or errors in chart templates.`,
-	Args: cobra.ExactArgs(1),
-	RunE: runFuzz,
+	Args:              cobra.MinimumNArgs(1),
+	ValidArgsFunction: completeCharts(-1, cobra.ShellCompDirectiveNoFileComp),
+	RunE:              runFuzz,
 }
 
 func init() {
 	rootCmd.AddCommand(fuzzCmd)
+	addSessionFlags(fuzzCmd)
 
-	fuzzCmd.Flags().BoolVar(&ciMode, "ci", false, "Run in CI mode (non-interactive)")
-	fuzzCmd.Flags().StringVar(&timeoutStr, "timeout", "5m", "Timeout for fuzzing session (e.g., 5m, 1h)")
-	fuzzCmd.Flags().IntVar(&iterations, "iterations", 0, "Number of iterations (overrides config)")
-	fuzzCmd.Flags().StringVar(&outputDir, "output", ".", "Output directory for reproduction files")
+	fuzzCmd.Flags().BoolVarP(&recursive, "recursive", "r", false, "Fuzz every chart found under the given directories")
+	fuzzCmd.Flags().BoolVar(&watchMode, "watch", false, "Re-run a quick session whenever the chart's templates or values change")
+	fuzzCmd.Flags().IntVar(&watchIterations, "watch-iterations", 100, "Iterations per session in watch mode, unless --iterations is set")
+	fuzzCmd.Flags().BoolVar(&continuous, "continuous", false, "Fuzz until interrupted, evolving a corpus of inputs that reach new coverage and resuming where the last run stopped")
+	fuzzCmd.Flags().DurationVar(&reportInterval, "report-interval", 10*time.Minute, "How often a continuous session rewrites its reports")
+	fuzzCmd.MarkFlagsMutuallyExclusive("continuous", "watch")
+	fuzzCmd.MarkFlagsMutuallyExclusive("continuous", "fail-fast")
+}
+
+// addSessionFlags registers the flags shared by commands that run fuzzing sessions
+func addSessionFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&ciMode, "ci", false, "Run in CI mode (non-interactive); defaults to true when stdout is not a terminal")
+	cmd.Flags().BoolVar(&plainMode, "plain", false, "Use the plain progress line instead of the interactive dashboard")
+	cmd.Flags().StringVar(&logFormat, "log-format", "text", "Output format: text or json (one JSON object per event)")
+	cmd.Flags().BoolVar(&annotate, "github-annotations", false, "Print GitHub Actions ::error/::warning annotations for findings")
+	cmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Print debug logging")
+	cmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "Only print errors and the final summary")
+	cmd.MarkFlagsMutuallyExclusive("verbose", "quiet")
+	cmd.Flags().StringVar(&metricsAddr, "metrics-addr", "", "Serve Prometheus metrics on this address (e.g. :9090)")
+	cmd.Flags().BoolVar(&noEmoji, "no-emoji", false, "ASCII-only output without emoji or color (also enabled by NO_COLOR)")
+	cmd.Flags().StringVar(&timeoutStr, "timeout", "5m", "Timeout for fuzzing session (e.g., 5m, 1h)")
+	cmd.Flags().StringVar(&throttle, "throttle", "", "Pace iterations to share the machine: a CPU percentage per worker such as 50% or a rate such as 20/s (overrides config)")
+	cmd.Flags().StringVar(&onCrash, "on-crash", "", "Shell command run for each new unique crash, given the finding in HELMFUZZ_FINDING_* variables and as JSON on stdin (overrides config)")
+	cmd.Flags().Uint64Var(&seed, "seed", 0, "Session seed every input is derived from, reproducing a session whatever its workers (overrides config); 0 draws a fresh seed, recorded in report.json")
+	cmd.Flags().BoolVar(&failFast, "fail-fast", false, "Stop at the first interesting crash instead of collecting every unique crash in the budget")
+	cmd.Flags().BoolVar(&resume, "resume", false, "Continue the interrupted session saved in the output directory, if any")
+	cmd.Flags().BoolVar(&guided, "guided", false, "Observe which template branches each input reaches and mutate inputs that reached new ones; renders each input twice")
+	cmd.Flags().BoolVar(&riskWeighted, "risk-weighted", false, "Vary the values paths feeding risky template constructs more often (see helm-fuzz risk)")
+	cmd.Flags().BoolVar(&builtIns, "builtins", false, "Vary the release name, namespace, revision, chart appVersion and Kubernetes patch version each input renders with")
+	cmd.Flags().BoolVar(&useEnvtest, "envtest", false, "Dry-run create every successful render on a local API server per Kubernetes version, started from setup-envtest binaries")
+	cmd.Flags().BoolVar(&upgrades, "upgrades", false, "Also install the chart's default render on each local API server and dry-run every successful render over it as an upgrade; implies --envtest")
+	cmd.Flags().BoolVar(&culprits, "culprits", true, "Ablate the input of each new unique crash to the value paths it needs, recorded in reports and reproduction files")
+	cmd.Flags().BoolVar(&tagCombos, "tag-combinations", false, "Render every combination of the tags gating the chart's dependencies in turn, recording the tags each finding rendered with")
+	cmd.Flags().IntVar(&raceRenders, "race-renders", 0, "Render every successful input again from this many goroutines at once, reporting renders that fail or differ as races; 0 disables")
+	cmd.Flags().BoolVar(&saveCorpus, "save-corpus", false, "Save inputs that reach new coverage or a new crash category to the corpus directory, replayed as seeds by later sessions")
+	cmd.Flags().BoolVar(&dependencyUpdate, "dependency-update", false, "Build missing chart dependencies before fuzzing, using helm's repositories and registry credentials")
+	cmd.Flags().IntVar(&iterations, "iterations", 0, "Number of iterations (overrides config); 0 runs until --timeout")
+	cmd.Flags().StringVar(&outputDir, "output", ".", "Output directory for reproduction files")
+	cmd.MarkFlagDirname("output")
+	cmd.Flags().StringVar(&artifactsDir, "artifacts-dir", "", "Run non-interactively and write reproductions, the session log and every report format to this directory, for containers and CI")
+	cmd.MarkFlagDirname("artifacts-dir")
+	cmd.MarkFlagsMutuallyExclusive("artifacts-dir", "output")
+	cmd.Flags().StringSliceVarP(&valueOpts.ValueFiles, "values", "f", nil, "Pin values from a YAML file in every input, as with helm install -f; repeatable")
+	cmd.MarkFlagFilename("values", "yaml", "yml")
+	cmd.Flags().StringArrayVar(&valueOpts.Values, "set", nil, "Pin a value in every input (e.g. license.key=abc); repeatable")
+	cmd.Flags().StringArrayVar(&valueOpts.StringValues, "set-string", nil, "Pin a STRING value in every input; repeatable")
+	cmd.Flags().StringArrayVar(&valueOpts.FileValues, "set-file", nil, "Pin a value read from a file in every input (e.g. tls.crt=path/to/cert); repeatable")
+	cmd.Flags().StringArrayVar(&valueOpts.JSONValues, "set-json", nil, "Pin a JSON value in every input; repeatable")
+	cmd.Flags().StringVar(&sessionBaseline, "baseline", "", "Fail only on findings not accepted by this baseline file (see helm-fuzz baseline)")
+	cmd.MarkFlagFilename("baseline", "json")
+	cmd.Flags().StringVar(&sessionFindings, "findings-db", "", "Record every finding in this findings database, reopening fixed ones found again (see helm-fuzz findings)")
+	cmd.MarkFlagFilename("findings-db", "db")
+	cmd.Flags().StringArrayVar(&reports, "report", nil, "Write a report at the end of the session as format=path (html, json, junit or markdown, e.g. html=report.html); repeatable")
 }
 
 func runFuzz(cmd *cobra.Command, args []string) error {
-	chartPath := args[0]
+	runs := make([]*chartRun, 0, len(args))
+	chartNames := make(map[string]string)
+	// foundNames names the charts --recursive found by their path below the
+	// directory searched, so charts of one name in different teams' trees
+	// stay apart
+	foundNames := make(map[string]string)
+	if recursive {
+		var charts []string
+		for _, arg := range args {
+			found, err := findCharts(arg)
+			if err != nil {
+				return err
+			}
+			for _, chart := range found {
+				foundNames[chart] = foundChartName(arg, chart)
+			}
+			charts = append(charts, found...)
+		}
+		if len(charts) == 0 {
+			return fmt.Errorf("no charts found under %s", strings.Join(args, ", "))
+		}
+		args = charts
+	}
+	for _, arg := range args {
+		// Resolve absolute path
+		chartPath, err := filepath.Abs(arg)
+		if err != nil {
+			return fmt.Errorf("failed to resolve chart path: %w", err)
+		}
 
-	// Resolve absolute path
-	absPath, err := filepath.Abs(chartPath)
+		// Verify chart exists
+		if _, err := os.Stat(chartPath); os.IsNotExist(err) {
+			return fmt.Errorf("chart path does not exist: %s", chartPath)
+		}
+
+		// Chart names label output rows and name per-chart output directories
+		name := filepath.Base(chartPath)
+		if found, ok := foundNames[arg]; ok {
+			name = found
+		}
+		if other, ok := chartNames[name]; ok {
+			return fmt.Errorf("charts %s and %s have the same name %q", other, chartPath, name)
+		}
+		chartNames[name] = chartPath
+		runs = append(runs, &chartRun{chartPath: chartPath, name: name})
+	}
+
+	if watchMode {
+		return watchCharts(cmd, runs)
+	}
+	if err := runSessions(cmd, runs, "chart"); err != nil {
+		return err
+	}
+	return sessionsResult(runs)
+}
+
+// findCharts returns the directories under root that contain a Chart.yaml,
+// in lexical order. Subcharts of a found chart and hidden directories are not
+// searched, since dependencies are fuzzed through their parent.
+func findCharts(root string) ([]string, error) {
+	var charts []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != root && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if _, err := os.Stat(filepath.Join(path, "Chart.yaml")); err == nil {
+			charts = append(charts, path)
+			return filepath.SkipDir
+		}
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("failed to resolve chart path: %w", err)
+		return nil, fmt.Errorf("failed to search for charts: %w", err)
+	}
+	return charts, nil
+}
+
+// foundChartName names a chart findCharts found under root by its slash
+// separated path relative to root, or by its directory name when it is root
+func foundChartName(root, chart string) string {
+	rel, err := filepath.Rel(root, chart)
+	if err != nil || rel == "." {
+		if abs, err := filepath.Abs(chart); err == nil {
+			chart = abs
+		}
+		return filepath.Base(chart)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// runSessions runs the sessions concurrently, each with its own output
+// subdirectory and progress row when there are several; label names what
+// each row is. Session failures are recorded on each run for sessionsResult.
+func runSessions(cmd *cobra.Command, runs []*chartRun, label string) error {
+	if logFormat != "text" && logFormat != "json" {
+		return fmt.Errorf("invalid log format %q: must be text or json", logFormat)
 	}
-	chartPath = absPath
 
-	// Verify chart exists
-	if _, err := os.Stat(chartPath); os.IsNotExist(err) {
-		return fmt.Errorf("chart path does not exist: %s", chartPath)
+	var reportSpecs []report.Spec
+	for _, arg := range reports {
+		spec, err := report.ParseSpec(arg)
+		if err != nil {
+			return err
+		}
+		reportSpecs = append(reportSpecs, spec)
+	}
+
+	var accepted *baseline.Baseline
+	if sessionBaseline != "" {
+		var err error
+		if accepted, err = baseline.Load(sessionBaseline); err != nil {
+			return err
+		}
+	}
+
+	if metricsAddr != "" {
+		if err := runner.NeedsNetwork("serving metrics"); err != nil {
+			return err
+		}
+	}
+	if dependencyUpdate {
+		if err := runner.NeedsNetwork("--dependency-update"); err != nil {
+			return fmt.Errorf("%w; build dependencies with helm dependency build beforehand", err)
+		}
+	}
+	if err := checkOfflineValues(valueOpts); err != nil {
+		return err
+	}
+
+	// Pinned values are read once, the way helm reads them, and shared by every session
+	pinned, err := valueOpts.MergeValues(getter.All(cli.New()))
+	if err != nil {
+		return fmt.Errorf("failed to read pinned values: %w", err)
 	}
 
 	// Parse timeout
@@ -63,120 +296,729 @@ func runFuzz(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("invalid timeout: %w", err)
 	}
 
+	// A continuous session has no budget at all
+	if continuous {
+		if cmd.Flags().Changed("iterations") || cmd.Flags().Changed("timeout") {
+			return fmt.Errorf("--continuous runs until interrupted; it takes no --iterations or --timeout")
+		}
+		if reportInterval <= 0 {
+			return fmt.Errorf("--report-interval must be positive")
+		}
+		timeout = 0
+	}
+
+	// An explicit --iterations 0 leaves the timeout as the only budget
+	timeBudget := cmd.Flags().Changed("iterations") && iterations == 0
+	if timeBudget && timeout <= 0 {
+		return fmt.Errorf("--iterations 0 needs a positive --timeout")
+	}
+
+	// The artifacts directory takes the place of the output directory
+	if artifactsDir != "" {
+		outputDir = artifactsDir
+	}
+
+	tui.UseASCII(noEmoji || tui.NoColorRequested())
+	verbosity := tui.VerbosityNormal
+	switch {
+	case verbose:
+		verbosity = tui.VerbosityVerbose
+	case quiet:
+		verbosity = tui.VerbosityQuiet
+	}
+
+	// One UI per session; several sessions share a board with a row each
+	var board *tui.Board
+	for _, run := range runs {
+		run.outputDir = outputDir
+		run.reports = reportSpecs
+		run.timeout = timeout
+		run.timeBudget = timeBudget
+		run.pinned = pinned
+		if run.values != nil {
+			run.pinned = runner.MergeValues(run.values, pinned)
+		}
+		run.continuous = continuous
+		run.guided = guided
+		run.riskWeighted = riskWeighted
+		run.builtIns = builtIns
+		run.envtest = useEnvtest
+		run.upgrades = upgrades
+		run.baseline = accepted
+		switch {
+		case len(runs) == 1:
+			run.ui = newUI(cmd)
+		case logFormat == "json":
+			run.ui = tui.NewJSON(os.Stdout)
+		default:
+			if board == nil {
+				board = tui.NewBoard(!interactiveOutput(cmd))
+				board.SetVerbosity(verbosity)
+				board.SetLabel(label)
+			}
+			run.ui = board.Chart(run.name)
+		}
+		run.ui.SetVerbosity(verbosity)
+
+		// Keep each session's files apart when running several
+		if len(runs) > 1 {
+			run.outputDir = filepath.Join(outputDir, run.name)
+			run.reports = chartReports(reportSpecs, run.name)
+		}
+		if artifactsDir != "" {
+			run.reports = append(run.reports, artifactReports(run.outputDir)...)
+		}
+	}
+
+	// Interrupts end sessions gracefully so their reports and state are saved
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	registry := metrics.NewRegistry()
+	if metricsAddr != "" {
+		addr, err := registry.Start(ctx, metricsAddr)
+		if err != nil {
+			return infraError(fmt.Errorf("failed to start metrics server: %w", err))
+		}
+		runs[0].ui.LogDebug("Serving metrics on http://%s/metrics", addr)
+	}
+
+	if board != nil {
+		board.Start(len(runs))
+	}
+
+	var wg sync.WaitGroup
+	for _, run := range runs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			run.session, run.crashFound, run.err = run.fuzz(ctx, registry)
+			if run.err == nil || len(runs) == 1 {
+				return
+			}
+			if row, ok := run.ui.(*tui.BoardRow); ok {
+				row.Fail(run.err)
+			} else {
+				run.ui.LogError("%v", run.err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if board != nil {
+		board.Finish()
+	}
+
+	if sessionFindings != "" {
+		if err := recordFindings(cmd, sessionFindings, runs); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to record findings: %v\n", err)
+		}
+	}
+	return nil
+}
+
+// sessionsResult prints annotations for the finished runs and turns their
+// outcome into the command's result
+func sessionsResult(runs []*chartRun) error {
+	var (
+		crashFound bool
+		failed     []string
+		code       = ExitUsage
+	)
+	for _, run := range runs {
+		if annotate && run.session != nil {
+			if err := report.WriteAnnotations(os.Stdout, run.session, annotationDir(run.chartPath)); err != nil {
+				return infraError(fmt.Errorf("failed to write annotations: %w", err))
+			}
+		}
+		if run.err != nil {
+			failed = append(failed, run.name)
+			code = max(code, ExitCode(run.err))
+		}
+		crashFound = crashFound || run.newFindings()
+	}
+
+	if len(runs) == 1 && runs[0].err != nil {
+		return runs[0].err
+	}
+	if len(failed) > 0 {
+		// The most severe failure decides the exit code, even if other runs found crashes
+		err := fmt.Errorf("fuzzing failed for %d of %d runs: %s", len(failed), len(runs), strings.Join(failed, ", "))
+		return &exitError{code: code, err: err}
+	}
+
+	if crashFound {
+		return findingsError(fmt.Errorf("fuzzing found crashes"))
+	}
+
+	return nil
+}
+
+// chartRun is the fuzzing session for one chart
+type chartRun struct {
+	chartPath string
+	// name labels the session's progress row and output subdirectory
+	name string
+	// kubeVersion pins rendering to one Kubernetes version instead of the configured list
+	kubeVersion string
+	// profile selects a profile from .helmfuzz.yaml
+	profile string
+	// subchart fuzzes only this dependency's values, keeping the rest at their defaults
+	subchart  string
+	ui        tui.UI
+	outputDir string
+	reports   []report.Spec
+	timeout   time.Duration
+	// timeBudget runs until the timeout with no iteration target
+	timeBudget bool
+	// values are the session's own base values, such as a helmfile release's;
+	// like pinned values they override every input
+	values map[string]interface{}
+	// iterations replaces the configured budget, as a scenario's does;
+	// --iterations still takes precedence
+	iterations int
+	// invariants are checked against every successful render
+	invariants []config.Invariant
+	// releaseName and namespace render inputs as a particular release
+	releaseName string
+	namespace   string
+	// includeCRDs renders the chart's crds/ directory along with its templates
+	includeCRDs bool
+	// postRenderer rewrites each render before the oracles see it
+	postRenderer postrender.PostRenderer
+	// pinned values from --values and --set override every input
+	pinned map[string]interface{}
+	// continuous runs until interrupted with an evolving corpus, resuming any saved state
+	continuous bool
+	// guided evolves inputs toward template branches no earlier input reached
+	guided bool
+	// riskWeighted varies the values paths feeding risky template constructs more often
+	riskWeighted bool
+	// builtIns varies the release, chart and Kubernetes version each input renders with
+	builtIns bool
+	// envtest validates renders against a local API server
+	envtest bool
+	// upgrades checks renders as upgrades from the chart's defaults on the API server
+	upgrades bool
+	// baseline accepts known findings, which then do not fail the session
+	baseline *baseline.Baseline
+
+	session    *report.Session
+	crashFound bool
+	err        error
+}
+
+// newFindings reports whether the session found crashes its baseline does not
+// accept, leaving out flaky ones
+func (run *chartRun) newFindings() bool {
+	if run.baseline == nil || run.session == nil {
+		return run.crashFound
+	}
+	known := 0
+	for _, f := range run.session.Findings {
+		if f.Flaky {
+			continue
+		}
+		if !run.baseline.Contains(f.Reason) {
+			return true
+		}
+		known++
+	}
+	if known > 0 {
+		fmt.Fprintf(os.Stderr, "%s: all %d finding(s) are in the baseline\n", run.name, known)
+	}
+	return false
+}
+
+// fuzz runs the session, returning the recorded session once fuzzing has started
+func (run *chartRun) fuzz(parent context.Context, registry *metrics.Registry) (*report.Session, bool, error) {
+	chartPath, ui, outputDir, timeout := run.chartPath, run.ui, run.outputDir, run.timeout
+
 	// Load configuration
-	cfg, err := config.LoadConfig(chartPath)
+	cfg, err := loadConfig(chartPath)
 	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
+		return nil, false, fmt.Errorf("failed to load config: %w", err)
+	}
+	if run.profile != "" {
+		if cfg, err = cfg.WithProfile(run.profile); err != nil {
+			return nil, false, err
+		}
+	}
+	if run.kubeVersion != "" {
+		cfg.KubeVersions = []string{run.kubeVersion}
+	}
+	if throttle != "" {
+		if err := cfg.SetThrottle(throttle); err != nil {
+			return nil, false, err
+		}
+	}
+	if onCrash != "" {
+		cfg.SetOnCrash(onCrash)
+	}
+	if seed != 0 {
+		cfg.Seed = seed
+	}
+	if len(cfg.Webhooks) > 0 {
+		if err := runner.NeedsNetwork("notifying webhooks"); err != nil {
+			return nil, false, fmt.Errorf("%w; remove the config's webhooks", err)
+		}
+	}
+	if cfg.Tracing != nil {
+		if err := runner.NeedsNetwork("exporting spans"); err != nil {
+			return nil, false, fmt.Errorf("%w; remove the config's tracing", err)
+		}
 	}
 
-	// Override iterations if specified
+	if run.iterations > 0 {
+		cfg.Iterations = run.iterations
+	}
+	// Override iterations if specified; zero means no iteration target
 	if iterations > 0 {
 		cfg.Iterations = iterations
 	}
+	if run.timeBudget {
+		cfg.Iterations = 0
+	}
+	if run.continuous {
+		cfg.Iterations = 0
+	}
+	// A saved or evolving corpus needs a home; the output directory keeps it with the findings
+	if (run.continuous || saveCorpus) && cfg.CorpusDir == "" {
+		if cfg.CorpusDir, err = filepath.Abs(filepath.Join(outputDir, "corpus")); err != nil {
+			return nil, false, fmt.Errorf("failed to resolve corpus directory: %w", err)
+		}
+	}
 
-	// Initialize TUI
-	ui := tui.New(ciMode)
 	chartName := filepath.Base(chartPath)
-	ui.Start(chartName, cfg.Iterations)
 
-	// Initialize schema engine
-	schemaEngine := schema.NewEngine(cfg)
+	// Continue a saved session where it stopped
+	statePath := filepath.Join(outputDir, report.StateFileName)
+	var resumed *report.State
+	if resume || run.continuous {
+		st, err := report.LoadState(statePath)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+		case err != nil:
+			return nil, false, infraError(err)
+		case st.Chart != chartName:
+			return nil, false, fmt.Errorf("session state in %s is for chart %q, not %q", outputDir, st.Chart, chartName)
+		case cfg.Seed != 0 && st.Seed != cfg.Seed:
+			return nil, false, fmt.Errorf("session state in %s is for seed %d, not %d", outputDir, st.Seed, cfg.Seed)
+		default:
+			resumed = st
+		}
+	}
+	first, maxIterations := 0, cfg.Iterations
+	if resumed != nil {
+		// The timeout and iteration target cover the whole session, not each
+		// run, and its inputs are drawn under the seed it started with
+		first, cfg.Seed = resumed.NextIteration, resumed.Seed
+		if run.timeout > 0 {
+			if timeout -= resumed.Elapsed; timeout <= 0 {
+				return nil, false, fmt.Errorf("saved session already used its %s timeout", run.timeout)
+			}
+		}
+		if cfg.Iterations > 0 {
+			if maxIterations -= first; maxIterations <= 0 {
+				return nil, false, fmt.Errorf("saved session already completed its %d iterations", cfg.Iterations)
+			}
+		}
+	}
+
+	if cfg.Seed == 0 && resumed == nil {
+		// Without a seed of its own each new session draws fresh inputs, so
+		// runs sharing a corpus compound rather than repeat each other; the
+		// state and report.json record it
+		if cfg.Seed, err = sessionSeed(); err != nil {
+			return nil, false, infraError(err)
+		}
+	}
+
+	// Artifacts of earlier sessions are bounded before this one adds its own
+	run.applyRetention(ui, cfg.Retention)
 
-	ui.LogDebug("Detecting schema...")
-	sch, err := schemaEngine.DetectSchema(chartPath)
+	// Record every event in the output directory for post-hoc debugging
+	sessionLog, err := tui.CreateSessionLog(outputDir, chartName)
 	if err != nil {
-		return fmt.Errorf("failed to detect schema: %w", err)
+		ui.LogWarning("Session log disabled: %v", err)
+	} else {
+		defer sessionLog.Close()
+		ui = tui.Multi(ui, sessionLog)
 	}
-	ui.LogDebug("Schema detected: %s", sch.Type)
 
-	// Initialize oracle and minimizer with deduplication
-	oracle := runner.NewOracleWithConfig(cfg.IgnoreErrors, cfg.UninterestingPatterns)
-	minimizer := runner.NewMinimizer(outputDir)
-	deduplicator := runner.NewDeduplicator()
+	// Library packages log through the UI so verbosity and the session log apply
+	logger := slog.New(tui.NewLogHandler(ui))
 
-	// Initialize generator
-	gen := generator.New(sch, cfg.MaxDepth)
+	// Spans of the session are exported until its reports are written
+	var tracer *tracing.Tracer
+	if tr := cfg.Tracing; tr != nil {
+		tracer = tracing.New(tracing.Options{Endpoint: tr.Endpoint, Headers: tr.Headers, ServiceName: tr.ServiceName, Logger: logger})
+		defer tracer.Close()
+		ui.LogDebug("Exporting spans to %s, one iteration in %d", tr.Endpoint, tr.IterationSample)
+	}
+	trace := tracer.Start("fuzz session", tracing.String("chart", chartName), tracing.Int("workers", cfg.Workers), tracing.Int("firstIteration", first))
+	defer trace.End()
 
-	// Run fuzzing with timeout
-	timeoutChan := time.After(timeout)
-	crashFound := false
+	ui.Start(chartName, maxIterations, timeout)
+	recorder := report.NewRecorder(chartName, cfg.Iterations)
+	if sessionLog != nil {
+		ui.LogDebug("Writing session log to %s", sessionLog.Path())
+	}
+	if resumed != nil {
+		recorder.Restore(resumed)
+		ui.LogDebug("Resuming session at iteration %d with %d unique crash(es) so far", first+1, len(resumed.Findings))
+	}
 
-	ui.LogDebug("Starting fuzzing loop...")
+	// Metrics, webhooks, the onCrash command and the recorder follow the
+	// session through its hooks
+	sessionMetrics := registry.Chart(run.name, cfg.Workers)
+	notifyOpts := notify.Options{Webhooks: cfg.Webhooks, Logger: logger}
+	if len(cfg.Webhooks) > 0 {
+		ui.LogDebug("Notifying %d webhook(s) of new crashes", len(cfg.Webhooks))
+	}
+	if cfg.OnCrash != nil {
+		notifyOpts.Command, notifyOpts.Timeout = cfg.ResolveCrashCommand(chartPath), cfg.OnCrash.Timeout
+		ui.LogDebug("Running %s for new crashes", strings.Join(cfg.OnCrash.Command, " "))
+	}
+	notifier := notify.NewWithOptions(notifyOpts)
 
-	// Run fuzzing iterations
-	for i := 0; i < cfg.Iterations; i++ {
-		// Check timeout
-		select {
-		case <-timeoutChan:
-			ui.LogDebug("Timeout reached")
-			goto finish
-		default:
+	var (
+		mu        sync.Mutex
+		completed int
+		// next is the lowest iteration not yet completed
+		next = first
+		seen []string
+	)
+	if resumed != nil {
+		for _, f := range resumed.Findings {
+			seen = append(seen, f.Reason)
 		}
+	}
 
-		// Rotate through Kubernetes versions to test multiple versions
-		kubeVersion := cfg.KubeVersions[i%len(cfg.KubeVersions)]
+	var session *fuzz.Session
+	session, err = fuzz.NewWithOptions(chartPath, fuzz.Options{
+		Config:           cfg,
+		OutputDir:        outputDir,
+		Timeout:          timeout,
+		FirstIteration:   first,
+		Seen:             seen,
+		Values:           run.pinned,
+		ReleaseName:      run.releaseName,
+		Namespace:        run.namespace,
+		Subchart:         run.subchart,
+		Invariants:       run.invariants,
+		IncludeCRDs:      run.includeCRDs,
+		PostRenderer:     run.postRenderer,
+		FailFast:         failFast,
+		Evolve:           run.continuous,
+		Guided:           run.guided,
+		RiskWeighted:     run.riskWeighted,
+		BuiltIns:         run.builtIns,
+		Envtest:          run.envtest,
+		Upgrades:         run.upgrades,
+		Culprits:         culprits,
+		RaceRenders:      raceRenders,
+		TagCombinations:  tagCombos,
+		SaveCorpus:       saveCorpus,
+		DependencyUpdate: dependencyUpdate,
+		Trace:            trace,
+		Logger:           logger,
+		Hooks: fuzz.Hooks{
+			Wait:        ui.Wait,
+			StartRender: sessionMetrics.StartRender,
+			Iteration: func(it fuzz.Iteration) {
+				sessionMetrics.RecordIteration(it.Category)
+				mu.Lock()
+				defer mu.Unlock()
+				completed++
+				next = it.Next
+				ui.Update(completed, it.Crashed)
+				recorder.RecordIteration(it.Crashed)
+			},
+			Crash: func(f fuzz.Finding) {
+				ui.ReportCrash(tui.Crash{
+					Iteration: f.Iteration,
+					Reason:    f.Reason,
+					ReproFile: f.ReproFile,
+					Overrides: crashOverrides(session.Defaults(), f),
+					Flaky:     f.Flaky,
+					Culprits:  f.Culprits,
+				})
+				sessionMetrics.RecordUniqueCrash(f.Category)
+				mu.Lock()
+				recorder.RecordFinding(f)
+				mu.Unlock()
+				notifier.Notify(notify.Finding{
+					Chart:       chartName,
+					ID:          f.ID,
+					Iteration:   f.Iteration,
+					Fingerprint: f.Fingerprint,
+					Category:    f.Category,
+					Reason:      f.Reason,
+					ReproFile:   f.ReproFile,
+					Flaky:       f.Flaky,
+				})
+			},
+		},
+	})
+	if err != nil {
+		var configErr *fuzz.ConfigError
+		if errors.As(err, &configErr) {
+			return nil, false, err
+		}
+		return nil, false, infraError(err)
+	}
+	defer session.Close()
+	ui.LogDebug("Deriving inputs from seed %d", cfg.Seed)
+	if len(run.pinned) > 0 {
+		ui.LogDebug("Pinning %d top-level value(s) from --values/--set", len(run.pinned))
+	}
 
-		// Initialize runner with the current Kubernetes version
-		testRunner, err := runner.NewWithKubeVersion(chartPath, kubeVersion)
-		if err != nil {
-			return fmt.Errorf("failed to create runner: %w", err)
+	// Save progress periodically so a killed session can be resumed
+	saveState := func() {
+		mu.Lock()
+		st := recorder.State(next)
+		mu.Unlock()
+		st.Seed = cfg.Seed
+		if err := report.SaveState(statePath, st); err != nil {
+			ui.LogWarning("%v", err)
 		}
+	}
+	if run.continuous {
+		ui.LogDebug("Evolving the corpus in %s", cfg.ResolveCorpusDir(chartPath))
+	}
 
-		// Validate chart on first iteration
-		if i == 0 {
-			ui.LogDebug("Validating chart...")
-			if err := testRunner.Validate(); err != nil {
-				return fmt.Errorf("chart validation failed: %w", err)
+	// A continuous session never ends, so its reports are refreshed as it runs
+	var reportTick <-chan time.Time
+	if run.continuous {
+		ticker := time.NewTicker(reportInterval)
+		defer ticker.Stop()
+		reportTick = ticker.C
+	}
+	stateDone := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(stateSaveInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				saveState()
+			case <-reportTick:
+				mu.Lock()
+				recorded := recorder.Session()
+				mu.Unlock()
+				run.writeReports(ui, recorded, cfg, sessionLog, trace)
+			case <-stateDone:
+				return
 			}
 		}
+	}()
+
+	result, runErr := session.Run(parent)
+	close(stateDone)
+	trace.SetAttributes(tracing.Int("iterations", result.Iterations), tracing.Int("findings", len(result.Findings)))
+	trace.SetError(runErr)
+	if runErr != nil {
+		runErr = infraError(runErr)
+	}
+	failing := 0
+	for _, f := range result.Findings {
+		if !f.Flaky {
+			failing++
+		}
+	}
+	if failFast && failing > 0 {
+		ui.LogDebug("Stopped at the first crash (--fail-fast)")
+	}
+	if flaky := len(result.Findings) - failing; flaky > 0 {
+		ui.LogWarning("%d flaky crash(es) did not reproduce on every replay and do not fail the session", flaky)
+	}
 
-		// Generate values using rapid's generator
-		// Use different seeds for each iteration to get variety
-		values := gen.Generate().Example(i)
+	// Keep the state of an interrupted session for --resume; a session that
+	// used up its budget or stopped at a crash leaves nothing to resume
+	if result.Interrupted || runErr != nil {
+		saveState()
+		ui.LogDebug("Saved session state to %s; continue with --resume", statePath)
+	} else if err := os.Remove(statePath); err != nil && !os.IsNotExist(err) {
+		ui.LogWarning("Failed to remove session state: %v", err)
+	}
 
-		// Run test
-		result := testRunner.Run(values)
+	// Webhook deliveries and crash commands run in the background; let them
+	// finish before exiting
+	notifier.Close()
 
-		// Update UI
-		isCrash := oracle.IsCrash(result)
-		ui.Update(i+1, isCrash)
+	ui.ReportCoverage(result.Coverage)
 
-		// Check for crash
-		if isCrash && oracle.IsInteresting(result) {
-			reason := oracle.GetCrashReason(result)
+	recorded := recorder.Session()
+	recorded.Coverage = &result.Coverage
+	run.writeReports(ui, recorded, cfg, sessionLog, trace)
+	if result.Evolved > 0 {
+		ui.LogDebug("Added %d input(s) to the corpus in %s", result.Evolved, cfg.ResolveCorpusDir(chartPath))
+	}
 
-			// Check if this is a duplicate crash
-			if deduplicator.IsDuplicate(reason) {
-				// Skip saving duplicate crashes
-				continue
-			}
+	// The files the reports point to are kept whatever the retention
+	keep := []string{recorded.Files.SessionLog}
+	for _, f := range recorded.Findings {
+		keep = append(keep, f.ReproFile)
+	}
+	run.applyRetention(ui, cfg.Retention, keep...)
 
-			crashFound = true
+	ui.Finish()
 
-			// Mark as seen and save reproduction file
-			deduplicator.MarkSeen(reason)
-			reproFile, err := minimizer.SaveReproduction(result, reason)
-			if err != nil {
-				ui.LogWarning("Failed to save reproduction file: %v", err)
-			}
+	return recorded, failing > 0, runErr
+}
 
-			ui.ReportCrash(i+1, reason, reproFile)
+// sessionSeed returns a random nonzero session seed
+func sessionSeed() (uint64, error) {
+	b := make([]byte, 8)
+	for {
+		if _, err := rand.Read(b); err != nil {
+			return 0, fmt.Errorf("failed to generate session seed: %w", err)
+		}
+		if seed := binary.BigEndian.Uint64(b); seed != 0 {
+			return seed, nil
+		}
+	}
+}
 
-			// Continue fuzzing to find more crashes
+// crashOverrides lists how a finding's values differ from the chart
+// defaults, using the minimized values when ablation found them so only the
+// culprit paths are shown
+func crashOverrides(defaults map[string]interface{}, f fuzz.Finding) []runner.ValueChange {
+	values := f.MinimalValues
+	if values == nil {
+		values = f.Values
+	}
+	return runner.DiffValues(defaults, values)
+}
+
+// applyRetention removes the artifacts of the output directory that the
+// config's retention does not keep, except the files in keep
+func (run *chartRun) applyRetention(ui tui.UI, r *config.Retention, keep ...string) {
+	if r == nil {
+		return
+	}
+	policy := retention.Policy{MaxReproFiles: r.MaxReproFiles, MaxBytes: r.MaxBytes(), MaxAge: r.MaxAge}
+	result, err := retention.Apply(run.outputDir, policy, time.Now(), keep...)
+	if err != nil {
+		ui.LogWarning("Retention: %v", err)
+	}
+	if len(result.Removed) > 0 {
+		ui.LogDebug("Removed %d artifact file(s) of earlier sessions from %s, freeing %d bytes", len(result.Removed), run.outputDir, result.Freed)
+	}
+	if err == nil && policy.MaxBytes > 0 && result.Size > policy.MaxBytes {
+		ui.LogWarning("%s holds %d bytes of artifacts, over the retention maxSize of %s, in files of this session, which retention keeps", run.outputDir, result.Size, r.MaxSize)
+	}
+}
+
+// writeReports fills in the session's context and writes the requested
+// reports and report.json, traced under the session's span
+func (run *chartRun) writeReports(ui tui.UI, recorded *report.Session, cfg *config.Config, sessionLog *tui.SessionLog, trace *tracing.Span) {
+	span := trace.Child("write reports", tracing.Int("reports", len(run.reports)+1))
+	defer span.End()
+
+	recorded.ChartPath = run.chartPath
+	recorded.ToolVersion = version
+	recorded.Config = cfg
+	if sessionLog != nil {
+		recorded.Files.SessionLog = sessionLog.Path()
+	}
+	for _, spec := range run.reports {
+		if err := report.WriteFile(spec, recorded); err != nil {
+			ui.LogError("%v", err)
+			continue
 		}
+		recorded.Files.Reports = append(recorded.Files.Reports, spec.Path)
+		ui.LogDebug("Wrote %s report to %s", spec.Format, spec.Path)
 	}
 
-finish:
+	// report.json is always written for tooling that consumes results
+	jsonReport := report.Spec{Format: "json", Path: filepath.Join(run.outputDir, report.JSONFileName)}
+	if err := report.WriteFile(jsonReport, recorded); err != nil {
+		ui.LogError("%v", err)
+	} else {
+		ui.LogDebug("Wrote report to %s", jsonReport.Path)
+	}
+}
 
-	ui.Finish()
+// annotationDir returns the chart directory relative to the repository root,
+// which is $GITHUB_WORKSPACE under GitHub Actions and the working directory otherwise
+func annotationDir(chartPath string) string {
+	root := os.Getenv("GITHUB_WORKSPACE")
+	if root == "" {
+		var err error
+		if root, err = os.Getwd(); err != nil {
+			return filepath.ToSlash(chartPath)
+		}
+	}
 
-	// Determine exit code
-	if crashFound {
-		if ciMode {
-			return fmt.Errorf("fuzzing found crashes")
+	rel, err := filepath.Rel(root, chartPath)
+	if err != nil {
+		return filepath.ToSlash(chartPath)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// newUI selects the output mode. Without an explicit --ci, non-interactive
+// output is used whenever stdout is not a terminal (pipes, CI runners).
+func newUI(cmd *cobra.Command) tui.UI {
+	if logFormat == "json" {
+		return tui.NewJSON(os.Stdout)
+	}
+
+	if !interactiveOutput(cmd) {
+		return tui.New(true)
+	}
+
+	// The dashboard also needs a terminal on stdin to read key presses
+	if plainMode || !term.IsTerminal(int(os.Stdin.Fd())) {
+		return tui.New(false)
+	}
+	return tui.NewDashboard()
+}
+
+// interactiveOutput reports whether progress can be redrawn in place: never
+// with --artifacts-dir, then an explicit --ci wins, otherwise stdout must be a terminal
+func interactiveOutput(cmd *cobra.Command) bool {
+	if artifactsDir != "" {
+		return false
+	}
+	if cmd.Flags().Changed("ci") {
+		return !ciMode
+	}
+	return term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// chartReports gives each chart its own report files by adding the chart name,
+// its slashes replaced by dashes, before the extension. The GitHub step summary
+// is shared since it is appended to.
+func chartReports(specs []report.Spec, chartName string) []report.Spec {
+	result := make([]report.Spec, len(specs))
+	for i, spec := range specs {
+		if summary := os.Getenv("GITHUB_STEP_SUMMARY"); summary == "" || spec.Path != summary {
+			ext := filepath.Ext(spec.Path)
+			spec.Path = strings.TrimSuffix(spec.Path, ext) + "-" + strings.ReplaceAll(chartName, "/", "-") + ext
 		}
-		os.Exit(1)
+		result[i] = spec
 	}
+	return result
+}
 
-	return nil
+// artifactReportNames are the files --artifacts-dir writes each report format
+// to; report.json is written to every output directory regardless
+var artifactReportNames = map[string]string{
+	"html":     "report.html",
+	"junit":    "junit.xml",
+	"markdown": "report.md",
+}
+
+// artifactReports returns a report for every format other than json in dir
+func artifactReports(dir string) []report.Spec {
+	var specs []report.Spec
+	for _, format := range report.Formats {
+		if name, ok := artifactReportNames[format]; ok {
+			specs = append(specs, report.Spec{Format: format, Path: filepath.Join(dir, name)})
+		}
+	}
+	return specs
 }
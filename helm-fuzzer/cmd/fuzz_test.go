@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/fuzz"
+	"github.com/kasuboski/helm-fuzzer/pkg/report"
+	"github.com/kasuboski/helm-fuzzer/pkg/tui"
+)
+
+func TestCrashOverrides(t *testing.T) {
+	defaults := map[string]interface{}{
+		"replicas": 1,
+		"image":    map[string]interface{}{"tag": "v1"},
+	}
+	f := fuzz.Finding{
+		Iteration: 3,
+		Reason:    "Error: boom",
+		Values: map[string]interface{}{
+			"replicas":       "x",
+			"image":          map[string]interface{}{"tag": "v2"},
+			"podAnnotations": map[string]interface{}{"a": ""},
+		},
+		MinimalValues: map[string]interface{}{"replicas": "x"},
+	}
+
+	var buf bytes.Buffer
+	ui := tui.New(true)
+	ui.SetWriter(&buf)
+	ui.Start("my-chart", 10, time.Minute)
+	ui.ReportCrash(tui.Crash{Iteration: f.Iteration, Reason: f.Reason, Overrides: crashOverrides(defaults, f)})
+
+	out := buf.String()
+	if !strings.Contains(out, `~ replicas: "x" (default: 1)`) {
+		t.Errorf("expected the culprit override in the crash detail:\n%s", out)
+	}
+	for _, path := range []string{"image.tag", "podAnnotations"} {
+		if strings.Contains(out, path) {
+			t.Errorf("expected %s, not a culprit, left out of the crash detail:\n%s", path, out)
+		}
+	}
+
+	// Without ablation every override is shown
+	f.MinimalValues = nil
+	if changes := crashOverrides(defaults, f); len(changes) != 3 {
+		t.Errorf("expected all 3 overrides of the values, got %+v", changes)
+	}
+}
+
+func TestFindCharts(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{
+		"team-a/app",
+		"team-b/app",
+		// A subchart is fuzzed through its parent
+		"team-a/app/charts/redis",
+		// Hidden directories are not searched
+		".git/app",
+		"team-b/.cache/app",
+	} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(root, dir, "Chart.yaml"), []byte("name: app\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(root, "docs"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	charts, err := findCharts(root)
+	if err != nil {
+		t.Fatalf("findCharts failed: %v", err)
+	}
+	want := []string{filepath.Join(root, "team-a/app"), filepath.Join(root, "team-b/app")}
+	if !reflect.DeepEqual(charts, want) {
+		t.Fatalf("expected %v, got %v", want, charts)
+	}
+
+	// Charts of the same directory name are named apart by their path
+	names := make(map[string]bool)
+	for _, chart := range charts {
+		names[foundChartName(root, chart)] = true
+	}
+	if !names["team-a/app"] || !names["team-b/app"] {
+		t.Errorf("expected the charts named team-a/app and team-b/app, got %v", names)
+	}
+	// A chart at the root searched keeps its directory name
+	if name := foundChartName(filepath.Join(root, "team-a/app"), filepath.Join(root, "team-a/app")); name != "app" {
+		t.Errorf("expected the root chart named app, got %q", name)
+	}
+}
+
+func TestChartReports(t *testing.T) {
+	t.Setenv("GITHUB_STEP_SUMMARY", "summary.md")
+	specs := chartReports([]report.Spec{
+		{Format: "html", Path: "out/report.html"},
+		{Format: "markdown", Path: "summary.md"},
+	}, "team-a/app")
+	if specs[0].Path != "out/report-team-a-app.html" {
+		t.Errorf("expected the chart path flattened into the file name, got %s", specs[0].Path)
+	}
+	if specs[1].Path != "summary.md" {
+		t.Errorf("expected the step summary to be shared, got %s", specs[1].Path)
+	}
+}
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/helmfile"
+)
+
+var helmfileReleases []string
+
+// helmfileCmd represents the helmfile command
+var helmfileCmd = &cobra.Command{
+	Use:   "helmfile <helmfile.yaml>",
+	Short: "Fuzz the releases of a helmfile",
+	Long: `Run one fuzzing session per release of a helmfile. Each release's chart is
+resolved the way helmfile would: local paths relative to the helmfile,
+repository/chart through the helmfile's repositories, or oci:// references.
+Remote charts are downloaded to a temporary directory for the session.
+
+The release's values files, inline values and set entries are merged and
+override every input, so only the values the release leaves open are fuzzed,
+and inputs render as the release in its namespace. --values and --set override
+the release values in turn. Releases with installed: false are skipped.
+
+Templated helmfiles are not rendered; run "helmfile build --embed-values" and
+fuzz its output instead. Each release writes its files to its own subdirectory
+of --output when there are several.`,
+	Args: cobra.ExactArgs(1),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return []string{"yaml", "yml"}, cobra.ShellCompDirectiveFilterFileExt
+	},
+	RunE: runHelmfile,
+}
+
+func init() {
+	rootCmd.AddCommand(helmfileCmd)
+	addSessionFlags(helmfileCmd)
+
+	helmfileCmd.Flags().StringSliceVar(&helmfileReleases, "release", nil, "Only fuzz these releases (default: every installed release)")
+}
+
+func runHelmfile(cmd *cobra.Command, args []string) error {
+	f, err := helmfile.Load(args[0])
+	if err != nil {
+		return err
+	}
+
+	var releases []helmfile.Release
+	for _, r := range f.Releases {
+		if r.IsInstalled() && (len(helmfileReleases) == 0 || slices.Contains(helmfileReleases, r.Name)) {
+			releases = append(releases, r)
+		}
+	}
+	for _, name := range helmfileReleases {
+		if !slices.ContainsFunc(f.Releases, func(r helmfile.Release) bool { return r.Name == name }) {
+			return fmt.Errorf("release %s is not in %s", name, args[0])
+		}
+	}
+	if len(releases) == 0 {
+		return fmt.Errorf("no installed releases to fuzz in %s", args[0])
+	}
+
+	dir, err := os.MkdirTemp("", "helm-fuzz-helmfile-")
+	if err != nil {
+		return infraError(fmt.Errorf("failed to create chart directory: %w", err))
+	}
+	defer os.RemoveAll(dir)
+
+	var runs []*chartRun
+	for i, r := range releases {
+		values, err := f.Values(r)
+		if err != nil {
+			return err
+		}
+		// Each remote chart gets its own directory, as several may share a name
+		chartDir := filepath.Join(dir, strconv.Itoa(i))
+		if err := os.Mkdir(chartDir, 0755); err != nil {
+			return infraError(fmt.Errorf("failed to create chart directory: %w", err))
+		}
+		chartPath, err := f.Chart(chartDir, r)
+		if err != nil {
+			return infraError(err)
+		}
+		runs = append(runs, &chartRun{
+			chartPath:   chartPath,
+			name:        helmfileRunName(releases, r),
+			values:      values,
+			releaseName: r.Name,
+			namespace:   r.Namespace,
+		})
+	}
+
+	if err := runSessions(cmd, runs, "release"); err != nil {
+		return err
+	}
+	return sessionsResult(runs)
+}
+
+// helmfileRunName names a release's progress row and output subdirectory,
+// adding the namespace when another release shares its name
+func helmfileRunName(releases []helmfile.Release, r helmfile.Release) string {
+	for _, other := range releases {
+		if other.Name == r.Name && other.Namespace != r.Namespace && r.Namespace != "" {
+			return r.Namespace + "-" + r.Name
+		}
+	}
+	return r.Name
+}
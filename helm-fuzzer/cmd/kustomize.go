@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/kustomize"
+	"github.com/kasuboski/helm-fuzzer/pkg/runner"
+)
+
+var (
+	kustomizeBinary string
+	kustomizeCharts []string
+)
+
+// kustomizeCmd represents the kustomize command
+var kustomizeCmd = &cobra.Command{
+	Use:   "kustomize <kustomization-dir>",
+	Short: "Fuzz the helmCharts of a kustomization through kustomize build",
+	Long: `Run one fuzzing session per helmCharts entry of a kustomization, checking the
+output of kustomize build rather than the chart's own render. Each input is
+rendered with the embedded Helm SDK, then the kustomization is built with that
+render in place of the entry, so patches, transformers and generators apply to
+it as they do when the chart ships. A build failure, such as a patch whose
+target the input disabled, is a finding.
+
+Charts are read from the chart home (helmGlobals.chartHome, default charts/) as
+kustomize keeps them, or pulled from the entry's repo. The entry's valuesFile,
+valuesInline (combined as valuesMerge says) and additionalValuesFiles override
+every input, and inputs render as its releaseName in its namespace. Other
+entries are rendered once with their own values.
+
+Each build copies the kustomization directory next to the original, so its
+parent must be writable. Each entry writes its files to its own subdirectory
+of --output when there are several.`,
+	Args: cobra.ExactArgs(1),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return nil, cobra.ShellCompDirectiveFilterDirs
+	},
+	RunE: runKustomize,
+}
+
+func init() {
+	rootCmd.AddCommand(kustomizeCmd)
+	addSessionFlags(kustomizeCmd)
+
+	kustomizeCmd.Flags().StringVar(&kustomizeBinary, "kustomize-binary", "kustomize", "kustomize binary that builds the kustomization")
+	kustomizeCmd.Flags().StringSliceVar(&kustomizeCharts, "chart", nil, "Only fuzz these helmCharts entries (default: every entry)")
+}
+
+func runKustomize(cmd *cobra.Command, args []string) error {
+	k, err := kustomize.Load(args[0])
+	if err != nil {
+		return err
+	}
+	for _, name := range kustomizeCharts {
+		if !slices.ContainsFunc(k.HelmCharts, func(c kustomize.HelmChart) bool { return c.Name == name }) {
+			return fmt.Errorf("helm chart %s is not in %s", name, args[0])
+		}
+	}
+
+	dir, err := os.MkdirTemp("", "helm-fuzz-kustomize-")
+	if err != nil {
+		return infraError(fmt.Errorf("failed to create chart directory: %w", err))
+	}
+	defer os.RemoveAll(dir)
+
+	// Every entry is rendered once with its own values; the fuzzed entry's
+	// render takes its place in each build
+	chartPaths := make([]string, len(k.HelmCharts))
+	values := make([]map[string]interface{}, len(k.HelmCharts))
+	static := make(map[int]string, len(k.HelmCharts))
+	for i, c := range k.HelmCharts {
+		// Each remote chart gets its own directory, as several may share a name
+		chartDir := filepath.Join(dir, strconv.Itoa(i))
+		if err := os.Mkdir(chartDir, 0755); err != nil {
+			return infraError(fmt.Errorf("failed to create chart directory: %w", err))
+		}
+		if chartPaths[i], err = k.Chart(chartDir, c); err != nil {
+			return infraError(err)
+		}
+		if values[i], err = k.Values(chartPaths[i], c); err != nil {
+			return err
+		}
+		r, err := runner.NewWithOptions(chartPaths[i], runner.Options{
+			ReleaseName: c.Release(),
+			Namespace:   c.Namespace,
+			IncludeCRDs: c.IncludeCRDs,
+		})
+		if err != nil {
+			return infraError(err)
+		}
+		res := r.Run(values[i])
+		if !res.Success {
+			return fmt.Errorf("helm chart %s does not render with its own values: %w", c.Name, res.Error)
+		}
+		static[i] = res.Manifest
+	}
+
+	var runs []*chartRun
+	for i, c := range k.HelmCharts {
+		if len(kustomizeCharts) > 0 && !slices.Contains(kustomizeCharts, c.Name) {
+			continue
+		}
+		others := make(map[int]string, len(static)-1)
+		for j, manifest := range static {
+			if j != i {
+				others[j] = manifest
+			}
+		}
+		renderer, err := k.Renderer(kustomizeBinary, i, others)
+		if err != nil {
+			return err
+		}
+		runs = append(runs, &chartRun{
+			chartPath:    chartPaths[i],
+			name:         kustomizeRunName(k.HelmCharts, i),
+			values:       values[i],
+			releaseName:  c.Release(),
+			namespace:    c.Namespace,
+			includeCRDs:  c.IncludeCRDs,
+			postRenderer: renderer,
+		})
+	}
+
+	if err := runSessions(cmd, runs, "chart"); err != nil {
+		return err
+	}
+	return sessionsResult(runs)
+}
+
+// kustomizeRunName names an entry's progress row and output subdirectory,
+// adding its release name when another entry uses the same chart
+func kustomizeRunName(charts []kustomize.HelmChart, index int) string {
+	c := charts[index]
+	for i, other := range charts {
+		if i != index && other.Name == c.Name {
+			return c.Name + "-" + c.Release()
+		}
+	}
+	return c.Name
+}
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	matrixKubeVersions []string
+	matrixProfiles     []string
+)
+
+// matrixCmd represents the matrix command
+var matrixCmd = &cobra.Command{
+	Use:   "matrix <chart-path>",
+	Short: "Fuzz a chart across Kubernetes versions and config profiles",
+	Long: `Run one fuzzing session per combination of Kubernetes version and .helmfuzz.yaml
+profile, each with the same iteration budget and timeout, and print a result
+table with one cell per combination. Each cell writes its files to its own
+subdirectory of --output.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeCharts(1, cobra.ShellCompDirectiveNoFileComp),
+	RunE:              runMatrix,
+}
+
+func init() {
+	rootCmd.AddCommand(matrixCmd)
+	addSessionFlags(matrixCmd)
+
+	matrixCmd.Flags().StringSliceVar(&matrixKubeVersions, "kube-versions", nil, "Kubernetes versions to render against (default: kubeVersions from .helmfuzz.yaml)")
+	matrixCmd.Flags().StringSliceVar(&matrixProfiles, "profiles", nil, "Profiles from .helmfuzz.yaml to run, in addition to none (default: no profiles)")
+	matrixCmd.RegisterFlagCompletionFunc("profiles", completeProfiles)
+}
+
+func runMatrix(cmd *cobra.Command, args []string) error {
+	chartPath, err := filepath.Abs(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to resolve chart path: %w", err)
+	}
+	if _, err := os.Stat(chartPath); os.IsNotExist(err) {
+		return fmt.Errorf("chart path does not exist: %s", chartPath)
+	}
+
+	cfg, err := loadConfig(chartPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	// Check profiles up front rather than failing every cell
+	for _, profile := range matrixProfiles {
+		if _, err := cfg.WithProfile(profile); err != nil {
+			return err
+		}
+	}
+
+	kubeVersions := matrixKubeVersions
+	if len(kubeVersions) == 0 {
+		kubeVersions = cfg.KubeVersions
+	}
+	// The empty profile is the config without any profile applied
+	profiles := append([]string{""}, matrixProfiles...)
+
+	var runs []*chartRun
+	for _, kubeVersion := range kubeVersions {
+		for _, profile := range profiles {
+			runs = append(runs, &chartRun{
+				chartPath:   chartPath,
+				name:        matrixCellName(kubeVersion, profile),
+				kubeVersion: kubeVersion,
+				profile:     profile,
+			})
+		}
+	}
+
+	if err := runSessions(cmd, runs, "cell"); err != nil {
+		return err
+	}
+	if logFormat == "text" {
+		if err := writeMatrix(cmd, kubeVersions, profiles, runs); err != nil {
+			return err
+		}
+	}
+	return sessionsResult(runs)
+}
+
+// matrixCellName names a cell's progress row and output subdirectory
+func matrixCellName(kubeVersion, profile string) string {
+	if profile == "" {
+		return "k8s-" + kubeVersion
+	}
+	return "k8s-" + kubeVersion + "-" + profile
+}
+
+// writeMatrix prints one row per Kubernetes version and one column per profile
+func writeMatrix(cmd *cobra.Command, kubeVersions, profiles []string, runs []*chartRun) error {
+	tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 3, ' ', 0)
+	fmt.Fprint(tw, "\nKUBERNETES")
+	for _, profile := range profiles {
+		if profile == "" {
+			profile = "(default)"
+		}
+		fmt.Fprintf(tw, "\t%s", profile)
+	}
+	fmt.Fprintln(tw)
+
+	for i, kubeVersion := range kubeVersions {
+		fmt.Fprint(tw, kubeVersion)
+		for j := range profiles {
+			fmt.Fprintf(tw, "\t%s", matrixCell(runs[i*len(profiles)+j]))
+		}
+		fmt.Fprintln(tw)
+	}
+	return tw.Flush()
+}
+
+// matrixCell summarizes a cell's outcome
+func matrixCell(run *chartRun) string {
+	switch {
+	case run.err != nil:
+		return "failed"
+	case run.session == nil:
+		return "-"
+	case len(run.session.Findings) > run.session.FlakyCount():
+		return fmt.Sprintf("%d unique crash(es)", len(run.session.Findings)-run.session.FlakyCount())
+	case len(run.session.Findings) > 0:
+		return fmt.Sprintf("%d flaky crash(es)", len(run.session.Findings))
+	default:
+		return fmt.Sprintf("clean (%d iterations)", run.session.Iterations)
+	}
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/report"
+)
+
+var combinedReports []string
+
+// reportCmd represents the report command
+var reportCmd = &cobra.Command{
+	Use:   "report <output-dir>...",
+	Short: "Combine the results of earlier sessions into one report",
+	Long: `Combine the report.json files of one or more fuzzing output directories, such
+as the artifacts of nightly matrix jobs, into a single report. Crashes found by
+several runs are listed once, with the runs that found them. Without --report a
+markdown summary is printed to stdout.`,
+	Args: cobra.MinimumNArgs(1),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return nil, cobra.ShellCompDirectiveFilterDirs
+	},
+	RunE: runReport,
+}
+
+func init() {
+	rootCmd.AddCommand(reportCmd)
+
+	reportCmd.Flags().StringArrayVar(&combinedReports, "report", nil, "Write the combined report as format=path (html, junit or markdown, e.g. junit=results.xml); repeatable")
+}
+
+func runReport(cmd *cobra.Command, args []string) error {
+	var specs []report.Spec
+	for _, arg := range combinedReports {
+		spec, err := report.ParseCombinedSpec(arg)
+		if err != nil {
+			return err
+		}
+		specs = append(specs, spec)
+	}
+
+	runs, err := report.LoadRuns(args)
+	if err != nil {
+		return err
+	}
+	combined := report.Combine(runs)
+
+	if len(specs) == 0 {
+		return report.WriteCombinedMarkdown(cmd.OutOrStdout(), combined)
+	}
+	for _, spec := range specs {
+		if err := report.WriteCombinedFile(spec, combined); err != nil {
+			return infraError(err)
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Wrote %s report for %d run(s) to %s\n", spec.Format, len(runs), spec.Path)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/runner"
+	"github.com/kasuboski/helm-fuzzer/pkg/triage"
+)
+
+var reproFormat string
+
+// reproCmd represents the repro command
+var reproCmd = &cobra.Command{
+	Use:   "repro",
+	Short: "Work with saved reproduction files",
+}
+
+var reproVerifyCmd = &cobra.Command{
+	Use:   "verify <chart-path> <repro-file-or-dir>...",
+	Short: "Check that saved reproductions no longer crash the chart",
+	Long: `Render every reproduction file against the chart as it is now and report
+each as passed (renders cleanly) or failed: still crashing the same way (open)
+or with a different error (changed). Directories contribute their
+fuzzer-repro-*.yaml files; other values files can be named directly. Exits with
+code 1 if any reproduction fails, so a release pipeline can require that every
+crash found so far is fixed. -o json prints one result per file.`,
+	Args:              cobra.MinimumNArgs(2),
+	ValidArgsFunction: completeCharts(1, cobra.ShellCompDirectiveDefault),
+	RunE:              runReproVerify,
+}
+
+func init() {
+	rootCmd.AddCommand(reproCmd)
+	reproCmd.AddCommand(reproVerifyCmd)
+
+	reproVerifyCmd.Flags().StringVarP(&reproFormat, "format", "o", "text", "Output format: "+strings.Join(triage.VerifyFormats, ", "))
+	// --output names the format here, since verify writes no files
+	reproVerifyCmd.Flags().SetNormalizeFunc(func(f *pflag.FlagSet, name string) pflag.NormalizedName {
+		if name == "output" {
+			name = "format"
+		}
+		return pflag.NormalizedName(name)
+	})
+}
+
+func runReproVerify(cmd *cobra.Command, args []string) error {
+	chartPath, err := filepath.Abs(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to resolve chart path: %w", err)
+	}
+	if _, err := os.Stat(chartPath); os.IsNotExist(err) {
+		return fmt.Errorf("chart path does not exist: %s", chartPath)
+	}
+	if !slices.Contains(triage.VerifyFormats, reproFormat) {
+		return fmt.Errorf("invalid format %q: must be one of %s", reproFormat, strings.Join(triage.VerifyFormats, ", "))
+	}
+
+	repros, err := triage.LoadRepros(args[1:])
+	if err != nil {
+		return err
+	}
+	if len(repros) == 0 {
+		return fmt.Errorf("no reproduction files found in %s", strings.Join(args[1:], ", "))
+	}
+
+	cfg, err := loadConfig(chartPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	oracle := runner.NewOracleWithConfig(cfg.IgnoreErrors, cfg.UninterestingPatterns)
+	replay, err := replayer(chartPath, cfg, oracle, nil)
+	if err != nil {
+		return err
+	}
+
+	report := triage.Verify(filepath.Base(chartPath), repros, replay)
+	if err := triage.WriteVerify(cmd.OutOrStdout(), reproFormat, report); err != nil {
+		return err
+	}
+	if report.Failed > 0 {
+		return findingsError(fmt.Errorf("%d of %d reproduction(s) still crash", report.Failed, len(report.Results)))
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"helm.sh/helm/v3/pkg/chart/loader"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/risk"
+)
+
+var riskFormat string
+
+// riskCmd represents the risk command
+var riskCmd = &cobra.Command{
+	Use:   "risk <chart-path>",
+	Short: "List the template constructs that fail on unexpected values",
+	Long: `Analyze the chart's templates, without rendering them, for constructs that fail
+on unexpected values and score the values paths feeding them, riskiest first:
+
+  nil index        reads a key of a map no enclosing if or with checks is set
+  printf           formats a value, whose type the verbs expect
+  toYaml           renders a value no enclosing if or with checks is set
+  int conversion   converts a value with int, int64, float64 or atoi
+
+Fuzzing with --risk-weighted keeps risky paths at their default less often,
+mutates them more often, and sometimes sets maps read by a nil index to null,
+which unsets them. The analysis follows .Values, $.Values and the dot of with
+blocks; values reached through variables or range elements are not scored.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeCharts(1, cobra.ShellCompDirectiveNoFileComp),
+	RunE:              runRisk,
+}
+
+func init() {
+	rootCmd.AddCommand(riskCmd)
+
+	riskCmd.Flags().StringVarP(&riskFormat, "format", "o", "text", "Output format: text or json")
+}
+
+func runRisk(cmd *cobra.Command, args []string) error {
+	if riskFormat != "text" && riskFormat != "json" {
+		return fmt.Errorf("invalid format %q: must be text or json", riskFormat)
+	}
+
+	chartPath, err := filepath.Abs(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to resolve chart path: %w", err)
+	}
+	if _, err := os.Stat(chartPath); os.IsNotExist(err) {
+		return fmt.Errorf("chart path does not exist: %s", chartPath)
+	}
+
+	c, err := loader.Load(chartPath)
+	if err != nil {
+		return fmt.Errorf("failed to load chart: %w", err)
+	}
+	scores := risk.Scores(risk.Analyze(c))
+
+	out := cmd.OutOrStdout()
+	if riskFormat == "json" {
+		if scores == nil {
+			scores = []risk.Score{}
+		}
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(scores)
+	}
+
+	if len(scores) == 0 {
+		fmt.Fprintln(out, "No risky constructs found")
+		return nil
+	}
+	tw := tabwriter.NewWriter(out, 0, 4, 3, ' ', 0)
+	fmt.Fprintln(tw, "PATH\tSCORE\tCONSTRUCTS")
+	for _, s := range scores {
+		constructs := make([]string, len(s.Constructs))
+		for i, c := range s.Constructs {
+			constructs[i] = fmt.Sprintf("%s at %s:%d", c.Kind, c.Template, c.Line)
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\n", s.Path, s.Score, strings.Join(constructs, ", "))
+	}
+	return tw.Flush()
+}
@@ -2,12 +2,21 @@ package cmd
 
 import (
 	"fmt"
+	"net/url"
+	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
+	"helm.sh/helm/v3/pkg/cli/values"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/config"
+	"github.com/kasuboski/helm-fuzzer/pkg/runner"
 )
 
 var (
-	version = "0.1.0"
+	version    = "0.1.0"
+	configFile string
+	offline    bool
 )
 
 // rootCmd represents the base command
@@ -18,15 +27,79 @@ var rootCmd = &cobra.Command{
 It generates thousands of randomized, valid-schema values.yaml inputs and attempts to
 render the chart. If a specific set of values causes the template engine to panic or
 produce error messages, the tool identifies it and minimizes the input to the smallest
-possible reproduction case.`,
+possible reproduction case.
+
+Every flag can also be set through the environment as HELMFUZZ_ followed by the
+flag's name in upper case with underscores for dashes, e.g. HELMFUZZ_ITERATIONS=500
+or HELMFUZZ_ARTIFACTS_DIR=/artifacts. Flags on the command line take precedence.`,
 	Version: version,
+	// Usage helps with bad arguments, not with errors from running the command
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		if err := applyEnv(cmd); err != nil {
+			return err
+		}
+		runner.SetOffline(offline)
+		return nil
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 func Execute() error {
+	rootCmd.SetArgs(defaultToFuzz(os.Args[1:]))
 	return rootCmd.Execute()
 }
 
+// defaultToFuzz makes fuzz the default command, so `helm fuzz <chart-path>`
+// works when helm runs the binary as a plugin with the arguments after "fuzz"
+func defaultToFuzz(args []string) []string {
+	if len(args) == 0 {
+		return args
+	}
+	switch args[0] {
+	case "-h", "--help", "--version", "help", "completion", cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd:
+		return args
+	}
+	for _, c := range rootCmd.Commands() {
+		if c.Name() == args[0] || c.HasAlias(args[0]) {
+			return args
+		}
+	}
+	return append([]string{fuzzCmd.Name()}, args...)
+}
+
 func init() {
 	rootCmd.SetVersionTemplate(fmt.Sprintf("helm-fuzz version %s\n", version))
+
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "Config file to use instead of the chart's "+config.FileName)
+	rootCmd.MarkPersistentFlagFilename("config", "yaml", "yml")
+	rootCmd.PersistentFlags().BoolVar(&offline, "offline", false, "Forbid all network access, failing anything that needs it, for air-gapped and restricted build environments")
+}
+
+// loadConfig loads the --config file if given, otherwise the chart's own config
+func loadConfig(chartPath string) (*config.Config, error) {
+	if configFile != "" {
+		return config.LoadConfigFile(configFile)
+	}
+	return config.LoadConfig(chartPath)
+}
+
+// checkOfflineValues returns an error under --offline for values files and
+// --set-file values at URLs, which helm would download
+func checkOfflineValues(opts values.Options) error {
+	paths := append([]string{}, opts.ValueFiles...)
+	for _, value := range opts.FileValues {
+		if _, path, ok := strings.Cut(value, "="); ok {
+			paths = append(paths, path)
+		}
+	}
+	for _, path := range paths {
+		// A single-letter scheme is a Windows drive
+		if u, err := url.Parse(path); err == nil && len(u.Scheme) > 1 && u.Scheme != "file" {
+			if err := runner.NeedsNetwork("reading values from " + path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
 }
@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/runner"
+)
+
+var scenarioNames []string
+
+// scenariosCmd represents the scenarios command
+var scenariosCmd = &cobra.Command{
+	Use:   "scenarios <chart-path>",
+	Short: "Run the scenarios in .helmfuzz.yaml as property tests",
+	Long: `Run one fuzzing session per scenario in .helmfuzz.yaml. A scenario pins its
+values in every input, like --set, and checks its invariants against every
+successful render: each bounds how many resources of a kind, optionally with
+an apiVersion and name, render, at least one unless min or max say otherwise.
+A render breaking an invariant is a finding.
+
+  scenarios:
+    ingress-enabled:
+      iterations: 500
+      values:
+        ingress:
+          enabled: true
+      invariants:
+        - kind: Ingress
+        - kind: Service
+          max: 1
+
+Each scenario has its own iteration budget (default: iterations), which
+--iterations overrides, and writes its files, reports included, to its own
+subdirectory of --output when there are several. Combine the reports with
+helm fuzz report to read them as one section per scenario.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeCharts(1, cobra.ShellCompDirectiveNoFileComp),
+	RunE:              runScenarios,
+}
+
+func init() {
+	rootCmd.AddCommand(scenariosCmd)
+	addSessionFlags(scenariosCmd)
+
+	scenariosCmd.Flags().StringSliceVar(&scenarioNames, "scenario", nil, "Only run these scenarios (default: every scenario)")
+	scenariosCmd.RegisterFlagCompletionFunc("scenario", completeScenarios)
+}
+
+func runScenarios(cmd *cobra.Command, args []string) error {
+	chartPath, err := filepath.Abs(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to resolve chart path: %w", err)
+	}
+	if _, err := os.Stat(chartPath); os.IsNotExist(err) {
+		return fmt.Errorf("chart path does not exist: %s", chartPath)
+	}
+
+	cfg, err := loadConfig(chartPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if len(cfg.Scenarios) == 0 {
+		return fmt.Errorf("no scenarios defined in the config")
+	}
+
+	names := scenarioNames
+	if len(names) == 0 {
+		for name := range cfg.Scenarios {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+
+	var runs []*chartRun
+	for _, name := range names {
+		scenario, ok := cfg.Scenarios[name]
+		if !ok {
+			return fmt.Errorf("unknown scenario %q", name)
+		}
+		runs = append(runs, &chartRun{
+			chartPath:  chartPath,
+			name:       name,
+			values:     scenario.Values,
+			iterations: scenario.Iterations,
+			invariants: scenario.Invariants,
+		})
+	}
+
+	if err := runSessions(cmd, runs, "scenario"); err != nil {
+		return err
+	}
+	if logFormat == "text" {
+		if err := writeScenarios(cmd, runs); err != nil {
+			return err
+		}
+	}
+	return sessionsResult(runs)
+}
+
+// writeScenarios prints one row per scenario with the invariants its renders
+// broke and its outcome
+func writeScenarios(cmd *cobra.Command, runs []*chartRun) error {
+	tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 3, ' ', 0)
+	fmt.Fprintln(tw, "\nSCENARIO\tINVARIANTS\tRESULT")
+	for _, run := range runs {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", run.name, invariantsCell(run), matrixCell(run))
+	}
+	return tw.Flush()
+}
+
+// invariantsCell summarizes how many of a scenario's invariants held
+func invariantsCell(run *chartRun) string {
+	if len(run.invariants) == 0 || run.session == nil {
+		return "-"
+	}
+	var broken []string
+	for _, f := range run.session.Findings {
+		if f.Category == runner.CategoryInvariant {
+			broken = append(broken, strings.TrimPrefix(f.Reason, "Invariant violated: "))
+		}
+	}
+	if len(broken) == 0 {
+		return fmt.Sprintf("%d held", len(run.invariants))
+	}
+	return fmt.Sprintf("%d broken: %s", len(broken), strings.Join(broken, "; "))
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/schema"
+)
+
+var schemaFormat string
+
+// schemaCmd represents the schema command
+var schemaCmd = &cobra.Command{
+	Use:   "schema <chart-path>",
+	Short: "Print the schema the fuzzer uses for a chart",
+	Long: `Print the schema detected from values.schema.json or values.schema.yaml, in any
+JSON Schema draft from draft-04 to 2020-12, or inferred from values.yaml, after applying the ignore and constraint entries in .helmfuzz.yaml. Paths affected
+by .helmfuzz.yaml are annotated, which helps debug why a path is not fuzzed the
+way you expect.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeCharts(1, cobra.ShellCompDirectiveNoFileComp),
+	RunE:              runSchema,
+}
+
+func init() {
+	rootCmd.AddCommand(schemaCmd)
+
+	schemaCmd.Flags().StringVarP(&schemaFormat, "format", "o", "tree", "Output format: tree or json")
+}
+
+func runSchema(cmd *cobra.Command, args []string) error {
+	if schemaFormat != "tree" && schemaFormat != "json" {
+		return fmt.Errorf("invalid format %q: must be tree or json", schemaFormat)
+	}
+
+	chartPath, err := filepath.Abs(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to resolve chart path: %w", err)
+	}
+	if _, err := os.Stat(chartPath); os.IsNotExist(err) {
+		return fmt.Errorf("chart path does not exist: %s", chartPath)
+	}
+
+	cfg, err := loadConfig(chartPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	sch, source, err := schema.NewEngine(cfg).Detect(chartPath)
+	if err != nil {
+		return infraError(fmt.Errorf("failed to detect schema: %w", err))
+	}
+
+	out := cmd.OutOrStdout()
+	if schemaFormat == "json" {
+		return schema.WriteJSON(out, sch, source)
+	}
+
+	fmt.Fprintf(out, "# Schema from %s (max depth %d, %d ignored, %d constraints)\n", source, cfg.MaxDepth, len(cfg.Ignore), len(cfg.Constraints))
+	return schema.WriteTree(out, sch)
+}
@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/runner"
+)
+
+var (
+	sdkDiffBinaries      []string
+	sdkDiffIterations    int
+	sdkDiffTimeout       string
+	sdkDiffOutput        string
+	sdkDiffNoOutputDiffs bool
+)
+
+// sdkDiffCmd represents the sdk-diff command
+var sdkDiffCmd = &cobra.Command{
+	Use:   "sdk-diff <chart-path> --helm <binary>...",
+	Short: "Find inputs a helm binary renders differently from the embedded Helm SDK",
+	Long: `Render the same inputs with the Helm SDK built into helm-fuzz and with each
+helm binary given by --helm, through helm template, and report where they
+diverge, as helm fuzz diff does for two versions of a chart: the embedded SDK is
+the old side and the binary the new one. Point --helm at the version you are
+about to upgrade to, or the one your CI or cluster tooling runs, to catch
+templates that depend on version-specific behavior. Inputs are the chart
+defaults, its seeds, then inputs generated from its schema. Each distinct
+divergence is reported once per binary and its input saved to --output. The
+command fails when any divergence is found.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeCharts(1, cobra.ShellCompDirectiveNoFileComp),
+	RunE:              runSDKDiff,
+}
+
+func init() {
+	rootCmd.AddCommand(sdkDiffCmd)
+
+	sdkDiffCmd.Flags().StringArrayVar(&sdkDiffBinaries, "helm", nil, "Helm binary to compare against the embedded SDK, a path or a name in PATH; repeatable")
+	sdkDiffCmd.Flags().IntVar(&sdkDiffIterations, "iterations", 0, "Number of inputs to compare (default: iterations from the chart's config)")
+	sdkDiffCmd.Flags().StringVar(&sdkDiffTimeout, "timeout", "5m", "Timeout for the comparison (e.g., 5m, 1h)")
+	sdkDiffCmd.Flags().StringVar(&sdkDiffOutput, "output", ".", "Output directory for divergent inputs")
+	sdkDiffCmd.Flags().BoolVar(&sdkDiffNoOutputDiffs, "no-output-changes", false, "Only report crash differences, not changed manifests")
+	sdkDiffCmd.MarkFlagRequired("helm")
+	sdkDiffCmd.MarkFlagFilename("helm")
+}
+
+// binaryComparison is the divergences found for one helm binary
+type binaryComparison struct {
+	binary  string
+	version string
+	// runners render with the binary per Kubernetes version
+	runners map[string]*runner.BinaryRunner
+	groups  map[string]*divergenceGroup
+	order   []string
+}
+
+func runSDKDiff(cmd *cobra.Command, args []string) error {
+	chartPath, err := filepath.Abs(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to resolve chart path: %w", err)
+	}
+	if _, err := os.Stat(chartPath); os.IsNotExist(err) {
+		return fmt.Errorf("chart path does not exist: %s", chartPath)
+	}
+	timeout, err := time.ParseDuration(sdkDiffTimeout)
+	if err != nil {
+		return fmt.Errorf("invalid timeout: %w", err)
+	}
+
+	cfg, err := loadConfig(chartPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if sdkDiffIterations > 0 {
+		cfg.Iterations = sdkDiffIterations
+	}
+	input, err := comparisonInputs(cfg, chartPath)
+	if err != nil {
+		return err
+	}
+	oracle := runner.NewOracleWithConfig(cfg.IgnoreErrors, cfg.UninterestingPatterns)
+	minimizer := runner.NewMinimizer(sdkDiffOutput)
+
+	comparisons := make([]*binaryComparison, len(sdkDiffBinaries))
+	for i, binary := range sdkDiffBinaries {
+		c := &binaryComparison{binary: binary, runners: make(map[string]*runner.BinaryRunner), groups: make(map[string]*divergenceGroup)}
+		for _, kubeVersion := range cfg.KubeVersions {
+			b, err := runner.NewBinaryRunner(binary, chartPath, kubeVersion)
+			if err != nil {
+				return err
+			}
+			c.runners[kubeVersion], c.version = b, b.Version()
+		}
+		comparisons[i] = c
+	}
+
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "Comparing Helm SDK %s with", runner.SDKVersion())
+	for _, c := range comparisons {
+		fmt.Fprintf(out, " %s (%s)", c.binary, c.version)
+	}
+	fmt.Fprintf(out, " over %d inputs\n", cfg.Iterations)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	compared := 0
+	for i := 0; i < cfg.Iterations && ctx.Err() == nil; i++ {
+		values := input(i)
+
+		// Every implementation renders each input against the same Kubernetes version
+		kubeVersion := cfg.KubeVersions[i%len(cfg.KubeVersions)]
+		sdkRunner, err := runner.NewWithKubeVersion(chartPath, kubeVersion)
+		if err != nil {
+			return infraError(fmt.Errorf("failed to create runner: %w", err))
+		}
+		sdkResult := sdkRunner.Run(values)
+		compared++
+
+		for _, c := range comparisons {
+			binaryResult := c.runners[kubeVersion].Run(values)
+
+			d := runner.Diverge(oracle, sdkResult, binaryResult)
+			if d == nil || (sdkDiffNoOutputDiffs && d.Kind == runner.DivergenceOutput) {
+				continue
+			}
+			if g, ok := c.groups[d.Key()]; ok {
+				g.count++
+				continue
+			}
+
+			g := &divergenceGroup{Divergence: d, iteration: i + 1, count: 1}
+			file, err := minimizer.SaveReproduction(binaryResult, fmt.Sprintf("helm %s %s", c.version, describeDivergence(d)))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to save divergent input: %v\n", err)
+			}
+			g.file = file
+			c.groups[d.Key()] = g
+			c.order = append(c.order, d.Key())
+		}
+	}
+
+	divergences := 0
+	for _, c := range comparisons {
+		fmt.Fprintf(out, "\n%s (%s): %d distinct divergence(s)\n", c.binary, c.version, len(c.order))
+		for _, key := range c.order {
+			writeDivergence(cmd, c.groups[key])
+		}
+		divergences += len(c.order)
+	}
+
+	fmt.Fprintf(out, "\nCompared %d inputs: %d distinct divergence(s)\n", compared, divergences)
+	if divergences > 0 {
+		return findingsError(fmt.Errorf("helm binaries diverged from the embedded SDK on %d input class(es)", divergences))
+	}
+	return nil
+}
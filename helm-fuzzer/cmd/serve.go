@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/runner"
+	"github.com/kasuboski/helm-fuzzer/pkg/server"
+)
+
+var (
+	serveAddr           string
+	serveDataDir        string
+	serveMaxJobs        int
+	serveDefaultTimeout time.Duration
+	serveMaxTimeout     time.Duration
+)
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run a fuzzing service with a REST API",
+	Long: `Serve a REST API that accepts charts, uploaded as a .tgz or pulled from an OCI
+registry, fuzzes them as queued jobs and serves their status, reports and
+reproduction files. Registry credentials are helm's, as set up with
+helm registry login. The API has no authentication of its own; expose it
+through a proxy that provides it.`,
+	Args: cobra.NoArgs,
+	RunE: runServe,
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().StringVar(&serveAddr, "addr", "localhost:8080", "Address to serve the API on")
+	serveCmd.Flags().StringVar(&serveDataDir, "data-dir", filepath.Join(os.TempDir(), "helm-fuzz-serve"), "Directory for job charts and output")
+	serveCmd.Flags().IntVar(&serveMaxJobs, "max-jobs", 2, "Number of jobs to run at once; more are queued")
+	serveCmd.Flags().DurationVar(&serveDefaultTimeout, "default-timeout", 5*time.Minute, "Timeout for jobs that set none")
+	serveCmd.Flags().DurationVar(&serveMaxTimeout, "max-timeout", time.Hour, "Longest timeout a job may ask for")
+	serveCmd.MarkFlagDirname("data-dir")
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	if err := runner.NeedsNetwork("serving jobs"); err != nil {
+		return err
+	}
+	if serveMaxJobs <= 0 {
+		return fmt.Errorf("--max-jobs must be positive")
+	}
+	if serveDefaultTimeout <= 0 || serveDefaultTimeout > serveMaxTimeout {
+		return fmt.Errorf("--default-timeout must be positive and at most --max-timeout")
+	}
+
+	logger := slog.New(slog.NewTextHandler(cmd.ErrOrStderr(), nil))
+	srv := server.New(server.Options{
+		DataDir:        serveDataDir,
+		MaxJobs:        serveMaxJobs,
+		DefaultTimeout: serveDefaultTimeout,
+		MaxTimeout:     serveMaxTimeout,
+		ToolVersion:    version,
+		Logger:         logger,
+	})
+
+	// Interrupts stop the server and cancel running jobs
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	addr, err := srv.Start(ctx, serveAddr)
+	if err != nil {
+		return infraError(err)
+	}
+	logger.Info("serving fuzzing API", "url", fmt.Sprintf("http://%s/api/v1/jobs", addr), "dataDir", serveDataDir)
+
+	<-ctx.Done()
+	srv.Close()
+	logger.Info("server stopped")
+	return nil
+}
@@ -0,0 +1,206 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/config"
+	"github.com/kasuboski/helm-fuzzer/pkg/runner"
+	"github.com/kasuboski/helm-fuzzer/pkg/snapshot"
+)
+
+var (
+	snapshotKubeVersion string
+	snapshotFormat      string
+)
+
+// defaultSnapshot is the scenario rendered with the chart defaults when the config names none
+const defaultSnapshot = "default"
+
+// snapshotCmd represents the snapshot command
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Record and verify the manifests rendered for named values",
+	Long: `Snapshots are the manifests a chart renders for the named values documents
+under snapshots in .helmfuzz.yaml, or for its defaults alone as "default" when
+there are none. They are normalized before being stored in snapshotDir
+(default __snapshots__ next to the config): resources are sorted by kind,
+namespace and name, map keys are sorted, and checksum/ annotations and chart
+version labels are dropped, so only changes to what would be applied show up.`,
+}
+
+var snapshotRecordCmd = &cobra.Command{
+	Use:               "record <chart-path> [name...]",
+	Short:             "Render the named snapshots, or all of them, and store the manifests",
+	Args:              cobra.MinimumNArgs(1),
+	ValidArgsFunction: completeCharts(1, cobra.ShellCompDirectiveNoFileComp),
+	RunE:              runSnapshotRecord,
+}
+
+var snapshotVerifyCmd = &cobra.Command{
+	Use:   "verify <chart-path> [name...]",
+	Short: "Check that the chart still renders the recorded manifests",
+	Long: `Render the named snapshots, or all of them, and compare the normalized
+manifests with the recorded ones, printing a diff for each that changed. Exits
+with code 1 if any snapshot changed, is not recorded, or no longer renders.`,
+	Args:              cobra.MinimumNArgs(1),
+	ValidArgsFunction: completeCharts(1, cobra.ShellCompDirectiveNoFileComp),
+	RunE:              runSnapshotVerify,
+}
+
+func init() {
+	rootCmd.AddCommand(snapshotCmd)
+	snapshotCmd.AddCommand(snapshotRecordCmd, snapshotVerifyCmd)
+
+	snapshotCmd.PersistentFlags().StringVar(&snapshotKubeVersion, "kube-version", "", "Kubernetes version to render against (default: the first of the config's kubeVersions)")
+	snapshotVerifyCmd.Flags().StringVarP(&snapshotFormat, "format", "o", "text", "Output format: text or json")
+}
+
+// snapshotSetup loads the chart's config and a runner for rendering its snapshots
+func snapshotSetup(chartArg string) (string, *config.Config, *runner.Runner, error) {
+	chartPath, err := filepath.Abs(chartArg)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("failed to resolve chart path: %w", err)
+	}
+	if _, err := os.Stat(chartPath); os.IsNotExist(err) {
+		return "", nil, nil, fmt.Errorf("chart path does not exist: %s", chartPath)
+	}
+	cfg, err := loadConfig(chartPath)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	kubeVersion := snapshotKubeVersion
+	if kubeVersion == "" {
+		kubeVersion = cfg.KubeVersions[0]
+	}
+	r, err := runner.NewWithKubeVersion(chartPath, kubeVersion)
+	if err != nil {
+		return "", nil, nil, infraError(fmt.Errorf("failed to create runner: %w", err))
+	}
+	return chartPath, cfg, r, nil
+}
+
+// snapshotScenarios returns the values of the named snapshots, or of all of
+// them, sorted by name
+func snapshotScenarios(cfg *config.Config, names []string) ([]string, map[string]map[string]interface{}, error) {
+	scenarios := cfg.Snapshots
+	if len(scenarios) == 0 {
+		scenarios = map[string]map[string]interface{}{defaultSnapshot: {}}
+	}
+	if len(names) == 0 {
+		for name := range scenarios {
+			names = append(names, name)
+		}
+	}
+	for _, name := range names {
+		if _, ok := scenarios[name]; !ok {
+			return nil, nil, fmt.Errorf("unknown snapshot %q", name)
+		}
+	}
+	sort.Strings(names)
+	return names, scenarios, nil
+}
+
+func runSnapshotRecord(cmd *cobra.Command, args []string) error {
+	chartPath, cfg, r, err := snapshotSetup(args[0])
+	if err != nil {
+		return err
+	}
+	names, scenarios, err := snapshotScenarios(cfg, args[1:])
+	if err != nil {
+		return err
+	}
+	dir := cfg.ResolveSnapshotDir(chartPath)
+
+	out := cmd.OutOrStdout()
+	for _, name := range names {
+		res := r.Run(scenarios[name])
+		if !res.Success {
+			return findingsError(fmt.Errorf("snapshot %s does not render: %w", name, res.Error))
+		}
+		normalized, err := snapshot.Normalize(res.Manifest)
+		if err != nil {
+			return findingsError(fmt.Errorf("snapshot %s: %w", name, err))
+		}
+		if err := snapshot.Save(dir, name, normalized); err != nil {
+			return infraError(err)
+		}
+		fmt.Fprintf(out, "Recorded %s in %s\n", name, snapshot.Path(dir, name))
+	}
+	return nil
+}
+
+func runSnapshotVerify(cmd *cobra.Command, args []string) error {
+	if snapshotFormat != "text" && snapshotFormat != "json" {
+		return fmt.Errorf("invalid format %q: must be text or json", snapshotFormat)
+	}
+	chartPath, cfg, r, err := snapshotSetup(args[0])
+	if err != nil {
+		return err
+	}
+	names, scenarios, err := snapshotScenarios(cfg, args[1:])
+	if err != nil {
+		return err
+	}
+	dir := cfg.ResolveSnapshotDir(chartPath)
+
+	results := make([]snapshot.Result, 0, len(names))
+	failed := 0
+	for _, name := range names {
+		res := r.Run(scenarios[name])
+		var renderErr error
+		if !res.Success {
+			renderErr = res.Error
+		}
+		result := snapshot.Verify(dir, name, res.Manifest, renderErr)
+		if result.Status != snapshot.StatusMatch {
+			failed++
+		}
+		results = append(results, result)
+	}
+
+	out := cmd.OutOrStdout()
+	if snapshotFormat == "json" {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(results); err != nil {
+			return err
+		}
+	} else {
+		for _, result := range results {
+			fmt.Fprintf(out, "%-8s %s\n", result.Status, result.Name)
+			switch result.Status {
+			case snapshot.StatusChanged:
+				for _, line := range strings.Split(strings.TrimSuffix(result.Diff, "\n"), "\n") {
+					fmt.Fprintf(out, "    %s\n", line)
+				}
+			case snapshot.StatusMissing:
+				fmt.Fprintf(out, "    not recorded; run helm fuzz snapshot record %s %s\n", args[0], result.Name)
+			case snapshot.StatusFailed:
+				fmt.Fprintf(out, "    %s\n", firstLine(result.Error))
+			}
+		}
+		// Snapshots of removed scenarios only matter when verifying all of them
+		if len(args) == 1 {
+			obsolete, err := snapshot.Obsolete(dir, names)
+			if err != nil {
+				return infraError(err)
+			}
+			for _, name := range obsolete {
+				fmt.Fprintf(out, "obsolete %s (no longer configured; delete %s)\n", name, snapshot.Path(dir, name))
+			}
+		}
+		fmt.Fprintf(out, "\n%d of %d snapshot(s) match\n", len(results)-failed, len(results))
+	}
+
+	if failed > 0 {
+		return findingsError(fmt.Errorf("%d snapshot(s) do not match", failed))
+	}
+	return nil
+}
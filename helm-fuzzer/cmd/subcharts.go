@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/fuzz"
+)
+
+var subchartNames []string
+
+// jointRunName names the session that fuzzes every value at once
+const jointRunName = "joint"
+
+// subchartsCmd represents the subcharts command
+var subchartsCmd = &cobra.Command{
+	Use:   "subcharts <chart-path>",
+	Short: "Fuzz each dependency of an umbrella chart in isolation and jointly",
+	Long: `Run one fuzzing session per dependency of an umbrella chart, each varying only
+that dependency's values while the parent's and every other dependency's keep
+their defaults, plus a joint session varying them all. An isolated session
+enables its dependency through its condition or tags, and reads its values
+from the dependency's own values schema or values.yaml.
+
+The result table attributes each finding to the subchart whose template it
+is in; joint findings in the parent's templates, or without a template
+location, count toward (chart). Each session writes its files to its own
+subdirectory of --output, named after the dependency or joint.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeCharts(1, cobra.ShellCompDirectiveNoFileComp),
+	RunE:              runSubcharts,
+}
+
+func init() {
+	rootCmd.AddCommand(subchartsCmd)
+	addSessionFlags(subchartsCmd)
+
+	subchartsCmd.Flags().StringSliceVar(&subchartNames, "subchart", nil, "Only isolate these dependencies, by alias or chart name (default: every dependency)")
+}
+
+func runSubcharts(cmd *cobra.Command, args []string) error {
+	chartPath, err := filepath.Abs(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to resolve chart path: %w", err)
+	}
+	if _, err := os.Stat(chartPath); os.IsNotExist(err) {
+		return fmt.Errorf("chart path does not exist: %s", chartPath)
+	}
+
+	subcharts, err := fuzz.Subcharts(chartPath)
+	if err != nil {
+		return err
+	}
+	if len(subcharts) == 0 {
+		return fmt.Errorf("%s has no dependencies", chartPath)
+	}
+	for _, name := range subchartNames {
+		if !slices.Contains(subcharts, name) {
+			return fmt.Errorf("chart has no dependency %s", name)
+		}
+	}
+	if len(subchartNames) > 0 {
+		subcharts = subchartNames
+	}
+
+	runs := []*chartRun{{chartPath: chartPath, name: jointRunName}}
+	for _, name := range subcharts {
+		if name == jointRunName {
+			return fmt.Errorf("dependency %s clashes with the joint session's name", name)
+		}
+		runs = append(runs, &chartRun{chartPath: chartPath, name: name, subchart: name})
+	}
+
+	if err := runSessions(cmd, runs, "subchart"); err != nil {
+		return err
+	}
+	if logFormat == "text" {
+		if err := writeSubcharts(cmd, subcharts, runs); err != nil {
+			return err
+		}
+	}
+	return sessionsResult(runs)
+}
+
+// writeSubcharts prints one row per subchart with its isolated findings and
+// the joint findings attributed to it, then the joint findings left to the chart
+func writeSubcharts(cmd *cobra.Command, subcharts []string, runs []*chartRun) error {
+	joint := runs[0]
+	attributed := make(map[string]int)
+	if joint.session != nil {
+		for _, f := range joint.session.Findings {
+			name := ""
+			if f.Attribution != nil {
+				name = f.Attribution.Subchart()
+			}
+			attributed[name]++
+		}
+	}
+
+	tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 3, ' ', 0)
+	fmt.Fprintln(tw, "\nSUBCHART\tISOLATED\tJOINT")
+	for i, name := range subcharts {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", name, matrixCell(runs[i+1]), jointCell(joint, attributed[name]))
+	}
+	fmt.Fprintf(tw, "(chart)\t-\t%s\n", jointCell(joint, attributed[""]))
+	return tw.Flush()
+}
+
+// jointCell summarizes the joint findings attributed to one row
+func jointCell(joint *chartRun, findings int) string {
+	switch {
+	case joint.err != nil:
+		return "failed"
+	case joint.session == nil:
+		return "-"
+	case findings > 0:
+		return fmt.Sprintf("%d unique crash(es)", findings)
+	default:
+		return "clean"
+	}
+}
@@ -0,0 +1,226 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/config"
+	"github.com/kasuboski/helm-fuzzer/pkg/envtest"
+	"github.com/kasuboski/helm-fuzzer/pkg/plugin"
+	"github.com/kasuboski/helm-fuzzer/pkg/report"
+	"github.com/kasuboski/helm-fuzzer/pkg/runner"
+	"github.com/kasuboski/helm-fuzzer/pkg/scanner"
+	"github.com/kasuboski/helm-fuzzer/pkg/triage"
+)
+
+var (
+	triageFormat  string
+	triageEnvtest bool
+)
+
+// triageCmd represents the triage command
+var triageCmd = &cobra.Command{
+	Use:   "triage <chart-path> <output-dir>...",
+	Short: "Replay saved crashes against the current chart",
+	Long: `Load the findings saved in one or more fuzzing output directories, regroup
+them by fingerprint under the current deduplication rules and the chart's current
+ignore patterns, and replay one input per group against the chart as it is now.
+Each group is reported as open (still crashes the same way), changed (crashes with
+a different error) or fixed (renders cleanly). Findings recorded for other charts
+are skipped.`,
+	Args:              cobra.MinimumNArgs(2),
+	ValidArgsFunction: completeCharts(1, cobra.ShellCompDirectiveFilterDirs),
+	RunE:              runTriage,
+}
+
+func init() {
+	rootCmd.AddCommand(triageCmd)
+
+	triageCmd.Flags().StringVarP(&triageFormat, "format", "o", "text", "Output format: "+strings.Join(triage.Formats, ", "))
+	triageCmd.Flags().BoolVar(&triageEnvtest, "envtest", false, "Also dry-run clean renders on local API servers, replaying findings of sessions run with --envtest or --upgrades")
+}
+
+func runTriage(cmd *cobra.Command, args []string) error {
+	chartPath, err := filepath.Abs(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to resolve chart path: %w", err)
+	}
+	if _, err := os.Stat(chartPath); os.IsNotExist(err) {
+		return fmt.Errorf("chart path does not exist: %s", chartPath)
+	}
+	if !slices.Contains(triage.Formats, triageFormat) {
+		return fmt.Errorf("invalid format %q: must be one of %s", triageFormat, strings.Join(triage.Formats, ", "))
+	}
+
+	cfg, err := loadConfig(chartPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	runs, err := chartRuns(cmd, filepath.Base(chartPath), args[1:])
+	if err != nil {
+		return err
+	}
+
+	oracle := runner.NewOracleWithConfig(cfg.IgnoreErrors, cfg.UninterestingPatterns)
+	clusters, dropped := triage.ClusterFindings(runs, oracle)
+	if dropped > 0 {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Dropped %d finding(s) now matched by ignore or uninteresting patterns\n", dropped)
+	}
+
+	var validators map[string]*envtest.Validator
+	if triageEnvtest {
+		var stop func()
+		validators, stop, err = envtest.StartValidators(cmd.Context(), cfg.ResolveEnvtestAssets(chartPath), cfg.KubeVersions, cfg.Envtest.StartTimeout, nil)
+		if err != nil {
+			return infraError(err)
+		}
+		defer stop()
+	}
+	replay, err := replayer(chartPath, cfg, oracle, validators)
+	if err != nil {
+		return err
+	}
+	results := triage.Replay(clusters, replay)
+
+	return triage.Write(cmd.OutOrStdout(), triageFormat, results)
+}
+
+// replayer renders saved inputs against the chart. Saved findings do not
+// record the Kubernetes version they crashed on, so each input is rendered
+// with every version until one crashes. Clean renders are checked by the
+// version's API server when validators has one, also as upgrades from the
+// chart's defaults installed there, and by the config's oracle plugins and
+// scanners, so their findings replay too; the scanners' findings on the
+// chart's defaults are left out, as in sessions.
+func replayer(chartPath string, cfg *config.Config, oracle *runner.Oracle, validators map[string]*envtest.Validator) (triage.ReplayFunc, error) {
+	runners := make([]*runner.Runner, 0, len(cfg.KubeVersions))
+	for _, kubeVersion := range cfg.KubeVersions {
+		r, err := runner.NewWithKubeVersion(chartPath, kubeVersion)
+		if err != nil {
+			return nil, infraError(fmt.Errorf("failed to create runner: %w", err))
+		}
+		runners = append(runners, r)
+	}
+	oracles := plugin.OfType(plugin.New(cfg, chartPath), config.PluginOracle)
+	scanners := scanner.New(cfg, chartPath)
+	baseline := map[string]bool{}
+	if len(scanners) > 0 {
+		if result := runners[0].Run(map[string]interface{}{}); result.Error == nil {
+			reasons, err := scanner.ScanAll(context.Background(), scanners, result.Manifest)
+			if err != nil {
+				return nil, infraError(err)
+			}
+			baseline = scanner.Baseline(reasons)
+		}
+	}
+	releases := make(map[string]*envtest.Release, len(validators))
+	for i, r := range runners {
+		v := validators[cfg.KubeVersions[i]]
+		if v == nil {
+			continue
+		}
+		if result := r.Run(map[string]interface{}{}); result.Error == nil {
+			release, err := v.Install(context.Background(), result.Manifest, runner.DefaultNamespace)
+			if err != nil {
+				return nil, infraError(err)
+			}
+			releases[cfg.KubeVersions[i]] = release
+		}
+	}
+	return func(values map[string]interface{}) string {
+		for i, r := range runners {
+			result := r.Run(values)
+			if oracle.IsCrash(result) {
+				if oracle.IsInteresting(result) {
+					return oracle.GetCrashReason(result)
+				}
+				continue
+			}
+			if v := validators[cfg.KubeVersions[i]]; v != nil {
+				reasons, err := v.Validate(context.Background(), result.Manifest, runner.DefaultNamespace)
+				if err != nil {
+					// Replays go on without an API server that stopped answering
+					fmt.Fprintf(os.Stderr, "Replaying without API server: %v\n", err)
+					delete(validators, cfg.KubeVersions[i])
+					delete(releases, cfg.KubeVersions[i])
+				}
+				for _, reason := range reasons {
+					if oracle.IsInterestingReason(reason) {
+						return reason
+					}
+				}
+			}
+			if release := releases[cfg.KubeVersions[i]]; release != nil {
+				reasons, err := release.Upgrade(context.Background(), result.Manifest, runner.DefaultNamespace)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Replaying without API server: %v\n", err)
+					delete(releases, cfg.KubeVersions[i])
+				}
+				for _, reason := range reasons {
+					if oracle.IsInterestingReason(reason) {
+						return reason
+					}
+				}
+			}
+			if len(oracles) > 0 {
+				reasons, err := plugin.CheckAll(context.Background(), oracles, plugin.CheckRequest{
+					Chart:       filepath.Base(chartPath),
+					KubeVersion: cfg.KubeVersions[i],
+					Values:      values,
+					Manifest:    result.Manifest,
+				})
+				if err != nil {
+					// Replays go on without the broken plugins rather than repeating the failure
+					fmt.Fprintf(os.Stderr, "Replaying without oracle plugins: %v\n", err)
+					oracles = nil
+				}
+				for _, reason := range reasons {
+					if oracle.IsInterestingReason(reason) {
+						return reason
+					}
+				}
+			}
+			if len(scanners) > 0 {
+				reasons, err := scanner.ScanAll(context.Background(), scanners, result.Manifest)
+				if err != nil {
+					// Likewise without broken scanners
+					fmt.Fprintf(os.Stderr, "Replaying without scanners: %v\n", err)
+					scanners = nil
+				}
+				for _, reason := range scanner.Without(reasons, baseline) {
+					if oracle.IsInterestingReason(reason) {
+						return reason
+					}
+				}
+			}
+		}
+		return ""
+	}, nil
+}
+
+// chartRuns loads the reports in paths, skipping those recorded for charts other than chartName
+func chartRuns(cmd *cobra.Command, chartName string, paths []string) ([]report.Run, error) {
+	loaded, err := report.LoadRuns(paths)
+	if err != nil {
+		return nil, err
+	}
+	var runs []report.Run
+	for _, run := range loaded {
+		if run.Report.Chart != "" && run.Report.Chart != chartName {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Skipping %s: findings are for chart %q\n", run.Name, run.Report.Chart)
+			continue
+		}
+		runs = append(runs, run)
+	}
+	if len(runs) == 0 {
+		return nil, fmt.Errorf("no reports for chart %q found", chartName)
+	}
+	return runs, nil
+}
@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/cli/values"
+	"helm.sh/helm/v3/pkg/getter"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/config"
+	"github.com/kasuboski/helm-fuzzer/pkg/generator"
+	"github.com/kasuboski/helm-fuzzer/pkg/runner"
+	"github.com/kasuboski/helm-fuzzer/pkg/schema"
+)
+
+var validateValueFiles []string
+
+// validateCmd represents the validate command
+var validateCmd = &cobra.Command{
+	Use:   "validate <chart-path>",
+	Short: "Render a chart once with its defaults and check the result",
+	Long: `Check the helm-fuzz setup for a chart without fuzzing: load .helmfuzz.yaml,
+detect the schema, then render the chart with its defaults (merged with any -f
+values files, as helm does) against every configured Kubernetes version and run
+the crash oracle on each result. Useful as a quick pre-commit check.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeCharts(1, cobra.ShellCompDirectiveNoFileComp),
+	RunE:              runValidate,
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+
+	validateCmd.Flags().StringArrayVarP(&validateValueFiles, "values", "f", nil, "Values file to merge over the chart defaults; repeatable, later files win")
+	validateCmd.MarkFlagFilename("values", "yaml", "yml")
+}
+
+func runValidate(cmd *cobra.Command, args []string) error {
+	chartPath, err := filepath.Abs(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to resolve chart path: %w", err)
+	}
+	if _, err := os.Stat(chartPath); os.IsNotExist(err) {
+		return fmt.Errorf("chart path does not exist: %s", chartPath)
+	}
+
+	out := cmd.OutOrStdout()
+	failures := 0
+	check := func(name string, err error, detail string) {
+		if err != nil {
+			failures++
+			fmt.Fprintf(out, "FAIL  %s: %v\n", name, err)
+			return
+		}
+		fmt.Fprintf(out, "ok    %s%s\n", name, detail)
+	}
+
+	cfg, err := loadConfig(chartPath)
+	check("config", err, configDetail(chartPath))
+	if err != nil {
+		return fmt.Errorf("validation failed")
+	}
+	for _, constraint := range cfg.Constraints {
+		if constraint.Template == "" {
+			continue
+		}
+		check("constraint "+constraint.Path, generator.ValidateTemplate(constraint.Template), "")
+	}
+
+	sch, source, err := schema.NewEngine(cfg).Detect(chartPath)
+	detail := ""
+	if err == nil {
+		detail = fmt.Sprintf(" from %s", source)
+		if sch.Type == schema.TypeObject {
+			detail += fmt.Sprintf(" (%d top-level keys)", len(sch.Properties))
+		}
+	}
+	check("schema", err, detail)
+
+	var vals map[string]interface{}
+	validateOpts := values.Options{ValueFiles: validateValueFiles}
+	if err = checkOfflineValues(validateOpts); err == nil {
+		vals, err = validateOpts.MergeValues(getter.All(cli.New()))
+	}
+	if len(validateValueFiles) > 0 {
+		check("values files", err, " "+strings.Join(validateValueFiles, ", "))
+	}
+	if err != nil {
+		return fmt.Errorf("validation failed")
+	}
+
+	oracle := runner.NewOracleWithConfig(cfg.IgnoreErrors, cfg.UninterestingPatterns)
+	for _, kubeVersion := range cfg.KubeVersions {
+		r, err := runner.NewWithKubeVersion(chartPath, kubeVersion)
+		if err != nil {
+			return infraError(fmt.Errorf("failed to create runner: %w", err))
+		}
+		result := r.Run(vals)
+		name := "render (Kubernetes " + kubeVersion + ")"
+		switch {
+		case !oracle.IsCrash(result):
+			check(name, nil, fmt.Sprintf(": %d template(s) rendered", len(result.Templates)))
+		case !oracle.IsInteresting(result):
+			check(name, nil, ": uninteresting error ignored: "+firstLine(oracle.GetCrashReason(result)))
+		default:
+			reason := oracle.GetCrashReason(result)
+			check(name, fmt.Errorf("%s", firstLine(reason)), "")
+			if attr := runner.Attribute(reason); attr != nil {
+				if snippet, err := r.Snippet(attr, vals, 2); err == nil {
+					fmt.Fprintf(out, "      at %s\n%s\n", attr, indent(snippet, "      "))
+				}
+			}
+		}
+	}
+
+	if failures > 0 {
+		return findingsError(fmt.Errorf("validation failed with %d problem(s)", failures))
+	}
+	return nil
+}
+
+// configDetail describes where the chart's config came from
+func configDetail(chartPath string) string {
+	if configFile != "" {
+		return " from " + configFile
+	}
+	if _, err := os.Stat(filepath.Join(chartPath, config.FileName)); err != nil {
+		return " (no " + config.FileName + ", using defaults)"
+	}
+	return " from " + config.FileName
+}
+
+// indent prefixes every line of s
+func indent(s, prefix string) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = prefix + line
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/watch"
+)
+
+var (
+	watchMode       bool
+	watchIterations int
+)
+
+// watchInterval is how often watch mode polls charts for changes
+const watchInterval = 500 * time.Millisecond
+
+// watchCharts runs a quick session for the charts, then again after every
+// change to their templates or values, until interrupted. Crashes and failed
+// sessions are reported but never end the loop.
+func watchCharts(cmd *cobra.Command, runs []*chartRun) error {
+	if !cmd.Flags().Changed("iterations") {
+		iterations = watchIterations
+	}
+
+	charts := make([]string, len(runs))
+	for i, run := range runs {
+		charts[i] = run.chartPath
+	}
+	watcher, err := watch.New(charts, watchInterval)
+	if err != nil {
+		return infraError(fmt.Errorf("failed to watch charts: %w", err))
+	}
+
+	out := cmd.OutOrStdout()
+	for {
+		// Each session starts from a fresh run so no state leaks between them
+		fresh := make([]*chartRun, len(runs))
+		for i, run := range runs {
+			fresh[i] = &chartRun{chartPath: run.chartPath, name: run.name}
+		}
+		if err := runSessions(cmd, fresh, "chart"); err != nil {
+			return err
+		}
+		if len(fresh) == 1 && fresh[0].err != nil {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", fresh[0].err)
+		}
+
+		fmt.Fprintf(out, "\nWatching %s for changes (Ctrl+C to stop)\n", strings.Join(charts, ", "))
+		changed, err := watcher.Wait(context.Background())
+		if err != nil {
+			return infraError(fmt.Errorf("failed to watch charts: %w", err))
+		}
+		fmt.Fprintf(out, "\nChanged: %s\n\n", strings.Join(relativePaths(changed), ", "))
+	}
+}
+
+// relativePaths shortens paths relative to the working directory for display
+func relativePaths(paths []string) []string {
+	wd, _ := os.Getwd()
+	out := make([]string, len(paths))
+	for i, path := range paths {
+		out[i] = path
+		if rel, err := filepath.Rel(wd, path); err == nil && !strings.HasPrefix(rel, "..") {
+			out[i] = rel
+		}
+	}
+	return out
+}
@@ -0,0 +1,268 @@
+// Package argocd reads ArgoCD Applications with Helm sources as fuzz targets:
+// each source's chart is resolved and its valueFiles, values, valuesObject
+// and parameters are merged the way ArgoCD passes them to helm template.
+package argocd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"helm.sh/helm/v3/pkg/registry"
+	"helm.sh/helm/v3/pkg/strvals"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/runner"
+)
+
+// Kind is the kind of ArgoCD Application manifests
+const Kind = "Application"
+
+// Application is the part of an ArgoCD Application that decides how it renders
+type Application struct {
+	Kind     string `yaml:"kind"`
+	Metadata struct {
+		Name string `yaml:"name"`
+	} `yaml:"metadata"`
+	Spec struct {
+		Source      *Source  `yaml:"source"`
+		Sources     []Source `yaml:"sources"`
+		Destination struct {
+			Namespace string `yaml:"namespace"`
+		} `yaml:"destination"`
+	} `yaml:"spec"`
+}
+
+// Source is an Application source: a chart from a Helm repository or OCI
+// registry, or a chart directory in a Git repository
+type Source struct {
+	RepoURL        string `yaml:"repoURL"`
+	Chart          string `yaml:"chart"`
+	Path           string `yaml:"path"`
+	TargetRevision string `yaml:"targetRevision"`
+	// Ref names the source so other sources' valueFiles can use $ref/...
+	Ref  string `yaml:"ref"`
+	Helm *Helm  `yaml:"helm"`
+}
+
+// Helm holds a source's helm options
+type Helm struct {
+	ReleaseName             string                 `yaml:"releaseName"`
+	ValueFiles              []string               `yaml:"valueFiles"`
+	Values                  string                 `yaml:"values"`
+	ValuesObject            map[string]interface{} `yaml:"valuesObject"`
+	Parameters              []Parameter            `yaml:"parameters"`
+	FileParameters          []FileParameter        `yaml:"fileParameters"`
+	IgnoreMissingValueFiles bool                   `yaml:"ignoreMissingValueFiles"`
+}
+
+// Parameter is a --set parameter, or --set-string when ForceString is set
+type Parameter struct {
+	Name        string `yaml:"name"`
+	Value       string `yaml:"value"`
+	ForceString bool   `yaml:"forceString"`
+}
+
+// FileParameter is a --set-file parameter
+type FileParameter struct {
+	Name string `yaml:"name"`
+	Path string `yaml:"path"`
+}
+
+// Target is one Helm source of an Application, ready to fuzz
+type Target struct {
+	Application string
+	// Index is the source's position in spec.sources, or 0 for spec.source
+	Index  int
+	Source Source
+	// ReleaseName and Namespace are what ArgoCD renders the chart as
+	ReleaseName string
+	Namespace   string
+	// refs maps the source names $ref/ value files may use to their repositories
+	refs map[string]string
+}
+
+// Load parses the Applications in the manifest at path and returns their
+// Helm sources. Other documents in the file are ignored.
+func Load(path string) ([]Target, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read application: %w", err)
+	}
+
+	var targets []Target
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var app Application
+		if err := dec.Decode(&app); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("failed to parse application: %w", err)
+		}
+		if app.Kind != Kind {
+			continue
+		}
+		if app.Metadata.Name == "" {
+			return nil, fmt.Errorf("application has no name")
+		}
+		targets = append(targets, app.targets()...)
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("%s has no Application with a Helm source", path)
+	}
+	return targets, nil
+}
+
+// targets returns the Helm sources of the Application
+func (app Application) targets() []Target {
+	sources := app.Spec.Sources
+	if app.Spec.Source != nil {
+		sources = []Source{*app.Spec.Source}
+	}
+
+	refs := make(map[string]string)
+	for _, s := range sources {
+		if s.Ref != "" {
+			refs[s.Ref] = s.RepoURL
+		}
+	}
+
+	var targets []Target
+	for i, s := range sources {
+		// Sources that only provide value files for $ref are not rendered
+		if s.Chart == "" && (s.Path == "" || s.Ref != "" && s.Helm == nil) {
+			continue
+		}
+		t := Target{
+			Application: app.Metadata.Name,
+			Index:       i,
+			Source:      s,
+			// ArgoCD names the release after the Application unless told otherwise
+			ReleaseName: app.Metadata.Name,
+			Namespace:   app.Spec.Destination.Namespace,
+			refs:        refs,
+		}
+		if s.Helm != nil && s.Helm.ReleaseName != "" {
+			t.ReleaseName = s.Helm.ReleaseName
+		}
+		targets = append(targets, t)
+	}
+	return targets
+}
+
+// Chart returns the chart directory of a target. Charts from Helm
+// repositories and OCI registries are downloaded and unpacked into dir; Git
+// sources are read from repoRoot, a local checkout of their repository.
+func (t Target) Chart(dir, repoRoot string) (string, error) {
+	switch {
+	case t.Source.Chart == "":
+		return runner.OpenChart(dir, filepath.Join(repoRoot, t.Source.Path))
+	case registry.IsOCI(t.Source.RepoURL):
+		return runner.PullChart(dir, strings.TrimSuffix(t.Source.RepoURL, "/")+"/"+t.Source.Chart, t.Source.TargetRevision)
+	case !strings.Contains(t.Source.RepoURL, "://"):
+		// ArgoCD treats a repository URL without a scheme as an OCI registry
+		return runner.PullChart(dir, "oci://"+strings.TrimSuffix(t.Source.RepoURL, "/")+"/"+t.Source.Chart, t.Source.TargetRevision)
+	default:
+		return runner.PullRepoChart(dir, t.Source.RepoURL, t.Source.Chart, t.Source.TargetRevision)
+	}
+}
+
+// Values returns the values ArgoCD renders the target with: its valueFiles,
+// then values, then valuesObject, then parameters and fileParameters.
+// Value files are relative to chartPath; $ref/ files are read from repoRoot.
+func (t Target) Values(chartPath, repoRoot string) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+	h := t.Source.Helm
+	if h == nil {
+		return values, nil
+	}
+
+	for _, name := range h.ValueFiles {
+		path, err := t.valueFilePath(name, chartPath, repoRoot)
+		if err != nil {
+			return nil, err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			if os.IsNotExist(err) && h.IgnoreMissingValueFiles {
+				continue
+			}
+			return nil, fmt.Errorf("application %s: failed to read value file: %w", t.Application, err)
+		}
+		file := map[string]interface{}{}
+		if err := yaml.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("application %s: failed to parse value file %s: %w", t.Application, name, err)
+		}
+		values = runner.MergeValues(values, file)
+	}
+
+	if h.Values != "" {
+		inline := map[string]interface{}{}
+		if err := yaml.Unmarshal([]byte(h.Values), &inline); err != nil {
+			return nil, fmt.Errorf("application %s: failed to parse helm.values: %w", t.Application, err)
+		}
+		values = runner.MergeValues(values, inline)
+	}
+	if h.ValuesObject != nil {
+		values = runner.MergeValues(values, h.ValuesObject)
+	}
+
+	for _, p := range h.Parameters {
+		// ArgoCD escapes commas so a parameter always sets a single value
+		set := p.Name + "=" + escapeCommas(p.Value)
+		parse := strvals.ParseInto
+		if p.ForceString {
+			parse = strvals.ParseIntoString
+		}
+		if err := parse(set, values); err != nil {
+			return nil, fmt.Errorf("application %s: invalid parameter %s: %w", t.Application, p.Name, err)
+		}
+	}
+	for _, p := range h.FileParameters {
+		path, err := t.valueFilePath(p.Path, chartPath, repoRoot)
+		if err != nil {
+			return nil, err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("application %s: failed to read file parameter: %w", t.Application, err)
+		}
+		if err := strvals.ParseIntoString(p.Name+"="+escapeCommas(string(data)), values); err != nil {
+			return nil, fmt.Errorf("application %s: invalid file parameter %s: %w", t.Application, p.Name, err)
+		}
+	}
+	return values, nil
+}
+
+// valueFilePath resolves a value file the way ArgoCD does: relative to the
+// chart, or to a referenced source's repository for $ref/ paths
+func (t Target) valueFilePath(name, chartPath, repoRoot string) (string, error) {
+	if strings.HasPrefix(name, "$") {
+		ref, rest, _ := strings.Cut(name[1:], "/")
+		if _, ok := t.refs[ref]; !ok {
+			return "", fmt.Errorf("application %s: value file %s refers to undefined source %s", t.Application, name, ref)
+		}
+		return filepath.Join(repoRoot, rest), nil
+	}
+	if filepath.IsAbs(name) {
+		return name, nil
+	}
+	return filepath.Join(chartPath, name), nil
+}
+
+// escapeCommas escapes the commas of a --set value that are not already escaped
+func escapeCommas(value string) string {
+	var b strings.Builder
+	for i, r := range value {
+		if r == ',' && (i == 0 || value[i-1] != '\\') {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
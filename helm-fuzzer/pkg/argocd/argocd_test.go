@@ -0,0 +1,115 @@
+package argocd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "apps.yaml")
+	writeFile(t, path, `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: unrelated
+---
+apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: web
+spec:
+  destination:
+    namespace: apps
+  source:
+    repoURL: https://charts.example.com
+    chart: web
+    targetRevision: 1.2.3
+---
+apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: api
+spec:
+  destination:
+    namespace: backend
+  sources:
+    - repoURL: https://git.example.com/org/config.git
+      ref: config
+    - repoURL: https://git.example.com/org/charts.git
+      path: charts/api
+      helm:
+        releaseName: api-prod
+`)
+
+	targets, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(targets) != 2 {
+		t.Fatalf("expected the two Helm sources, got %+v", targets)
+	}
+	if web := targets[0]; web.ReleaseName != "web" || web.Namespace != "apps" || web.Source.Chart != "web" {
+		t.Errorf("expected web to render as its Application, got %+v", web)
+	}
+	if api := targets[1]; api.ReleaseName != "api-prod" || api.Namespace != "backend" || api.Index != 1 {
+		t.Errorf("expected api to use its releaseName, got %+v", api)
+	}
+}
+
+func TestValues(t *testing.T) {
+	root := t.TempDir()
+	chartPath := filepath.Join(root, "charts", "api")
+	writeFile(t, filepath.Join(chartPath, "values-prod.yaml"), "replicas: 2\nimage:\n  tag: v1\n  pullPolicy: Always\n")
+	writeFile(t, filepath.Join(root, "env", "prod.yaml"), "replicas: 3\n")
+	writeFile(t, filepath.Join(chartPath, "config.txt"), "a,b")
+
+	target := Target{
+		Application: "api",
+		Source: Source{Path: "charts/api", Helm: &Helm{
+			ValueFiles:              []string{"values-prod.yaml", "$config/env/prod.yaml", "missing.yaml"},
+			IgnoreMissingValueFiles: true,
+			Values:                  "image:\n  tag: v2\n",
+			ValuesObject:            map[string]interface{}{"debug": true},
+			Parameters: []Parameter{
+				{Name: "service.port", Value: "8080"},
+				{Name: "annotations.team", Value: "a,b"},
+				{Name: "image.tag", Value: "3", ForceString: true},
+			},
+			FileParameters: []FileParameter{{Name: "config", Path: "config.txt"}},
+		}},
+		refs: map[string]string{"config": "https://git.example.com/org/config.git"},
+	}
+
+	values, err := target.Values(chartPath, root)
+	if err != nil {
+		t.Fatalf("Values failed: %v", err)
+	}
+	want := map[string]interface{}{
+		"replicas":    3,
+		"image":       map[string]interface{}{"tag": "3", "pullPolicy": "Always"},
+		"debug":       true,
+		"service":     map[string]interface{}{"port": int64(8080)},
+		"annotations": map[string]interface{}{"team": "a,b"},
+		"config":      "a,b",
+	}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("expected values %v, got %v", want, values)
+	}
+
+	target.Source.Helm.IgnoreMissingValueFiles = false
+	if _, err := target.Values(chartPath, root); err == nil {
+		t.Error("expected an error for a missing value file")
+	}
+}
@@ -0,0 +1,152 @@
+// Package baseline records the findings a chart is known to have, so that
+// later sessions fail only on findings that are new. It is the adoption path
+// for legacy charts: accept today's crashes, then stop new ones.
+package baseline
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/report"
+	"github.com/kasuboski/helm-fuzzer/pkg/runner"
+)
+
+const (
+	// FileName is the baseline kept in a chart directory by default
+	FileName = ".helmfuzz-baseline.json"
+	// Kind identifies a baseline document
+	Kind = "Baseline"
+)
+
+// Baseline is the set of accepted findings for a chart
+type Baseline struct {
+	APIVersion string  `json:"apiVersion"`
+	Kind       string  `json:"kind"`
+	Chart      string  `json:"chart"`
+	Findings   []Entry `json:"findings"`
+}
+
+// Entry is an accepted finding, identified by its fingerprint
+type Entry struct {
+	// ID is the finding's ID (see runner.FindingID), for reference
+	ID          string `json:"id"`
+	Fingerprint string `json:"fingerprint"`
+	Category    string `json:"category"`
+	Location    string `json:"location,omitempty"`
+	Reason      string `json:"reason"`
+}
+
+// New creates a baseline accepting the findings, one entry per fingerprint,
+// sorted so the file diffs cleanly when it is re-recorded
+func New(chart string, findings []report.JSONFinding) *Baseline {
+	b := &Baseline{APIVersion: report.JSONAPIVersion, Kind: Kind, Chart: chart, Findings: []Entry{}}
+	seen := make(map[string]bool)
+	for _, f := range findings {
+		fingerprint := runner.Fingerprint(f.Reason)
+		if seen[fingerprint] {
+			continue
+		}
+		seen[fingerprint] = true
+		b.Findings = append(b.Findings, Entry{
+			ID:          runner.FindingID(chart, fingerprint),
+			Fingerprint: fingerprint,
+			Category:    runner.CategorizeReason(f.Reason),
+			Location:    f.Location(),
+			Reason:      f.Reason,
+		})
+	}
+	sort.Slice(b.Findings, func(i, j int) bool {
+		return b.Findings[i].Fingerprint < b.Findings[j].Fingerprint
+	})
+	return b
+}
+
+// Load reads a baseline saved by Save, giving entries recorded before
+// findings had IDs theirs. The error wraps os.ErrNotExist when there is no
+// baseline.
+func Load(path string) (*Baseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baseline: %w", err)
+	}
+
+	var b Baseline
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("failed to parse baseline %s: %w", path, err)
+	}
+	if b.Kind != Kind {
+		return nil, fmt.Errorf("%s is not a baseline (kind %q)", path, b.Kind)
+	}
+	for i, entry := range b.Findings {
+		if entry.ID == "" {
+			b.Findings[i].ID = runner.FindingID(b.Chart, entry.Fingerprint)
+		}
+	}
+	return &b, nil
+}
+
+// Save writes the baseline to path
+func (b *Baseline) Save(path string) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode baseline: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create baseline directory: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write baseline: %w", err)
+	}
+	return nil
+}
+
+// Contains reports whether a crash with this reason is accepted. The
+// fingerprint is recomputed from the reason, so findings saved by older
+// versions match under the current deduplication rules.
+func (b *Baseline) Contains(reason string) bool {
+	fingerprint := runner.Fingerprint(reason)
+	for _, entry := range b.Findings {
+		if entry.Fingerprint == fingerprint || runner.Fingerprint(entry.Reason) == fingerprint {
+			return true
+		}
+	}
+	return false
+}
+
+// Comparison is a set of findings checked against a baseline
+type Comparison struct {
+	// New are findings the baseline does not accept
+	New []report.JSONFinding `json:"new"`
+	// Known are findings the baseline accepts
+	Known []report.JSONFinding `json:"known"`
+	// Resolved are baseline entries none of the findings matched
+	Resolved []Entry `json:"resolved"`
+}
+
+// Compare sorts findings into new and known and lists the baseline entries
+// that were not found again. Findings sharing a fingerprint count once.
+func (b *Baseline) Compare(findings []report.JSONFinding) *Comparison {
+	c := &Comparison{New: []report.JSONFinding{}, Known: []report.JSONFinding{}, Resolved: []Entry{}}
+	found := make(map[string]bool)
+	for _, f := range findings {
+		fingerprint := runner.Fingerprint(f.Reason)
+		if found[fingerprint] {
+			continue
+		}
+		found[fingerprint] = true
+		if b.Contains(f.Reason) {
+			c.Known = append(c.Known, f)
+		} else {
+			c.New = append(c.New, f)
+		}
+	}
+	for _, entry := range b.Findings {
+		if !found[entry.Fingerprint] && !found[runner.Fingerprint(entry.Reason)] {
+			c.Resolved = append(c.Resolved, entry)
+		}
+	}
+	return c
+}
@@ -0,0 +1,83 @@
+package baseline
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/report"
+	"github.com/kasuboski/helm-fuzzer/pkg/runner"
+)
+
+func TestBaseline(t *testing.T) {
+	nilPointer := "Error: template: app/templates/a.yaml:3:4: nil pointer evaluating interface {}.port"
+	wrongType := "Error: template: app/templates/b.yaml:1:2: wrong type for value; expected string; got int"
+	recorded := []report.JSONFinding{
+		{Reason: nilPointer, Template: "app/templates/a.yaml", Line: 3},
+		{Reason: wrongType},
+		// Same fingerprint as the first, at another line
+		{Reason: "Error: template: app/templates/a.yaml:9:4: nil pointer evaluating interface {}.port"},
+	}
+
+	b := New("app", recorded)
+	if len(b.Findings) != 2 {
+		t.Fatalf("expected one entry per fingerprint, got %+v", b.Findings)
+	}
+
+	path := filepath.Join(t.TempDir(), FileName)
+	if err := b.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if loaded.Chart != "app" || len(loaded.Findings) != 2 {
+		t.Errorf("expected the saved baseline back, got %+v", loaded)
+	}
+	for _, entry := range loaded.Findings {
+		if entry.ID != runner.FindingID("app", entry.Fingerprint) {
+			t.Errorf("expected entry %s to have its finding ID, got %q", entry.Fingerprint, entry.ID)
+		}
+	}
+
+	parse := "Error: parse error at line 7"
+	c := loaded.Compare([]report.JSONFinding{
+		{Reason: "Error: template: app/templates/a.yaml:12:4: nil pointer evaluating interface {}.port"},
+		{Reason: parse},
+		{Reason: parse},
+	})
+	if len(c.Known) != 1 || c.Known[0].Reason == nilPointer {
+		t.Errorf("expected the moved nil pointer to be known, got %+v", c.Known)
+	}
+	if len(c.New) != 1 || c.New[0].Reason != parse {
+		t.Errorf("expected the parse error to be new once, got %+v", c.New)
+	}
+	if len(c.Resolved) != 1 || c.Resolved[0].Reason != wrongType {
+		t.Errorf("expected the wrong type to be resolved, got %+v", c.Resolved)
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := Load(filepath.Join(dir, FileName)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected a missing baseline to wrap os.ErrNotExist, got %v", err)
+	}
+
+	path := filepath.Join(dir, "report.json")
+	if err := os.WriteFile(path, []byte(`{"kind": "Report"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("expected a report to be rejected as a baseline")
+	}
+
+	// Baselines recorded before findings had IDs are given them
+	if err := os.WriteFile(path, []byte(`{"kind": "Baseline", "chart": "app", "findings": [{"fingerprint": "0123456789abcdef"}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := Load(path); err != nil || b.Findings[0].ID != "app-FZ-01234567" {
+		t.Errorf("expected the entry to be given its ID, got %+v, %v", b, err)
+	}
+}
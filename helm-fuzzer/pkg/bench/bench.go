@@ -0,0 +1,210 @@
+// Package bench measures how long a chart takes to render across generated
+// inputs, so template performance regressions show up before they reach a
+// cluster's release pipeline.
+package bench
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/fuzz"
+	"github.com/kasuboski/helm-fuzzer/pkg/runner"
+)
+
+// Formats lists the output formats Write supports
+var Formats = []string{"text", "json"}
+
+// Options configures a benchmark
+type Options struct {
+	// Iterations is the number of timed renders (default: 200)
+	Iterations int
+	// Warmup is the number of untimed renders of the first input, so chart
+	// loading and caches settle before timing starts (default: 3; negative disables)
+	Warmup int
+	// Slowest is the number of slowest inputs kept with their values (default: 5)
+	Slowest int
+}
+
+// Sample is one timed render
+type Sample struct {
+	Iteration   int                    `json:"iteration"`
+	KubeVersion string                 `json:"kubeVersion"`
+	Duration    time.Duration          `json:"durationNanos"`
+	Crashed     bool                   `json:"crashed"`
+	Values      map[string]interface{} `json:"values"`
+}
+
+// Summary is the latency distribution of a benchmark
+type Summary struct {
+	Chart   string        `json:"chart"`
+	Renders int           `json:"renders"`
+	Crashes int           `json:"crashes"`
+	Min     time.Duration `json:"minNanos"`
+	Mean    time.Duration `json:"meanNanos"`
+	P50     time.Duration `json:"p50Nanos"`
+	P95     time.Duration `json:"p95Nanos"`
+	P99     time.Duration `json:"p99Nanos"`
+	Max     time.Duration `json:"maxNanos"`
+	// Slowest are the slowest renders, slowest first
+	Slowest []Sample `json:"slowest"`
+}
+
+// Run renders the session's inputs one at a time, rotating through its
+// Kubernetes versions the way fuzzing does, and summarizes the latencies.
+// Renders run sequentially so workers do not compete for the CPU being
+// measured. A canceled ctx summarizes the renders completed so far.
+func Run(ctx context.Context, chartPath string, session *fuzz.Session, opts Options) (*Summary, error) {
+	if opts.Iterations <= 0 {
+		opts.Iterations = 200
+	}
+	if opts.Warmup < 0 {
+		opts.Warmup = 0
+	} else if opts.Warmup == 0 {
+		opts.Warmup = 3
+	}
+	if opts.Slowest <= 0 {
+		opts.Slowest = 5
+	}
+
+	cfg := session.Config()
+	runners := make([]*runner.Runner, 0, len(cfg.KubeVersions))
+	for _, kubeVersion := range cfg.KubeVersions {
+		r, err := runner.NewWithKubeVersion(chartPath, kubeVersion)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create runner: %w", err)
+		}
+		runners = append(runners, r)
+	}
+	oracle := runner.NewOracleWithConfig(cfg.IgnoreErrors, cfg.UninterestingPatterns)
+
+	for i := 0; i < opts.Warmup; i++ {
+		runners[0].Run(session.Input(0))
+	}
+
+	samples := make([]Sample, 0, opts.Iterations)
+	for i := 0; i < opts.Iterations && ctx.Err() == nil; i++ {
+		values := session.Input(i)
+		r := runners[i%len(runners)]
+
+		started := time.Now()
+		res := r.Run(values)
+		elapsed := time.Since(started)
+
+		samples = append(samples, Sample{
+			Iteration:   i + 1,
+			KubeVersion: cfg.KubeVersions[i%len(runners)],
+			Duration:    elapsed,
+			Crashed:     oracle.IsCrash(res),
+			Values:      values,
+		})
+	}
+
+	summary := Summarize(samples, opts.Slowest)
+	summary.Chart = filepath.Base(chartPath)
+	return &summary, nil
+}
+
+// Summarize computes the latency distribution of samples, keeping the
+// slowest n. Percentiles use the nearest-rank method.
+func Summarize(samples []Sample, n int) Summary {
+	s := Summary{Renders: len(samples), Slowest: []Sample{}}
+	if len(samples) == 0 {
+		return s
+	}
+
+	sorted := append([]Sample(nil), samples...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Duration < sorted[j].Duration
+	})
+
+	var total time.Duration
+	for _, sample := range sorted {
+		total += sample.Duration
+		if sample.Crashed {
+			s.Crashes++
+		}
+	}
+	s.Min = sorted[0].Duration
+	s.Max = sorted[len(sorted)-1].Duration
+	s.Mean = total / time.Duration(len(sorted))
+	s.P50 = percentile(sorted, 50)
+	s.P95 = percentile(sorted, 95)
+	s.P99 = percentile(sorted, 99)
+
+	for i := len(sorted) - 1; i >= 0 && len(s.Slowest) < n; i-- {
+		s.Slowest = append(s.Slowest, sorted[i])
+	}
+	return s
+}
+
+// percentile returns the nearest-rank percentile p of samples sorted by duration
+func percentile(sorted []Sample, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1].Duration
+}
+
+// Write renders a summary in the given format
+func Write(w io.Writer, format string, s *Summary) error {
+	switch format {
+	case "text":
+		return WriteText(w, s)
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(s)
+	default:
+		return fmt.Errorf("unsupported format %q (supported: %s)", format, strings.Join(Formats, ", "))
+	}
+}
+
+// WriteText prints the distribution and the slowest inputs with their values
+func WriteText(w io.Writer, s *Summary) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Rendered %s %d time(s), %d crashed\n\n", s.Chart, s.Renders, s.Crashes)
+	fmt.Fprintf(&b, "  min   %s\n  mean  %s\n  p50   %s\n  p95   %s\n  p99   %s\n  max   %s\n",
+		round(s.Min), round(s.Mean), round(s.P50), round(s.P95), round(s.P99), round(s.Max))
+
+	if len(s.Slowest) > 0 {
+		b.WriteString("\nSlowest inputs:\n")
+	}
+	for i, sample := range s.Slowest {
+		status := ""
+		if sample.Crashed {
+			status = ", crashed"
+		}
+		fmt.Fprintf(&b, "\n%d. %s (iteration %d, Kubernetes %s%s)\n", i+1, round(sample.Duration), sample.Iteration, sample.KubeVersion, status)
+		data, err := yaml.Marshal(sample.Values)
+		if err != nil {
+			return fmt.Errorf("failed to encode values: %w", err)
+		}
+		for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+			fmt.Fprintf(&b, "   %s\n", line)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// round shortens a duration for display
+func round(d time.Duration) time.Duration {
+	switch {
+	case d >= time.Second:
+		return d.Round(time.Millisecond)
+	case d >= time.Millisecond:
+		return d.Round(10 * time.Microsecond)
+	default:
+		return d.Round(time.Microsecond)
+	}
+}
@@ -0,0 +1,75 @@
+package bench
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/config"
+	"github.com/kasuboski/helm-fuzzer/pkg/fuzz"
+)
+
+func TestSummarize(t *testing.T) {
+	var samples []Sample
+	for i := 1; i <= 100; i++ {
+		samples = append(samples, Sample{Iteration: i, Duration: time.Duration(i) * time.Millisecond, Crashed: i%10 == 0})
+	}
+
+	s := Summarize(samples, 3)
+	if s.Renders != 100 || s.Crashes != 10 {
+		t.Errorf("expected 100 renders and 10 crashes, got %d and %d", s.Renders, s.Crashes)
+	}
+	want := map[string][2]time.Duration{
+		"min":  {s.Min, time.Millisecond},
+		"p50":  {s.P50, 50 * time.Millisecond},
+		"p95":  {s.P95, 95 * time.Millisecond},
+		"p99":  {s.P99, 99 * time.Millisecond},
+		"max":  {s.Max, 100 * time.Millisecond},
+		"mean": {s.Mean, 50500 * time.Microsecond},
+	}
+	for name, got := range want {
+		if got[0] != got[1] {
+			t.Errorf("expected %s %s, got %s", name, got[1], got[0])
+		}
+	}
+	if len(s.Slowest) != 3 || s.Slowest[0].Iteration != 100 || s.Slowest[2].Iteration != 98 {
+		t.Errorf("expected the three slowest inputs, slowest first, got %+v", s.Slowest)
+	}
+
+	if empty := Summarize(nil, 3); empty.Renders != 0 || empty.Slowest == nil {
+		t.Errorf("expected an empty summary, got %+v", empty)
+	}
+}
+
+func TestRun(t *testing.T) {
+	chartPath, err := filepath.Abs("../../testdata/buggy-chart")
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err := fuzz.NewWithOptions(chartPath, fuzz.Options{Config: config.DefaultConfig()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := Run(context.Background(), chartPath, session, Options{Iterations: 20, Warmup: -1, Slowest: 2})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if s.Chart != "buggy-chart" || s.Renders != 20 || len(s.Slowest) != 2 {
+		t.Errorf("expected 20 renders of buggy-chart with the 2 slowest kept, got %+v", s)
+	}
+	if s.Min <= 0 || s.Min > s.P50 || s.P50 > s.P99 || s.P99 > s.Max {
+		t.Errorf("expected ordered percentiles, got %+v", s)
+	}
+
+	var out bytes.Buffer
+	if err := Write(&out, "text", s); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "p95") || !strings.Contains(out.String(), "Slowest inputs") {
+		t.Errorf("expected the distribution and slowest inputs, got:\n%s", out.String())
+	}
+}
@@ -1,14 +1,25 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/quantity"
 )
 
 // Config represents the .helmfuzz.yaml configuration file
 type Config struct {
+	// APIVersion is the config format version (default: the current version)
+	APIVersion string `yaml:"apiVersion,omitempty"`
 	// Ignore lists JSON paths to skip during fuzzing
 	Ignore []string `yaml:"ignore"`
 	// Constraints defines value constraints for specific paths
@@ -17,54 +28,372 @@ type Config struct {
 	MaxDepth int `yaml:"maxDepth"`
 	// Iterations number of fuzz iterations (default: 1000)
 	Iterations int `yaml:"iterations"`
+	// Seed is the session seed every iteration's inputs are derived from, so
+	// a session is reproduced from it whatever its workers (default: 0, which
+	// derives them from the iteration index alone; helm fuzz draws a fresh
+	// seed for each session instead)
+	Seed uint64 `yaml:"seed,omitempty"`
 	// IgnoreErrors lists error message patterns to ignore during crash detection
 	IgnoreErrors []string `yaml:"ignoreErrors,omitempty"`
 	// UninterestingPatterns lists error patterns considered uninteresting
 	UninterestingPatterns []string `yaml:"uninterestingPatterns,omitempty"`
+	// Replays is how many times each new unique crash is replayed before it
+	// is reported; crashes some replays do not reproduce are reported as
+	// flaky and do not fail the session (default: 2, 0 disables)
+	Replays int `yaml:"replays"`
+	// RenderTimeout cuts short renders taking longer, such as a range over a
+	// range of huge fuzzed counts, as findings naming the template they were
+	// stuck in (default: 0, no timeout)
+	RenderTimeout time.Duration `yaml:"renderTimeout,omitempty"`
+	// Suppress lists the IDs of findings never reported, such as
+	// my-chart-FZ-3fa9c2d1, for crashes tracked elsewhere or accepted
+	Suppress []string `yaml:"suppress,omitempty"`
 	// KubeVersions lists Kubernetes versions to test against (default: ["1.28.0", "1.29.0", "1.30.0", "1.31.0"])
 	KubeVersions []string `yaml:"kubeVersions,omitempty"`
+	// Seeds lists inline values documents rendered before any generated input
+	Seeds []map[string]interface{} `yaml:"seeds,omitempty"`
+	// Lookup lists the objects the lookup function finds, as if in a cluster;
+	// each iteration finds every one of them or not at random
+	Lookup []map[string]interface{} `yaml:"lookup,omitempty"`
+	// Files are virtual chart files templates read with .Files; each
+	// iteration renders without them or with one of their edge-case contents
+	Files []File `yaml:"files,omitempty"`
+	// Budget caps the cluster resources a render requests; a render over it
+	// is a finding
+	Budget *Budget `yaml:"budget,omitempty"`
+	// SlowRender flags renders taking far longer than the median render as
+	// findings, such as nested ranges over fuzzed lists
+	SlowRender *SlowRender `yaml:"slowRender,omitempty"`
+	// DefaultsDiff flags renders straying far from the render of the chart's
+	// defaults as findings, such as a list value multiplying objects
+	DefaultsDiff *DefaultsDiff `yaml:"defaultsDiff,omitempty"`
+	// YAML11 flags strings of the values, such as "no" or 1:30, that render
+	// unquoted where YAML 1.1 or 1.2 parsers read another type as findings
+	YAML11 bool `yaml:"yaml11,omitempty"`
+	// LargeNumbers flags integers beyond 2^53 and floats in scientific
+	// notation that render rounded or as 1e+09 as findings
+	LargeNumbers bool `yaml:"largeNumbers,omitempty"`
+	// Unicode flags names and labels with invalid UTF-8, or over 63 bytes in
+	// 63 characters, as findings
+	Unicode bool `yaml:"unicode,omitempty"`
+	// MemoryGrowth flags a live heap that keeps growing over a session as a
+	// finding, blamed on the templates rendered while it grew
+	MemoryGrowth *MemoryGrowth `yaml:"memoryGrowth,omitempty"`
+	// KeepOutput is how much of a finding's rendered output is saved beside
+	// its reproduction file: "full" (default), "none", or a size such as
+	// "64Ki" to truncate it to
+	KeepOutput string `yaml:"keepOutput,omitempty"`
+	// Retention bounds the reproduction files and session logs the output
+	// directory accumulates over sessions
+	Retention *Retention `yaml:"retention,omitempty"`
+	// CorpusDir is a directory of values files replayed as seeds (relative to the config file)
+	CorpusDir string `yaml:"corpusDir,omitempty"`
+	// Snapshots are named values documents whose rendered manifests are recorded and verified
+	Snapshots map[string]map[string]interface{} `yaml:"snapshots,omitempty"`
+	// Scenarios are named property tests run by the scenarios command
+	Scenarios map[string]Scenario `yaml:"scenarios,omitempty"`
+	// SnapshotDir holds recorded snapshots (relative to the config file, default: __snapshots__)
+	SnapshotDir string `yaml:"snapshotDir,omitempty"`
+	// Workers is the number of concurrent fuzzing workers (default: 1)
+	Workers int `yaml:"workers,omitempty"`
+	// CPUThrottle caps each worker's busy time as a percentage of wall time (0 disables)
+	CPUThrottle int `yaml:"cpuThrottle,omitempty"`
+	// MaxRate caps the iterations per second of all workers together (0 disables)
+	MaxRate float64 `yaml:"maxRate,omitempty"`
+	// MaxTotalValuesSize caps the YAML size of each generated values document in bytes (0 = unlimited)
+	MaxTotalValuesSize int `yaml:"maxTotalValuesSize,omitempty"`
+	// MaxKeysPerObject caps the number of keys generated per object (0 = unlimited)
+	MaxKeysPerObject int `yaml:"maxKeysPerObject,omitempty"`
+	// Webhooks are notified of each new unique crash
+	Webhooks []Webhook `yaml:"webhooks,omitempty"`
+	// OnCrash runs a command for each new unique crash
+	OnCrash *CrashCommand `yaml:"onCrash,omitempty"`
+	// Tracing exports spans of each session to an OpenTelemetry collector
+	Tracing *Tracing `yaml:"tracing,omitempty"`
+	// Plugins are external programs that check rendered output or supply inputs
+	Plugins []Plugin `yaml:"plugins,omitempty"`
+	// Scanners are external security scanners run on every successful render
+	Scanners []Scanner `yaml:"scanners,omitempty"`
+	// Envtest configures the local API servers renders are validated against
+	Envtest Envtest `yaml:"envtest,omitempty"`
+	// Profiles are named sets of overrides for these settings, selected with WithProfile
+	Profiles map[string]yaml.Node `yaml:"profiles,omitempty"`
+
+	// baseDir is the directory of the file the config was loaded from
+	baseDir string
+}
+
+// Webhook defines an endpoint notified of new unique crashes
+type Webhook struct {
+	// URL receives a POST per finding; ${VAR} references are expanded from the environment
+	URL string `yaml:"url"`
+	// Format is the payload format: "json" (default) or "slack"
+	Format string `yaml:"format,omitempty"`
+}
+
+// Retention bounds the artifacts of the output directory, applied when a
+// session starts and again when it ends; unset fields impose no bound
+type Retention struct {
+	// MaxReproFiles keeps this many reproduction files, the most recently written
+	MaxReproFiles int `yaml:"maxReproFiles,omitempty"`
+	// MaxSize caps the total size of the output directory's artifacts, such
+	// as 500Mi, removing the oldest first
+	MaxSize string `yaml:"maxSize,omitempty"`
+	// MaxAge removes artifacts not written for longer, such as 168h
+	MaxAge time.Duration `yaml:"maxAge,omitempty"`
+}
+
+// MaxBytes returns the retention's size cap in bytes, 0 for none
+func (r *Retention) MaxBytes() int64 {
+	if r.MaxSize == "" {
+		return 0
+	}
+	// A size that does not parse, which loading the config rejects, caps nothing
+	v, _ := quantity.Parse(r.MaxSize)
+	return int64(v)
+}
+
+// Tracing configures the OTLP/HTTP endpoint a session's spans are exported to
+type Tracing struct {
+	// Endpoint is the collector's base URL, to which /v1/traces is added;
+	// ${VAR} references are expanded from the environment (default:
+	// $OTEL_EXPORTER_OTLP_ENDPOINT, or http://localhost:4318)
+	Endpoint string `yaml:"endpoint,omitempty"`
+	// Headers are sent with each export, such as an API key; values expand
+	// ${VAR} references like the endpoint
+	Headers map[string]string `yaml:"headers,omitempty"`
+	// ServiceName is the service.name of the spans (default: helm-fuzz)
+	ServiceName string `yaml:"serviceName,omitempty"`
+	// IterationSample traces one iteration in this many (default: 100)
+	IterationSample int `yaml:"iterationSample,omitempty"`
+}
+
+// DefaultCrashCommandTimeout bounds each run of the onCrash command when its
+// timeout is unset
+const DefaultCrashCommandTimeout = 30 * time.Second
+
+// CrashCommand defines a program run for each new unique crash, which is
+// given the finding in HELMFUZZ_FINDING_* environment variables and as the
+// webhook's JSON payload on stdin
+type CrashCommand struct {
+	// Command is the program and its arguments, resolved like a plugin's
+	Command []string `yaml:"command"`
+	// Timeout bounds each run (default: 30s)
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+}
+
+// Plugin types
+const (
+	// PluginOracle checks every render and reports findings of its own
+	PluginOracle = "oracle"
+	// PluginGenerator supplies inputs rendered after the seeds
+	PluginGenerator = "generator"
+)
+
+// Plugin defines an external program spoken to over the plugin protocol
+type Plugin struct {
+	// Name identifies the plugin in findings and logs
+	Name string `yaml:"name"`
+	// Type is "oracle" or "generator"
+	Type string `yaml:"type"`
+	// Command is the program and its arguments; a relative program path
+	// containing a slash resolves against the config file's directory
+	Command []string `yaml:"command"`
+	// Timeout bounds each invocation (default: 10s)
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+	// Inputs is how many inputs a generator is asked for (default: 100)
+	Inputs int `yaml:"inputs,omitempty"`
+}
+
+// Scanner formats
+const (
+	ScannerTrivy     = "trivy"
+	ScannerCheckov   = "checkov"
+	ScannerKubeScore = "kube-score"
+)
+
+// Scanner defines an existing security scanner whose JSON output is read
+// into findings, such as trivy config, checkov or kube-score
+type Scanner struct {
+	// Name identifies the scanner in findings and logs
+	Name string `yaml:"name"`
+	// Format is the scanner's JSON output: "trivy", "checkov" or "kube-score"
+	Format string `yaml:"format"`
+	// Command is the program and its arguments, resolved like a plugin's. A
+	// "{manifest}" argument is replaced by the path of a file holding the
+	// render, which is otherwise piped to stdin.
+	Command []string `yaml:"command"`
+	// Timeout bounds each scan (default: 30s)
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+	// Ignore lists check IDs never reported
+	Ignore []string `yaml:"ignore,omitempty"`
+}
+
+// Envtest locates the etcd and kube-apiserver binaries started to dry-run
+// rendered objects against a real API server
+type Envtest struct {
+	// Assets is a directory holding the binaries, or a directory of them per
+	// Kubernetes version as setup-envtest installs them (default: $KUBEBUILDER_ASSETS)
+	Assets string `yaml:"assets,omitempty"`
+	// StartTimeout bounds how long each API server may take to become ready (default: 1m)
+	StartTimeout time.Duration `yaml:"startTimeout,omitempty"`
+}
+
+// Scenario is a property test: a values fragment pinned in every input and
+// invariants every successful render must hold
+type Scenario struct {
+	// Values override the same keys of every input, like --set
+	Values map[string]interface{} `yaml:"values,omitempty"`
+	// Iterations is the scenario's budget (default: iterations)
+	Iterations int `yaml:"iterations,omitempty"`
+	// Invariants are checked against each successful render
+	Invariants []Invariant `yaml:"invariants,omitempty"`
+}
+
+// Invariant bounds how many rendered resources match a kind, and optionally
+// an apiVersion and name. Without bounds at least one must render.
+type Invariant struct {
+	Kind       string `yaml:"kind"`
+	APIVersion string `yaml:"apiVersion,omitempty"`
+	Name       string `yaml:"name,omitempty"`
+	// Min and Max bound the number of matching resources
+	Min *int `yaml:"min,omitempty"`
+	Max *int `yaml:"max,omitempty"`
+}
+
+// Bounds returns the least and most matching resources the invariant
+// allows; max is -1 when unbounded
+func (i Invariant) Bounds() (int, int) {
+	lo, hi := 0, -1
+	if i.Min != nil {
+		lo = *i.Min
+	}
+	if i.Max != nil {
+		hi = *i.Max
+	}
+	if i.Min == nil && i.Max == nil {
+		lo = 1
+	}
+	return lo, hi
+}
+
+// File is a virtual chart file, added to or replacing one of the chart's own
+type File struct {
+	// Path is relative to the chart directory, as .Files.Get takes it
+	Path string `yaml:"path"`
+	// Content is the file's usual content; without one, contents are generated
+	Content *string `yaml:"content,omitempty"`
+}
+
+// Budget caps the resources requested by all of a render's workloads
+// together, each workload's pod requests multiplied by its replicas. Unset
+// caps are not checked.
+type Budget struct {
+	// CPU and Memory cap the total requests, as Kubernetes quantities such as 16 or 64Gi
+	CPU    string `yaml:"cpu,omitempty"`
+	Memory string `yaml:"memory,omitempty"`
+	// Pods caps the total replicas
+	Pods int `yaml:"pods,omitempty"`
+}
+
+// SlowRender bounds a render's time relative to the median of the session's
+// successful renders
+type SlowRender struct {
+	// Factor is how many times the median render time a render may take (default: 10)
+	Factor float64 `yaml:"factor,omitempty"`
+	// Min is the render time no render below counts as slow, so scheduling
+	// noise on a chart that renders in microseconds is not flagged (default: 100ms)
+	Min time.Duration `yaml:"min,omitempty"`
+}
+
+// DefaultsDiff bounds how far a render may stray from the render of the
+// chart's defaults, with pinned values applied
+type DefaultsDiff struct {
+	// SizeRatio is how many times the size of the defaults' manifest a
+	// render's may be (default: 10)
+	SizeRatio float64 `yaml:"sizeRatio,omitempty"`
+	// ObjectRatio is how many times as many objects as the defaults render a
+	// render may have (0 disables)
+	ObjectRatio float64 `yaml:"objectRatio,omitempty"`
+	// Keep lists kinds of the defaults' objects, such as Deployment, that
+	// every render must still have one of
+	Keep []string `yaml:"keep,omitempty"`
+}
+
+// MemoryGrowth bounds how the session's live heap may grow, sampled after a
+// garbage collection every interval iterations
+type MemoryGrowth struct {
+	// Interval is how many iterations are rendered between samples (default: 500)
+	Interval int `yaml:"interval,omitempty"`
+	// Samples is how many samples in a row the heap must grow in (default: 5)
+	Samples int `yaml:"samples,omitempty"`
+	// Min is how much the heap must grow over those samples, as a quantity
+	// such as 64Mi, so caches filling up are not flagged (default: 64Mi)
+	Min string `yaml:"min,omitempty"`
 }
 
 // Constraint defines constraints for a specific value path
 type Constraint struct {
 	// Path is the JSON path (e.g., "service.port")
-	Path string `yaml:"path"`
+	Path string `yaml:"path" json:"path"`
 	// Type is the value type ("int", "string", "bool", etc.)
-	Type string `yaml:"type"`
+	Type string `yaml:"type" json:"type"`
 	// Min is the minimum value for numeric types
-	Min *int `yaml:"min,omitempty"`
+	Min *int `yaml:"min,omitempty" json:"min,omitempty"`
 	// Max is the maximum value for numeric types
-	Max *int `yaml:"max,omitempty"`
+	Max *int `yaml:"max,omitempty" json:"max,omitempty"`
 	// Pattern is a regex pattern for string types
-	Pattern string `yaml:"pattern,omitempty"`
+	Pattern string `yaml:"pattern,omitempty" json:"pattern,omitempty"`
 	// Enum lists allowed values
-	Enum []interface{} `yaml:"enum,omitempty"`
+	Enum []interface{} `yaml:"enum,omitempty" json:"enum,omitempty"`
 	// Required indicates if this field must be present
-	Required bool `yaml:"required,omitempty"`
+	Required bool `yaml:"required,omitempty" json:"required,omitempty"`
+	// Template is a Go template producing the value (e.g., "https://{{ hostname }}/api")
+	Template string `yaml:"template,omitempty" json:"template,omitempty"`
 }
 
 // DefaultConfig returns a config with sensible defaults
 func DefaultConfig() *Config {
 	return &Config{
+		APIVersion:   CurrentAPIVersion,
 		Ignore:       []string{},
 		Constraints:  []Constraint{},
 		MaxDepth:     5,
 		Iterations:   1000,
 		KubeVersions: []string{"1.28.0", "1.29.0", "1.30.0", "1.31.0"},
+		Workers:      1,
+		Replays:      2,
+		Envtest:      Envtest{StartTimeout: time.Minute},
 	}
 }
 
+// FileName is the name of the config file looked up in a chart directory
+const FileName = ".helmfuzz.yaml"
+
 // LoadConfig loads configuration from a .helmfuzz.yaml file
 // If the file doesn't exist, returns default config
 func LoadConfig(chartPath string) (*Config, error) {
-	configPath := filepath.Join(chartPath, ".helmfuzz.yaml")
+	configPath := filepath.Join(chartPath, FileName)
 
 	// Check if config file exists
 	if _, err := os.Stat(configPath); os.IsNotExist(err) {
 		return DefaultConfig(), nil
 	}
 
-	data, err := os.ReadFile(configPath)
+	return LoadConfigFile(configPath)
+}
+
+// LoadConfigFile loads configuration from a file at any path, such as a
+// config kept outside the chart. Relative paths in the config resolve
+// against the file's directory.
+func LoadConfigFile(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Upgrade older config formats before decoding
+	data, err = migrate(data)
 	if err != nil {
 		return nil, err
 	}
@@ -73,19 +402,342 @@ func LoadConfig(chartPath string) (*Config, error) {
 	if err := yaml.Unmarshal(data, config); err != nil {
 		return nil, err
 	}
+	if err := config.normalize(); err != nil {
+		return nil, err
+	}
+	if config.baseDir, err = filepath.Abs(filepath.Dir(path)); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// WithProfile returns a copy of the config with the named profile's settings applied
+func (c *Config) WithProfile(name string) (*Config, error) {
+	node, ok := c.Profiles[name]
+	if !ok {
+		names := make([]string, 0, len(c.Profiles))
+		for n := range c.Profiles {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown profile %q (available: %s)", name, strings.Join(names, ", "))
+	}
+
+	profiled := *c
+	profiled.Profiles = nil
+	if err := node.Decode(&profiled); err != nil {
+		return nil, fmt.Errorf("invalid profile %q: %w", name, err)
+	}
+	if err := profiled.normalize(); err != nil {
+		return nil, fmt.Errorf("invalid profile %q: %w", name, err)
+	}
+	profiled.Profiles = c.Profiles
+	return &profiled, nil
+}
+
+// SetThrottle replaces the config's pacing with a throttle spec: a busy
+// percentage such as 50%, setting CPUThrottle, or a rate such as 20/s,
+// setting MaxRate
+func (c *Config) SetThrottle(spec string) error {
+	if percent, ok := strings.CutSuffix(spec, "%"); ok {
+		n, err := strconv.Atoi(percent)
+		if err != nil || n < 1 || n > 100 {
+			return fmt.Errorf("invalid throttle %q: the percentage must be between 1 and 100", spec)
+		}
+		c.CPUThrottle, c.MaxRate = n, 0
+		return nil
+	}
+	if rate, ok := strings.CutSuffix(spec, "/s"); ok {
+		r, err := strconv.ParseFloat(rate, 64)
+		if err != nil || r <= 0 {
+			return fmt.Errorf("invalid throttle %q: the rate must be a positive number of iterations per second", spec)
+		}
+		c.CPUThrottle, c.MaxRate = 0, r
+		return nil
+	}
+	return fmt.Errorf("invalid throttle %q: expected a CPU percentage such as 50%% or a rate such as 20/s", spec)
+}
+
+// KeepOutput settings besides a size
+const (
+	KeepOutputFull = "full"
+	KeepOutputNone = "none"
+)
+
+// OutputLimit returns the bytes of rendered output kept per finding: -1 for
+// all of it, 0 for none
+func (c *Config) OutputLimit() int64 {
+	switch c.KeepOutput {
+	case "", KeepOutputFull:
+		return -1
+	case KeepOutputNone:
+		return 0
+	}
+	// A size that does not parse, which loading the config rejects, keeps none
+	v, _ := quantity.Parse(c.KeepOutput)
+	return int64(v)
+}
+
+// SetOnCrash replaces the config's onCrash command with a shell command,
+// run with sh -c, keeping a configured timeout
+func (c *Config) SetOnCrash(command string) {
+	timeout := DefaultCrashCommandTimeout
+	if c.OnCrash != nil {
+		timeout = c.OnCrash.Timeout
+	}
+	c.OnCrash = &CrashCommand{Command: []string{"sh", "-c", command}, Timeout: timeout}
+}
+
+// snapshotName matches snapshot names, which name files
+var snapshotName = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9._-]*$`)
 
+// normalize applies defaults for unset fields and validates the rest
+func (c *Config) normalize() error {
 	// Apply defaults if not set
-	if config.MaxDepth == 0 {
-		config.MaxDepth = 5
+	if c.MaxDepth == 0 {
+		c.MaxDepth = 5
+	}
+	if c.Iterations == 0 {
+		c.Iterations = 1000
+	}
+	if len(c.KubeVersions) == 0 {
+		c.KubeVersions = []string{"1.28.0", "1.29.0", "1.30.0", "1.31.0"}
+	}
+	if c.Workers <= 0 {
+		c.Workers = 1
+	}
+	if c.CPUThrottle < 0 || c.CPUThrottle > 100 {
+		return fmt.Errorf("cpuThrottle must be between 0 and 100, got %d", c.CPUThrottle)
 	}
-	if config.Iterations == 0 {
-		config.Iterations = 1000
+	if c.MaxRate < 0 {
+		return fmt.Errorf("maxRate must not be negative, got %g", c.MaxRate)
 	}
-	if len(config.KubeVersions) == 0 {
-		config.KubeVersions = []string{"1.28.0", "1.29.0", "1.30.0", "1.31.0"}
+	if c.Replays < 0 {
+		return fmt.Errorf("replays must not be negative, got %d", c.Replays)
+	}
+	if c.RenderTimeout < 0 {
+		return fmt.Errorf("renderTimeout must not be negative, got %s", c.RenderTimeout)
+	}
+	if c.MaxTotalValuesSize < 0 {
+		return fmt.Errorf("maxTotalValuesSize must not be negative, got %d", c.MaxTotalValuesSize)
+	}
+	if c.MaxKeysPerObject < 0 {
+		return fmt.Errorf("maxKeysPerObject must not be negative, got %d", c.MaxKeysPerObject)
+	}
+	for name := range c.Snapshots {
+		if !snapshotName.MatchString(name) {
+			return fmt.Errorf("snapshot name %q must only contain letters, digits, '.', '_' and '-'", name)
+		}
+	}
+	for name, scenario := range c.Scenarios {
+		if !snapshotName.MatchString(name) {
+			return fmt.Errorf("scenario name %q must only contain letters, digits, '.', '_' and '-'", name)
+		}
+		if scenario.Iterations < 0 {
+			return fmt.Errorf("scenario %q must not have negative iterations", name)
+		}
+		for i, invariant := range scenario.Invariants {
+			if invariant.Kind == "" {
+				return fmt.Errorf("invariant %d of scenario %q has no kind", i, name)
+			}
+			lo, hi := invariant.Bounds()
+			if lo < 0 || (invariant.Max != nil && hi < lo) {
+				return fmt.Errorf("invariant %d of scenario %q needs 0 <= min <= max", i, name)
+			}
+		}
+	}
+	for i, obj := range c.Lookup {
+		apiVersion, _ := obj["apiVersion"].(string)
+		kind, _ := obj["kind"].(string)
+		metadata, _ := obj["metadata"].(map[string]interface{})
+		name, _ := metadata["name"].(string)
+		if apiVersion == "" || kind == "" || name == "" {
+			return fmt.Errorf("lookup object %d needs apiVersion, kind and metadata.name", i)
+		}
+	}
+	for i, file := range c.Files {
+		clean := path.Clean(file.Path)
+		if file.Path == "" || path.IsAbs(clean) || clean != file.Path || clean == ".." || strings.HasPrefix(clean, "../") || strings.HasPrefix(clean, "templates/") {
+			return fmt.Errorf("file %d needs a clean path relative to the chart, outside templates/, got %q", i, file.Path)
+		}
+	}
+	if c.KeepOutput == "" {
+		c.KeepOutput = KeepOutputFull
+	}
+	if c.KeepOutput != KeepOutputFull && c.KeepOutput != KeepOutputNone {
+		if v, err := quantity.Parse(c.KeepOutput); err != nil || v < 1 {
+			return fmt.Errorf("keepOutput must be full, none or a positive size such as 64Ki, got %q", c.KeepOutput)
+		}
+	}
+	if r := c.Retention; r != nil {
+		if r.MaxReproFiles < 0 {
+			return fmt.Errorf("retention must not have a negative maxReproFiles, got %d", r.MaxReproFiles)
+		}
+		if r.MaxSize != "" {
+			if v, err := quantity.Parse(r.MaxSize); err != nil || v < 1 {
+				return fmt.Errorf("retention maxSize must be a positive size such as 500Mi, got %q", r.MaxSize)
+			}
+		}
+		if r.MaxAge < 0 {
+			return fmt.Errorf("retention must not have a negative maxAge, got %s", r.MaxAge)
+		}
+	}
+	if b := c.Budget; b != nil {
+		for name, q := range map[string]string{"cpu": b.CPU, "memory": b.Memory} {
+			if q == "" {
+				continue
+			}
+			if v, err := quantity.Parse(q); err != nil || v <= 0 {
+				return fmt.Errorf("budget %s must be a positive quantity, got %q", name, q)
+			}
+		}
+		if b.Pods < 0 {
+			return fmt.Errorf("budget pods must not be negative, got %d", b.Pods)
+		}
+	}
+	if sr := c.SlowRender; sr != nil {
+		if sr.Factor == 0 {
+			sr.Factor = 10
+		}
+		if sr.Min == 0 {
+			sr.Min = 100 * time.Millisecond
+		}
+		if sr.Factor <= 1 || sr.Min < 0 {
+			return fmt.Errorf("slowRender needs a factor above 1 and a non-negative min, got %g and %s", sr.Factor, sr.Min)
+		}
+	}
+	if dd := c.DefaultsDiff; dd != nil {
+		if dd.SizeRatio == 0 {
+			dd.SizeRatio = 10
+		}
+		if dd.SizeRatio <= 1 || dd.ObjectRatio < 0 || (dd.ObjectRatio > 0 && dd.ObjectRatio <= 1) {
+			return fmt.Errorf("defaultsDiff needs a sizeRatio above 1 and an objectRatio of 0 or above 1, got %g and %g", dd.SizeRatio, dd.ObjectRatio)
+		}
+	}
+	if mg := c.MemoryGrowth; mg != nil {
+		if mg.Interval == 0 {
+			mg.Interval = 500
+		}
+		if mg.Samples == 0 {
+			mg.Samples = 5
+		}
+		if mg.Min == "" {
+			mg.Min = "64Mi"
+		}
+		if v, err := quantity.Parse(mg.Min); err != nil || v <= 0 || mg.Interval < 0 || mg.Samples < 2 {
+			return fmt.Errorf("memoryGrowth needs a positive interval, at least 2 samples and a positive min, got %d, %d and %q", mg.Interval, mg.Samples, mg.Min)
+		}
+	}
+	for i := range c.Webhooks {
+		hook := &c.Webhooks[i]
+		hook.URL = os.ExpandEnv(hook.URL)
+		if hook.URL == "" {
+			return fmt.Errorf("webhook %d has no url", i)
+		}
+		if hook.Format == "" {
+			hook.Format = "json"
+		}
+		if hook.Format != "json" && hook.Format != "slack" {
+			return fmt.Errorf("webhook %d has invalid format %q: must be json or slack", i, hook.Format)
+		}
+	}
+	if tr := c.Tracing; tr != nil {
+		tr.Endpoint = os.ExpandEnv(tr.Endpoint)
+		if tr.Endpoint == "" {
+			tr.Endpoint = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+		}
+		if tr.Endpoint == "" {
+			tr.Endpoint = "http://localhost:4318"
+		}
+		for name, value := range tr.Headers {
+			tr.Headers[name] = os.ExpandEnv(value)
+		}
+		if tr.ServiceName == "" {
+			tr.ServiceName = "helm-fuzz"
+		}
+		if tr.IterationSample == 0 {
+			tr.IterationSample = 100
+		}
+		if tr.IterationSample < 0 {
+			return fmt.Errorf("tracing must not have a negative iterationSample")
+		}
+	}
+	if hook := c.OnCrash; hook != nil {
+		if len(hook.Command) == 0 {
+			return fmt.Errorf("onCrash has no command")
+		}
+		if hook.Timeout < 0 {
+			return fmt.Errorf("onCrash must not have a negative timeout")
+		}
+		if hook.Timeout == 0 {
+			hook.Timeout = DefaultCrashCommandTimeout
+		}
+	}
+	names := make(map[string]bool, len(c.Plugins))
+	for i := range c.Plugins {
+		plugin := &c.Plugins[i]
+		if plugin.Name == "" {
+			return fmt.Errorf("plugin %d has no name", i)
+		}
+		if names[plugin.Name] {
+			return fmt.Errorf("plugin %q is defined more than once", plugin.Name)
+		}
+		names[plugin.Name] = true
+		if plugin.Type != PluginOracle && plugin.Type != PluginGenerator {
+			return fmt.Errorf("plugin %q has invalid type %q: must be oracle or generator", plugin.Name, plugin.Type)
+		}
+		if len(plugin.Command) == 0 {
+			return fmt.Errorf("plugin %q has no command", plugin.Name)
+		}
+		if plugin.Timeout < 0 || plugin.Inputs < 0 {
+			return fmt.Errorf("plugin %q must not have a negative timeout or inputs", plugin.Name)
+		}
+		if plugin.Timeout == 0 {
+			plugin.Timeout = 10 * time.Second
+		}
+		if plugin.Inputs == 0 {
+			plugin.Inputs = 100
+		}
+	}
+	if c.Envtest.StartTimeout < 0 {
+		return fmt.Errorf("envtest startTimeout must not be negative")
+	}
+	if c.Envtest.StartTimeout == 0 {
+		c.Envtest.StartTimeout = time.Minute
+	}
+	names = make(map[string]bool, len(c.Scanners))
+	for i := range c.Scanners {
+		scanner := &c.Scanners[i]
+		if scanner.Name == "" {
+			return fmt.Errorf("scanner %d has no name", i)
+		}
+		if names[scanner.Name] {
+			return fmt.Errorf("scanner %q is defined more than once", scanner.Name)
+		}
+		names[scanner.Name] = true
+		switch scanner.Format {
+		case ScannerTrivy, ScannerCheckov, ScannerKubeScore:
+		default:
+			return fmt.Errorf("scanner %q has invalid format %q: must be trivy, checkov or kube-score", scanner.Name, scanner.Format)
+		}
+		if len(scanner.Command) == 0 {
+			return fmt.Errorf("scanner %q has no command", scanner.Name)
+		}
+		if scanner.Timeout < 0 {
+			return fmt.Errorf("scanner %q must not have a negative timeout", scanner.Name)
+		}
+		if scanner.Timeout == 0 {
+			scanner.Timeout = 30 * time.Second
+		}
+	}
+	for _, id := range c.Suppress {
+		if chart, fingerprint, ok := strings.Cut(id, "-FZ-"); !ok || chart == "" || fingerprint == "" {
+			return fmt.Errorf("suppress entry %q is not a finding ID such as my-chart-FZ-3fa9c2d1", id)
+		}
 	}
 
-	return config, nil
+	return nil
 }
 
 // IsIgnored checks if a given path should be ignored
@@ -107,3 +759,105 @@ func (c *Config) GetConstraint(path string) *Constraint {
 	}
 	return nil
 }
+
+// ResolveCorpusDir returns the corpus directory resolved against the directory
+// of the config file, or the chart path for default configs.
+// Returns an empty string if no corpus directory is configured.
+func (c *Config) ResolveCorpusDir(chartPath string) string {
+	if c.CorpusDir == "" {
+		return ""
+	}
+	return c.resolve(chartPath, c.CorpusDir)
+}
+
+// DefaultSnapshotDir is where snapshots are kept when snapshotDir is unset
+const DefaultSnapshotDir = "__snapshots__"
+
+// ResolveSnapshotDir returns the snapshot directory resolved like ResolveCorpusDir
+func (c *Config) ResolveSnapshotDir(chartPath string) string {
+	dir := c.SnapshotDir
+	if dir == "" {
+		dir = DefaultSnapshotDir
+	}
+	return c.resolve(chartPath, dir)
+}
+
+// ResolvePluginCommand returns the plugin's command with a relative program
+// path resolved like ResolveCorpusDir. Bare names are looked up in PATH.
+func (c *Config) ResolvePluginCommand(chartPath string, plugin Plugin) []string {
+	return c.resolveCommand(chartPath, plugin.Command)
+}
+
+// ResolveCrashCommand returns the onCrash command resolved like
+// ResolvePluginCommand, or nil when none is configured
+func (c *Config) ResolveCrashCommand(chartPath string) []string {
+	if c.OnCrash == nil {
+		return nil
+	}
+	return c.resolveCommand(chartPath, c.OnCrash.Command)
+}
+
+// ResolveEnvtestAssets returns the envtest assets directory resolved like
+// ResolveCorpusDir, or $KUBEBUILDER_ASSETS when none is configured
+func (c *Config) ResolveEnvtestAssets(chartPath string) string {
+	if c.Envtest.Assets == "" {
+		return os.Getenv("KUBEBUILDER_ASSETS")
+	}
+	return c.resolve(chartPath, c.Envtest.Assets)
+}
+
+// ResolveScannerCommand returns the scanner's command resolved like ResolvePluginCommand
+func (c *Config) ResolveScannerCommand(chartPath string, scanner Scanner) []string {
+	return c.resolveCommand(chartPath, scanner.Command)
+}
+
+// resolveCommand copies command, resolving a relative program path containing a slash
+func (c *Config) resolveCommand(chartPath string, command []string) []string {
+	command = append([]string{}, command...)
+	if strings.ContainsRune(command[0], '/') {
+		command[0] = c.resolve(chartPath, command[0])
+	}
+	return command
+}
+
+// resolve resolves a configured path against the config file's directory, or
+// the chart path for default configs
+func (c *Config) resolve(chartPath, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	if c.baseDir != "" {
+		return filepath.Join(c.baseDir, path)
+	}
+	return filepath.Join(chartPath, path)
+}
+
+// Redacted is what replaces secrets in a redacted config
+const Redacted = "REDACTED"
+
+// Redacted returns a copy of the config without the secrets that expanding
+// ${VAR} references may have put in it, for writing where others read it,
+// such as report.json: webhook URLs, the tracing endpoint and the values of
+// tracing headers are replaced with Redacted
+func (c *Config) Redacted() *Config {
+	redacted := *c
+	if len(c.Webhooks) > 0 {
+		redacted.Webhooks = make([]Webhook, len(c.Webhooks))
+		for i, hook := range c.Webhooks {
+			hook.URL = Redacted
+			redacted.Webhooks[i] = hook
+		}
+	}
+	if c.Tracing != nil {
+		tr := *c.Tracing
+		tr.Endpoint = Redacted
+		if len(c.Tracing.Headers) > 0 {
+			tr.Headers = make(map[string]string, len(c.Tracing.Headers))
+			for name := range c.Tracing.Headers {
+				tr.Headers[name] = Redacted
+			}
+		}
+		redacted.Tracing = &tr
+	}
+	return &redacted
+}
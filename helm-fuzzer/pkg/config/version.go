@@ -0,0 +1,79 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// apiGroup prefixes every .helmfuzz.yaml apiVersion
+	apiGroup = "helmfuzz"
+	// CurrentAPIVersion is the newest config format this build understands
+	CurrentAPIVersion = apiGroup + "/v1"
+)
+
+// currentVersion is the numeric form of CurrentAPIVersion
+const currentVersion = 1
+
+// migrations upgrade a raw config document from the keyed version to the next one.
+// Version 0 is the unversioned format used before apiVersion existed.
+var migrations = map[int]func(raw map[string]interface{}) error{
+	0: func(raw map[string]interface{}) error {
+		// The unversioned format is identical to v1
+		return nil
+	},
+}
+
+// migrate upgrades raw config data to CurrentAPIVersion
+func migrate(data []byte) ([]byte, error) {
+	raw := make(map[string]interface{})
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	apiVersion, _ := raw["apiVersion"].(string)
+	version, err := parseAPIVersion(apiVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	if version == currentVersion {
+		return data, nil
+	}
+
+	for v := version; v < currentVersion; v++ {
+		if err := migrations[v](raw); err != nil {
+			return nil, fmt.Errorf("failed to migrate config from version %d: %w", v, err)
+		}
+	}
+	raw["apiVersion"] = CurrentAPIVersion
+
+	return yaml.Marshal(raw)
+}
+
+// parseAPIVersion returns the numeric version of an apiVersion string.
+// An empty apiVersion is the legacy unversioned format (version 0).
+func parseAPIVersion(apiVersion string) (int, error) {
+	if apiVersion == "" {
+		return 0, nil
+	}
+
+	group, version, ok := strings.Cut(apiVersion, "/")
+	if !ok || group != apiGroup || !strings.HasPrefix(version, "v") {
+		return 0, fmt.Errorf("unsupported config apiVersion %q (expected %s)", apiVersion, CurrentAPIVersion)
+	}
+
+	n, err := strconv.Atoi(strings.TrimPrefix(version, "v"))
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("unsupported config apiVersion %q (expected %s)", apiVersion, CurrentAPIVersion)
+	}
+
+	if n > currentVersion {
+		return 0, fmt.Errorf("config apiVersion %q is newer than this helm-fuzz supports (%s); please upgrade helm-fuzz", apiVersion, CurrentAPIVersion)
+	}
+
+	return n, nil
+}
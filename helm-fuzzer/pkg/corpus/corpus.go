@@ -0,0 +1,181 @@
+package corpus
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Entry is a values file in a corpus directory
+type Entry struct {
+	Name   string
+	Path   string
+	Values map[string]interface{}
+}
+
+// Load reads every values file (*.yaml, *.yml) in dir, sorted by filename
+// so seed order is reproducible. A missing directory yields an empty corpus.
+func Load(dir string) ([]map[string]interface{}, error) {
+	entries, err := Entries(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]map[string]interface{}, 0, len(entries))
+	for _, entry := range entries {
+		values = append(values, entry.Values)
+	}
+	return values, nil
+}
+
+// Entries reads every values file in dir like Load, keeping the file names
+func Entries(dir string) ([]Entry, error) {
+	dirEntries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read corpus directory: %w", err)
+	}
+
+	var names []string
+	for _, entry := range dirEntries {
+		if entry.IsDir() || !isValuesFile(entry.Name()) {
+			continue
+		}
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+
+	entries := make([]Entry, 0, len(names))
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		v, err := LoadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, Entry{Name: name, Path: path, Values: v})
+	}
+
+	return entries, nil
+}
+
+// Add copies the values file at src into dir, creating dir if needed. The
+// entry keeps the source's file name, with a content hash appended if that
+// name is taken. Returns the existing entry's name and false when the corpus
+// already holds the same values.
+func Add(dir, src string) (string, bool, error) {
+	values, err := LoadFile(src)
+	if err != nil {
+		return "", false, err
+	}
+	return Save(dir, filepath.Base(src), values)
+}
+
+// Save writes values into dir as an entry named name, like Add does for a file
+func Save(dir, name string, values map[string]interface{}) (string, bool, error) {
+	data, err := yaml.Marshal(values)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to encode corpus entry: %w", err)
+	}
+
+	existing, err := Entries(dir)
+	if err != nil {
+		return "", false, err
+	}
+	taken := make(map[string]bool, len(existing))
+	for _, entry := range existing {
+		taken[entry.Name] = true
+		// yaml.v3 sorts map keys, so equal values encode identically
+		if other, err := yaml.Marshal(entry.Values); err == nil && string(other) == string(data) {
+			return entry.Name, false, nil
+		}
+	}
+
+	if !isValuesFile(name) {
+		name += ".yaml"
+	}
+	if taken[name] {
+		sum := sha256.Sum256(data)
+		ext := filepath.Ext(name)
+		name = fmt.Sprintf("%s-%x%s", strings.TrimSuffix(name, ext), sum[:4], ext)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", false, fmt.Errorf("failed to create corpus directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+		return "", false, fmt.Errorf("failed to write corpus entry: %w", err)
+	}
+	return name, true, nil
+}
+
+// Minimize picks a subset of entries that covers every feature the whole
+// corpus covers, given each entry's features (such as value paths set,
+// templates rendered and crash fingerprints). Entries are chosen greedily by
+// how many uncovered features they add, ties broken by name. Returns the
+// names to keep, sorted.
+func Minimize(features map[string][]string) []string {
+	remaining := make(map[string]map[string]bool, len(features))
+	for name, fs := range features {
+		set := make(map[string]bool, len(fs))
+		for _, f := range fs {
+			set[f] = true
+		}
+		remaining[name] = set
+	}
+
+	var keep []string
+	for {
+		best, bestCount := "", 0
+		for name, set := range remaining {
+			if len(set) > bestCount || (len(set) == bestCount && bestCount > 0 && name < best) {
+				best, bestCount = name, len(set)
+			}
+		}
+		if bestCount == 0 {
+			break
+		}
+
+		keep = append(keep, best)
+		covered := remaining[best]
+		delete(remaining, best)
+		for _, set := range remaining {
+			for f := range covered {
+				delete(set, f)
+			}
+		}
+	}
+
+	sort.Strings(keep)
+	return keep
+}
+
+// LoadFile reads a single values file
+func LoadFile(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read corpus entry %s: %w", path, err)
+	}
+
+	values := make(map[string]interface{})
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("failed to parse corpus entry %s: %w", path, err)
+	}
+	if values == nil {
+		values = make(map[string]interface{})
+	}
+
+	return values, nil
+}
+
+// isValuesFile checks if a filename looks like a YAML values file
+func isValuesFile(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	return ext == ".yaml" || ext == ".yml"
+}
@@ -0,0 +1,110 @@
+package corpus
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoad_MissingDir(t *testing.T) {
+	values, err := Load(filepath.Join(t.TempDir(), "missing"))
+	if err != nil {
+		t.Fatalf("expected no error for missing corpus dir, got: %v", err)
+	}
+
+	if len(values) != 0 {
+		t.Errorf("expected empty corpus, got %d entries", len(values))
+	}
+}
+
+func TestLoad_SortedEntries(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	files := map[string]string{
+		"b.yaml":     "replicaCount: 2\n",
+		"a.yml":      "replicaCount: 1\n",
+		"notes.txt":  "not a values file\n",
+		"empty.yaml": "",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	values, err := Load(tmpDir)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if len(values) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(values))
+	}
+
+	if values[0]["replicaCount"] != 1 {
+		t.Errorf("expected a.yml first, got %v", values[0])
+	}
+
+	if values[2]["replicaCount"] != nil {
+		t.Errorf("expected empty.yaml to load as empty values, got %v", values[2])
+	}
+}
+
+func TestAdd(t *testing.T) {
+	src := t.TempDir()
+	dir := filepath.Join(t.TempDir(), "corpus")
+
+	write := func(name, content string) string {
+		path := filepath.Join(src, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		return path
+	}
+
+	name, added, err := Add(dir, write("prod.yaml", "replicaCount: 3\nimage: {tag: v1}\n"))
+	if err != nil || !added || name != "prod.yaml" {
+		t.Fatalf("expected prod.yaml to be added, got %q %v %v", name, added, err)
+	}
+
+	// Same values in a different layout are a duplicate
+	name, added, err = Add(dir, write("copy.yaml", "image:\n  tag: v1\nreplicaCount: 3\n"))
+	if err != nil || added || name != "prod.yaml" {
+		t.Errorf("expected duplicate of prod.yaml, got %q %v %v", name, added, err)
+	}
+
+	// A taken name gets a hash suffix
+	other := filepath.Join(t.TempDir(), "prod.yaml")
+	if err := os.WriteFile(other, []byte("replicaCount: 5\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	name, added, err = Add(dir, other)
+	if err != nil || !added || name == "prod.yaml" || filepath.Ext(name) != ".yaml" {
+		t.Errorf("expected a renamed entry, got %q %v %v", name, added, err)
+	}
+
+	entries, err := Entries(dir)
+	if err != nil || len(entries) != 2 {
+		t.Errorf("expected 2 entries, got %d (%v)", len(entries), err)
+	}
+
+	if _, _, err := Add(dir, write("bad.yaml", "key: [unclosed\n")); err == nil {
+		t.Error("expected error for invalid YAML")
+	}
+}
+
+func TestMinimize(t *testing.T) {
+	keep := Minimize(map[string][]string{
+		"all.yaml":     {"path:a", "path:b", "template:x"},
+		"subset.yaml":  {"path:a"},
+		"same.yaml":    {"path:a", "path:b", "template:x"},
+		"crash.yaml":   {"path:a", "crash:1234"},
+		"nothing.yaml": nil,
+	})
+
+	want := []string{"all.yaml", "crash.yaml"}
+	if !reflect.DeepEqual(keep, want) {
+		t.Errorf("Minimize() = %v, want %v", keep, want)
+	}
+}
@@ -0,0 +1,244 @@
+package coverage
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/schema"
+)
+
+// Tracker records which schema paths, enum values and templates a session exercised
+type Tracker struct {
+	mu sync.Mutex
+	// paths maps every schema path to whether a generated input ever set it
+	paths map[string]bool
+	// enums maps each enum path to whether each of its values was chosen, keyed by formatted value
+	enums map[string]map[string]bool
+	// templates maps every renderable template to whether it ever produced output
+	templates map[string]bool
+	// schemas maps every schema path, and the root as "", to its schema
+	schemas map[string]*schema.Schema
+	// mutations counts how each schema path was set, keyed like paths
+	mutations map[string]*Mutations
+}
+
+// New creates a tracker for the paths in sch and the given templates
+func New(sch *schema.Schema, templates []string) *Tracker {
+	t := &Tracker{
+		paths:     make(map[string]bool),
+		enums:     make(map[string]map[string]bool),
+		templates: make(map[string]bool),
+		schemas:   make(map[string]*schema.Schema),
+		mutations: make(map[string]*Mutations),
+	}
+	if sch != nil {
+		t.addSchema("", sch)
+	}
+	for _, name := range templates {
+		t.templates[name] = false
+	}
+	return t
+}
+
+// addSchema registers the paths below prefix
+func (t *Tracker) addSchema(prefix string, s *schema.Schema) {
+	t.schemas[prefix] = s
+	if prefix != "" {
+		t.paths[prefix] = false
+		t.mutations[prefix] = &Mutations{}
+	}
+	if len(s.Enum) > 0 {
+		values := make(map[string]bool, len(s.Enum))
+		for _, v := range s.Enum {
+			values[formatValue(v)] = false
+		}
+		t.enums[prefix] = values
+	}
+	for name, prop := range s.Properties {
+		t.addSchema(join(prefix, name), prop)
+	}
+	if s.Items != nil {
+		t.addSchema(prefix+"[]", s.Items)
+	}
+}
+
+// Record marks the paths set by values and the templates that rendered. It
+// returns the features, as listed by Features, that no earlier input covered.
+func (t *Tracker) Record(values map[string]interface{}, rendered []string) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var fresh []string
+	t.recordValue("", values, &fresh)
+	for _, name := range rendered {
+		if covered, ok := t.templates[name]; ok && !covered {
+			t.templates[name] = true
+			fresh = append(fresh, "template:"+name)
+		}
+	}
+	sort.Strings(fresh)
+	return fresh
+}
+
+// recordValue marks path and everything below it as set
+func (t *Tracker) recordValue(path string, value interface{}, fresh *[]string) {
+	if set, ok := t.paths[path]; ok && !set {
+		t.paths[path] = true
+		*fresh = append(*fresh, "path:"+path)
+	}
+	if m, ok := t.mutations[path]; ok {
+		m.record(t.schemas[path], value)
+	}
+	if values, ok := t.enums[path]; ok {
+		key := formatValue(value)
+		if chosen, ok := values[key]; ok && !chosen {
+			values[key] = true
+			*fresh = append(*fresh, "enum:"+path+"="+key)
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			t.recordValue(join(path, key), child, fresh)
+		}
+		if s := t.schemas[path]; s != nil {
+			for name := range s.Properties {
+				if _, set := v[name]; !set {
+					t.mutations[join(path, name)].Omitted++
+				}
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			t.recordValue(path+"[]", item, fresh)
+		}
+	}
+}
+
+// Summary is the coverage of a session
+type Summary struct {
+	Paths             int `json:"paths"`
+	PathsSet          int `json:"pathsSet"`
+	EnumValues        int `json:"enumValues"`
+	EnumValuesChosen  int `json:"enumValuesChosen"`
+	Templates         int `json:"templates"`
+	TemplatesRendered int `json:"templatesRendered"`
+	// Regions and RegionsExecuted count instrumented template regions, zero
+	// unless the session was guided by them
+	Regions         int `json:"regions,omitempty"`
+	RegionsExecuted int `json:"regionsExecuted,omitempty"`
+	// UnsetPaths lists schema paths no input ever set
+	UnsetPaths []string `json:"unsetPaths"`
+	// UnchosenEnumValues lists enum values never chosen, as path=value
+	UnchosenEnumValues []string `json:"unchosenEnumValues"`
+	// UnrenderedTemplates lists templates that never produced output
+	UnrenderedTemplates []string `json:"unrenderedTemplates"`
+	// Mutations counts, per schema path, how the inputs set it
+	Mutations map[string]Mutations `json:"mutations,omitempty"`
+}
+
+// Summary returns the coverage recorded so far
+func (t *Tracker) Summary() Summary {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s := Summary{
+		Paths:               len(t.paths),
+		Templates:           len(t.templates),
+		UnsetPaths:          []string{},
+		UnchosenEnumValues:  []string{},
+		UnrenderedTemplates: []string{},
+	}
+	if len(t.mutations) > 0 {
+		s.Mutations = make(map[string]Mutations, len(t.mutations))
+		for path, m := range t.mutations {
+			s.Mutations[path] = *m
+		}
+	}
+	for path, set := range t.paths {
+		if set {
+			s.PathsSet++
+		} else {
+			s.UnsetPaths = append(s.UnsetPaths, path)
+		}
+	}
+	for path, values := range t.enums {
+		for value, chosen := range values {
+			s.EnumValues++
+			if chosen {
+				s.EnumValuesChosen++
+			} else {
+				s.UnchosenEnumValues = append(s.UnchosenEnumValues, path+"="+value)
+			}
+		}
+	}
+	for name, rendered := range t.templates {
+		if rendered {
+			s.TemplatesRendered++
+		} else {
+			s.UnrenderedTemplates = append(s.UnrenderedTemplates, name)
+		}
+	}
+
+	sort.Strings(s.UnsetPaths)
+	sort.Strings(s.UnchosenEnumValues)
+	sort.Strings(s.UnrenderedTemplates)
+	return s
+}
+
+// Features lists everything recorded so far as path:<path>, enum:<path>=<value>
+// and template:<name> keys, sorted, for comparing what different inputs exercise
+func (t *Tracker) Features() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var features []string
+	for path, set := range t.paths {
+		if set {
+			features = append(features, "path:"+path)
+		}
+	}
+	for path, values := range t.enums {
+		for value, chosen := range values {
+			if chosen {
+				features = append(features, "enum:"+path+"="+value)
+			}
+		}
+	}
+	for name, rendered := range t.templates {
+		if rendered {
+			features = append(features, "template:"+name)
+		}
+	}
+	sort.Strings(features)
+	return features
+}
+
+// Percent returns part as a percentage of total, 100 when there is nothing to cover
+func Percent(part, total int) float64 {
+	if total == 0 {
+		return 100
+	}
+	return float64(part) * 100 / float64(total)
+}
+
+// join appends a key to a dotted path
+func join(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// formatValue renders an enum value as a comparable key, so 1 and 1.0 match
+func formatValue(v interface{}) string {
+	switch n := v.(type) {
+	case int:
+		return fmt.Sprint(float64(n))
+	case int64:
+		return fmt.Sprint(float64(n))
+	}
+	return fmt.Sprint(v)
+}
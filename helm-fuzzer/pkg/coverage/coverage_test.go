@@ -0,0 +1,224 @@
+package coverage
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/engine"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/schema"
+)
+
+func TestTracker(t *testing.T) {
+	sch := &schema.Schema{
+		Type: schema.TypeObject,
+		Properties: map[string]*schema.Schema{
+			"replicas": {Type: schema.TypeInteger},
+			"service": {
+				Type: schema.TypeObject,
+				Properties: map[string]*schema.Schema{
+					"type": {Type: schema.TypeString, Enum: []interface{}{"ClusterIP", "NodePort"}},
+					"port": {Type: schema.TypeInteger, Enum: []interface{}{float64(80), float64(443)}},
+				},
+			},
+			"hosts": {Type: schema.TypeArray, Items: &schema.Schema{Type: schema.TypeString}},
+		},
+	}
+	tracker := New(sch, []string{"app/templates/deployment.yaml", "app/templates/ingress.yaml"})
+
+	tracker.Record(map[string]interface{}{
+		"service": map[string]interface{}{"type": "ClusterIP", "port": 80},
+		"hosts":   []interface{}{"a"},
+	}, []string{"app/templates/deployment.yaml", "app/templates/unknown.yaml"})
+
+	got := tracker.Summary()
+	want := Summary{
+		Paths:               6,
+		PathsSet:            5,
+		EnumValues:          4,
+		EnumValuesChosen:    2,
+		Templates:           2,
+		TemplatesRendered:   1,
+		UnsetPaths:          []string{"replicas"},
+		UnchosenEnumValues:  []string{"service.port=443", "service.type=NodePort"},
+		UnrenderedTemplates: []string{"app/templates/ingress.yaml"},
+		Mutations: map[string]Mutations{
+			"replicas":     {Omitted: 1},
+			"service":      {Included: 1},
+			"service.type": {Included: 1},
+			"service.port": {Included: 1},
+			"hosts":        {Included: 1},
+			"hosts[]":      {Included: 1},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Summary() =\n%+v\nwant\n%+v", got, want)
+	}
+
+	features := tracker.Features()
+	wantFeatures := []string{
+		"enum:service.port=80", "enum:service.type=ClusterIP",
+		"path:hosts", "path:hosts[]", "path:service", "path:service.port", "path:service.type",
+		"template:app/templates/deployment.yaml",
+	}
+	if !reflect.DeepEqual(features, wantFeatures) {
+		t.Errorf("Features() = %v, want %v", features, wantFeatures)
+	}
+
+	// Record reports only what the input covered first
+	fresh := tracker.Record(map[string]interface{}{
+		"replicas": 2,
+		"service":  map[string]interface{}{"type": "NodePort"},
+	}, []string{"app/templates/deployment.yaml"})
+	wantFresh := []string{"enum:service.type=NodePort", "path:replicas"}
+	if !reflect.DeepEqual(fresh, wantFresh) {
+		t.Errorf("Record() = %v, want %v", fresh, wantFresh)
+	}
+}
+
+func TestTracker_Mutations(t *testing.T) {
+	minReplicas, maxLength := 1.0, 3
+	sch := &schema.Schema{
+		Type: schema.TypeObject,
+		Properties: map[string]*schema.Schema{
+			"replicas": {Type: schema.TypeInteger, Minimum: &minReplicas},
+			"name":     {Type: schema.TypeString, MaxLength: &maxLength},
+			"labels":   {Type: schema.TypeObject},
+		},
+	}
+	tracker := New(sch, nil)
+	for _, values := range []map[string]interface{}{
+		{"replicas": 1, "name": "abc", "labels": map[string]interface{}{}},
+		{"replicas": 2, "name": nil},
+		{"replicas": float64(0), "name": ""},
+		{},
+	} {
+		tracker.Record(values, nil)
+	}
+
+	want := map[string]Mutations{
+		"replicas": {Included: 3, Omitted: 1, Boundary: 2},
+		"name":     {Included: 2, Omitted: 1, Nulled: 1, Boundary: 2},
+		"labels":   {Included: 1, Omitted: 3, Boundary: 1},
+	}
+	if got := tracker.Summary().Mutations; !reflect.DeepEqual(got, want) {
+		t.Errorf("Mutations =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestPercent(t *testing.T) {
+	if got := Percent(1, 4); got != 25 {
+		t.Errorf("Percent(1, 4) = %v, want 25", got)
+	}
+	if got := Percent(0, 0); got != 100 {
+		t.Errorf("Percent(0, 0) = %v, want 100", got)
+	}
+}
+
+// regionChart builds a chart with a partial, branches and a subchart
+func regionChart() *chart.Chart {
+	sub := &chart.Chart{
+		Metadata: &chart.Metadata{APIVersion: "v2", Name: "sub", Version: "0.1.0"},
+		Templates: []*chart.File{
+			{Name: "templates/cm.yaml", Data: []byte("kind: ConfigMap\n")},
+		},
+	}
+	c := &chart.Chart{
+		Metadata: &chart.Metadata{APIVersion: "v2", Name: "app", Version: "0.1.0"},
+		Templates: []*chart.File{
+			{Name: "templates/_helpers.tpl", Data: []byte(`{{- define "app.name" -}}
+{{ .Chart.Name }}
+{{- end }}
+{{- define "app.unused" }}unused{{ end }}`)},
+			{Name: "templates/deploy.yaml", Data: []byte(`kind: Deployment
+name: {{ include "app.name" . }}
+{{- if .Values.debug }}
+debug: true
+{{- else if .Values.verbose }}
+verbose: true
+{{- end }}
+{{- with .Values.labels }}
+labels:
+{{- range $k, $v := . }}
+  {{ $k }}: {{ $v | quote }}
+{{- end }}
+{{- end }}
+`)},
+			{Name: "templates/NOTES.txt", Data: []byte("{{ if .Values.debug }}debugging{{ end }}")},
+		},
+	}
+	c.AddDependency(sub)
+	return c
+}
+
+func TestInstrument(t *testing.T) {
+	c := regionChart()
+	in := Instrument(c)
+
+	var got []string
+	for _, r := range in.Regions {
+		got = append(got, r.String())
+	}
+	want := []string{
+		"app/templates/_helpers.tpl:1 define app.name",
+		"app/templates/_helpers.tpl:4 define app.unused",
+		"app/templates/deploy.yaml:1 template",
+		"app/templates/deploy.yaml:3 if .Values.debug",
+		"app/templates/deploy.yaml:5 else if .Values.debug",
+		"app/templates/deploy.yaml:5 if .Values.verbose",
+		"app/templates/deploy.yaml:8 with .Values.labels",
+		"app/templates/deploy.yaml:10 range $k, $v := .",
+		"app/charts/sub/templates/cm.yaml:1 template",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected regions\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+
+	render := func(values map[string]interface{}) map[string]string {
+		t.Helper()
+		fresh := regionChart()
+		in.Apply(fresh)
+		vals, err := chartutil.ToRenderValues(fresh, values, chartutil.ReleaseOptions{Name: "test"}, nil)
+		if err != nil {
+			t.Fatalf("ToRenderValues failed: %v", err)
+		}
+		rendered, err := engine.Render(fresh, vals)
+		if err != nil {
+			t.Fatalf("instrumented chart failed to render: %v", err)
+		}
+		return rendered
+	}
+
+	tracker := NewRegionTracker(in)
+	rendered := render(map[string]interface{}{"verbose": true, "labels": map[string]interface{}{"a": "b"}})
+	if out := markerPattern.ReplaceAllString(rendered["app/templates/deploy.yaml"], ""); !strings.Contains(out, "name: app") || !strings.Contains(out, `a: "b"`) {
+		t.Errorf("expected instrumentation to keep the output, got %q", out)
+	}
+	tracker.Record(in.Executed(rendered))
+
+	s := tracker.Summary()
+	if s.Regions != 9 || s.Executed != 7 {
+		t.Errorf("expected 7 of 9 regions executed, got %d of %d", s.Executed, s.Regions)
+	}
+	var unexecuted []string
+	for _, r := range s.Unexecuted {
+		unexecuted = append(unexecuted, r.String())
+	}
+	if want := []string{"app/templates/_helpers.tpl:4 define app.unused", "app/templates/deploy.yaml:3 if .Values.debug"}; !reflect.DeepEqual(unexecuted, want) {
+		t.Errorf("expected unexecuted %v, got %v", want, unexecuted)
+	}
+	if len(s.Kinds) != 6 || s.Kinds[0] != (KindCount{Kind: KindDefine, Regions: 2, Executed: 1}) {
+		t.Errorf("unexpected kinds %+v", s.Kinds)
+	}
+
+	fresh := tracker.Record(in.Executed(render(map[string]interface{}{"debug": true})))
+	if len(fresh) != 1 || fresh[0].String() != "app/templates/deploy.yaml:3 if .Values.debug" {
+		t.Errorf("expected only the debug branch to be new, got %v", fresh)
+	}
+	if s := tracker.Summary(); len(s.Unexecuted) != 1 {
+		t.Errorf("expected only the unused define left, got %v", s.Unexecuted)
+	}
+}
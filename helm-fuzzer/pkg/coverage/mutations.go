@@ -0,0 +1,69 @@
+package coverage
+
+import (
+	"github.com/kasuboski/helm-fuzzer/pkg/schema"
+)
+
+// Mutations counts the ways inputs set a schema path, so a session can show
+// that a path was exercised beyond ever being set: a template guarding a
+// value with `if` is only tested once it is also omitted and null
+type Mutations struct {
+	// Included counts inputs setting the path to a value other than null
+	Included int `json:"included"`
+	// Omitted counts inputs setting the object holding the path without it.
+	// Items of lists are never omitted.
+	Omitted int `json:"omitted"`
+	// Nulled counts inputs setting the path to null
+	Nulled int `json:"nulled"`
+	// Boundary counts included values on a boundary of the path's schema
+	// (see IsBoundary)
+	Boundary int `json:"boundary"`
+}
+
+// record counts one value set at a path with schema s
+func (m *Mutations) record(s *schema.Schema, value interface{}) {
+	if value == nil {
+		m.Nulled++
+		return
+	}
+	m.Included++
+	if IsBoundary(s, value) {
+		m.Boundary++
+	}
+}
+
+// IsBoundary reports whether a value lies on a boundary of schema s: a
+// number equal to zero or to the schema's minimum or maximum, a string empty
+// or as long as its minLength or maxLength allows, or an empty list or
+// object. Values of other types are never on one.
+func IsBoundary(s *schema.Schema, value interface{}) bool {
+	switch v := value.(type) {
+	case string:
+		n := len([]rune(v))
+		return n == 0 || s != nil && (s.MinLength != nil && n == *s.MinLength || s.MaxLength != nil && n == *s.MaxLength)
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	}
+	n, ok := toFloat(value)
+	if !ok {
+		return false
+	}
+	return n == 0 || s != nil && (s.Minimum != nil && n == *s.Minimum || s.Maximum != nil && n == *s.Maximum)
+}
+
+// toFloat converts the numbers generated values and decoded YAML hold
+func toFloat(value interface{}) (float64, bool) {
+	switch n := value.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
@@ -0,0 +1,301 @@
+package coverage
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template/parse"
+
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+// markerPattern matches the markers instrumented templates emit. The unit
+// separator keeps them from colliding with anything a chart renders.
+var markerPattern = regexp.MustCompile("\x1fhelmfuzz:([0-9]+)\x1f")
+
+// Region kinds
+const (
+	KindTemplate = "template"
+	KindDefine   = "define"
+	KindIf       = "if"
+	KindElse     = "else"
+	KindWith     = "with"
+	KindRange    = "range"
+)
+
+// Region is a part of a template whose execution instrumentation observes:
+// a whole template file, a define, or one branch of an if, with or range
+type Region struct {
+	// Template is the file, named as in rendered manifests
+	Template string `json:"template"`
+	Line     int    `json:"line"`
+	Kind     string `json:"kind"`
+	// Name is the define's name or the branch's pipeline; an else names the branch it belongs to
+	Name string `json:"name,omitempty"`
+}
+
+func (r Region) String() string {
+	label := r.Kind
+	if r.Name != "" {
+		label += " " + r.Name
+	}
+	return fmt.Sprintf("%s:%d %s", r.Template, r.Line, label)
+}
+
+// Instrumentation is a chart's templates rewritten so that rendering them
+// emits a marker as each region starts executing. Markers are output text:
+// functions such as b64enc or fromYaml lose them, and checks on an include's
+// output can see them, so coverage is a lower bound.
+type Instrumentation struct {
+	Regions []Region
+	// sources maps template names, as in rendered manifests, to instrumented source
+	sources map[string][]byte
+}
+
+// Instrument rewrites the templates of c and its subcharts. Templates that
+// cannot be parsed or rewritten keep their source and add no regions.
+func Instrument(c *chart.Chart) *Instrumentation {
+	in := &Instrumentation{sources: make(map[string][]byte)}
+	walkTemplates(c, "", func(name string, t *chart.File) {
+		if path.Base(name) == "NOTES.txt" {
+			return
+		}
+		src, regions, err := instrumentTemplate(name, string(t.Data), len(in.Regions))
+		if err != nil {
+			return
+		}
+		in.sources[name] = []byte(src)
+		in.Regions = append(in.Regions, regions...)
+	})
+	return in
+}
+
+// Apply replaces the templates of a freshly loaded copy of the chart with
+// their instrumented source
+func (in *Instrumentation) Apply(c *chart.Chart) {
+	walkTemplates(c, "", func(name string, t *chart.File) {
+		if src, ok := in.sources[name]; ok {
+			t.Data = src
+		}
+	})
+}
+
+// Executed returns the regions whose markers appear in rendered output, by index
+func (in *Instrumentation) Executed(rendered map[string]string) []int {
+	seen := make(map[int]bool)
+	for _, out := range rendered {
+		for _, m := range markerPattern.FindAllStringSubmatch(out, -1) {
+			if id, err := strconv.Atoi(m[1]); err == nil && id < len(in.Regions) {
+				seen[id] = true
+			}
+		}
+	}
+	ids := make([]int, 0, len(seen))
+	for id := range seen {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// walkTemplates calls fn for each template of c and its subcharts, named as in rendered manifests
+func walkTemplates(c *chart.Chart, prefix string, fn func(name string, t *chart.File)) {
+	prefix = path.Join(prefix, c.Name())
+	for _, t := range c.Templates {
+		fn(path.Join(prefix, t.Name), t)
+	}
+	for _, dep := range c.Dependencies() {
+		walkTemplates(dep, path.Join(prefix, "charts"), fn)
+	}
+}
+
+// instrumenter inserts markers into one template file's parse trees
+type instrumenter struct {
+	name    string
+	src     string
+	next    int
+	regions []Region
+}
+
+// instrumentTemplate parses a template file and returns its source with a
+// marker opening every region, numbering the regions from first
+func instrumentTemplate(name, src string, first int) (string, []Region, error) {
+	treeSet := make(map[string]*parse.Tree)
+	main := parse.New(name)
+	main.Mode = parse.SkipFuncCheck
+	if _, err := main.Parse(src, "{{", "}}", treeSet); err != nil {
+		return "", nil, err
+	}
+
+	in := &instrumenter{name: name, src: src, next: first}
+	var b strings.Builder
+
+	// Partials only hold defines; their own output is never rendered
+	if !strings.HasPrefix(path.Base(name), "_") {
+		in.list(main.Root, KindTemplate, "", 0)
+	}
+	b.WriteString(main.Root.String())
+
+	defines := make([]string, 0, len(treeSet))
+	for defineName := range treeSet {
+		if defineName != name {
+			defines = append(defines, defineName)
+		}
+	}
+	sort.Strings(defines)
+	for _, defineName := range defines {
+		tree := treeSet[defineName]
+		in.list(tree.Root, KindDefine, defineName, definePos(src, defineName, tree.Root.Pos))
+		fmt.Fprintf(&b, "{{define %s}}%s{{end}}", strconv.Quote(defineName), tree.Root.String())
+	}
+
+	// Refuse a rewrite that does not parse back rather than break rendering
+	check := parse.New(name)
+	check.Mode = parse.SkipFuncCheck
+	if _, err := check.Parse(b.String(), "{{", "}}", make(map[string]*parse.Tree)); err != nil {
+		return "", nil, fmt.Errorf("instrumented %s does not parse: %w", name, err)
+	}
+	return b.String(), in.regions, nil
+}
+
+// definePos finds where a define or block action opens, since parse trees
+// only record where the body starts
+func definePos(src, name string, body parse.Pos) parse.Pos {
+	action := regexp.MustCompile(`\{\{-?\s*(define|block)\s+` + regexp.QuoteMeta(strconv.Quote(name)))
+	if loc := action.FindStringIndex(src); loc != nil {
+		return parse.Pos(loc[0])
+	}
+	return body
+}
+
+// list opens a region at the start of list and instruments what it contains
+func (in *instrumenter) list(list *parse.ListNode, kind, name string, pos parse.Pos) {
+	if list == nil {
+		return
+	}
+	id := in.next
+	in.next++
+	in.regions = append(in.regions, Region{Template: in.name, Line: in.line(pos), Kind: kind, Name: name})
+
+	marker := &parse.TextNode{NodeType: parse.NodeText, Pos: list.Pos, Text: []byte(fmt.Sprintf("\x1fhelmfuzz:%d\x1f", id))}
+	nodes := list.Nodes
+	list.Nodes = append([]parse.Node{marker}, nodes...)
+	for _, n := range nodes {
+		in.node(n)
+	}
+}
+
+// node instruments the branches of control structures
+func (in *instrumenter) node(n parse.Node) {
+	switch n := n.(type) {
+	case *parse.IfNode:
+		in.branch(&n.BranchNode, KindIf)
+	case *parse.WithNode:
+		in.branch(&n.BranchNode, KindWith)
+	case *parse.RangeNode:
+		in.branch(&n.BranchNode, KindRange)
+	case *parse.ListNode:
+		for _, child := range n.Nodes {
+			in.node(child)
+		}
+	}
+}
+
+// branch opens a region for each arm of an if, with or range
+func (in *instrumenter) branch(b *parse.BranchNode, kind string) {
+	pipe := b.Pipe.String()
+	in.list(b.List, kind, pipe, b.Pos)
+	if b.ElseList != nil {
+		in.list(b.ElseList, KindElse, kind+" "+pipe, b.ElseList.Pos)
+	}
+}
+
+// line converts a byte offset in the source to a line number
+func (in *instrumenter) line(pos parse.Pos) int {
+	if int(pos) > len(in.src) {
+		pos = parse.Pos(len(in.src))
+	}
+	return 1 + strings.Count(in.src[:pos], "\n")
+}
+
+// RegionTracker records which instrumented regions executed across renders
+type RegionTracker struct {
+	mu      sync.Mutex
+	regions []Region
+	hits    []int
+}
+
+// NewRegionTracker creates a tracker for the instrumentation's regions
+func NewRegionTracker(in *Instrumentation) *RegionTracker {
+	return &RegionTracker{regions: in.Regions, hits: make([]int, len(in.Regions))}
+}
+
+// Record counts the executed regions of one render and returns those no
+// earlier render reached
+func (t *RegionTracker) Record(executed []int) []Region {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var fresh []Region
+	for _, id := range executed {
+		if t.hits[id] == 0 {
+			fresh = append(fresh, t.regions[id])
+		}
+		t.hits[id]++
+	}
+	return fresh
+}
+
+// RegionSummary is how much of a chart's templates a set of renders executed
+type RegionSummary struct {
+	Regions  int `json:"regions"`
+	Executed int `json:"executed"`
+	// Kinds counts regions and executed regions per kind, sorted by kind
+	Kinds []KindCount `json:"kinds"`
+	// Unexecuted lists the regions no render reached, by template and line
+	Unexecuted []Region `json:"unexecuted"`
+}
+
+// KindCount is the coverage of one kind of region
+type KindCount struct {
+	Kind     string `json:"kind"`
+	Regions  int    `json:"regions"`
+	Executed int    `json:"executed"`
+}
+
+// Summary returns the region coverage recorded so far
+func (t *RegionTracker) Summary() RegionSummary {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s := RegionSummary{Regions: len(t.regions), Kinds: []KindCount{}, Unexecuted: []Region{}}
+	kinds := make(map[string]*KindCount)
+	for i, r := range t.regions {
+		k, ok := kinds[r.Kind]
+		if !ok {
+			k = &KindCount{Kind: r.Kind}
+			kinds[r.Kind] = k
+		}
+		k.Regions++
+		if t.hits[i] > 0 {
+			s.Executed++
+			k.Executed++
+		} else {
+			s.Unexecuted = append(s.Unexecuted, r)
+		}
+	}
+	for _, k := range kinds {
+		s.Kinds = append(s.Kinds, *k)
+	}
+	sort.Slice(s.Kinds, func(i, j int) bool { return s.Kinds[i].Kind < s.Kinds[j].Kind })
+	sort.SliceStable(s.Unexecuted, func(i, j int) bool {
+		if s.Unexecuted[i].Template != s.Unexecuted[j].Template {
+			return s.Unexecuted[i].Template < s.Unexecuted[j].Template
+		}
+		return s.Unexecuted[i].Line < s.Unexecuted[j].Line
+	})
+	return s
+}
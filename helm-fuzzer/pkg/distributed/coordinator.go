@@ -0,0 +1,357 @@
+// Package distributed shards a fuzzing session across worker processes. A
+// coordinator hands out ranges of iteration indices; workers fuzz them with
+// pkg/fuzz and send back their findings. Inputs are drawn from the iteration
+// index, so a distributed session tests exactly what a single process would.
+package distributed
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/config"
+	"github.com/kasuboski/helm-fuzzer/pkg/corpus"
+	"github.com/kasuboski/helm-fuzzer/pkg/fuzz"
+	"github.com/kasuboski/helm-fuzzer/pkg/logging"
+	"github.com/kasuboski/helm-fuzzer/pkg/notify"
+	"github.com/kasuboski/helm-fuzzer/pkg/plugin"
+	"github.com/kasuboski/helm-fuzzer/pkg/report"
+	"github.com/kasuboski/helm-fuzzer/pkg/runner"
+)
+
+// Lease is a range of iterations assigned to one worker
+type Lease struct {
+	ID string `json:"id"`
+	// Start and End bound the iteration indices to fuzz, End excluded
+	Start int `json:"start"`
+	End   int `json:"end"`
+	// Seen lists the crash reasons found so far, which the worker does not report again
+	Seen []string `json:"seen"`
+}
+
+// LeaseResponse answers a worker asking for work. Without a lease, Done
+// means the session is over; otherwise the worker should ask again later.
+type LeaseResponse struct {
+	Lease *Lease `json:"lease,omitempty"`
+	Done  bool   `json:"done,omitempty"`
+}
+
+// Results are a worker's outcome for a completed lease
+type Results struct {
+	Worker     string         `json:"worker"`
+	Iterations int            `json:"iterations"`
+	Crashes    int            `json:"crashes"`
+	Findings   []fuzz.Finding `json:"findings"`
+}
+
+// CoordinatorOptions configures a coordinator
+type CoordinatorOptions struct {
+	// Config is the session's configuration; its Iterations are sharded
+	Config *config.Config
+	// OutputDir receives reproduction files
+	OutputDir string
+	// ShardSize is the number of iterations per lease (default: 100)
+	ShardSize int
+	// LeaseTimeout is how long a worker has to complete a lease before it is
+	// handed to another worker (default: 10m)
+	LeaseTimeout time.Duration
+	Logger       *slog.Logger
+}
+
+// shard is a range of iterations, End excluded
+type shard struct {
+	start, end int
+}
+
+// lease is a shard assigned to a worker
+type lease struct {
+	shard
+	expires time.Time
+}
+
+// Coordinator shards a session's iterations into leases and merges the
+// results workers send back
+type Coordinator struct {
+	opts     CoordinatorOptions
+	logger   *slog.Logger
+	archive  []byte
+	config   []byte
+	chart    string
+	recorder *report.Recorder
+	notifier *notify.Notifier
+
+	mu           sync.Mutex
+	pending      []shard
+	leased       map[string]*lease
+	completed    int
+	total        int
+	deduplicator *runner.Deduplicator
+	minimizer    *runner.Minimizer
+	done         chan struct{}
+}
+
+// NewCoordinator prepares a distributed session for the chart. Seeds from
+// the corpus are folded into the configuration workers receive, so workers
+// need nothing but the coordinator's address.
+func NewCoordinator(chartPath string, opts CoordinatorOptions) (*Coordinator, error) {
+	if opts.ShardSize <= 0 {
+		opts.ShardSize = 100
+	}
+	if opts.LeaseTimeout <= 0 {
+		opts.LeaseTimeout = 10 * time.Minute
+	}
+	if opts.OutputDir == "" {
+		opts.OutputDir = "."
+	}
+	cfg := opts.Config
+	if cfg.Iterations <= 0 {
+		return nil, fmt.Errorf("a distributed session needs an iteration target")
+	}
+
+	archive, err := runner.PackageChart(chartPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Workers load the config from bytes, so nothing in it may point at local files
+	shared := *cfg
+	shared.Seeds = append([]map[string]interface{}{}, cfg.Seeds...)
+	if corpusDir := cfg.ResolveCorpusDir(chartPath); corpusDir != "" {
+		entries, err := corpus.Load(corpusDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load corpus: %w", err)
+		}
+		shared.Seeds = append(shared.Seeds, entries...)
+	}
+	// Generator plugins run here once, like the corpus; oracle plugins are
+	// local programs that workers could not run, so they are dropped
+	plugins := plugin.New(cfg, chartPath)
+	for _, p := range plugin.OfType(plugins, config.PluginGenerator) {
+		inputs, err := p.Generate(context.Background(), plugin.GenerateRequest{Chart: filepath.Base(chartPath), ChartPath: chartPath})
+		if err != nil {
+			return nil, err
+		}
+		shared.Seeds = append(shared.Seeds, inputs...)
+	}
+	if oracles := plugin.OfType(plugins, config.PluginOracle); len(oracles) > 0 {
+		logging.OrDiscard(opts.Logger).Warn("oracle plugins only run in local sessions; workers fuzz without them", "plugins", len(oracles))
+	}
+	if len(shared.Scanners) > 0 {
+		logging.OrDiscard(opts.Logger).Warn("scanners only run in local sessions; workers fuzz without them", "scanners", len(shared.Scanners))
+	}
+	shared.CorpusDir = ""
+	shared.Webhooks = nil
+	shared.OnCrash = nil
+	shared.Tracing = nil
+	shared.Plugins = nil
+	shared.Scanners = nil
+	configData, err := yaml.Marshal(&shared)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+
+	c := &Coordinator{
+		opts:         opts,
+		logger:       logging.OrDiscard(opts.Logger),
+		archive:      archive,
+		config:       configData,
+		chart:        filepath.Base(chartPath),
+		leased:       make(map[string]*lease),
+		deduplicator: runner.NewDeduplicator(),
+		minimizer:    runner.NewMinimizer(opts.OutputDir),
+		done:         make(chan struct{}),
+	}
+	c.recorder = report.NewRecorder(c.chart, cfg.Iterations)
+	notifyOpts := notify.Options{Webhooks: cfg.Webhooks, Logger: c.logger}
+	if cfg.OnCrash != nil {
+		notifyOpts.Command, notifyOpts.Timeout = cfg.ResolveCrashCommand(chartPath), cfg.OnCrash.Timeout
+	}
+	c.notifier = notify.NewWithOptions(notifyOpts)
+	for start := 0; start < cfg.Iterations; start += opts.ShardSize {
+		c.pending = append(c.pending, shard{start: start, end: min(start+opts.ShardSize, cfg.Iterations)})
+	}
+	c.total = len(c.pending)
+	return c, nil
+}
+
+// Handler returns the HTTP handler workers talk to:
+//
+//	GET  /api/v1/chart               the chart as a .tgz archive
+//	GET  /api/v1/config              the session's .helmfuzz.yaml
+//	POST /api/v1/leases              ask for a lease
+//	POST /api/v1/leases/{id}/results report a completed lease
+func (c *Coordinator) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/chart", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/gzip")
+		w.Write(c.archive)
+	})
+	mux.HandleFunc("GET /api/v1/config", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Write(c.config)
+	})
+	mux.HandleFunc("POST /api/v1/leases", c.handleLease)
+	mux.HandleFunc("POST /api/v1/leases/{id}/results", c.handleResults)
+	return mux
+}
+
+// Start serves the coordinator API on addr in the background until ctx is
+// cancelled. It returns once the listener is bound, so address errors
+// surface immediately.
+func (c *Coordinator) Start(ctx context.Context, addr string) (net.Addr, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	server := &http.Server{Handler: c.Handler(), ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+		c.notifier.Close()
+	}()
+	go func() {
+		// Serve only fails once the listener is closed by Shutdown
+		_ = server.Serve(listener)
+	}()
+
+	return listener.Addr(), nil
+}
+
+// Done is closed once every shard has been completed
+func (c *Coordinator) Done() <-chan struct{} {
+	return c.done
+}
+
+// Session returns the merged session so far
+func (c *Coordinator) Session() *report.Session {
+	return c.recorder.Session()
+}
+
+// Progress returns the number of completed and total shards
+func (c *Coordinator) Progress() (completed, total int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.completed, c.total
+}
+
+func (c *Coordinator) handleLease(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Shards of workers that went quiet go back to the queue
+	now := time.Now()
+	for id, l := range c.leased {
+		if now.After(l.expires) {
+			c.logger.Warn("lease expired, reassigning", "lease", id, "start", l.start, "end", l.end)
+			delete(c.leased, id)
+			c.pending = append(c.pending, l.shard)
+		}
+	}
+
+	if len(c.pending) == 0 {
+		writeJSON(w, http.StatusOK, LeaseResponse{Done: c.completed == c.total})
+		return
+	}
+
+	id, err := newID()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s := c.pending[0]
+	c.pending = c.pending[1:]
+	c.leased[id] = &lease{shard: s, expires: now.Add(c.opts.LeaseTimeout)}
+
+	var seen []string
+	for _, f := range c.recorder.Session().Findings {
+		seen = append(seen, f.Reason)
+	}
+	writeJSON(w, http.StatusOK, LeaseResponse{Lease: &Lease{ID: id, Start: s.start, End: s.end, Seen: seen}})
+}
+
+func (c *Coordinator) handleResults(w http.ResponseWriter, r *http.Request) {
+	var results Results
+	if err := json.NewDecoder(r.Body).Decode(&results); err != nil {
+		http.Error(w, fmt.Sprintf("invalid results: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id := r.PathValue("id")
+	l, ok := c.leased[id]
+	if !ok {
+		// The lease expired and went to another worker, which will report it
+		http.Error(w, fmt.Sprintf("lease %q is not active", id), http.StatusConflict)
+		return
+	}
+	delete(c.leased, id)
+
+	for i := 0; i < results.Iterations; i++ {
+		c.recorder.RecordIteration(i < results.Crashes)
+	}
+	for _, f := range results.Findings {
+		if c.deduplicator.IsDuplicate(f.Reason) {
+			continue
+		}
+		c.deduplicator.MarkSeen(f.Reason)
+
+		// Reproduction files are written where the coordinator runs
+		f.ID = runner.FindingID(c.chart, runner.Fingerprint(f.Reason))
+		reproFile, err := c.minimizer.SaveFinding(&runner.Result{Values: f.Values}, f.Reason, f.ID, f.Culprits)
+		if err != nil {
+			c.logger.Warn("failed to save reproduction file", "error", err)
+		}
+		f.ReproFile = reproFile
+		c.recorder.RecordFinding(f)
+		c.notifier.Notify(notify.Finding{
+			Chart:       c.chart,
+			ID:          f.ID,
+			Iteration:   f.Iteration,
+			Fingerprint: f.Fingerprint,
+			Category:    f.Category,
+			Reason:      f.Reason,
+			ReproFile:   reproFile,
+			Flaky:       f.Flaky,
+		})
+		c.logger.Info("new unique crash", "worker", results.Worker, "iteration", f.Iteration, "category", f.Category)
+	}
+
+	c.completed++
+	c.logger.Info("shard completed", "worker", results.Worker, "start", l.start, "end", l.end,
+		"shards", fmt.Sprintf("%d/%d", c.completed, c.total))
+	if c.completed == c.total {
+		close(c.done)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// newID returns a random lease ID
+func newID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate lease ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
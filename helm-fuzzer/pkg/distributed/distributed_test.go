@@ -0,0 +1,130 @@
+package distributed
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/config"
+	"github.com/kasuboski/helm-fuzzer/pkg/fuzz"
+)
+
+func fingerprints(findings []fuzz.Finding) []string {
+	var out []string
+	for _, f := range findings {
+		out = append(out, f.Fingerprint)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func TestDistributedMatchesSingleProcess(t *testing.T) {
+	chartPath, err := filepath.Abs("../../testdata/buggy-chart")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.DefaultConfig()
+	cfg.Iterations = 90
+
+	c, err := NewCoordinator(chartPath, CoordinatorOptions{Config: cfg, OutputDir: t.TempDir(), ShardSize: 20})
+	if err != nil {
+		t.Fatalf("NewCoordinator failed: %v", err)
+	}
+	ts := httptest.NewServer(c.Handler())
+	defer ts.Close()
+
+	var wg sync.WaitGroup
+	leases := make([]int, 3)
+	for i := range leases {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := NewWorker(ts.URL, WorkerOptions{Name: "worker", PollInterval: 10 * time.Millisecond})
+			n, err := w.Run(context.Background())
+			if err != nil {
+				t.Errorf("worker failed: %v", err)
+			}
+			leases[i] = n
+		}()
+	}
+	wg.Wait()
+
+	select {
+	case <-c.Done():
+	default:
+		t.Fatal("expected every shard to be completed")
+	}
+	if total := leases[0] + leases[1] + leases[2]; total != 5 {
+		t.Errorf("expected 5 leases completed, got %d", total)
+	}
+
+	session := c.Session()
+	if session.Iterations != 90 {
+		t.Errorf("expected 90 iterations, got %d", session.Iterations)
+	}
+	for _, f := range session.Findings {
+		if !strings.HasPrefix(f.ReproFile, c.opts.OutputDir) {
+			t.Errorf("expected reproduction files in the coordinator's output, got %q", f.ReproFile)
+		}
+	}
+
+	// The same iterations in one process find the same crashes
+	single, err := fuzz.NewWithOptions(chartPath, fuzz.Options{Config: cfg, OutputDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := single.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, want := fingerprints(session.Findings), fingerprints(result.Findings)
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("expected the single-process findings %v, got %v", want, got)
+	}
+}
+
+func TestCoordinator_ExpiredLease(t *testing.T) {
+	chartPath, err := filepath.Abs("../../testdata/buggy-chart")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.DefaultConfig()
+	cfg.Iterations = 10
+
+	c, err := NewCoordinator(chartPath, CoordinatorOptions{Config: cfg, OutputDir: t.TempDir(), LeaseTimeout: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(c.Handler())
+	defer ts.Close()
+
+	// A worker that takes a lease and vanishes
+	resp, err := http.Post(ts.URL+"/api/v1/leases", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	time.Sleep(5 * time.Millisecond)
+
+	w := NewWorker(ts.URL, WorkerOptions{PollInterval: 10 * time.Millisecond})
+	if n, err := w.Run(context.Background()); err != nil || n != 1 {
+		t.Fatalf("expected the expired lease to be reassigned, got %d leases: %v", n, err)
+	}
+	if got := c.Session().Iterations; got != 10 {
+		t.Errorf("expected 10 iterations, got %d", got)
+	}
+}
+
+func TestNewCoordinator_NeedsIterations(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Iterations = 0
+	if _, err := NewCoordinator("../../testdata/buggy-chart", CoordinatorOptions{Config: cfg}); err == nil {
+		t.Error("expected a session without an iteration target to be rejected")
+	}
+}
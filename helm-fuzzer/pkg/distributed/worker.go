@@ -0,0 +1,218 @@
+package distributed
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/config"
+	"github.com/kasuboski/helm-fuzzer/pkg/fuzz"
+	"github.com/kasuboski/helm-fuzzer/pkg/logging"
+	"github.com/kasuboski/helm-fuzzer/pkg/runner"
+)
+
+// WorkerOptions configures a worker
+type WorkerOptions struct {
+	// Name identifies the worker in the coordinator's logs (default: the hostname)
+	Name string
+	// Workers overrides the config's number of concurrent workers; 0 keeps it
+	Workers int
+	// PollInterval is how long to wait when every shard is leased (default: 1s)
+	PollInterval time.Duration
+	Logger       *slog.Logger
+}
+
+// Worker fuzzes leases from a coordinator until the session is done
+type Worker struct {
+	url    string
+	opts   WorkerOptions
+	logger *slog.Logger
+	client *http.Client
+}
+
+// NewWorker creates a worker for the coordinator at url
+func NewWorker(url string, opts WorkerOptions) *Worker {
+	if opts.Name == "" {
+		opts.Name, _ = os.Hostname()
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = time.Second
+	}
+	return &Worker{
+		url:    strings.TrimSuffix(url, "/"),
+		opts:   opts,
+		logger: logging.OrDiscard(opts.Logger),
+		client: &http.Client{Timeout: time.Minute},
+	}
+}
+
+// Run fetches the chart and config, then fuzzes leases until the
+// coordinator reports the session done or ctx is canceled. It returns the
+// number of leases completed.
+func (w *Worker) Run(ctx context.Context) (int, error) {
+	dir, err := os.MkdirTemp("", "helm-fuzz-worker-")
+	if err != nil {
+		return 0, fmt.Errorf("failed to create work directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	archive, err := w.get(ctx, "/api/v1/chart")
+	if err != nil {
+		return 0, err
+	}
+	chartPath, err := runner.ExpandChart(filepath.Join(dir, "chart"), archive)
+	if err != nil {
+		return 0, err
+	}
+	configData, err := w.get(ctx, "/api/v1/config")
+	if err != nil {
+		return 0, err
+	}
+	configPath := filepath.Join(dir, config.FileName)
+	if err := os.WriteFile(configPath, configData, 0644); err != nil {
+		return 0, fmt.Errorf("failed to save config: %w", err)
+	}
+	cfg, err := config.LoadConfigFile(configPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load config: %w", err)
+	}
+	if w.opts.Workers > 0 {
+		cfg.Workers = w.opts.Workers
+	}
+
+	completed := 0
+	for {
+		var resp LeaseResponse
+		if err := w.post(ctx, "/api/v1/leases", nil, &resp); err != nil {
+			if ctx.Err() != nil {
+				return completed, nil
+			}
+			return completed, err
+		}
+		if resp.Done {
+			return completed, nil
+		}
+		if resp.Lease == nil {
+			select {
+			case <-time.After(w.opts.PollInterval):
+				continue
+			case <-ctx.Done():
+				return completed, nil
+			}
+		}
+
+		results, err := w.fuzz(ctx, chartPath, dir, cfg, resp.Lease)
+		if err != nil {
+			return completed, err
+		}
+		if results == nil {
+			// Interrupted; the lease expires and goes to another worker
+			return completed, nil
+		}
+		if err := w.post(ctx, "/api/v1/leases/"+resp.Lease.ID+"/results", results, nil); err != nil {
+			if ctx.Err() != nil {
+				return completed, nil
+			}
+			return completed, err
+		}
+		completed++
+	}
+}
+
+// fuzz runs a lease's iterations, returning nil results if it was interrupted
+func (w *Worker) fuzz(ctx context.Context, chartPath, dir string, cfg *config.Config, l *Lease) (*Results, error) {
+	leaseCfg := *cfg
+	leaseCfg.Iterations = l.End
+	w.logger.Info("fuzzing lease", "lease", l.ID, "start", l.Start, "end", l.End)
+
+	session, err := fuzz.NewWithOptions(chartPath, fuzz.Options{
+		Config:         &leaseCfg,
+		OutputDir:      filepath.Join(dir, "output"),
+		FirstIteration: l.Start,
+		Seen:           l.Seen,
+		Logger:         w.logger,
+	})
+	if err != nil {
+		return nil, err
+	}
+	result, err := session.Run(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if result.Interrupted {
+		return nil, nil
+	}
+
+	findings := result.Findings
+	if findings == nil {
+		findings = []fuzz.Finding{}
+	}
+	return &Results{
+		Worker:     w.opts.Name,
+		Iterations: result.Iterations,
+		Crashes:    result.Crashes,
+		Findings:   findings,
+	}, nil
+}
+
+// get fetches a coordinator resource
+func (w *Worker) get(ctx context.Context, path string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, w.url+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach coordinator: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("coordinator answered %s for %s: %s", resp.Status, path, strings.TrimSpace(string(data)))
+	}
+	return data, nil
+}
+
+// post sends body as JSON and decodes the response into out, if given
+func (w *Worker) post(ctx context.Context, path string, body, out interface{}) error {
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url+path, &buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach coordinator: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusConflict:
+		// Someone else completes an expired lease; move on
+		w.logger.Warn("lease expired before its results arrived", "path", path)
+		return nil
+	case resp.StatusCode >= 300:
+		data, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("coordinator answered %s for %s: %s", resp.Status, path, strings.TrimSpace(string(data)))
+	case out != nil:
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
@@ -0,0 +1,362 @@
+// Package envtest validates renders against a real API server. It starts
+// etcd and kube-apiserver from the binaries setup-envtest installs, one pair
+// per Kubernetes version, and dry-run creates every rendered object, so the
+// defaulting, validation and admission errors client-only rendering never
+// meets become findings. No controllers run: nothing is scheduled, and
+// objects are only ever checked, never stored, apart from the namespaces
+// they are created in and the releases upgrades are checked against.
+package envtest
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/client-go/rest"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/logging"
+)
+
+// Binaries an environment starts
+const (
+	etcdBinary      = "etcd"
+	apiServerBinary = "kube-apiserver"
+)
+
+// Environment is a running etcd and kube-apiserver
+type Environment struct {
+	// Config reaches the API server as a cluster admin
+	Config *rest.Config
+
+	dir       string
+	etcd      *process
+	apiServer *process
+}
+
+// process is a started binary whose exit is watched
+type process struct {
+	cmd    *exec.Cmd
+	stderr bytes.Buffer
+	// exited is closed once the process has exited
+	exited chan struct{}
+}
+
+// FindAssets returns the directory holding the binaries for a Kubernetes
+// version: assets itself when it holds them, as $KUBEBUILDER_ASSETS does,
+// otherwise the subdirectory setup-envtest installed the version in, such
+// as 1.29.0-linux-amd64, or the latest patch of its minor version.
+func FindAssets(assets, kubeVersion string) (string, error) {
+	if assets == "" {
+		return "", errors.New("no envtest assets: set envtest.assets in the config or KUBEBUILDER_ASSETS (see setup-envtest)")
+	}
+	if hasBinaries(assets) {
+		return assets, nil
+	}
+	entries, err := os.ReadDir(assets)
+	if err != nil {
+		return "", fmt.Errorf("failed to read envtest assets: %w", err)
+	}
+
+	version := strings.TrimPrefix(kubeVersion, "v")
+	minor := version
+	if parts := strings.SplitN(version, ".", 3); len(parts) >= 2 {
+		minor = parts[0] + "." + parts[1]
+	}
+	var candidates []string
+	for _, entry := range entries {
+		name := entry.Name()
+		dir := filepath.Join(assets, name)
+		if !entry.IsDir() || !hasBinaries(dir) {
+			continue
+		}
+		dirVersion, _, _ := strings.Cut(name, "-")
+		if dirVersion == version {
+			return dir, nil
+		}
+		if strings.HasPrefix(dirVersion, minor+".") {
+			candidates = append(candidates, dirVersion+"\x00"+dir)
+		}
+	}
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("no envtest binaries for Kubernetes %s in %s (install them with setup-envtest use %s)", kubeVersion, assets, minor)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return patchOf(candidates[i]) < patchOf(candidates[j])
+	})
+	_, dir, _ := strings.Cut(candidates[len(candidates)-1], "\x00")
+	return dir, nil
+}
+
+// patchOf returns the patch number of a candidate's version
+func patchOf(candidate string) int {
+	version, _, _ := strings.Cut(candidate, "\x00")
+	parts := strings.SplitN(version, ".", 3)
+	if len(parts) < 3 {
+		return 0
+	}
+	patch, _ := strconv.Atoi(parts[2])
+	return patch
+}
+
+// hasBinaries reports whether dir holds both binaries
+func hasBinaries(dir string) bool {
+	for _, name := range []string{etcdBinary, apiServerBinary} {
+		if info, err := os.Stat(filepath.Join(dir, name)); err != nil || info.IsDir() {
+			return false
+		}
+	}
+	return true
+}
+
+// Start starts etcd and kube-apiserver from the binaries in binDir and waits
+// up to timeout for the API server to become ready. Stop removes them.
+func Start(ctx context.Context, binDir string, timeout time.Duration, logger *slog.Logger) (*Environment, error) {
+	logger = logging.OrDiscard(logger)
+	dir, err := os.MkdirTemp("", "helmfuzz-envtest-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create envtest directory: %w", err)
+	}
+	env := &Environment{dir: dir}
+	if err := env.start(ctx, binDir, timeout, logger); err != nil {
+		env.Stop()
+		return nil, err
+	}
+	return env, nil
+}
+
+// StartValidators starts an environment for each Kubernetes version, one
+// shared by versions whose binaries are the same, and returns a validator
+// per version and a function stopping the environments
+func StartValidators(ctx context.Context, assets string, kubeVersions []string, timeout time.Duration, logger *slog.Logger) (map[string]*Validator, func(), error) {
+	var envs []*Environment
+	stop := func() {
+		for _, env := range envs {
+			env.Stop()
+		}
+	}
+	byDir := make(map[string]*Validator)
+	validators := make(map[string]*Validator, len(kubeVersions))
+	for _, kubeVersion := range kubeVersions {
+		binDir, err := FindAssets(assets, kubeVersion)
+		if err != nil {
+			stop()
+			return nil, nil, err
+		}
+		if v, ok := byDir[binDir]; ok {
+			validators[kubeVersion] = v
+			continue
+		}
+		env, err := Start(ctx, binDir, timeout, logger)
+		if err != nil {
+			stop()
+			return nil, nil, fmt.Errorf("failed to start API server for Kubernetes %s: %w", kubeVersion, err)
+		}
+		envs = append(envs, env)
+		v, err := NewValidator(env.Config)
+		if err != nil {
+			stop()
+			return nil, nil, err
+		}
+		byDir[binDir], validators[kubeVersion] = v, v
+	}
+	return validators, stop, nil
+}
+
+// start writes the API server's credentials and starts both binaries
+func (e *Environment) start(ctx context.Context, binDir string, timeout time.Duration, logger *slog.Logger) error {
+	token, err := randomToken()
+	if err != nil {
+		return err
+	}
+	// The token authenticates a member of system:masters, which RBAC lets do anything
+	tokenFile := filepath.Join(e.dir, "tokens.csv")
+	if err := os.WriteFile(tokenFile, []byte(token+`,admin,admin,"system:masters"`+"\n"), 0600); err != nil {
+		return fmt.Errorf("failed to write envtest token: %w", err)
+	}
+	keyFile := filepath.Join(e.dir, "sa.key")
+	if err := writeServiceAccountKey(keyFile); err != nil {
+		return err
+	}
+	etcdPort, err := freePort()
+	if err != nil {
+		return err
+	}
+	apiServerPort, err := freePort()
+	if err != nil {
+		return err
+	}
+
+	etcdURL := "http://127.0.0.1:" + strconv.Itoa(etcdPort)
+	e.etcd, err = startProcess(filepath.Join(binDir, etcdBinary),
+		"--data-dir="+filepath.Join(e.dir, "etcd"),
+		"--listen-client-urls="+etcdURL,
+		"--advertise-client-urls="+etcdURL,
+		"--listen-peer-urls=http://localhost:0",
+		"--unsafe-no-fsync=true",
+	)
+	if err != nil {
+		return err
+	}
+	e.apiServer, err = startProcess(filepath.Join(binDir, apiServerBinary),
+		"--etcd-servers="+etcdURL,
+		"--cert-dir="+filepath.Join(e.dir, "certs"),
+		"--bind-address=127.0.0.1",
+		"--advertise-address=127.0.0.1",
+		"--secure-port="+strconv.Itoa(apiServerPort),
+		"--service-cluster-ip-range=10.0.0.0/24",
+		"--allow-privileged=true",
+		"--authorization-mode=RBAC",
+		"--token-auth-file="+tokenFile,
+		"--service-account-issuer=https://kubernetes.default.svc",
+		"--service-account-key-file="+keyFile,
+		"--service-account-signing-key-file="+keyFile,
+		// No controller creates the default service account pods would need
+		"--disable-admission-plugins=ServiceAccount",
+	)
+	if err != nil {
+		return err
+	}
+
+	host := "https://127.0.0.1:" + strconv.Itoa(apiServerPort)
+	// The API server signs its own serving certificate into the cert dir
+	e.Config = &rest.Config{
+		Host:            host,
+		BearerToken:     token,
+		TLSClientConfig: rest.TLSClientConfig{Insecure: true},
+		QPS:             -1,
+	}
+	logger.Debug("starting API server", "binaries", binDir, "host", host)
+	return e.waitReady(ctx, host, token, timeout)
+}
+
+// waitReady polls the API server's readyz endpoint until it answers, either
+// process exits or the timeout passes
+func (e *Environment) waitReady(ctx context.Context, host, token string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	client := &http.Client{
+		Timeout:   time.Second,
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
+	}
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, host+"/readyz", nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		if resp, err := client.Do(req); err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return nil
+			}
+		}
+
+		select {
+		case <-e.etcd.exited:
+			return e.etcd.failure()
+		case <-e.apiServer.exited:
+			return e.apiServer.failure()
+		case <-ctx.Done():
+			return fmt.Errorf("API server not ready after %s", timeout)
+		case <-ticker.C:
+		}
+	}
+}
+
+// Stop kills both binaries and removes their data
+func (e *Environment) Stop() error {
+	for _, p := range []*process{e.apiServer, e.etcd} {
+		if p != nil {
+			p.stop()
+		}
+	}
+	return os.RemoveAll(e.dir)
+}
+
+// startProcess starts a binary with its stderr kept for errors
+func startProcess(path string, args ...string) (*process, error) {
+	p := &process{exited: make(chan struct{})}
+	p.cmd = exec.Command(path, args...)
+	p.cmd.Stderr = &p.stderr
+	if err := p.cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %s: %w", filepath.Base(path), err)
+	}
+	go func() {
+		p.cmd.Wait()
+		close(p.exited)
+	}()
+	return p, nil
+}
+
+// failure describes a process that exited early, with its last line of stderr
+func (p *process) failure() error {
+	name := filepath.Base(p.cmd.Path)
+	lines := strings.Split(strings.TrimSpace(p.stderr.String()), "\n")
+	if msg := strings.TrimSpace(lines[len(lines)-1]); msg != "" {
+		return fmt.Errorf("%s exited: %s", name, msg)
+	}
+	return fmt.Errorf("%s exited: %s", name, p.cmd.ProcessState)
+}
+
+// stop kills the process and waits for it to exit
+func (p *process) stop() {
+	select {
+	case <-p.exited:
+		return
+	default:
+	}
+	p.cmd.Process.Kill()
+	<-p.exited
+}
+
+// freePort returns a local TCP port nothing is listening on
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, fmt.Errorf("failed to find a free port: %w", err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// randomToken returns a bearer token for the API server
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate envtest token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// writeServiceAccountKey writes the key the API server signs and verifies
+// service account tokens with, which it requires to start
+func writeServiceAccountKey(path string) error {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return fmt.Errorf("failed to generate service account key: %w", err)
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write service account key: %w", err)
+	}
+	return nil
+}
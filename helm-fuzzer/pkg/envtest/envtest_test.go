@@ -0,0 +1,207 @@
+package envtest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// installBinaries writes empty stand-ins for the binaries into dir
+func installBinaries(t *testing.T, dir string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{etcdBinary, apiServerBinary} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestFindAssets(t *testing.T) {
+	assets := t.TempDir()
+	for _, version := range []string{"1.29.0-linux-amd64", "1.29.3-linux-amd64", "1.30.0-linux-amd64"} {
+		installBinaries(t, filepath.Join(assets, version))
+	}
+	if err := os.MkdirAll(filepath.Join(assets, "1.31.0-linux-amd64"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	for kubeVersion, want := range map[string]string{
+		"1.29.0":  "1.29.0-linux-amd64",
+		"v1.30.0": "1.30.0-linux-amd64",
+		"1.29.7":  "1.29.3-linux-amd64",
+	} {
+		got, err := FindAssets(assets, kubeVersion)
+		if err != nil || got != filepath.Join(assets, want) {
+			t.Errorf("expected %s for %s, got %s, %v", want, kubeVersion, got, err)
+		}
+	}
+	// A directory without both binaries is not an install
+	if _, err := FindAssets(assets, "1.31.0"); err == nil || !strings.Contains(err.Error(), "setup-envtest use 1.31") {
+		t.Errorf("expected a missing version to name the install command, got %v", err)
+	}
+	if _, err := FindAssets("", "1.29.0"); err == nil {
+		t.Error("expected an error without assets")
+	}
+
+	// $KUBEBUILDER_ASSETS points at the binaries themselves, used for every version
+	single := filepath.Join(assets, "1.30.0-linux-amd64")
+	if got, err := FindAssets(single, "1.28.0"); err != nil || got != single {
+		t.Errorf("expected the binaries directory itself, got %s, %v", got, err)
+	}
+}
+
+func TestStatusReasons(t *testing.T) {
+	invalid := metav1.Status{
+		Message: `Deployment.apps "web" is invalid: spec.replicas: Invalid value: -1: must be greater than or equal to 0`,
+		Details: &metav1.StatusDetails{Causes: []metav1.StatusCause{
+			{Field: "spec.replicas", Message: "Invalid value: -1: must be greater than or equal to 0"},
+			{Field: "spec.template.spec.containers[0].image", Message: "Required value"},
+		}},
+	}
+	want := []string{
+		"API server rejected Deployment: spec.replicas: Invalid value: must be greater than or equal to 0",
+		"API server rejected Deployment: spec.template.spec.containers[0].image: Required value",
+	}
+	if got := statusReasons(ReasonPrefix, "Deployment", invalid); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected reasons per field\n%v\ngot\n%v", want, got)
+	}
+
+	badRequest := metav1.Status{Message: `Service in version "v1" cannot be handled as a Service: strict decoding error: unknown field "spec.prots"`}
+	if got := statusReasons(ReasonPrefix, "Service", badRequest); len(got) != 1 || !strings.HasPrefix(got[0], "API server rejected Service: Service in version") {
+		t.Errorf("expected the message without causes, got %v", got)
+	}
+}
+
+func TestDecodeObjects(t *testing.T) {
+	objects := decodeObjects(`---
+# Source: app/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+spec:
+  ports:
+    - port: 80
+---
+---
+data: {}
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: other
+`)
+	if len(objects) != 2 || objects[0].GetKind() != "Service" || objects[1].GetNamespace() != "other" {
+		t.Fatalf("expected the Service and Deployment, got %v", objects)
+	}
+}
+
+// TestValidate starts a real API server when setup-envtest binaries are installed
+func TestValidate(t *testing.T) {
+	assets := os.Getenv("KUBEBUILDER_ASSETS")
+	if assets == "" || testing.Short() {
+		t.Skip("KUBEBUILDER_ASSETS is not set")
+	}
+	validators, stop, err := StartValidators(context.Background(), assets, []string{"1.30.0"}, time.Minute, nil)
+	if err != nil {
+		t.Fatalf("StartValidators failed: %v", err)
+	}
+	defer stop()
+
+	reasons, err := validators["1.30.0"].Validate(context.Background(), `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: -1
+  selector:
+    matchLabels: {app: web}
+  template:
+    metadata:
+      labels: {app: web}
+    spec:
+      containers:
+        - name: web
+          image: nginx
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: web
+data:
+  key: value
+---
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: web
+`, "fuzz")
+	if err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	if len(reasons) != 1 || !strings.HasPrefix(reasons[0], "API server rejected Deployment: spec.replicas") {
+		t.Errorf("expected only the negative replicas rejected, got %v", reasons)
+	}
+}
+
+// TestUpgrade starts a real API server when setup-envtest binaries are installed
+func TestUpgrade(t *testing.T) {
+	assets := os.Getenv("KUBEBUILDER_ASSETS")
+	if assets == "" || testing.Short() {
+		t.Skip("KUBEBUILDER_ASSETS is not set")
+	}
+	validators, stop, err := StartValidators(context.Background(), assets, []string{"1.30.0"}, time.Minute, nil)
+	if err != nil {
+		t.Fatalf("StartValidators failed: %v", err)
+	}
+	defer stop()
+
+	deployment := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  selector:
+    matchLabels: {app: %s}
+  template:
+    metadata:
+      labels: {app: %s}
+    spec:
+      containers:
+        - name: web
+          image: nginx
+`
+	v := validators["1.30.0"]
+	release, err := v.Install(context.Background(), fmt.Sprintf(deployment, "web", "web"), "fuzz")
+	if err != nil {
+		t.Fatalf("Install failed: %v", err)
+	}
+
+	// The installed objects live apart, so creating them again still validates
+	if reasons, err := v.Validate(context.Background(), fmt.Sprintf(deployment, "web", "web"), "fuzz"); err != nil || len(reasons) != 0 {
+		t.Errorf("expected the defaults to validate, got %v, %v", reasons, err)
+	}
+
+	reasons, err := release.Upgrade(context.Background(), fmt.Sprintf(deployment, "web", "web"), "fuzz")
+	if err != nil || len(reasons) != 0 {
+		t.Errorf("expected an unchanged upgrade to pass, got %v, %v", reasons, err)
+	}
+	reasons, err = release.Upgrade(context.Background(), fmt.Sprintf(deployment, "api", "api"), "fuzz")
+	if err != nil {
+		t.Fatalf("Upgrade failed: %v", err)
+	}
+	if len(reasons) != 1 || !strings.HasPrefix(reasons[0], "Upgrade rejected Deployment: spec.selector") {
+		t.Errorf("expected the changed selector rejected, got %v", reasons)
+	}
+}
@@ -0,0 +1,158 @@
+package envtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// UpgradeReasonPrefix starts the crash reason of every object the API server
+// rejects as an update of the installed release
+const UpgradeReasonPrefix = "Upgrade rejected "
+
+// Release is a render installed on an API server, which later renders are
+// dry-run applied over as upgrades. It is safe for concurrent use.
+type Release struct {
+	v *Validator
+	// namespaces maps the namespaces the render used to those it was installed in
+	namespaces map[string]string
+	// objects holds the installed objects by kind, namespace and name
+	objects map[objectKey]installed
+}
+
+// objectKey names an object of a render
+type objectKey struct {
+	kind      schema.GroupKind
+	namespace string
+	name      string
+}
+
+// installed is an object a release created
+type installed struct {
+	resource        schema.GroupVersionResource
+	namespace       string
+	resourceVersion string
+}
+
+// Install creates the namespaced objects of a rendered manifest for real,
+// those without a namespace in namespace, as the release upgrades start from.
+// Each namespace the render uses is replaced by one of the release's own, so
+// releases never meet each other or the objects Validate dry-runs. Objects
+// the server rejects are left out, and cluster-scoped objects are not
+// installed, so their creates keep being checked.
+func (v *Validator) Install(ctx context.Context, manifest, namespace string) (*Release, error) {
+	r := &Release{v: v, namespaces: make(map[string]string), objects: make(map[objectKey]installed)}
+	for _, obj := range decodeObjects(manifest) {
+		mapping, key, ok, err := v.namespaced(obj, namespace)
+		if err != nil {
+			return nil, err
+		} else if !ok {
+			continue
+		}
+		ns, err := r.namespace(ctx, key.namespace)
+		if err != nil {
+			return nil, err
+		}
+		obj = obj.DeepCopy()
+		obj.SetNamespace(ns)
+		live, err := v.client.Resource(mapping.Resource).Namespace(ns).Create(ctx, obj, metav1.CreateOptions{})
+		var status apierrors.APIStatus
+		switch {
+		case err == nil:
+			r.objects[key] = installed{resource: mapping.Resource, namespace: ns, resourceVersion: live.GetResourceVersion()}
+		case errors.As(err, &status) && !isUnavailable(err):
+		default:
+			return nil, fmt.Errorf("failed to reach API server: %w", err)
+		}
+	}
+	return r, nil
+}
+
+// Upgrade dry-run updates each object of a rendered manifest the release
+// installed with its new version, and returns a crash reason for each
+// problem the API server reports, sorted, such as immutable selectors,
+// shrunk volume claims or changed cluster IPs. Objects the release lacks are
+// skipped: their creates are Validate's to check.
+func (r *Release) Upgrade(ctx context.Context, manifest, namespace string) ([]string, error) {
+	seen := make(map[string]bool)
+	var reasons []string
+	for _, obj := range decodeObjects(manifest) {
+		_, key, ok, err := r.v.namespaced(obj, namespace)
+		if err != nil {
+			return nil, err
+		}
+		old, installed := r.objects[key]
+		if !ok || !installed {
+			continue
+		}
+		obj = obj.DeepCopy()
+		obj.SetNamespace(old.namespace)
+		// Updates only ever dry-run, so the installed version stays current
+		obj.SetResourceVersion(old.resourceVersion)
+		_, err = r.v.client.Resource(old.resource).Namespace(old.namespace).Update(ctx, obj, metav1.UpdateOptions{
+			DryRun:          []string{metav1.DryRunAll},
+			FieldValidation: "Strict",
+		})
+		var status apierrors.APIStatus
+		switch {
+		case err == nil:
+			continue
+		case errors.As(err, &status) && !isUnavailable(err):
+			for _, reason := range statusReasons(UpgradeReasonPrefix, key.kind.Kind, status.Status()) {
+				if !seen[reason] {
+					seen[reason] = true
+					reasons = append(reasons, reason)
+				}
+			}
+		default:
+			return nil, fmt.Errorf("failed to reach API server: %w", err)
+		}
+	}
+	sort.Strings(reasons)
+	return reasons, nil
+}
+
+// namespaced returns the mapping and key of an object the server serves in
+// a namespace, and false for cluster-scoped objects and unserved kinds
+func (v *Validator) namespaced(obj *unstructured.Unstructured, namespace string) (*meta.RESTMapping, objectKey, bool, error) {
+	gvk := obj.GroupVersionKind()
+	mapping, err := v.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if meta.IsNoMatchError(err) {
+		return nil, objectKey{}, false, nil
+	} else if err != nil {
+		return nil, objectKey{}, false, fmt.Errorf("failed to map %s: %w", gvk, err)
+	}
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		return nil, objectKey{}, false, nil
+	}
+	ns := obj.GetNamespace()
+	if ns == "" {
+		ns = namespace
+	}
+	return mapping, objectKey{kind: gvk.GroupKind(), namespace: ns, name: obj.GetName()}, true, nil
+}
+
+// namespace returns the namespace the release installs objects of a
+// render's namespace in, creating it the first time
+func (r *Release) namespace(ctx context.Context, rendered string) (string, error) {
+	if ns, ok := r.namespaces[rendered]; ok {
+		return ns, nil
+	}
+	r.v.mu.Lock()
+	r.v.releases++
+	ns := "helmfuzz-upgrade-" + strconv.Itoa(r.v.releases)
+	r.v.mu.Unlock()
+	if err := r.v.ensureNamespace(ctx, ns); err != nil {
+		return "", err
+	}
+	r.namespaces[rendered] = ns
+	return ns, nil
+}
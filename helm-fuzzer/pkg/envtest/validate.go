@@ -0,0 +1,212 @@
+package envtest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+)
+
+// ReasonPrefix starts the crash reason of every object the API server rejects
+const ReasonPrefix = "API server rejected "
+
+// namespaces is the resource namespaces are created through
+var namespaces = schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}
+
+// Validator dry-run creates rendered objects on an API server. It is safe
+// for concurrent use.
+type Validator struct {
+	client dynamic.Interface
+	mapper meta.RESTMapper
+
+	mu sync.Mutex
+	// created holds the namespaces created so far
+	created map[string]bool
+	// releases counts the namespaces installed releases were given
+	releases int
+}
+
+// NewValidator returns a validator creating objects through cfg
+func NewValidator(cfg *rest.Config) (*Validator, error) {
+	client, err := dynamic.NewForConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create API client: %w", err)
+	}
+	disc, err := discovery.NewDiscoveryClientForConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create discovery client: %w", err)
+	}
+	return &Validator{
+		client:  client,
+		mapper:  restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(disc)),
+		created: map[string]bool{"default": true},
+	}, nil
+}
+
+// Validate dry-run creates each object of a rendered manifest, those without
+// a namespace in namespace, and returns a crash reason for each problem the
+// API server reports, sorted. Objects of kinds the server does not serve,
+// such as custom resources of CRDs it lacks, are skipped, as are documents
+// that do not decode, which other oracles report. The error is for an API
+// server that cannot be reached, not for objects it rejects.
+func (v *Validator) Validate(ctx context.Context, manifest, namespace string) ([]string, error) {
+	seen := make(map[string]bool)
+	var reasons []string
+	for _, obj := range decodeObjects(manifest) {
+		found, err := v.create(ctx, obj, namespace)
+		if err != nil {
+			return nil, err
+		}
+		for _, reason := range found {
+			if !seen[reason] {
+				seen[reason] = true
+				reasons = append(reasons, reason)
+			}
+		}
+	}
+	sort.Strings(reasons)
+	return reasons, nil
+}
+
+// create dry-run creates one object and returns the reasons it was rejected
+func (v *Validator) create(ctx context.Context, obj *unstructured.Unstructured, namespace string) ([]string, error) {
+	gvk := obj.GroupVersionKind()
+	mapping, err := v.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if meta.IsNoMatchError(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to map %s: %w", gvk, err)
+	}
+
+	resource := v.client.Resource(mapping.Resource)
+	var client dynamic.ResourceInterface = resource
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		ns := obj.GetNamespace()
+		if ns == "" {
+			ns = namespace
+		}
+		if err := v.ensureNamespace(ctx, ns); err != nil {
+			return nil, err
+		}
+		client = resource.Namespace(ns)
+	}
+
+	_, err = client.Create(ctx, obj, metav1.CreateOptions{
+		DryRun: []string{metav1.DryRunAll},
+		// Unknown and duplicate fields are rejected rather than dropped
+		FieldValidation: "Strict",
+	})
+	var status apierrors.APIStatus
+	switch {
+	case err == nil, apierrors.IsAlreadyExists(err):
+		return nil, nil
+	case errors.As(err, &status) && !isUnavailable(err):
+		return statusReasons(ReasonPrefix, gvk.Kind, status.Status()), nil
+	default:
+		return nil, fmt.Errorf("failed to reach API server: %w", err)
+	}
+}
+
+// ensureNamespace creates a namespace the first time an object is created in it
+func (v *Validator) ensureNamespace(ctx context.Context, name string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.created[name] {
+		return nil
+	}
+	ns := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Namespace",
+		"metadata":   map[string]interface{}{"name": name},
+	}}
+	_, err := v.client.Resource(namespaces).Create(ctx, ns, metav1.CreateOptions{})
+	if err != nil && !apierrors.IsAlreadyExists(err) {
+		// An invalid name is the object's problem; its create reports it
+		if !apierrors.IsInvalid(err) {
+			return fmt.Errorf("failed to create namespace %s: %w", name, err)
+		}
+	}
+	v.created[name] = true
+	return nil
+}
+
+// isUnavailable reports whether an API error is about the server rather
+// than the object
+func isUnavailable(err error) bool {
+	return apierrors.IsServiceUnavailable(err) || apierrors.IsServerTimeout(err) ||
+		apierrors.IsTimeout(err) || apierrors.IsTooManyRequests(err) ||
+		apierrors.IsUnauthorized(err) || apierrors.IsInternalError(err)
+}
+
+// statusReasons turns a rejection into crash reasons starting with prefix:
+// one per invalid field when the server lists them, named by field rather
+// than value so inputs breaking a field the same way share a fingerprint,
+// otherwise its message
+func statusReasons(prefix, kind string, status metav1.Status) []string {
+	if status.Details == nil || len(status.Details.Causes) == 0 {
+		return []string{prefix + kind + ": " + status.Message}
+	}
+	var reasons []string
+	for _, cause := range status.Details.Causes {
+		message := causeMessage(cause.Message)
+		if cause.Field != "" {
+			message = cause.Field + ": " + message
+		}
+		reasons = append(reasons, prefix+kind+": "+message)
+	}
+	return reasons
+}
+
+// causeMessage drops the offending value from an invalid value message,
+// "Invalid value: <value>: <detail>", keeping what was wrong with it
+func causeMessage(message string) string {
+	const invalid = "Invalid value: "
+	if !strings.HasPrefix(message, invalid) {
+		return message
+	}
+	if i := strings.LastIndex(message, ": "); i >= len(invalid) {
+		return invalid + message[i+2:]
+	}
+	return message
+}
+
+// decodeObjects decodes the documents of a YAML stream into objects,
+// skipping empty documents, those without a kind and everything after a
+// document that does not parse
+func decodeObjects(manifest string) []*unstructured.Unstructured {
+	var objects []*unstructured.Unstructured
+	dec := yaml.NewDecoder(strings.NewReader(manifest))
+	for {
+		var doc map[string]interface{}
+		if err := dec.Decode(&doc); err != nil {
+			return objects
+		}
+		if doc == nil || doc["kind"] == nil {
+			continue
+		}
+		data, err := json.Marshal(doc)
+		if err != nil {
+			continue
+		}
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(data); err != nil {
+			continue
+		}
+		objects = append(objects, obj)
+	}
+}
@@ -0,0 +1,394 @@
+// Package explain finds out why a values file crashes a chart: which of its
+// values the crash needs, which values the failing expression expected to be
+// set, and where in the templates it fails.
+package explain
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/runner"
+)
+
+// maxPasses bounds the ablation passes; each pass after the first only
+// retries the values that earlier removals may have made unnecessary
+const maxPasses = 3
+
+// ReplayFunc renders values against the chart, returning the crash reason or
+// an empty string if the values render cleanly
+type ReplayFunc func(values map[string]interface{}) string
+
+// Condition is a value the crash depends on
+type Condition struct {
+	// Path is the value path, e.g. ingress.hosts[0].host
+	Path string `json:"path"`
+	// Value is the value the crash needs, nil when Unset
+	Value interface{} `json:"value,omitempty"`
+	// Unset means the failing expression reads the path but no value sets it
+	Unset bool `json:"unset,omitempty"`
+}
+
+// String renders the condition as path=value or "path unset"
+func (c Condition) String() string {
+	if c.Unset {
+		return c.Path + " unset"
+	}
+	return c.Path + "=" + formatValue(c.Value)
+}
+
+// Explanation is why a values file crashes the chart
+type Explanation struct {
+	Reason      string `json:"reason"`
+	Fingerprint string `json:"fingerprint"`
+	Category    string `json:"category"`
+	// Location is the template file and line the crash is attributed to
+	Location string `json:"location,omitempty"`
+	// Conditions are the values the crash requires, then the paths it expects to be set
+	Conditions []Condition `json:"conditions"`
+	// Minimal is the smallest values file found that still crashes the same way
+	Minimal map[string]interface{} `json:"minimal"`
+	// Leaves and MinimalLeaves count the leaf values of the input and of Minimal
+	Leaves        int `json:"leaves"`
+	MinimalLeaves int `json:"minimalLeaves"`
+	// Renders is the number of renders the ablation took
+	Renders int `json:"renders"`
+	// Snippet is the attributed template source, empty if unavailable
+	Snippet string `json:"snippet,omitempty"`
+}
+
+// Summary states the explanation in one sentence, e.g. "crash requires
+// ingress.enabled=true and ingress.hosts[0].host unset at templates/ingress.yaml:23"
+func (e *Explanation) Summary() string {
+	var b strings.Builder
+	if len(e.Conditions) == 0 {
+		b.WriteString("crash occurs with the chart defaults")
+	} else {
+		parts := make([]string, len(e.Conditions))
+		for i, c := range e.Conditions {
+			parts[i] = c.String()
+		}
+		b.WriteString("crash requires ")
+		if len(parts) > 1 {
+			b.WriteString(strings.Join(parts[:len(parts)-1], ", ") + " and ")
+		}
+		b.WriteString(parts[len(parts)-1])
+	}
+	if e.Location != "" {
+		b.WriteString(" at " + e.Location)
+	}
+	return b.String()
+}
+
+// Explain ablates values to the smallest set that still crashes the same way:
+// it removes whole subtrees where it can and single values where it must.
+// Removed values fall back to the chart defaults, as when installing, so the
+// remaining values are those the crash depends on. defaults are the chart's
+// default values, used to find the paths the failing expression expects.
+func Explain(values, defaults map[string]interface{}, replay ReplayFunc) (*Explanation, error) {
+	reason := replay(values)
+	if reason == "" {
+		return nil, fmt.Errorf("values do not crash the chart")
+	}
+
+	e := &explainer{replay: replay, fingerprint: runner.Fingerprint(reason), renders: 1}
+	minimal := copyValue(values).(map[string]interface{})
+	for pass := 0; pass < maxPasses; pass++ {
+		before := countLeaves(minimal)
+		minimal = e.ablate(minimal, nil)
+		if countLeaves(minimal) == before {
+			break
+		}
+	}
+
+	explanation := &Explanation{
+		Reason:        reason,
+		Fingerprint:   e.fingerprint,
+		Category:      runner.CategorizeReason(reason),
+		Minimal:       minimal,
+		Leaves:        countLeaves(values),
+		MinimalLeaves: countLeaves(minimal),
+		Renders:       e.renders,
+	}
+	// A null value deletes the default, as Helm does, so it reads as unset
+	unset := make(map[string]bool)
+	for _, leaf := range leafPaths(minimal, nil) {
+		value, _ := lookup(minimal, leaf)
+		path := formatPath(leaf)
+		unset[path] = value == nil
+		explanation.Conditions = append(explanation.Conditions, Condition{Path: path, Value: value, Unset: value == nil})
+	}
+	if attr := runner.Attribute(reason); attr != nil {
+		explanation.Location = attr.String()
+		if path := unsetPath(attr.ValuePath, runner.MergeValues(defaults, minimal)); path != "" && !unset[path] {
+			explanation.Conditions = append(explanation.Conditions, Condition{Path: path, Unset: true})
+		}
+	}
+	return explanation, nil
+}
+
+// explainer ablates values while they keep crashing with one fingerprint
+type explainer struct {
+	replay      ReplayFunc
+	fingerprint string
+	renders     int
+}
+
+// crashes reports whether values still crash the same way
+func (e *explainer) crashes(values map[string]interface{}) bool {
+	e.renders++
+	reason := e.replay(values)
+	return reason != "" && runner.Fingerprint(reason) == e.fingerprint
+}
+
+// ablate removes the value at path if the crash survives without it, and
+// otherwise tries its children
+func (e *explainer) ablate(values map[string]interface{}, path []interface{}) map[string]interface{} {
+	if len(path) > 0 {
+		if _, isKey := path[len(path)-1].(string); isKey {
+			candidate := without(values, path)
+			if e.crashes(candidate) {
+				return candidate
+			}
+		}
+	}
+
+	node, ok := lookup(values, path)
+	if !ok {
+		return values
+	}
+	for _, child := range children(node) {
+		values = e.ablate(values, append(append([]interface{}(nil), path...), child))
+	}
+	return values
+}
+
+// children lists the steps into a node that ablation descends: the keys of a
+// map, in order, and the indexes of list elements that are maps
+func children(node interface{}) []interface{} {
+	var steps []interface{}
+	switch n := node.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(n))
+		for key := range n {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			steps = append(steps, key)
+		}
+	case []interface{}:
+		for i, item := range n {
+			if _, ok := item.(map[string]interface{}); ok {
+				steps = append(steps, i)
+			}
+		}
+	}
+	return steps
+}
+
+// leafPaths lists the paths of values that ablation does not descend into
+func leafPaths(node interface{}, path []interface{}) [][]interface{} {
+	steps := children(node)
+	if len(steps) == 0 {
+		if len(path) == 0 {
+			return nil
+		}
+		return [][]interface{}{path}
+	}
+	var paths [][]interface{}
+	for _, step := range steps {
+		paths = append(paths, leafPaths(childAt(node, step), append(append([]interface{}(nil), path...), step))...)
+	}
+	// A list whose elements are not all maps is a value in its own right
+	if list, ok := node.([]interface{}); ok && len(steps) < len(list) {
+		return [][]interface{}{path}
+	}
+	return paths
+}
+
+// countLeaves counts the leaf values of values
+func countLeaves(values map[string]interface{}) int {
+	return len(leafPaths(values, nil))
+}
+
+// childAt steps into a map or list
+func childAt(node interface{}, step interface{}) interface{} {
+	switch s := step.(type) {
+	case string:
+		m, _ := node.(map[string]interface{})
+		return m[s]
+	case int:
+		l, _ := node.([]interface{})
+		if s < len(l) {
+			return l[s]
+		}
+	}
+	return nil
+}
+
+// lookup returns the value at path
+func lookup(values map[string]interface{}, path []interface{}) (interface{}, bool) {
+	var node interface{} = values
+	for _, step := range path {
+		switch s := step.(type) {
+		case string:
+			m, ok := node.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+			if node, ok = m[s]; !ok {
+				return nil, false
+			}
+		case int:
+			l, ok := node.([]interface{})
+			if !ok || s >= len(l) {
+				return nil, false
+			}
+			node = l[s]
+		}
+	}
+	return node, true
+}
+
+// without returns a copy of values with the map key at path removed
+func without(values map[string]interface{}, path []interface{}) map[string]interface{} {
+	result := copyValue(values).(map[string]interface{})
+	parent, ok := lookup(result, path[:len(path)-1])
+	if !ok {
+		return result
+	}
+	if m, ok := parent.(map[string]interface{}); ok {
+		delete(m, path[len(path)-1].(string))
+	}
+	return result
+}
+
+// copyValue deep-copies maps and lists
+func copyValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for key, value := range t {
+			m[key] = copyValue(value)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(t))
+		for i, value := range t {
+			l[i] = copyValue(value)
+		}
+		return l
+	default:
+		return v
+	}
+}
+
+// unsetPath returns the first step of a .Values expression that merged
+// values do not set, e.g. optional.config for .Values.optional.config.key
+// when optional has no config. It is empty when the expression is not a
+// .Values path or every step is set.
+func unsetPath(expr string, merged map[string]interface{}) string {
+	rest, ok := strings.CutPrefix(expr, ".Values.")
+	if !ok {
+		return ""
+	}
+	var node interface{} = merged
+	var path []string
+	for _, key := range strings.Split(rest, ".") {
+		path = append(path, key)
+		m, ok := node.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		value, ok := m[key]
+		if !ok || value == nil {
+			return strings.Join(path, ".")
+		}
+		node = value
+	}
+	return ""
+}
+
+// formatPath renders a path as ingress.hosts[0].host
+func formatPath(path []interface{}) string {
+	var b strings.Builder
+	for _, step := range path {
+		switch s := step.(type) {
+		case string:
+			if b.Len() > 0 {
+				b.WriteByte('.')
+			}
+			b.WriteString(s)
+		case int:
+			b.WriteString("[" + strconv.Itoa(s) + "]")
+		}
+	}
+	return b.String()
+}
+
+// formatValue renders a value compactly, as JSON
+func formatValue(v interface{}) string {
+	var b strings.Builder
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return fmt.Sprint(v)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// Formats lists the supported explanation formats
+var Formats = []string{"text", "json"}
+
+// Write renders an explanation in the given format
+func Write(w io.Writer, format string, e *Explanation) error {
+	switch format {
+	case "text":
+		return WriteText(w, e)
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(e)
+	default:
+		return fmt.Errorf("unsupported format %q (supported: %s)", format, strings.Join(Formats, ", "))
+	}
+}
+
+// WriteText prints the summary, the error, the minimal values and the template source
+func WriteText(w io.Writer, e *Explanation) error {
+	var b strings.Builder
+	summary := e.Summary()
+	fmt.Fprintf(&b, "%s%s\n\n", strings.ToUpper(summary[:1]), summary[1:])
+	fmt.Fprintf(&b, "%s\n", e.Reason)
+	fingerprint := e.Fingerprint
+	if len(fingerprint) > 12 {
+		fingerprint = fingerprint[:12]
+	}
+	fmt.Fprintf(&b, "Category: %s, fingerprint %s\n", e.Category, fingerprint)
+
+	fmt.Fprintf(&b, "\nMinimal values (%d of %d leaf value(s), %d render(s)):\n", e.MinimalLeaves, e.Leaves, e.Renders)
+	if len(e.Minimal) == 0 {
+		b.WriteString("  {}\n")
+	} else {
+		data, err := yaml.Marshal(e.Minimal)
+		if err != nil {
+			return fmt.Errorf("failed to encode values: %w", err)
+		}
+		for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+			fmt.Fprintf(&b, "  %s\n", line)
+		}
+	}
+
+	if e.Snippet != "" {
+		fmt.Fprintf(&b, "\n%s:\n%s", e.Location, e.Snippet)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,86 @@
+package explain
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/runner"
+)
+
+func TestExplain(t *testing.T) {
+	defaults := map[string]interface{}{
+		"ingress": map[string]interface{}{"enabled": false, "tls": map[string]interface{}{"secret": "tls"}},
+	}
+	nilPointer := `Error: template: app/templates/ingress.yaml:23:18: executing "app/templates/ingress.yaml" at <.Values.ingress.tls.secret>: nil pointer evaluating interface {}.secret`
+
+	// The chart crashes when ingress is enabled without TLS settings
+	replay := func(values map[string]interface{}) string {
+		merged := runner.MergeValues(defaults, values)
+		ingress := merged["ingress"].(map[string]interface{})
+		if ingress["enabled"] == true && ingress["tls"] == nil {
+			return nilPointer
+		}
+		if merged["replicas"] == "many" {
+			return "Error: parse error at line 7"
+		}
+		return ""
+	}
+
+	values := map[string]interface{}{
+		"replicas": "many",
+		"image":    map[string]interface{}{"tag": "1.0", "pullPolicy": "Always"},
+		"ingress": map[string]interface{}{
+			"enabled": true,
+			"tls":     nil,
+			"hosts":   []interface{}{map[string]interface{}{"host": "example.com", "paths": []interface{}{"/"}}},
+		},
+	}
+	e, err := Explain(values, defaults, replay)
+	if err != nil {
+		t.Fatalf("Explain failed: %v", err)
+	}
+
+	want := map[string]interface{}{"ingress": map[string]interface{}{"enabled": true, "tls": nil}}
+	if !reflect.DeepEqual(e.Minimal, want) {
+		t.Errorf("expected minimal values %v, got %v", want, e.Minimal)
+	}
+	if e.Leaves != 7 || e.MinimalLeaves != 2 {
+		t.Errorf("expected 2 of 7 leaves, got %d of %d", e.MinimalLeaves, e.Leaves)
+	}
+	if got, want := e.Summary(), "crash requires ingress.enabled=true and ingress.tls unset at templates/ingress.yaml:23:18"; got != want {
+		t.Errorf("expected summary %q, got %q", want, got)
+	}
+	if e.Category != "nil pointer" {
+		t.Errorf("expected the nil pointer category, got %q", e.Category)
+	}
+
+	var b strings.Builder
+	if err := Write(&b, "text", e); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if !strings.HasPrefix(b.String(), "Crash requires ingress.enabled=true") {
+		t.Errorf("expected the summary first, got %s", b.String())
+	}
+
+	if _, err := Explain(map[string]interface{}{}, defaults, replay); err == nil {
+		t.Error("expected values that render cleanly to be rejected")
+	}
+}
+
+func TestUnsetPath(t *testing.T) {
+	merged := map[string]interface{}{
+		"optional": map[string]interface{}{"enabled": true, "config": map[string]interface{}{}},
+	}
+	tests := map[string]string{
+		".Values.optional.config.key": "optional.config.key",
+		".Values.optional.enabled":    "",
+		".Values.missing.key":         "missing",
+		".Release.Name":               "",
+	}
+	for expr, want := range tests {
+		if got := unsetPath(expr, merged); got != want {
+			t.Errorf("unsetPath(%q) = %q, want %q", expr, got, want)
+		}
+	}
+}
@@ -0,0 +1,347 @@
+// Package findingsdb keeps the crash history of charts across sessions: when
+// each fingerprint was first and last seen, against which chart version, and
+// whether it has been fixed. It is a SQLite database, through a driver in pure
+// Go so builds need no C toolchain; sessions recording findings at once update
+// it in transactions rather than overwriting each other, and it can be queried
+// with any SQLite client beyond what the findings command offers.
+package findingsdb
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	// Registers the sqlite driver
+	_ "modernc.org/sqlite"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/report"
+	"github.com/kasuboski/helm-fuzzer/pkg/runner"
+)
+
+// FileName is the database kept in the working directory by default
+const FileName = ".helmfuzz-findings.db"
+
+// schemaVersion is the user_version of databases in the current schema
+const schemaVersion = 1
+
+// schema creates the findings table; times are UTC in timeFormat, which
+// sorts as it reads, and fixed_at is NULL while a finding is open
+const schema = `
+CREATE TABLE IF NOT EXISTS findings (
+	chart         TEXT NOT NULL,
+	fingerprint   TEXT NOT NULL,
+	category      TEXT NOT NULL,
+	severity      TEXT NOT NULL,
+	location      TEXT NOT NULL DEFAULT '',
+	reason        TEXT NOT NULL,
+	repro_file    TEXT NOT NULL DEFAULT '',
+	status        TEXT NOT NULL,
+	first_version TEXT NOT NULL DEFAULT '',
+	last_version  TEXT NOT NULL DEFAULT '',
+	first_seen    TEXT NOT NULL,
+	last_seen     TEXT NOT NULL,
+	sessions      INTEGER NOT NULL DEFAULT 0,
+	fixed_at      TEXT,
+	reopened      INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY (chart, fingerprint)
+);
+CREATE INDEX IF NOT EXISTS findings_last_seen ON findings (last_seen);
+`
+
+// timeFormat is how times are stored: fixed width, so they compare as text
+const timeFormat = "2006-01-02T15:04:05.000000000Z"
+
+// columns are the columns of a Record, in the order scanRecords reads them
+const columns = `chart, fingerprint, category, severity, location, reason, repro_file, status,
+	first_version, last_version, first_seen, last_seen, sessions, fixed_at, reopened`
+
+// Finding statuses
+const (
+	StatusOpen  = "open"
+	StatusFixed = "fixed"
+)
+
+// Severities, from most to least severe
+const (
+	SeverityCritical = "critical"
+	SeverityHigh     = "high"
+	SeverityMedium   = "medium"
+	SeverityLow      = "low"
+)
+
+// Severities lists the severities from most to least severe
+var Severities = []string{SeverityCritical, SeverityHigh, SeverityMedium, SeverityLow}
+
+// Severity ranks a crash category: panics take Helm itself down and races
+// corrupt renders nondeterministically, nil pointers and type mismatches fail
+// installs on plausible values, and memory growth and renders that time out
+// take down or tie up the processes rendering the chart, and template and
+// parse errors usually need unusual ones
+func Severity(category string) string {
+	switch category {
+	case runner.CategoryPanic, runner.CategoryRace:
+		return SeverityCritical
+	case runner.CategoryNilPointer, runner.CategoryType, runner.CategoryMemory, runner.CategoryTimeout:
+		return SeverityHigh
+	case runner.CategoryTemplate, runner.CategoryParse:
+		return SeverityMedium
+	default:
+		return SeverityLow
+	}
+}
+
+// Record is the history of one crash fingerprint in one chart
+type Record struct {
+	// ID is the finding's ID (see runner.FindingID), derived from the chart
+	// and fingerprint rather than stored
+	ID          string `json:"id"`
+	Chart       string `json:"chart"`
+	Fingerprint string `json:"fingerprint"`
+	Category    string `json:"category"`
+	Severity    string `json:"severity"`
+	Location    string `json:"location,omitempty"`
+	Reason      string `json:"reason"`
+	ReproFile   string `json:"reproFile,omitempty"`
+	Status      string `json:"status"`
+	// FirstVersion and LastVersion are the chart versions the crash was first and last seen in
+	FirstVersion string    `json:"firstVersion,omitempty"`
+	LastVersion  string    `json:"lastVersion,omitempty"`
+	FirstSeen    time.Time `json:"firstSeen"`
+	LastSeen     time.Time `json:"lastSeen"`
+	// Sessions counts the sessions that found the crash
+	Sessions int `json:"sessions"`
+	// FixedAt is when the finding was marked fixed, nil while open
+	FixedAt *time.Time `json:"fixedAt,omitempty"`
+	// Reopened counts the times a fixed finding was found again
+	Reopened int `json:"reopened,omitempty"`
+}
+
+// DB is an open findings database
+type DB struct {
+	db *sql.DB
+}
+
+// Open opens the database at path, creating it and its directory if there is
+// none. Sessions writing to it at once wait up to 30s for each other.
+func Open(path string) (*DB, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create findings database directory: %w", err)
+	}
+	// Transactions take the write lock when they begin, so concurrent
+	// sessions queue rather than fail to upgrade a read lock
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(30000)&_txlock=immediate")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open findings database %s: %w", path, err)
+	}
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open findings database %s: %w", path, err)
+	}
+	return &DB{db: db}, nil
+}
+
+// migrate creates the schema of a new database and refuses one written by a
+// newer version
+func migrate(db *sql.DB) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var version int
+	if err := tx.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+	if version > schemaVersion {
+		return fmt.Errorf("schema version %d is newer than this version of helm-fuzz supports (%d)", version, schemaVersion)
+	}
+	if _, err := tx.Exec(schema); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", schemaVersion)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Close closes the database
+func (db *DB) Close() error {
+	return db.db.Close()
+}
+
+// RecordSession adds the findings of a session of chart at version, seen at
+// the given time, in one transaction. Findings are matched by fingerprint,
+// recomputed from the reason as baselines do; known ones are updated and fixed
+// ones reopened. Returns the number of new and reopened findings.
+func (db *DB) RecordSession(chart, version string, findings []report.Finding, at time.Time) (added, reopened int, err error) {
+	tx, err := db.db.Begin()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to record findings: %w", err)
+	}
+	defer tx.Rollback()
+
+	seen := make(map[string]bool)
+	for _, f := range findings {
+		fingerprint := runner.Fingerprint(f.Reason)
+		if seen[fingerprint] {
+			continue
+		}
+		seen[fingerprint] = true
+
+		var status string
+		err := tx.QueryRow(`SELECT status FROM findings WHERE chart = ? AND fingerprint = ?`, chart, fingerprint).Scan(&status)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			_, err = tx.Exec(`INSERT INTO findings (chart, fingerprint, category, severity, location, reason, repro_file,
+				status, first_version, last_version, first_seen, last_seen, sessions)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 1)`,
+				chart, fingerprint, f.Category, Severity(f.Category),
+				f.Location(), f.Reason, f.ReproFile, StatusOpen, version, version, formatTime(at), formatTime(at))
+			added++
+		case err == nil:
+			reopen := 0
+			if status == StatusFixed {
+				reopen = 1
+			}
+			reopened += reopen
+			_, err = tx.Exec(`UPDATE findings SET location = ?, reason = ?, repro_file = ?, last_version = ?, last_seen = ?,
+				sessions = sessions + 1, status = ?, fixed_at = NULL, reopened = reopened + ?
+				WHERE chart = ? AND fingerprint = ?`,
+				f.Location(), f.Reason, f.ReproFile, version, formatTime(at), StatusOpen, reopen, chart, fingerprint)
+		}
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to record finding %s: %w", runner.FindingID(chart, fingerprint), err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, 0, fmt.Errorf("failed to record findings: %w", err)
+	}
+	return added, reopened, nil
+}
+
+// Filter selects records; empty fields match everything
+type Filter struct {
+	Chart    string
+	Status   string
+	Severity string
+	Category string
+	// Since keeps records last seen at or after it, unless zero
+	Since time.Time
+}
+
+// List returns the records matching filter, most severe first, then most recently seen
+func (db *DB) List(filter Filter) ([]*Record, error) {
+	var where []string
+	var args []interface{}
+	for _, c := range []struct{ column, value string }{
+		{"chart", filter.Chart},
+		{"status", filter.Status},
+		{"severity", filter.Severity},
+		{"category", filter.Category},
+	} {
+		if c.value != "" {
+			where = append(where, c.column+" = ?")
+			args = append(args, c.value)
+		}
+	}
+	if !filter.Since.IsZero() {
+		where = append(where, "last_seen >= ?")
+		args = append(args, formatTime(filter.Since))
+	}
+	query := "SELECT " + columns + " FROM findings"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	// Unknown severities rank after the known ones
+	rank := "CASE severity"
+	for i, s := range Severities {
+		rank += fmt.Sprintf(" WHEN '%s' THEN %d", s, i)
+	}
+	rank += fmt.Sprintf(" ELSE %d END", len(Severities))
+	return db.query(query+" ORDER BY "+rank+", last_seen DESC", args...)
+}
+
+// Lookup returns the records whose fingerprint starts with prefix, in chart
+// if it is not empty, so short fingerprints can be used as on the command
+// line. A finding ID is looked up in its own chart.
+func (db *DB) Lookup(chart, prefix string) ([]*Record, error) {
+	if idChart, short, ok := runner.ParseFindingID(prefix); ok {
+		if chart != "" && chart != idChart {
+			return nil, nil
+		}
+		chart, prefix = idChart, short
+	}
+	return db.query("SELECT "+columns+" FROM findings WHERE (? = '' OR chart = ?) AND substr(fingerprint, 1, ?) = ? ORDER BY chart, first_seen",
+		chart, chart, len(prefix), prefix)
+}
+
+// MarkFixed marks the one record matching a finding ID or fingerprint prefix as fixed
+func (db *DB) MarkFixed(chart, prefix string, at time.Time) (*Record, error) {
+	records, err := db.Lookup(chart, prefix)
+	if err != nil {
+		return nil, err
+	}
+	switch len(records) {
+	case 0:
+		return nil, fmt.Errorf("no finding matches %q", prefix)
+	case 1:
+	default:
+		return nil, fmt.Errorf("%q matches %d findings; give more of the fingerprint or --chart", prefix, len(records))
+	}
+	r := records[0]
+	if _, err := db.db.Exec(`UPDATE findings SET status = ?, fixed_at = ? WHERE chart = ? AND fingerprint = ?`,
+		StatusFixed, formatTime(at), r.Chart, r.Fingerprint); err != nil {
+		return nil, fmt.Errorf("failed to mark %s as fixed: %w", r.ID, err)
+	}
+	r.Status, r.FixedAt = StatusFixed, &at
+	return r, nil
+}
+
+// query returns the records a SELECT of columns finds
+func (db *DB) query(query string, args ...interface{}) ([]*Record, error) {
+	rows, err := db.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query findings database: %w", err)
+	}
+	defer rows.Close()
+
+	var records []*Record
+	for rows.Next() {
+		r := &Record{}
+		var firstSeen, lastSeen string
+		var fixedAt sql.NullString
+		if err := rows.Scan(&r.Chart, &r.Fingerprint, &r.Category, &r.Severity, &r.Location, &r.Reason, &r.ReproFile, &r.Status,
+			&r.FirstVersion, &r.LastVersion, &firstSeen, &lastSeen, &r.Sessions, &fixedAt, &r.Reopened); err != nil {
+			return nil, fmt.Errorf("failed to read findings database: %w", err)
+		}
+		r.ID = runner.FindingID(r.Chart, r.Fingerprint)
+		if r.FirstSeen, err = time.Parse(timeFormat, firstSeen); err != nil {
+			return nil, fmt.Errorf("invalid first_seen of %s: %w", r.ID, err)
+		}
+		if r.LastSeen, err = time.Parse(timeFormat, lastSeen); err != nil {
+			return nil, fmt.Errorf("invalid last_seen of %s: %w", r.ID, err)
+		}
+		if fixedAt.Valid {
+			t, err := time.Parse(timeFormat, fixedAt.String)
+			if err != nil {
+				return nil, fmt.Errorf("invalid fixed_at of %s: %w", r.ID, err)
+			}
+			r.FixedAt = &t
+		}
+		records = append(records, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read findings database: %w", err)
+	}
+	return records, nil
+}
+
+// formatTime formats a time as it is stored
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeFormat)
+}
@@ -0,0 +1,169 @@
+package findingsdb
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/report"
+	"github.com/kasuboski/helm-fuzzer/pkg/runner"
+)
+
+var (
+	nilPointer = report.Finding{Category: runner.CategoryNilPointer, Reason: "template: app/templates/deployment.yaml:12:20: nil pointer evaluating interface {}.port"}
+	panicked   = report.Finding{Category: runner.CategoryPanic, Reason: "Panic: runtime error: index out of range [3] with length 3"}
+)
+
+func TestRecordSession(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db", FileName)
+	db, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open failed on a missing file: %v", err)
+	}
+	defer func() { db.Close() }()
+
+	first := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	// The same crash on another line is one finding
+	moved := nilPointer
+	moved.Reason = "template: app/templates/deployment.yaml:14:20: nil pointer evaluating interface {}.port"
+	if added, reopened, err := db.RecordSession("app", "0.1.0", []report.Finding{nilPointer, moved, panicked}, first); err != nil || added != 2 || reopened != 0 {
+		t.Errorf("expected 2 new findings, got %d new and %d reopened, %v", added, reopened, err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	db, err = Open(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	records, err := db.List(Filter{})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(records) != 2 || records[0].Severity != SeverityCritical || records[1].Severity != SeverityHigh {
+		t.Fatalf("expected the panic then the nil pointer, got %+v", records)
+	}
+
+	fingerprint := runner.Fingerprint(nilPointer.Reason)
+	if _, err := db.MarkFixed("", fingerprint[:8], first); err != nil {
+		t.Fatalf("MarkFixed failed: %v", err)
+	}
+	if open, _ := db.List(Filter{Status: StatusOpen}); len(open) != 1 || open[0].Category != runner.CategoryPanic {
+		t.Errorf("expected only the panic to be open, got %+v", open)
+	}
+
+	// A fixed finding found again is reopened, and its history kept
+	later := first.Add(24 * time.Hour)
+	if added, reopened, err := db.RecordSession("app", "0.2.0", []report.Finding{nilPointer}, later); err != nil || added != 0 || reopened != 1 {
+		t.Errorf("expected 1 reopened finding, got %d new and %d reopened, %v", added, reopened, err)
+	}
+	found, err := db.Lookup("app", fingerprint)
+	if err != nil || len(found) != 1 {
+		t.Fatalf("expected one finding, got %+v, %v", found, err)
+	}
+	r := found[0]
+	if r.Status != StatusOpen || r.FixedAt != nil || r.Reopened != 1 || r.Sessions != 2 ||
+		r.FirstVersion != "0.1.0" || r.LastVersion != "0.2.0" || !r.FirstSeen.Equal(first) || !r.LastSeen.Equal(later) {
+		t.Errorf("unexpected history %+v", r)
+	}
+
+	if recent, _ := db.List(Filter{Since: later}); len(recent) != 1 || recent[0].Fingerprint != fingerprint {
+		t.Errorf("expected only the finding seen since %s, got %+v", later, recent)
+	}
+	if other, _ := db.List(Filter{Chart: "other"}); len(other) != 0 {
+		t.Errorf("expected no findings for another chart, got %+v", other)
+	}
+}
+
+func TestMarkFixed_Ambiguous(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), FileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.RecordSession("app", "", []report.Finding{nilPointer}, time.Now())
+	db.RecordSession("web", "", []report.Finding{nilPointer}, time.Now())
+
+	if _, err := db.MarkFixed("", "", time.Now()); err == nil {
+		t.Error("expected an error for a prefix matching several findings")
+	}
+	if _, err := db.MarkFixed("", "zz", time.Now()); err == nil {
+		t.Error("expected an error for a prefix matching nothing")
+	}
+	if r, err := db.MarkFixed("web", runner.Fingerprint(nilPointer.Reason), time.Now()); err != nil || r.Chart != "web" {
+		t.Errorf("expected the chart to disambiguate, got %+v, %v", r, err)
+	}
+	// A finding ID names its chart
+	id := runner.FindingID("app", runner.Fingerprint(nilPointer.Reason))
+	if r, err := db.MarkFixed("", id, time.Now()); err != nil || r.Chart != "app" || r.ID != id {
+		t.Errorf("expected the ID to pick the app finding, got %+v, %v", r, err)
+	}
+	if records, _ := db.Lookup("web", id); len(records) != 0 {
+		t.Errorf("expected an ID of another chart to match nothing, got %+v", records)
+	}
+}
+
+func TestRecordSession_Concurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	const sessions = 8
+	errs := make(chan error, sessions)
+	for i := 0; i < sessions; i++ {
+		go func() {
+			// Each session opens the database of its own, as separate processes do
+			db, err := Open(path)
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer db.Close()
+			_, _, err = db.RecordSession("app", "0.1.0", []report.Finding{nilPointer, panicked}, time.Now())
+			errs <- err
+		}()
+	}
+	for i := 0; i < sessions; i++ {
+		if err := <-errs; err != nil {
+			t.Fatalf("RecordSession failed: %v", err)
+		}
+	}
+
+	db, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	records, err := db.List(Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0].Sessions != sessions || records[1].Sessions != sessions {
+		t.Errorf("expected both findings seen by all %d sessions, got %+v", sessions, records)
+	}
+}
+
+func TestOpen_NotADatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	if err := os.WriteFile(path, []byte(`{"kind": "Baseline"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(path); err == nil {
+		t.Error("expected an error for a file of another kind")
+	}
+}
+
+func TestSeverity(t *testing.T) {
+	for category, want := range map[string]string{
+		runner.CategoryPanic:      SeverityCritical,
+		runner.CategoryRace:       SeverityCritical,
+		runner.CategoryNilPointer: SeverityHigh,
+		runner.CategoryMemory:     SeverityHigh,
+		runner.CategoryTimeout:    SeverityHigh,
+		runner.CategoryTemplate:   SeverityMedium,
+		"unknown":                 SeverityLow,
+	} {
+		if got := Severity(category); got != want {
+			t.Errorf("Severity(%q) = %s, want %s", category, got, want)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package fuzz
+
+import (
+	"fmt"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/config"
+	"github.com/kasuboski/helm-fuzzer/pkg/quantity"
+)
+
+// podSpecPaths are where each workload kind keeps its pod spec
+var podSpecPaths = map[string][]string{
+	"Pod":                   {"spec"},
+	"Deployment":            {"spec", "template", "spec"},
+	"StatefulSet":           {"spec", "template", "spec"},
+	"ReplicaSet":            {"spec", "template", "spec"},
+	"ReplicationController": {"spec", "template", "spec"},
+	"DaemonSet":             {"spec", "template", "spec"},
+	"Job":                   {"spec", "template", "spec"},
+	"CronJob":               {"spec", "jobTemplate", "spec", "template", "spec"},
+}
+
+// replicaPaths are where workload kinds that run several pods at once keep
+// how many, 1 when unset; DaemonSets count one pod, as on a single node
+var replicaPaths = map[string][]string{
+	"Deployment":            {"spec", "replicas"},
+	"StatefulSet":           {"spec", "replicas"},
+	"ReplicaSet":            {"spec", "replicas"},
+	"ReplicationController": {"spec", "replicas"},
+	"Job":                   {"spec", "parallelism"},
+	"CronJob":               {"spec", "jobTemplate", "spec", "parallelism"},
+}
+
+// checkBudget returns a crash reason for each cap of the budget that the
+// rendered workloads exceed together: each workload's pod requests, or
+// limits where requests are unset, times its replicas, raised to the
+// maxReplicas of an autoscaler targeting it. Like invariant reasons, the
+// reasons leave out the totals so every input over a cap shares its
+// fingerprint.
+func checkBudget(manifest string, budget config.Budget) []string {
+	resources, err := decodeResources(manifest)
+	if err != nil {
+		return []string{fmt.Sprintf("Budget unchecked: rendered manifest does not parse: %v", err)}
+	}
+
+	scaled := make(map[string]float64)
+	for _, resource := range resources {
+		if resource["kind"] != "HorizontalPodAutoscaler" {
+			continue
+		}
+		target := mapAt(resource, "spec", "scaleTargetRef")
+		if n, ok := number(valueAt(resource, "spec", "maxReplicas")); ok {
+			key := fmt.Sprintf("%v/%v", target["kind"], target["name"])
+			scaled[key] = max(scaled[key], n)
+		}
+	}
+
+	var cpu, memory, pods float64
+	for _, resource := range resources {
+		kind, _ := resource["kind"].(string)
+		specPath, ok := podSpecPaths[kind]
+		if !ok {
+			continue
+		}
+		replicas := 1.0
+		if path, ok := replicaPaths[kind]; ok {
+			if n, ok := number(valueAt(resource, path...)); ok {
+				replicas = n
+			}
+		}
+		key := fmt.Sprintf("%v/%v", kind, mapAt(resource, "metadata")["name"])
+		replicas = max(replicas, scaled[key])
+
+		podCPU, podMemory := podRequests(mapAt(resource, specPath...))
+		cpu += replicas * podCPU
+		memory += replicas * podMemory
+		pods += replicas
+	}
+
+	var reasons []string
+	if limit, err := quantity.Parse(budget.CPU); err == nil && cpu > limit {
+		reasons = append(reasons, fmt.Sprintf("Budget exceeded: workloads request more than %s CPU", budget.CPU))
+	}
+	if limit, err := quantity.Parse(budget.Memory); err == nil && memory > limit {
+		reasons = append(reasons, fmt.Sprintf("Budget exceeded: workloads request more than %s memory", budget.Memory))
+	}
+	if budget.Pods > 0 && pods > float64(budget.Pods) {
+		reasons = append(reasons, fmt.Sprintf("Budget exceeded: workloads run more than %d pods", budget.Pods))
+	}
+	return reasons
+}
+
+// podRequests returns the CPU and memory a pod spec requests: its
+// containers' together, or its largest init container's if that is more
+func podRequests(spec map[string]interface{}) (float64, float64) {
+	var cpu, memory float64
+	containers, _ := spec["containers"].([]interface{})
+	for _, c := range containers {
+		container, _ := c.(map[string]interface{})
+		cpu += containerRequest(container, "cpu")
+		memory += containerRequest(container, "memory")
+	}
+	initContainers, _ := spec["initContainers"].([]interface{})
+	for _, c := range initContainers {
+		container, _ := c.(map[string]interface{})
+		cpu = max(cpu, containerRequest(container, "cpu"))
+		memory = max(memory, containerRequest(container, "memory"))
+	}
+	return cpu, memory
+}
+
+// containerRequest returns a container's request for a resource, which
+// defaults to its limit; quantities that do not parse count as nothing
+func containerRequest(container map[string]interface{}, resource string) float64 {
+	for _, field := range []string{"requests", "limits"} {
+		v, ok := mapAt(container, "resources", field)[resource]
+		if !ok {
+			continue
+		}
+		q, err := quantity.Parse(fmt.Sprint(v))
+		if err != nil {
+			return 0
+		}
+		return q
+	}
+	return 0
+}
+
+// valueAt returns the value at a path of nested maps, nil if absent
+func valueAt(m map[string]interface{}, path ...string) interface{} {
+	if len(path) == 0 {
+		return m
+	}
+	return mapAt(m, path[:len(path)-1]...)[path[len(path)-1]]
+}
+
+// mapAt returns the map at a path of nested maps, nil if absent
+func mapAt(m map[string]interface{}, path ...string) map[string]interface{} {
+	for _, key := range path {
+		m, _ = m[key].(map[string]interface{})
+	}
+	return m
+}
+
+// number converts a decoded YAML number
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
@@ -0,0 +1,107 @@
+package fuzz
+
+import (
+	"fmt"
+	"strings"
+
+	"pgregory.net/rapid"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/generator"
+	"github.com/kasuboski/helm-fuzzer/pkg/runner"
+)
+
+// dnsChars are the characters of DNS-1123 labels, which release names and
+// namespaces are; labels start and end with one of the first 36
+const dnsChars = "abcdefghijklmnopqrstuvwxyz0123456789-"
+
+// appVersions are shapes of appVersion charts meet in the wild: quoted
+// numbers YAML reads as floats, v prefixes, pre-releases and build metadata
+// that are invalid in image tags or labels, and values too long for a label
+var appVersions = []string{
+	"",
+	"1.0",
+	"v1.2.3",
+	"1.2.3-rc.1",
+	"1.2.3+build.5",
+	"2024.01.15",
+	"latest",
+	"sha-4f2c9e1",
+	"v" + strings.Repeat("1.", 32) + "0",
+}
+
+// kubeSuffixes are what managed Kubernetes distributions append to their
+// versions; semver constraints without a -0 suffix reject the pre-releases
+var kubeSuffixes = []string{"", "-gke.1200", "-eks-2d98532", "+k3s1", "+rke2r1"}
+
+// drawBuiltIns returns the built-in objects an iteration renders with, drawn
+// from the iteration's seed as generated inputs are, or nil unless
+// Options.BuiltIns is set. Each object often keeps the value the session
+// would render with anyway.
+func (s *Session) drawBuiltIns(iteration int, kubeVersion string) *runner.BuiltIns {
+	if !s.opts.BuiltIns {
+		return nil
+	}
+	releaseName, namespace := s.opts.ReleaseName, s.opts.Namespace
+	if releaseName == "" {
+		releaseName = runner.DefaultReleaseName
+	}
+	if namespace == "" {
+		namespace = runner.DefaultNamespace
+	}
+	return builtInsGenerator(runner.BuiltIns{
+		ReleaseName: releaseName,
+		Namespace:   namespace,
+		Revision:    1,
+		AppVersion:  s.appVersion,
+		KubeVersion: kubeVersion,
+	}).Example(generator.Seed(s.cfg.Seed, generator.StreamBuiltIns, iteration))
+}
+
+// builtInsGenerator draws built-in objects around base: release names and
+// namespaces of every valid length, revisions of a first install, an
+// upgrade and a long-lived release, appVersion shapes, and patch levels and
+// distribution suffixes of base's Kubernetes minor version
+func builtInsGenerator(base runner.BuiltIns) *rapid.Generator[*runner.BuiltIns] {
+	return rapid.Custom(func(t *rapid.T) *runner.BuiltIns {
+		return &runner.BuiltIns{
+			ReleaseName: rapid.OneOf(rapid.Just(base.ReleaseName), dnsLabel(53)).Draw(t, "releaseName"),
+			Namespace:   rapid.OneOf(rapid.Just(base.Namespace), dnsLabel(63)).Draw(t, "namespace"),
+			Revision:    rapid.OneOf(rapid.Just(base.Revision), rapid.IntRange(2, 10), rapid.IntRange(11, 1_000_000)).Draw(t, "revision"),
+			AppVersion:  rapid.OneOf(rapid.Just(base.AppVersion), rapid.SampledFrom(appVersions)).Draw(t, "appVersion"),
+			KubeVersion: kubePatchVersion(base.KubeVersion).Draw(t, "kubeVersion"),
+		}
+	})
+}
+
+// dnsLabel draws DNS-1123 labels of up to maxLen characters, favouring the
+// shortest and the longest, at which names built from them collide or
+// overflow
+func dnsLabel(maxLen int) *rapid.Generator[string] {
+	return rapid.Custom(func(t *rapid.T) string {
+		n := rapid.OneOf(rapid.Just(1), rapid.Just(maxLen), rapid.IntRange(1, maxLen)).Draw(t, "length")
+		b := make([]byte, n)
+		for i := range b {
+			chars := dnsChars
+			if i == 0 || i == n-1 {
+				chars = dnsChars[:36]
+			}
+			b[i] = chars[rapid.IntRange(0, len(chars)-1).Draw(t, "char")]
+		}
+		return string(b)
+	})
+}
+
+// kubePatchVersion draws patch levels and distribution suffixes of a
+// Kubernetes version's minor version, or the version itself when it has no
+// minor version
+func kubePatchVersion(version string) *rapid.Generator[string] {
+	parts := strings.SplitN(strings.TrimPrefix(version, "v"), ".", 3)
+	if len(parts) < 2 {
+		return rapid.Just(version)
+	}
+	return rapid.Custom(func(t *rapid.T) string {
+		patch := rapid.IntRange(0, 40).Draw(t, "patch")
+		suffix := rapid.SampledFrom(kubeSuffixes).Draw(t, "suffix")
+		return fmt.Sprintf("v%s.%s.%d%s", parts[0], parts[1], patch, suffix)
+	})
+}
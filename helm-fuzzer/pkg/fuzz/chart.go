@@ -0,0 +1,106 @@
+package fuzz
+
+import (
+	"path/filepath"
+	"testing"
+
+	"pgregory.net/rapid"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/runner"
+)
+
+// Chart fuzzes a chart as part of a Go test:
+//
+//	func TestChart(t *testing.T) {
+//		fuzz.Chart(t, "../charts/app", fuzz.Options{})
+//	}
+//
+// Seeds and corpus entries are rendered first, then rapid draws inputs from
+// the chart's schema. An interesting crash fails the test; rapid shrinks it to
+// a minimal input, which is saved as a reproduction file in OutputDir
+// (testdata/helm-fuzz/<test name> by default). The number of inputs follows
+// rapid's -rapid.checks flag, so -short keeps the run quick; Timeout,
+// FirstIteration, Seen, FailFast and Hooks do not apply.
+func Chart(t *testing.T, chartPath string, opts Options) {
+	t.Helper()
+
+	if opts.OutputDir == "" {
+		opts.OutputDir = filepath.Join("testdata", "helm-fuzz", t.Name())
+	}
+	s, err := NewWithOptions(chartPath, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	cfg := s.cfg
+	oracle := runner.NewOracleWithConfig(cfg.IgnoreErrors, cfg.UninterestingPatterns)
+	minimizer := runner.NewMinimizer(opts.OutputDir)
+	minimizer.KeepOutput = cfg.OutputLimit()
+	runners := make(map[string]*runner.Runner, len(cfg.KubeVersions))
+	for _, kubeVersion := range cfg.KubeVersions {
+		r, err := s.newRunner(kubeVersion, cfg.Lookup, usualFiles(cfg.Files), s.logger)
+		if err != nil {
+			t.Fatalf("failed to create runner: %v", err)
+		}
+		runners[kubeVersion] = r
+	}
+
+	// render returns the result and its crash reason, empty unless the
+	// values crash the chart in an interesting way
+	render := func(r *runner.Runner, values map[string]interface{}) (*runner.Result, string) {
+		result := r.Run(values)
+		if !oracle.IsCrash(result) || !oracle.IsInteresting(result) {
+			return result, ""
+		}
+		return result, oracle.GetCrashReason(result)
+	}
+	save := func(result *runner.Result, reason string) string {
+		reproFile, err := minimizer.SaveReproduction(result, reason)
+		if err != nil {
+			t.Logf("failed to save reproduction file: %v", err)
+		}
+		return reproFile
+	}
+
+	for i := range s.seeds {
+		kubeVersion := cfg.KubeVersions[i%len(cfg.KubeVersions)]
+		if result, reason := render(runners[kubeVersion], s.Input(i)); reason != "" {
+			t.Errorf("seed %d crashes on Kubernetes %s: %s\nreproduction file: %s", i, kubeVersion, reason, save(result, reason))
+		}
+	}
+
+	// rapid replays the minimal failing input last, so the last failure is the one to save
+	var (
+		failed       *runner.Result
+		failedReason string
+	)
+	defer func() {
+		if failed != nil {
+			t.Logf("reproduction file: %s", save(failed, failedReason))
+		}
+	}()
+
+	values := s.gen.Generate()
+	rapid.Check(t, func(rt *rapid.T) {
+		input := values.Draw(rt, "values")
+		if len(s.opts.Values) > 0 {
+			input = runner.MergeValues(input, s.opts.Values)
+		}
+		kubeVersion := rapid.SampledFrom(cfg.KubeVersions).Draw(rt, "kubeVersion")
+		r := runners[kubeVersion]
+		if len(cfg.Lookup) > 0 || len(cfg.Files) > 0 {
+			found := lookupGenerator(cfg.Lookup).Draw(rt, "lookup")
+			files := filesGenerator(cfg.Files).Draw(rt, "files")
+			var err error
+			if r, err = s.newRunner(kubeVersion, found, files, s.logger); err != nil {
+				rt.Fatalf("failed to create runner: %v", err)
+			}
+		}
+
+		if result, reason := render(r, input); reason != "" {
+			failed, failedReason = result, reason
+			rt.Fatalf("chart crashed: %s", reason)
+		}
+	})
+}
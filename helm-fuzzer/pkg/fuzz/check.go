@@ -0,0 +1,242 @@
+package fuzz
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/envtest"
+	"github.com/kasuboski/helm-fuzzer/pkg/explain"
+	"github.com/kasuboski/helm-fuzzer/pkg/plugin"
+	"github.com/kasuboski/helm-fuzzer/pkg/runner"
+	"github.com/kasuboski/helm-fuzzer/pkg/scanner"
+)
+
+// checker runs a session's oracles over renders
+type checker struct {
+	s          *Session
+	oracle     *runner.Oracle
+	validators map[string]*envtest.Validator
+	releases   map[string]*envtest.Release
+	// baseline holds the scanner findings of the chart's defaults, which
+	// renders are not blamed for
+	baseline map[string]bool
+	// defaults is the render of the chart's defaults, nil unless renders are
+	// compared with it
+	defaults *renderedDefaults
+	// times holds the latest render times, nil unless slow renders are checked
+	times *renderTimes
+	// races is how many concurrent renders each successful render is
+	// compared with, 0 unless races are checked
+	races int
+	// clusterWarned holds the templates warned about needing a cluster
+	clusterWarned sync.Map
+}
+
+// verdict is what the oracles made of a render
+type verdict struct {
+	crashed bool
+	// category is the crash category, empty unless it crashed
+	category string
+	// reasons are the interesting crash reasons of the render, the API
+	// server's, oracle plugins' and scanners' included
+	reasons []string
+}
+
+// check runs the oracles over a render of values against kubeVersion by r,
+// each one only if the render passed the ones before. The error is for an
+// oracle that could not run, not for a render it rejects.
+func (c *checker) check(ctx context.Context, r *runner.Runner, res *runner.Result, kubeVersion string, values map[string]interface{}) (verdict, error) {
+	s, oracle := c.s, c.oracle
+	var v verdict
+	// add records the reasons an oracle found in category
+	add := func(category string, found []string) {
+		if len(found) > 0 && !v.crashed {
+			v.crashed, v.category = true, category
+		}
+		for _, reason := range found {
+			if oracle.IsInterestingReason(reason) {
+				v.reasons = append(v.reasons, reason)
+			}
+		}
+	}
+
+	if oracle.IsCrash(res) {
+		reason := oracle.GetCrashReason(res)
+		v.crashed, v.category = true, runner.CategorizeReason(reason)
+		// A lookup that finds nothing fails client-only whatever the values
+		if runner.NeedsCluster(reason) {
+			c.warnCluster(reason)
+			return v, nil
+		}
+		if oracle.IsInteresting(res) {
+			v.reasons = append(v.reasons, reason)
+		}
+		return v, nil
+	}
+
+	if len(s.opts.Invariants) > 0 {
+		for _, reason := range checkInvariants(res.Manifest, s.opts.Invariants) {
+			add(runner.CategorizeReason(reason), []string{reason})
+		}
+	}
+	if s.cfg.Budget != nil {
+		for _, reason := range checkBudget(res.Manifest, *s.cfg.Budget) {
+			add(runner.CategorizeReason(reason), []string{reason})
+		}
+	}
+	if c.defaults != nil {
+		for _, reason := range c.defaults.checkDiff(res.Manifest, *s.cfg.DefaultsDiff) {
+			add(runner.CategorizeReason(reason), []string{reason})
+		}
+	}
+	if s.cfg.YAML11 {
+		add(runner.CategoryYAMLQuirk, checkYAML11(res.Manifest, values))
+	}
+	if s.cfg.LargeNumbers {
+		add(runner.CategoryNumber, checkNumbers(res.Manifest, values))
+	}
+	if s.cfg.Unicode {
+		add(runner.CategoryUnicode, checkUnicode(res.Manifest))
+	}
+	if c.times != nil {
+		if reason := c.times.checkSlow(res.Duration, *s.cfg.SlowRender); reason != "" {
+			add(runner.CategorySlow, []string{reason})
+		}
+	}
+	if !v.crashed && c.races > 0 {
+		if reason := c.checkRace(r, res); reason != "" {
+			add(runner.CategoryRace, []string{reason})
+		}
+	}
+	if !v.crashed && c.validators != nil {
+		found, err := c.validators[kubeVersion].Validate(ctx, res.Manifest, s.namespace(res))
+		if err != nil {
+			return verdict{}, err
+		}
+		add(runner.CategoryAPIServer, found)
+	}
+	if release := c.releases[kubeVersion]; !v.crashed && release != nil {
+		found, err := release.Upgrade(ctx, res.Manifest, s.namespace(res))
+		if err != nil {
+			return verdict{}, err
+		}
+		add(runner.CategoryUpgrade, found)
+	}
+	if !v.crashed && len(s.oracles) > 0 {
+		found, err := plugin.CheckAll(ctx, s.oracles, plugin.CheckRequest{
+			Chart:       filepath.Base(s.chartPath),
+			KubeVersion: kubeVersion,
+			Values:      values,
+			Manifest:    res.Manifest,
+		})
+		if err != nil {
+			return verdict{}, err
+		}
+		add(runner.CategoryPlugin, found)
+	}
+	if !v.crashed && len(s.scanners) > 0 {
+		found, err := scanner.ScanAll(ctx, s.scanners, res.Manifest)
+		if err != nil {
+			return verdict{}, err
+		}
+		add(runner.CategoryScanner, scanner.Without(found, c.baseline))
+	}
+	return v, nil
+}
+
+// inspection is what was learned about a new crash before it is reported
+type inspection struct {
+	// replays counts the replays, reproduced those that crashed the same way
+	replays    int
+	reproduced int
+	// culprits are the value paths the crash needs, and minimal the values
+	// ablated to them, both nil when not ablated
+	culprits []string
+	minimal  map[string]interface{}
+}
+
+// inspect replays the input of a new crash the given number of times and,
+// if ablate is set and every replay reproduced it, ablates the input to its
+// culprit paths; a flaky crash would not ablate reliably
+func (c *checker) inspect(ctx context.Context, r *runner.Runner, res *runner.Result, kubeVersion string, values map[string]interface{}, reason string, replays int, ablate bool) (inspection, error) {
+	in := inspection{replays: replays}
+	var err error
+	if replays > 0 {
+		if in.reproduced, err = c.reproduce(ctx, r, res, kubeVersion, values, reason, replays); err != nil {
+			return inspection{}, err
+		}
+	}
+	if ablate && in.reproduced == replays {
+		if in.culprits, in.minimal, err = c.culprits(ctx, r, res, kubeVersion, values, reason); err != nil {
+			return inspection{}, err
+		}
+	}
+	return in, nil
+}
+
+// reproduce replays the input of a crash the given number of times, rendering
+// it with the same runner and built-in objects and running every oracle
+// again, and returns how many replays crashed with the same fingerprint
+func (c *checker) reproduce(ctx context.Context, r *runner.Runner, res *runner.Result, kubeVersion string, values map[string]interface{}, reason string, replays int) (int, error) {
+	fingerprint := runner.Fingerprint(reason)
+	reproduced := 0
+	for n := 0; n < replays; n++ {
+		v, err := c.check(ctx, r, r.RunWithBuiltIns(values, res.BuiltIns), kubeVersion, values)
+		if err != nil {
+			return 0, err
+		}
+		for _, found := range v.reasons {
+			if runner.Fingerprint(found) == fingerprint {
+				reproduced++
+				break
+			}
+		}
+	}
+	return reproduced, nil
+}
+
+// culprits ablates the input of a crash as the explain command does, each
+// candidate rendered with the same runner and built-in objects, pinned values
+// applied, and run through every oracle, and returns the value paths the
+// crash needs: those set as they must be, then any it expects unset, with
+// the smallest values that still crash. It returns nil if the input no longer
+// crashes the same way.
+func (c *checker) culprits(ctx context.Context, r *runner.Runner, res *runner.Result, kubeVersion string, values map[string]interface{}, reason string) ([]string, map[string]interface{}, error) {
+	fingerprint := runner.Fingerprint(reason)
+	var checkErr error
+	replay := func(candidate map[string]interface{}) string {
+		if checkErr != nil {
+			return ""
+		}
+		if len(c.s.opts.Values) > 0 {
+			candidate = runner.MergeValues(candidate, c.s.opts.Values)
+		}
+		v, err := c.check(ctx, r, r.RunWithBuiltIns(candidate, res.BuiltIns), kubeVersion, candidate)
+		if err != nil {
+			checkErr = err
+			return ""
+		}
+		for _, found := range v.reasons {
+			if runner.Fingerprint(found) == fingerprint {
+				return found
+			}
+		}
+		if len(v.reasons) > 0 {
+			return v.reasons[0]
+		}
+		return ""
+	}
+
+	explanation, err := explain.Explain(values, c.s.defaults, replay)
+	if checkErr != nil {
+		return nil, nil, checkErr
+	} else if err != nil {
+		return nil, nil, nil
+	}
+	paths := make([]string, len(explanation.Conditions))
+	for i, condition := range explanation.Conditions {
+		paths[i] = condition.Path
+	}
+	return paths, explanation.Minimal, nil
+}
@@ -0,0 +1,49 @@
+package fuzz
+
+import (
+	"slices"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/runner"
+)
+
+// skipClusterTemplates renders the chart's defaults, with pinned values
+// applied, and leaves out of the session's renders the templates failing
+// them for want of a cluster. Such a template fails every render the same
+// way, so without it the rest of the chart is fuzzed instead of one
+// uninteresting error found over and over. Templates are left out one at a
+// time until the defaults render or fail for another reason; the last
+// render is returned, nil if none could be made.
+func (s *Session) skipClusterTemplates() *runner.Result {
+	var res *runner.Result
+	for {
+		r, err := s.newRunner(s.cfg.KubeVersions[0], s.cfg.Lookup, usualFiles(s.cfg.Files), s.logger)
+		if err != nil {
+			break
+		}
+		res = r.Run(runner.MergeValues(map[string]interface{}{}, s.opts.Values))
+		if res.Error == nil {
+			break
+		}
+		template := r.ClusterTemplate(res.Error.Error())
+		if template == "" || slices.Contains(s.skipped, template) {
+			break
+		}
+		s.skipped = append(s.skipped, template)
+	}
+	if len(s.skipped) > 0 {
+		s.logger.Warn("fuzzing without templates that need a cluster to render", "templates", s.skipped)
+	}
+	return res
+}
+
+// warnCluster warns, once per template, that a crash is for want of a
+// cluster and not reported
+func (c *checker) warnCluster(reason string) {
+	template := "the chart"
+	if attr := runner.Attribute(reason); attr != nil {
+		template = attr.Template
+	}
+	if _, warned := c.clusterWarned.LoadOrStore(template, true); !warned {
+		c.s.logger.Warn("not reporting renders that need a cluster", "template", template, "error", reason)
+	}
+}
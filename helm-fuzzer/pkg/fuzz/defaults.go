@@ -0,0 +1,78 @@
+package fuzz
+
+import (
+	"fmt"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/config"
+	"github.com/kasuboski/helm-fuzzer/pkg/runner"
+)
+
+// renderedDefaults is the render of the chart's defaults, with pinned values
+// applied, made once when the session is created and parsed then, so the
+// oracles comparing renders against it cost no more than the renders' own
+// parsing
+type renderedDefaults struct {
+	manifest string
+	objects  []map[string]interface{}
+	// kinds counts the objects of each kind
+	kinds map[string]int
+	// err is why the defaults could not be rendered or parsed, nil if they were
+	err error
+}
+
+// newRenderedDefaults parses the render of the defaults; a nil render is
+// one that never ran
+func newRenderedDefaults(res *runner.Result) *renderedDefaults {
+	switch {
+	case res == nil:
+		return &renderedDefaults{err: fmt.Errorf("the defaults were not rendered")}
+	case res.Error != nil:
+		return &renderedDefaults{err: res.Error}
+	}
+	objects, err := decodeResources(res.Manifest)
+	if err != nil {
+		return &renderedDefaults{err: fmt.Errorf("rendered manifest does not parse: %w", err)}
+	}
+	d := &renderedDefaults{manifest: res.Manifest, objects: objects, kinds: make(map[string]int)}
+	for _, obj := range objects {
+		if kind, ok := obj["kind"].(string); ok {
+			d.kinds[kind]++
+		}
+	}
+	return d
+}
+
+// checkDiff returns a crash reason for each bound a render strays past from
+// the defaults: a manifest more than SizeRatio times theirs, more than
+// ObjectRatio times their objects, or no object of a Keep kind they have.
+// Like budget reasons, the reasons name the bounds rather than the sizes,
+// so every render past one shares its fingerprint.
+func (d *renderedDefaults) checkDiff(manifest string, bounds config.DefaultsDiff) []string {
+	var reasons []string
+	if len(d.manifest) > 0 && float64(len(manifest)) > bounds.SizeRatio*float64(len(d.manifest)) {
+		reasons = append(reasons, fmt.Sprintf("Defaults diff: the manifest is more than %gx the size of the defaults' manifest", bounds.SizeRatio))
+	}
+	if bounds.ObjectRatio == 0 && len(bounds.Keep) == 0 {
+		return reasons
+	}
+
+	objects, err := decodeResources(manifest)
+	if err != nil {
+		return append(reasons, fmt.Sprintf("Defaults diff unchecked: rendered manifest does not parse: %v", err))
+	}
+	if bounds.ObjectRatio > 0 && len(d.objects) > 0 && float64(len(objects)) > bounds.ObjectRatio*float64(len(d.objects)) {
+		reasons = append(reasons, fmt.Sprintf("Defaults diff: more than %gx the defaults' %d objects", bounds.ObjectRatio, len(d.objects)))
+	}
+	kinds := make(map[string]bool, len(objects))
+	for _, obj := range objects {
+		if kind, ok := obj["kind"].(string); ok {
+			kinds[kind] = true
+		}
+	}
+	for _, kind := range bounds.Keep {
+		if d.kinds[kind] > 0 && !kinds[kind] {
+			reasons = append(reasons, fmt.Sprintf("Defaults diff: no %s, which the defaults render", kind))
+		}
+	}
+	return reasons
+}
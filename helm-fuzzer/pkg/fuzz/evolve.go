@@ -0,0 +1,164 @@
+package fuzz
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+
+	"helm.sh/helm/v3/pkg/chart/loader"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/corpus"
+	"github.com/kasuboski/helm-fuzzer/pkg/coverage"
+	"github.com/kasuboski/helm-fuzzer/pkg/generator"
+	"github.com/kasuboski/helm-fuzzer/pkg/runner"
+)
+
+// templateWeight is how much more an input that rendered a new template is
+// mutated than one that only set a new value path
+const templateWeight = 10
+
+// regionWeight is the same for an input that reached a new define or branch
+// of a guided session
+const regionWeight = 5
+
+// pool is the evolving corpus of a session with Options.Evolve: inputs that
+// reached coverage no earlier input did, which later inputs are mutated from
+type pool struct {
+	mu sync.Mutex
+	// dir persists new entries; empty keeps them in memory
+	dir     string
+	entries []poolEntry
+	total   int
+}
+
+// poolEntry is an input and how often it is picked for mutation
+type poolEntry struct {
+	values map[string]interface{}
+	weight int
+}
+
+// entryWeight favours inputs that rendered new templates, then those that
+// reached new regions, over those that only set new paths
+func entryWeight(fresh []string) int {
+	weight := 1
+	for _, feature := range fresh {
+		switch {
+		case strings.HasPrefix(feature, "template:"):
+			weight += templateWeight
+		case strings.HasPrefix(feature, "region:"):
+			weight += regionWeight
+		default:
+			weight++
+		}
+	}
+	return weight
+}
+
+// add keeps values for mutation, weighted by the coverage it added
+func (p *pool) add(values map[string]interface{}, fresh []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	weight := entryWeight(fresh)
+	p.entries = append(p.entries, poolEntry{values: values, weight: weight})
+	p.total += weight
+}
+
+// save adds values and writes them to the corpus directory, if there is one
+func (p *pool) save(name string, values map[string]interface{}, fresh []string) error {
+	p.add(values, fresh)
+	if p.dir == "" {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, _, err := corpus.Save(p.dir, name, values); err != nil {
+		return err
+	}
+	return nil
+}
+
+// size returns the number of inputs in the pool
+func (p *pool) size() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.entries)
+}
+
+// next derives the input for an iteration: a mutation of a pool entry picked
+// by weight, or a freshly generated input for half the iterations and while
+// the pool is empty, drawn from the iteration's seeds under the session seed.
+// What the pool holds depends on the iterations before, so an evolving
+// session only draws the same inputs again when it runs in the same order.
+func (p *pool) next(gen *generator.Generator, seed uint64, iteration int) map[string]interface{} {
+	r := rand.New(rand.NewSource(int64(generator.Seed(seed, generator.StreamEvolve, iteration))))
+
+	p.mu.Lock()
+	var base map[string]interface{}
+	if p.total > 0 {
+		pick := r.Intn(p.total)
+		for _, entry := range p.entries {
+			if pick < entry.weight {
+				base = entry.values
+				break
+			}
+			pick -= entry.weight
+		}
+	}
+	p.mu.Unlock()
+
+	values := generator.Seed(seed, generator.StreamValues, iteration)
+	if base == nil || r.Intn(2) == 0 {
+		return gen.Generate().Example(values)
+	}
+	return gen.Mutate(base).Example(values)
+}
+
+// calibrate renders the seeds to record the coverage they reach and adds
+// them to the pool, so an evolving session picks up where the corpus left off
+func (s *Session) calibrate(tracker *coverage.Tracker, regions *coverage.RegionTracker) error {
+	r, err := s.newRunner(s.cfg.KubeVersions[0], s.cfg.Lookup, usualFiles(s.cfg.Files), s.logger)
+	if err != nil {
+		return fmt.Errorf("failed to create runner: %w", err)
+	}
+
+	for _, seed := range s.seeds {
+		values := seed
+		if len(s.opts.Values) > 0 {
+			values = runner.MergeValues(values, s.opts.Values)
+		}
+		res := r.Run(values)
+		fresh := tracker.Record(values, res.Templates)
+		if regions != nil {
+			fresh = append(fresh, s.reached(regions, r, values)...)
+		}
+		s.pool.add(values, fresh)
+	}
+	s.logger.Debug("calibrated evolving corpus", "entries", len(s.seeds))
+	return nil
+}
+
+// reached renders values with instrumented templates and returns the regions
+// no earlier input reached, as region:<template>:<line> <kind> features.
+// Rendering modifies the chart, so each render instruments a fresh copy. An
+// input that fails to render reaches nothing.
+func (s *Session) reached(regions *coverage.RegionTracker, r *runner.Runner, values map[string]interface{}) []string {
+	c, err := loader.Load(s.renderPath)
+	if err != nil {
+		s.logger.Debug("failed to load chart for instrumentation", "error", err)
+		return nil
+	}
+	s.instrumentation.Apply(c)
+	rendered, err := r.RenderChart(c, values)
+	if err != nil {
+		return nil
+	}
+
+	var features []string
+	for _, region := range regions.Record(s.instrumentation.Executed(rendered)) {
+		features = append(features, "region:"+region.String())
+	}
+	return features
+}
@@ -0,0 +1,76 @@
+package fuzz
+
+import (
+	"strings"
+
+	"pgregory.net/rapid"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/config"
+	"github.com/kasuboski/helm-fuzzer/pkg/generator"
+)
+
+// largeFileSize is the size of the large content drawn for virtual files
+const largeFileSize = 64 << 10
+
+// drawFiles returns the configured virtual files an iteration renders with,
+// each missing or with a content drawn from the iteration's seed, so
+// templates reading them with .Files meet missing and malformed files
+func (s *Session) drawFiles(iteration int) map[string][]byte {
+	if len(s.cfg.Files) == 0 {
+		return nil
+	}
+	return filesGenerator(s.cfg.Files).Example(generator.Seed(s.cfg.Seed, generator.StreamFiles, iteration))
+}
+
+// filesGenerator draws the files at their paths, nil when missing
+func filesGenerator(files []config.File) *rapid.Generator[map[string][]byte] {
+	return rapid.Custom(func(t *rapid.T) map[string][]byte {
+		drawn := make(map[string][]byte, len(files))
+		for _, file := range files {
+			if !rapid.Bool().Draw(t, "present") {
+				drawn[file.Path] = nil
+				continue
+			}
+			var content *rapid.Generator[string]
+			if file.Content != nil {
+				content = rapid.OneOf(rapid.Just(*file.Content), rapid.SampledFrom(fileContents(*file.Content)))
+			} else {
+				content = rapid.OneOf(rapid.String(), rapid.SampledFrom(fileContents("")))
+			}
+			drawn[file.Path] = []byte(content.Draw(t, "content"))
+		}
+		return drawn
+	})
+}
+
+// fileContents returns the edge cases of a file's content: empty and
+// whitespace only, CRLF line endings, no trailing newline, a byte order
+// mark, invalid UTF-8 and a large file
+func fileContents(content string) []string {
+	unit := content
+	if unit == "" {
+		unit = "x"
+	}
+	return []string{
+		"",
+		"\n",
+		strings.ReplaceAll(content, "\n", "\r\n"),
+		strings.TrimRight(content, "\n"),
+		"\ufeff" + content,
+		content + "\xff\xfe",
+		strings.Repeat(unit, largeFileSize/len(unit)+1),
+	}
+}
+
+// usualFiles returns the virtual files with their configured content, for
+// renders that replay seeds rather than fuzz; files without one are left
+// to the chart
+func usualFiles(files []config.File) map[string][]byte {
+	usual := make(map[string][]byte)
+	for _, file := range files {
+		if file.Content != nil {
+			usual[file.Path] = []byte(*file.Content)
+		}
+	}
+	return usual
+}
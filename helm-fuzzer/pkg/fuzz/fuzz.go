@@ -0,0 +1,1044 @@
+// Package fuzz runs fuzzing sessions against a chart. It is the engine behind
+// the fuzz command, exposed so chart repositories can fuzz from their own Go
+// tests and tooling instead of running the CLI.
+package fuzz
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/postrender"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/config"
+	"github.com/kasuboski/helm-fuzzer/pkg/corpus"
+	"github.com/kasuboski/helm-fuzzer/pkg/coverage"
+	"github.com/kasuboski/helm-fuzzer/pkg/envtest"
+	"github.com/kasuboski/helm-fuzzer/pkg/generator"
+	"github.com/kasuboski/helm-fuzzer/pkg/logging"
+	"github.com/kasuboski/helm-fuzzer/pkg/plugin"
+	"github.com/kasuboski/helm-fuzzer/pkg/report"
+	"github.com/kasuboski/helm-fuzzer/pkg/risk"
+	"github.com/kasuboski/helm-fuzzer/pkg/runner"
+	"github.com/kasuboski/helm-fuzzer/pkg/scanner"
+	"github.com/kasuboski/helm-fuzzer/pkg/schema"
+	"github.com/kasuboski/helm-fuzzer/pkg/tracing"
+)
+
+// Finding is a unique crash found by a session
+type Finding = report.Finding
+
+// Options configures a session. The zero value fuzzes with the chart's
+// .helmfuzz.yaml, writing reproduction files to the working directory.
+type Options struct {
+	// Config is the fuzzing configuration; nil loads it from the chart
+	Config *config.Config
+	// OutputDir receives reproduction files
+	OutputDir string
+	// Timeout ends the session; zero leaves it to the iteration target and
+	// the context passed to Run
+	Timeout time.Duration
+	// FirstIteration skips the iterations below it, continuing an earlier
+	// session. Inputs are drawn per iteration index, so the rest match.
+	FirstIteration int
+	// Seen lists crash reasons already reported, which are not reported again
+	Seen []string
+	// Values override the same keys of every input
+	Values map[string]interface{}
+	// ReleaseName and Namespace are the release inputs are rendered as
+	// (default: runner.DefaultReleaseName in runner.DefaultNamespace)
+	ReleaseName string
+	Namespace   string
+	// IncludeCRDs renders the chart's crds/ directory along with its templates
+	IncludeCRDs bool
+	// PostRenderer rewrites every rendered manifest before the oracles see
+	// it, such as kustomize building the chart into a kustomization
+	PostRenderer postrender.PostRenderer
+	// Subchart fuzzes only the values of this dependency, named by its alias
+	// or chart name, keeping every other value at its default and enabling
+	// the dependency through its condition or tags
+	Subchart string
+	// Invariants are checked against every successful render, each one a
+	// render breaks being a crash, as a scenario's are
+	Invariants []config.Invariant
+	// BuiltIns varies the built-in objects each iteration renders with:
+	// release name and namespace lengths, the revision, with upgrades above
+	// 1, the chart's appVersion, and the patch level and distribution suffix
+	// of the Kubernetes version
+	BuiltIns bool
+	// Envtest dry-run creates the objects of every successful render on a
+	// real API server started for its Kubernetes version from the config's
+	// envtest assets, each problem the server reports being a crash
+	Envtest bool
+	// Upgrades implies Envtest and also installs the render of the chart's
+	// defaults on each API server, then dry-run applies every successful
+	// render over it as an upgrade, so value changes the server refuses to
+	// update, such as new selectors or smaller volume claims, are crashes
+	Upgrades bool
+	// FailFast stops the session at the first unique crash
+	FailFast bool
+	// Evolve derives inputs from an evolving corpus instead of from the
+	// iteration index alone: inputs that reach new coverage are kept, saved to
+	// the config's corpus directory if it has one, and mutated into later
+	// inputs, favouring those that rendered new templates. Seeds seed the
+	// corpus rather than being replayed as iterations.
+	Evolve bool
+	// Guided implies Evolve and also renders each input with instrumented
+	// templates, so inputs that reach a define or an if/else, with or range
+	// branch no earlier input did join the corpus, weighted between a new
+	// template and a new value path. Each input is rendered twice.
+	Guided bool
+	// RiskWeighted analyzes the chart's templates for constructs that fail
+	// on unexpected values (see package risk) and varies the values paths
+	// feeding them more often, sometimes unsetting maps read unchecked
+	RiskWeighted bool
+	// Culprits ablates the input of each new unique crash, as the explain
+	// command does, to the value paths the crash needs, recorded with the
+	// finding and in its reproduction file. Each ablation takes a render per
+	// value tried, through every oracle.
+	Culprits bool
+	// SaveCorpus writes inputs that reach new coverage or crash in a category
+	// no earlier input did to the config's corpus directory, which later
+	// sessions replay as seeds. Evolving sessions always save their corpus.
+	SaveCorpus bool
+	// RaceRenders renders the input of every successful render again from
+	// this many goroutines at once, each failing or rendering differently
+	// being a race crash; inputs that render differently alone, as random
+	// functions do, are not judged. 0 disables the check.
+	RaceRenders int
+	// TagCombinations renders combinations of the tags gating the chart's
+	// dependencies, overriding the tags map of generated inputs, and records
+	// the tags each finding rendered with
+	TagCombinations bool
+	// DependencyUpdate builds missing chart dependencies instead of fuzzing without them
+	DependencyUpdate bool
+	// Trace is the span the session's spans are started under: schema
+	// detection, one iteration in the config's tracing.iterationSample with
+	// its render and oracles, and each new crash's minimization. Nil traces
+	// nothing.
+	Trace *tracing.Span
+	// Logger receives progress and diagnostics; nil discards them
+	Logger *slog.Logger
+	Hooks  Hooks
+}
+
+// Hooks observe a running session; nil hooks are skipped. Iteration and
+// Crash are called one at a time, the others from every worker at once.
+type Hooks struct {
+	// Wait runs before each iteration and blocks while the session is
+	// paused; returning false stops the session
+	Wait func() bool
+	// StartRender runs as a worker starts rendering and returns a function
+	// called when the render finishes
+	StartRender func() func()
+	// Iteration runs after each completed iteration
+	Iteration func(Iteration)
+	// Crash runs for each new unique crash, after its reproduction file is saved
+	Crash func(Finding)
+}
+
+// Iteration is one completed render
+type Iteration struct {
+	// Index is the iteration's index, which determines its input
+	Index       int
+	KubeVersion string
+	Values      map[string]interface{}
+	Crashed     bool
+	// Category is the crash category, empty unless it crashed
+	Category string
+	// Next is the lowest iteration not yet completed; a session resumed
+	// there repeats nothing that was completed
+	Next int
+}
+
+// Result is the outcome of a session
+type Result struct {
+	// Iterations and Crashes count this run only, not a resumed session's earlier runs
+	Iterations int
+	Crashes    int
+	// Findings are the new unique crashes in the order they were found
+	Findings []Finding
+	Coverage coverage.Summary
+	// Next is where a resumed session would continue
+	Next int
+	// Evolved counts the inputs added to an evolving corpus, or written to
+	// the corpus directory with SaveCorpus
+	Evolved int
+	// Interrupted reports that the context was canceled or the Wait hook
+	// stopped the session before its budget ran out
+	Interrupted bool
+}
+
+// ConfigError is a configuration the session cannot use. Other errors from
+// NewWithOptions come from loading, validating or inspecting the chart.
+type ConfigError struct {
+	Err error
+}
+
+func (e *ConfigError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// dependencyMu keeps sessions of the same chart from building its charts/ directory at once
+var dependencyMu sync.Mutex
+
+// Session is a chart prepared for fuzzing
+type Session struct {
+	chartPath string
+	// renderPath is the chart that is rendered: chartPath, or a copy with
+	// dependencies built when chartPath is read-only
+	renderPath string
+	// copyDir holds the copy, removed by Close
+	copyDir   string
+	opts      Options
+	cfg       *config.Config
+	logger    *slog.Logger
+	schema    *schema.Schema
+	gen       *generator.Generator
+	seeds     []map[string]interface{}
+	templates []string
+	defaults  map[string]interface{}
+	// pool is the evolving corpus, nil unless Options.Evolve or Options.Guided is set
+	pool *pool
+	// instrumentation observes the regions inputs reach, nil unless Options.Guided is set
+	instrumentation *coverage.Instrumentation
+	// oracles are the oracle plugins that check every successful render
+	oracles []*plugin.Plugin
+	// scanners are the security scanners run over every successful render
+	scanners []*scanner.Scanner
+	// appVersion is the chart's own, which built-in objects are drawn around
+	appVersion string
+	// skipped are the templates left out of renders for needing a cluster
+	skipped []string
+	// defaultRender is the render of the chart's defaults, made once
+	defaultRender *renderedDefaults
+	// tags are the dependency tags whose combinations are rendered
+	tags []string
+}
+
+// New prepares a session for the chart with default options
+func New(chartPath string) (*Session, error) {
+	return NewWithOptions(chartPath, Options{})
+}
+
+// NewWithOptions prepares a session: it loads the configuration and seeds,
+// asks generator plugins for inputs, detects the values schema and checks
+// that the chart loads
+func NewWithOptions(chartPath string, opts Options) (*Session, error) {
+	logger := logging.OrDiscard(opts.Logger)
+	if opts.OutputDir == "" {
+		opts.OutputDir = "."
+	}
+
+	cfg := opts.Config
+	if cfg == nil {
+		loaded, err := config.LoadConfig(chartPath)
+		if err != nil {
+			return nil, &ConfigError{fmt.Errorf("failed to load config: %w", err)}
+		}
+		cfg = loaded
+	}
+
+	// Reject constraint templates that don't parse before fuzzing starts
+	for _, constraint := range cfg.Constraints {
+		if constraint.Template == "" {
+			continue
+		}
+		if err := generator.ValidateTemplate(constraint.Template); err != nil {
+			return nil, &ConfigError{fmt.Errorf("invalid template for constraint %s: %w", constraint.Path, err)}
+		}
+	}
+
+	// Find every API server's binaries before fuzzing starts
+	if opts.Upgrades {
+		opts.Envtest = true
+	}
+	if opts.Envtest {
+		for _, kubeVersion := range cfg.KubeVersions {
+			if _, err := envtest.FindAssets(cfg.ResolveEnvtestAssets(chartPath), kubeVersion); err != nil {
+				return nil, &ConfigError{err}
+			}
+		}
+	}
+
+	detect := opts.Trace.Child("detect schema")
+	sch, err := schema.NewEngineWithLogger(cfg, logger).DetectSchema(chartPath)
+	detect.SetError(err)
+	detect.End()
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect schema: %w", err)
+	}
+	logger.Debug("detected schema", "type", sch.Type)
+
+	// Collect seed inputs: inline seeds first, then corpus entries
+	seeds := append([]map[string]interface{}{}, cfg.Seeds...)
+	if corpusDir := cfg.ResolveCorpusDir(chartPath); corpusDir != "" {
+		entries, err := corpus.Load(corpusDir)
+		if err != nil {
+			return nil, &ConfigError{fmt.Errorf("failed to load corpus: %w", err)}
+		}
+		seeds = append(seeds, entries...)
+	}
+	if len(seeds) > 0 {
+		logger.Debug("loaded seed inputs", "count", len(seeds))
+	}
+
+	r, err := runner.NewWithOptions(chartPath, runner.Options{Logger: logger})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create runner: %w", err)
+	}
+	if err := r.Validate(); err != nil {
+		return nil, fmt.Errorf("chart validation failed: %w", err)
+	}
+
+	// Missing subcharts render as if disabled, hiding their templates from the session
+	renderPath, copyDir := chartPath, ""
+	if err := r.CheckDependencies(); err != nil {
+		if !opts.DependencyUpdate {
+			logger.Warn("fuzzing without missing dependencies; build them with helm dependency build or --dependency-update", "error", err)
+		} else {
+			// A read-only chart, such as one mounted into a container, gets
+			// its dependencies built in a copy that the session renders
+			if !writable(chartPath) {
+				if copyDir, renderPath, err = copyChart(chartPath); err != nil {
+					return nil, err
+				}
+				logger.Debug("building dependencies in a copy of the read-only chart", "path", renderPath)
+				if r, err = runner.NewWithOptions(renderPath, runner.Options{Logger: logger}); err != nil {
+					os.RemoveAll(copyDir)
+					return nil, fmt.Errorf("failed to create runner: %w", err)
+				}
+			}
+			dependencyMu.Lock()
+			err := r.BuildDependencies(logWriter{logger})
+			dependencyMu.Unlock()
+			if err != nil {
+				if copyDir != "" {
+					os.RemoveAll(copyDir)
+				}
+				return nil, err
+			}
+		}
+	}
+
+	templates, err := r.Templates()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list templates: %w", err)
+	}
+
+	// Crash reports show how the failing values differ from these defaults
+	defaults, err := r.DefaultValues()
+	if err != nil {
+		logger.Warn("values diffs disabled", "error", err)
+	}
+
+	if opts.Subchart != "" {
+		isolated, enable, err := isolate(schema.NewEngineWithLogger(cfg, logger), renderPath, opts.Subchart, defaults)
+		if err != nil {
+			if copyDir != "" {
+				os.RemoveAll(copyDir)
+			}
+			return nil, err
+		}
+		sch = isolated
+		opts.Values = runner.MergeValues(enable, opts.Values)
+		logger.Debug("fuzzing subchart in isolation", "subchart", opts.Subchart)
+	}
+
+	if opts.RiskWeighted {
+		c, err := loader.Load(renderPath)
+		if err != nil {
+			if copyDir != "" {
+				os.RemoveAll(copyDir)
+			}
+			return nil, fmt.Errorf("failed to load chart: %w", err)
+		}
+		weighted := 0
+		for _, score := range risk.Scores(risk.Analyze(c)) {
+			if sch.MarkRisk(score.Path, score.Score, score.Nilable()) {
+				weighted++
+			}
+		}
+		logger.Debug("weighted risky values paths", "paths", weighted)
+	}
+
+	appVersion := ""
+	if opts.BuiltIns {
+		c, err := loader.Load(renderPath)
+		if err != nil {
+			if copyDir != "" {
+				os.RemoveAll(copyDir)
+			}
+			return nil, fmt.Errorf("failed to load chart: %w", err)
+		}
+		appVersion = c.Metadata.AppVersion
+	}
+
+	var tags []string
+	if opts.TagCombinations {
+		c, err := loader.Load(renderPath)
+		if err != nil {
+			if copyDir != "" {
+				os.RemoveAll(copyDir)
+			}
+			return nil, fmt.Errorf("failed to load chart: %w", err)
+		}
+		tags = dependencyTags(c)
+		logger.Debug("rendering combinations of dependency tags", "tags", tags)
+	}
+
+	// Generator plugins contribute their inputs once, after the other seeds
+	plugins := plugin.New(cfg, chartPath)
+	for _, p := range plugin.OfType(plugins, config.PluginGenerator) {
+		inputs, err := p.Generate(context.Background(), plugin.GenerateRequest{
+			Chart:     filepath.Base(chartPath),
+			ChartPath: chartPath,
+			Defaults:  defaults,
+		})
+		if err != nil {
+			if copyDir != "" {
+				os.RemoveAll(copyDir)
+			}
+			return nil, err
+		}
+		logger.Debug("generated inputs", "plugin", p.Name, "count", len(inputs))
+		seeds = append(seeds, inputs...)
+	}
+
+	var evolving *pool
+	if opts.Evolve || opts.Guided {
+		evolving = &pool{dir: cfg.ResolveCorpusDir(chartPath)}
+	}
+	var instrumentation *coverage.Instrumentation
+	if opts.Guided {
+		c, err := loader.Load(renderPath)
+		if err != nil {
+			if copyDir != "" {
+				os.RemoveAll(copyDir)
+			}
+			return nil, fmt.Errorf("failed to load chart: %w", err)
+		}
+		instrumentation = coverage.Instrument(c)
+		logger.Debug("instrumented templates", "regions", len(instrumentation.Regions))
+	}
+
+	s := &Session{
+		chartPath:  chartPath,
+		renderPath: renderPath,
+		copyDir:    copyDir,
+		opts:       opts,
+		cfg:        cfg,
+		logger:     logger,
+		schema:     sch,
+		gen: generator.NewWithOptions(sch, generator.Options{
+			MaxDepth:           cfg.MaxDepth,
+			MaxTotalValuesSize: cfg.MaxTotalValuesSize,
+			MaxKeysPerObject:   cfg.MaxKeysPerObject,
+			Logger:             logger,
+		}),
+		seeds:     seeds,
+		templates: templates,
+		defaults:  defaults,
+		pool:      evolving,
+
+		instrumentation: instrumentation,
+		oracles:         plugin.OfType(plugins, config.PluginOracle),
+		scanners:        scanner.New(cfg, chartPath),
+		appVersion:      appVersion,
+		tags:            tags,
+	}
+	s.defaultRender = newRenderedDefaults(s.skipClusterTemplates())
+	return s, nil
+}
+
+// Close removes the copy of a read-only chart made to build its
+// dependencies, if any. The session cannot render afterwards.
+func (s *Session) Close() error {
+	if s.copyDir == "" {
+		return nil
+	}
+	return os.RemoveAll(s.copyDir)
+}
+
+// writable reports whether files can be created in dir
+func writable(dir string) bool {
+	f, err := os.CreateTemp(dir, ".helm-fuzz-")
+	if err != nil {
+		return false
+	}
+	f.Close()
+	os.Remove(f.Name())
+	return true
+}
+
+// copyChart copies the chart, honouring its .helmignore, into a new
+// temporary directory, returning the directory and the copy's path.
+// Dependencies on relative file:// paths resolve against the chart's
+// directory, so the copy is nested as deep as they reach and each is linked
+// to the original it refers to.
+func copyChart(chartPath string) (string, string, error) {
+	c, err := loader.Load(chartPath)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to load chart: %w", err)
+	}
+	archive, err := runner.PackageChart(chartPath)
+	if err != nil {
+		return "", "", err
+	}
+
+	var local []string
+	depth := 0
+	for _, dep := range c.Metadata.Dependencies {
+		rel, ok := strings.CutPrefix(dep.Repository, "file://")
+		if !ok || filepath.IsAbs(rel) {
+			continue
+		}
+		local = append(local, rel)
+		up := 0
+		for _, part := range strings.Split(filepath.ToSlash(filepath.Clean(rel)), "/") {
+			if part != ".." {
+				break
+			}
+			up++
+		}
+		depth = max(depth, up)
+	}
+
+	root, err := os.MkdirTemp("", "helm-fuzz-chart-")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to copy chart: %w", err)
+	}
+	dir := root
+	for i := 0; i < depth; i++ {
+		dir = filepath.Join(dir, "nested")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		os.RemoveAll(root)
+		return "", "", fmt.Errorf("failed to copy chart: %w", err)
+	}
+	path, err := runner.ExpandChart(dir, archive)
+	if err != nil {
+		os.RemoveAll(root)
+		return "", "", err
+	}
+
+	for _, rel := range local {
+		link := filepath.Join(path, rel)
+		if _, err := os.Lstat(link); err == nil {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(link), 0755); err != nil {
+			os.RemoveAll(root)
+			return "", "", fmt.Errorf("failed to copy chart: %w", err)
+		}
+		if err := os.Symlink(filepath.Join(chartPath, rel), link); err != nil {
+			os.RemoveAll(root)
+			return "", "", fmt.Errorf("failed to link dependency %s: %w", rel, err)
+		}
+	}
+	return root, path, nil
+}
+
+// Config returns the configuration the session fuzzes with
+func (s *Session) Config() *config.Config {
+	return s.cfg
+}
+
+// Schema returns the detected values schema
+func (s *Session) Schema() *schema.Schema {
+	return s.schema
+}
+
+// Defaults returns the chart's default values, nil if they could not be read
+func (s *Session) Defaults() map[string]interface{} {
+	return s.defaults
+}
+
+// Run fuzzes until the configured iterations are done, the timeout passes,
+// ctx is canceled or, with FailFast, a crash is found. With no iteration
+// target and no timeout it runs until ctx is canceled. The result covers the
+// iterations completed even when an error stopped the session.
+func (s *Session) Run(ctx context.Context) (*Result, error) {
+	cfg, opts, hooks := s.cfg, s.opts, s.opts.Hooks
+
+	oracle := runner.NewOracleWithConfig(cfg.IgnoreErrors, cfg.UninterestingPatterns)
+	minimizer := runner.NewMinimizer(opts.OutputDir)
+	minimizer.KeepOutput = cfg.OutputLimit()
+	deduplicator := runner.NewDeduplicator()
+	for _, reason := range opts.Seen {
+		deduplicator.MarkSeen(reason)
+	}
+	tracker := coverage.New(s.schema, s.templates)
+	corpusDir := cfg.ResolveCorpusDir(s.chartPath)
+	var regions *coverage.RegionTracker
+	if s.instrumentation != nil {
+		regions = coverage.NewRegionTracker(s.instrumentation)
+	}
+
+	throttle := runner.NewThrottle(cfg.CPUThrottle)
+	throttle.Rate = cfg.MaxRate
+	// sample is how many iterations are run per one traced, 0 for none
+	sample := 0
+	if opts.Trace != nil && cfg.Tracing != nil {
+		sample = cfg.Tracing.IterationSample
+	}
+	if cfg.CPUThrottle > 0 || cfg.MaxRate > 0 {
+		s.logger.Debug("throttling workers", "cpuPercent", cfg.CPUThrottle, "maxRate", cfg.MaxRate)
+	}
+	if len(opts.Values) > 0 {
+		s.logger.Debug("pinning values", "count", len(opts.Values))
+	}
+	if len(opts.Invariants) > 0 {
+		s.logger.Debug("checking invariants", "count", len(opts.Invariants))
+	}
+	if opts.BuiltIns {
+		s.logger.Debug("fuzzing built-in objects")
+	}
+	if len(cfg.Lookup) > 0 {
+		s.logger.Debug("stubbing lookup", "objects", len(cfg.Lookup))
+	}
+	if len(cfg.Files) > 0 {
+		s.logger.Debug("fuzzing virtual files", "files", len(cfg.Files))
+	}
+	if cfg.Budget != nil {
+		s.logger.Debug("checking resource budget", "cpu", cfg.Budget.CPU, "memory", cfg.Budget.Memory, "pods", cfg.Budget.Pods)
+	}
+	var validators map[string]*envtest.Validator
+	if opts.Envtest {
+		var stop func()
+		var err error
+		validators, stop, err = envtest.StartValidators(ctx, cfg.ResolveEnvtestAssets(s.chartPath), cfg.KubeVersions, cfg.Envtest.StartTimeout, s.logger)
+		if err != nil {
+			return &Result{Next: opts.FirstIteration}, err
+		}
+		defer stop()
+		s.logger.Debug("validating against API servers", "kubeVersions", len(validators))
+	}
+	var releases map[string]*envtest.Release
+	if opts.Upgrades {
+		var err error
+		if releases, err = s.installDefaults(ctx, validators); err != nil {
+			return &Result{Next: opts.FirstIteration}, err
+		}
+		s.logger.Debug("checking upgrades", "releases", len(releases))
+	}
+	var baseline map[string]bool
+	if len(s.scanners) > 0 {
+		var err error
+		if baseline, err = s.scanBaseline(ctx); err != nil {
+			return &Result{Next: opts.FirstIteration}, err
+		}
+		s.logger.Debug("scanning renders", "scanners", len(s.scanners), "baseline", len(baseline))
+	}
+	checks := &checker{s: s, oracle: oracle, validators: validators, releases: releases, baseline: baseline, races: opts.RaceRenders}
+	if cfg.DefaultsDiff != nil {
+		if err := s.defaultRender.err; err != nil {
+			s.logger.Warn("not comparing renders with the defaults, which do not render", "error", err)
+		} else {
+			checks.defaults = s.defaultRender
+			s.logger.Debug("comparing renders with the defaults", "sizeRatio", cfg.DefaultsDiff.SizeRatio, "objectRatio", cfg.DefaultsDiff.ObjectRatio, "keep", cfg.DefaultsDiff.Keep)
+		}
+	}
+	if cfg.YAML11 {
+		s.logger.Debug("checking for strings rendered unquoted as other types")
+	}
+	if cfg.LargeNumbers {
+		s.logger.Debug("checking for numbers rendered rounded or in scientific notation")
+	}
+	if cfg.Unicode {
+		s.logger.Debug("checking rendered names and labels for invalid UTF-8 and byte lengths")
+	}
+	if cfg.SlowRender != nil {
+		checks.times = &renderTimes{}
+		s.logger.Debug("checking render times", "factor", cfg.SlowRender.Factor, "min", cfg.SlowRender.Min)
+	}
+	var memory *memoryTracker
+	if cfg.MemoryGrowth != nil {
+		memory = newMemoryTracker(*cfg.MemoryGrowth)
+		s.logger.Debug("sampling the live heap", "interval", cfg.MemoryGrowth.Interval, "samples", cfg.MemoryGrowth.Samples, "min", cfg.MemoryGrowth.Min)
+	}
+	if opts.RaceRenders > 0 {
+		s.logger.Debug("checking concurrent renders", "renders", opts.RaceRenders)
+	}
+	if cfg.Replays > 0 {
+		s.logger.Debug("replaying new crashes", "replays", cfg.Replays)
+	}
+
+	if s.pool != nil && s.pool.size() == 0 {
+		if err := s.calibrate(tracker, regions); err != nil {
+			return &Result{Next: opts.FirstIteration}, err
+		}
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if opts.Timeout > 0 {
+		runCtx, cancel = context.WithTimeout(runCtx, opts.Timeout)
+		defer cancel()
+	}
+
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		result = &Result{Next: opts.FirstIteration}
+		runErr error
+		quit   bool
+		// done holds the completed iterations above result.Next
+		done = make(map[int]bool)
+		// categories holds the crash categories seen so far
+		categories = make(map[string]bool)
+	)
+
+	// Feed iteration indices to workers until the budget or timeout is exhausted;
+	// generated inputs are drawn per index, so a time budget never runs dry
+	iterationCh := make(chan int)
+	go func() {
+		defer close(iterationCh)
+		for i := opts.FirstIteration; cfg.Iterations == 0 || i < cfg.Iterations; i++ {
+			select {
+			case iterationCh <- i:
+			case <-runCtx.Done():
+				return
+			}
+		}
+	}()
+
+	s.logger.Debug("starting fuzzing loop", "workers", cfg.Workers)
+
+	for w := 0; w < cfg.Workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for i := range iterationCh {
+				// Block while paused; stop if the caller quit
+				if hooks.Wait != nil && !hooks.Wait() {
+					mu.Lock()
+					quit = true
+					mu.Unlock()
+					cancel()
+					return
+				}
+
+				started := time.Now()
+
+				// Rotate through Kubernetes versions to test multiple versions
+				kubeVersion := cfg.KubeVersions[i%len(cfg.KubeVersions)]
+
+				var span *tracing.Span
+				if sample > 0 && i%sample == 0 {
+					span = opts.Trace.Child("iteration", tracing.Int("iteration", i+1), tracing.Int("worker", w), tracing.String("kubeVersion", kubeVersion))
+				}
+
+				testRunner, err := s.newRunner(kubeVersion, s.drawLookup(i), s.drawFiles(i), s.logger.With("worker", w))
+				if err != nil {
+					mu.Lock()
+					if runErr == nil {
+						runErr = fmt.Errorf("failed to create runner: %w", err)
+					}
+					mu.Unlock()
+					cancel()
+					return
+				}
+
+				values := s.Input(i)
+
+				var renderDone func()
+				if hooks.StartRender != nil {
+					renderDone = hooks.StartRender()
+				}
+				render := span.Child("render")
+				res := testRunner.RunWithBuiltIns(values, s.drawBuiltIns(i, kubeVersion))
+				render.SetError(res.Error)
+				render.End()
+				if renderDone != nil {
+					renderDone()
+				}
+				fresh := tracker.Record(values, res.Templates)
+				if regions != nil {
+					fresh = append(fresh, s.reached(regions, testRunner, values)...)
+				}
+				check := span.Child("check")
+				v, err := checks.check(runCtx, testRunner, res, kubeVersion, values)
+				check.SetError(err)
+				check.End()
+				if err != nil {
+					// An oracle cut short by the end of the session is not broken
+					if runCtx.Err() != nil {
+						return
+					}
+					mu.Lock()
+					if runErr == nil {
+						runErr = err
+					}
+					mu.Unlock()
+					cancel()
+					return
+				}
+				isCrash, category := v.crashed, v.category
+
+				// Replay new crashes before reporting them, so those the
+				// environment rather than the input caused are told apart,
+				// and ablate their inputs to the value paths they need
+				var inspections map[string]inspection
+				if (cfg.Replays > 0 || opts.Culprits) && len(v.reasons) > 0 {
+					var unseen []string
+					mu.Lock()
+					for _, reason := range v.reasons {
+						if !deduplicator.IsDuplicate(reason) && !s.suppressed(reason) {
+							unseen = append(unseen, reason)
+						}
+					}
+					mu.Unlock()
+					inspections = make(map[string]inspection, len(unseen))
+					for _, reason := range unseen {
+						minimize := opts.Trace.Child("minimize", tracing.String("category", runner.CategorizeReason(reason)), tracing.Int("replays", cfg.Replays))
+						in, err := checks.inspect(runCtx, testRunner, res, kubeVersion, values, reason, cfg.Replays, opts.Culprits)
+						minimize.SetAttributes(tracing.Int("reproduced", in.reproduced), tracing.Int("culprits", len(in.culprits)))
+						minimize.SetError(err)
+						minimize.End()
+						if err != nil {
+							if runCtx.Err() != nil {
+								return
+							}
+							mu.Lock()
+							if runErr == nil {
+								runErr = err
+							}
+							mu.Unlock()
+							cancel()
+							return
+						}
+						inspections[reason] = in
+					}
+				}
+
+				mu.Lock()
+				result.Iterations++
+				if isCrash {
+					result.Crashes++
+				}
+				done[i] = true
+				for done[result.Next] {
+					delete(done, result.Next)
+					result.Next++
+				}
+				if isCrash && !categories[category] {
+					categories[category] = true
+					fresh = append(fresh, "category:"+category)
+				}
+				// Inputs that reached new coverage join the evolving corpus, or
+				// the corpus directory for later sessions to replay
+				switch {
+				case len(fresh) == 0:
+				case s.pool != nil:
+					if err := s.pool.save(fmt.Sprintf("iteration-%d", i+1), values, fresh); err != nil {
+						s.logger.Warn("failed to save corpus entry", "error", err)
+					}
+					result.Evolved++
+					s.logger.Debug("new coverage", "iteration", i+1, "features", len(fresh))
+				case opts.SaveCorpus && corpusDir != "":
+					// Seeds replayed from the corpus are already in it
+					_, added, err := corpus.Save(corpusDir, fmt.Sprintf("iteration-%d", i+1), values)
+					if err != nil {
+						s.logger.Warn("failed to save corpus entry", "error", err)
+					} else if added {
+						result.Evolved++
+						s.logger.Debug("new coverage", "iteration", i+1, "features", len(fresh))
+					}
+				}
+				if hooks.Iteration != nil {
+					hooks.Iteration(Iteration{
+						Index:       i,
+						KubeVersion: kubeVersion,
+						Values:      values,
+						Crashed:     isCrash,
+						Category:    category,
+						Next:        result.Next,
+					})
+				}
+
+				// report records a new crash found at an iteration, unless it
+				// is a duplicate of an already saved crash or suppressed
+				report := func(reason string, iteration int, res *runner.Result, in inspection) {
+					if deduplicator.IsDuplicate(reason) || s.suppressed(reason) {
+						return
+					}
+					// Mark as seen and save reproduction file
+					deduplicator.MarkSeen(reason)
+					id := runner.FindingID(filepath.Base(s.chartPath), runner.Fingerprint(reason))
+					save := opts.Trace.Child("save finding", tracing.String("id", id))
+					reproFile, err := minimizer.SaveFinding(res, reason, id, in.culprits)
+					save.SetError(err)
+					save.End()
+					if err != nil {
+						s.logger.Warn("failed to save reproduction file", "error", err)
+					}
+
+					finding := newFinding(testRunner, iteration+1, reason, reproFile, res.Values)
+					finding.ID = id
+					finding.BuiltIns = res.BuiltIns
+					if in.replays > 0 {
+						finding.Replays, finding.Reproduced = in.replays, in.reproduced
+						finding.Flaky = finding.Reproduced < finding.Replays
+					}
+					finding.Culprits, finding.MinimalValues = in.culprits, in.minimal
+					finding.Tags = s.activeTags(res.Values)
+					result.Findings = append(result.Findings, finding)
+					if hooks.Crash != nil {
+						hooks.Crash(finding)
+					}
+
+					// Flaky crashes do not stop the session, as they do not fail it
+					if opts.FailFast && !finding.Flaky {
+						s.logger.Debug("stopping at the first crash")
+						cancel()
+					}
+				}
+				for _, reason := range v.reasons {
+					report(reason, i, res, inspections[reason])
+				}
+
+				// A live heap that keeps growing is blamed on the latest input
+				// rendering the template most rendered while it grew
+				if memory != nil {
+					if growth := memory.record(i, res); growth != nil {
+						s.logger.Warn("live heap keeps growing", "heap", growth.heap, "templates", growth.templates)
+						suspect := growth.suspect
+						report(growth.reason, suspect.iteration, &runner.Result{Values: suspect.values, BuiltIns: suspect.builtIns}, inspection{})
+					}
+				}
+				mu.Unlock()
+
+				span.SetAttributes(tracing.Bool("crashed", isCrash), tracing.String("category", category))
+				span.End()
+				throttle.Pace(time.Since(started))
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if runCtx.Err() == context.DeadlineExceeded {
+		s.logger.Debug("timeout reached")
+	}
+
+	result.Coverage = tracker.Summary()
+	if regions != nil {
+		summary := regions.Summary()
+		result.Coverage.Regions, result.Coverage.RegionsExecuted = summary.Regions, summary.Executed
+	}
+	result.Interrupted = ctx.Err() != nil || quit
+	return result, runErr
+}
+
+// newRunner creates a runner rendering the session's chart and release
+// against kubeVersion, with lookup finding the given objects and the given
+// files replacing the chart's
+func (s *Session) newRunner(kubeVersion string, lookup []map[string]interface{}, files map[string][]byte, logger *slog.Logger) (*runner.Runner, error) {
+	return runner.NewWithOptions(s.renderPath, runner.Options{
+		KubeVersion:  kubeVersion,
+		ReleaseName:  s.opts.ReleaseName,
+		Namespace:    s.opts.Namespace,
+		IncludeCRDs:  s.opts.IncludeCRDs,
+		PostRenderer: s.opts.PostRenderer,
+		Lookup:       lookup,
+		Files:        files,
+		Skip:         s.skipped,
+		Timeout:      s.cfg.RenderTimeout,
+		Logger:       logger,
+	})
+}
+
+// namespace returns the namespace a render was released into
+func (s *Session) namespace(res *runner.Result) string {
+	namespace := s.opts.Namespace
+	if res.BuiltIns != nil {
+		namespace = res.BuiltIns.Namespace
+	}
+	if namespace == "" {
+		namespace = runner.DefaultNamespace
+	}
+	return namespace
+}
+
+// suppressed reports whether a crash reason is a finding the config suppresses
+func (s *Session) suppressed(reason string) bool {
+	for _, id := range s.cfg.Suppress {
+		if runner.MatchesFindingID(id, filepath.Base(s.chartPath), reason) {
+			return true
+		}
+	}
+	return false
+}
+
+// Input returns the input for an iteration: seeds are replayed first, then
+// inputs are generated from the iteration's seed under the session seed,
+// with the iteration's dependency tags and pinned values applied.
+// An evolving session mutates its corpus instead, so its inputs also depend
+// on what earlier iterations covered.
+func (s *Session) Input(iteration int) map[string]interface{} {
+	var values map[string]interface{}
+	switch {
+	case s.pool != nil:
+		values = s.pool.next(s.gen, s.cfg.Seed, iteration)
+	case iteration < len(s.seeds):
+		values = s.seeds[iteration]
+	default:
+		values = s.gen.Generate().Example(generator.Seed(s.cfg.Seed, generator.StreamValues, iteration))
+	}
+	// Seeds are replayed with the tags they have
+	if tags := s.drawTags(iteration); tags != nil && (s.pool != nil || iteration >= len(s.seeds)) {
+		values = runner.MergeValues(values, map[string]interface{}{"tags": tags})
+	}
+	if len(s.opts.Values) > 0 {
+		values = runner.MergeValues(values, s.opts.Values)
+	}
+	return values
+}
+
+// newFinding builds a finding, attributing the crash to a template location
+func newFinding(r *runner.Runner, iteration int, reason, reproFile string, values map[string]interface{}) Finding {
+	finding := Finding{
+		Fingerprint: runner.Fingerprint(reason),
+		Iteration:   iteration,
+		Category:    runner.CategorizeReason(reason),
+		Reason:      reason,
+		ReproFile:   reproFile,
+		Values:      values,
+	}
+
+	if attr := runner.Attribute(reason); attr != nil {
+		finding.Attribution = attr
+		// A missing snippet only makes the report less detailed
+		if snippet, err := r.Snippet(attr, values, 3); err == nil {
+			finding.Snippet = snippet
+		}
+	}
+
+	return finding
+}
+
+// logWriter forwards written lines to a logger at debug level
+type logWriter struct {
+	logger *slog.Logger
+}
+
+func (w logWriter) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		if line != "" {
+			w.logger.Debug(line, "source", "dependencies")
+		}
+	}
+	return len(p), nil
+}
@@ -0,0 +1,1209 @@
+package fuzz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"helm.sh/helm/v3/pkg/chart"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/config"
+	"github.com/kasuboski/helm-fuzzer/pkg/corpus"
+	"github.com/kasuboski/helm-fuzzer/pkg/runner"
+)
+
+func newSession(t *testing.T, cfg *config.Config, opts Options) *Session {
+	t.Helper()
+	chartPath, err := filepath.Abs("../../testdata/buggy-chart")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts.Config = cfg
+	if opts.OutputDir == "" {
+		opts.OutputDir = t.TempDir()
+	}
+	s, err := NewWithOptions(chartPath, opts)
+	if err != nil {
+		t.Fatalf("NewWithOptions failed: %v", err)
+	}
+	return s
+}
+
+func TestRun(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Iterations = 40
+	cfg.Workers = 2
+
+	var iterations, crashes int
+	var found []Finding
+	s := newSession(t, cfg, Options{
+		Hooks: Hooks{
+			Iteration: func(it Iteration) {
+				iterations++
+				if it.Crashed {
+					crashes++
+				}
+			},
+			Crash: func(f Finding) { found = append(found, f) },
+		},
+	})
+
+	result, err := s.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if result.Iterations != 40 || iterations != 40 {
+		t.Errorf("expected 40 iterations, got %d (hook saw %d)", result.Iterations, iterations)
+	}
+	if result.Next != 40 {
+		t.Errorf("expected next iteration 40, got %d", result.Next)
+	}
+	if result.Crashes != crashes {
+		t.Errorf("expected %d crashes, got %d", crashes, result.Crashes)
+	}
+	if len(result.Findings) == 0 {
+		t.Fatal("expected the buggy chart to crash")
+	}
+	if !reflect.DeepEqual(result.Findings, found) {
+		t.Error("expected the Crash hook to see every finding")
+	}
+	for _, f := range result.Findings {
+		if f.Fingerprint == "" || f.Category == "" {
+			t.Errorf("expected fingerprint and category, got %+v", f)
+		}
+		if f.ID != runner.FindingID("buggy-chart", f.Fingerprint) {
+			t.Errorf("expected the finding's ID, got %q", f.ID)
+		}
+		if _, err := os.Stat(f.ReproFile); err != nil {
+			t.Errorf("expected reproduction file: %v", err)
+		}
+		if filepath.Base(f.ReproFile) != "fuzzer-repro-"+f.ID+".yaml" {
+			t.Errorf("expected the reproduction file named after the ID, got %s", f.ReproFile)
+		}
+	}
+	if result.Interrupted {
+		t.Error("expected a completed session not to be interrupted")
+	}
+}
+
+func TestRun_FailFastAndSeen(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Iterations = 200
+
+	result, err := newSession(t, cfg, Options{FailFast: true}).Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(result.Findings) != 1 {
+		t.Fatalf("expected fail-fast to stop at one finding, got %d", len(result.Findings))
+	}
+	first := result.Findings[0]
+
+	// A resumed session does not report what the earlier run already found
+	result, err = newSession(t, cfg, Options{FailFast: true, Seen: []string{first.Reason}}).Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	for _, f := range result.Findings {
+		if f.Fingerprint == first.Fingerprint {
+			t.Errorf("expected %s to be skipped as seen", f.Fingerprint)
+		}
+	}
+
+	// Nor what the config suppresses by ID
+	cfg.Suppress = []string{first.ID}
+	result, err = newSession(t, cfg, Options{FailFast: true}).Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	for _, f := range result.Findings {
+		if f.ID == first.ID {
+			t.Errorf("expected %s to be suppressed", f.ID)
+		}
+	}
+}
+
+func TestRun_Culprits(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Iterations = 40
+
+	result, err := newSession(t, cfg, Options{Culprits: true}).Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	ablated := 0
+	for _, f := range result.Findings {
+		if f.Culprits == nil {
+			continue
+		}
+		ablated++
+		if f.MinimalValues == nil {
+			t.Errorf("expected the minimized values of %s", f.ID)
+		}
+		data, err := os.ReadFile(f.ReproFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(f.Culprits) > 0 && !strings.Contains(string(data), "# Culprit Paths: "+strings.Join(f.Culprits, ", ")) {
+			t.Errorf("expected the culprit paths in %s:\n%s", f.ReproFile, data)
+		}
+	}
+	if ablated != len(result.Findings) {
+		t.Errorf("expected every finding ablated, got %d of %d", ablated, len(result.Findings))
+	}
+}
+
+func TestRun_RaceRenders(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Iterations = 20
+	cfg.Workers = 2
+
+	result, err := newSession(t, cfg, Options{RaceRenders: 4}).Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	// Helm renders share no state between releases, so the chart has no race
+	for _, f := range result.Findings {
+		if f.Category == runner.CategoryRace {
+			t.Errorf("expected no race, got %q", f.Reason)
+		}
+	}
+}
+
+func TestDifferingTemplate(t *testing.T) {
+	manifest := `---
+# Source: app/templates/svc.yaml
+kind: Service
+---
+# Source: app/templates/deploy.yaml
+kind: Deployment
+replicas: %d
+`
+	for _, c := range []struct {
+		a, b, want string
+	}{
+		{fmt.Sprintf(manifest, 1), fmt.Sprintf(manifest, 2), "app/templates/deploy.yaml"},
+		{fmt.Sprintf(manifest, 1), fmt.Sprintf(manifest, 1) + "# Source: app/templates/cm.yaml\nkind: ConfigMap\n", "app/templates/cm.yaml"},
+		{"kind: Service\n", "kind: ConfigMap\n", "the manifest"},
+	} {
+		if got := differingTemplate(c.a, c.b); got != c.want {
+			t.Errorf("expected %s to differ, got %s", c.want, got)
+		}
+	}
+}
+
+func TestRun_WaitStops(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Iterations = 0
+
+	calls := 0
+	s := newSession(t, cfg, Options{
+		FirstIteration: 5,
+		Hooks: Hooks{
+			Wait: func() bool {
+				calls++
+				return calls <= 3
+			},
+		},
+	})
+	result, err := s.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if !result.Interrupted {
+		t.Error("expected a session stopped by Wait to be interrupted")
+	}
+	if result.Iterations != 3 || result.Next != 8 {
+		t.Errorf("expected 3 iterations ending before 8, got %d ending before %d", result.Iterations, result.Next)
+	}
+}
+
+func TestInput(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Seeds = []map[string]interface{}{{"replicaCount": 3}}
+	s := newSession(t, cfg, Options{Values: map[string]interface{}{"nameOverride": "pinned"}})
+
+	seed := s.Input(0)
+	if seed["replicaCount"] != 3 || seed["nameOverride"] != "pinned" {
+		t.Errorf("expected the pinned seed, got %v", seed)
+	}
+	if !reflect.DeepEqual(s.Input(7), s.Input(7)) {
+		t.Error("expected generated inputs to depend only on the iteration")
+	}
+	if s.Input(7)["nameOverride"] != "pinned" {
+		t.Error("expected pinned values to override generated inputs")
+	}
+}
+
+func TestInput_Seed(t *testing.T) {
+	seeded := func(seed uint64) *Session {
+		cfg := config.DefaultConfig()
+		cfg.Seed = seed
+		return newSession(t, cfg, Options{})
+	}
+	a, b, unseeded := seeded(42), seeded(42), seeded(0)
+
+	differs := false
+	for i := 0; i < 10; i++ {
+		if !reflect.DeepEqual(a.Input(i), b.Input(i)) {
+			t.Fatalf("expected sessions with the same seed to draw the same input at iteration %d", i)
+		}
+		differs = differs || !reflect.DeepEqual(a.Input(i), unseeded.Input(i))
+	}
+	if !differs {
+		t.Error("expected another seed to draw other inputs")
+	}
+}
+
+func TestDrawTags(t *testing.T) {
+	db := &chart.Chart{Metadata: &chart.Metadata{Name: "db", Dependencies: []*chart.Dependency{{Name: "cache", Tags: []string{"cache"}}}}}
+	c := &chart.Chart{Metadata: &chart.Metadata{Name: "app", Dependencies: []*chart.Dependency{
+		{Name: "db", Tags: []string{"storage", "backend"}},
+		{Name: "ui", Tags: []string{"frontend"}},
+	}}}
+	c.AddDependency(db)
+	tags := dependencyTags(c)
+	if want := []string{"backend", "cache", "frontend", "storage"}; !reflect.DeepEqual(tags, want) {
+		t.Fatalf("dependencyTags() = %v, want %v", tags, want)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.KubeVersions = []string{"1.29.0", "1.30.0"}
+	s := &Session{cfg: cfg, tags: tags}
+	// Each combination renders against both Kubernetes versions in turn
+	seen := make(map[string]bool)
+	for i := 0; i < 32; i++ {
+		drawn := s.drawTags(i)
+		if i%2 == 1 && !reflect.DeepEqual(drawn, s.drawTags(i-1)) {
+			t.Errorf("expected iterations %d and %d to share a combination", i-1, i)
+		}
+		seen[fmt.Sprint(drawn)] = true
+	}
+	if len(seen) != 16 {
+		t.Errorf("expected all 16 combinations, got %d", len(seen))
+	}
+
+	active := s.activeTags(map[string]interface{}{"tags": map[string]interface{}{"backend": true, "frontend": false, "other": true}})
+	if want := map[string]bool{"backend": true, "frontend": false}; !reflect.DeepEqual(active, want) {
+		t.Errorf("activeTags() = %v, want %v", active, want)
+	}
+	if (&Session{cfg: cfg}).drawTags(3) != nil {
+		t.Error("expected no tags drawn without tags")
+	}
+}
+
+func TestRun_Evolve(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Iterations = 60
+	cfg.Workers = 2
+	cfg.CorpusDir = t.TempDir()
+	cfg.Seeds = []map[string]interface{}{{"replicaCount": 3}}
+
+	result, err := newSession(t, cfg, Options{Evolve: true}).Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if result.Iterations != 60 {
+		t.Errorf("expected seeds to seed the corpus rather than take iterations, got %d iterations", result.Iterations)
+	}
+	if result.Evolved == 0 {
+		t.Fatal("expected inputs reaching new coverage to join the corpus")
+	}
+	saved, err := filepath.Glob(filepath.Join(cfg.CorpusDir, "iteration-*.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved) != result.Evolved {
+		t.Errorf("expected %d corpus entries saved, got %d", result.Evolved, len(saved))
+	}
+
+	// A later session starts from the saved corpus, so the same coverage is not new again
+	cfg.Iterations = 120
+	next, err := newSession(t, cfg, Options{Evolve: true, FirstIteration: 60}).Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if next.Evolved >= result.Evolved {
+		t.Errorf("expected the resumed session to add fewer entries than the first (%d), got %d", result.Evolved, next.Evolved)
+	}
+}
+
+func TestRun_SaveCorpus(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Iterations = 60
+	cfg.Workers = 2
+	cfg.CorpusDir = t.TempDir()
+	cfg.Seed = 1
+
+	result, err := newSession(t, cfg, Options{SaveCorpus: true}).Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if result.Evolved == 0 || result.Crashes == 0 {
+		t.Fatalf("expected crashing inputs with new coverage, got %d saved and %d crashes", result.Evolved, result.Crashes)
+	}
+	saved, err := corpus.Load(cfg.CorpusDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved) != result.Evolved {
+		t.Errorf("expected %d corpus entries saved, got %d", result.Evolved, len(saved))
+	}
+
+	// The next session replays the saved entries as seeds, so they are not
+	// saved again, and draws inputs of its own from another seed
+	cfg.Seed = 2
+	next, err := newSession(t, cfg, Options{SaveCorpus: true}).Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if next.Evolved >= result.Evolved {
+		t.Errorf("expected the next session to add fewer entries than the first (%d), got %d", result.Evolved, next.Evolved)
+	}
+	if saved, err = corpus.Load(cfg.CorpusDir); err != nil {
+		t.Fatal(err)
+	}
+	if len(saved) != result.Evolved+next.Evolved {
+		t.Errorf("expected %d corpus entries after both sessions, got %d", result.Evolved+next.Evolved, len(saved))
+	}
+}
+
+func TestRun_Guided(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Iterations = 60
+	cfg.Workers = 2
+
+	result, err := newSession(t, cfg, Options{Guided: true}).Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	c := result.Coverage
+	if c.Regions == 0 || c.RegionsExecuted == 0 || c.RegionsExecuted > c.Regions {
+		t.Fatalf("expected region coverage, got %d of %d", c.RegionsExecuted, c.Regions)
+	}
+	if result.Evolved == 0 {
+		t.Error("expected inputs reaching new regions to join the corpus")
+	}
+}
+
+func TestRun_Plugins(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins are shell scripts")
+	}
+	dir := t.TempDir()
+	for name, body := range map[string]string{
+		"oracle.sh":    `cat >/dev/null; echo '{"findings": [{"reason": "every render is suspect"}]}'`,
+		"generator.sh": `cat >/dev/null; echo '{"inputs": [{"fromPlugin": true}]}'`,
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+body+"\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg := config.DefaultConfig()
+	cfg.Iterations = 20
+	cfg.Plugins = []config.Plugin{
+		{Name: "suspect", Type: config.PluginOracle, Command: []string{filepath.Join(dir, "oracle.sh")}, Timeout: 5 * time.Second},
+		{Name: "inputs", Type: config.PluginGenerator, Command: []string{filepath.Join(dir, "generator.sh")}, Timeout: 5 * time.Second, Inputs: 1},
+	}
+
+	s := newSession(t, cfg, Options{})
+	if input := s.Input(0); input["fromPlugin"] != true {
+		t.Errorf("expected the generator's input first, got %v", input)
+	}
+	result, err := s.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	reported := 0
+	for _, f := range result.Findings {
+		if f.Category == runner.CategoryPlugin {
+			reported++
+			if f.Reason != "Plugin suspect: every render is suspect" {
+				t.Errorf("expected the plugin's reason, got %q", f.Reason)
+			}
+			if f.Flaky || f.Replays != 2 || f.Reproduced != 2 {
+				t.Errorf("expected every replay to reproduce the finding, got %d/%d", f.Reproduced, f.Replays)
+			}
+		}
+	}
+	if reported != 1 {
+		t.Errorf("expected the plugin's finding once, got %d", reported)
+	}
+
+	// A finding its replays do not reproduce is flaky
+	flaky := filepath.Join(dir, "flaky.sh")
+	script := fmt.Sprintf(`#!/bin/sh
+cat >/dev/null
+if [ -e %[1]s ]; then echo '{"findings": []}'; exit; fi
+touch %[1]s
+echo '{"findings": [{"reason": "cluster unreachable"}]}'
+`, filepath.Join(dir, "fired"))
+	if err := os.WriteFile(flaky, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	cfg.Plugins = []config.Plugin{{Name: "flaky", Type: config.PluginOracle, Command: []string{flaky}, Timeout: 5 * time.Second}}
+	result, err = newSession(t, cfg, Options{}).Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	reported = 0
+	for _, f := range result.Findings {
+		if f.Category == runner.CategoryPlugin {
+			reported++
+			if !f.Flaky || f.Reproduced != 0 {
+				t.Errorf("expected the finding flaky, got %+v", f)
+			}
+		}
+	}
+	if reported != 1 {
+		t.Errorf("expected the flaky finding once, got %d", reported)
+	}
+
+	// A broken plugin fails the session rather than passing every render
+	cfg.Plugins = cfg.Plugins[:1]
+	cfg.Plugins[0].Command = []string{filepath.Join(dir, "missing.sh")}
+	if _, err := newSession(t, cfg, Options{}).Run(context.Background()); err == nil {
+		t.Error("expected an error from a plugin that cannot run")
+	}
+}
+
+func TestRun_Scanners(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("scanners are shell scripts")
+	}
+	dir := t.TempDir()
+	// The first manifest scanned is the defaults'; renders differing from it
+	// fail a second check
+	scan := `manifest=$(cat)
+[ -f "$0.base" ] || printf '%s' "$manifest" > "$0.base"
+if [ "$manifest" = "$(cat "$0.base")" ]; then
+  echo '{"Results": [{"Misconfigurations": [{"ID": "KSV001", "Title": "Defaults", "Status": "FAIL"}]}]}'
+else
+  echo '{"Results": [{"Misconfigurations": [{"ID": "KSV001", "Title": "Defaults", "Status": "FAIL"}, {"ID": "KSV002", "Title": "Changed", "Status": "FAIL"}]}]}'
+fi
+exit 1
+`
+	if err := os.WriteFile(filepath.Join(dir, "scan.sh"), []byte("#!/bin/sh\n"+scan), 0755); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.DefaultConfig()
+	cfg.Iterations = 20
+	cfg.Scanners = []config.Scanner{
+		{Name: "trivy", Format: config.ScannerTrivy, Command: []string{filepath.Join(dir, "scan.sh")}, Timeout: 5 * time.Second},
+	}
+
+	result, err := newSession(t, cfg, Options{}).Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	var reported []string
+	for _, f := range result.Findings {
+		if f.Category == runner.CategoryScanner {
+			reported = append(reported, f.Reason)
+		}
+	}
+	if !reflect.DeepEqual(reported, []string{"Scanner trivy: KSV002 Changed"}) {
+		t.Errorf("expected only the check the defaults pass reported once, got %v", reported)
+	}
+
+	// A broken scanner fails the session rather than passing every render
+	cfg.Scanners[0].Command = []string{filepath.Join(dir, "missing.sh")}
+	if _, err := newSession(t, cfg, Options{}).Run(context.Background()); err == nil {
+		t.Error("expected an error from a scanner that cannot run")
+	}
+}
+
+func TestRun_Invariants(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Iterations = 20
+	one := 1
+
+	result, err := newSession(t, cfg, Options{
+		Invariants: []config.Invariant{
+			{Kind: "Deployment", APIVersion: "apps/v1", Max: &one},
+			{Kind: "Ingress"},
+		},
+	}).Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	var violated []string
+	for _, f := range result.Findings {
+		if f.Category == runner.CategoryInvariant {
+			violated = append(violated, f.Reason)
+		}
+	}
+	if !reflect.DeepEqual(violated, []string{"Invariant violated: expected at least 1 Ingress"}) {
+		t.Errorf("expected only the Ingress invariant reported once, got %v", violated)
+	}
+}
+
+func TestRun_BuiltIns(t *testing.T) {
+	chartPath := t.TempDir()
+	files := map[string]string{
+		"Chart.yaml":               "apiVersion: v2\nname: upgrader\nversion: 0.1.0\nappVersion: 1.0.0\n",
+		"values.yaml":              "name: app\n",
+		"templates/configmap.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: {{ .Release.Name }}\ndata:\n  minor: {{ .Capabilities.KubeVersion.Minor | quote }}\n{{- if .Release.IsUpgrade }}\n{{- fail \"upgrades are unsupported\" }}\n{{- end }}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(chartPath, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Iterations = 30
+	s, err := NewWithOptions(chartPath, Options{Config: cfg, OutputDir: t.TempDir(), BuiltIns: true})
+	if err != nil {
+		t.Fatalf("NewWithOptions failed: %v", err)
+	}
+	result, err := s.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(result.Findings) != 1 {
+		t.Fatalf("expected only the upgrade failure, got %+v", result.Findings)
+	}
+	f := result.Findings[0]
+	if f.BuiltIns == nil || f.BuiltIns.Revision < 2 {
+		t.Errorf("expected the finding to record an upgrade revision, got %+v", f.BuiltIns)
+	}
+
+	for i := 0; i < 100; i++ {
+		b := s.drawBuiltIns(i, "1.28.0")
+		if len(b.ReleaseName) == 0 || len(b.ReleaseName) > 53 || len(b.Namespace) == 0 || len(b.Namespace) > 63 {
+			t.Fatalf("expected valid release name and namespace lengths, got %+v", b)
+		}
+		if !strings.HasPrefix(b.KubeVersion, "v1.28.") {
+			t.Fatalf("expected a patch level of 1.28, got %s", b.KubeVersion)
+		}
+	}
+}
+
+func TestRun_Lookup(t *testing.T) {
+	chartPath := t.TempDir()
+	files := map[string]string{
+		"Chart.yaml":               "apiVersion: v2\nname: looker\nversion: 0.1.0\n",
+		"values.yaml":              "name: app\n",
+		"templates/configmap.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: looker\n{{- if not (lookup \"v1\" \"Secret\" .Release.Namespace \"db\") }}\n{{- fail \"db secret not found\" }}\n{{- end }}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(chartPath, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Iterations = 30
+	cfg.Lookup = []map[string]interface{}{
+		{"apiVersion": "v1", "kind": "Secret", "metadata": map[string]interface{}{"name": "db"}},
+	}
+	s, err := NewWithOptions(chartPath, Options{Config: cfg, OutputDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("NewWithOptions failed: %v", err)
+	}
+	result, err := s.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	// Iterations that find the Secret render, the others fail
+	if result.Crashes == 0 || result.Crashes == result.Iterations {
+		t.Errorf("expected the Secret to be found in some iterations only, got %d crashes in %d", result.Crashes, result.Iterations)
+	}
+}
+
+func TestRun_ClusterTemplates(t *testing.T) {
+	chartPath := t.TempDir()
+	files := map[string]string{
+		"Chart.yaml":               "apiVersion: v2\nname: clustered\nversion: 0.1.0\n",
+		"values.yaml":              "name: app\n",
+		"templates/secret.yaml":    "{{- if not (lookup \"v1\" \"Secret\" .Release.Namespace \"db\") }}\n{{- fail \"db secret not found\" }}\n{{- end }}\n",
+		"templates/monitor.yaml":   "{{- if not (.Capabilities.APIVersions.Has \"monitoring.coreos.com/v1\") }}\n{{- fail \"the Prometheus operator is not installed\" }}\n{{- end }}\n",
+		"templates/configmap.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: {{ .Values.name | quote }}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(chartPath, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Iterations = 20
+	s, err := NewWithOptions(chartPath, Options{Config: cfg, OutputDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("NewWithOptions failed: %v", err)
+	}
+	want := []string{"clustered/templates/monitor.yaml", "clustered/templates/secret.yaml"}
+	skipped := append([]string{}, s.skipped...)
+	sort.Strings(skipped)
+	if !reflect.DeepEqual(skipped, want) {
+		t.Fatalf("expected %v left out, got %v", want, skipped)
+	}
+	result, err := s.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	// The rest of the chart is fuzzed rather than failing on every input
+	if result.Crashes == result.Iterations {
+		t.Errorf("expected renders without the cluster templates, got %d crashes in %d", result.Crashes, result.Iterations)
+	}
+}
+
+func TestRun_Files(t *testing.T) {
+	chartPath := t.TempDir()
+	files := map[string]string{
+		"Chart.yaml":               "apiVersion: v2\nname: filer\nversion: 0.1.0\n",
+		"values.yaml":              "name: app\n",
+		"templates/configmap.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: filer\n{{- $cfg := .Files.Get \"config/app.json\" | fromJson }}\n{{- if not (hasKey $cfg \"debug\") }}\n{{- fail \"config/app.json has no debug\" }}\n{{- end }}\ndata:\n  debug: {{ $cfg.debug | quote }}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(chartPath, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Iterations = 30
+	content := `{"debug": false}`
+	cfg.Files = []config.File{{Path: "config/app.json", Content: &content}}
+	s, err := NewWithOptions(chartPath, Options{Config: cfg, OutputDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("NewWithOptions failed: %v", err)
+	}
+	result, err := s.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	// The usual content renders; missing and malformed files fail
+	if result.Crashes == 0 || result.Crashes == result.Iterations {
+		t.Errorf("expected only some iterations to fail on the file, got %d crashes in %d", result.Crashes, result.Iterations)
+	}
+}
+
+func TestCheckBudget(t *testing.T) {
+	manifest := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 3
+  template:
+    spec:
+      initContainers:
+        - name: migrate
+          resources:
+            requests:
+              memory: 2Gi
+      containers:
+        - name: app
+          resources:
+            requests:
+              cpu: 500m
+              memory: 256Mi
+        - name: proxy
+          resources:
+            limits:
+              cpu: 250m
+              memory: 256Mi
+---
+apiVersion: autoscaling/v2
+kind: HorizontalPodAutoscaler
+metadata:
+  name: web
+spec:
+  scaleTargetRef:
+    kind: Deployment
+    name: web
+  maxReplicas: 10
+---
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: backup
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+            - name: backup
+              resources:
+                requests:
+                  cpu: 1
+`
+	// 10 web pods of 750m and 2Gi plus a backup pod of 1 CPU
+	tests := []struct {
+		budget config.Budget
+		want   []string
+	}{
+		{config.Budget{CPU: "8.5", Memory: "20Gi", Pods: 11}, nil},
+		{config.Budget{CPU: "8"}, []string{"Budget exceeded: workloads request more than 8 CPU"}},
+		{config.Budget{Memory: "16Gi", Pods: 10}, []string{
+			"Budget exceeded: workloads request more than 16Gi memory",
+			"Budget exceeded: workloads run more than 10 pods",
+		}},
+	}
+	for _, tt := range tests {
+		if got := checkBudget(manifest, tt.budget); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("checkBudget(%+v) = %v, want %v", tt.budget, got, tt.want)
+		}
+	}
+}
+
+func TestCheckSlow(t *testing.T) {
+	bounds := config.SlowRender{Factor: 10, Min: 100 * time.Millisecond}
+	times := &renderTimes{}
+
+	// Nothing is judged until enough renders were timed
+	if reason := times.checkSlow(time.Second, bounds); reason != "" {
+		t.Errorf("expected the first render not to be judged, got %q", reason)
+	}
+	for i := 1; i < minRenderSamples; i++ {
+		times.checkSlow(5*time.Millisecond, bounds)
+	}
+
+	for _, c := range []struct {
+		d    time.Duration
+		slow bool
+	}{
+		{40 * time.Millisecond, false},
+		// Over the factor, but under the minimum
+		{90 * time.Millisecond, false},
+		{200 * time.Millisecond, true},
+	} {
+		reason := times.checkSlow(c.d, bounds)
+		if (reason != "") != c.slow {
+			t.Errorf("expected %s slow=%v, got %q", c.d, c.slow, reason)
+		}
+		if c.slow && runner.CategorizeReason(reason) != runner.CategorySlow {
+			t.Errorf("expected the slow render category, got %q", runner.CategorizeReason(reason))
+		}
+	}
+}
+
+func TestMemoryTracker(t *testing.T) {
+	m := newMemoryTracker(config.MemoryGrowth{Interval: 2, Samples: 3, Min: "1Mi"})
+	var heap uint64 = 100 << 20
+	m.heap = func() uint64 { return heap }
+	render := func(i int, templates ...string) *memoryGrowth {
+		return m.record(i, &runner.Result{Templates: templates, Values: map[string]interface{}{"i": i}})
+	}
+
+	// The first sample is the baseline, then the heap shrinks once
+	render(0, "app/templates/svc.yaml")
+	render(1, "app/templates/svc.yaml")
+	heap -= 1 << 20
+	render(2, "app/templates/svc.yaml")
+	render(3, "app/templates/svc.yaml")
+
+	// The heap grows by 1Mi in each of three samples, whenever the
+	// configmap is rendered, which the service is in every interval
+	var growth *memoryGrowth
+	for i := 4; i < 10; i += 2 {
+		if growth != nil {
+			t.Fatalf("expected growth only after three samples, got %q at %d", growth.reason, i)
+		}
+		heap += 1 << 20
+		render(i, "app/templates/svc.yaml")
+		growth = render(i+1, "app/templates/svc.yaml", "app/templates/cm.yaml")
+	}
+	if growth == nil {
+		t.Fatal("expected growth after three growing samples")
+	}
+	if runner.CategorizeReason(growth.reason) != runner.CategoryMemory || !strings.HasSuffix(growth.reason, "app/templates/svc.yaml") {
+		t.Errorf("expected growth blamed on the service rendered twice as often, got %q", growth.reason)
+	}
+	if growth.suspect.iteration != 9 {
+		t.Errorf("expected the latest input rendering the service, got iteration %d", growth.suspect.iteration)
+	}
+
+	// Growth under the minimum is not reported, and intervals start over
+	for i := 10; i < 16; i += 2 {
+		heap += 1 << 10
+		render(i, "app/templates/svc.yaml")
+		if growth := render(i+1, "app/templates/svc.yaml"); growth != nil {
+			t.Errorf("expected growth under the minimum not reported, got %q", growth.reason)
+		}
+	}
+
+	// Growth while timed-out renders run in the background is theirs
+	background := 1
+	m.background = func() int { return background }
+	for i := 16; i < 24; i += 2 {
+		heap += 1 << 20
+		render(i, "app/templates/svc.yaml")
+		if growth := render(i+1, "app/templates/svc.yaml"); growth != nil {
+			t.Errorf("expected growth with renders in the background not reported, got %q", growth.reason)
+		}
+	}
+	background = 0
+	for i := 24; i < 28; i += 2 {
+		heap += 1 << 20
+		render(i, "app/templates/svc.yaml")
+		if growth := render(i+1, "app/templates/svc.yaml"); growth != nil {
+			t.Errorf("expected intervals to start over once the background renders end, got %q at %d", growth.reason, i)
+		}
+	}
+}
+
+func TestCheckDefaultsDiff(t *testing.T) {
+	defaults := newRenderedDefaults(&runner.Result{Success: true, Manifest: `---
+kind: Deployment
+metadata:
+  name: web
+---
+kind: Service
+metadata:
+  name: web
+`})
+	if defaults.err != nil {
+		t.Fatalf("expected the defaults parsed, got %v", defaults.err)
+	}
+	bounds := config.DefaultsDiff{SizeRatio: 3, ObjectRatio: 2, Keep: []string{"Deployment", "Ingress"}}
+
+	if reasons := defaults.checkDiff(defaults.manifest, bounds); len(reasons) != 0 {
+		t.Errorf("expected the defaults within their own bounds, got %v", reasons)
+	}
+
+	// Ten ConfigMaps and no Deployment stray past every bound; Ingress is
+	// kept only if the defaults have one
+	var b strings.Builder
+	for i := 0; i < 10; i++ {
+		fmt.Fprintf(&b, "---\nkind: ConfigMap\nmetadata:\n  name: cm-%d\n", i)
+	}
+	want := []string{
+		"Defaults diff: the manifest is more than 3x the size of the defaults' manifest",
+		"Defaults diff: more than 2x the defaults' 2 objects",
+		"Defaults diff: no Deployment, which the defaults render",
+	}
+	if got := defaults.checkDiff(b.String(), bounds); !reflect.DeepEqual(got, want) {
+		t.Errorf("checkDiff() =\n%q\nwant\n%q", got, want)
+	}
+
+	if failed := newRenderedDefaults(&runner.Result{Error: errors.New("boom")}); failed.err == nil {
+		t.Error("expected defaults that fail to render to carry the error")
+	}
+}
+
+func TestCheckYAML11(t *testing.T) {
+	manifest := `---
+# Source: app/templates/configmap.yaml
+kind: ConfigMap
+data:
+  enabled: no
+  quoted: "no"
+  window: [1:30]
+---
+# Source: app/templates/deployment.yaml
+kind: Deployment
+spec:
+  mode: 0777
+  chart: yes
+`
+	values := map[string]interface{}{
+		"enabled": "no",
+		"window":  []interface{}{"1:30"},
+		"mode":    map[string]interface{}{"file": "0777"},
+		"name":    "web",
+	}
+	// yes is the chart's own, not a string of the values
+	want := []string{
+		`YAML quirk: the string "no" renders unquoted at data.enabled in app/templates/configmap.yaml (ConfigMap), which YAML 1.1 parsers read as a boolean`,
+		`YAML quirk: the string "1:30" renders unquoted at data.window[] in app/templates/configmap.yaml (ConfigMap), which YAML 1.1 parsers read as an integer`,
+		`YAML quirk: the string "0777" renders unquoted at spec.mode in app/templates/deployment.yaml (Deployment), which YAML 1.1 parsers read as an integer`,
+	}
+	if got := checkYAML11(manifest, values); !reflect.DeepEqual(got, want) {
+		t.Errorf("checkYAML11() =\n%q\nwant\n%q", got, want)
+	}
+
+	for s, want := range map[string]string{"off": "a boolean", "190:20:30": "an integer", "~": "null", "2001-12-14": "a timestamp", "1e3": "", "web": ""} {
+		if got := yaml11Type(s); got != want {
+			t.Errorf("yaml11Type(%q) = %q, want %q", s, got, want)
+		}
+	}
+	if got := yaml12Type("1e3"); got != "a float" {
+		t.Errorf("expected YAML 1.2 to read 1e3 as a float, got %q", got)
+	}
+}
+
+func TestCheckNumbers(t *testing.T) {
+	manifest := `---
+# Source: app/templates/configmap.yaml
+kind: ConfigMap
+data:
+  id: 9.007199254740992e+15
+  rounded: 9007199254740992
+  exact: 12345678901234567
+  size: 1e+09
+  small: 1.5e-07
+  ratio: 0.25
+---
+# Source: app/templates/deployment.yaml
+kind: Deployment
+spec:
+  replicas: "1e+06"
+`
+	values := map[string]interface{}{
+		"id":    1<<53 + 1,
+		"exact": int64(12345678901234567),
+		"size":  1e9,
+		"small": 1.5e-7,
+		"ratio": 0.25,
+		"list":  []interface{}{1e6},
+	}
+	// A float rendered exactly in scientific notation is left alone, as are
+	// numbers Go never formats that way
+	want := []string{
+		"Number format: an integer beyond 2^53 renders in scientific notation at data.id in app/templates/configmap.yaml (ConfigMap)",
+		"Number format: an integer beyond 2^53 renders with lost precision at data.rounded in app/templates/configmap.yaml (ConfigMap)",
+		"Number format: a whole number renders in scientific notation at data.size in app/templates/configmap.yaml (ConfigMap)",
+		"Number format: a whole number renders in scientific notation at spec.replicas in app/templates/deployment.yaml (Deployment)",
+	}
+	if got := checkNumbers(manifest, values); !reflect.DeepEqual(got, want) {
+		t.Errorf("checkNumbers() =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestCheckUnicode(t *testing.T) {
+	name := strings.Repeat("a", 61) + "日本"
+	manifest := `---
+# Source: app/templates/configmap.yaml
+kind: ConfigMap
+metadata:
+  name: ` + name[:63] + `
+---
+# Source: app/templates/service.yaml
+kind: Service
+metadata:
+  name: web
+  labels:
+    app.kubernetes.io/instance: ` + name + `
+data:
+  description: ` + name + name + `
+`
+	// The description is no name or label, so any length is fine
+	want := []string{
+		"Unicode: invalid UTF-8 renders at name in app/templates/configmap.yaml",
+		"Unicode: 63 characters or fewer render over 63 bytes at metadata.labels.app.kubernetes.io/instance in app/templates/service.yaml (Service)",
+	}
+	if got := checkUnicode(manifest); !reflect.DeepEqual(got, want) {
+		t.Errorf("checkUnicode() =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestCheckInvariants(t *testing.T) {
+	manifest := `---
+# Source: app/templates/svc.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: metrics
+`
+	zero, two := 0, 2
+	tests := []struct {
+		invariant config.Invariant
+		want      []string
+	}{
+		{config.Invariant{Kind: "Service"}, nil},
+		{config.Invariant{Kind: "Service", Name: "web", Min: &two}, []string{"Invariant violated: expected at least 2 Service named web"}},
+		{config.Invariant{Kind: "Service", APIVersion: "v2"}, []string{"Invariant violated: expected at least 1 v2 Service"}},
+		{config.Invariant{Kind: "Service", Max: &zero}, []string{"Invariant violated: expected exactly 0 Service"}},
+		{config.Invariant{Kind: "Ingress", Max: &two}, nil},
+	}
+	for _, tt := range tests {
+		if got := checkInvariants(manifest, []config.Invariant{tt.invariant}); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("checkInvariants(%+v) = %v, want %v", tt.invariant, got, tt.want)
+		}
+	}
+}
+
+func TestNewWithOptions_RiskWeighted(t *testing.T) {
+	s := newSession(t, config.DefaultConfig(), Options{RiskWeighted: true})
+	optional := s.Schema().Properties["optional"]
+	if optional.Risk == 0 || !optional.Properties["config"].Nilable {
+		t.Errorf("expected optional.config to be weighted as nilable, got %+v", optional)
+	}
+	if replicas := s.Schema().Properties["replicaCount"]; replicas.Risk != 0 {
+		t.Errorf("expected replicaCount not to be weighted, got %d", replicas.Risk)
+	}
+
+	if unweighted := newSession(t, config.DefaultConfig(), Options{}); unweighted.Schema().Properties["optional"].Risk != 0 {
+		t.Error("expected no weighting without RiskWeighted")
+	}
+}
+
+func TestEntryWeight(t *testing.T) {
+	path := entryWeight([]string{"path:image.tag"})
+	region := entryWeight([]string{"region:app/templates/deploy.yaml:3 if .Values.debug"})
+	template := entryWeight([]string{"template:app/templates/deploy.yaml"})
+	if !(path < region && region < template) {
+		t.Errorf("expected a new region to weigh between a new path and a new template, got %d, %d, %d", path, region, template)
+	}
+}
+
+func TestNewWithOptions_ConfigError(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Constraints = []config.Constraint{{Path: "image.tag", Template: "{{ .Unclosed"}}
+
+	_, err := NewWithOptions("../../testdata/buggy-chart", Options{Config: cfg})
+	var configErr *ConfigError
+	if !errors.As(err, &configErr) {
+		t.Errorf("expected a ConfigError, got %v", err)
+	}
+
+	// API server binaries are looked for before fuzzing starts
+	cfg = config.DefaultConfig()
+	cfg.Envtest.Assets = t.TempDir()
+	_, err = NewWithOptions("../../testdata/buggy-chart", Options{Config: cfg, Envtest: true})
+	if !errors.As(err, &configErr) {
+		t.Errorf("expected a ConfigError for missing envtest binaries, got %v", err)
+	}
+	_, err = NewWithOptions("../../testdata/buggy-chart", Options{Config: cfg, Upgrades: true})
+	if !errors.As(err, &configErr) {
+		t.Errorf("expected upgrades to need envtest binaries, got %v", err)
+	}
+
+	_, err = NewWithOptions(filepath.Join(t.TempDir(), "missing"), Options{Config: config.DefaultConfig()})
+	if err == nil || errors.As(err, &configErr) {
+		t.Errorf("expected a chart error, got %v", err)
+	}
+}
+
+func TestChart(t *testing.T) {
+	chartPath := t.TempDir()
+	files := map[string]string{
+		"Chart.yaml":               "apiVersion: v2\nname: sturdy\nversion: 0.1.0\n",
+		"values.yaml":              "name: app\nreplicas: 1\n",
+		"templates/configmap.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: sturdy\ndata:\n  name: {{ .Values.name | quote }}\n  replicas: {{ .Values.replicas | quote }}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(chartPath, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A chart that quotes every value renders whatever rapid draws
+	Chart(t, chartPath, Options{OutputDir: t.TempDir()})
+}
+
+func TestCopyChart(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"sub/Chart.yaml":        "apiVersion: v2\nname: sub\nversion: 0.1.0\n",
+		"sub/templates/cm.yaml": "kind: ConfigMap\n",
+		"app/Chart.yaml":        "apiVersion: v2\nname: app\nversion: 0.1.0\ndependencies:\n- name: sub\n  version: 0.1.0\n  repository: file://../sub\n",
+		"app/values.yaml":       "replicas: 1\n",
+		"app/templates/cm.yaml": "kind: ConfigMap\ndata:\n  replicas: {{ .Values.replicas | quote }}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	root, copied, err := copyChart(filepath.Join(dir, "app"))
+	if err != nil {
+		t.Fatalf("copyChart failed: %v", err)
+	}
+	defer os.RemoveAll(root)
+
+	// The relative dependency still resolves, so building it fills only the copy
+	s, err := NewWithOptions(copied, Options{Config: config.DefaultConfig(), DependencyUpdate: true})
+	if err != nil {
+		t.Fatalf("NewWithOptions failed: %v", err)
+	}
+	want := []string{"app/charts/sub/templates/cm.yaml", "app/templates/cm.yaml"}
+	if !reflect.DeepEqual(s.templates, want) {
+		t.Errorf("expected the subchart's templates, got %v", s.templates)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "app", "charts")); !os.IsNotExist(err) {
+		t.Errorf("expected the original chart untouched, got %v", err)
+	}
+}
+
+func TestNewWithOptions_Subchart(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"Chart.yaml":                     "apiVersion: v2\nname: app\nversion: 0.1.0\ndependencies:\n- name: cache\n  version: 0.1.0\n  condition: cache.enabled\n",
+		"values.yaml":                    "replicas: 1\ncache:\n  enabled: false\n  size: 2\n",
+		"templates/cm.yaml":              "kind: ConfigMap\n",
+		"charts/cache/Chart.yaml":        "apiVersion: v2\nname: cache\nversion: 0.1.0\n",
+		"charts/cache/values.yaml":       "size: 1\nimage: redis\n",
+		"charts/cache/templates/ss.yaml": "kind: StatefulSet\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	names, err := Subcharts(dir)
+	if err != nil || !reflect.DeepEqual(names, []string{"cache"}) {
+		t.Fatalf("expected the cache subchart, got %v (%v)", names, err)
+	}
+
+	s, err := NewWithOptions(dir, Options{Config: config.DefaultConfig(), Subchart: "cache"})
+	if err != nil {
+		t.Fatalf("NewWithOptions failed: %v", err)
+	}
+	props := s.Schema().Properties
+	if len(props) != 1 || props["cache"] == nil || props["cache"].Properties["image"] == nil || props["cache"].Properties["enabled"] == nil {
+		t.Fatalf("expected a schema of the cache values only, got %+v", props)
+	}
+	for i := 0; i < 10; i++ {
+		values := s.Input(i)
+		if _, ok := values["replicas"]; ok {
+			t.Errorf("expected the parent's values at their defaults, got %v", values)
+		}
+		if cache, _ := values["cache"].(map[string]interface{}); cache["enabled"] != true {
+			t.Errorf("expected the cache subchart enabled, got %v", values)
+		}
+	}
+
+	_, err = NewWithOptions(dir, Options{Config: config.DefaultConfig(), Subchart: "db"})
+	var configErr *ConfigError
+	if !errors.As(err, &configErr) {
+		t.Errorf("expected a config error for an unknown subchart, got %v", err)
+	}
+}
@@ -0,0 +1,30 @@
+package fuzz
+
+import (
+	"pgregory.net/rapid"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/generator"
+)
+
+// drawLookup returns the configured lookup objects an iteration finds, each
+// found or not by a draw from the iteration's seed, so both branches of a
+// template checking for an object are fuzzed
+func (s *Session) drawLookup(iteration int) []map[string]interface{} {
+	if len(s.cfg.Lookup) == 0 {
+		return nil
+	}
+	return lookupGenerator(s.cfg.Lookup).Example(generator.Seed(s.cfg.Seed, generator.StreamLookup, iteration))
+}
+
+// lookupGenerator draws subsets of objects
+func lookupGenerator(objects []map[string]interface{}) *rapid.Generator[[]map[string]interface{}] {
+	return rapid.Custom(func(t *rapid.T) []map[string]interface{} {
+		var found []map[string]interface{}
+		for _, obj := range objects {
+			if rapid.Bool().Draw(t, "found") {
+				found = append(found, obj)
+			}
+		}
+		return found
+	})
+}
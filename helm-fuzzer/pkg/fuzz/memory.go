@@ -0,0 +1,174 @@
+package fuzz
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/config"
+	"github.com/kasuboski/helm-fuzzer/pkg/quantity"
+	"github.com/kasuboski/helm-fuzzer/pkg/runner"
+)
+
+// suspect is an input rendered while the heap grew
+type suspect struct {
+	// iteration is the input's iteration index
+	iteration int
+	values    map[string]interface{}
+	builtIns  *runner.BuiltIns
+}
+
+// heapInterval is what was rendered between two samples of the live heap
+type heapInterval struct {
+	// growth is how much the live heap grew, negative if it shrank
+	growth int64
+	// renders counts the iterations, templates the renders of each template
+	renders   int
+	templates map[string]int
+	// suspects holds the latest input rendering each template
+	suspects map[string]suspect
+}
+
+// memoryTracker samples the live heap every bounds.Interval iterations and
+// keeps what was rendered between the latest samples, to blame a heap that
+// keeps growing on the templates rendered while it grew. It is not safe for
+// concurrent use; the session lock guards it.
+type memoryTracker struct {
+	bounds config.MemoryGrowth
+	// min is bounds.Min in bytes
+	min float64
+	// heap returns the live heap in bytes
+	heap func() uint64
+	// background returns how many timed-out renders still run in the background
+	background func() int
+	// last is the live heap at the latest sample, 0 before the first
+	last uint64
+	// current is what was rendered since the latest sample
+	current heapInterval
+	// intervals are the latest intervals, oldest first, at most bounds.Samples
+	intervals []heapInterval
+}
+
+// memoryGrowth is a heap that kept growing
+type memoryGrowth struct {
+	reason string
+	// suspect is the latest input rendering the template the growth is blamed on
+	suspect suspect
+	// heap is the live heap in bytes when the growth was found
+	heap uint64
+	// templates are the templates rendered while the heap grew, most to least suspect
+	templates []string
+}
+
+// newMemoryTracker returns a tracker sampling the live heap after a garbage
+// collection, so garbage never looks like growth
+func newMemoryTracker(bounds config.MemoryGrowth) *memoryTracker {
+	// A minimum that does not parse, which loading the config rejects, is none
+	minGrowth, _ := quantity.Parse(bounds.Min)
+	return &memoryTracker{bounds: bounds, min: minGrowth, heap: liveHeap, background: runner.Abandoned, current: newHeapInterval()}
+}
+
+func newHeapInterval() heapInterval {
+	return heapInterval{templates: make(map[string]int), suspects: make(map[string]suspect)}
+}
+
+// liveHeap collects garbage and returns the bytes of live heap objects
+func liveHeap() uint64 {
+	runtime.GC()
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.HeapAlloc
+}
+
+// record adds a render of an iteration's input and, every bounds.Interval
+// renders, samples the live heap. It returns the growth found if the heap
+// grew in each of the latest bounds.Samples intervals and by bounds.Min in
+// all, nil otherwise. The growth is blamed on the template rendered the most
+// in those intervals, each weighted by how much the heap grew in it; like
+// race reasons, the reason names the template rather than the bytes, so
+// growth blamed on one template shares a fingerprint.
+func (m *memoryTracker) record(iteration int, res *runner.Result) *memoryGrowth {
+	m.current.renders++
+	for _, template := range res.Templates {
+		m.current.templates[template]++
+		m.current.suspects[template] = suspect{iteration: iteration, values: res.Values, builtIns: res.BuiltIns}
+	}
+	if m.current.renders < m.bounds.Interval {
+		return nil
+	}
+
+	heap := m.heap()
+	interval := m.current
+	m.current = newHeapInterval()
+	if m.last == 0 || m.background() > 0 {
+		// Renders before the first sample warm caches up rather than leak,
+		// and timed-out renders grow the heap in the background whatever
+		// is rendered, so intervals start over from this sample
+		m.last = heap
+		m.intervals = nil
+		return nil
+	}
+	interval.growth = int64(heap) - int64(m.last)
+	m.last = heap
+	m.intervals = append(m.intervals, interval)
+	if len(m.intervals) > m.bounds.Samples {
+		m.intervals = m.intervals[1:]
+	}
+	if len(m.intervals) < m.bounds.Samples {
+		return nil
+	}
+
+	var total int64
+	for _, in := range m.intervals {
+		if in.growth <= 0 {
+			return nil
+		}
+		total += in.growth
+	}
+	if float64(total) < m.min {
+		return nil
+	}
+
+	templates := m.suspectTemplates()
+	if len(templates) == 0 {
+		return nil
+	}
+	growth := &memoryGrowth{
+		reason:    fmt.Sprintf("Memory growth: the live heap grew in %d samples in a row, most with renders of %s", m.bounds.Samples, templates[0]),
+		heap:      heap,
+		templates: templates,
+	}
+	// The latest input rendering the template is the freshest suspect
+	for i := len(m.intervals) - 1; i >= 0; i-- {
+		if s, ok := m.intervals[i].suspects[templates[0]]; ok {
+			growth.suspect = s
+			break
+		}
+	}
+	// The next growth is judged on intervals of its own
+	m.intervals = nil
+	return growth
+}
+
+// suspectTemplates returns the templates rendered in the latest intervals,
+// by how much of each interval's growth their share of its renders accounts
+// for, most first
+func (m *memoryTracker) suspectTemplates() []string {
+	scores := make(map[string]float64)
+	for _, in := range m.intervals {
+		for template, renders := range in.templates {
+			scores[template] += float64(in.growth) * float64(renders) / float64(in.renders)
+		}
+	}
+	templates := make([]string, 0, len(scores))
+	for template := range scores {
+		templates = append(templates, template)
+	}
+	sort.Slice(templates, func(i, j int) bool {
+		if scores[templates[i]] != scores[templates[j]] {
+			return scores[templates[i]] > scores[templates[j]]
+		}
+		return templates[i] < templates[j]
+	})
+	return templates
+}
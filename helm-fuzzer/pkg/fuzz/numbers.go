@@ -0,0 +1,111 @@
+package fuzz
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maxExactInteger is the largest integer every smaller one of which a
+// float64 holds exactly
+const maxExactInteger = 1 << 53
+
+// trackedNumber is a number of the values that float formatting mangles
+type trackedNumber struct {
+	// integer is set for integers beyond 2^53, value for the rest
+	integer *int64
+	value   float64
+	// what describes the number in reasons
+	what string
+}
+
+// checkNumbers returns a crash reason for each number of the values that
+// renders mangled: an integer beyond 2^53 or a float Go formats in
+// scientific notation, rendered as a scalar close to it but not it, or a
+// whole number rendered in scientific notation, such as 1e+09 for
+// 1000000000. Such renders come from templates that push numbers through
+// float arithmetic or formatting, and the object ends up with a number other
+// than the one in the values, or one its field does not accept. Like budget
+// reasons, the reasons name where the number rendered rather than the
+// number, so every render mangling numbers in one place shares a
+// fingerprint.
+func checkNumbers(manifest string, values map[string]interface{}) []string {
+	var numbers []trackedNumber
+	collectNumbers(values, &numbers)
+	if len(numbers) == 0 {
+		return nil
+	}
+
+	var reasons []string
+	seen := make(map[string]bool)
+	renderedScalars(manifest, func(_, at string, n *yaml.Node) {
+		for _, number := range numbers {
+			how := number.mangled(n.Value)
+			if how == "" {
+				continue
+			}
+			reason := fmt.Sprintf("Number format: %s renders %s at %s", number.what, how, at)
+			if !seen[reason] {
+				seen[reason] = true
+				reasons = append(reasons, reason)
+			}
+			return
+		}
+	})
+	return reasons
+}
+
+// mangled returns how a rendered scalar mangles the number, or an empty
+// string if it is the number exactly or not the number at all
+func (t trackedNumber) mangled(s string) string {
+	if t.integer != nil {
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil && n == *t.integer {
+			return ""
+		}
+	}
+	y, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsInf(y, 0) || math.IsNaN(y) || math.Abs(y-t.value) > 1e-6*math.Abs(t.value) {
+		return ""
+	}
+	whole := t.integer != nil || t.value == math.Trunc(t.value)
+	switch {
+	case whole && strings.ContainsAny(s, "eE"):
+		return "in scientific notation"
+	case t.integer != nil || y != t.value:
+		return "with lost precision"
+	}
+	return ""
+}
+
+// collectNumbers appends the numbers of value float formatting mangles:
+// integers beyond 2^53, and floats Go formats in scientific notation
+func collectNumbers(value interface{}, numbers *[]trackedNumber) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, child := range v {
+			collectNumbers(child, numbers)
+		}
+	case []interface{}:
+		for _, child := range v {
+			collectNumbers(child, numbers)
+		}
+	case int:
+		collectNumbers(int64(v), numbers)
+	case int64:
+		if v > maxExactInteger || v < -maxExactInteger {
+			*numbers = append(*numbers, trackedNumber{integer: &v, value: float64(v), what: "an integer beyond 2^53"})
+		}
+	case float64:
+		if math.IsInf(v, 0) || math.IsNaN(v) || !strings.Contains(strconv.FormatFloat(v, 'g', -1, 64), "e") {
+			return
+		}
+		what := "a float"
+		if v == math.Trunc(v) {
+			what = "a whole number"
+		}
+		*numbers = append(*numbers, trackedNumber{value: v, what: what})
+	}
+}
@@ -0,0 +1,118 @@
+package fuzz
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/runner"
+)
+
+// checkRace renders the input of a successful render again from c.races
+// goroutines at once, sharing the runner as parallel workers do, and returns
+// a crash reason if any concurrent render failed or rendered differently.
+// The input is first rendered again alone: a chart whose templates draw
+// random values renders differently every time, which is no race, so such
+// inputs are not judged. Each goroutine renders its own copy of the values,
+// so the check itself is safe under the race detector. Like slow reasons,
+// the reason names what went wrong rather than the values, so every race in
+// a template shares a fingerprint.
+func (c *checker) checkRace(r *runner.Runner, res *runner.Result) string {
+	serial := r.RunWithBuiltIns(copyValues(res.Values), res.BuiltIns)
+	if serial.Error != nil || serial.Manifest != res.Manifest {
+		return ""
+	}
+
+	results := make([]*runner.Result, c.races)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = r.RunWithBuiltIns(copyValues(res.Values), res.BuiltIns)
+		}(i)
+	}
+	wg.Wait()
+
+	for _, concurrent := range results {
+		if c.oracle.IsCrash(concurrent) {
+			return fmt.Sprintf("Race: a concurrent render of the same input failed (%s)", runner.CategorizeReason(c.oracle.GetCrashReason(concurrent)))
+		}
+	}
+	for _, concurrent := range results {
+		if concurrent.Manifest != res.Manifest {
+			return fmt.Sprintf("Race: concurrent renders of the same input rendered %s differently", differingTemplate(res.Manifest, concurrent.Manifest))
+		}
+	}
+	return ""
+}
+
+// differingTemplate returns the first template, named by the Source comments
+// of the manifests, whose output differs between them, or "the manifest" if
+// they differ outside any template
+func differingTemplate(a, b string) string {
+	as, bs := templateOutputs(a), templateOutputs(b)
+	for _, name := range as.names {
+		if as.outputs[name] != bs.outputs[name] {
+			return name
+		}
+	}
+	for _, name := range bs.names {
+		if _, ok := as.outputs[name]; !ok {
+			return name
+		}
+	}
+	return "the manifest"
+}
+
+// sourcedOutputs are the outputs of a manifest's templates in the order they appear
+type sourcedOutputs struct {
+	names   []string
+	outputs map[string]string
+}
+
+// templateOutputs splits a manifest at its Source comments, joining the
+// documents of each template without their separators
+func templateOutputs(manifest string) sourcedOutputs {
+	out := sourcedOutputs{outputs: make(map[string]string)}
+	var current string
+	for _, line := range strings.Split(manifest, "\n") {
+		if name, ok := strings.CutPrefix(line, "# Source: "); ok {
+			current = strings.TrimSpace(name)
+			if _, seen := out.outputs[current]; !seen {
+				out.names = append(out.names, current)
+				out.outputs[current] = ""
+			}
+			continue
+		}
+		if current != "" && line != "---" && strings.TrimSpace(line) != "" {
+			out.outputs[current] += line + "\n"
+		}
+	}
+	return out
+}
+
+// copyValues deep-copies values, so renders sharing an input never share its maps
+func copyValues(values map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		out[key] = copyValue(value)
+	}
+	return out
+}
+
+// copyValue deep-copies maps and slices
+func copyValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return copyValues(v)
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, child := range v {
+			out[i] = copyValue(child)
+		}
+		return out
+	default:
+		return v
+	}
+}
@@ -0,0 +1,25 @@
+package fuzz
+
+import (
+	"context"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/scanner"
+)
+
+// scanBaseline scans the render of the chart's defaults, with pinned values
+// applied, and returns the reasons found there, which iterations leave out:
+// a check the defaults already fail would otherwise make every render a
+// finding. Defaults that do not render have an empty baseline.
+func (s *Session) scanBaseline(ctx context.Context) (map[string]bool, error) {
+	if s.defaultRender.err != nil {
+		return map[string]bool{}, nil
+	}
+	reasons, err := scanner.ScanAll(ctx, s.scanners, s.defaultRender.manifest)
+	if err != nil {
+		return nil, err
+	}
+	for _, reason := range reasons {
+		s.logger.Debug("default render already fails scanner check", "reason", reason)
+	}
+	return scanner.Baseline(reasons), nil
+}
@@ -0,0 +1,93 @@
+package fuzz
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/config"
+)
+
+// checkInvariants returns a crash reason for each invariant the rendered
+// manifest breaks. The reasons leave out how many resources rendered, so
+// every input breaking an invariant the same way shares its fingerprint.
+func checkInvariants(manifest string, invariants []config.Invariant) []string {
+	resources, err := decodeResources(manifest)
+	if err != nil {
+		return []string{fmt.Sprintf("Invariant unchecked: rendered manifest does not parse: %v", err)}
+	}
+
+	var reasons []string
+	for _, invariant := range invariants {
+		count := 0
+		for _, resource := range resources {
+			if matches(invariant, resource) {
+				count++
+			}
+		}
+		if lo, hi := invariant.Bounds(); count < lo || (hi >= 0 && count > hi) {
+			reasons = append(reasons, "Invariant violated: expected "+describe(invariant))
+		}
+	}
+	return reasons
+}
+
+// decodeResources decodes every document of a YAML stream, skipping empty ones
+func decodeResources(manifest string) ([]map[string]interface{}, error) {
+	var resources []map[string]interface{}
+	dec := yaml.NewDecoder(strings.NewReader(manifest))
+	for {
+		var doc map[string]interface{}
+		if err := dec.Decode(&doc); errors.Is(err, io.EOF) {
+			return resources, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if doc != nil {
+			resources = append(resources, doc)
+		}
+	}
+}
+
+// matches reports whether a resource is one the invariant counts
+func matches(invariant config.Invariant, resource map[string]interface{}) bool {
+	if resource["kind"] != invariant.Kind {
+		return false
+	}
+	if invariant.APIVersion != "" && resource["apiVersion"] != invariant.APIVersion {
+		return false
+	}
+	if invariant.Name != "" {
+		metadata, _ := resource["metadata"].(map[string]interface{})
+		if metadata["name"] != invariant.Name {
+			return false
+		}
+	}
+	return true
+}
+
+// describe phrases an invariant as what a render should contain, such as
+// at least 1 networking.k8s.io/v1 Ingress
+func describe(invariant config.Invariant) string {
+	selector := invariant.Kind
+	if invariant.APIVersion != "" {
+		selector = invariant.APIVersion + " " + selector
+	}
+	if invariant.Name != "" {
+		selector += " named " + invariant.Name
+	}
+
+	switch lo, hi := invariant.Bounds(); {
+	case hi < 0:
+		return fmt.Sprintf("at least %d %s", lo, selector)
+	case lo == hi:
+		return fmt.Sprintf("exactly %d %s", lo, selector)
+	case lo == 0:
+		return fmt.Sprintf("at most %d %s", hi, selector)
+	default:
+		return fmt.Sprintf("between %d and %d %s", lo, hi, selector)
+	}
+}
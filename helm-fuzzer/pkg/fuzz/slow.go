@@ -0,0 +1,57 @@
+package fuzz
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/config"
+)
+
+const (
+	// renderSamples is how many of the latest render times the median is taken over
+	renderSamples = 1000
+	// minRenderSamples is how many renders are timed before any is judged
+	minRenderSamples = 20
+)
+
+// renderTimes holds the times of the latest successful renders, which
+// later renders are judged against. It is safe for concurrent use.
+type renderTimes struct {
+	mu      sync.Mutex
+	samples []time.Duration
+	// next is where the next sample goes once samples is full
+	next int
+}
+
+// checkSlow records a render time and returns a crash reason if the render
+// took more than the bounds allow: over their minimum and more than their
+// factor times the median of the renders before it. Like budget reasons,
+// the reason names the bound rather than the time, so every slow render
+// shares a fingerprint.
+func (t *renderTimes) checkSlow(d time.Duration, bounds config.SlowRender) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var reason string
+	if len(t.samples) >= minRenderSamples && d > bounds.Min {
+		if median := t.median(); float64(d) > bounds.Factor*float64(median) {
+			reason = fmt.Sprintf("Slow render: took more than %gx the median render time", bounds.Factor)
+		}
+	}
+	if len(t.samples) < renderSamples {
+		t.samples = append(t.samples, d)
+	} else {
+		t.samples[t.next] = d
+		t.next = (t.next + 1) % renderSamples
+	}
+	return reason
+}
+
+// median returns the median of the samples; the caller holds the lock
+func (t *renderTimes) median() time.Duration {
+	sorted := append([]time.Duration(nil), t.samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[len(sorted)/2]
+}
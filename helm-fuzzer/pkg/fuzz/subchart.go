@@ -0,0 +1,112 @@
+package fuzz
+
+import (
+	"fmt"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/strvals"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/runner"
+	"github.com/kasuboski/helm-fuzzer/pkg/schema"
+)
+
+// Subcharts lists the chart's dependencies by the names their values live
+// under in the chart's values: their aliases, or their chart names
+func Subcharts(chartPath string) ([]string, error) {
+	c, err := loader.Load(chartPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chart: %w", err)
+	}
+	var names []string
+	for _, dep := range c.Metadata.Dependencies {
+		names = append(names, subchartName(dep))
+	}
+	return names, nil
+}
+
+// subchartName is the key of a dependency's values in its parent's values
+func subchartName(dep *chart.Dependency) string {
+	if dep.Alias != "" {
+		return dep.Alias
+	}
+	return dep.Name
+}
+
+// isolate returns a schema covering only the values of the named dependency
+// and values enabling it through its condition and tags. The dependency's
+// own values schema describes its values if it has one; otherwise they
+// are inferred from its defaults with the parent's overrides applied.
+func isolate(engine *schema.Engine, chartPath, name string, defaults map[string]interface{}) (*schema.Schema, map[string]interface{}, error) {
+	c, err := loader.Load(chartPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load chart: %w", err)
+	}
+
+	var dep *chart.Dependency
+	for _, d := range c.Metadata.Dependencies {
+		if subchartName(d) == name {
+			dep = d
+		}
+	}
+	if dep == nil {
+		names, _ := Subcharts(chartPath)
+		return nil, nil, &ConfigError{fmt.Errorf("chart has no dependency %q (dependencies: %s)", name, strings.Join(names, ", "))}
+	}
+	var sub *chart.Chart
+	for _, d := range c.Dependencies() {
+		if d.Name() == dep.Name {
+			sub = d
+		}
+	}
+	if sub == nil {
+		return nil, nil, fmt.Errorf("dependency %s is missing from charts/; build it with helm dependency build or --dependency-update", name)
+	}
+
+	var subSchema *schema.Schema
+	if schemaFile, data := subchartSchema(sub); data != nil {
+		if subSchema, err = engine.ParseJSONSchema(data, name); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse %s of %s: %w", schemaFile, name, err)
+		}
+	} else {
+		overrides, _ := defaults[name].(map[string]interface{})
+		subSchema = engine.InferFromMap(runner.MergeValues(sub.Values, overrides), name)
+	}
+
+	// Helm checks the first condition path that is set, so setting the first
+	// enables the dependency; without a condition, any true tag does
+	enable := map[string]interface{}{}
+	condition, _, _ := strings.Cut(dep.Condition, ",")
+	if condition = strings.TrimSpace(condition); condition != "" {
+		if err := strvals.ParseInto(condition+"=true", enable); err != nil {
+			return nil, nil, fmt.Errorf("invalid condition of %s: %w", name, err)
+		}
+	} else if len(dep.Tags) > 0 {
+		if err := strvals.ParseInto("tags."+dep.Tags[0]+"=true", enable); err != nil {
+			return nil, nil, fmt.Errorf("invalid tag of %s: %w", name, err)
+		}
+	}
+
+	return &schema.Schema{
+		Type:       schema.TypeObject,
+		Properties: map[string]*schema.Schema{name: subSchema},
+	}, enable, nil
+}
+
+// subchartSchema returns the values schema of a loaded chart and the file it
+// is in, nil if it has none. Helm loads values.schema.json as the chart's
+// schema; its YAML forms are among the chart's other files.
+func subchartSchema(c *chart.Chart) (string, []byte) {
+	if len(c.Schema) > 0 {
+		return schema.SchemaFiles[0], c.Schema
+	}
+	for _, name := range schema.SchemaFiles[1:] {
+		for _, f := range c.Files {
+			if f.Name == name {
+				return name, f.Data
+			}
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,77 @@
+package fuzz
+
+import (
+	"math/rand"
+	"sort"
+
+	"helm.sh/helm/v3/pkg/chart"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/generator"
+)
+
+// maxEnumeratedTags is the most tags whose combinations are rendered in
+// turn; with more, each iteration turns each tag on or off at random
+const maxEnumeratedTags = 10
+
+// dependencyTags returns the tags gating the dependencies of c and of its
+// dependencies, sorted. Helm reads every chart's tags from the top-level
+// values' tags map, so one map switches them all.
+func dependencyTags(c *chart.Chart) []string {
+	seen := make(map[string]bool)
+	var walk func(c *chart.Chart)
+	walk = func(c *chart.Chart) {
+		for _, dep := range c.Metadata.Dependencies {
+			for _, tag := range dep.Tags {
+				seen[tag] = true
+			}
+		}
+		for _, dep := range c.Dependencies() {
+			walk(dep)
+		}
+	}
+	walk(c)
+
+	tags := make([]string, 0, len(seen))
+	for tag := range seen {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// drawTags returns the tags map an iteration renders with, nil unless
+// Options.TagCombinations found tags. Up to maxEnumeratedTags, iterations
+// render the combinations in turn, each against every Kubernetes version
+// the session rotates through before the next combination; beyond it,
+// combinations are drawn from the iteration's seed.
+func (s *Session) drawTags(iteration int) map[string]interface{} {
+	if len(s.tags) == 0 {
+		return nil
+	}
+	combination := uint64(iteration / len(s.cfg.KubeVersions))
+	if len(s.tags) > maxEnumeratedTags {
+		combination = rand.New(rand.NewSource(int64(generator.Seed(s.cfg.Seed, generator.StreamTags, iteration)))).Uint64()
+	}
+	tags := make(map[string]interface{}, len(s.tags))
+	for i, tag := range s.tags {
+		tags[tag] = combination>>i&1 == 1
+	}
+	return tags
+}
+
+// activeTags returns the session's tags as an input sets them, nil unless
+// Options.TagCombinations found tags; tags the input leaves unset, as a
+// pinned tags map might, are left out
+func (s *Session) activeTags(values map[string]interface{}) map[string]bool {
+	if len(s.tags) == 0 {
+		return nil
+	}
+	set, _ := values["tags"].(map[string]interface{})
+	active := make(map[string]bool, len(s.tags))
+	for _, tag := range s.tags {
+		if on, ok := set[tag].(bool); ok {
+			active[tag] = on
+		}
+	}
+	return active
+}
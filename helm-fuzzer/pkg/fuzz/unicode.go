@@ -0,0 +1,71 @@
+package fuzz
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maxLabelBytes is the most bytes Kubernetes allows in a label value, and
+// the length charts truncate names to
+const maxLabelBytes = 63
+
+// checkUnicode returns a crash reason for each document of the manifest with
+// invalid UTF-8, as a helper truncating a multi-byte character in half with
+// trunc renders, and for each name or label value of 63 characters or fewer
+// but more than 63 bytes, as a helper counting characters rather than bytes
+// renders. Like budget reasons, the reasons name where the text rendered
+// rather than the text, so every render miscounting in one place shares a
+// fingerprint.
+func checkUnicode(manifest string) []string {
+	var reasons []string
+	seen := make(map[string]bool)
+	add := func(reason string) {
+		if !seen[reason] {
+			seen[reason] = true
+			reasons = append(reasons, reason)
+		}
+	}
+
+	// Documents with invalid UTF-8 do not parse, so the rest are walked alone
+	var valid strings.Builder
+	for _, doc := range sourcedDocuments(manifest) {
+		if utf8.ValidString(doc.text) {
+			valid.WriteString("---\n" + doc.text)
+			continue
+		}
+		add(fmt.Sprintf("Unicode: invalid UTF-8 renders at %s in %s", invalidKey(doc.text), doc.source))
+	}
+	renderedScalars(valid.String(), func(path, at string, n *yaml.Node) {
+		if isNameOrLabel(path) && len(n.Value) > maxLabelBytes && utf8.RuneCountInString(n.Value) <= maxLabelBytes {
+			add(fmt.Sprintf("Unicode: %d characters or fewer render over %d bytes at %s", maxLabelBytes, maxLabelBytes, at))
+		}
+	})
+	return reasons
+}
+
+// invalidKey returns the key of the first line of text with invalid UTF-8,
+// or "a line" if the line has none
+func invalidKey(text string) string {
+	for _, line := range strings.Split(text, "\n") {
+		if utf8.ValidString(line) {
+			continue
+		}
+		key, _, found := strings.Cut(strings.TrimLeft(line, " -"), ":")
+		if !found || !utf8.ValidString(key) || key == "" {
+			return "a line"
+		}
+		return key
+	}
+	return "a line"
+}
+
+// isNameOrLabel reports whether a scalar's path is an object's name or a
+// label value, its own or its pod template's or a selector's
+func isNameOrLabel(path string) bool {
+	return path == "metadata.name" || strings.HasSuffix(path, ".metadata.name") ||
+		strings.Contains(path, "labels.") || strings.Contains(path, "matchLabels.") ||
+		strings.HasPrefix(path, "spec.selector.")
+}
@@ -0,0 +1,38 @@
+package fuzz
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/envtest"
+	"github.com/kasuboski/helm-fuzzer/pkg/runner"
+)
+
+// installDefaults installs the render of the chart's defaults, with pinned
+// values applied, on each Kubernetes version's API server and returns the
+// releases iterations upgrade. A version whose default render fails has no
+// release, so its iterations are not checked as upgrades.
+func (s *Session) installDefaults(ctx context.Context, validators map[string]*envtest.Validator) (map[string]*envtest.Release, error) {
+	namespace := s.opts.Namespace
+	if namespace == "" {
+		namespace = runner.DefaultNamespace
+	}
+	releases := make(map[string]*envtest.Release, len(validators))
+	for _, kubeVersion := range s.cfg.KubeVersions {
+		r, err := s.newRunner(kubeVersion, s.cfg.Lookup, usualFiles(s.cfg.Files), s.logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create runner: %w", err)
+		}
+		res := r.Run(runner.MergeValues(map[string]interface{}{}, s.opts.Values))
+		if res.Error != nil {
+			s.logger.Debug("default render fails, not checking upgrades", "kubeVersion", kubeVersion, "error", res.Error)
+			continue
+		}
+		release, err := validators[kubeVersion].Install(ctx, res.Manifest, namespace)
+		if err != nil {
+			return nil, err
+		}
+		releases[kubeVersion] = release
+	}
+	return releases, nil
+}
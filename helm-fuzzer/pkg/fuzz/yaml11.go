@@ -0,0 +1,194 @@
+package fuzz
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yaml11Types are the YAML 1.1 type repository's patterns for plain scalars,
+// in the order they are resolved; a plain scalar matching none is a string
+var yaml11Types = []struct {
+	name    string
+	pattern *regexp.Regexp
+}{
+	{"a boolean", regexp.MustCompile(`^(?:y|Y|yes|Yes|YES|n|N|no|No|NO|true|True|TRUE|false|False|FALSE|on|On|ON|off|Off|OFF)$`)},
+	{"null", regexp.MustCompile(`^(?:~|null|Null|NULL)$`)},
+	{"an integer", regexp.MustCompile(`^[-+]?(?:0b[01_]+|0[0-7_]+|0|[1-9][0-9_]*|0x[0-9a-fA-F_]+|[1-9][0-9_]*(?::[0-5]?[0-9])+)$`)},
+	{"a float", regexp.MustCompile(`^(?:[-+]?(?:[0-9][0-9_]*)?\.[0-9.]*(?:[eE][-+][0-9]+)?|[-+]?[0-9][0-9_]*(?::[0-5]?[0-9])+\.[0-9_]*|[-+]?\.(?:inf|Inf|INF)|\.(?:nan|NaN|NAN))$`)},
+	{"a timestamp", regexp.MustCompile(`^[0-9]{4}-[0-9]{1,2}-[0-9]{1,2}(?:(?:[Tt]|[ \t]+)[0-9]{1,2}:[0-9]{2}:[0-9]{2}(?:\.[0-9]*)?(?:[ \t]*(?:Z|[-+][0-9]{1,2}(?::[0-9]{2})?))?)?$`)},
+}
+
+// yaml12Types name the types of YAML 1.2 core schema tags
+var yaml12Types = map[string]string{
+	"!!bool":      "a boolean",
+	"!!null":      "null",
+	"!!int":       "an integer",
+	"!!float":     "a float",
+	"!!timestamp": "a timestamp",
+}
+
+// yaml11Type returns the type YAML 1.1 reads a plain scalar as, or an empty
+// string for a string. The empty scalar, null in YAML 1.1, is left out:
+// templates render it for unset values all the time.
+func yaml11Type(s string) string {
+	for _, t := range yaml11Types {
+		if s != "" && t.pattern.MatchString(s) {
+			return t.name
+		}
+	}
+	return ""
+}
+
+// yaml12Type returns the type YAML 1.2 reads a plain scalar as, as Helm's
+// own parser does, or an empty string for a string
+func yaml12Type(s string) string {
+	if s == "" {
+		return ""
+	}
+	return yaml12Types[(&yaml.Node{Kind: yaml.ScalarNode, Value: s}).ShortTag()]
+}
+
+// checkYAML11 returns a crash reason for each string of the values that
+// renders as a plain scalar which YAML 1.1 or 1.2 parsers read as another
+// type: "no" rendered unquoted is false to kubectl, 1:30 is 90 and 0777 is
+// 511. Only strings of the values are looked for, so the chart's own
+// booleans and numbers are not flagged. Like budget reasons, the reasons
+// name where the string rendered rather than the string, once quoted
+// strings are normalized, so every quirk rendered in one place shares a
+// fingerprint.
+func checkYAML11(manifest string, values map[string]interface{}) []string {
+	quirks := make(map[string]string)
+	collectQuirks(values, quirks)
+	if len(quirks) == 0 {
+		return nil
+	}
+
+	var reasons []string
+	seen := make(map[string]bool)
+	renderedScalars(manifest, func(_, at string, n *yaml.Node) {
+		// Quoted, block and explicitly tagged scalars keep their type
+		if n.Style != 0 {
+			return
+		}
+		parser, ok := quirks[n.Value]
+		if !ok {
+			return
+		}
+		reason := fmt.Sprintf("YAML quirk: the string %q renders unquoted at %s, which %s", n.Value, at, parser)
+		if !seen[reason] {
+			seen[reason] = true
+			reasons = append(reasons, reason)
+		}
+	})
+	return reasons
+}
+
+// collectQuirks maps each string leaf of value that YAML reads as another
+// type when unquoted to which parsers do and what as
+func collectQuirks(value interface{}, quirks map[string]string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, child := range v {
+			collectQuirks(child, quirks)
+		}
+	case []interface{}:
+		for _, child := range v {
+			collectQuirks(child, quirks)
+		}
+	case string:
+		if t := yaml11Type(v); t != "" {
+			quirks[v] = "YAML 1.1 parsers read as " + t
+		} else if t := yaml12Type(v); t != "" {
+			quirks[v] = "YAML 1.2 parsers read as " + t
+		}
+	}
+}
+
+// renderedScalars calls fn with each scalar value of the manifest's
+// documents that parse, its path, and where it is for reasons, such as
+// "data.enabled in mychart/templates/configmap.yaml (ConfigMap)"
+func renderedScalars(manifest string, fn func(path, at string, n *yaml.Node)) {
+	for _, doc := range sourcedDocuments(manifest) {
+		var root yaml.Node
+		if err := yaml.Unmarshal([]byte(doc.text), &root); err != nil || len(root.Content) == 0 {
+			continue
+		}
+		kind := "object"
+		if k := mappingValue(root.Content[0], "kind"); k != nil && k.Kind == yaml.ScalarNode {
+			kind = k.Value
+		}
+		walkScalars(root.Content[0], "", func(path string, n *yaml.Node) {
+			fn(path, fmt.Sprintf("%s in %s (%s)", path, doc.source, kind), n)
+		})
+	}
+}
+
+// walkScalars calls fn with each scalar value below n and its path, keys
+// joined with dots and list items marked with []
+func walkScalars(n *yaml.Node, path string, fn func(path string, n *yaml.Node)) {
+	switch n.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key := n.Content[i].Value
+			if path != "" {
+				key = path + "." + key
+			}
+			walkScalars(n.Content[i+1], key, fn)
+		}
+	case yaml.SequenceNode:
+		for _, item := range n.Content {
+			walkScalars(item, path+"[]", fn)
+		}
+	case yaml.ScalarNode:
+		if path != "" {
+			fn(path, n)
+		}
+	}
+}
+
+// mappingValue returns the value of key in a mapping node, nil if it has none
+func mappingValue(n *yaml.Node, key string) *yaml.Node {
+	if n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// sourcedDocument is a document of a manifest and the template it came from
+type sourcedDocument struct {
+	source string
+	text   string
+}
+
+// sourcedDocuments splits a manifest into its documents, each named by its
+// Source comment, or "the manifest" without one
+func sourcedDocuments(manifest string) []sourcedDocument {
+	var docs []sourcedDocument
+	current := sourcedDocument{source: "the manifest"}
+	flush := func() {
+		if strings.TrimSpace(current.text) != "" {
+			docs = append(docs, current)
+		}
+		current = sourcedDocument{source: "the manifest"}
+	}
+	for _, line := range strings.Split(manifest, "\n") {
+		if line == "---" {
+			flush()
+			continue
+		}
+		if name, ok := strings.CutPrefix(line, "# Source: "); ok {
+			current.source = strings.TrimSpace(name)
+		}
+		current.text += line + "\n"
+	}
+	flush()
+	return docs
+}
@@ -2,47 +2,91 @@ package generator
 
 import (
 	"fmt"
+	"log/slog"
 	"regexp"
+	"sort"
 	"strings"
+	"unicode/utf8"
 
 	"pgregory.net/rapid"
 
+	"github.com/kasuboski/helm-fuzzer/pkg/logging"
 	"github.com/kasuboski/helm-fuzzer/pkg/schema"
 )
 
 // Generator generates random values based on a schema
 type Generator struct {
-	schema   *schema.Schema
-	maxDepth int
+	schema       *schema.Schema
+	maxDepth     int
+	maxTotalSize int
+	maxKeys      int
+	logger       *slog.Logger
+}
+
+// Options configures the limits applied to generated values
+type Options struct {
+	// MaxDepth limits nesting depth of generated values
+	MaxDepth int
+	// MaxTotalValuesSize caps the YAML-encoded size of a generated document in bytes (0 = unlimited)
+	MaxTotalValuesSize int
+	// MaxKeysPerObject caps the number of properties generated per object (0 = unlimited)
+	MaxKeysPerObject int
+	// Logger receives debug logging; nil disables logging
+	Logger *slog.Logger
 }
 
 // New creates a new generator for the given schema
 func New(s *schema.Schema, maxDepth int) *Generator {
+	return NewWithOptions(s, Options{MaxDepth: maxDepth})
+}
+
+// NewWithOptions creates a new generator with size and shape limits
+func NewWithOptions(s *schema.Schema, opts Options) *Generator {
 	return &Generator{
-		schema:   s,
-		maxDepth: maxDepth,
+		schema:       s,
+		maxDepth:     opts.MaxDepth,
+		maxTotalSize: opts.MaxTotalValuesSize,
+		maxKeys:      opts.MaxKeysPerObject,
+		logger:       logging.OrDiscard(opts.Logger),
 	}
 }
 
 // Generate returns a rapid generator for map[string]interface{}
 func (g *Generator) Generate() *rapid.Generator[map[string]interface{}] {
 	return rapid.Custom(func(t *rapid.T) map[string]interface{} {
-		return g.generateValue(t, g.schema, 0).(map[string]interface{})
+		values := g.generateValue(t, g.schema, 0).(map[string]interface{})
+		if dropped := trimToSize(values, g.schema, g.maxTotalSize); dropped > 0 {
+			g.logger.Debug("trimmed generated values to size limit", "dropped", dropped, "maxTotalValuesSize", g.maxTotalSize)
+		}
+		return values
 	})
 }
 
 // generateValue generates a value based on schema and current depth
 func (g *Generator) generateValue(t *rapid.T, s *schema.Schema, depth int) interface{} {
-	// Prevent deep recursion
-	if depth >= g.maxDepth {
+	// Prevent deep recursion; ignored paths always keep their default
+	if depth >= g.maxDepth || s.Ignored {
 		return g.generateDefault(s)
 	}
 
-	// If there's a default value and randomly use it
-	if s.Default != nil && rapid.Bool().Draw(t, "use_default") {
+	// Risky paths keep their default a quarter of the time rather than half,
+	// and maps templates read from unchecked are sometimes unset with null
+	if s.Risk > 0 {
+		if s.Nilable && rapid.IntRange(0, 3).Draw(t, "risky_null") == 0 {
+			return nil
+		}
+		if s.Default != nil && rapid.IntRange(0, 3).Draw(t, "risky_default") == 0 {
+			return s.Default
+		}
+	} else if s.Default != nil && rapid.Bool().Draw(t, "use_default") {
 		return s.Default
 	}
 
+	// Templates take precedence since they describe the whole value
+	if s.Template != "" {
+		return g.generateFromTemplate(t, s)
+	}
+
 	// Handle enum values first
 	if len(s.Enum) > 0 {
 		idx := rapid.IntRange(0, len(s.Enum)-1).Draw(t, "enum_idx")
@@ -109,12 +153,38 @@ func (g *Generator) generateString(t *rapid.T, s *schema.Schema) string {
 		minLen = maxLen
 	}
 
+	// Strings YAML 1.1 reads as other types are drawn an eighth of the time,
+	// to find templates rendering them unquoted
+	var quirks []string
+	for _, quirk := range yaml11Strings {
+		if len(quirk) >= minLen && len(quirk) <= maxLen {
+			quirks = append(quirks, quirk)
+		}
+	}
+	if len(quirks) > 0 && rapid.IntRange(0, 7).Draw(t, "yaml11") == 0 {
+		return rapid.SampledFrom(quirks).Draw(t, "yaml11_string")
+	}
+
+	// Names straddling 63 bytes with multi-byte characters are drawn an
+	// eighth of the time too, where the length bounds allow them, to find
+	// truncating helpers that miscount them
+	if minLen <= 57 && maxLen >= 78 && rapid.IntRange(0, 7).Draw(t, "unicode") == 0 {
+		return nearLimitUnicode().Draw(t, "unicode_string")
+	}
+
 	length := rapid.IntRange(minLen, maxLen).Draw(t, "string_length")
 	// Use maxLen for both rune count and byte length to ensure we don't exceed byte limit
 	str := rapid.StringN(length, length, maxLen).Draw(t, "string")
 
 	// Sanitize the string to remove YAML control characters
-	return sanitizeYAMLString(str)
+	str = sanitizeYAMLString(str)
+	// Sanitizing may drop characters, so pad back up to the minimum length,
+	// which counts characters; each dropped one freed at least the byte its
+	// padding takes, so the byte limit still holds
+	if n := utf8.RuneCountInString(str); n < minLen {
+		str += strings.Repeat("a", minLen-n)
+	}
+	return str
 }
 
 // generateInteger generates a random integer
@@ -134,6 +204,12 @@ func (g *Generator) generateInteger(t *rapid.T, s *schema.Schema) int {
 		min = max
 	}
 
+	// Integers beyond 2^53 are drawn an eighth of the time, to find
+	// templates rendering them through floats
+	if large := explicitlyBounded(largeIntegers, s); len(large) > 0 && rapid.IntRange(0, 7).Draw(t, "large_int") == 0 {
+		return rapid.SampledFrom(large).Draw(t, "large_int_value")
+	}
+
 	return rapid.IntRange(min, max).Draw(t, "int")
 }
 
@@ -154,6 +230,12 @@ func (g *Generator) generateNumber(t *rapid.T, s *schema.Schema) float64 {
 		min = max
 	}
 
+	// Floats Go formats in scientific notation are drawn an eighth of the
+	// time, to find templates rendering them that way
+	if scientific := explicitlyBounded(scientificNumbers, s); len(scientific) > 0 && rapid.IntRange(0, 7).Draw(t, "scientific") == 0 {
+		return rapid.SampledFrom(scientific).Draw(t, "scientific_value")
+	}
+
 	return rapid.Float64Range(min, max).Draw(t, "float")
 }
 
@@ -165,7 +247,16 @@ func (g *Generator) generateObject(t *rapid.T, s *schema.Schema, depth int) map[
 		return result
 	}
 
-	for propName, propSchema := range s.Properties {
+	// Visit properties in a stable order so draws are reproducible
+	propNames := make([]string, 0, len(s.Properties))
+	for propName := range s.Properties {
+		propNames = append(propNames, propName)
+	}
+	sort.Strings(propNames)
+
+	for _, propName := range propNames {
+		propSchema := s.Properties[propName]
+
 		// Check if property is required
 		isRequired := false
 		for _, req := range s.Required {
@@ -175,8 +266,17 @@ func (g *Generator) generateObject(t *rapid.T, s *schema.Schema, depth int) map[
 			}
 		}
 
-		// If not required, randomly omit it (50% chance)
-		if !isRequired && rapid.Bool().Draw(t, fmt.Sprintf("include_%s", propName)) {
+		// If not required, randomly omit it (50% chance, 25% when risky)
+		if !isRequired && propSchema.Risk > 0 {
+			if rapid.IntRange(0, 3).Draw(t, fmt.Sprintf("include_risky_%s", propName)) == 0 {
+				continue
+			}
+		} else if !isRequired && rapid.Bool().Draw(t, fmt.Sprintf("include_%s", propName)) {
+			continue
+		}
+
+		// Once the key cap is reached only required properties are added
+		if !isRequired && g.maxKeys > 0 && len(result) >= g.maxKeys {
 			continue
 		}
 
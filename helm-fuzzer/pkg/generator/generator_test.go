@@ -1,7 +1,11 @@
 package generator
 
 import (
+	"fmt"
+	"reflect"
+	"strings"
 	"testing"
+	"unicode/utf8"
 
 	"pgregory.net/rapid"
 
@@ -55,6 +59,65 @@ func TestGenerateStringWithConstraints(t *testing.T) {
 	})
 }
 
+func TestGenerateStringMinLengthCountsCharacters(t *testing.T) {
+	minLen := 5
+	maxLen := 20
+
+	sch := &schema.Schema{
+		Type:      schema.TypeString,
+		MinLength: &minLen,
+		MaxLength: &maxLen,
+	}
+
+	gen := New(sch, 5)
+
+	rapid.Check(t, func(t *rapid.T) {
+		str := gen.generateValue(t, sch, 0).(string)
+
+		// Multi-byte characters left after sanitizing count once
+		if n := utf8.RuneCountInString(str); n < minLen {
+			t.Errorf("string %q has %d characters, less than min %d", str, n, minLen)
+		}
+		if len(str) > maxLen {
+			t.Errorf("string length %d is greater than max %d", len(str), maxLen)
+		}
+	})
+}
+
+func TestGenerateStringYAML11(t *testing.T) {
+	sch := &schema.Schema{Type: schema.TypeString}
+	gen := New(sch, 5)
+
+	draw := rapid.Custom(func(t *rapid.T) string {
+		return gen.generateString(t, sch)
+	})
+	quirks := map[string]bool{}
+	for _, quirk := range yaml11Strings {
+		quirks[quirk] = true
+	}
+	drawn := 0
+	for i := 0; i < 400; i++ {
+		if quirks[draw.Example(i)] {
+			drawn++
+		}
+	}
+	if drawn == 0 {
+		t.Error("expected strings YAML 1.1 reads as other types to be drawn sometimes")
+	}
+}
+
+func TestNearLimitUnicode(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		s := nearLimitUnicode().Draw(t, "s")
+		if !utf8.ValidString(s) {
+			t.Fatalf("expected valid UTF-8, got %q", s)
+		}
+		if runes := utf8.RuneCountInString(s); runes > 66 || len(s) <= 56 || runes == len(s) {
+			t.Fatalf("expected multi-byte characters around 63 bytes, got %q", s)
+		}
+	})
+}
+
 func TestGenerateInteger(t *testing.T) {
 	sch := &schema.Schema{
 		Type: schema.TypeInteger,
@@ -102,6 +165,32 @@ func TestGenerateIntegerWithConstraints(t *testing.T) {
 	})
 }
 
+func TestGenerateLargeNumbers(t *testing.T) {
+	unbounded := &schema.Schema{Type: schema.TypeInteger}
+	gen := New(unbounded, 5)
+	draw := rapid.Custom(func(t *rapid.T) int {
+		return gen.generateInteger(t, unbounded)
+	})
+	large := 0
+	for i := 0; i < 400; i++ {
+		if n := draw.Example(i); n > 1<<53 || n < -(1<<53) {
+			large++
+		}
+	}
+	if large == 0 {
+		t.Error("expected integers beyond 2^53 to be drawn sometimes")
+	}
+
+	// A maximum keeps them out, as it does any integer over it
+	max := 100.0
+	bounded := &schema.Schema{Type: schema.TypeInteger, Maximum: &max}
+	rapid.Check(t, func(t *rapid.T) {
+		if n := gen.generateInteger(t, bounded); n > 100 {
+			t.Fatalf("expected at most 100, got %d", n)
+		}
+	})
+}
+
 func TestGenerateBoolean(t *testing.T) {
 	sch := &schema.Schema{
 		Type: schema.TypeBoolean,
@@ -211,6 +300,61 @@ func TestGenerateEnum(t *testing.T) {
 	})
 }
 
+func TestGenerateIgnored(t *testing.T) {
+	sch := &schema.Schema{
+		Type:    schema.TypeInteger,
+		Default: 3,
+		Ignored: true,
+	}
+
+	gen := New(sch, 5)
+
+	rapid.Check(t, func(t *rapid.T) {
+		if value := gen.generateValue(t, sch, 0); value != 3 {
+			t.Fatalf("expected ignored path to keep its default 3, got %v", value)
+		}
+	})
+}
+
+func TestGenerateRisky(t *testing.T) {
+	object := func(risk int, nilable bool) *schema.Schema {
+		return &schema.Schema{
+			Type:       schema.TypeObject,
+			Properties: map[string]*schema.Schema{"key": {Type: schema.TypeString}},
+			Default:    map[string]interface{}{"key": "value"},
+			Risk:       risk,
+			Nilable:    nilable,
+		}
+	}
+	sch := &schema.Schema{
+		Type:       schema.TypeObject,
+		Properties: map[string]*schema.Schema{"safe": object(0, false), "risky": object(2, true)},
+	}
+
+	gen := New(sch, 5)
+	varied := map[string]int{}
+	nulls := 0
+	for i := 0; i < 400; i++ {
+		values := gen.Generate().Example(i)
+		for name, value := range values {
+			if value == nil {
+				nulls++
+			} else if !reflect.DeepEqual(value, sch.Properties[name].Default) {
+				varied[name]++
+			}
+		}
+		if _, ok := values["safe"]; ok && values["safe"] == nil {
+			t.Fatal("expected a path that is not nilable never to be null")
+		}
+	}
+	if varied["risky"] <= varied["safe"] {
+		t.Errorf("expected the risky path varied more often, got %v", varied)
+	}
+	if nulls == 0 {
+		t.Error("expected the nilable path to be null sometimes")
+	}
+}
+
 func TestGenerateWithDepthLimit(t *testing.T) {
 	// Create deeply nested schema
 	sch := &schema.Schema{
@@ -266,3 +410,223 @@ func TestGenerateRequiredFields(t *testing.T) {
 		}
 	})
 }
+
+func TestMutate(t *testing.T) {
+	sch := &schema.Schema{
+		Type: schema.TypeObject,
+		Properties: map[string]*schema.Schema{
+			"name": {Type: schema.TypeString},
+			"service": {
+				Type: schema.TypeObject,
+				Properties: map[string]*schema.Schema{
+					"port": {Type: schema.TypeInteger},
+				},
+				Required: []string{"port"},
+			},
+		},
+		Required: []string{"service"},
+	}
+	gen := New(sch, 5)
+	base := map[string]interface{}{
+		"name":    "app",
+		"service": map[string]interface{}{"port": 80},
+	}
+
+	changed := false
+	rapid.Check(t, func(t *rapid.T) {
+		values := gen.Mutate(base).Draw(t, "values")
+
+		// Required properties are regenerated, never removed
+		service, ok := values["service"].(map[string]interface{})
+		if !ok {
+			t.Fatalf("expected service to be kept, got %v", values)
+		}
+		if _, ok := service["port"]; !ok {
+			t.Fatalf("expected service.port to be kept, got %v", values)
+		}
+		if !reflect.DeepEqual(values, base) {
+			changed = true
+		}
+	})
+	if !changed {
+		t.Error("expected mutations to change the input")
+	}
+	if base["name"] != "app" || base["service"].(map[string]interface{})["port"] != 80 {
+		t.Errorf("expected the base input to be left alone, got %v", base)
+	}
+}
+
+func TestGenerateMaxKeysPerObject(t *testing.T) {
+	sch := &schema.Schema{
+		Type: schema.TypeObject,
+		Properties: map[string]*schema.Schema{
+			"a":        {Type: schema.TypeString},
+			"b":        {Type: schema.TypeString},
+			"c":        {Type: schema.TypeString},
+			"d":        {Type: schema.TypeString},
+			"required": {Type: schema.TypeString},
+		},
+		Required: []string{"required"},
+	}
+
+	gen := NewWithOptions(sch, Options{MaxDepth: 5, MaxKeysPerObject: 2})
+
+	rapid.Check(t, func(t *rapid.T) {
+		obj := gen.generateValue(t, sch, 0).(map[string]interface{})
+
+		// The cap may be exceeded only by required properties
+		if len(obj) > 3 {
+			t.Fatalf("expected at most 3 keys, got %d: %v", len(obj), obj)
+		}
+		if _, exists := obj["required"]; !exists {
+			t.Fatalf("required field was not present")
+		}
+	})
+}
+
+func TestTrimToSize(t *testing.T) {
+	values := map[string]interface{}{
+		"small": "x",
+		"large": strings.Repeat("y", 200),
+	}
+
+	if dropped := trimToSize(values, nil, 50); dropped != 1 {
+		t.Errorf("expected 1 key dropped, got %d", dropped)
+	}
+	if _, exists := values["large"]; exists {
+		t.Errorf("expected largest key to be dropped")
+	}
+	if _, exists := values["small"]; !exists {
+		t.Errorf("expected small key to be kept")
+	}
+
+	// Zero disables the limit
+	untouched := map[string]interface{}{"large": strings.Repeat("y", 200)}
+	if trimToSize(untouched, nil, 0) != 0 || len(untouched) != 1 {
+		t.Errorf("expected no trimming with a zero limit")
+	}
+}
+
+func TestTrimToSize_KeepsRequired(t *testing.T) {
+	sch := &schema.Schema{
+		Type: schema.TypeObject,
+		Properties: map[string]*schema.Schema{
+			"name": {Type: schema.TypeString},
+			"service": {
+				Type: schema.TypeObject,
+				Properties: map[string]*schema.Schema{
+					"port":        {Type: schema.TypeInteger},
+					"annotations": {Type: schema.TypeObject},
+				},
+				Required: []string{"port"},
+			},
+			"extra": {Type: schema.TypeString},
+		},
+		Required: []string{"name", "service"},
+	}
+	values := map[string]interface{}{
+		"name": strings.Repeat("n", 100),
+		"service": map[string]interface{}{
+			"port":        80,
+			"annotations": map[string]interface{}{"note": strings.Repeat("a", 100)},
+		},
+		"extra": "x",
+	}
+
+	// The optional top-level key goes first, then the optional key of the
+	// required object; the required name is kept though it is the largest
+	trimToSize(values, sch, 140)
+
+	if _, exists := values["extra"]; exists {
+		t.Errorf("expected the optional key to be dropped")
+	}
+	service := values["service"].(map[string]interface{})
+	if _, exists := service["annotations"]; exists {
+		t.Errorf("expected the optional nested key to be dropped")
+	}
+	if _, exists := values["name"]; !exists {
+		t.Errorf("expected the required key to be kept")
+	}
+	if _, exists := service["port"]; !exists {
+		t.Errorf("expected the required nested key to be kept")
+	}
+
+	// Required properties stay even when they alone exceed the limit
+	trimToSize(values, sch, 10)
+	if _, exists := values["name"]; !exists {
+		t.Errorf("expected the required key to be kept over the limit")
+	}
+}
+
+func TestGenerateFromTemplate(t *testing.T) {
+	sch := &schema.Schema{
+		Type:     schema.TypeString,
+		Template: "https://{{ hostname }}:{{ port }}/",
+	}
+
+	gen := New(sch, 5)
+
+	rapid.Check(t, func(t *rapid.T) {
+		value := gen.generateValue(t, sch, 0)
+
+		str, ok := value.(string)
+		if !ok {
+			t.Fatalf("expected string, got %T", value)
+		}
+		if !strings.HasPrefix(str, "https://") || !strings.Contains(str, ".example.com:") {
+			t.Errorf("unexpected templated value %q", str)
+		}
+	})
+}
+
+func TestGenerateFromTemplateTyped(t *testing.T) {
+	sch := &schema.Schema{
+		Type:     schema.TypeInteger,
+		Template: "{{ int 1 3 }}",
+	}
+
+	gen := New(sch, 5)
+
+	rapid.Check(t, func(t *rapid.T) {
+		value := gen.generateValue(t, sch, 0)
+
+		num, ok := value.(int)
+		if !ok {
+			t.Fatalf("expected int, got %T", value)
+		}
+		if num < 1 || num > 3 {
+			t.Errorf("value %d out of range", num)
+		}
+	})
+}
+
+func TestValidateTemplate(t *testing.T) {
+	if err := ValidateTemplate("{{ hostname }}"); err != nil {
+		t.Errorf("expected valid template, got: %v", err)
+	}
+	if err := ValidateTemplate("{{ unknownFunc }}"); err == nil {
+		t.Error("expected error for unknown function")
+	}
+}
+
+func TestSeed(t *testing.T) {
+	if got := Seed(0, StreamLookup, 42); got != 42 {
+		t.Errorf("Seed(0, lookup, 42) = %d, want the iteration index", got)
+	}
+	if Seed(7, StreamValues, 3) != Seed(7, StreamValues, 3) {
+		t.Error("expected the same seed for the same session, stream and iteration")
+	}
+
+	seen := make(map[int]string)
+	for _, session := range []uint64{1, 2} {
+		for _, stream := range []string{StreamValues, StreamLookup, StreamFiles, StreamBuiltIns, StreamEvolve, StreamTags} {
+			for i := 0; i < 100; i++ {
+				seed := Seed(session, stream, i)
+				if other, ok := seen[seed]; ok {
+					t.Fatalf("session %d, %s iteration %d has the seed of %s", session, stream, i, other)
+				}
+				seen[seed] = fmt.Sprintf("session %d, %s iteration %d", session, stream, i)
+			}
+		}
+	}
+}
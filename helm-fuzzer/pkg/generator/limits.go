@@ -0,0 +1,103 @@
+package generator
+
+import (
+	"sort"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/schema"
+)
+
+// trimToSize drops optional keys, largest first, until the YAML encoding of
+// values fits within maxBytes, and returns how many it dropped. Properties s
+// requires are never dropped: once an object's optional keys are gone, the
+// objects its required properties hold are trimmed in turn, so a document
+// whose required properties alone exceed the limit is left over it. A
+// maxBytes of zero or less disables the limit.
+func trimToSize(values map[string]interface{}, s *schema.Schema, maxBytes int) int {
+	if maxBytes <= 0 {
+		return 0
+	}
+	dropped := 0
+	trimObject(values, s, func() bool { return encodedSize(values) <= maxBytes }, &dropped)
+	return dropped
+}
+
+// trimObject drops the optional keys of obj, then trims the objects held by
+// its required ones, until fits reports the whole document fits
+func trimObject(obj map[string]interface{}, s *schema.Schema, fits func() bool, dropped *int) bool {
+	if fits() {
+		return true
+	}
+
+	var optional, required []string
+	for key := range obj {
+		if isRequired(s, key) {
+			required = append(required, key)
+		} else {
+			optional = append(optional, key)
+		}
+	}
+	sortBySize(obj, optional)
+	sortBySize(obj, required)
+
+	for _, key := range optional {
+		delete(obj, key)
+		*dropped++
+		if fits() {
+			return true
+		}
+	}
+	for _, key := range required {
+		child, ok := obj[key].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		var prop *schema.Schema
+		if s != nil {
+			prop = s.Properties[key]
+		}
+		if trimObject(child, prop, fits, dropped) {
+			return true
+		}
+	}
+	return false
+}
+
+// sortBySize orders keys of obj by the encoded size of their entries,
+// largest first
+func sortBySize(obj map[string]interface{}, keys []string) {
+	sizes := make(map[string]int, len(keys))
+	for _, key := range keys {
+		sizes[key] = encodedSize(map[string]interface{}{key: obj[key]})
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if sizes[keys[i]] != sizes[keys[j]] {
+			return sizes[keys[i]] > sizes[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+}
+
+// isRequired reports whether s requires the named property; nothing is
+// required of values without a schema
+func isRequired(s *schema.Schema, name string) bool {
+	if s == nil {
+		return false
+	}
+	for _, req := range s.Required {
+		if req == name {
+			return true
+		}
+	}
+	return false
+}
+
+// encodedSize returns the size in bytes of the YAML encoding of a value
+func encodedSize(value interface{}) int {
+	data, err := yaml.Marshal(value)
+	if err != nil {
+		return 0
+	}
+	return len(data)
+}
@@ -0,0 +1,124 @@
+package generator
+
+import (
+	"sort"
+
+	"pgregory.net/rapid"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/schema"
+)
+
+// mutationTarget is a schema property a mutation can regenerate or remove
+type mutationTarget struct {
+	path     []string
+	schema   *schema.Schema
+	required bool
+}
+
+// Mutate returns a rapid generator of variations of base. Each variation
+// regenerates or removes up to three schema properties, favouring risky ones
+// (see schema.Schema.Risk), and keeps the rest of base, so inputs that reached
+// interesting templates can be explored further. base is not modified.
+func (g *Generator) Mutate(base map[string]interface{}) *rapid.Generator[map[string]interface{}] {
+	var targets []mutationTarget
+	g.mutationTargets(nil, g.schema, &targets)
+	if len(targets) == 0 {
+		return g.Generate()
+	}
+
+	return rapid.Custom(func(t *rapid.T) map[string]interface{} {
+		values := copyValue(base).(map[string]interface{})
+		mutations := rapid.IntRange(1, 3).Draw(t, "mutations")
+		for i := 0; i < mutations; i++ {
+			target := rapid.SampledFrom(targets).Draw(t, "target")
+			if !target.required && rapid.IntRange(0, 3).Draw(t, "remove") == 0 {
+				removePath(values, target.path)
+				continue
+			}
+			setPath(values, target.path, g.generateValue(t, target.schema, len(target.path)))
+		}
+		trimToSize(values, g.schema, g.maxTotalSize)
+		return values
+	})
+}
+
+// mutationTargets collects the properties below s within the depth limit,
+// in a stable order so mutations are reproducible
+func (g *Generator) mutationTargets(path []string, s *schema.Schema, targets *[]mutationTarget) {
+	if s == nil || len(path) >= g.maxDepth {
+		return
+	}
+
+	names := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		prop := s.Properties[name]
+		if prop.Ignored {
+			continue
+		}
+		propPath := append(append([]string{}, path...), name)
+		required := false
+		for _, req := range s.Required {
+			if req == name {
+				required = true
+				break
+			}
+		}
+		// Risky properties are listed up to three more times, so they are
+		// picked for mutation more often
+		target := mutationTarget{path: propPath, schema: prop, required: required}
+		for i := 0; i <= min(prop.Risk, 3); i++ {
+			*targets = append(*targets, target)
+		}
+		g.mutationTargets(propPath, prop, targets)
+	}
+}
+
+// setPath sets the value at path, replacing anything in the way that is not an object
+func setPath(values map[string]interface{}, path []string, value interface{}) {
+	for _, key := range path[:len(path)-1] {
+		next, ok := values[key].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			values[key] = next
+		}
+		values = next
+	}
+	values[path[len(path)-1]] = value
+}
+
+// removePath deletes the value at path, if present
+func removePath(values map[string]interface{}, path []string) {
+	for _, key := range path[:len(path)-1] {
+		next, ok := values[key].(map[string]interface{})
+		if !ok {
+			return
+		}
+		values = next
+	}
+	delete(values, path[len(path)-1])
+}
+
+// copyValue deep-copies maps and slices so mutations never touch the original
+func copyValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, child := range v {
+			out[key] = copyValue(child)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, child := range v {
+			out[i] = copyValue(child)
+		}
+		return out
+	default:
+		return v
+	}
+}
@@ -0,0 +1,33 @@
+package generator
+
+import (
+	"math"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/schema"
+)
+
+// largeIntegers are integers beyond 2^53, which a float64 cannot hold
+// exactly: templates that push them through float formatting or arithmetic
+// render them rounded or in scientific notation
+var largeIntegers = []int{
+	1<<53 + 1, -(1<<53 + 1), 12345678901234567, 1<<62 + 3, math.MaxInt64, math.MinInt64,
+}
+
+// scientificNumbers are floats Go formats in scientific notation, whole
+// numbers among them that a template would be expected to render as such
+var scientificNumbers = []float64{
+	1e6, 1e9, 2.5e10, 1e21, 6.02214076e23, 1.5e-7, 9007199254740993,
+}
+
+// explicitlyBounded returns the candidates within the schema's minimum and
+// maximum, those it sets; the generator's own default range is not applied,
+// as the candidates are meant to lie beyond it
+func explicitlyBounded[N int | float64](candidates []N, s *schema.Schema) []N {
+	var in []N
+	for _, n := range candidates {
+		if (s.Minimum == nil || float64(n) >= *s.Minimum) && (s.Maximum == nil || float64(n) <= *s.Maximum) {
+			in = append(in, n)
+		}
+	}
+	return in
+}
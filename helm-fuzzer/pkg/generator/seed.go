@@ -0,0 +1,40 @@
+package generator
+
+import (
+	"hash/fnv"
+)
+
+// Streams are the draws an iteration makes, each from a seed of its own, so
+// that varying one, such as whether lookup objects are found, leaves the
+// others' draws alone
+const (
+	StreamValues   = "values"
+	StreamLookup   = "lookup"
+	StreamFiles    = "files"
+	StreamBuiltIns = "builtins"
+	StreamEvolve   = "evolve"
+	StreamTags     = "tags"
+)
+
+// Seed derives the seed of one stream of an iteration's draws from the
+// session seed. The seed is a hash of the three, split with SplitMix64, so
+// it depends on nothing but them: iterations drawn in any order, by any
+// worker or process, draw the same inputs. Session seed 0 is the iteration
+// index itself, for every stream, as sessions drew before they had seeds.
+func Seed(session uint64, stream string, iteration int) int {
+	if session == 0 {
+		return iteration
+	}
+	h := fnv.New64a()
+	h.Write([]byte(stream))
+	return int(splitMix64(splitMix64(session^h.Sum64()) ^ uint64(iteration)))
+}
+
+// splitMix64 is the finalizer of the SplitMix64 generator, which spreads
+// nearby inputs over unrelated outputs
+func splitMix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
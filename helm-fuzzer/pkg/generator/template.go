@@ -0,0 +1,95 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+	"pgregory.net/rapid"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/schema"
+)
+
+// templateData is the data passed to constraint templates
+type templateData struct {
+	// Default is the chart's default value for the path
+	Default interface{}
+}
+
+// generateFromTemplate renders a constraint template into a value.
+// String schemas keep the rendered text; other types decode it as YAML.
+func (g *Generator) generateFromTemplate(t *rapid.T, s *schema.Schema) interface{} {
+	tmpl, err := template.New("constraint").Funcs(templateFuncs(t)).Parse(s.Template)
+	if err != nil {
+		t.Fatalf("invalid constraint template %q: %v", s.Template, err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, templateData{Default: s.Default}); err != nil {
+		t.Fatalf("failed to execute constraint template %q: %v", s.Template, err)
+	}
+
+	rendered := buf.String()
+	if s.Type == schema.TypeString || s.Type == "" {
+		return rendered
+	}
+
+	var value interface{}
+	if err := yaml.Unmarshal([]byte(rendered), &value); err != nil {
+		g.logger.Debug("constraint template output is not YAML, using it as a string", "type", s.Type, "error", err)
+		return rendered
+	}
+	return value
+}
+
+// ValidateTemplate checks if a constraint template parses
+func ValidateTemplate(text string) error {
+	_, err := template.New("constraint").Funcs(templateFuncs(nil)).Parse(text)
+	return err
+}
+
+// templateFuncs returns the functions available to constraint templates.
+// Random helpers draw from t so templated values shrink and replay like any other draw.
+func templateFuncs(t *rapid.T) template.FuncMap {
+	return template.FuncMap{
+		"dnsLabel": func() string {
+			return rapid.StringMatching(`[a-z]([a-z0-9-]{0,14}[a-z0-9])?`).Draw(t, "template_dns_label")
+		},
+		"hostname": func() string {
+			labels := rapid.SliceOfN(rapid.StringMatching(`[a-z]([a-z0-9-]{0,14}[a-z0-9])?`), 1, 3).Draw(t, "template_hostname")
+			return strings.Join(labels, ".") + ".example.com"
+		},
+		"alphaNum": func(n int) string {
+			return rapid.StringMatching(fmt.Sprintf(`[a-zA-Z0-9]{%d}`, n)).Draw(t, "template_alphanum")
+		},
+		"int": func(min, max int) int {
+			return rapid.IntRange(min, max).Draw(t, "template_int")
+		},
+		"port": func() int {
+			return rapid.IntRange(1, 65535).Draw(t, "template_port")
+		},
+		"oneOf": func(choices ...interface{}) interface{} {
+			if len(choices) == 0 {
+				return nil
+			}
+			return choices[rapid.IntRange(0, len(choices)-1).Draw(t, "template_choice")]
+		},
+		"lower": strings.ToLower,
+		"upper": strings.ToUpper,
+		"trunc": func(n int, s string) string {
+			if n >= 0 && len(s) > n {
+				return s[:n]
+			}
+			return s
+		},
+		"join": func(sep string, items ...interface{}) string {
+			parts := make([]string, len(items))
+			for i, item := range items {
+				parts[i] = fmt.Sprint(item)
+			}
+			return strings.Join(parts, sep)
+		},
+	}
+}
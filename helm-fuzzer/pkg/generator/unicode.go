@@ -0,0 +1,31 @@
+package generator
+
+import (
+	"strings"
+
+	"pgregory.net/rapid"
+)
+
+// multiByteRunes are characters of two, three and four bytes in UTF-8
+var multiByteRunes = []string{"é", "ß", "ü", "日", "本", "語", "😀", "🚀"}
+
+// nearLimitUnicode draws strings of a few lowercase characters short of 63,
+// the limit on Kubernetes names and label values, ending in multi-byte
+// characters that straddle it: helpers truncating by bytes cut one in half,
+// and helpers truncating by characters leave more than 63 bytes
+func nearLimitUnicode() *rapid.Generator[string] {
+	return rapid.Custom(func(t *rapid.T) string {
+		var b strings.Builder
+		prefix := rapid.IntRange(55, 62).Draw(t, "unicode_prefix")
+		for i := 0; i < prefix; i++ {
+			b.WriteByte(dnsLower[rapid.IntRange(0, len(dnsLower)-1).Draw(t, "unicode_char")])
+		}
+		for i := rapid.IntRange(1, 4).Draw(t, "unicode_runes"); i > 0; i-- {
+			b.WriteString(rapid.SampledFrom(multiByteRunes).Draw(t, "unicode_rune"))
+		}
+		return b.String()
+	})
+}
+
+// dnsLower are the characters of DNS-1123 labels besides the hyphen
+const dnsLower = "abcdefghijklmnopqrstuvwxyz0123456789"
@@ -0,0 +1,14 @@
+package generator
+
+// yaml11Strings are strings YAML 1.1 parsers resolve to other types when
+// they render unquoted: booleans (the Norway problem), sexagesimal numbers,
+// octal and other numbers, nulls and timestamps. Helm parses YAML 1.2, where
+// most of them are plain strings, but kubectl, client libraries and other
+// tools reading rendered manifests often do not.
+var yaml11Strings = []string{
+	"no", "No", "NO", "yes", "Yes", "on", "On", "off", "OFF", "y", "n", "Y", "N",
+	"1:30", "190:20:30",
+	"0777", "012", "0o17", "0x1F", "1e3", "1_000",
+	"~", "null", ".inf", ".NaN",
+	"2001-12-14",
+}
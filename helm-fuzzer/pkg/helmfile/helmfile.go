@@ -0,0 +1,213 @@
+// Package helmfile reads the releases of a helmfile.yaml as fuzz targets:
+// each release's chart is resolved and its values files, inline values and
+// set entries are merged into the values the release installs with.
+//
+// Only plain YAML is understood. Templated helmfiles and values files
+// (.gotmpl, or containing {{ }}) must be rendered first with
+// "helmfile build --embed-values".
+package helmfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"helm.sh/helm/v3/pkg/registry"
+	"helm.sh/helm/v3/pkg/strvals"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/runner"
+)
+
+// buildHint tells users how to turn a templated helmfile into plain YAML
+const buildHint = `render it first with "helmfile build --embed-values > helmfile.rendered.yaml"`
+
+// File is a parsed helmfile
+type File struct {
+	Repositories []Repository `yaml:"repositories"`
+	Releases     []Release    `yaml:"releases"`
+
+	// dir resolves relative chart and values paths
+	dir string
+}
+
+// Repository is a chart repository releases refer to by name
+type Repository struct {
+	Name string `yaml:"name"`
+	URL  string `yaml:"url"`
+	OCI  bool   `yaml:"oci"`
+}
+
+// Release is one release of a helmfile
+type Release struct {
+	Name      string `yaml:"name"`
+	Namespace string `yaml:"namespace"`
+	// Chart is a local path, repository/chart or an oci:// reference
+	Chart   string `yaml:"chart"`
+	Version string `yaml:"version"`
+	// Values holds values file paths and inline values maps, merged in order
+	Values    []interface{} `yaml:"values"`
+	Set       []SetValue    `yaml:"set"`
+	Installed *bool         `yaml:"installed"`
+}
+
+// SetValue sets a single value, like helm's --set and --set-file
+type SetValue struct {
+	Name  string      `yaml:"name"`
+	Value interface{} `yaml:"value"`
+	File  string      `yaml:"file"`
+}
+
+// IsInstalled reports whether the release is installed; releases are unless
+// they say otherwise
+func (r Release) IsInstalled() bool {
+	return r.Installed == nil || *r.Installed
+}
+
+// Load parses the helmfile at path. Documents separated by --- are merged.
+func Load(path string) (*File, error) {
+	if strings.HasSuffix(path, ".gotmpl") {
+		return nil, fmt.Errorf("%s is templated: %s", path, buildHint)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read helmfile: %w", err)
+	}
+	if bytes.Contains(data, []byte("{{")) {
+		return nil, fmt.Errorf("%s is templated: %s", path, buildHint)
+	}
+
+	f := &File{dir: filepath.Dir(path)}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc File
+		if err := dec.Decode(&doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("failed to parse helmfile: %w", err)
+		}
+		f.Repositories = append(f.Repositories, doc.Repositories...)
+		f.Releases = append(f.Releases, doc.Releases...)
+	}
+
+	seen := make(map[string]bool, len(f.Releases))
+	for i, r := range f.Releases {
+		if r.Name == "" {
+			return nil, fmt.Errorf("release %d has no name", i+1)
+		}
+		if r.Chart == "" {
+			return nil, fmt.Errorf("release %s has no chart", r.Name)
+		}
+		if seen[r.Namespace+"/"+r.Name] {
+			return nil, fmt.Errorf("release %s is defined more than once", r.Name)
+		}
+		seen[r.Namespace+"/"+r.Name] = true
+	}
+	return f, nil
+}
+
+// Chart returns the chart directory of a release. Local charts are used in
+// place; remote charts are downloaded and unpacked into dir.
+func (f *File) Chart(dir string, r Release) (string, error) {
+	if registry.IsOCI(r.Chart) {
+		return runner.PullChart(dir, r.Chart, r.Version)
+	}
+
+	// Like helmfile, prefer a local chart over a repository of the same name
+	local := r.Chart
+	if !filepath.IsAbs(local) {
+		local = filepath.Join(f.dir, local)
+	}
+	if _, err := os.Stat(local); err == nil {
+		return runner.OpenChart(dir, local)
+	}
+
+	repoName, name, ok := strings.Cut(r.Chart, "/")
+	if !ok {
+		return "", fmt.Errorf("release %s: chart %s is neither a local path nor repository/chart", r.Name, r.Chart)
+	}
+	for _, repo := range f.Repositories {
+		if repo.Name != repoName {
+			continue
+		}
+		if repo.OCI {
+			return runner.PullChart(dir, "oci://"+strings.TrimSuffix(repo.URL, "/")+"/"+name, r.Version)
+		}
+		return runner.PullRepoChart(dir, repo.URL, name, r.Version)
+	}
+	return "", fmt.Errorf("release %s: repository %s is not defined", r.Name, repoName)
+}
+
+// Values returns the values a release installs with: its values files and
+// inline values merged in order, then its set entries
+func (f *File) Values(r Release) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+	for _, entry := range r.Values {
+		switch v := entry.(type) {
+		case string:
+			file, err := f.valuesFile(v)
+			if err != nil {
+				return nil, fmt.Errorf("release %s: %w", r.Name, err)
+			}
+			values = runner.MergeValues(values, file)
+		case map[string]interface{}:
+			values = runner.MergeValues(values, v)
+		case nil:
+		default:
+			return nil, fmt.Errorf("release %s: values entries must be file paths or maps, got %T", r.Name, entry)
+		}
+	}
+
+	for _, set := range r.Set {
+		if set.Name == "" {
+			return nil, fmt.Errorf("release %s: set entry has no name", r.Name)
+		}
+		value := set.Value
+		if set.File != "" {
+			data, err := os.ReadFile(f.path(set.File))
+			if err != nil {
+				return nil, fmt.Errorf("release %s: failed to read set file: %w", r.Name, err)
+			}
+			value = string(data)
+		}
+		// JSON keeps the value's type, and its commas, intact
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("release %s: invalid value for %s: %w", r.Name, set.Name, err)
+		}
+		if err := strvals.ParseJSON(set.Name+"="+string(encoded), values); err != nil {
+			return nil, fmt.Errorf("release %s: invalid set %s: %w", r.Name, set.Name, err)
+		}
+	}
+	return values, nil
+}
+
+// valuesFile reads a values file relative to the helmfile
+func (f *File) valuesFile(name string) (map[string]interface{}, error) {
+	if strings.HasSuffix(name, ".gotmpl") {
+		return nil, fmt.Errorf("values file %s is templated: %s", name, buildHint)
+	}
+	data, err := os.ReadFile(f.path(name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read values file: %w", err)
+	}
+	values := map[string]interface{}{}
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("failed to parse values file %s: %w", name, err)
+	}
+	return values, nil
+}
+
+// path resolves a path relative to the helmfile
+func (f *File) path(name string) string {
+	if filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(f.dir, name)
+}
@@ -0,0 +1,97 @@
+package helmfile
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "helmfile.yaml")
+	writeFile(t, filepath.Join(dir, "values", "web.yaml"), "replicas: 2\nimage:\n  tag: v1\n  pullPolicy: Always\n")
+	writeFile(t, path, `repositories:
+  - name: bitnami
+    url: https://charts.bitnami.com/bitnami
+releases:
+  - name: web
+    namespace: apps
+    chart: ../../testdata/buggy-chart
+    values:
+      - values/web.yaml
+      - image:
+          tag: v2
+    set:
+      - name: annotations.team
+        value: a,b
+      - name: service.port
+        value: 8080
+---
+releases:
+  - name: cache
+    chart: bitnami/redis
+    installed: false
+`)
+
+	f, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(f.Releases) != 2 || len(f.Repositories) != 1 {
+		t.Fatalf("expected both documents merged, got %+v", f)
+	}
+	if !f.Releases[0].IsInstalled() || f.Releases[1].IsInstalled() {
+		t.Errorf("expected only web to be installed")
+	}
+
+	values, err := f.Values(f.Releases[0])
+	if err != nil {
+		t.Fatalf("Values failed: %v", err)
+	}
+	want := map[string]interface{}{
+		"replicas":    2,
+		"image":       map[string]interface{}{"tag": "v2", "pullPolicy": "Always"},
+		"annotations": map[string]interface{}{"team": "a,b"},
+		"service":     map[string]interface{}{"port": float64(8080)},
+	}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("expected values %v, got %v", want, values)
+	}
+}
+
+func TestLoad_Templated(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "helmfile.yaml")
+	writeFile(t, path, "releases:\n  - name: web\n    chart: ./web\n    namespace: {{ .Environment.Name }}\n")
+	if _, err := Load(path); err == nil {
+		t.Error("expected an error for a templated helmfile")
+	}
+	if _, err := Load(filepath.Join(dir, "helmfile.yaml.gotmpl")); err == nil {
+		t.Error("expected an error for a .gotmpl helmfile")
+	}
+}
+
+func TestChart(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "charts", "web", "Chart.yaml"), "apiVersion: v2\nname: web\nversion: 0.1.0\n")
+	f := &File{dir: dir}
+
+	chart, err := f.Chart(t.TempDir(), Release{Name: "web", Chart: "charts/web"})
+	if err != nil || chart != filepath.Join(dir, "charts", "web") {
+		t.Errorf("expected the local chart, got %q, %v", chart, err)
+	}
+	if _, err := f.Chart(t.TempDir(), Release{Name: "db", Chart: "missing/postgres"}); err == nil {
+		t.Error("expected an error for an undefined repository")
+	}
+}
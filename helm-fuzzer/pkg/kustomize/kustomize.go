@@ -0,0 +1,431 @@
+// Package kustomize reads the helmCharts of a kustomization as fuzz targets
+// and builds the kustomization around each fuzzed render, so oracles check
+// the manifests kustomize ships rather than the chart's own output.
+package kustomize
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"helm.sh/helm/v3/pkg/registry"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/runner"
+)
+
+// FileNames are the names kustomize looks up a kustomization under, in order
+var FileNames = []string{"kustomization.yaml", "kustomization.yml", "Kustomization"}
+
+// DefaultChartHome is where charts are kept when helmGlobals.chartHome is unset
+const DefaultChartHome = "charts"
+
+// DefaultReleaseName is the release helm template renders when kustomize
+// passes no releaseName
+const DefaultReleaseName = "release-name"
+
+// valuesMerge options of a helmCharts entry
+const (
+	// MergeOverride lets valuesInline override the values file (the default)
+	MergeOverride = "override"
+	// MergeMerge only adds the valuesInline keys the values file lacks
+	MergeMerge = "merge"
+	// MergeReplace uses valuesInline instead of the values file
+	MergeReplace = "replace"
+)
+
+// Kustomization is a kustomization with helmCharts
+type Kustomization struct {
+	HelmGlobals struct {
+		ChartHome string `yaml:"chartHome"`
+	} `yaml:"helmGlobals"`
+	HelmCharts []HelmChart `yaml:"helmCharts"`
+
+	// dir is the kustomization directory and file its kustomization file
+	dir  string
+	file string
+	// doc is the whole kustomization, rewritten for each build
+	doc map[string]interface{}
+}
+
+// HelmChart is an entry of helmCharts, inflated by kustomize with helm template
+type HelmChart struct {
+	Name string `yaml:"name"`
+	// Repo is a Helm repository or oci:// registry the chart is pulled from
+	// when it is not in the chart home
+	Repo        string `yaml:"repo"`
+	Version     string `yaml:"version"`
+	ReleaseName string `yaml:"releaseName"`
+	Namespace   string `yaml:"namespace"`
+	// ValuesFile replaces the chart's values.yaml (relative to the kustomization)
+	ValuesFile   string                 `yaml:"valuesFile"`
+	ValuesInline map[string]interface{} `yaml:"valuesInline"`
+	// ValuesMerge is how ValuesInline combines with the values file
+	ValuesMerge           string   `yaml:"valuesMerge"`
+	AdditionalValuesFiles []string `yaml:"additionalValuesFiles"`
+	IncludeCRDs           bool     `yaml:"includeCRDs"`
+}
+
+// Load parses the kustomization in dir, or at dir if it names the file itself
+func Load(dir string) (*Kustomization, error) {
+	path := dir
+	if info, err := os.Stat(dir); err == nil && info.IsDir() {
+		path = ""
+		for _, name := range FileNames {
+			if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+				path = filepath.Join(dir, name)
+				break
+			}
+		}
+		if path == "" {
+			return nil, fmt.Errorf("no kustomization in %s", dir)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read kustomization: %w", err)
+	}
+	k := &Kustomization{dir: filepath.Dir(path), file: filepath.Base(path)}
+	if err := yaml.Unmarshal(data, k); err != nil {
+		return nil, fmt.Errorf("failed to parse kustomization: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &k.doc); err != nil {
+		return nil, fmt.Errorf("failed to parse kustomization: %w", err)
+	}
+	if len(k.HelmCharts) == 0 {
+		return nil, fmt.Errorf("%s has no helmCharts", path)
+	}
+
+	for i, c := range k.HelmCharts {
+		if c.Name == "" {
+			return nil, fmt.Errorf("helmCharts entry %d has no name", i+1)
+		}
+		switch c.ValuesMerge {
+		case "", MergeOverride, MergeMerge, MergeReplace:
+		default:
+			return nil, fmt.Errorf("helm chart %s has invalid valuesMerge %q: must be override, merge or replace", c.Name, c.ValuesMerge)
+		}
+	}
+	return k, nil
+}
+
+// ChartHome returns the directory charts are looked up in
+func (k *Kustomization) ChartHome() string {
+	home := k.HelmGlobals.ChartHome
+	if home == "" {
+		home = DefaultChartHome
+	}
+	return k.path(home)
+}
+
+// Chart returns the chart directory of an entry: the chart home's copy,
+// as kustomize keeps it, or the chart pulled from its repo into dir
+func (k *Kustomization) Chart(dir string, c HelmChart) (string, error) {
+	local := []string{filepath.Join(k.ChartHome(), c.Name)}
+	if c.Version != "" {
+		local = append([]string{filepath.Join(k.ChartHome(), c.Name+"-"+c.Version, c.Name)}, local...)
+	}
+	for _, path := range local {
+		if _, err := os.Stat(filepath.Join(path, "Chart.yaml")); err == nil {
+			return path, nil
+		}
+	}
+
+	switch {
+	case c.Repo == "":
+		return "", fmt.Errorf("helm chart %s is not in %s and has no repo", c.Name, k.ChartHome())
+	case registry.IsOCI(c.Repo):
+		return runner.PullChart(dir, strings.TrimSuffix(c.Repo, "/")+"/"+c.Name, c.Version)
+	default:
+		return runner.PullRepoChart(dir, c.Repo, c.Name, c.Version)
+	}
+}
+
+// Release returns the name of the release an entry renders as
+func (c HelmChart) Release() string {
+	if c.ReleaseName == "" {
+		return DefaultReleaseName
+	}
+	return c.ReleaseName
+}
+
+// Values returns the values an entry pins beyond the chart's defaults at
+// chartPath: its values file, its inline values combined as valuesMerge
+// says, then its additional values files
+func (k *Kustomization) Values(chartPath string, c HelmChart) (map[string]interface{}, error) {
+	// Without a values file kustomize passes the chart's values.yaml, which
+	// pins nothing that rendering would not use anyway
+	file := map[string]interface{}{}
+	if c.ValuesFile != "" && c.ValuesMerge != MergeReplace {
+		var err error
+		if file, err = readValues(k.path(c.ValuesFile)); err != nil {
+			return nil, fmt.Errorf("helm chart %s: %w", c.Name, err)
+		}
+	}
+
+	var values map[string]interface{}
+	switch c.ValuesMerge {
+	case MergeReplace:
+		values = runner.MergeValues(map[string]interface{}{}, c.ValuesInline)
+	case MergeMerge:
+		base := file
+		if c.ValuesFile == "" {
+			defaults, err := readValues(filepath.Join(chartPath, "values.yaml"))
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return nil, fmt.Errorf("helm chart %s: %w", c.Name, err)
+			}
+			base = defaults
+		}
+		values = runner.MergeValues(file, missing(c.ValuesInline, base))
+	default:
+		values = runner.MergeValues(file, c.ValuesInline)
+	}
+
+	for _, name := range c.AdditionalValuesFiles {
+		extra, err := readValues(k.path(name))
+		if err != nil {
+			return nil, fmt.Errorf("helm chart %s: %w", c.Name, err)
+		}
+		values = runner.MergeValues(values, extra)
+	}
+	return values, nil
+}
+
+// missing returns the values of inline whose keys base lacks, descending
+// into maps present in both
+func missing(inline, base map[string]interface{}) map[string]interface{} {
+	result := map[string]interface{}{}
+	for key, value := range inline {
+		existing, ok := base[key]
+		if !ok {
+			result[key] = value
+			continue
+		}
+		inner, innerOK := value.(map[string]interface{})
+		baseInner, baseOK := existing.(map[string]interface{})
+		if innerOK && baseOK {
+			if rest := missing(inner, baseInner); len(rest) > 0 {
+				result[key] = rest
+			}
+		}
+	}
+	return result
+}
+
+// readValues reads a values file. A missing file keeps its os.ErrNotExist.
+func readValues(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read values file: %w", err)
+	}
+	values := map[string]interface{}{}
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("failed to parse values file %s: %w", path, err)
+	}
+	return values, nil
+}
+
+// path resolves a path relative to the kustomization
+func (k *Kustomization) path(name string) string {
+	if filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(k.dir, name)
+}
+
+// Renderer is a helm post-renderer that builds the kustomization with one
+// entry's render in place of its helmCharts entry. It implements
+// postrender.PostRenderer.
+type Renderer struct {
+	k      *Kustomization
+	binary string
+	index  int
+	// static holds the renders of the other entries, which keep their own values
+	static map[int]string
+}
+
+// Renderer returns a post-renderer for the entry at index, building with the
+// kustomize binary at binary, a path or a name looked up in PATH. static
+// holds a render of every other entry.
+func (k *Kustomization) Renderer(binary string, index int, static map[int]string) (*Renderer, error) {
+	path, err := exec.LookPath(binary)
+	if err != nil {
+		return nil, fmt.Errorf("kustomize binary %s not found: %w", binary, err)
+	}
+	for i, c := range k.HelmCharts {
+		if _, ok := static[i]; !ok && i != index {
+			return nil, fmt.Errorf("helm chart %s has no render", c.Name)
+		}
+	}
+	// kustomize build fetches remote resources itself, each time
+	if runner.Offline() {
+		if remote := k.RemoteResources(); len(remote) > 0 {
+			return nil, runner.NeedsNetwork(fmt.Sprintf("building %s, which references %s,", filepath.Join(k.dir, k.file), remote[0]))
+		}
+	}
+	return &Renderer{k: k, binary: path, index: index, static: static}, nil
+}
+
+// remotePrefixes start the resources kustomize fetches rather than reads,
+// beside URLs: git repositories in its shorthand forms
+var remotePrefixes = []string{"git@", "git::", "gh:", "github.com/", "gitlab.com/", "bitbucket.org/"}
+
+// RemoteResources returns the remote resources, bases and components of the
+// kustomization and of the local kustomizations it builds on, sorted: URLs
+// and git repositories, which kustomize build fetches
+func (k *Kustomization) RemoteResources() []string {
+	seen := make(map[string]bool)
+	remote := make(map[string]bool)
+	var walk func(dir string, doc map[string]interface{})
+	walk = func(dir string, doc map[string]interface{}) {
+		seen[dir] = true
+		for _, field := range []string{"resources", "bases", "components"} {
+			entries, _ := doc[field].([]interface{})
+			for _, entry := range entries {
+				name, ok := entry.(string)
+				if !ok {
+					continue
+				}
+				if isRemote(name) {
+					remote[name] = true
+					continue
+				}
+				path := name
+				if !filepath.IsAbs(path) {
+					path = filepath.Join(dir, name)
+				}
+				if seen[path] {
+					continue
+				}
+				for _, file := range FileNames {
+					data, err := os.ReadFile(filepath.Join(path, file))
+					if err != nil {
+						continue
+					}
+					var nested map[string]interface{}
+					if yaml.Unmarshal(data, &nested) == nil {
+						walk(path, nested)
+					}
+					break
+				}
+			}
+		}
+	}
+	walk(k.dir, k.doc)
+
+	resources := make([]string, 0, len(remote))
+	for name := range remote {
+		resources = append(resources, name)
+	}
+	sort.Strings(resources)
+	return resources
+}
+
+// isRemote reports whether a resource is fetched by kustomize
+func isRemote(name string) bool {
+	if strings.Contains(name, "://") {
+		return !strings.HasPrefix(name, "file://")
+	}
+	for _, prefix := range remotePrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Run builds the kustomization with rendered as the entry's output. The
+// kustomization is copied next to the original, at the same depth, so its
+// relative references to other directories resolve as they do in place;
+// the copy is removed afterwards.
+func (r *Renderer) Run(rendered *bytes.Buffer) (*bytes.Buffer, error) {
+	dir, err := os.MkdirTemp(filepath.Dir(r.k.dir), "."+filepath.Base(r.k.dir)+"-helm-fuzz-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy kustomization: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := r.copy(dir); err != nil {
+		return nil, fmt.Errorf("failed to copy kustomization: %w", err)
+	}
+
+	// The chart renders join the resources, as kustomize adds generated ones
+	doc := make(map[string]interface{}, len(r.k.doc))
+	for key, value := range r.k.doc {
+		doc[key] = value
+	}
+	delete(doc, "helmCharts")
+	delete(doc, "helmGlobals")
+	resources, _ := doc["resources"].([]interface{})
+	resources = append([]interface{}{}, resources...)
+	for i := range r.k.HelmCharts {
+		manifest := r.static[i]
+		if i == r.index {
+			manifest = rendered.String()
+		}
+		name := fmt.Sprintf("helm-fuzz-chart-%d.yaml", i)
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(manifest), 0644); err != nil {
+			return nil, fmt.Errorf("failed to write chart render: %w", err)
+		}
+		resources = append(resources, name)
+	}
+	doc["resources"] = resources
+	data, err := yaml.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write kustomization: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, FileNames[0]), data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write kustomization: %w", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(r.binary, "build", dir)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimPrefix(strings.TrimSpace(stderr.String()), "Error: ")
+		if msg == "" {
+			msg = err.Error()
+		}
+		// Paths in kustomize's errors name the copy; point them at the original
+		msg = strings.ReplaceAll(msg, dir, r.k.dir)
+		return nil, fmt.Errorf("kustomize build failed: %s", msg)
+	}
+	return &stdout, nil
+}
+
+// copy copies the kustomization directory into dir, leaving out the
+// kustomization file, which Run rewrites, and the chart home
+func (r *Renderer) copy(dir string) error {
+	home := r.k.ChartHome()
+	return filepath.WalkDir(r.k.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(r.k.dir, path)
+		if err != nil {
+			return err
+		}
+		switch {
+		case rel == ".":
+			return nil
+		case path == home && d.IsDir():
+			return filepath.SkipDir
+		case d.IsDir():
+			return os.Mkdir(filepath.Join(dir, rel), 0755)
+		case rel == r.k.file || !d.Type().IsRegular():
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(dir, rel), data, 0644)
+	})
+}
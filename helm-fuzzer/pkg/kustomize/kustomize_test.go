@@ -0,0 +1,228 @@
+package kustomize
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/runner"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadAndChart(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "overlay")
+	writeFile(t, filepath.Join(dir, "kustomization.yml"), `resources:
+  - configmap.yaml
+helmGlobals:
+  chartHome: vendor
+helmCharts:
+  - name: app
+    version: 1.0.0
+  - name: db
+    releaseName: pg
+    namespace: data
+`)
+	writeFile(t, filepath.Join(dir, "vendor", "app-1.0.0", "app", "Chart.yaml"), "name: app\n")
+	writeFile(t, filepath.Join(dir, "vendor", "db", "Chart.yaml"), "name: db\n")
+
+	k, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(k.HelmCharts) != 2 {
+		t.Fatalf("expected two helm charts, got %+v", k.HelmCharts)
+	}
+	if got := k.HelmCharts[0].Release(); got != DefaultReleaseName {
+		t.Errorf("expected the default release name, got %q", got)
+	}
+	if got := k.HelmCharts[1].Release(); got != "pg" {
+		t.Errorf("expected release pg, got %q", got)
+	}
+
+	app, err := k.Chart(t.TempDir(), k.HelmCharts[0])
+	if err != nil || app != filepath.Join(dir, "vendor", "app-1.0.0", "app") {
+		t.Errorf("expected the versioned chart home copy, got %s (%v)", app, err)
+	}
+	db, err := k.Chart(t.TempDir(), k.HelmCharts[1])
+	if err != nil || db != filepath.Join(dir, "vendor", "db") {
+		t.Errorf("expected the unversioned chart home copy, got %s (%v)", db, err)
+	}
+
+	writeFile(t, filepath.Join(dir, "kustomization.yml"), "helmCharts:\n  - name: app\n    valuesMerge: append\n")
+	if _, err := Load(dir); err == nil {
+		t.Error("expected an error for an invalid valuesMerge")
+	}
+}
+
+func TestValues(t *testing.T) {
+	dir := t.TempDir()
+	chartPath := filepath.Join(dir, "charts", "app")
+	writeFile(t, filepath.Join(chartPath, "values.yaml"), "replicas: 1\nimage:\n  tag: latest\n")
+	writeFile(t, filepath.Join(dir, "values-prod.yaml"), "replicas: 3\n")
+	writeFile(t, filepath.Join(dir, "extra.yaml"), "debug: true\n")
+	k := &Kustomization{dir: dir}
+
+	inline := map[string]interface{}{
+		"replicas": 5,
+		"image":    map[string]interface{}{"tag": "v2", "pullPolicy": "Always"},
+	}
+	tests := []struct {
+		name  string
+		chart HelmChart
+		want  map[string]interface{}
+	}{
+		{
+			name:  "override",
+			chart: HelmChart{Name: "app", ValuesFile: "values-prod.yaml", ValuesInline: inline, AdditionalValuesFiles: []string{"extra.yaml"}},
+			want: map[string]interface{}{
+				"replicas": 5,
+				"image":    map[string]interface{}{"tag": "v2", "pullPolicy": "Always"},
+				"debug":    true,
+			},
+		},
+		{
+			name:  "merge keeps the values file",
+			chart: HelmChart{Name: "app", ValuesFile: "values-prod.yaml", ValuesInline: inline, ValuesMerge: MergeMerge},
+			want: map[string]interface{}{
+				"replicas": 3,
+				"image":    map[string]interface{}{"tag": "v2", "pullPolicy": "Always"},
+			},
+		},
+		{
+			name:  "merge keeps the chart defaults",
+			chart: HelmChart{Name: "app", ValuesInline: inline, ValuesMerge: MergeMerge},
+			want: map[string]interface{}{
+				"image": map[string]interface{}{"pullPolicy": "Always"},
+			},
+		},
+		{
+			name:  "replace",
+			chart: HelmChart{Name: "app", ValuesFile: "values-prod.yaml", ValuesInline: inline, ValuesMerge: MergeReplace},
+			want:  inline,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := k.Values(chartPath, tt.chart)
+			if err != nil {
+				t.Fatalf("Values failed: %v", err)
+			}
+			if !reflect.DeepEqual(values, tt.want) {
+				t.Errorf("expected values %v, got %v", tt.want, values)
+			}
+		})
+	}
+}
+
+// fakeKustomize writes a script that answers kustomize build by printing the
+// resources of the kustomization, failing when a resource is marked broken
+func fakeKustomize(t *testing.T) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake kustomize is a shell script")
+	}
+	script := `#!/bin/sh
+cd "$2" || exit 1
+[ -f kustomization.yaml ] || { echo "Error: no kustomization.yaml" >&2; exit 1; }
+if grep -q helmCharts kustomization.yaml; then echo "Error: helmCharts left in" >&2; exit 1; fi
+for f in $(sed -n 's/^ *- //p' kustomization.yaml); do
+  if grep -q broken "$f"; then echo "Error: no matches for patch target in $PWD/$f" >&2; exit 1; fi
+  cat "$f"
+done
+`
+	path := filepath.Join(t.TempDir(), "kustomize")
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRenderer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "overlay")
+	writeFile(t, filepath.Join(dir, "kustomization.yaml"), `resources:
+  - namespace.yaml
+helmCharts:
+  - name: app
+  - name: db
+`)
+	writeFile(t, filepath.Join(dir, "namespace.yaml"), "kind: Namespace\n")
+	k, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	r, err := k.Renderer(fakeKustomize(t), 0, map[int]string{1: "kind: StatefulSet\n"})
+	if err != nil {
+		t.Fatalf("Renderer failed: %v", err)
+	}
+	out, err := r.Run(bytes.NewBufferString("kind: Deployment\n"))
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if got := out.String(); got != "kind: Namespace\nkind: Deployment\nkind: StatefulSet\n" {
+		t.Errorf("expected the resources and both renders, got %q", got)
+	}
+
+	_, err = r.Run(bytes.NewBufferString("kind: broken\n"))
+	if err == nil || !strings.Contains(err.Error(), "kustomize build failed: no matches") || !strings.Contains(err.Error(), dir+string(filepath.Separator)) {
+		t.Errorf("expected the build error naming the original directory, got %v", err)
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(dir))
+	if err != nil || len(entries) != 1 {
+		t.Errorf("expected the copies to be removed, got %v", entries)
+	}
+
+	if _, err := k.Renderer(fakeKustomize(t), 0, nil); err == nil {
+		t.Error("expected an error without a render of the other chart")
+	}
+}
+
+func TestRemoteResources(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "overlay")
+	writeFile(t, filepath.Join(dir, "kustomization.yaml"), `resources:
+  - namespace.yaml
+  - ../base
+  - https://raw.githubusercontent.com/org/repo/main/crd.yaml
+components:
+  - file:///opt/components/labels
+helmCharts:
+  - name: app
+`)
+	writeFile(t, filepath.Join(dir, "namespace.yaml"), "kind: Namespace\n")
+	writeFile(t, filepath.Join(root, "base", "kustomization.yml"), `bases:
+  - github.com/org/repo/deploy?ref=v1.0.0
+resources:
+  - ../overlay
+`)
+	k, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	want := []string{"github.com/org/repo/deploy?ref=v1.0.0", "https://raw.githubusercontent.com/org/repo/main/crd.yaml"}
+	if got := k.RemoteResources(); !reflect.DeepEqual(got, want) {
+		t.Errorf("RemoteResources() = %v, want %v", got, want)
+	}
+
+	runner.SetOffline(true)
+	defer runner.SetOffline(false)
+	if _, err := k.Renderer(fakeKustomize(t), 0, nil); !errors.Is(err, runner.ErrOffline) || !strings.Contains(err.Error(), want[0]) {
+		t.Errorf("expected an offline error naming the remote base, got %v", err)
+	}
+}
@@ -0,0 +1,23 @@
+package logging
+
+import (
+	"io"
+	"log/slog"
+)
+
+// discard drops every record
+var discard = slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.Level(127)}))
+
+// Discard returns a logger that drops every record
+func Discard() *slog.Logger {
+	return discard
+}
+
+// OrDiscard returns l, or a discarding logger if l is nil, so packages can
+// treat an unset logger option as "no logging"
+func OrDiscard(l *slog.Logger) *slog.Logger {
+	if l == nil {
+		return discard
+	}
+	return l
+}
@@ -0,0 +1,169 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry collects the metrics of every chart fuzzed by the process
+type Registry struct {
+	registry *prometheus.Registry
+}
+
+// NewRegistry creates a registry with the Go runtime and process collectors
+func NewRegistry() *Registry {
+	r := &Registry{registry: prometheus.NewRegistry()}
+	r.registry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+	)
+	return r
+}
+
+// Metrics exposes fuzzing session metrics in Prometheus format
+type Metrics struct {
+	registry *Registry
+
+	iterations     prometheus.Counter
+	crashes        *prometheus.CounterVec
+	uniqueCrashes  *prometheus.CounterVec
+	renderDuration prometheus.Histogram
+	workers        prometheus.Gauge
+	busyWorkers    prometheus.Gauge
+	lastIteration  prometheus.Gauge
+}
+
+// New creates session metrics for the given number of workers in their own registry
+func New(chartName string, workers int) *Metrics {
+	return NewRegistry().Chart(chartName, workers)
+}
+
+// Chart creates session metrics for one chart; every chart's metrics are
+// served together, told apart by the chart label
+func (r *Registry) Chart(chartName string, workers int) *Metrics {
+	labels := prometheus.Labels{"chart": chartName}
+	m := &Metrics{
+		registry: r,
+		iterations: prometheus.NewCounter(prometheus.CounterOpts{
+			Name:        "helm_fuzz_iterations_total",
+			Help:        "Number of completed fuzzing iterations.",
+			ConstLabels: labels,
+		}),
+		crashes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:        "helm_fuzz_crashes_total",
+			Help:        "Number of crashing iterations by category, including duplicates.",
+			ConstLabels: labels,
+		}, []string{"category"}),
+		uniqueCrashes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:        "helm_fuzz_unique_crashes_total",
+			Help:        "Number of unique crashes by category.",
+			ConstLabels: labels,
+		}, []string{"category"}),
+		renderDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:        "helm_fuzz_render_duration_seconds",
+			Help:        "Time taken to render the chart for one iteration.",
+			ConstLabels: labels,
+			Buckets:     prometheus.ExponentialBuckets(0.0005, 2, 14),
+		}),
+		workers: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name:        "helm_fuzz_workers",
+			Help:        "Number of configured fuzzing workers.",
+			ConstLabels: labels,
+		}),
+		busyWorkers: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name:        "helm_fuzz_workers_busy",
+			Help:        "Number of workers currently rendering; divide by helm_fuzz_workers for utilization.",
+			ConstLabels: labels,
+		}),
+		lastIteration: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name:        "helm_fuzz_last_iteration_timestamp_seconds",
+			Help:        "Unix time of the most recently completed iteration, for stall alerts.",
+			ConstLabels: labels,
+		}),
+	}
+
+	r.registry.MustRegister(
+		m.iterations,
+		m.crashes,
+		m.uniqueCrashes,
+		m.renderDuration,
+		m.workers,
+		m.busyWorkers,
+		m.lastIteration,
+	)
+	m.workers.Set(float64(workers))
+
+	return m
+}
+
+// StartRender marks a worker busy and returns a function that records the
+// render duration and marks it idle again
+func (m *Metrics) StartRender() func() {
+	m.busyWorkers.Inc()
+	started := time.Now()
+	return func() {
+		m.renderDuration.Observe(time.Since(started).Seconds())
+		m.busyWorkers.Dec()
+	}
+}
+
+// RecordIteration records a completed iteration; category is empty unless it crashed
+func (m *Metrics) RecordIteration(category string) {
+	m.iterations.Inc()
+	m.lastIteration.SetToCurrentTime()
+	if category != "" {
+		m.crashes.WithLabelValues(category).Inc()
+	}
+}
+
+// RecordUniqueCrash records a new unique crash
+func (m *Metrics) RecordUniqueCrash(category string) {
+	m.uniqueCrashes.WithLabelValues(category).Inc()
+}
+
+// Handler returns the HTTP handler serving the metrics
+func (m *Metrics) Handler() http.Handler {
+	return m.registry.Handler()
+}
+
+// Start serves /metrics on addr in the background until ctx is cancelled
+func (m *Metrics) Start(ctx context.Context, addr string) (net.Addr, error) {
+	return m.registry.Start(ctx, addr)
+}
+
+// Handler returns the HTTP handler serving every chart's metrics
+func (r *Registry) Handler() http.Handler {
+	return promhttp.HandlerFor(r.registry, promhttp.HandlerOpts{})
+}
+
+// Start serves /metrics on addr in the background until ctx is cancelled.
+// It returns once the listener is bound, so address errors surface immediately.
+func (r *Registry) Start(ctx context.Context, addr string) (net.Addr, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", r.Handler())
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+	go func() {
+		// Serve only fails once the listener is closed by Shutdown
+		_ = server.Serve(listener)
+	}()
+
+	return listener.Addr(), nil
+}
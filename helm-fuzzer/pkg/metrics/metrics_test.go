@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	m := New("my-chart", 4)
+
+	done := m.StartRender()
+	done()
+	m.RecordIteration("")
+	m.RecordIteration("nil pointer")
+	m.RecordUniqueCrash("nil pointer")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	addr, err := m.Start(ctx, "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	resp, err := http.Get("http://" + addr.String() + "/metrics")
+	if err != nil {
+		t.Fatalf("failed to scrape metrics: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		`helm_fuzz_iterations_total{chart="my-chart"} 2`,
+		`helm_fuzz_crashes_total{category="nil pointer",chart="my-chart"} 1`,
+		`helm_fuzz_unique_crashes_total{category="nil pointer",chart="my-chart"} 1`,
+		`helm_fuzz_render_duration_seconds_count{chart="my-chart"} 1`,
+		`helm_fuzz_workers{chart="my-chart"} 4`,
+		`helm_fuzz_workers_busy{chart="my-chart"} 0`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("expected metrics to contain %q", want)
+		}
+	}
+}
+
+func TestStart_InvalidAddress(t *testing.T) {
+	if _, err := New("my-chart", 1).Start(context.Background(), "not-an-address"); err == nil {
+		t.Error("expected error for invalid address")
+	}
+}
+
+func TestRegistry_MultipleCharts(t *testing.T) {
+	r := NewRegistry()
+	r.Chart("app", 1).RecordIteration("")
+	r.Chart("db", 2).RecordIteration("parse error")
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	for _, want := range []string{
+		`helm_fuzz_iterations_total{chart="app"} 1`,
+		`helm_fuzz_iterations_total{chart="db"} 1`,
+		`helm_fuzz_crashes_total{category="parse error",chart="db"} 1`,
+		`helm_fuzz_workers{chart="db"} 2`,
+	} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("expected metrics to contain %q", want)
+		}
+	}
+}
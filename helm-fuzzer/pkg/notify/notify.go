@@ -0,0 +1,210 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/config"
+	"github.com/kasuboski/helm-fuzzer/pkg/logging"
+)
+
+// requestTimeout bounds each webhook delivery so a slow endpoint cannot stall the session
+const requestTimeout = 10 * time.Second
+
+// maxReasonLength caps the reason included in Slack messages
+const maxReasonLength = 500
+
+// Finding is the payload sent for a new unique crash
+type Finding struct {
+	Chart       string `json:"chart"`
+	ID          string `json:"id"`
+	Iteration   int    `json:"iteration"`
+	Fingerprint string `json:"fingerprint"`
+	Category    string `json:"category"`
+	Reason      string `json:"reason"`
+	ReproFile   string `json:"reproFile,omitempty"`
+	// Flaky is set when replays of the input did not all reproduce the crash
+	Flaky bool `json:"flaky,omitempty"`
+}
+
+// Notifier posts findings to the configured webhooks, and runs the
+// configured command for them, in the background
+type Notifier struct {
+	webhooks []config.Webhook
+	command  []string
+	timeout  time.Duration
+	client   *http.Client
+	logger   *slog.Logger
+	wg       sync.WaitGroup
+}
+
+// Options configure a notifier
+type Options struct {
+	// Webhooks receive a POST per finding
+	Webhooks []config.Webhook
+	// Command is run per finding, with the finding in HELMFUZZ_FINDING_*
+	// environment variables and as the JSON payload on stdin; nil runs nothing
+	Command []string
+	// Timeout bounds each run of Command (default 30s)
+	Timeout time.Duration
+	// Logger receives delivery failures; nil discards them
+	Logger *slog.Logger
+}
+
+// New creates a notifier for the given webhooks; a nil logger discards
+// delivery failures
+func New(webhooks []config.Webhook, logger *slog.Logger) *Notifier {
+	return NewWithOptions(Options{Webhooks: webhooks, Logger: logger})
+}
+
+// NewWithOptions creates a notifier with the given options
+func NewWithOptions(opts Options) *Notifier {
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = config.DefaultCrashCommandTimeout
+	}
+	return &Notifier{
+		webhooks: opts.Webhooks,
+		command:  opts.Command,
+		timeout:  timeout,
+		client:   &http.Client{Timeout: requestTimeout},
+		logger:   logging.OrDiscard(opts.Logger),
+	}
+}
+
+// Notify delivers a finding to every webhook and runs the command for it
+// without blocking the caller. Failures are logged as warnings and never
+// stop the session.
+func (n *Notifier) Notify(f Finding) {
+	for _, hook := range n.webhooks {
+		n.wg.Add(1)
+		go func(hook config.Webhook) {
+			defer n.wg.Done()
+			if err := n.send(context.Background(), hook, f); err != nil {
+				n.logger.Warn("webhook delivery failed", "error", err)
+			}
+		}(hook)
+	}
+	if len(n.command) > 0 {
+		n.wg.Add(1)
+		go func() {
+			defer n.wg.Done()
+			if err := n.run(context.Background(), f); err != nil {
+				n.logger.Warn("crash command failed", "id", f.ID, "error", err)
+			}
+		}()
+	}
+}
+
+// Close waits for pending deliveries to finish
+func (n *Notifier) Close() {
+	n.wg.Wait()
+}
+
+// send posts one finding to one webhook
+func (n *Notifier) send(ctx context.Context, hook config.Webhook, f Finding) error {
+	body, err := payload(hook.Format, f)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// run runs the command for one finding
+func (n *Notifier) run(ctx context.Context, f Finding) error {
+	input, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, n.timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, n.command[0], n.command[1:]...)
+	// Children of a killed command may hold its output open; stop waiting for them
+	cmd.WaitDelay = time.Second
+	cmd.Env = append(os.Environ(), environment(f)...)
+	cmd.Stdin = bytes.NewReader(input)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("timed out after %s", n.timeout)
+		}
+		lines := strings.Split(strings.TrimSpace(stderr.String()), "\n")
+		if msg := strings.TrimSpace(lines[len(lines)-1]); msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+		return err
+	}
+	return nil
+}
+
+// environment returns the HELMFUZZ_FINDING_* variables describing a finding.
+// The reproduction file is made absolute, so the command may run anywhere.
+func environment(f Finding) []string {
+	reproFile := f.ReproFile
+	if reproFile != "" {
+		if abs, err := filepath.Abs(reproFile); err == nil {
+			reproFile = abs
+		}
+	}
+	return []string{
+		"HELMFUZZ_FINDING_CHART=" + f.Chart,
+		"HELMFUZZ_FINDING_ID=" + f.ID,
+		"HELMFUZZ_FINDING_ITERATION=" + strconv.Itoa(f.Iteration),
+		"HELMFUZZ_FINDING_FINGERPRINT=" + f.Fingerprint,
+		"HELMFUZZ_FINDING_CATEGORY=" + f.Category,
+		"HELMFUZZ_FINDING_REASON=" + f.Reason,
+		"HELMFUZZ_FINDING_REPRO_FILE=" + reproFile,
+		"HELMFUZZ_FINDING_FLAKY=" + strconv.FormatBool(f.Flaky),
+	}
+}
+
+// payload encodes a finding in the webhook's format
+func payload(format string, f Finding) ([]byte, error) {
+	if format == "slack" {
+		reason := f.Reason
+		if len(reason) > maxReasonLength {
+			reason = reason[:maxReasonLength] + "..."
+		}
+		kind := f.Category
+		if f.Flaky {
+			kind = "flaky " + kind
+		}
+		text := fmt.Sprintf("*Helm Fuzz* found a new %s crash in `%s` at iteration %d\n*ID:* `%s`\n```%s```",
+			kind, f.Chart, f.Iteration, f.ID, reason)
+		if f.ReproFile != "" {
+			text += fmt.Sprintf("\n*Reproduction:* `%s`", f.ReproFile)
+		}
+		return json.Marshal(map[string]string{"text": text})
+	}
+	return json.Marshal(f)
+}
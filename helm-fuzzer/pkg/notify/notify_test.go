@@ -0,0 +1,119 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/config"
+)
+
+func TestNotify(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		bodies = map[string]string{}
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies[r.URL.Path] = string(body)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	n := New([]config.Webhook{
+		{URL: server.URL + "/json", Format: "json"},
+		{URL: server.URL + "/slack", Format: "slack"},
+	}, nil)
+
+	n.Notify(Finding{
+		Chart:       "my-chart",
+		ID:          "my-chart-FZ-01234567",
+		Iteration:   42,
+		Fingerprint: "0123456789abcdef0123",
+		Category:    "nil pointer",
+		Reason:      "template: my-chart/templates/deployment.yaml:8:3: nil pointer",
+		ReproFile:   "fuzzer-repro-0123.yaml",
+	})
+	n.Close()
+
+	var got Finding
+	if err := json.Unmarshal([]byte(bodies["/json"]), &got); err != nil {
+		t.Fatalf("invalid JSON payload %q: %v", bodies["/json"], err)
+	}
+	if got.Fingerprint != "0123456789abcdef0123" || got.ID != "my-chart-FZ-01234567" || got.Iteration != 42 || got.ReproFile != "fuzzer-repro-0123.yaml" {
+		t.Errorf("unexpected JSON payload: %+v", got)
+	}
+
+	var slack map[string]string
+	if err := json.Unmarshal([]byte(bodies["/slack"]), &slack); err != nil {
+		t.Fatalf("invalid Slack payload %q: %v", bodies["/slack"], err)
+	}
+	for _, want := range []string{"`my-chart`", "iteration 42", "`my-chart-FZ-01234567`", "deployment.yaml:8:3", "fuzzer-repro-0123.yaml"} {
+		if !strings.Contains(slack["text"], want) {
+			t.Errorf("Slack text missing %q:\n%s", want, slack["text"])
+		}
+	}
+}
+
+func TestNotifyFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	n := New([]config.Webhook{{URL: server.URL, Format: "json"}}, nil)
+	if err := n.send(context.Background(), n.webhooks[0], Finding{}); err == nil {
+		t.Error("expected error for non-2xx response")
+	}
+}
+
+func TestNotifyCommand(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+	n := NewWithOptions(Options{
+		Command: []string{"sh", "-c", `printf '%s\n%s\n%s\n' "$HELMFUZZ_FINDING_ID" "$HELMFUZZ_FINDING_REPRO_FILE" "$HELMFUZZ_FINDING_FLAKY" > "$0"; cat >> "$0"`, out},
+	})
+	n.Notify(Finding{
+		Chart:     "my-chart",
+		ID:        "my-chart-FZ-01234567",
+		Iteration: 42,
+		Category:  "nil pointer",
+		ReproFile: "fuzzer-repro-my-chart-FZ-01234567.yaml",
+	})
+	n.Close()
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.SplitN(string(data), "\n", 4)
+	if lines[0] != "my-chart-FZ-01234567" || !filepath.IsAbs(lines[1]) || lines[2] != "false" {
+		t.Errorf("unexpected environment: %q", lines[:3])
+	}
+	var got Finding
+	if err := json.Unmarshal([]byte(lines[3]), &got); err != nil || got.Iteration != 42 {
+		t.Errorf("expected the JSON payload on stdin, got %q: %v", lines[3], err)
+	}
+}
+
+func TestNotifyCommandFailure(t *testing.T) {
+	n := NewWithOptions(Options{Command: []string{"sh", "-c", "echo 'no ticket system' >&2; exit 3"}})
+	err := n.run(context.Background(), Finding{})
+	if err == nil || !strings.Contains(err.Error(), "no ticket system") {
+		t.Errorf("expected the command's error, got %v", err)
+	}
+
+	n = NewWithOptions(Options{Command: []string{"sleep", "5"}, Timeout: 50 * time.Millisecond})
+	if err := n.run(context.Background(), Finding{}); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("expected a timeout, got %v", err)
+	}
+}
@@ -0,0 +1,194 @@
+// Package plugin runs external oracles and generators. A plugin is any
+// program: it is started once per request, reads one JSON request from
+// stdin and writes one JSON response to stdout. Oracles are asked to check
+// every successful render and answer with findings; generators are asked
+// once per session for inputs, which are rendered after the seeds.
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/config"
+)
+
+// APIVersion versions the plugin protocol; bump it on breaking changes
+const APIVersion = "helmfuzz/v1"
+
+// Request kinds
+const (
+	KindCheck    = "CheckRequest"
+	KindGenerate = "GenerateRequest"
+)
+
+// ReasonPrefix starts the crash reason of every plugin finding
+const ReasonPrefix = "Plugin "
+
+// CheckRequest asks an oracle to check one render
+type CheckRequest struct {
+	APIVersion  string                 `json:"apiVersion"`
+	Kind        string                 `json:"kind"`
+	Chart       string                 `json:"chart"`
+	KubeVersion string                 `json:"kubeVersion"`
+	Values      map[string]interface{} `json:"values"`
+	// Manifest is the rendered release, hooks included
+	Manifest string `json:"manifest"`
+}
+
+// CheckResponse is an oracle's verdict; no findings means the render passed
+type CheckResponse struct {
+	Findings []Finding `json:"findings"`
+}
+
+// Finding is a problem an oracle found in a render
+type Finding struct {
+	// Reason describes the problem; findings with the same reason are duplicates
+	Reason string `json:"reason"`
+}
+
+// GenerateRequest asks a generator for inputs
+type GenerateRequest struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Chart      string `json:"chart"`
+	// ChartPath lets the generator read the chart, such as its values schema
+	ChartPath string `json:"chartPath"`
+	// Count is how many inputs are wanted; fewer or more are accepted
+	Count    int                    `json:"count"`
+	Defaults map[string]interface{} `json:"defaults,omitempty"`
+}
+
+// GenerateResponse holds a generator's inputs
+type GenerateResponse struct {
+	Inputs []map[string]interface{} `json:"inputs"`
+}
+
+// Plugin is a configured plugin ready to run
+type Plugin struct {
+	Name    string
+	Type    string
+	command []string
+	timeout time.Duration
+	inputs  int
+}
+
+// New prepares the plugins of cfg, resolving their commands for chartPath
+func New(cfg *config.Config, chartPath string) []*Plugin {
+	plugins := make([]*Plugin, 0, len(cfg.Plugins))
+	for _, p := range cfg.Plugins {
+		plugins = append(plugins, &Plugin{
+			Name:    p.Name,
+			Type:    p.Type,
+			command: cfg.ResolvePluginCommand(chartPath, p),
+			timeout: p.Timeout,
+			inputs:  p.Inputs,
+		})
+	}
+	return plugins
+}
+
+// OfType returns the plugins of one type
+func OfType(plugins []*Plugin, pluginType string) []*Plugin {
+	var matching []*Plugin
+	for _, p := range plugins {
+		if p.Type == pluginType {
+			matching = append(matching, p)
+		}
+	}
+	return matching
+}
+
+// Check asks an oracle about a render, returning the crash reasons of its
+// findings, each prefixed with the plugin's name
+func (p *Plugin) Check(ctx context.Context, req CheckRequest) ([]string, error) {
+	req.APIVersion, req.Kind = APIVersion, KindCheck
+	var resp CheckResponse
+	if err := p.call(ctx, req, &resp); err != nil {
+		return nil, err
+	}
+	reasons := make([]string, 0, len(resp.Findings))
+	for _, f := range resp.Findings {
+		if f.Reason == "" {
+			return nil, fmt.Errorf("plugin %s returned a finding without a reason", p.Name)
+		}
+		reasons = append(reasons, Reason(p.Name, f.Reason))
+	}
+	return reasons, nil
+}
+
+// CheckAll asks each oracle about a render, collecting the crash reasons of their findings
+func CheckAll(ctx context.Context, oracles []*Plugin, req CheckRequest) ([]string, error) {
+	var reasons []string
+	for _, p := range oracles {
+		found, err := p.Check(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		reasons = append(reasons, found...)
+	}
+	return reasons, nil
+}
+
+// Generate asks a generator for its inputs
+func (p *Plugin) Generate(ctx context.Context, req GenerateRequest) ([]map[string]interface{}, error) {
+	req.APIVersion, req.Kind, req.Count = APIVersion, KindGenerate, p.inputs
+	var resp GenerateResponse
+	if err := p.call(ctx, req, &resp); err != nil {
+		return nil, err
+	}
+	inputs := make([]map[string]interface{}, 0, len(resp.Inputs))
+	for _, input := range resp.Inputs {
+		if input == nil {
+			input = map[string]interface{}{}
+		}
+		inputs = append(inputs, input)
+	}
+	return inputs, nil
+}
+
+// Reason is the crash reason of a finding reported by the named plugin
+func Reason(name, reason string) string {
+	return ReasonPrefix + name + ": " + reason
+}
+
+// call runs the plugin with req on stdin and decodes its stdout into resp
+func (p *Plugin) call(ctx context.Context, req, resp interface{}) error {
+	input, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to encode request for plugin %s: %w", p.Name, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, p.command[0], p.command[1:]...)
+	// Children of a killed plugin may hold its output open; stop waiting for them
+	cmd.WaitDelay = time.Second
+	cmd.Stdin = bytes.NewReader(input)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("plugin %s timed out after %s", p.Name, p.timeout)
+		}
+		if msg := lastLine(stderr.String()); msg != "" {
+			return fmt.Errorf("plugin %s failed: %w: %s", p.Name, err, msg)
+		}
+		return fmt.Errorf("plugin %s failed: %w", p.Name, err)
+	}
+	if err := json.Unmarshal(stdout.Bytes(), resp); err != nil {
+		return fmt.Errorf("plugin %s wrote an invalid response: %w", p.Name, err)
+	}
+	return nil
+}
+
+// lastLine returns the last non-empty line of a plugin's stderr, usually its error
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
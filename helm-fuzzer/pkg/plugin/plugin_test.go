@@ -0,0 +1,86 @@
+package plugin
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/config"
+)
+
+// script writes a shell script plugin and returns its configuration
+func script(t *testing.T, name, pluginType, body string) config.Plugin {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins are shell scripts")
+	}
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return config.Plugin{Name: name, Type: pluginType, Command: []string{path}, Timeout: 5 * time.Second, Inputs: 3}
+}
+
+func TestCheck(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Plugins = []config.Plugin{
+		// Flags ConfigMaps, echoing the request kind to show it was read
+		script(t, "configmaps", config.PluginOracle, `
+req=$(cat)
+case "$req" in
+  *'"kind":"CheckRequest"'*'kind: ConfigMap'*) echo '{"findings": [{"reason": "ConfigMaps are not allowed"}]}' ;;
+  *) echo '{"findings": []}' ;;
+esac
+`),
+		script(t, "presets", config.PluginGenerator, `echo '{"inputs": [{"replicaCount": 0}, null]}'`),
+	}
+	plugins := New(cfg, t.TempDir())
+	oracles := OfType(plugins, config.PluginOracle)
+	if len(oracles) != 1 || oracles[0].Name != "configmaps" {
+		t.Fatalf("expected the oracle plugin, got %+v", oracles)
+	}
+
+	reasons, err := CheckAll(context.Background(), oracles, CheckRequest{Manifest: "kind: ConfigMap\n"})
+	if err != nil {
+		t.Fatalf("CheckAll failed: %v", err)
+	}
+	if len(reasons) != 1 || reasons[0] != "Plugin configmaps: ConfigMaps are not allowed" {
+		t.Errorf("expected the plugin's finding, got %v", reasons)
+	}
+	if reasons, err := CheckAll(context.Background(), oracles, CheckRequest{Manifest: "kind: Service\n"}); err != nil || len(reasons) != 0 {
+		t.Errorf("expected no findings, got %v, %v", reasons, err)
+	}
+
+	inputs, err := OfType(plugins, config.PluginGenerator)[0].Generate(context.Background(), GenerateRequest{})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if len(inputs) != 2 || inputs[0]["replicaCount"] != float64(0) || inputs[1] == nil {
+		t.Errorf("expected two inputs, the null one empty, got %v", inputs)
+	}
+}
+
+func TestCheck_Failures(t *testing.T) {
+	for _, tt := range []struct {
+		name, body, want string
+	}{
+		{"exit", "echo 'cannot read manifest' >&2\nexit 3\n", "cannot read manifest"},
+		{"garbage", "echo 'not json'\n", "invalid response"},
+		{"blank", `echo '{"findings": [{"reason": ""}]}'` + "\n", "without a reason"},
+		{"slow", "sleep 5\n", "timed out"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			p := script(t, tt.name, config.PluginOracle, tt.body)
+			p.Timeout = 200 * time.Millisecond
+			cfg := &config.Config{Plugins: []config.Plugin{p}}
+			_, err := New(cfg, "")[0].Check(context.Background(), CheckRequest{})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+// Package quantity parses Kubernetes resource quantities such as 500m, 2 or
+// 1.5Gi into numbers, precisely enough to add them up and compare them
+package quantity
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// suffixes are the SI and binary suffixes of quantities
+var suffixes = map[string]float64{
+	"":   1,
+	"m":  1e-3,
+	"k":  1e3,
+	"M":  1e6,
+	"G":  1e9,
+	"T":  1e12,
+	"P":  1e15,
+	"E":  1e18,
+	"Ki": 1 << 10,
+	"Mi": 1 << 20,
+	"Gi": 1 << 30,
+	"Ti": 1 << 40,
+	"Pi": 1 << 50,
+	"Ei": 1 << 60,
+}
+
+// Parse returns the value of a quantity, in cores for CPU and bytes for memory
+func Parse(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	i := len(s)
+	for i > 0 && isLetter(s[i-1]) {
+		i--
+	}
+	number, suffix := s[:i], s[i:]
+	multiplier, ok := suffixes[suffix]
+	if !ok || number == "" {
+		return 0, fmt.Errorf("invalid quantity %q", s)
+	}
+	// Decimal exponents, as in 1e3, stay with the number
+	v, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid quantity %q", s)
+	}
+	return v * multiplier, nil
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -0,0 +1,31 @@
+package quantity
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+	}{
+		{"2", 2},
+		{"500m", 0.5},
+		{"0.25", 0.25},
+		{"1.5Gi", 1.5 * (1 << 30)},
+		{"128Mi", 128 << 20},
+		{"1G", 1e9},
+		{"1e3", 1000},
+		{" 64Ki ", 64 << 10},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("Parse(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+
+	for _, invalid := range []string{"", "Gi", "1.5Gb", "lots", "1..5"} {
+		if _, err := Parse(invalid); err == nil {
+			t.Errorf("expected an error for %q", invalid)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package report
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Run is a report.json loaded from a session's output directory
+type Run struct {
+	// Name identifies the run, derived from the path it was loaded from
+	Name   string
+	Report *JSONReport
+}
+
+// LoadRuns loads the report.json of every output directory in paths. A path
+// may be a report.json file, a directory containing one, or a directory of
+// per-chart subdirectories as written when fuzzing several charts at once.
+// Repro file paths are rebased onto the directory the report was found in,
+// since artifacts are usually downloaded somewhere other than where they
+// were written.
+func LoadRuns(paths []string) ([]Run, error) {
+	var runs []Run
+	for _, path := range paths {
+		files, err := findReports(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			r, err := ReadJSON(file)
+			if err != nil {
+				return nil, err
+			}
+			rebaseRepros(r, filepath.Dir(file))
+			runs = append(runs, Run{Name: filepath.Dir(file), Report: r})
+		}
+	}
+	return runs, nil
+}
+
+// findReports returns the report.json files at path
+func findReports(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	direct := filepath.Join(path, JSONFileName)
+	if _, err := os.Stat(direct); err == nil {
+		return []string{direct}, nil
+	}
+	nested, err := filepath.Glob(filepath.Join(path, "*", JSONFileName))
+	if err != nil {
+		return nil, err
+	}
+	if len(nested) == 0 {
+		return nil, fmt.Errorf("no %s found in %s", JSONFileName, path)
+	}
+	sort.Strings(nested)
+	return nested, nil
+}
+
+// rebaseRepros points repro files at dir when they were moved there with the report
+func rebaseRepros(r *JSONReport, dir string) {
+	for i := range r.Findings {
+		f := &r.Findings[i]
+		if f.ReproFile == "" {
+			continue
+		}
+		candidate := filepath.Join(dir, filepath.Base(f.ReproFile))
+		if _, err := os.Stat(candidate); err == nil {
+			f.ReproFile = candidate
+		}
+	}
+}
+
+// Combined is several runs merged for one report, with findings
+// deduplicated by fingerprint across runs
+type Combined struct {
+	Runs     []Run
+	Findings []CombinedFinding
+
+	Iterations int
+	Crashes    int
+	Duration   time.Duration
+}
+
+// CombinedFinding is a unique crash and the runs it was found in, flaky
+// only if every run found it flaky
+type CombinedFinding struct {
+	JSONFinding
+	Chart string
+	// Runs names every run that found the crash, in load order
+	Runs []string
+}
+
+// Combine merges runs, keeping findings in the order they were first seen
+func Combine(runs []Run) *Combined {
+	c := &Combined{Runs: runs}
+	index := make(map[string]int)
+	for _, run := range runs {
+		r := run.Report
+		c.Iterations += r.Stats.Iterations
+		c.Crashes += r.Stats.Crashes
+		c.Duration += seconds(r.Duration)
+
+		for _, f := range r.Findings {
+			key := r.Chart + "/" + f.Fingerprint
+			if i, ok := index[key]; ok {
+				c.Findings[i].Runs = append(c.Findings[i].Runs, run.Name)
+				// A crash any run reproduced reliably is not flaky
+				c.Findings[i].Flaky = c.Findings[i].Flaky && f.Flaky
+				continue
+			}
+			index[key] = len(c.Findings)
+			c.Findings = append(c.Findings, CombinedFinding{JSONFinding: f, Chart: r.Chart, Runs: []string{run.Name}})
+		}
+	}
+	return c
+}
+
+// CombinedFormats lists the formats the report command can write
+var CombinedFormats = []string{"html", "junit", "markdown"}
+
+// WriteCombinedFile renders combined runs to the file named by spec
+func WriteCombinedFile(spec Spec, c *Combined) error {
+	f, err := create(spec.Path)
+	if err != nil {
+		return err
+	}
+
+	switch spec.Format {
+	case "html":
+		err = WriteCombinedHTML(f, c)
+	case "junit":
+		err = WriteJUnit(f, c)
+	case "markdown":
+		err = WriteCombinedMarkdown(f, c)
+	default:
+		err = fmt.Errorf("unsupported report format %q", spec.Format)
+	}
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write %s report: %w", spec.Format, err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,74 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// WriteAnnotations prints GitHub Actions workflow commands so findings show up
+// inline on the pull request. chartDir is the chart directory relative to the
+// repository root, used to turn template names into repository paths.
+//
+// Template errors become ::error annotations on the failing line. YAML parse
+// errors only know the line in the rendered manifest, so they annotate the file
+// without a line. Findings with no template location become ::warning
+// annotations, as do flaky findings, which do not fail the session.
+func WriteAnnotations(w io.Writer, s *Session, chartDir string) error {
+	for _, f := range s.Findings {
+		message := fmt.Sprintf("%s crash at iteration %d: %s", f.Category, f.Iteration, f.Reason)
+		if f.Flaky {
+			message = fmt.Sprintf("flaky %s crash at iteration %d, reproduced by %d/%d replays: %s", f.Category, f.Iteration, f.Reproduced, f.Replays, f.Reason)
+		}
+		if f.ID != "" {
+			message = f.ID + ": " + message
+		}
+		attr := f.Attribution
+		if attr != nil && attr.Rendered {
+			message += fmt.Sprintf("\n(line %d of the rendered manifest)", attr.Line)
+		}
+		if f.ReproFile != "" {
+			message += fmt.Sprintf("\nReproduce with: helm install --dry-run <chart> -f %s", f.ReproFile)
+		}
+
+		if attr == nil {
+			if _, err := fmt.Fprintf(w, "::warning title=%s::%s\n", escapeProperty("Helm Fuzz: "+f.Category), escapeData(message)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		props := []string{"file=" + escapeProperty(path.Join(chartDir, attr.File()))}
+		if !attr.Rendered && attr.Line > 0 {
+			props = append(props, fmt.Sprintf("line=%d", attr.Line))
+			if attr.Column > 0 {
+				props = append(props, fmt.Sprintf("col=%d", attr.Column))
+			}
+		}
+		props = append(props, "title="+escapeProperty("Helm Fuzz: "+f.Category))
+
+		command := "error"
+		if f.Flaky {
+			command = "warning"
+		}
+		if _, err := fmt.Fprintf(w, "::%s %s::%s\n", command, strings.Join(props, ","), escapeData(message)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// escapeData escapes a workflow command message
+func escapeData(s string) string {
+	s = strings.ReplaceAll(s, "%", "%25")
+	s = strings.ReplaceAll(s, "\r", "%0D")
+	return strings.ReplaceAll(s, "\n", "%0A")
+}
+
+// escapeProperty escapes a workflow command property value
+func escapeProperty(s string) string {
+	s = escapeData(s)
+	s = strings.ReplaceAll(s, ":", "%3A")
+	return strings.ReplaceAll(s, ",", "%2C")
+}
@@ -0,0 +1,291 @@
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Chart dimensions for the inline SVG graphs
+const (
+	chartWidth  = 800
+	chartHeight = 160
+	chartMargin = 30
+)
+
+// WriteHTML renders the session as a self-contained HTML page with inline SVG charts
+func WriteHTML(w io.Writer, s *Session) error {
+	tmpl, err := template.New("report").Funcs(template.FuncMap{
+		"duration":  formatDuration,
+		"progress":  progressOf,
+		"yaml":      toYAML,
+		"ratio":     ratio,
+		"join":      strings.Join,
+		"ratePath":  func() string { return ratePath(s.Rate) },
+		"rateMax":   func() int { return maxInt(s.Rate) },
+		"crashX":    func(f Finding) float64 { return timelineX(f.Elapsed, s.Duration) },
+		"timestamp": func(t time.Time) string { return t.Format(time.RFC1123) },
+		"plotRight": func() int { return chartWidth - chartMargin },
+		"plotBase":  func() int { return chartHeight - chartMargin },
+		"style":     func() template.CSS { return htmlStyle },
+	}).Parse(htmlTemplate)
+	if err != nil {
+		return err
+	}
+
+	return tmpl.Execute(w, s)
+}
+
+// ratePath converts per-second iteration counts into SVG polyline points
+func ratePath(rate []int) string {
+	if len(rate) == 0 {
+		return ""
+	}
+
+	max := maxInt(rate)
+	plotW := float64(chartWidth - 2*chartMargin)
+	plotH := float64(chartHeight - 2*chartMargin)
+
+	points := make([]string, len(rate))
+	for i, n := range rate {
+		x := float64(chartMargin)
+		if len(rate) > 1 {
+			x += plotW * float64(i) / float64(len(rate)-1)
+		}
+		y := float64(chartHeight - chartMargin)
+		if max > 0 {
+			y -= plotH * float64(n) / float64(max)
+		}
+		points[i] = fmt.Sprintf("%.1f,%.1f", x, y)
+	}
+	return strings.Join(points, " ")
+}
+
+// timelineX positions a crash on the timeline by elapsed time
+func timelineX(elapsed, total time.Duration) float64 {
+	plotW := float64(chartWidth - 2*chartMargin)
+	if total <= 0 {
+		return chartMargin
+	}
+	return chartMargin + plotW*float64(elapsed)/float64(total)
+}
+
+func maxInt(values []int) int {
+	max := 0
+	for _, v := range values {
+		if v > max {
+			max = v
+		}
+	}
+	return max
+}
+
+// toYAML renders values for display, falling back to Go formatting
+func toYAML(values map[string]interface{}) string {
+	if len(values) == 0 {
+		return "{}"
+	}
+	data, err := yaml.Marshal(values)
+	if err != nil {
+		return fmt.Sprintf("%v", values)
+	}
+	return string(data)
+}
+
+// progressOf formats iterations against the target, or alone for sessions
+// bounded only by time
+func progressOf(iterations, maxIterations int) string {
+	if maxIterations <= 0 {
+		return fmt.Sprint(iterations)
+	}
+	return fmt.Sprintf("%d / %d", iterations, maxIterations)
+}
+
+// formatDuration formats a duration for display
+func formatDuration(d time.Duration) string {
+	if d < time.Minute {
+		return fmt.Sprintf("%.1fs", d.Seconds())
+	}
+	if d < time.Hour {
+		return fmt.Sprintf("%dm%ds", int(d.Minutes()), int(d.Seconds())%60)
+	}
+	return fmt.Sprintf("%dh%dm", int(d.Hours()), int(d.Minutes())%60)
+}
+
+// WriteCombinedHTML renders several runs as one self-contained HTML page
+func WriteCombinedHTML(w io.Writer, c *Combined) error {
+	tmpl, err := template.New("combined").Funcs(template.FuncMap{
+		"duration": formatDuration,
+		"progress": progressOf,
+		"seconds":  seconds,
+		"ratio":    ratio,
+		"yaml":     toYAML,
+		"join":     strings.Join,
+		"style":    func() template.CSS { return htmlStyle },
+	}).Parse(combinedHTMLTemplate)
+	if err != nil {
+		return err
+	}
+
+	return tmpl.Execute(w, c)
+}
+
+// htmlStyle is shared by the session and combined reports
+const htmlStyle = `body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em auto; max-width: 900px; color: #24292f; }
+h1 { font-size: 1.6em; }
+h2 { font-size: 1.2em; border-bottom: 1px solid #d0d7de; padding-bottom: .3em; margin-top: 2em; }
+table { border-collapse: collapse; }
+td, th { padding: .3em .8em; text-align: left; border-bottom: 1px solid #eaeef2; }
+.stats td:first-child { color: #57606a; }
+.crash { color: #cf222e; font-weight: bold; }
+.flaky { color: #9a6700; font-weight: bold; }
+pre { background: #f6f8fa; padding: .8em; overflow-x: auto; font-size: .85em; }
+details { margin: .5em 0 1.5em; }
+summary { cursor: pointer; }
+.finding { border: 1px solid #d0d7de; border-radius: 6px; padding: .5em 1em; margin: 1em 0; }
+.meta { color: #57606a; font-size: .9em; }
+svg text { font-size: 11px; fill: #57606a; }
+`
+
+const htmlTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Helm Fuzz Report - {{.Chart}}</title>
+<style>
+{{style}}</style>
+</head>
+<body>
+<h1>🔍 Helm Fuzz Report: {{.Chart}}</h1>
+<p class="meta">Started {{timestamp .StartTime}}</p>
+
+<h2>Summary</h2>
+<table class="stats">
+<tr><td>Iterations</td><td>{{progress .Iterations .MaxIterations}}</td></tr>
+<tr><td>Crashes</td><td class="{{if .Crashes}}crash{{end}}">{{.Crashes}}</td></tr>
+<tr><td>Unique findings</td><td>{{len .Findings}}</td></tr>
+{{- with .FlakyCount}}
+<tr><td>Flaky findings</td><td class="flaky">{{.}}</td></tr>
+{{- end}}
+<tr><td>Duration</td><td>{{duration .Duration}}</td></tr>
+{{- with .Coverage}}
+<tr><td>Value paths set</td><td>{{ratio .PathsSet .Paths}}</td></tr>
+<tr><td>Enum values chosen</td><td>{{ratio .EnumValuesChosen .EnumValues}}</td></tr>
+<tr><td>Templates rendered</td><td>{{ratio .TemplatesRendered .Templates}}</td></tr>
+{{- end}}
+{{- range .Categories}}
+<tr><td>{{.Name}}</td><td>{{.Count}}</td></tr>
+{{- end}}
+</table>
+
+<h2>Iteration Rate</h2>
+<svg width="800" height="160" viewBox="0 0 800 160" role="img" aria-label="Iterations per second">
+<line x1="30" y1="{{plotBase}}" x2="{{plotRight}}" y2="{{plotBase}}" stroke="#d0d7de"/>
+<line x1="30" y1="30" x2="30" y2="{{plotBase}}" stroke="#d0d7de"/>
+<text x="0" y="34">{{rateMax}}/s</text>
+<text x="{{plotRight}}" y="150" text-anchor="end">{{duration .Duration}}</text>
+<polyline fill="none" stroke="#0969da" stroke-width="2" points="{{ratePath}}"/>
+</svg>
+
+<h2>Crash Timeline</h2>
+<svg width="800" height="60" viewBox="0 0 800 60" role="img" aria-label="Crash timeline">
+<line x1="30" y1="30" x2="{{plotRight}}" y2="30" stroke="#d0d7de" stroke-width="2"/>
+<text x="30" y="55">0s</text>
+<text x="{{plotRight}}" y="55" text-anchor="end">{{duration .Duration}}</text>
+{{- range .Findings}}
+<circle cx="{{printf "%.1f" (crashX .)}}" cy="30" r="6" fill="#cf222e"><title>#{{.Iteration}} {{.Category}} at {{duration .Elapsed}}</title></circle>
+{{- end}}
+</svg>
+
+<h2>Findings</h2>
+{{- if not .Findings}}
+<p>🎉 No crashes found.</p>
+{{- end}}
+{{- range $i, $f := .Findings}}
+<div class="finding">
+<h3>{{$f.Category}} at iteration {{$f.Iteration}} <code>{{$f.ID}}</code>{{if $f.Flaky}} <span class="flaky">flaky</span>{{end}}</h3>
+<p class="meta">
+Found after {{duration $f.Elapsed}}
+{{- if $f.Location}} · <code>{{$f.Location}}</code>{{end}}
+{{- if $f.ValuePath}} · <code>{{$f.ValuePath}}</code>{{end}}
+{{- if $f.ReproFile}} · repro <code>{{$f.ReproFile}}</code>{{end}}
+{{- if $f.BuiltIns}} · rendered as {{$f.BuiltIns}}{{end}}
+{{- if $f.Replays}} · reproduced by {{$f.Reproduced}}/{{$f.Replays}} replays{{end}}
+{{- if $f.Culprits}} · needs <code>{{join $f.Culprits ", "}}</code>{{end}}
+{{- if $f.Tags}} · tags <code>{{$f.TagSet}}</code>{{end}}
+</p>
+<pre>{{$f.Reason}}</pre>
+{{- if $f.Snippet}}
+<details open><summary>Snippet</summary><pre>{{$f.Snippet}}</pre></details>
+{{- end}}
+{{- if $f.MinimalValues}}
+<details open><summary>Minimized values</summary><pre>{{yaml $f.MinimalValues}}</pre></details>
+{{- end}}
+<details><summary>Values</summary><pre>{{yaml $f.Values}}</pre></details>
+</div>
+{{- end}}
+</body>
+</html>
+`
+
+const combinedHTMLTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Helm Fuzz Report - {{len .Runs}} runs</title>
+<style>
+{{style}}</style>
+</head>
+<body>
+<h1>🔍 Helm Fuzz Report: {{len .Runs}} runs</h1>
+<p class="meta">{{.Iterations}} iterations, {{.Crashes}} crashes, {{len .Findings}} unique findings in {{duration .Duration}}</p>
+
+<h2>Runs</h2>
+<table>
+<tr><th>Run</th><th>Chart</th><th>Iterations</th><th>Crashes</th><th>Unique</th><th>Coverage</th><th>Duration</th></tr>
+{{- range .Runs}}
+<tr>
+<td><code>{{.Name}}</code></td>
+<td>{{.Report.Chart}}</td>
+<td>{{progress .Report.Stats.Iterations .Report.Stats.MaxIterations}}</td>
+<td class="{{if .Report.Stats.Crashes}}crash{{end}}">{{.Report.Stats.Crashes}}</td>
+<td>{{.Report.Stats.UniqueCrashes}}</td>
+<td>{{with .Report.Coverage}}{{ratio .PathsSet .Paths}} paths, {{ratio .TemplatesRendered .Templates}} templates{{end}}</td>
+<td>{{duration (seconds .Report.Duration)}}</td>
+</tr>
+{{- end}}
+</table>
+
+<h2>Findings</h2>
+{{- if not .Findings}}
+<p>🎉 No crashes found.</p>
+{{- end}}
+{{- range .Findings}}
+<div class="finding">
+<h3>{{.Category}} in {{.Chart}} <code>{{.ID}}</code>{{if .Flaky}} <span class="flaky">flaky</span>{{end}}</h3>
+<p class="meta">
+Found in {{len .Runs}} run(s)
+{{- if .Location}} · <code>{{.Location}}</code>{{end}}
+{{- if .ValuePath}} · <code>{{.ValuePath}}</code>{{end}}
+{{- if .ReproFile}} · repro <code>{{.ReproFile}}</code>{{end}}
+{{- if .Replays}} · reproduced by {{.Reproduced}}/{{.Replays}} replays{{end}}
+{{- if .Culprits}} · needs <code>{{join .Culprits ", "}}</code>{{end}}
+</p>
+<pre>{{.Reason}}</pre>
+<details><summary>Runs</summary><ul>{{range .Runs}}<li><code>{{.}}</code></li>{{end}}</ul></details>
+{{- if .Snippet}}
+<details open><summary>Snippet</summary><pre>{{.Snippet}}</pre></details>
+{{- end}}
+{{- if .MinimalValues}}
+<details open><summary>Minimized values</summary><pre>{{yaml .MinimalValues}}</pre></details>
+{{- end}}
+<details><summary>Values</summary><pre>{{yaml .Values}}</pre></details>
+</div>
+{{- end}}
+</body>
+</html>
+`
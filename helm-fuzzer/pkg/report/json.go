@@ -0,0 +1,218 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/coverage"
+	"github.com/kasuboski/helm-fuzzer/pkg/runner"
+)
+
+const (
+	// JSONAPIVersion versions the report.json format; bump it on breaking changes
+	JSONAPIVersion = "helmfuzz/v1"
+	// JSONKind identifies a report.json document
+	JSONKind = "Report"
+	// JSONFileName is the report written to the output directory after every session
+	JSONFileName = "report.json"
+)
+
+// JSONReport is the machine-readable session report. It is the contract for
+// tooling that consumes fuzzing results, so fields are only ever added.
+type JSONReport struct {
+	APIVersion  string                 `json:"apiVersion"`
+	Kind        string                 `json:"kind"`
+	ToolVersion string                 `json:"toolVersion,omitempty"`
+	Chart       string                 `json:"chart"`
+	ChartPath   string                 `json:"chartPath,omitempty"`
+	StartTime   time.Time              `json:"startTime"`
+	Duration    float64                `json:"durationSeconds"`
+	Config      map[string]interface{} `json:"config,omitempty"`
+	Stats       JSONStats              `json:"stats"`
+	Coverage    *coverage.Summary      `json:"coverage,omitempty"`
+	Findings    []JSONFinding          `json:"findings"`
+	Files       JSONFiles              `json:"files"`
+}
+
+// JSONStats summarizes the session
+type JSONStats struct {
+	MaxIterations int `json:"maxIterations"`
+	Iterations    int `json:"iterations"`
+	Crashes       int `json:"crashes"`
+	UniqueCrashes int `json:"uniqueCrashes"`
+	// FlakyCrashes counts the unique crashes some replays did not reproduce
+	FlakyCrashes int             `json:"flakyCrashes,omitempty"`
+	Categories   []CategoryCount `json:"categories"`
+	// Rate holds the number of iterations completed in each second of the session
+	Rate []int `json:"rate"`
+}
+
+// JSONFinding is a unique crash in report.json
+type JSONFinding struct {
+	ID          string                 `json:"id"`
+	Fingerprint string                 `json:"fingerprint"`
+	Iteration   int                    `json:"iteration"`
+	Elapsed     float64                `json:"elapsedSeconds"`
+	Category    string                 `json:"category"`
+	Reason      string                 `json:"reason"`
+	Template    string                 `json:"template,omitempty"`
+	Subchart    string                 `json:"subchart,omitempty"`
+	Line        int                    `json:"line,omitempty"`
+	Column      int                    `json:"column,omitempty"`
+	ValuePath   string                 `json:"valuePath,omitempty"`
+	Rendered    bool                   `json:"rendered,omitempty"`
+	Snippet     string                 `json:"snippet,omitempty"`
+	ReproFile   string                 `json:"reproFile,omitempty"`
+	Values      map[string]interface{} `json:"values"`
+	BuiltIns    *runner.BuiltIns       `json:"builtIns,omitempty"`
+	Replays     int                    `json:"replays,omitempty"`
+	Reproduced  int                    `json:"reproduced,omitempty"`
+	Flaky       bool                   `json:"flaky,omitempty"`
+	Culprits    []string               `json:"culprits,omitempty"`
+	// MinimalValues are the values ablated to the culprits, absent when the
+	// crash was not ablated
+	MinimalValues map[string]interface{} `json:"minimalValues,omitempty"`
+	Tags          map[string]bool        `json:"tags,omitempty"`
+}
+
+// Location returns the attributed template location (file:line[:column]), empty if unknown
+func (f JSONFinding) Location() string {
+	if f.Template == "" {
+		return ""
+	}
+	attr := runner.Attribution{Template: f.Template, Line: f.Line, Column: f.Column}
+	return attr.String()
+}
+
+// JSONFiles lists the files written by the session
+type JSONFiles struct {
+	SessionLog string   `json:"sessionLog,omitempty"`
+	Reports    []string `json:"reports,omitempty"`
+}
+
+// NewJSONReport converts a session to its report.json form
+func NewJSONReport(s *Session) (*JSONReport, error) {
+	categories := s.Categories()
+	if categories == nil {
+		categories = []CategoryCount{}
+	}
+	rate := s.Rate
+	if rate == nil {
+		rate = []int{}
+	}
+
+	r := &JSONReport{
+		APIVersion:  JSONAPIVersion,
+		Kind:        JSONKind,
+		ToolVersion: s.ToolVersion,
+		Chart:       s.Chart,
+		ChartPath:   s.ChartPath,
+		StartTime:   s.StartTime,
+		Duration:    s.Duration.Seconds(),
+		Stats: JSONStats{
+			MaxIterations: s.MaxIterations,
+			Iterations:    s.Iterations,
+			Crashes:       s.Crashes,
+			UniqueCrashes: len(s.Findings),
+			FlakyCrashes:  s.FlakyCount(),
+			Categories:    categories,
+			Rate:          rate,
+		},
+		Coverage: s.Coverage,
+		Findings: make([]JSONFinding, 0, len(s.Findings)),
+		Files: JSONFiles{
+			SessionLog: s.Files.SessionLog,
+			Reports:    s.Files.Reports,
+		},
+	}
+
+	if s.Config != nil {
+		// Round-trip through YAML so keys match .helmfuzz.yaml; report.json
+		// is uploaded and shared, so expanded secrets are left out
+		data, err := yaml.Marshal(s.Config.Redacted())
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode config: %w", err)
+		}
+		if err := yaml.Unmarshal(data, &r.Config); err != nil {
+			return nil, fmt.Errorf("failed to encode config: %w", err)
+		}
+	}
+
+	for _, f := range s.Findings {
+		jf := JSONFinding{
+			ID:            f.ID,
+			Fingerprint:   f.Fingerprint,
+			Iteration:     f.Iteration,
+			Elapsed:       f.Elapsed.Seconds(),
+			Category:      f.Category,
+			Reason:        f.Reason,
+			Snippet:       f.Snippet,
+			ReproFile:     f.ReproFile,
+			Values:        f.Values,
+			BuiltIns:      f.BuiltIns,
+			Replays:       f.Replays,
+			Reproduced:    f.Reproduced,
+			Flaky:         f.Flaky,
+			Culprits:      f.Culprits,
+			MinimalValues: f.MinimalValues,
+			Tags:          f.Tags,
+		}
+		if attr := f.Attribution; attr != nil {
+			jf.Template = attr.File()
+			jf.Subchart = attr.Subchart()
+			jf.Line = attr.Line
+			jf.Column = attr.Column
+			jf.ValuePath = attr.ValuePath
+			jf.Rendered = attr.Rendered
+		}
+		r.Findings = append(r.Findings, jf)
+	}
+
+	return r, nil
+}
+
+// WriteJSON writes the session as an indented report.json document
+func WriteJSON(w io.Writer, s *Session) error {
+	r, err := NewJSONReport(s)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// ReadJSON loads a report.json file, rejecting documents from other tools or
+// newer format versions. Findings of reports written before findings had IDs
+// are given theirs.
+func ReadJSON(path string) (*JSONReport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read report: %w", err)
+	}
+
+	var r JSONReport
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("failed to parse report %s: %w", path, err)
+	}
+	if r.Kind != JSONKind || r.APIVersion != JSONAPIVersion {
+		return nil, fmt.Errorf("unsupported report %s: apiVersion %q kind %q (expected %s %s)", path, r.APIVersion, r.Kind, JSONAPIVersion, JSONKind)
+	}
+	for i, f := range r.Findings {
+		if f.ID == "" {
+			r.Findings[i].ID = runner.FindingID(r.Chart, f.Fingerprint)
+		}
+	}
+	return &r, nil
+}
+
+// seconds converts a report duration in seconds back to a time.Duration
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,101 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// JUnit XML elements, limited to what CI systems display
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Name     string       `xml:"name,attr"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Skipped  int          `xml:"skipped,attr"`
+	Time     float64      `xml:"time,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      float64         `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr,omitempty"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Body    string `xml:",cdata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+// WriteJUnit renders runs as JUnit XML: one suite per run and one failing
+// test case per unique crash, skipped instead for flaky crashes, or a single
+// passing case for a clean run
+func WriteJUnit(w io.Writer, c *Combined) error {
+	suites := junitSuites{Name: "helm-fuzz", Time: c.Duration.Seconds()}
+	for _, run := range c.Runs {
+		r := run.Report
+		suite := junitSuite{
+			Name:      run.Name,
+			Time:      r.Duration,
+			Timestamp: r.StartTime.Format("2006-01-02T15:04:05"),
+		}
+		for _, f := range r.Findings {
+			body := f.Reason
+			if f.ReproFile != "" {
+				body += "\n\nReproduce with: helm template " + r.Chart + " -f " + f.ReproFile
+			}
+			tc := junitTestCase{
+				Name:      fmt.Sprintf("%s %s", f.ID, f.Category),
+				ClassName: r.Chart,
+			}
+			if f.Flaky {
+				tc.Skipped = &junitSkipped{Message: fmt.Sprintf("flaky: reproduced by %d/%d replays: %s", f.Reproduced, f.Replays, firstLine(f.Reason))}
+				suite.Skipped++
+			} else {
+				tc.Failure = &junitFailure{Message: firstLine(f.Reason), Type: f.Category, Body: body}
+				suite.Failures++
+			}
+			suite.Cases = append(suite.Cases, tc)
+		}
+		if len(suite.Cases) == 0 {
+			suite.Cases = append(suite.Cases, junitTestCase{
+				Name:      fmt.Sprintf("fuzz %d iterations", r.Stats.Iterations),
+				ClassName: r.Chart,
+			})
+		}
+		suite.Tests = len(suite.Cases)
+
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Skipped += suite.Skipped
+		suites.Suites = append(suites.Suites, suite)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
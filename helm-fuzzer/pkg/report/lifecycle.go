@@ -0,0 +1,152 @@
+package report
+
+import (
+	"sort"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/runner"
+)
+
+// LifecycleKind identifies a lifecycle document
+const LifecycleKind = "Lifecycle"
+
+// Lifecycle is how the findings of later runs compare to those of earlier
+// ones, such as this release's sessions against the last release's. Like
+// report.json it is a contract for dashboards and release gates, so fields
+// are only ever added.
+type Lifecycle struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	// New are findings of the later runs the earlier ones did not find
+	New []LifecycleFinding `json:"new"`
+	// Persisting are findings both found, as the later runs found them
+	Persisting []LifecycleFinding `json:"persisting"`
+	// Resolved are findings of the earlier runs the later ones did not find
+	Resolved []LifecycleFinding `json:"resolved"`
+	// Unfuzzed are charts only the earlier runs fuzzed; their findings are
+	// left out rather than counted as resolved
+	Unfuzzed []string       `json:"unfuzzed,omitempty"`
+	Trend    LifecycleTrend `json:"trend"`
+}
+
+// LifecycleFinding is a unique crash of a chart, identified by its finding ID
+type LifecycleFinding struct {
+	ID          string `json:"id"`
+	Chart       string `json:"chart"`
+	Fingerprint string `json:"fingerprint"`
+	Category    string `json:"category"`
+	Location    string `json:"location,omitempty"`
+	Reason      string `json:"reason"`
+	Flaky       bool   `json:"flaky,omitempty"`
+}
+
+// LifecycleTrend counts the unique findings before and after
+type LifecycleTrend struct {
+	Before     int `json:"before"`
+	After      int `json:"after"`
+	New        int `json:"new"`
+	Persisting int `json:"persisting"`
+	Resolved   int `json:"resolved"`
+	// Change is After minus Before
+	Change     int             `json:"change"`
+	Categories []CategoryTrend `json:"categories"`
+}
+
+// CategoryTrend counts the unique findings of a category before and after
+type CategoryTrend struct {
+	Name   string `json:"name"`
+	Before int    `json:"before"`
+	After  int    `json:"after"`
+}
+
+// CompareRuns sorts the findings of the later runs into new and persisting
+// and lists those of the earlier runs that were not found again, each once
+// per chart and fingerprint, sorted by ID. Fingerprints and categories are
+// recomputed from the reasons, so reports of older versions compare under
+// the current deduplication rules.
+func CompareRuns(before, after []Run) *Lifecycle {
+	l := &Lifecycle{
+		APIVersion: JSONAPIVersion,
+		Kind:       LifecycleKind,
+		New:        []LifecycleFinding{},
+		Persisting: []LifecycleFinding{},
+		Resolved:   []LifecycleFinding{},
+	}
+	earlier, later := uniqueFindings(before), uniqueFindings(after)
+	fuzzed := make(map[string]bool)
+	for _, run := range after {
+		fuzzed[run.Report.Chart] = true
+	}
+
+	categories := make(map[string]*CategoryTrend)
+	category := func(name string) *CategoryTrend {
+		if categories[name] == nil {
+			categories[name] = &CategoryTrend{Name: name}
+		}
+		return categories[name]
+	}
+	for key, f := range later {
+		if _, ok := earlier[key]; ok {
+			l.Persisting = append(l.Persisting, f)
+		} else {
+			l.New = append(l.New, f)
+		}
+		category(f.Category).After++
+	}
+	unfuzzed := make(map[string]bool)
+	for key, f := range earlier {
+		if !fuzzed[f.Chart] {
+			unfuzzed[f.Chart] = true
+			continue
+		}
+		if _, ok := later[key]; !ok {
+			l.Resolved = append(l.Resolved, f)
+		}
+		category(f.Category).Before++
+	}
+	for chart := range unfuzzed {
+		l.Unfuzzed = append(l.Unfuzzed, chart)
+	}
+	sort.Strings(l.Unfuzzed)
+	for _, findings := range [][]LifecycleFinding{l.New, l.Persisting, l.Resolved} {
+		sort.Slice(findings, func(i, j int) bool { return findings[i].ID < findings[j].ID })
+	}
+
+	t := &l.Trend
+	t.New, t.Persisting, t.Resolved = len(l.New), len(l.Persisting), len(l.Resolved)
+	t.Before, t.After = t.Persisting+t.Resolved, t.New+t.Persisting
+	t.Change = t.After - t.Before
+	t.Categories = make([]CategoryTrend, 0, len(categories))
+	for _, c := range categories {
+		t.Categories = append(t.Categories, *c)
+	}
+	sort.Slice(t.Categories, func(i, j int) bool { return t.Categories[i].Name < t.Categories[j].Name })
+	return l
+}
+
+// uniqueFindings returns the findings of runs by chart and fingerprint,
+// keeping the first of each; a crash any run reproduced reliably is not flaky
+func uniqueFindings(runs []Run) map[string]LifecycleFinding {
+	findings := make(map[string]LifecycleFinding)
+	for _, run := range runs {
+		chart := run.Report.Chart
+		for _, f := range run.Report.Findings {
+			fingerprint := runner.Fingerprint(f.Reason)
+			key := chart + "/" + fingerprint
+			if existing, ok := findings[key]; ok {
+				existing.Flaky = existing.Flaky && f.Flaky
+				findings[key] = existing
+				continue
+			}
+			findings[key] = LifecycleFinding{
+				ID:          runner.FindingID(chart, fingerprint),
+				Chart:       chart,
+				Fingerprint: fingerprint,
+				Category:    runner.CategorizeReason(f.Reason),
+				Location:    f.Location(),
+				Reason:      f.Reason,
+				Flaky:       f.Flaky,
+			}
+		}
+	}
+	return findings
+}
@@ -0,0 +1,182 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/coverage"
+)
+
+// maxReasonLength caps crash reasons in markdown tables to keep rows readable
+const maxReasonLength = 120
+
+// WriteMarkdown renders a compact summary suitable for $GITHUB_STEP_SUMMARY or MR comments
+func WriteMarkdown(w io.Writer, s *Session) error {
+	var b strings.Builder
+
+	flaky := s.FlakyCount()
+	status := "✅ No crashes found"
+	if n := len(s.Findings) - flaky; n > 0 {
+		status = fmt.Sprintf("💥 %d unique crash(es) found", n)
+	}
+	if flaky > 0 {
+		status += fmt.Sprintf(", ⚠️ %d flaky crash(es) not reproduced by every replay", flaky)
+	}
+	fmt.Fprintf(&b, "## 🔍 Helm Fuzz: %s\n\n%s\n\n", s.Chart, status)
+
+	b.WriteString("| Iterations | Crashes | Unique | Duration |\n")
+	b.WriteString("|-----------:|--------:|-------:|---------:|\n")
+	fmt.Fprintf(&b, "| %s | %d | %d | %s |\n", progressOf(s.Iterations, s.MaxIterations), s.Crashes, len(s.Findings), formatDuration(s.Duration))
+
+	if c := s.Coverage; c != nil {
+		fmt.Fprintf(&b, "\n**Coverage:** %s value paths set, %s enum values chosen, %s templates rendered\n",
+			ratio(c.PathsSet, c.Paths), ratio(c.EnumValuesChosen, c.EnumValues), ratio(c.TemplatesRendered, c.Templates))
+	}
+
+	if len(s.Findings) > flaky {
+		b.WriteString("\n| ID | Iteration | Category | Location | Reason | Repro |\n")
+		b.WriteString("|----|----------:|----------|----------|--------|-------|\n")
+		for _, f := range s.Findings {
+			if f.Flaky {
+				continue
+			}
+			fmt.Fprintf(&b, "| %s | %d | %s | %s | %s | %s |\n",
+				code(f.ID),
+				f.Iteration,
+				escapeCell(f.Category),
+				code(f.Location()),
+				escapeCell(truncate(firstLine(f.Reason), maxReasonLength)),
+				code(f.ReproFile))
+		}
+	}
+	if flaky > 0 {
+		b.WriteString("\n### ⚠️ Flaky crashes\n\n")
+		b.WriteString("| ID | Iteration | Category | Reproduced | Reason | Repro |\n")
+		b.WriteString("|----|----------:|----------|-----------:|--------|-------|\n")
+		for _, f := range s.Findings {
+			if !f.Flaky {
+				continue
+			}
+			fmt.Fprintf(&b, "| %s | %d | %s | %d/%d | %s | %s |\n",
+				code(f.ID),
+				f.Iteration,
+				escapeCell(f.Category),
+				f.Reproduced, f.Replays,
+				escapeCell(truncate(firstLine(f.Reason), maxReasonLength)),
+				code(f.ReproFile))
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteCombinedMarkdown renders a summary of several runs with their
+// findings deduplicated across runs
+func WriteCombinedMarkdown(w io.Writer, c *Combined) error {
+	var b strings.Builder
+
+	flaky := 0
+	for _, f := range c.Findings {
+		if f.Flaky {
+			flaky++
+		}
+	}
+	status := fmt.Sprintf("✅ No crashes found in %d run(s)", len(c.Runs))
+	if n := len(c.Findings) - flaky; n > 0 {
+		status = fmt.Sprintf("💥 %d unique crash(es) found across %d run(s)", n, len(c.Runs))
+	}
+	if flaky > 0 {
+		status += fmt.Sprintf(", ⚠️ %d flaky crash(es) not reproduced by every replay", flaky)
+	}
+	fmt.Fprintf(&b, "## 🔍 Helm Fuzz: %d run(s)\n\n%s\n\n", len(c.Runs), status)
+
+	b.WriteString("| Run | Chart | Iterations | Crashes | Unique | Paths | Templates | Duration |\n")
+	b.WriteString("|-----|-------|-----------:|--------:|-------:|------:|----------:|---------:|\n")
+	for _, run := range c.Runs {
+		r := run.Report
+		paths, templates := "", ""
+		if cov := r.Coverage; cov != nil {
+			paths = fmt.Sprintf("%.0f%%", coverage.Percent(cov.PathsSet, cov.Paths))
+			templates = fmt.Sprintf("%.0f%%", coverage.Percent(cov.TemplatesRendered, cov.Templates))
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %d | %d | %s | %s | %s |\n",
+			code(run.Name), escapeCell(r.Chart), progressOf(r.Stats.Iterations, r.Stats.MaxIterations),
+			r.Stats.Crashes, r.Stats.UniqueCrashes, paths, templates, formatDuration(seconds(r.Duration)))
+	}
+	fmt.Fprintf(&b, "| **Total** | | %d | %d | %d | | | %s |\n", c.Iterations, c.Crashes, len(c.Findings), formatDuration(c.Duration))
+
+	if len(c.Findings) > flaky {
+		b.WriteString("\n| ID | Category | Location | Reason | Runs | Repro |\n")
+		b.WriteString("|----|----------|----------|--------|-----:|-------|\n")
+		for _, f := range c.Findings {
+			if f.Flaky {
+				continue
+			}
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %d | %s |\n",
+				code(f.ID),
+				escapeCell(f.Category),
+				code(f.Location()),
+				escapeCell(truncate(firstLine(f.Reason), maxReasonLength)),
+				len(f.Runs),
+				code(f.ReproFile))
+		}
+	}
+	if flaky > 0 {
+		b.WriteString("\n### ⚠️ Flaky crashes\n\n")
+		b.WriteString("| ID | Category | Reproduced | Reason | Runs | Repro |\n")
+		b.WriteString("|----|----------|-----------:|--------|-----:|-------|\n")
+		for _, f := range c.Findings {
+			if !f.Flaky {
+				continue
+			}
+			fmt.Fprintf(&b, "| %s | %s | %d/%d | %s | %d | %s |\n",
+				code(f.ID),
+				escapeCell(f.Category),
+				f.Reproduced, f.Replays,
+				escapeCell(truncate(firstLine(f.Reason), maxReasonLength)),
+				len(f.Runs),
+				code(f.ReproFile))
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// ratio formats part/total with a percentage
+func ratio(part, total int) string {
+	return fmt.Sprintf("%d/%d (%.0f%%)", part, total, coverage.Percent(part, total))
+}
+
+// escapeCell makes text safe to place in a markdown table cell
+func escapeCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.ReplaceAll(s, "\n", " ")
+}
+
+// code wraps non-empty text in backticks for a table cell
+func code(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "`" + strings.ReplaceAll(escapeCell(s), "`", "'") + "`"
+}
+
+// truncate shortens s to at most n runes, marking the cut with an ellipsis
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
+
+// firstLine returns the first line of a multi-line string
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
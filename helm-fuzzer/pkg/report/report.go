@@ -0,0 +1,326 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/config"
+	"github.com/kasuboski/helm-fuzzer/pkg/coverage"
+	"github.com/kasuboski/helm-fuzzer/pkg/runner"
+)
+
+// Session is the data collected during a fuzzing session for reporting
+type Session struct {
+	Chart         string
+	MaxIterations int
+	StartTime     time.Time
+	Duration      time.Duration
+	Iterations    int
+	Crashes       int
+	// Rate holds the number of iterations completed in each second of the session
+	Rate     []int
+	Findings []Finding
+	// Coverage is what the session exercised, nil if not tracked
+	Coverage *coverage.Summary
+
+	// ChartPath, ToolVersion, Config and Files are filled in by the caller
+	// once the session ends; reports omit whatever is unset
+	ChartPath   string
+	ToolVersion string
+	Config      *config.Config
+	Files       Files
+}
+
+// Files lists the files a session wrote besides reproductions
+type Files struct {
+	SessionLog string
+	Reports    []string
+}
+
+// Finding is a unique crash found during the session. It encodes to JSON
+// with the field names of report.json findings, the attribution nested and
+// the elapsed time in seconds; the JSON form is a contract for tooling, so
+// fields are only ever added.
+type Finding struct {
+	// Fingerprint identifies the crash across sessions (see runner.Fingerprint)
+	Fingerprint string `json:"fingerprint"`
+	// ID is the stable, human-readable name of the crash in its chart, for
+	// issue trackers (see runner.FindingID)
+	ID        string `json:"id"`
+	Iteration int    `json:"iteration"`
+	// Elapsed is the time since the session started when the crash was found
+	Elapsed   time.Duration          `json:"-"`
+	Category  string                 `json:"category"`
+	Reason    string                 `json:"reason"`
+	ReproFile string                 `json:"reproFile,omitempty"`
+	Values    map[string]interface{} `json:"values"`
+	// Attribution is the template location of the crash, nil if unknown
+	Attribution *runner.Attribution `json:"attribution,omitempty"`
+	// Snippet is the template source or rendered output around the attributed line
+	Snippet string `json:"snippet,omitempty"`
+	// BuiltIns are the built-in objects the crash rendered with, nil when
+	// they were not fuzzed
+	BuiltIns *runner.BuiltIns `json:"builtIns,omitempty"`
+	// Replays is how many times the crash was replayed before it was
+	// reported and Reproduced how many of them crashed the same way, both
+	// zero when crashes are not replayed
+	Replays    int `json:"replays,omitempty"`
+	Reproduced int `json:"reproduced,omitempty"`
+	// Flaky is set when some replays did not reproduce the crash, which then
+	// does not fail the session
+	Flaky bool `json:"flaky,omitempty"`
+	// Culprits are the value paths the crash needs, found by ablating its
+	// input; nil when it was not ablated
+	Culprits []string `json:"culprits,omitempty"`
+	// MinimalValues are the values ablated to the culprits, the smallest
+	// that still crash the same way; nil when not ablated
+	MinimalValues map[string]interface{} `json:"minimalValues,omitempty"`
+	// Tags are the dependency tags the crash rendered with, nil unless
+	// their combinations were fuzzed
+	Tags map[string]bool `json:"tags,omitempty"`
+}
+
+// finding has the fields of Finding without its JSON methods
+type finding Finding
+
+// MarshalJSON encodes the finding with its elapsed time in seconds
+func (f Finding) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		finding
+		Elapsed float64 `json:"elapsedSeconds"`
+	}{finding(f), f.Elapsed.Seconds()})
+}
+
+// UnmarshalJSON decodes a finding MarshalJSON encoded
+func (f *Finding) UnmarshalJSON(data []byte) error {
+	var in struct {
+		finding
+		Elapsed float64 `json:"elapsedSeconds"`
+	}
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	*f = Finding(in.finding)
+	f.Elapsed = seconds(in.Elapsed)
+	return nil
+}
+
+// Location returns the attributed template location (file:line[:column]), empty if unknown
+func (f Finding) Location() string {
+	if f.Attribution == nil {
+		return ""
+	}
+	return f.Attribution.String()
+}
+
+// ValuePath returns the attributed value expression, empty if unknown
+func (f Finding) ValuePath() string {
+	if f.Attribution == nil {
+		return ""
+	}
+	return f.Attribution.ValuePath
+}
+
+// TagSet returns the dependency tags the crash rendered with as tag=bool
+// pairs sorted by tag, empty if unknown
+func (f Finding) TagSet() string {
+	tags := make([]string, 0, len(f.Tags))
+	for tag := range f.Tags {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	for i, tag := range tags {
+		tags[i] = fmt.Sprintf("%s=%t", tag, f.Tags[tag])
+	}
+	return strings.Join(tags, ", ")
+}
+
+// Recorder collects session data while fuzzing
+type Recorder struct {
+	mu      sync.Mutex
+	session Session
+}
+
+// NewRecorder creates a recorder for a session starting now
+func NewRecorder(chartName string, maxIterations int) *Recorder {
+	return &Recorder{
+		session: Session{
+			Chart:         chartName,
+			MaxIterations: maxIterations,
+			StartTime:     time.Now(),
+		},
+	}
+}
+
+// RecordIteration records a completed iteration
+func (r *Recorder) RecordIteration(crashed bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.session.Iterations++
+	if crashed {
+		r.session.Crashes++
+	}
+
+	second := int(time.Since(r.session.StartTime) / time.Second)
+	for len(r.session.Rate) <= second {
+		r.session.Rate = append(r.session.Rate, 0)
+	}
+	r.session.Rate[second]++
+}
+
+// RecordFinding records a unique crash, stamping it with the elapsed time,
+// its fingerprint and its ID
+func (r *Recorder) RecordFinding(f Finding) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if f.Fingerprint == "" {
+		f.Fingerprint = runner.Fingerprint(f.Reason)
+	}
+	if f.ID == "" {
+		f.ID = runner.FindingID(r.session.Chart, f.Fingerprint)
+	}
+	f.Elapsed = time.Since(r.session.StartTime)
+	r.session.Findings = append(r.session.Findings, f)
+}
+
+// Session returns a snapshot of the recorded session
+func (r *Recorder) Session() *Session {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s := r.session
+	s.Duration = time.Since(s.StartTime)
+	s.Rate = append([]int(nil), s.Rate...)
+	s.Findings = append([]Finding(nil), s.Findings...)
+	return &s
+}
+
+// FlakyCount returns the number of findings some replays did not reproduce
+func (s *Session) FlakyCount() int {
+	n := 0
+	for _, f := range s.Findings {
+		if f.Flaky {
+			n++
+		}
+	}
+	return n
+}
+
+// Categories returns the number of findings per category, sorted by name
+func (s *Session) Categories() []CategoryCount {
+	counts := make(map[string]int)
+	for _, f := range s.Findings {
+		counts[f.Category]++
+	}
+
+	result := make([]CategoryCount, 0, len(counts))
+	for name, count := range counts {
+		result = append(result, CategoryCount{Name: name, Count: count})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// CategoryCount is the number of findings in a crash category
+type CategoryCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// Spec is a requested report in format=path form
+type Spec struct {
+	Format string
+	Path   string
+}
+
+// Formats lists the supported report formats
+var Formats = []string{"html", "json", "junit", "markdown"}
+
+// ParseSpec parses a --report argument such as html=report.html
+func ParseSpec(arg string) (Spec, error) {
+	return parseSpec(arg, Formats)
+}
+
+// ParseCombinedSpec parses a report argument for combined runs
+func ParseCombinedSpec(arg string) (Spec, error) {
+	return parseSpec(arg, CombinedFormats)
+}
+
+// parseSpec parses a format=path argument, accepting only the given formats
+func parseSpec(arg string, formats []string) (Spec, error) {
+	format, path, ok := strings.Cut(arg, "=")
+	if !ok || format == "" || path == "" {
+		return Spec{}, fmt.Errorf("invalid report %q: expected format=path", arg)
+	}
+
+	for _, f := range formats {
+		if f == format {
+			return Spec{Format: format, Path: path}, nil
+		}
+	}
+	return Spec{}, fmt.Errorf("unsupported report format %q (supported: %s)", format, strings.Join(formats, ", "))
+}
+
+// Write renders the session in the given format
+func Write(w io.Writer, format string, s *Session) error {
+	switch format {
+	case "html":
+		return WriteHTML(w, s)
+	case "json":
+		return WriteJSON(w, s)
+	case "junit":
+		r, err := NewJSONReport(s)
+		if err != nil {
+			return err
+		}
+		return WriteJUnit(w, Combine([]Run{{Name: s.Chart, Report: r}}))
+	case "markdown":
+		return WriteMarkdown(w, s)
+	default:
+		return fmt.Errorf("unsupported report format %q", format)
+	}
+}
+
+// WriteFile renders the session to the file named by spec
+func WriteFile(spec Spec, s *Session) error {
+	f, err := create(spec.Path)
+	if err != nil {
+		return err
+	}
+
+	if err := Write(f, spec.Format, s); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write %s report: %w", spec.Format, err)
+	}
+	return f.Close()
+}
+
+// create opens a report file for writing, creating its directory. The GitHub
+// Actions step summary file is appended to rather than replaced.
+func create(path string) (*os.File, error) {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create report directory: %w", err)
+		}
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if summary := os.Getenv("GITHUB_STEP_SUMMARY"); summary != "" && summary == path {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+
+	f, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create report: %w", err)
+	}
+	return f, nil
+}
@@ -0,0 +1,98 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// StateFileName is the name of the session state file in an output directory
+const StateFileName = "session-state.json"
+
+// State is the progress of an unfinished session, saved so that a later
+// run can resume it. Inputs are derived from the session seed and their
+// iteration index, so Seed and NextIteration are all a resumed session
+// needs to continue drawing where this one stopped.
+type State struct {
+	Chart string `json:"chart"`
+	// NextIteration is the lowest iteration index not yet completed; every
+	// index below it has been rendered
+	NextIteration int `json:"nextIteration"`
+	// Seed is the session seed inputs are derived from (see config.Config.Seed)
+	Seed       uint64 `json:"seed,omitempty"`
+	Iterations int    `json:"iterations"`
+	Crashes    int    `json:"crashes"`
+	// Elapsed is the fuzzing time spent across all runs of the session
+	Elapsed time.Duration `json:"elapsed"`
+	Rate    []int         `json:"rate"`
+	// Findings are the unique crashes so far; their fingerprints restore the
+	// deduplication cache
+	Findings []Finding `json:"findings"`
+	SavedAt  time.Time `json:"savedAt"`
+}
+
+// State captures the recorded session for resuming at nextIteration
+func (r *Recorder) State(nextIteration int) *State {
+	s := r.Session()
+	return &State{
+		Chart:         s.Chart,
+		NextIteration: nextIteration,
+		Iterations:    s.Iterations,
+		Crashes:       s.Crashes,
+		Elapsed:       s.Duration,
+		Rate:          s.Rate,
+		Findings:      s.Findings,
+		SavedAt:       time.Now(),
+	}
+}
+
+// Restore continues recording from a saved state. The start time is moved
+// back by the time already spent so durations and rates carry on from it.
+func (r *Recorder) Restore(st *State) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.session.StartTime = time.Now().Add(-st.Elapsed)
+	r.session.Iterations = st.Iterations
+	r.session.Crashes = st.Crashes
+	r.session.Rate = append([]int(nil), st.Rate...)
+	r.session.Findings = append([]Finding(nil), st.Findings...)
+}
+
+// SaveState writes the state to path, replacing any previous state
+// atomically so an interrupted write never leaves a truncated file
+func SaveState(path string, st *State) error {
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode session state: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write session state: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write session state: %w", err)
+	}
+	return nil
+}
+
+// LoadState reads a state saved by SaveState. The error wraps
+// os.ErrNotExist when there is no state to resume.
+func LoadState(path string) (*State, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session state: %w", err)
+	}
+
+	var st State
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, fmt.Errorf("failed to parse session state %s: %w", path, err)
+	}
+	return &st, nil
+}
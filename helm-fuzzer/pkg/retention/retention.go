@@ -0,0 +1,215 @@
+// Package retention bounds the artifacts that sessions writing to the same
+// output directory accumulate, so nightly jobs on persistent runners do not
+// fill their disks: reproduction files, with the rendered output saved beside
+// them, and session logs. Reports, session state and corpus entries are
+// rewritten or curated rather than accumulated, and are neither counted nor
+// removed, like anything else in the directory.
+package retention
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/runner"
+	"github.com/kasuboski/helm-fuzzer/pkg/tui"
+)
+
+// Policy bounds the artifacts of an output directory; zero fields impose no
+// bound
+type Policy struct {
+	// MaxReproFiles keeps this many reproduction files, the most recently written
+	MaxReproFiles int
+	// MaxBytes caps the total size of the directory's artifacts, kept ones
+	// included; the oldest are removed until they fit, or none are left.
+	// Reports, state, the corpus and unrelated files are not counted.
+	MaxBytes int64
+	// MaxAge removes artifacts not written for longer
+	MaxAge time.Duration
+}
+
+// Result is what applying a policy removed
+type Result struct {
+	// Removed are the paths of the removed files
+	Removed []string
+	// Freed is the bytes the removed files took
+	Freed int64
+	// Size is the total size of the directory's artifacts afterwards
+	Size int64
+}
+
+// artifact is a reproduction file with the output saved beside it, or a
+// file alone, removed together
+type artifact struct {
+	paths   []string
+	size    int64
+	modTime time.Time
+	repro   bool
+}
+
+// Apply removes the artifacts of dir that the policy does not retain, as of
+// now: first those older than its maximum age, then reproduction files
+// beyond its maximum count, oldest first, then the oldest artifacts of any
+// kind until they fit its maximum size. Files in keep, such as those a
+// session just reported, are never removed. A missing directory has nothing
+// to remove.
+func Apply(dir string, policy Policy, now time.Time, keep ...string) (Result, error) {
+	var result Result
+	artifacts, size, err := scan(dir, keep)
+	if err != nil {
+		return result, err
+	}
+
+	remove := func(a artifact) error {
+		for _, path := range a.paths {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove %s: %w", path, err)
+			}
+			result.Removed = append(result.Removed, path)
+		}
+		result.Freed += a.size
+		size -= a.size
+		return nil
+	}
+
+	// Artifacts are oldest first, so what is kept is always the newest
+	var retained []artifact
+	for _, a := range artifacts {
+		if policy.MaxAge > 0 && now.Sub(a.modTime) > policy.MaxAge {
+			if err := remove(a); err != nil {
+				return result, err
+			}
+			continue
+		}
+		retained = append(retained, a)
+	}
+	if policy.MaxReproFiles > 0 {
+		repros := 0
+		for _, a := range retained {
+			if a.repro {
+				repros++
+			}
+		}
+		kept := retained[:0]
+		for _, a := range retained {
+			if a.repro && repros > policy.MaxReproFiles {
+				repros--
+				if err := remove(a); err != nil {
+					return result, err
+				}
+				continue
+			}
+			kept = append(kept, a)
+		}
+		retained = kept
+	}
+	for i := 0; policy.MaxBytes > 0 && size > policy.MaxBytes && i < len(retained); i++ {
+		if err := remove(retained[i]); err != nil {
+			return result, err
+		}
+	}
+
+	result.Size = size
+	return result, nil
+}
+
+// scan returns the artifacts of dir not in keep, oldest first, and the total
+// size of its artifacts, kept or not. Only the files directly in dir that
+// sessions write are counted, as the output directory may be the working
+// directory with the chart and everything else in it.
+func scan(dir string, keep []string) ([]artifact, int64, error) {
+	kept := make(map[string]bool, len(keep))
+	for _, path := range keep {
+		path = filepath.Clean(path)
+		kept[path] = true
+		if ok, _ := filepath.Match(runner.ReproPattern, filepath.Base(path)); ok {
+			kept[outputPath(path)] = true
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to scan %s: %w", dir, err)
+	}
+	var size int64
+	files := make(map[string]fs.FileInfo)
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || !managed(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan %s: %w", dir, err)
+		}
+		size += info.Size()
+		if !kept[filepath.Join(dir, entry.Name())] {
+			files[entry.Name()] = info
+		}
+	}
+
+	var artifacts []artifact
+	add := func(repro bool, names ...string) {
+		a := artifact{repro: repro}
+		for _, name := range names {
+			info := files[name]
+			delete(files, name)
+			a.paths = append(a.paths, filepath.Join(dir, name))
+			a.size += info.Size()
+			if info.ModTime().After(a.modTime) {
+				a.modTime = info.ModTime()
+			}
+		}
+		artifacts = append(artifacts, a)
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if ok, _ := filepath.Match(runner.ReproPattern, name); !ok {
+			continue
+		}
+		if output := outputPath(name); files[output] != nil {
+			add(true, name, output)
+		} else {
+			add(true, name)
+		}
+	}
+	for _, name := range names {
+		if _, ok := files[name]; !ok {
+			continue
+		}
+		add(false, name)
+	}
+
+	sort.SliceStable(artifacts, func(i, j int) bool {
+		return artifacts[i].modTime.Before(artifacts[j].modTime)
+	})
+	return artifacts, size, nil
+}
+
+// managed reports whether a file is an artifact sessions write: a
+// reproduction file, a saved output or a session log
+func managed(name string) bool {
+	for _, pattern := range []string{runner.ReproPattern, runner.OutputPattern, tui.SessionLogPattern} {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// outputPath returns the path of the output saved beside a reproduction
+// file, which shares its name
+func outputPath(repro string) string {
+	dir, name := filepath.Split(repro)
+	return dir + "fuzzer-output-" + strings.TrimPrefix(name, "fuzzer-repro-")
+}
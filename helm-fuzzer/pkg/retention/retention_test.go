@@ -0,0 +1,153 @@
+package retention
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestApply(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	unrelated := []string{
+		".git/objects/pack/pack-1.pack",
+		"Chart.yaml",
+		"corpus/seed.yaml",
+		"report.json",
+		"templates/fuzzer-repro-notes.yaml",
+	}
+	setup := func(t *testing.T) string {
+		t.Helper()
+		dir := t.TempDir()
+		write := func(name string, size int, age time.Duration) {
+			path := filepath.Join(dir, name)
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte(strings.Repeat("x", size)), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chtimes(path, now.Add(-age), now.Add(-age)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		write("fuzzer-repro-app-FZ-00000001.yaml", 100, 10*24*time.Hour)
+		write("fuzzer-output-app-FZ-00000001.yaml", 400, 10*24*time.Hour)
+		write("fuzzer-repro-app-FZ-00000002.yaml", 100, 3*24*time.Hour)
+		write("fuzzer-repro-app-FZ-00000003.yaml", 100, time.Hour)
+		write("helm-fuzz-app-20261001-020000.log", 200, 15*24*time.Hour)
+		write("helm-fuzz-app-20261016-020000.log", 200, 10*time.Hour)
+		// The output directory may be the working directory, holding
+		// files that are neither removed nor counted towards its size
+		for _, name := range unrelated {
+			write(name, 1000, 30*24*time.Hour)
+		}
+		return dir
+	}
+	remaining := func(t *testing.T, dir string) []string {
+		t.Helper()
+		var names []string
+		filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+			if err == nil && !d.IsDir() {
+				rel, _ := filepath.Rel(dir, path)
+				names = append(names, rel)
+			}
+			return nil
+		})
+		sort.Strings(names)
+		return names
+	}
+
+	tests := []struct {
+		name   string
+		policy Policy
+		keep   []string
+		want   []string
+	}{
+		{
+			name:   "max age",
+			policy: Policy{MaxAge: 7 * 24 * time.Hour},
+			want: []string{
+				"fuzzer-repro-app-FZ-00000002.yaml",
+				"fuzzer-repro-app-FZ-00000003.yaml",
+				"helm-fuzz-app-20261016-020000.log",
+			},
+		},
+		{
+			name:   "max repro files",
+			policy: Policy{MaxReproFiles: 1},
+			want: []string{
+				"fuzzer-repro-app-FZ-00000003.yaml",
+				"helm-fuzz-app-20261001-020000.log",
+				"helm-fuzz-app-20261016-020000.log",
+			},
+		},
+		{
+			name:   "max size",
+			policy: Policy{MaxBytes: 600},
+			want: []string{
+				"fuzzer-repro-app-FZ-00000002.yaml",
+				"fuzzer-repro-app-FZ-00000003.yaml",
+				"helm-fuzz-app-20261016-020000.log",
+			},
+		},
+		{
+			name:   "kept files",
+			policy: Policy{MaxReproFiles: 1, MaxAge: 7 * 24 * time.Hour},
+			keep:   []string{"fuzzer-repro-app-FZ-00000001.yaml"},
+			want: []string{
+				"fuzzer-output-app-FZ-00000001.yaml",
+				"fuzzer-repro-app-FZ-00000001.yaml",
+				"fuzzer-repro-app-FZ-00000003.yaml",
+				"helm-fuzz-app-20261016-020000.log",
+			},
+		},
+		{
+			name: "no policy",
+			want: []string{
+				"fuzzer-output-app-FZ-00000001.yaml",
+				"fuzzer-repro-app-FZ-00000001.yaml",
+				"fuzzer-repro-app-FZ-00000002.yaml",
+				"fuzzer-repro-app-FZ-00000003.yaml",
+				"helm-fuzz-app-20261001-020000.log",
+				"helm-fuzz-app-20261016-020000.log",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := setup(t)
+			var keep []string
+			for _, name := range tt.keep {
+				keep = append(keep, filepath.Join(dir, name))
+			}
+			result, err := Apply(dir, tt.policy, now, keep...)
+			if err != nil {
+				t.Fatalf("Apply failed: %v", err)
+			}
+			want := append(append([]string{}, tt.want...), unrelated...)
+			sort.Strings(want)
+			if got := remaining(t, dir); !reflect.DeepEqual(got, want) {
+				t.Errorf("remaining files = %v, want %v", got, want)
+			}
+			var size int64
+			for _, name := range tt.want {
+				info, err := os.Stat(filepath.Join(dir, name))
+				if err != nil {
+					t.Fatal(err)
+				}
+				size += info.Size()
+			}
+			if result.Size != size || result.Freed != 1100-size {
+				t.Errorf("size %d and freed %d, want %d and %d", result.Size, result.Freed, size, 1100-size)
+			}
+		})
+	}
+
+	if _, err := Apply(filepath.Join(t.TempDir(), "missing"), Policy{MaxAge: time.Hour}, now); err != nil {
+		t.Errorf("expected a missing directory to have nothing to remove, got %v", err)
+	}
+}
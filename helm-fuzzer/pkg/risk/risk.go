@@ -0,0 +1,389 @@
+// Package risk finds template constructs that fail on unexpected values
+// without rendering anything: field access and index into maps that may be
+// unset, printf and number conversions of values, and toYaml of optional
+// blocks. Scoring the values paths that feed them lets generation vary those
+// paths more often.
+package risk
+
+import (
+	"path"
+	"sort"
+	"strings"
+	"text/template/parse"
+
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+// Construct kinds
+const (
+	// KindNilIndex reads a key of a map no enclosing if or with checks is
+	// set, failing with a nil pointer when it is not
+	KindNilIndex = "nil index"
+	// KindPrintf formats a value with printf, whose verbs expect a type
+	KindPrintf = "printf"
+	// KindToYaml renders a value no enclosing if or with checks is set with
+	// toYaml, printing null into the manifest when it is not
+	KindToYaml = "toYaml"
+	// KindConversion converts a value with int, int64, float64 or atoi
+	KindConversion = "int conversion"
+)
+
+// conversions are the functions converting values to numbers
+var conversions = map[string]bool{"int": true, "int64": true, "float64": true, "atoi": true}
+
+// Construct is one risky use of a value in a template
+type Construct struct {
+	// Template is the file, named as in rendered manifests
+	Template string `json:"template"`
+	Line     int    `json:"line"`
+	Kind     string `json:"kind"`
+	// Path is the dotted values path the construct reads, relative to the
+	// top-level chart's values; for a nil index, the map it reads from
+	Path string `json:"path"`
+	// Source is the action or pipeline the construct is in
+	Source string `json:"source"`
+}
+
+// Score is how risky the constructs reading one values path make it
+type Score struct {
+	Path string `json:"path"`
+	// Score counts the constructs reading the path
+	Score      int         `json:"score"`
+	Constructs []Construct `json:"constructs"`
+}
+
+// Nilable reports whether a template reads from the path's map without
+// checking it is set
+func (s Score) Nilable() bool {
+	for _, c := range s.Constructs {
+		if c.Kind == KindNilIndex {
+			return true
+		}
+	}
+	return false
+}
+
+// Analyze finds the risky constructs in the templates of c and its
+// subcharts, whose values paths are prefixed with the subchart's alias or
+// name. Templates that do not parse are skipped.
+func Analyze(c *chart.Chart) []Construct {
+	var constructs []Construct
+	// Each kind of construct counts once per path and line
+	seen := make(map[Construct]bool)
+	walk(c, "", "", func(name, prefix string, src string) {
+		for _, found := range analyzeTemplate(name, prefix, src) {
+			key := found
+			key.Source = ""
+			if !seen[key] {
+				seen[key] = true
+				constructs = append(constructs, found)
+			}
+		}
+	})
+	return constructs
+}
+
+// Scores groups constructs by values path, riskiest first
+func Scores(constructs []Construct) []Score {
+	byPath := make(map[string]*Score)
+	var scores []*Score
+	for _, c := range constructs {
+		s, ok := byPath[c.Path]
+		if !ok {
+			s = &Score{Path: c.Path}
+			byPath[c.Path] = s
+			scores = append(scores, s)
+		}
+		s.Score++
+		s.Constructs = append(s.Constructs, c)
+	}
+	sort.SliceStable(scores, func(i, j int) bool {
+		if scores[i].Score != scores[j].Score {
+			return scores[i].Score > scores[j].Score
+		}
+		return scores[i].Path < scores[j].Path
+	})
+	out := make([]Score, len(scores))
+	for i, s := range scores {
+		out[i] = *s
+	}
+	return out
+}
+
+// walk calls fn with the source of each template of c and its subcharts,
+// named as in rendered manifests, and the values path of the chart's values
+func walk(c *chart.Chart, name, prefix string, fn func(name, prefix, src string)) {
+	name = path.Join(name, c.Name())
+	for _, t := range c.Templates {
+		if path.Base(t.Name) != "NOTES.txt" {
+			fn(path.Join(name, t.Name), prefix, string(t.Data))
+		}
+	}
+	for _, dep := range c.Dependencies() {
+		key := dep.Name()
+		if c.Metadata != nil {
+			for _, d := range c.Metadata.Dependencies {
+				if d.Name == dep.Name() && d.Alias != "" {
+					key = d.Alias
+				}
+			}
+		}
+		walk(dep, path.Join(name, "charts"), join(prefix, key), fn)
+	}
+}
+
+// scope is what dot refers to: the chart's root context, a values path, or
+// something unknown such as a range element
+type scope struct {
+	root bool
+	path string
+}
+
+// analyzer walks the parse trees of one template file
+type analyzer struct {
+	name   string
+	prefix string
+	src    string
+	// guards are the values paths checked by enclosing if and with actions
+	guards     []string
+	constructs []Construct
+}
+
+// analyzeTemplate finds the risky constructs of one template file
+func analyzeTemplate(name, prefix, src string) []Construct {
+	treeSet := make(map[string]*parse.Tree)
+	t := parse.New(name)
+	t.Mode = parse.SkipFuncCheck
+	if _, err := t.Parse(src, "{{", "}}", treeSet); err != nil {
+		return nil
+	}
+	a := &analyzer{name: name, prefix: prefix, src: src}
+
+	names := make([]string, 0, len(treeSet))
+	for treeName := range treeSet {
+		names = append(names, treeName)
+	}
+	sort.Strings(names)
+	// Defines are usually included with the root context
+	for _, treeName := range names {
+		a.list(treeSet[treeName].Root, scope{root: true})
+	}
+	return a.constructs
+}
+
+// list analyzes the nodes of a list
+func (a *analyzer) list(list *parse.ListNode, sc scope) {
+	if list == nil {
+		return
+	}
+	for _, n := range list.Nodes {
+		a.node(n, sc)
+	}
+}
+
+// node analyzes one node, narrowing dot and adding guards inside if and with
+func (a *analyzer) node(n parse.Node, sc scope) {
+	switch n := n.(type) {
+	case *parse.ActionNode:
+		a.pipe(n.Pipe, sc)
+	case *parse.TemplateNode:
+		if n.Pipe != nil {
+			a.pipe(n.Pipe, sc)
+		}
+	case *parse.IfNode:
+		a.pipe(n.Pipe, sc)
+		a.guarded(reads(n.Pipe, sc), func() { a.list(n.List, sc) })
+		a.list(n.ElseList, sc)
+	case *parse.WithNode:
+		paths := a.pipe(n.Pipe, sc)
+		inner := scope{root: false}
+		if len(paths) == 1 && len(n.Pipe.Decl) == 0 {
+			inner.path = paths[0]
+		}
+		a.guarded(reads(n.Pipe, sc), func() { a.list(n.List, inner) })
+		a.list(n.ElseList, sc)
+	case *parse.RangeNode:
+		a.pipe(n.Pipe, sc)
+		a.list(n.List, scope{})
+		a.list(n.ElseList, sc)
+	case *parse.ListNode:
+		a.list(n, sc)
+	}
+}
+
+// guarded runs fn with paths added to the guards
+func (a *analyzer) guarded(paths []string, fn func()) {
+	n := len(a.guards)
+	a.guards = append(a.guards, paths...)
+	fn()
+	a.guards = a.guards[:n]
+}
+
+// isGuarded reports whether an enclosing if or with checks the path, or a
+// path below it, is set
+func (a *analyzer) isGuarded(p string) bool {
+	for _, g := range a.guards {
+		if g == p || strings.HasPrefix(g, p+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// reads returns every values path a pipeline reads, at any depth
+func reads(pipe *parse.PipeNode, sc scope) []string {
+	var paths []string
+	var visit func(n parse.Node)
+	visit = func(n parse.Node) {
+		switch n := n.(type) {
+		case *parse.PipeNode:
+			for _, cmd := range n.Cmds {
+				for _, arg := range cmd.Args {
+					visit(arg)
+				}
+			}
+		default:
+			if p, ok := resolve(n, sc); ok {
+				paths = append(paths, p)
+			}
+		}
+	}
+	visit(pipe)
+	return paths
+}
+
+// pipe analyzes the commands of a pipeline and returns the values paths of
+// its result: the path it reads when it ends in one, nothing after a function
+func (a *analyzer) pipe(pipe *parse.PipeNode, sc scope) []string {
+	var prev []string
+	for _, cmd := range pipe.Cmds {
+		if len(cmd.Args) == 0 {
+			continue
+		}
+		ident, isFunc := cmd.Args[0].(*parse.IdentifierNode)
+		if !isFunc {
+			prev = a.operand(cmd.Args[0], sc)
+			continue
+		}
+
+		// and stops at the first unset argument, guarding the rest
+		var args []string
+		guards := len(a.guards)
+		for _, arg := range cmd.Args[1:] {
+			read := a.operand(arg, sc)
+			if ident.Ident == "and" {
+				a.guards = append(a.guards, read...)
+			}
+			args = append(args, read...)
+		}
+		a.guards = a.guards[:guards]
+		// A piped value is the last argument
+		args = append(args, prev...)
+		prev = nil
+
+		switch {
+		case ident.Ident == "printf":
+			a.record(cmd, KindPrintf, args...)
+		case ident.Ident == "toYaml":
+			for _, p := range args {
+				if !a.isGuarded(p) {
+					a.record(cmd, KindToYaml, p)
+				}
+			}
+		case conversions[ident.Ident]:
+			a.record(cmd, KindConversion, args...)
+		case ident.Ident == "index" && len(cmd.Args) > 2:
+			if p, ok := resolve(cmd.Args[1], sc); ok && !a.isGuarded(p) {
+				a.record(cmd, KindNilIndex, p)
+			}
+		}
+	}
+	return prev
+}
+
+// operand analyzes a command argument and returns the values paths it reads
+func (a *analyzer) operand(n parse.Node, sc scope) []string {
+	if pipe, ok := n.(*parse.PipeNode); ok {
+		return a.pipe(pipe, sc)
+	}
+	p, ok := resolve(n, sc)
+	if !ok {
+		return nil
+	}
+	// Reading a.b.c reads keys of a and a.b; the deeper map is the likelier
+	// to be unset, and a guard on it or anything below covers both
+	if _, isDot := n.(*parse.DotNode); !isDot {
+		if parent, _, nested := cutLast(p); nested && !a.isGuarded(parent) {
+			a.record(n, KindNilIndex, parent)
+		}
+	}
+	return []string{p}
+}
+
+// record adds a construct reading each of paths
+func (a *analyzer) record(n parse.Node, kind string, paths ...string) {
+	for _, p := range paths {
+		full := p
+		if a.prefix != "" && p != "global" && !strings.HasPrefix(p, "global.") {
+			full = join(a.prefix, p)
+		}
+		a.constructs = append(a.constructs, Construct{
+			Template: a.name,
+			Line:     a.line(n.Position()),
+			Kind:     kind,
+			Path:     full,
+			Source:   n.String(),
+		})
+	}
+}
+
+// line converts a byte offset in the source to a line number
+func (a *analyzer) line(pos parse.Pos) int {
+	if int(pos) > len(a.src) {
+		pos = parse.Pos(len(a.src))
+	}
+	return 1 + strings.Count(a.src[:pos], "\n")
+}
+
+// resolve returns the values path a field, variable or dot reads
+func resolve(n parse.Node, sc scope) (string, bool) {
+	switch n := n.(type) {
+	case *parse.FieldNode:
+		if sc.root && len(n.Ident) > 1 && n.Ident[0] == "Values" {
+			return strings.Join(n.Ident[1:], "."), true
+		}
+		if sc.path != "" {
+			return join(sc.path, strings.Join(n.Ident, ".")), true
+		}
+	case *parse.VariableNode:
+		if len(n.Ident) > 2 && n.Ident[0] == "$" && n.Ident[1] == "Values" {
+			return strings.Join(n.Ident[2:], "."), true
+		}
+	case *parse.DotNode:
+		if sc.path != "" {
+			return sc.path, true
+		}
+	}
+	return "", false
+}
+
+// cutLast splits a dotted path before its last key
+func cutLast(p string) (string, string, bool) {
+	i := strings.LastIndex(p, ".")
+	if i < 0 {
+		return "", p, false
+	}
+	return p[:i], p[i+1:], true
+}
+
+// join joins dotted paths, either of which may be empty
+func join(a, b string) string {
+	switch {
+	case a == "":
+		return b
+	case b == "":
+		return a
+	default:
+		return a + "." + b
+	}
+}
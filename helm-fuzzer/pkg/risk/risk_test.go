@@ -0,0 +1,96 @@
+package risk
+
+import (
+	"reflect"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+// riskChart builds a chart with risky and guarded constructs and an aliased subchart
+func riskChart() *chart.Chart {
+	sub := &chart.Chart{
+		Metadata: &chart.Metadata{APIVersion: "v2", Name: "redis", Version: "0.1.0"},
+		Templates: []*chart.File{
+			{Name: "templates/ss.yaml", Data: []byte(`port: {{ .Values.master.port | int }}
+region: {{ .Values.global.region.name }}
+`)},
+		},
+	}
+	c := &chart.Chart{
+		Metadata: &chart.Metadata{
+			APIVersion:   "v2",
+			Name:         "app",
+			Version:      "0.1.0",
+			Dependencies: []*chart.Dependency{{Name: "redis", Alias: "cache"}},
+		},
+		Templates: []*chart.File{
+			{Name: "templates/deploy.yaml", Data: []byte(`kind: Deployment
+image: {{ printf "%s:%s" .Values.image.repository .Values.image.tag }}
+tls: {{ .Values.ingress.tls.secretName }}
+{{- if .Values.probe.http }}
+path: {{ .Values.probe.http.path }}
+{{- end }}
+{{- with .Values.resources }}
+resources: {{ toYaml . | nindent 2 }}
+cpu: {{ .limits.cpu }}
+{{- end }}
+annotations: {{ toYaml .Values.annotations }}
+key: {{ index .Values.secrets "key" }}
+{{- if and .Values.metrics .Values.metrics.port }}
+metrics: true
+{{- end }}
+{{- range .Values.hosts }}
+host: {{ .name.value }}
+{{- end }}
+`)},
+			{Name: "templates/broken.yaml", Data: []byte("{{ if }")},
+		},
+	}
+	c.AddDependency(sub)
+	return c
+}
+
+func TestAnalyze(t *testing.T) {
+	constructs := Analyze(riskChart())
+	type key struct {
+		Template string
+		Line     int
+		Kind     string
+		Path     string
+	}
+	var got []key
+	for _, c := range constructs {
+		got = append(got, key{c.Template, c.Line, c.Kind, c.Path})
+	}
+	want := []key{
+		{"app/templates/deploy.yaml", 2, KindNilIndex, "image"},
+		{"app/templates/deploy.yaml", 2, KindPrintf, "image.repository"},
+		{"app/templates/deploy.yaml", 2, KindPrintf, "image.tag"},
+		{"app/templates/deploy.yaml", 3, KindNilIndex, "ingress.tls"},
+		{"app/templates/deploy.yaml", 4, KindNilIndex, "probe"},
+		{"app/templates/deploy.yaml", 9, KindNilIndex, "resources.limits"},
+		{"app/templates/deploy.yaml", 11, KindToYaml, "annotations"},
+		{"app/templates/deploy.yaml", 12, KindNilIndex, "secrets"},
+		{"app/charts/redis/templates/ss.yaml", 1, KindNilIndex, "cache.master"},
+		{"app/charts/redis/templates/ss.yaml", 1, KindConversion, "cache.master.port"},
+		{"app/charts/redis/templates/ss.yaml", 2, KindNilIndex, "global.region"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected constructs\n%v\ngot\n%v", want, got)
+	}
+}
+
+func TestScores(t *testing.T) {
+	scores := Scores([]Construct{
+		{Kind: KindPrintf, Path: "image.tag"},
+		{Kind: KindNilIndex, Path: "ingress.tls"},
+		{Kind: KindConversion, Path: "image.tag"},
+	})
+	if len(scores) != 2 || scores[0].Path != "image.tag" || scores[0].Score != 2 || scores[1].Path != "ingress.tls" {
+		t.Fatalf("expected image.tag first with a score of 2, got %+v", scores)
+	}
+	if scores[0].Nilable() || !scores[1].Nilable() {
+		t.Error("expected only the nil index to make a path nilable")
+	}
+}
@@ -0,0 +1,126 @@
+package runner
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/registry"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+// PackageChart packages a chart directory as a .tgz archive, honouring its
+// .helmignore, for shipping the chart to another process
+func PackageChart(chartPath string) ([]byte, error) {
+	chart, err := loader.Load(chartPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chart: %w", err)
+	}
+	dir, err := os.MkdirTemp("", "helm-fuzz-package-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to package chart: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path, err := chartutil.Save(chart, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to package chart: %w", err)
+	}
+	return os.ReadFile(path)
+}
+
+// ExpandChart unpacks a chart archive into dir and returns the chart's
+// directory. Archive paths cannot escape dir.
+func ExpandChart(dir string, archive []byte) (string, error) {
+	if err := chartutil.Expand(dir, bytes.NewReader(archive)); err != nil {
+		return "", fmt.Errorf("failed to unpack chart archive: %w", err)
+	}
+
+	// Expand names the directory after the chart; it is the only entry
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to read unpacked chart: %w", err)
+	}
+	if len(entries) != 1 || !entries[0].IsDir() {
+		return "", fmt.Errorf("chart archive did not unpack to a single chart directory")
+	}
+	return filepath.Join(dir, entries[0].Name()), nil
+}
+
+// PullChart downloads a chart from an OCI registry, using the registry
+// credentials helm would use, and unpacks it into dir. A non-empty version
+// is appended to ref as its tag.
+func PullChart(dir, ref, version string) (string, error) {
+	ref = strings.TrimPrefix(ref, "oci://")
+	if version != "" {
+		ref += ":" + version
+	}
+	if err := NeedsNetwork("pulling " + ref); err != nil {
+		return "", err
+	}
+
+	settings := cli.New()
+	client, err := registry.NewClient(
+		registry.ClientOptCredentialsFile(settings.RegistryConfig),
+		registry.ClientOptEnableCache(true),
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to create registry client: %w", err)
+	}
+	result, err := client.Pull(ref, registry.PullOptWithChart(true))
+	if err != nil {
+		return "", fmt.Errorf("failed to pull %s: %w", ref, err)
+	}
+	return ExpandChart(dir, result.Chart.Data)
+}
+
+// PullRepoChart downloads a chart from a classic Helm repository at repoURL
+// and unpacks it into dir. An empty version selects the latest release.
+func PullRepoChart(dir, repoURL, name, version string) (string, error) {
+	if err := NeedsNetwork(fmt.Sprintf("downloading %s from %s", name, repoURL)); err != nil {
+		return "", err
+	}
+	getters := getter.All(cli.New())
+	chartURL, err := repo.FindChartInRepoURL(repoURL, name, version, "", "", "", getters)
+	if err != nil {
+		return "", fmt.Errorf("failed to find %s in %s: %w", name, repoURL, err)
+	}
+	scheme, _, _ := strings.Cut(chartURL, "://")
+	g, err := getters.ByScheme(scheme)
+	if err != nil {
+		return "", fmt.Errorf("failed to download %s: %w", chartURL, err)
+	}
+	archive, err := g.Get(chartURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to download %s: %w", chartURL, err)
+	}
+	return ExpandChart(dir, archive.Bytes())
+}
+
+// OpenChart returns a chart directory for ref: a chart directory as it is, or
+// a packaged .tgz or an OCI reference such as oci://ghcr.io/org/charts/app:1.2.0
+// unpacked into dir
+func OpenChart(dir, ref string) (string, error) {
+	if registry.IsOCI(ref) {
+		return PullChart(dir, ref, "")
+	}
+
+	info, err := os.Stat(ref)
+	if err != nil {
+		return "", fmt.Errorf("chart path does not exist: %s", ref)
+	}
+	if info.IsDir() {
+		return ref, nil
+	}
+	archive, err := os.ReadFile(ref)
+	if err != nil {
+		return "", fmt.Errorf("failed to read chart archive: %w", err)
+	}
+	return ExpandChart(dir, archive)
+}
@@ -0,0 +1,97 @@
+package runner
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart/loader"
+)
+
+func TestPackageAndExpandChart(t *testing.T) {
+	archive, err := PackageChart("../../testdata/buggy-chart")
+	if err != nil {
+		t.Fatalf("PackageChart failed: %v", err)
+	}
+
+	chartPath, err := ExpandChart(t.TempDir(), archive)
+	if err != nil {
+		t.Fatalf("ExpandChart failed: %v", err)
+	}
+	chart, err := loader.Load(chartPath)
+	if err != nil {
+		t.Fatalf("expected the unpacked chart to load: %v", err)
+	}
+	original, err := loader.Load("../../testdata/buggy-chart")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chart.Templates) != len(original.Templates) {
+		t.Errorf("expected %d templates, got %d", len(original.Templates), len(chart.Templates))
+	}
+
+	// Anything but a chart archive is rejected
+	if _, err := ExpandChart(t.TempDir(), []byte("not an archive")); err == nil {
+		t.Error("expected an invalid archive to be rejected")
+	}
+}
+
+func TestOpenChart(t *testing.T) {
+	if path, err := OpenChart(t.TempDir(), "../../testdata/buggy-chart"); err != nil || path != "../../testdata/buggy-chart" {
+		t.Errorf("expected a chart directory to be used as it is, got %q, %v", path, err)
+	}
+
+	archive, err := PackageChart("../../testdata/buggy-chart")
+	if err != nil {
+		t.Fatalf("PackageChart failed: %v", err)
+	}
+	tgz := filepath.Join(t.TempDir(), "buggy-chart-0.1.0.tgz")
+	if err := os.WriteFile(tgz, archive, 0644); err != nil {
+		t.Fatal(err)
+	}
+	path, err := OpenChart(t.TempDir(), tgz)
+	if err != nil {
+		t.Fatalf("OpenChart failed: %v", err)
+	}
+	if _, err := loader.Load(path); err != nil {
+		t.Errorf("expected the unpacked archive to load: %v", err)
+	}
+
+	if _, err := OpenChart(t.TempDir(), filepath.Join(t.TempDir(), "missing.tgz")); err == nil {
+		t.Error("expected an error for a missing chart")
+	}
+}
+
+func TestPullRepoChart(t *testing.T) {
+	archive, err := PackageChart("../../testdata/buggy-chart")
+	if err != nil {
+		t.Fatalf("PackageChart failed: %v", err)
+	}
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/index.yaml":
+			fmt.Fprintf(w, "apiVersion: v1\nentries:\n  buggy-chart:\n    - name: buggy-chart\n      version: 0.1.0\n      urls: [%s/buggy-chart-0.1.0.tgz]\n", server.URL)
+		case "/buggy-chart-0.1.0.tgz":
+			w.Write(archive)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	path, err := PullRepoChart(t.TempDir(), server.URL, "buggy-chart", "0.1.0")
+	if err != nil {
+		t.Fatalf("PullRepoChart failed: %v", err)
+	}
+	if _, err := loader.Load(path); err != nil {
+		t.Errorf("expected the downloaded chart to load: %v", err)
+	}
+
+	if _, err := PullRepoChart(t.TempDir(), server.URL, "buggy-chart", "9.9.9"); err == nil {
+		t.Error("expected an error for a version the repository does not have")
+	}
+}
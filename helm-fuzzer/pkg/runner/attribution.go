@@ -0,0 +1,150 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Attribution points a crash at the template location that caused it
+type Attribution struct {
+	// Template is the template name as reported by Helm (e.g. mychart/templates/deployment.yaml)
+	Template string `json:"template"`
+	// Line is the 1-based line number, 0 if unknown
+	Line int `json:"line,omitempty"`
+	// Column is the 1-based column number, 0 if unknown
+	Column int `json:"column,omitempty"`
+	// ValuePath is the expression being evaluated (e.g. .Values.resources.limits)
+	ValuePath string `json:"valuePath,omitempty"`
+	// Rendered is true when Line refers to the rendered manifest rather than the template source
+	Rendered bool `json:"rendered,omitempty"`
+}
+
+var (
+	// template: mychart/templates/deployment.yaml:25:12: executing "mychart/templates/deployment.yaml" at <.Values.x>: ...
+	templateErrorPattern = regexp.MustCompile(`template: ([^:\s]+):(\d+)(?::(\d+))?:`)
+	valuePathPattern     = regexp.MustCompile(`at <([^>]+)>`)
+	// execution error at (mychart/templates/secret.yaml:3:4): db secret not found
+	// Helm rewrites the errors of fail, and of required, to name only the
+	// location in the template being rendered
+	executionErrorPattern = regexp.MustCompile(`execution error at \(([^:\s)]+):(\d+)(?::(\d+))?\)`)
+	// YAML parse error on mychart/templates/deployment.yaml: error converting YAML to JSON: yaml: line 20: ...
+	yamlErrorPattern = regexp.MustCompile(`YAML parse error on ([^:\s]+):.*?line (\d+)`)
+)
+
+// Attribute extracts the template file, line and value path from a crash reason.
+// It returns nil when the reason does not reference a template.
+func Attribute(reason string) *Attribution {
+	if m := yamlErrorPattern.FindStringSubmatch(reason); m != nil {
+		line, _ := strconv.Atoi(m[2])
+		return &Attribution{Template: m[1], Line: line, Rendered: true}
+	}
+	if m := timeoutPattern.FindStringSubmatch(reason); m != nil {
+		return &Attribution{Template: m[1]}
+	}
+
+	m := executionErrorPattern.FindStringSubmatch(reason)
+	if m == nil {
+		m = templateErrorPattern.FindStringSubmatch(reason)
+	}
+	if m == nil {
+		return nil
+	}
+
+	attr := &Attribution{Template: m[1]}
+	attr.Line, _ = strconv.Atoi(m[2])
+	if m[3] != "" {
+		attr.Column, _ = strconv.Atoi(m[3])
+	}
+	if v := valuePathPattern.FindStringSubmatch(reason); v != nil {
+		attr.ValuePath = v[1]
+	}
+	return attr
+}
+
+// File returns the template path relative to the chart directory
+func (a *Attribution) File() string {
+	// Helm prefixes template names with the chart name
+	if i := strings.IndexByte(a.Template, '/'); i >= 0 {
+		return a.Template[i+1:]
+	}
+	return a.Template
+}
+
+// Subchart returns the dependency whose template the crash is in, as named
+// in its parent's values, or an empty string for the chart's own templates.
+// Templates of nested dependencies are attributed to the direct dependency.
+func (a *Attribution) Subchart() string {
+	parts := strings.Split(a.File(), "/")
+	if len(parts) > 2 && parts[0] == "charts" {
+		return parts[1]
+	}
+	return ""
+}
+
+// String formats the attribution as file:line[:column]
+func (a *Attribution) String() string {
+	s := a.File()
+	if a.Line > 0 {
+		s += ":" + strconv.Itoa(a.Line)
+	}
+	if a.Column > 0 {
+		s += ":" + strconv.Itoa(a.Column)
+	}
+	return s
+}
+
+// Snippet returns the lines surrounding the attributed location, numbered and
+// with the offending line marked. Rendered attributions are resolved by
+// re-rendering the chart with the crashing values.
+func (r *Runner) Snippet(attr *Attribution, values map[string]interface{}, context int) (string, error) {
+	if attr == nil || attr.Line == 0 {
+		return "", nil
+	}
+
+	var source string
+	if attr.Rendered {
+		rendered, err := r.Render(values)
+		if err != nil {
+			return "", err
+		}
+		var ok bool
+		if source, ok = rendered[attr.Template]; !ok {
+			return "", fmt.Errorf("template %s was not rendered", attr.Template)
+		}
+	} else {
+		data, err := os.ReadFile(filepath.Join(r.chartPath, filepath.FromSlash(attr.File())))
+		if err != nil {
+			return "", fmt.Errorf("failed to read template: %w", err)
+		}
+		source = string(data)
+	}
+
+	return numberLines(source, attr.Line, context), nil
+}
+
+// numberLines formats the lines within context of line with a marker on line
+func numberLines(source string, line int, context int) string {
+	lines := strings.Split(source, "\n")
+	start := line - context
+	if start < 1 {
+		start = 1
+	}
+	end := line + context
+	if end > len(lines) {
+		end = len(lines)
+	}
+
+	var b strings.Builder
+	for n := start; n <= end; n++ {
+		marker := "  "
+		if n == line {
+			marker = "> "
+		}
+		fmt.Fprintf(&b, "%s%4d | %s\n", marker, n, lines[n-1])
+	}
+	return b.String()
+}
@@ -0,0 +1,132 @@
+package runner
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAttribute(t *testing.T) {
+	tests := []struct {
+		name     string
+		reason   string
+		expected *Attribution
+	}{
+		{
+			name:   "template execution error",
+			reason: `Error: template: mychart/templates/deployment.yaml:25:12: executing "mychart/templates/deployment.yaml" at <.Values.resources.limits>: nil pointer evaluating interface {}.cpu`,
+			expected: &Attribution{
+				Template:  "mychart/templates/deployment.yaml",
+				Line:      25,
+				Column:    12,
+				ValuePath: ".Values.resources.limits",
+			},
+		},
+		{
+			name:   "template parse error",
+			reason: `Error: parse error at (mychart/templates/svc.yaml:3): template: mychart/templates/svc.yaml:3: unexpected "}" in operand`,
+			expected: &Attribution{
+				Template: "mychart/templates/svc.yaml",
+				Line:     3,
+			},
+		},
+		{
+			name:   "fail execution error",
+			reason: `Error: execution error at (mychart/templates/secret.yaml:1:3): db secret not found`,
+			expected: &Attribution{
+				Template: "mychart/templates/secret.yaml",
+				Line:     1,
+				Column:   3,
+			},
+		},
+		{
+			name:   "yaml parse error",
+			reason: `Error: YAML parse error on mychart/templates/deployment.yaml: error converting YAML to JSON: yaml: line 20: mapping keys are not allowed in this context`,
+			expected: &Attribution{
+				Template: "mychart/templates/deployment.yaml",
+				Line:     20,
+				Rendered: true,
+			},
+		},
+		{
+			name:   "render timeout",
+			reason: `Error: render timed out after 30s in mychart/templates/configmap.yaml`,
+			expected: &Attribution{
+				Template: "mychart/templates/configmap.yaml",
+			},
+		},
+		{
+			name:     "no template reference",
+			reason:   "Panic: runtime error",
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Attribute(tt.reason)
+			if tt.expected == nil {
+				if got != nil {
+					t.Errorf("expected nil attribution, got %+v", got)
+				}
+				return
+			}
+			if got == nil || *got != *tt.expected {
+				t.Errorf("expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestAttributionString(t *testing.T) {
+	attr := &Attribution{Template: "mychart/templates/deployment.yaml", Line: 25, Column: 12}
+	if got := attr.String(); got != "templates/deployment.yaml:25:12" {
+		t.Errorf("unexpected attribution string: %s", got)
+	}
+}
+
+func TestAttributionSubchart(t *testing.T) {
+	tests := map[string]string{
+		"umbrella/templates/deployment.yaml":                         "",
+		"umbrella/charts/cache/templates/statefulset.yaml":           "cache",
+		"umbrella/charts/cache/charts/common/templates/_helpers.tpl": "cache",
+		"umbrella/charts/cache/templates/tests/test-connection.yaml": "cache",
+	}
+	for template, want := range tests {
+		if got := (&Attribution{Template: template}).Subchart(); got != want {
+			t.Errorf("Subchart() of %s = %q, want %q", template, got, want)
+		}
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	chartPath, err := filepath.Abs("../../testdata/buggy-chart")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := New(chartPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	attr := &Attribution{Template: "buggy-chart/templates/deployment.yaml", Line: 8}
+	snippet, err := r.Snippet(attr, nil, 1)
+	if err != nil {
+		t.Fatalf("Snippet failed: %v", err)
+	}
+	if !strings.Contains(snippet, ">    8 |   replicas: {{ .Values.replicaCount }}") {
+		t.Errorf("expected line 8 to be marked, got:\n%s", snippet)
+	}
+	if strings.Count(snippet, "\n") != 3 {
+		t.Errorf("expected 3 lines of context, got:\n%s", snippet)
+	}
+
+	attr.Rendered = true
+	snippet, err = r.Snippet(attr, map[string]interface{}{"replicaCount": 7}, 0)
+	if err != nil {
+		t.Fatalf("rendered Snippet failed: %v", err)
+	}
+	if !strings.Contains(snippet, "replicas: 7") {
+		t.Errorf("expected rendered replicas line, got:\n%s", snippet)
+	}
+}
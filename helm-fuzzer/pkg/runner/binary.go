@@ -0,0 +1,161 @@
+package runner
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime/debug"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// helmModule is the module path of the embedded Helm SDK
+const helmModule = "helm.sh/helm/v3"
+
+// SDKVersion returns the version of the embedded Helm SDK, or "unknown" when
+// the binary carries no module information
+func SDKVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range info.Deps {
+			if dep.Path == helmModule {
+				if dep.Replace != nil {
+					return dep.Replace.Version
+				}
+				return dep.Version
+			}
+		}
+	}
+	return "unknown"
+}
+
+// BinaryRunner renders a chart with an external helm binary through helm
+// template, with the release name, namespace and Kubernetes version Runner
+// uses, so the two can be compared input by input
+type BinaryRunner struct {
+	binary      string
+	chartPath   string
+	kubeVersion string
+	version     string
+}
+
+// NewBinaryRunner creates a runner for the helm binary at binary, a path or
+// a name looked up in PATH, and checks that it reports a version
+func NewBinaryRunner(binary, chartPath, kubeVersion string) (*BinaryRunner, error) {
+	path, err := exec.LookPath(binary)
+	if err != nil {
+		return nil, fmt.Errorf("helm binary %s not found: %w", binary, err)
+	}
+	if _, err := os.Stat(chartPath); os.IsNotExist(err) {
+		return nil, fmt.Errorf("chart path does not exist: %s", chartPath)
+	}
+	if kubeVersion == "" {
+		kubeVersion = defaultKubeVersion
+	}
+
+	out, err := exec.Command(path, "version", "--template", "{{.Version}}").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the version of %s: %w", binary, err)
+	}
+	return &BinaryRunner{
+		binary:      path,
+		chartPath:   chartPath,
+		kubeVersion: kubeVersion,
+		version:     strings.TrimSpace(string(out)),
+	}, nil
+}
+
+// Version returns the version the binary reports, e.g. v3.15.2
+func (b *BinaryRunner) Version() string {
+	return b.version
+}
+
+// Run renders values with helm template. The error of a failed render is
+// helm's error message without its "Error: " prefix, as the SDK reports it,
+// and a panicking helm yields its panic message.
+func (b *BinaryRunner) Run(values map[string]interface{}) *Result {
+	result := &Result{Values: values}
+
+	valuesFile, err := writeValuesFile(values)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+	defer os.Remove(valuesFile)
+
+	cmd := exec.Command(b.binary, "template", "fuzz-test", b.chartPath,
+		"--namespace", "default",
+		"--kube-version", b.kubeVersion,
+		"--values", valuesFile,
+	)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	err = cmd.Run()
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		result.Success = true
+		result.Manifest = stdout.String()
+		result.Templates = manifestTemplates(result.Manifest)
+	case errors.As(err, &exitErr):
+		if msg := panicMessage(stderr.String()); msg != "" {
+			result.Panic = msg
+			result.Error = fmt.Errorf("PANIC: %s", msg)
+		} else {
+			result.Error = errors.New(helmError(stderr.String()))
+		}
+	default:
+		result.Error = fmt.Errorf("failed to run %s: %w", b.binary, err)
+	}
+	return result
+}
+
+// writeValuesFile writes values to a temporary file for --values
+func writeValuesFile(values map[string]interface{}) (string, error) {
+	data, err := yaml.Marshal(values)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode values: %w", err)
+	}
+	f, err := os.CreateTemp("", "helm-fuzz-values-*.yaml")
+	if err != nil {
+		return "", fmt.Errorf("failed to create values file: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(data); err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("failed to write values file: %w", err)
+	}
+	return f.Name(), nil
+}
+
+// helmError extracts the error helm printed, skipping warnings before it
+func helmError(stderr string) string {
+	if i := strings.Index(stderr, "Error: "); i >= 0 {
+		return strings.TrimSpace(stderr[i+len("Error: "):])
+	}
+	return strings.TrimSpace(stderr)
+}
+
+// panicMessage returns the message of a Go panic in stderr, or an empty string
+func panicMessage(stderr string) string {
+	for _, line := range strings.Split(stderr, "\n") {
+		if msg, ok := strings.CutPrefix(line, "panic: "); ok {
+			return strings.TrimSpace(msg)
+		}
+	}
+	return ""
+}
+
+// manifestTemplates lists the templates named by the Source comments of a manifest
+func manifestTemplates(manifest string) []string {
+	var names []string
+	for _, line := range strings.Split(manifest, "\n") {
+		if name, ok := strings.CutPrefix(line, "# Source: "); ok {
+			names = append(names, strings.TrimSpace(name))
+		}
+	}
+	return names
+}
@@ -0,0 +1,66 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// fakeHelm writes a script that answers helm version and helm template like
+// helm does, failing when the values set crash and panicking on panic
+func fakeHelm(t *testing.T) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake helm is a shell script")
+	}
+	script := `#!/bin/sh
+if [ "$1" = version ]; then printf v3.99.0; exit 0; fi
+for last; do :; done
+if grep -q 'crash: true' "$last"; then
+  echo 'WARNING: Kubernetes configuration file is group-readable' >&2
+  echo 'Error: template: app/templates/cm.yaml:3:4: executing "app/templates/cm.yaml" at <.Values.x>: nil pointer' >&2
+  exit 1
+fi
+if grep -q 'panic: true' "$last"; then
+  printf 'panic: runtime error: index out of range\n\ngoroutine 1 [running]:\n' >&2
+  exit 2
+fi
+printf -- '---\n# Source: app/templates/cm.yaml\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app\n'
+`
+	path := filepath.Join(t.TempDir(), "helm")
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestBinaryRunner(t *testing.T) {
+	b, err := NewBinaryRunner(fakeHelm(t), "../../testdata/buggy-chart", "")
+	if err != nil {
+		t.Fatalf("NewBinaryRunner failed: %v", err)
+	}
+	if b.Version() != "v3.99.0" {
+		t.Errorf("expected version v3.99.0, got %q", b.Version())
+	}
+
+	res := b.Run(map[string]interface{}{"crash": false})
+	if !res.Success || len(res.Templates) != 1 || res.Templates[0] != "app/templates/cm.yaml" {
+		t.Errorf("expected a successful render of cm.yaml, got %+v", res)
+	}
+
+	res = b.Run(map[string]interface{}{"crash": true})
+	want := `template: app/templates/cm.yaml:3:4: executing "app/templates/cm.yaml" at <.Values.x>: nil pointer`
+	if res.Success || res.Error == nil || res.Error.Error() != want {
+		t.Errorf("expected error %q without the warning or prefix, got %v", want, res.Error)
+	}
+
+	res = b.Run(map[string]interface{}{"panic": true})
+	if res.Panic != "runtime error: index out of range" {
+		t.Errorf("expected the panic message, got %v", res.Panic)
+	}
+
+	if _, err := NewBinaryRunner(filepath.Join(t.TempDir(), "missing"), "../../testdata/buggy-chart", ""); err == nil {
+		t.Error("expected an error for a missing binary")
+	}
+}
@@ -0,0 +1,135 @@
+package runner
+
+import (
+	"path"
+	"regexp"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+)
+
+// clusterPattern matches template source that renders differently in a
+// cluster: lookup finds nothing client-only, and .Capabilities.APIVersions
+// only has Kubernetes' built-in API versions
+var clusterPattern = regexp.MustCompile(`\blookup\b|\.Capabilities\.APIVersions`)
+
+var (
+	// definePattern matches the start of a named template's definition
+	definePattern = regexp.MustCompile(`\{\{-?\s*define\s+"([^"]+)"`)
+	// includePattern matches calls of named templates
+	includePattern = regexp.MustCompile(`\b(?:include|template)\s+"([^"]+)"`)
+)
+
+// ClusterTemplate returns the template a render error is in when the
+// failure is for want of a cluster: the template, or one it includes, calls
+// lookup or reads .Capabilities.APIVersions. It returns an empty string for
+// other errors, and for errors in partials, which fail through the
+// templates including them.
+func (r *Runner) ClusterTemplate(reason string) string {
+	attr := Attribute(reason)
+	if attr == nil || strings.HasPrefix(path.Base(attr.Template), "_") {
+		return ""
+	}
+	c, err := loader.Load(r.chartPath)
+	if err != nil {
+		return ""
+	}
+	sources := make(map[string][]byte)
+	defines := make(map[string][]byte)
+	forEachTemplate(c, func(name string, t *chart.File) {
+		sources[name] = t.Data
+		for define, body := range definitions(t.Data) {
+			defines[define] = body
+		}
+	})
+
+	// Errors of fail and required name only the template being rendered, so
+	// the named templates it includes are followed too
+	var pending [][]byte
+	for _, m := range append([][]string{{"", attr.Template}}, templateErrorPattern.FindAllStringSubmatch(reason, -1)...) {
+		pending = append(pending, sources[m[1]])
+	}
+	followed := make(map[string]bool)
+	for len(pending) > 0 {
+		source := pending[0]
+		pending = pending[1:]
+		if clusterPattern.Match(source) {
+			return attr.Template
+		}
+		for _, m := range includePattern.FindAllSubmatch(source, -1) {
+			if name := string(m[1]); !followed[name] && defines[name] != nil {
+				followed[name] = true
+				pending = append(pending, defines[name])
+			}
+		}
+	}
+	return ""
+}
+
+// definitions returns the bodies of the named templates a template source
+// defines, each running to the next definition or the end of the source
+func definitions(source []byte) map[string][]byte {
+	bodies := make(map[string][]byte)
+	matches := definePattern.FindAllSubmatchIndex(source, -1)
+	for i, m := range matches {
+		end := len(source)
+		if i+1 < len(matches) {
+			end = matches[i+1][0]
+		}
+		bodies[string(source[m[2]:m[3]])] = source[m[1]:end]
+	}
+	return bodies
+}
+
+// NeedsCluster reports whether a render error is in an expression calling
+// lookup or reading .Capabilities.APIVersions, which fails client-only
+// whatever the values
+func NeedsCluster(reason string) bool {
+	for _, m := range valuePathPattern.FindAllStringSubmatch(reason, -1) {
+		if clusterPattern.MatchString(m[1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// skipTemplates removes the templates, named as in rendered manifests, from
+// c and its dependencies
+func skipTemplates(c *chart.Chart, names []string) {
+	skip := make(map[string]bool, len(names))
+	for _, name := range names {
+		skip[name] = true
+	}
+	var walk func(c *chart.Chart, prefix string)
+	walk = func(c *chart.Chart, prefix string) {
+		prefix = path.Join(prefix, c.Name())
+		kept := c.Templates[:0]
+		for _, t := range c.Templates {
+			if !skip[path.Join(prefix, t.Name)] {
+				kept = append(kept, t)
+			}
+		}
+		c.Templates = kept
+		for _, dep := range c.Dependencies() {
+			walk(dep, path.Join(prefix, "charts"))
+		}
+	}
+	walk(c, "")
+}
+
+// forEachTemplate calls fn with each template of c and its dependencies,
+// partials included, named as in rendered manifests
+func forEachTemplate(c *chart.Chart, fn func(name string, t *chart.File)) {
+	var walk func(c *chart.Chart, prefix string)
+	walk = func(c *chart.Chart, prefix string) {
+		prefix = path.Join(prefix, c.Name())
+		for _, t := range c.Templates {
+			fn(path.Join(prefix, t.Name), t)
+		}
+		for _, dep := range c.Dependencies() {
+			walk(dep, path.Join(prefix, "charts"))
+		}
+	}
+	walk(c, "")
+}
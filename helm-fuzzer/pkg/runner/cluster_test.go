@@ -0,0 +1,71 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNeedsCluster(t *testing.T) {
+	tests := []struct {
+		reason string
+		want   bool
+	}{
+		{`template: app/templates/secret.yaml:3:14: executing "app/templates/secret.yaml" at <(lookup "v1" "Secret" .Release.Namespace "db").data.password>: nil pointer evaluating interface {}.password`, true},
+		{`template: app/templates/secret.yaml:3:14: executing "app/templates/secret.yaml" at <include "app.password" .>: error calling include: template: app/templates/_helpers.tpl:2:5: executing "app.password" at <index (lookup "v1" "Secret" "" "db").data "password">: error calling index: index of untyped nil`, true},
+		{`template: app/templates/deploy.yaml:5:3: executing "app/templates/deploy.yaml" at <.Values.image.tag>: nil pointer evaluating interface {}.tag`, false},
+		{`YAML parse error on app/templates/deploy.yaml: error converting YAML to JSON: yaml: line 4: did not find expected key`, false},
+	}
+	for _, tt := range tests {
+		if got := NeedsCluster(tt.reason); got != tt.want {
+			t.Errorf("NeedsCluster(%q) = %v, want %v", tt.reason, got, tt.want)
+		}
+	}
+}
+
+func TestClusterTemplate(t *testing.T) {
+	chartPath := t.TempDir()
+	files := map[string]string{
+		"Chart.yaml":               "apiVersion: v2\nname: app\nversion: 0.1.0\n",
+		"templates/_helpers.tpl":   "{{- define \"app.secret\" }}{{ if not (lookup \"v1\" \"Secret\" .Release.Namespace \"db\") }}{{ fail \"db secret not found\" }}{{ end }}{{ end }}\n",
+		"templates/secret.yaml":    "{{ include \"app.secret\" . }}\n",
+		"templates/configmap.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: {{ required \"name is required\" .Values.name }}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(chartPath, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r, err := New(chartPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := r.Run(map[string]interface{}{"name": "app"})
+	if result.Success {
+		t.Fatal("expected the lookup to fail client-only")
+	}
+	if got := r.ClusterTemplate(result.Error.Error()); got != "app/templates/secret.yaml" {
+		t.Errorf("expected the template including the lookup, got %q from %v", got, result.Error)
+	}
+	if got := r.ClusterTemplate(`template: app/templates/configmap.yaml:4:11: executing "app/templates/configmap.yaml" at <required "name is required" .Values.name>: error calling required: name is required`); got != "" {
+		t.Errorf("expected a failure of the values not to need a cluster, got %q", got)
+	}
+
+	r, err = NewWithOptions(chartPath, Options{Skip: []string{"app/templates/secret.yaml"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result = r.Run(map[string]interface{}{"name": "app"})
+	if !result.Success {
+		t.Fatalf("expected the chart to render without the template, got %v", result.Error)
+	}
+	if !strings.Contains(result.Manifest, "kind: ConfigMap") {
+		t.Errorf("expected the rest of the chart rendered, got\n%s", result.Manifest)
+	}
+}
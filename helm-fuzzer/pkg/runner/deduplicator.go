@@ -21,19 +21,18 @@ func NewDeduplicator() *Deduplicator {
 
 // IsDuplicate checks if a crash reason has been seen before
 func (d *Deduplicator) IsDuplicate(reason string) bool {
-	normalized := d.normalizeReason(reason)
-	return d.seen[normalized]
+	return d.seen[Fingerprint(reason)]
 }
 
 // MarkSeen marks a crash reason as seen
 func (d *Deduplicator) MarkSeen(reason string) {
-	normalized := d.normalizeReason(reason)
-	d.seen[normalized] = true
+	d.seen[Fingerprint(reason)] = true
 }
 
-// normalizeReason normalizes crash reasons to detect duplicates
-// It removes dynamic values like file names, line numbers, and unique IDs
-func (d *Deduplicator) normalizeReason(reason string) string {
+// Fingerprint identifies a crash independently of the input that caused it.
+// It hashes the reason after removing dynamic values like file names, line
+// numbers, and unique IDs, so duplicates share a fingerprint.
+func Fingerprint(reason string) string {
 	// Remove "Error: " or "Panic: " prefix for consistency
 	normalized := strings.TrimPrefix(reason, "Error: ")
 	normalized = strings.TrimPrefix(normalized, "Panic: ")
@@ -64,6 +63,40 @@ func (d *Deduplicator) normalizeReason(reason string) string {
 	return fmt.Sprintf("%x", hash)
 }
 
+// idInfix separates the chart from the short fingerprint in a finding ID
+const idInfix = "-FZ-"
+
+// shortFingerprintLength is how much of the fingerprint a finding ID keeps
+const shortFingerprintLength = 8
+
+// FindingID is the stable, human-readable ID of a crash in a chart, such as
+// nginx-FZ-3fa9c2d1, for referring to findings from issue trackers. It is
+// the chart name and the start of the fingerprint, so it stays the same
+// across sessions for as long as the fingerprint does.
+func FindingID(chart, fingerprint string) string {
+	if len(fingerprint) > shortFingerprintLength {
+		fingerprint = fingerprint[:shortFingerprintLength]
+	}
+	return chart + idInfix + fingerprint
+}
+
+// ParseFindingID splits a finding ID into its chart and short fingerprint,
+// returning false for anything else, such as a bare fingerprint
+func ParseFindingID(id string) (chart, fingerprint string, ok bool) {
+	i := strings.LastIndex(id, idInfix)
+	if i <= 0 || i+len(idInfix) == len(id) {
+		return "", "", false
+	}
+	return id[:i], id[i+len(idInfix):], true
+}
+
+// MatchesFindingID reports whether a crash reason in a chart is the finding
+// an ID names
+func MatchesFindingID(id, chart, reason string) bool {
+	idChart, short, ok := ParseFindingID(id)
+	return ok && idChart == chart && strings.HasPrefix(Fingerprint(reason), short)
+}
+
 // GetUniqueCount returns the number of unique crashes seen
 func (d *Deduplicator) GetUniqueCount() int {
 	return len(d.seen)
@@ -0,0 +1,34 @@
+package runner
+
+import "testing"
+
+func TestFindingID(t *testing.T) {
+	reason := `template: app/templates/deployment.yaml:12:3: executing "x" at <.Values.image.tag>: nil pointer evaluating interface {}.tag`
+	fingerprint := Fingerprint(reason)
+	id := FindingID("my-app", fingerprint)
+	if id != "my-app-FZ-"+fingerprint[:8] {
+		t.Fatalf("unexpected ID %s", id)
+	}
+	// The line moving keeps the fingerprint, and so the ID
+	moved := `template: app/templates/deployment.yaml:40:3: executing "x" at <.Values.image.tag>: nil pointer evaluating interface {}.tag`
+	if FindingID("my-app", Fingerprint(moved)) != id {
+		t.Error("expected the ID to survive the crash moving lines")
+	}
+
+	chart, short, ok := ParseFindingID(id)
+	if !ok || chart != "my-app" || short != fingerprint[:8] {
+		t.Errorf("expected my-app and %s, got %s, %s, %v", fingerprint[:8], chart, short, ok)
+	}
+	for _, invalid := range []string{fingerprint, "-FZ-abc", "my-app-FZ-"} {
+		if _, _, ok := ParseFindingID(invalid); ok {
+			t.Errorf("expected %q not to parse", invalid)
+		}
+	}
+
+	if !MatchesFindingID(id, "my-app", reason) {
+		t.Error("expected the ID to match its reason")
+	}
+	if MatchesFindingID(id, "other", reason) || MatchesFindingID(id, "my-app", "Error: something else") {
+		t.Error("expected the ID to match only its chart and reason")
+	}
+}
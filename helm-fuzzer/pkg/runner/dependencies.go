@@ -0,0 +1,58 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/downloader"
+	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/registry"
+)
+
+// CheckDependencies returns an error naming the dependencies declared in
+// Chart.yaml that are missing from the chart's charts/ directory. Rendering
+// silently skips missing subcharts, so fuzzing without them tests less.
+func (r *Runner) CheckDependencies() error {
+	chart, err := loader.Load(r.chartPath)
+	if err != nil {
+		return fmt.Errorf("failed to load chart: %w", err)
+	}
+	if deps := chart.Metadata.Dependencies; len(deps) > 0 {
+		return action.CheckDependencies(chart, deps)
+	}
+	return nil
+}
+
+// BuildDependencies downloads the chart's dependencies into charts/ as
+// `helm dependency build` does, from Chart.lock if present. Repositories,
+// the repository cache and registry credentials come from Helm's
+// environment, so a run as a Helm plugin uses the same ones as helm itself.
+func (r *Runner) BuildDependencies(out io.Writer) error {
+	if err := NeedsNetwork("building dependencies"); err != nil {
+		return fmt.Errorf("%w; build them with helm dependency build beforehand", err)
+	}
+	registryClient, err := registry.NewClient(
+		registry.ClientOptCredentialsFile(r.settings.RegistryConfig),
+		registry.ClientOptWriter(out),
+		registry.ClientOptEnableCache(true),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create registry client: %w", err)
+	}
+
+	manager := &downloader.Manager{
+		Out:              out,
+		ChartPath:        r.chartPath,
+		Debug:            r.settings.Debug,
+		Getters:          getter.All(r.settings),
+		RegistryClient:   registryClient,
+		RepositoryConfig: r.settings.RepositoryConfig,
+		RepositoryCache:  r.settings.RepositoryCache,
+	}
+	if err := manager.Build(); err != nil {
+		return fmt.Errorf("failed to build dependencies: %w", err)
+	}
+	return nil
+}
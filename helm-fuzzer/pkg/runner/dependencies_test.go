@@ -0,0 +1,54 @@
+package runner
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDependencies(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HELM_REPOSITORY_CONFIG", filepath.Join(dir, "repositories.yaml"))
+	t.Setenv("HELM_REPOSITORY_CACHE", filepath.Join(dir, "cache"))
+	t.Setenv("HELM_REGISTRY_CONFIG", filepath.Join(dir, "registry.json"))
+
+	writeFile := func(path, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile(filepath.Join(dir, "sub", "Chart.yaml"), "apiVersion: v2\nname: sub\nversion: 0.1.0\n")
+	writeFile(filepath.Join(dir, "sub", "templates", "cm.yaml"), "kind: ConfigMap\nmetadata: {name: sub}\n")
+	parent := filepath.Join(dir, "parent")
+	writeFile(filepath.Join(parent, "Chart.yaml"), `apiVersion: v2
+name: parent
+version: 0.1.0
+dependencies:
+  - name: sub
+    version: 0.1.0
+    repository: file://../sub
+`)
+
+	r, err := New(parent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.CheckDependencies(); err == nil {
+		t.Fatal("expected the unbuilt dependency to be reported missing")
+	}
+
+	if err := r.BuildDependencies(io.Discard); err != nil {
+		t.Fatalf("BuildDependencies failed: %v", err)
+	}
+	if err := r.CheckDependencies(); err != nil {
+		t.Errorf("expected dependencies to be present after building, got %v", err)
+	}
+	if result := r.Run(nil); !result.Success || len(result.Templates) != 1 {
+		t.Errorf("expected the subchart template to render, got %+v", result)
+	}
+}
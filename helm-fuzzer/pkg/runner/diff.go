@@ -0,0 +1,115 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// maxDiffValueLength caps the length of values shown in a diff
+const maxDiffValueLength = 60
+
+// ValueChange is a value path that an input overrides relative to the chart defaults
+type ValueChange struct {
+	// Path is the dotted value path (e.g. "service.port")
+	Path string
+	// Default is the chart default, nil if the path is not in values.yaml
+	Default interface{}
+	// Value is the overriding value
+	Value interface{}
+	// Added reports whether the path is absent from the defaults
+	Added bool
+}
+
+// String renders the change as "+ path: value" or "~ path: value (default: old)"
+func (c ValueChange) String() string {
+	if c.Added {
+		return fmt.Sprintf("+ %s: %s", c.Path, formatDiffValue(c.Value))
+	}
+	return fmt.Sprintf("~ %s: %s (default: %s)", c.Path, formatDiffValue(c.Value), formatDiffValue(c.Default))
+}
+
+// DiffValues lists the leaf paths where values differ from defaults, sorted by
+// path. Nested maps present on both sides are compared key by key, matching how
+// Helm coalesces user values over the chart defaults.
+func DiffValues(defaults, values map[string]interface{}) []ValueChange {
+	var changes []ValueChange
+	diffValues("", defaults, values, &changes)
+	return changes
+}
+
+// MergeValues returns base with overrides coalesced over it: nested maps are
+// merged key by key and any other override value replaces the base value.
+// Neither input is modified.
+func MergeValues(base, overrides map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(base)+len(overrides))
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range overrides {
+		overrideMap, overrideIsMap := value.(map[string]interface{})
+		baseMap, baseIsMap := merged[key].(map[string]interface{})
+		if overrideIsMap && baseIsMap {
+			merged[key] = MergeValues(baseMap, overrideMap)
+			continue
+		}
+		merged[key] = value
+	}
+	return merged
+}
+
+func diffValues(prefix string, defaults, values map[string]interface{}, changes *[]ValueChange) {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+
+		value := values[key]
+		def, ok := defaults[key]
+		if !ok {
+			*changes = append(*changes, ValueChange{Path: path, Value: value, Added: true})
+			continue
+		}
+
+		valueMap, valueIsMap := value.(map[string]interface{})
+		defMap, defIsMap := def.(map[string]interface{})
+		if valueIsMap && defIsMap {
+			diffValues(path, defMap, valueMap, changes)
+			continue
+		}
+
+		// Compare encoded forms so ints from generators equal floats from values.yaml
+		if encodeDiffValue(value) != encodeDiffValue(def) {
+			*changes = append(*changes, ValueChange{Path: path, Default: def, Value: value})
+		}
+	}
+}
+
+// encodeDiffValue encodes a value as JSON, falling back to Go formatting
+func encodeDiffValue(v interface{}) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// formatDiffValue encodes a value for display, truncating long values
+func formatDiffValue(v interface{}) string {
+	runes := []rune(encodeDiffValue(v))
+	if len(runes) > maxDiffValueLength {
+		return string(runes[:maxDiffValueLength-3]) + "..."
+	}
+	return string(runes)
+}
@@ -0,0 +1,74 @@
+package runner
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffValues(t *testing.T) {
+	defaults := map[string]interface{}{
+		"replicaCount": float64(1),
+		"image":        map[string]interface{}{"repository": "nginx", "tag": "1.19"},
+		"service":      map[string]interface{}{"port": float64(80)},
+	}
+	values := map[string]interface{}{
+		"replicaCount": 1,
+		"image":        map[string]interface{}{"repository": "nginx", "tag": nil},
+		"service":      "oops",
+		"extra":        map[string]interface{}{"enabled": true},
+	}
+
+	var got []string
+	for _, c := range DiffValues(defaults, values) {
+		got = append(got, c.String())
+	}
+
+	want := []string{
+		`+ extra: {"enabled":true}`,
+		`~ image.tag: null (default: "1.19")`,
+		`~ service: "oops" (default: {"port":80})`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DiffValues() =\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestMergeValues(t *testing.T) {
+	base := map[string]interface{}{
+		"image":   map[string]interface{}{"repository": 42, "tag": "x"},
+		"service": "oops",
+	}
+	overrides := map[string]interface{}{
+		"image":   map[string]interface{}{"repository": "nginx"},
+		"service": map[string]interface{}{"port": 80},
+		"license": "abc",
+	}
+
+	got := MergeValues(base, overrides)
+	want := map[string]interface{}{
+		"image":   map[string]interface{}{"repository": "nginx", "tag": "x"},
+		"service": map[string]interface{}{"port": 80},
+		"license": "abc",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MergeValues() = %v, want %v", got, want)
+	}
+	if base["image"].(map[string]interface{})["repository"] != 42 {
+		t.Error("MergeValues modified its base")
+	}
+}
+
+func TestDefaultValues(t *testing.T) {
+	r, err := New("../../testdata/buggy-chart")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	defaults, err := r.DefaultValues()
+	if err != nil {
+		t.Fatalf("DefaultValues failed: %v", err)
+	}
+	if _, ok := defaults["replicaCount"]; !ok {
+		t.Errorf("expected replicaCount in defaults, got %v", defaults)
+	}
+}
@@ -0,0 +1,209 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Divergence kinds, from the new chart's point of view
+const (
+	// DivergenceRegression is an input the old chart renders and the new chart crashes on
+	DivergenceRegression = "regression"
+	// DivergenceFixed is an input the old chart crashes on and the new chart renders
+	DivergenceFixed = "fixed"
+	// DivergenceChangedError is an input both charts crash on with different errors
+	DivergenceChangedError = "changed error"
+	// DivergenceOutput is an input both charts render to materially different manifests
+	DivergenceOutput = "output changed"
+)
+
+// versionLabels change with every chart release, so they are not material
+var versionLabels = map[string]bool{
+	"helm.sh/chart":             true,
+	"app.kubernetes.io/version": true,
+	"chart":                     true,
+}
+
+// Divergence is an input two versions of a chart handle differently
+type Divergence struct {
+	Kind      string
+	OldReason string
+	NewReason string
+	// Changes lists the resources and fields whose rendered output differs
+	Changes []string
+}
+
+// Key identifies equivalent divergences: the same crashes, or output
+// differing in the same fields
+func (d *Divergence) Key() string {
+	switch d.Kind {
+	case DivergenceOutput:
+		return d.Kind + "/" + strings.Join(d.Changes, ",")
+	default:
+		return d.Kind + "/" + fingerprintOrEmpty(d.OldReason) + "/" + fingerprintOrEmpty(d.NewReason)
+	}
+}
+
+func fingerprintOrEmpty(reason string) string {
+	if reason == "" {
+		return ""
+	}
+	return Fingerprint(reason)
+}
+
+// Diverge compares the results of rendering the same input with the old and
+// new chart, returning nil when they behave the same
+func Diverge(oracle *Oracle, old, new *Result) *Divergence {
+	oldCrash := oracle.IsCrash(old) && oracle.IsInteresting(old)
+	newCrash := oracle.IsCrash(new) && oracle.IsInteresting(new)
+
+	switch {
+	case !oldCrash && newCrash:
+		return &Divergence{Kind: DivergenceRegression, NewReason: oracle.GetCrashReason(new)}
+	case oldCrash && !newCrash:
+		return &Divergence{Kind: DivergenceFixed, OldReason: oracle.GetCrashReason(old)}
+	case oldCrash && newCrash:
+		oldReason, newReason := oracle.GetCrashReason(old), oracle.GetCrashReason(new)
+		if Fingerprint(oldReason) == Fingerprint(newReason) {
+			return nil
+		}
+		return &Divergence{Kind: DivergenceChangedError, OldReason: oldReason, NewReason: newReason}
+	}
+
+	// Uninteresting failures on either side leave nothing to compare
+	if !old.Success || !new.Success {
+		return nil
+	}
+	changes, err := DiffManifests(old.Manifest, new.Manifest)
+	if err != nil {
+		return &Divergence{Kind: DivergenceOutput, Changes: []string{err.Error()}}
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	return &Divergence{Kind: DivergenceOutput, Changes: changes}
+}
+
+// DiffManifests compares two rendered YAML streams resource by resource and
+// returns the changed fields as "Kind/name: path", plus "+ Kind/name" and
+// "- Kind/name" for added and removed resources, sorted. Chart version labels
+// are ignored since they differ between any two releases.
+func DiffManifests(old, new string) ([]string, error) {
+	oldResources, err := parseResources(old)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse old manifest: %w", err)
+	}
+	newResources, err := parseResources(new)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse new manifest: %w", err)
+	}
+
+	var changes []string
+	for id, oldDoc := range oldResources {
+		newDoc, ok := newResources[id]
+		if !ok {
+			changes = append(changes, "- "+id)
+			continue
+		}
+		for _, path := range diffPaths("", oldDoc, newDoc) {
+			changes = append(changes, id+": "+path)
+		}
+	}
+	for id := range newResources {
+		if _, ok := oldResources[id]; !ok {
+			changes = append(changes, "+ "+id)
+		}
+	}
+
+	sort.Strings(changes)
+	return changes, nil
+}
+
+// parseResources splits a YAML stream into resources keyed by Kind/name
+func parseResources(manifest string) (map[string]interface{}, error) {
+	resources := make(map[string]interface{})
+	dec := yaml.NewDecoder(strings.NewReader(manifest))
+	for {
+		var doc map[string]interface{}
+		if err := dec.Decode(&doc); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if doc == nil {
+			continue
+		}
+
+		name := ""
+		if metadata, ok := doc["metadata"].(map[string]interface{}); ok {
+			name = fmt.Sprint(metadata["name"])
+		}
+		id := fmt.Sprintf("%v/%s", doc["kind"], name)
+		resources[id] = StripVersionLabels(doc)
+	}
+	return resources, nil
+}
+
+// StripVersionLabels returns a copy of a decoded document without the chart
+// version labels, wherever they appear, which change with every release
+func StripVersionLabels(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(t))
+		for k, child := range t {
+			if versionLabels[k] {
+				continue
+			}
+			out[k] = StripVersionLabels(child)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(t))
+		for i, child := range t {
+			out[i] = StripVersionLabels(child)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// diffPaths returns the dotted paths at which two documents differ, stopping
+// at lists since reordered items would otherwise report every index
+func diffPaths(prefix string, old, new interface{}) []string {
+	oldMap, oldOK := old.(map[string]interface{})
+	newMap, newOK := new.(map[string]interface{})
+	if !oldOK || !newOK {
+		if reflect.DeepEqual(old, new) {
+			return nil
+		}
+		if prefix == "" {
+			return []string{"."}
+		}
+		return []string{prefix}
+	}
+
+	var paths []string
+	for k, v := range oldMap {
+		paths = append(paths, diffPaths(join(prefix, k), v, newMap[k])...)
+	}
+	for k, v := range newMap {
+		if _, ok := oldMap[k]; !ok {
+			paths = append(paths, diffPaths(join(prefix, k), nil, v)...)
+		}
+	}
+	return paths
+}
+
+// join appends a key to a dotted path
+func join(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
@@ -0,0 +1,96 @@
+package runner
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDiffManifests(t *testing.T) {
+	old := `---
+# Source: app/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  labels:
+    helm.sh/chart: app-1.0.0
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers: [{name: app, image: "nginx:1"}]
+---
+# Source: app/templates/configmap.yaml
+kind: ConfigMap
+metadata: {name: app}
+`
+	new := `---
+# Source: app/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  labels:
+    helm.sh/chart: app-1.1.0
+spec:
+  replicas: 2
+  template:
+    spec:
+      containers: [{name: app, image: "nginx:2"}]
+---
+# Source: app/templates/service.yaml
+kind: Service
+metadata: {name: app}
+`
+	changes, err := DiffManifests(old, new)
+	if err != nil {
+		t.Fatalf("DiffManifests failed: %v", err)
+	}
+	want := []string{
+		"+ Service/app",
+		"- ConfigMap/app",
+		"Deployment/app: spec.replicas",
+		"Deployment/app: spec.template.spec.containers",
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("DiffManifests() = %v, want %v", changes, want)
+	}
+
+	if changes, _ := DiffManifests(old, old); len(changes) != 0 {
+		t.Errorf("expected no changes for identical manifests, got %v", changes)
+	}
+}
+
+func TestDiverge(t *testing.T) {
+	oracle := NewOracle()
+	ok := &Result{Success: true, Manifest: "kind: ConfigMap\nmetadata: {name: a}\n"}
+	changed := &Result{Success: true, Manifest: "kind: ConfigMap\nmetadata: {name: a}\ndata: {k: v}\n"}
+	nilPointer := &Result{Error: errors.New("template: app/templates/a.yaml:3:4: nil pointer evaluating interface {}.port")}
+	badType := &Result{Error: errors.New("template: app/templates/a.yaml:5:2: wrong type for value; expected string; got int")}
+
+	tests := []struct {
+		name     string
+		old, new *Result
+		want     string
+	}{
+		{"same", ok, ok, ""},
+		{"regression", ok, nilPointer, DivergenceRegression},
+		{"fixed", nilPointer, ok, DivergenceFixed},
+		{"same crash", nilPointer, nilPointer, ""},
+		{"changed error", nilPointer, badType, DivergenceChangedError},
+		{"output", ok, changed, DivergenceOutput},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := Diverge(oracle, tt.old, tt.new)
+			got := ""
+			if d != nil {
+				got = d.Kind
+			}
+			if got != tt.want {
+				t.Errorf("Diverge() kind = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package runner
+
+import (
+	"sort"
+
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+// replaceFiles replaces the top-level chart's files at the paths of files,
+// which templates read with .Files, removing those whose content is nil
+func replaceFiles(c *chart.Chart, files map[string][]byte) {
+	kept := c.Files[:0]
+	for _, f := range c.Files {
+		if _, ok := files[f.Name]; !ok {
+			kept = append(kept, f)
+		}
+	}
+	c.Files = kept
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if data := files[name]; data != nil {
+			c.Files = append(c.Files, &chart.File{Name: name, Data: data})
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package runner
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template/parse"
+
+	"gopkg.in/yaml.v3"
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+// lookupDefine is the define stubbed lookup calls include
+const lookupDefine = "helmfuzz.lookup"
+
+// lookupTemplate holds the define, added to the top-level chart so that
+// subchart templates can include it too
+const lookupTemplate = "templates/_helmfuzz_lookup.tpl"
+
+// stubLookup rewrites the lookup calls in the templates of c and its
+// subcharts to return objects instead of querying a cluster, which
+// client-only rendering never does:
+//
+//	lookup "v1" "Secret" .Release.Namespace "db"
+//	fromYaml (include "helmfuzz.lookup" (list "v1" "Secret" .Release.Namespace "db"))
+//
+// The rewrite stays on the same line, so errors keep their line numbers.
+// Objects that are not there are an empty map, as lookup returns; a name of
+// "" lists the kind in the namespace, or in every namespace when that is "".
+// Objects without a namespace are found in the release's namespace and, for
+// cluster-scoped kinds, in "".
+func stubLookup(c *chart.Chart, objects []map[string]interface{}, namespace string) error {
+	table, err := lookupTable(objects, namespace)
+	if err != nil {
+		return err
+	}
+	define := fmt.Sprintf("{{- define %s -}}\n{{- get (fromYaml %s) (join \"/\" .) -}}\n{{- end -}}\n",
+		strconv.Quote(lookupDefine), strconv.Quote(table))
+
+	var walk func(c *chart.Chart)
+	walk = func(c *chart.Chart) {
+		for _, t := range c.Templates {
+			t.Data = []byte(stubTemplate(t.Name, string(t.Data)))
+		}
+		for _, dep := range c.Dependencies() {
+			walk(dep)
+		}
+	}
+	walk(c)
+	c.Templates = append(c.Templates, &chart.File{Name: lookupTemplate, Data: []byte(define)})
+	return nil
+}
+
+// lookupTable encodes what each lookup finds as YAML, keyed by its
+// arguments joined with slashes
+func lookupTable(objects []map[string]interface{}, namespace string) (string, error) {
+	found := make(map[string]interface{})
+	lists := make(map[string][]interface{})
+	for _, obj := range objects {
+		apiVersion, _ := obj["apiVersion"].(string)
+		kind, _ := obj["kind"].(string)
+		metadata, _ := obj["metadata"].(map[string]interface{})
+		name, _ := metadata["name"].(string)
+		objNamespace, _ := metadata["namespace"].(string)
+
+		namespaces := []string{objNamespace}
+		if objNamespace == "" {
+			namespaces = []string{namespace, ""}
+		}
+		for _, ns := range namespaces {
+			inNamespace := obj
+			if ns != "" && objNamespace == "" {
+				inNamespace = withNamespace(obj, ns)
+			}
+			found[lookupKey(apiVersion, kind, ns, name)] = inNamespace
+			lists[lookupKey(apiVersion, kind, ns, "")] = append(lists[lookupKey(apiVersion, kind, ns, "")], inNamespace)
+		}
+		if objNamespace != "" {
+			lists[lookupKey(apiVersion, kind, "", "")] = append(lists[lookupKey(apiVersion, kind, "", "")], obj)
+		}
+	}
+	for key, items := range lists {
+		parts := strings.Split(key, "/")
+		found[key] = map[string]interface{}{
+			"apiVersion": strings.Join(parts[:len(parts)-3], "/"),
+			"kind":       parts[len(parts)-3] + "List",
+			"items":      items,
+		}
+	}
+
+	table := make(map[string]string, len(found))
+	for key, obj := range found {
+		data, err := yaml.Marshal(obj)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode lookup object %s: %w", key, err)
+		}
+		table[key] = string(data)
+	}
+	data, err := yaml.Marshal(table)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode lookup objects: %w", err)
+	}
+	return string(data), nil
+}
+
+// lookupKey joins lookup's arguments as the define does
+func lookupKey(apiVersion, kind, namespace, name string) string {
+	return strings.Join([]string{apiVersion, kind, namespace, name}, "/")
+}
+
+// withNamespace copies an object into a namespace
+func withNamespace(obj map[string]interface{}, namespace string) map[string]interface{} {
+	metadata := map[string]interface{}{"namespace": namespace}
+	if m, ok := obj["metadata"].(map[string]interface{}); ok {
+		for k, v := range m {
+			metadata[k] = v
+		}
+		metadata["namespace"] = namespace
+	}
+	copied := make(map[string]interface{}, len(obj))
+	for k, v := range obj {
+		copied[k] = v
+	}
+	copied["metadata"] = metadata
+	return copied
+}
+
+// stubTemplate rewrites the lookup calls of one template file, returning
+// the source unchanged when it has none or does not parse
+func stubTemplate(name, src string) string {
+	if !strings.Contains(src, "lookup") || path.Base(name) == "NOTES.txt" {
+		return src
+	}
+	treeSet := make(map[string]*parse.Tree)
+	t := parse.New(name)
+	t.Mode = parse.SkipFuncCheck
+	if _, err := t.Parse(src, "{{", "}}", treeSet); err != nil {
+		return src
+	}
+
+	var calls []int
+	for _, tree := range treeSet {
+		calls = append(calls, lookupCalls(tree.Root)...)
+	}
+	// Rewrite from the end, so earlier offsets stay put
+	sort.Sort(sort.Reverse(sort.IntSlice(calls)))
+	for _, start := range calls {
+		args := start + len("lookup")
+		end := commandEnd(src, args)
+		src = src[:start] + "fromYaml (include " + strconv.Quote(lookupDefine) + " (list" + src[args:end] + "))" + src[end:]
+	}
+	return src
+}
+
+// lookupCalls returns the offsets of the lookup commands under n
+func lookupCalls(n parse.Node) []int {
+	var calls []int
+	var visit func(n parse.Node)
+	branch := func(b *parse.BranchNode) {
+		visit(b.Pipe)
+		visit(b.List)
+		visit(b.ElseList)
+	}
+	visit = func(n parse.Node) {
+		switch n := n.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, child := range n.Nodes {
+				visit(child)
+			}
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+			for _, cmd := range n.Cmds {
+				if len(cmd.Args) == 0 {
+					continue
+				}
+				if ident, ok := cmd.Args[0].(*parse.IdentifierNode); ok && ident.Ident == "lookup" {
+					calls = append(calls, int(ident.Position()))
+				}
+				for _, arg := range cmd.Args {
+					visit(arg)
+				}
+			}
+		case *parse.ActionNode:
+			visit(n.Pipe)
+		case *parse.TemplateNode:
+			visit(n.Pipe)
+		case *parse.ChainNode:
+			visit(n.Node)
+		case *parse.IfNode:
+			branch(&n.BranchNode)
+		case *parse.WithNode:
+			branch(&n.BranchNode)
+		case *parse.RangeNode:
+			branch(&n.BranchNode)
+		}
+	}
+	visit(n)
+	return calls
+}
+
+// commandEnd returns where the command whose arguments start at i ends:
+// before the pipe, closing parenthesis or action delimiter that ends it and
+// any space and trim marker in front of that
+func commandEnd(src string, i int) int {
+	depth := 0
+	for ; i < len(src); i++ {
+		switch src[i] {
+		case '"', '\'':
+			quote := src[i]
+			for i++; i < len(src) && src[i] != quote; i++ {
+				if src[i] == '\\' {
+					i++
+				}
+			}
+		case '`':
+			if j := strings.IndexByte(src[i+1:], '`'); j >= 0 {
+				i += j + 1
+			}
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 {
+				return trimSpaceBefore(src, i)
+			}
+			depth--
+		case '|':
+			if depth == 0 {
+				return trimSpaceBefore(src, i)
+			}
+		case '}':
+			if depth == 0 && strings.HasPrefix(src[i:], "}}") {
+				end := i
+				if end >= 2 && src[end-1] == '-' && isSpace(src[end-2]) {
+					end--
+				}
+				return trimSpaceBefore(src, end)
+			}
+		}
+	}
+	return len(src)
+}
+
+// trimSpaceBefore moves i back over the spaces before it
+func trimSpaceBefore(src string, i int) int {
+	for i > 0 && isSpace(src[i-1]) {
+		i--
+	}
+	return i
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
@@ -0,0 +1,87 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStubTemplate(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{
+			src:  `{{ $s := lookup "v1" "Secret" .Release.Namespace "db" }}`,
+			want: `{{ $s := fromYaml (include "helmfuzz.lookup" (list "v1" "Secret" .Release.Namespace "db")) }}`,
+		},
+		{
+			src:  `{{- if (lookup "v1" "Secret" "a}}b" "db") -}}ok{{ end }}`,
+			want: `{{- if (fromYaml (include "helmfuzz.lookup" (list "v1" "Secret" "a}}b" "db"))) -}}ok{{ end }}`,
+		},
+		{
+			src:  `{{ (lookup "v1" "Secret" "" "").items | len -}}`,
+			want: `{{ (fromYaml (include "helmfuzz.lookup" (list "v1" "Secret" "" ""))).items | len -}}`,
+		},
+		{
+			src:  `{{ "lookup" }}`,
+			want: `{{ "lookup" }}`,
+		},
+		{
+			src:  "{{ if }",
+			want: "{{ if }",
+		},
+	}
+	for _, tt := range tests {
+		if got := stubTemplate("app/templates/t.yaml", tt.src); got != tt.want {
+			t.Errorf("stubTemplate(%s)\n got %s\nwant %s", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestRun_Lookup(t *testing.T) {
+	chartPath := t.TempDir()
+	files := map[string]string{
+		"Chart.yaml": "apiVersion: v2\nname: app\nversion: 0.1.0\n",
+		"templates/configmap.yaml": `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app
+data:
+{{- $secret := lookup "v1" "Secret" .Release.Namespace "db" }}
+  password: {{ $secret.data.password | quote }}
+  missing: {{ lookup "v1" "Secret" .Release.Namespace "other" | len | quote }}
+  namespaces: {{ (lookup "v1" "Namespace" "" "").items | len | quote }}
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(chartPath, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r, err := NewWithOptions(chartPath, Options{
+		Namespace: "prod",
+		Lookup: []map[string]interface{}{
+			{"apiVersion": "v1", "kind": "Secret", "metadata": map[string]interface{}{"name": "db"}, "data": map[string]interface{}{"password": "cGFzcw=="}},
+			{"apiVersion": "v1", "kind": "Namespace", "metadata": map[string]interface{}{"name": "prod"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := r.Run(nil)
+	if !result.Success {
+		t.Fatalf("expected the stubbed lookups to render, got %v", result.Error)
+	}
+	for _, want := range []string{`password: "cGFzcw=="`, `missing: "0"`, `namespaces: "1"`} {
+		if !strings.Contains(result.Manifest, want) {
+			t.Errorf("expected %s in\n%s", want, result.Manifest)
+		}
+	}
+}
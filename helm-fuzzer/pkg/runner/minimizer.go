@@ -5,13 +5,28 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
 
+// ReproPattern matches the names of reproduction files
+const ReproPattern = "fuzzer-repro-*.yaml"
+
+// reasonPrefix starts the header line of a reproduction file that records its crash reason
+const reasonPrefix = "# Crash Reason: "
+
+// OutputPattern matches the names of the rendered output files saved beside
+// reproduction files
+const OutputPattern = "fuzzer-output-*.yaml"
+
 // Minimizer handles shrinking failing inputs and saving reproduction files
 type Minimizer struct {
 	outputDir string
+	// KeepOutput caps the bytes of a successful render's manifest saved
+	// beside its reproduction file: 0, the default, saves none and a
+	// negative cap saves all of it
+	KeepOutput int64
 }
 
 // NewMinimizer creates a new minimizer
@@ -23,10 +38,25 @@ func NewMinimizer(outputDir string) *Minimizer {
 
 // SaveReproduction saves a failing input to a reproduction file
 func (m *Minimizer) SaveReproduction(result *Result, reason string) (string, error) {
-	// Generate hash of the values for unique filename
+	// Generate hash of the values for unique filename; the same values
+	// crashing with other built-in objects are a different case
 	hash := m.hashValues(result.Values)
+	if result.BuiltIns != nil {
+		hash = m.hashValues(map[string]interface{}{"values": result.Values, "builtIns": *result.BuiltIns})
+	}
+	return m.save(result, reason, hash[:8], nil)
+}
+
+// SaveFinding saves the input of a unique crash to a reproduction file named
+// after its finding ID, so each session finding the crash writes the same
+// file. Culprits, the value paths the crash needs, are listed in its header.
+func (m *Minimizer) SaveFinding(result *Result, reason, id string, culprits []string) (string, error) {
+	return m.save(result, reason, id, culprits)
+}
 
-	filename := fmt.Sprintf("fuzzer-repro-%s.yaml", hash[:8])
+// save writes a reproduction file named fuzzer-repro-<name>.yaml
+func (m *Minimizer) save(result *Result, reason, name string, culprits []string) (string, error) {
+	filename := fmt.Sprintf("fuzzer-repro-%s.yaml", name)
 	filepath := filepath.Join(m.outputDir, filename)
 
 	// Create output directory if it doesn't exist
@@ -35,7 +65,23 @@ func (m *Minimizer) SaveReproduction(result *Result, reason string) (string, err
 	}
 
 	// Add comment header with crash information
-	header := fmt.Sprintf("# Helm Fuzz Reproduction Case\n# Crash Reason: %s\n# To reproduce: helm install --dry-run <chart> -f %s\n\n", reason, filename)
+	header := "# Helm Fuzz Reproduction Case\n" + reasonPrefix + reason + "\n"
+	if len(culprits) > 0 {
+		header += "# Culprit Paths: " + strings.Join(culprits, ", ") + "\n"
+	}
+	outputName, err := m.saveOutput(result, name)
+	if err != nil {
+		return "", err
+	}
+	if outputName != "" {
+		header += "# Rendered Output: " + outputName + "\n"
+	}
+	if b := result.BuiltIns; b != nil {
+		header += fmt.Sprintf("# Built-in Objects: %s\n# To reproduce: set appVersion in Chart.yaml, then helm template %s <chart> --namespace %s --kube-version %s -f %s\n\n",
+			b, b.ReleaseName, b.Namespace, b.KubeVersion, filename)
+	} else {
+		header += fmt.Sprintf("# To reproduce: helm install --dry-run <chart> -f %s\n\n", filename)
+	}
 
 	// Marshal values to YAML
 	data, err := yaml.Marshal(result.Values)
@@ -52,6 +98,69 @@ func (m *Minimizer) SaveReproduction(result *Result, reason string) (string, err
 	return filepath, nil
 }
 
+// saveOutput writes a successful render's manifest, cut to KeepOutput bytes,
+// to fuzzer-output-<name>.yaml and returns the file's name. Without a
+// manifest to keep it removes any such file left by an earlier save, so
+// the file always belongs to the latest one, and returns an empty name.
+func (m *Minimizer) saveOutput(result *Result, name string) (string, error) {
+	filename := fmt.Sprintf("fuzzer-output-%s.yaml", name)
+	path := filepath.Join(m.outputDir, filename)
+	if m.KeepOutput == 0 || result.Manifest == "" {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return "", fmt.Errorf("failed to remove rendered output: %w", err)
+		}
+		return "", nil
+	}
+	if err := os.WriteFile(path, []byte(truncateOutput(result.Manifest, m.KeepOutput)), 0644); err != nil {
+		return "", fmt.Errorf("failed to write rendered output: %w", err)
+	}
+	return filename, nil
+}
+
+// truncateOutput cuts a manifest to at most limit bytes at the end of a
+// line, noting how much was left out; a negative limit keeps it whole
+func truncateOutput(manifest string, limit int64) string {
+	if limit < 0 || int64(len(manifest)) <= limit {
+		return manifest
+	}
+	kept := manifest[:limit]
+	if i := strings.LastIndexByte(kept, '\n'); i >= 0 {
+		kept = kept[:i+1]
+	} else {
+		kept = ""
+	}
+	return kept + fmt.Sprintf("# ... %d more bytes not kept (keepOutput)\n", len(manifest)-len(kept))
+}
+
+// LoadReproduction reads a reproduction file, returning its values and the
+// crash reason recorded in its header, empty for a plain values file
+func LoadReproduction(path string) (map[string]interface{}, string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read reproduction file: %w", err)
+	}
+
+	values := make(map[string]interface{})
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, "", fmt.Errorf("failed to parse reproduction file %s: %w", path, err)
+	}
+	if values == nil {
+		values = make(map[string]interface{})
+	}
+
+	reason := ""
+	for _, line := range strings.Split(string(data), "\n") {
+		if !strings.HasPrefix(line, "#") {
+			break
+		}
+		if r, ok := strings.CutPrefix(line, reasonPrefix); ok {
+			reason = r
+			break
+		}
+	}
+	return values, reason, nil
+}
+
 // hashValues generates a hash of the values map
 func (m *Minimizer) hashValues(values map[string]interface{}) string {
 	// Marshal to YAML for consistent hashing
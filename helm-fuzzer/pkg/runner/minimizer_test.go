@@ -0,0 +1,94 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReproductionRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	values := map[string]interface{}{"ingress": map[string]interface{}{"enabled": true}}
+	reason := "Error: template: app/templates/ingress.yaml:3:4: nil pointer evaluating interface {}.host"
+
+	path, err := NewMinimizer(dir).SaveReproduction(&Result{Values: values}, reason)
+	if err != nil {
+		t.Fatalf("SaveReproduction failed: %v", err)
+	}
+	if ok, _ := filepath.Match(ReproPattern, filepath.Base(path)); !ok {
+		t.Errorf("expected %s to match %s", path, ReproPattern)
+	}
+
+	loaded, loadedReason, err := LoadReproduction(path)
+	if err != nil {
+		t.Fatalf("LoadReproduction failed: %v", err)
+	}
+	if !reflect.DeepEqual(loaded, values) || loadedReason != reason {
+		t.Errorf("expected the saved values and reason back, got %v %q", loaded, loadedReason)
+	}
+
+	// A finding's file lists its culprit paths and still loads
+	path, err = NewMinimizer(dir).SaveFinding(&Result{Values: values}, reason, "app-FZ-0a1b2c3d", []string{"ingress.enabled", "ingress.host"})
+	if err != nil {
+		t.Fatalf("SaveFinding failed: %v", err)
+	}
+	if data, _ := os.ReadFile(path); !strings.Contains(string(data), "\n# Culprit Paths: ingress.enabled, ingress.host\n") {
+		t.Errorf("expected the culprit paths in the header:\n%s", data)
+	}
+	if _, loadedReason, err = LoadReproduction(path); err != nil || loadedReason != reason {
+		t.Errorf("expected the reason back, got %q, %v", loadedReason, err)
+	}
+
+	// A plain values file has no recorded reason
+	plain := filepath.Join(dir, "values.yaml")
+	if err := os.WriteFile(plain, []byte("# replicas\nreplicas: 3\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, r, err := LoadReproduction(plain); err != nil || r != "" {
+		t.Errorf("expected no reason for a plain values file, got %q, %v", r, err)
+	}
+}
+
+func TestSaveFinding_Output(t *testing.T) {
+	dir := t.TempDir()
+	manifest := "---\n# Source: app/templates/cm.yaml\nkind: ConfigMap\ndata:\n  key: value\n"
+	result := &Result{Success: true, Values: map[string]interface{}{"key": "value"}, Manifest: manifest}
+	output := filepath.Join(dir, "fuzzer-output-app-FZ-0a1b2c3d.yaml")
+
+	m := NewMinimizer(dir)
+	m.KeepOutput = -1
+	path, err := m.SaveFinding(result, "Invariant: no ConfigMaps", "app-FZ-0a1b2c3d", nil)
+	if err != nil {
+		t.Fatalf("SaveFinding failed: %v", err)
+	}
+	if data, _ := os.ReadFile(output); string(data) != manifest {
+		t.Errorf("expected the whole manifest kept, got:\n%s", data)
+	}
+	if data, _ := os.ReadFile(path); !strings.Contains(string(data), "\n# Rendered Output: fuzzer-output-app-FZ-0a1b2c3d.yaml\n") {
+		t.Errorf("expected the output file in the header:\n%s", data)
+	}
+	if ok, _ := filepath.Match(ReproPattern, filepath.Base(output)); ok {
+		t.Errorf("expected %s not to be taken for a reproduction file", output)
+	}
+
+	// A cap keeps whole lines up to it
+	m.KeepOutput = 40
+	if _, err := m.SaveFinding(result, "Invariant: no ConfigMaps", "app-FZ-0a1b2c3d", nil); err != nil {
+		t.Fatalf("SaveFinding failed: %v", err)
+	}
+	want := "---\n# Source: app/templates/cm.yaml\n# ... 35 more bytes not kept (keepOutput)\n"
+	if data, _ := os.ReadFile(output); string(data) != want {
+		t.Errorf("expected the manifest truncated, got:\n%s", data)
+	}
+
+	// Keeping none removes what an earlier save kept
+	m.KeepOutput = 0
+	if _, err := m.SaveFinding(result, "Invariant: no ConfigMaps", "app-FZ-0a1b2c3d", nil); err != nil {
+		t.Fatalf("SaveFinding failed: %v", err)
+	}
+	if _, err := os.Stat(output); !os.IsNotExist(err) {
+		t.Errorf("expected no rendered output kept, got %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"sync/atomic"
+
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+// ErrOffline is wrapped by the errors of everything that needs the network
+// while it is disabled
+var ErrOffline = errors.New("network access is disabled")
+
+// offline disables the package's network access
+var offline atomic.Bool
+
+// SetOffline disables, or enables again, the network access of the package:
+// chart pulls from OCI registries and repositories, dependency builds, and
+// renders of charts whose values.schema.json references remote schemas,
+// which Helm fetches each time it validates values. They fail with an error
+// wrapping ErrOffline instead. It is meant for restricted build
+// environments, where a request leaving the machine is an error rather than
+// a timeout.
+func SetOffline(disabled bool) {
+	offline.Store(disabled)
+}
+
+// Offline reports whether the package's network access is disabled
+func Offline() bool {
+	return offline.Load()
+}
+
+// NeedsNetwork returns an error wrapping ErrOffline for doing what, such as
+// "pulling a chart", while the network is disabled, and nil otherwise
+func NeedsNetwork(what string) error {
+	if !offline.Load() {
+		return nil
+	}
+	return fmt.Errorf("%w: %s needs the network", ErrOffline, what)
+}
+
+// checkSchemaRefs returns an error wrapping ErrOffline if the network is
+// disabled and the chart's or a subchart's values.schema.json references a
+// remote schema
+func checkSchemaRefs(c *chart.Chart) error {
+	if !offline.Load() {
+		return nil
+	}
+	if refs := RemoteSchemaRefs(c); len(refs) > 0 {
+		return NeedsNetwork(fmt.Sprintf("validating values against %s, referenced by values.schema.json,", refs[0]))
+	}
+	return nil
+}
+
+// RemoteSchemaRefs returns the remote schemas the values.schema.json files
+// of the chart and its subcharts reference, sorted: $refs to http or https
+// URLs, directly or relative to a remote $id
+func RemoteSchemaRefs(c *chart.Chart) []string {
+	seen := make(map[string]bool)
+	var walkChart func(c *chart.Chart)
+	walkChart = func(c *chart.Chart) {
+		// Schemas without a URL in them cannot reference a remote one
+		if len(c.Schema) > 0 && bytes.Contains(c.Schema, []byte("://")) {
+			var schema interface{}
+			if json.Unmarshal(c.Schema, &schema) == nil {
+				remoteRefs(schema, &url.URL{}, seen)
+			}
+		}
+		for _, dep := range c.Dependencies() {
+			walkChart(dep)
+		}
+	}
+	walkChart(c)
+
+	refs := make([]string, 0, len(seen))
+	for ref := range seen {
+		refs = append(refs, ref)
+	}
+	sort.Strings(refs)
+	return refs
+}
+
+// remoteRefs adds the remote $refs below a schema node to seen, resolving
+// them against the base set by the nearest $id
+func remoteRefs(node interface{}, base *url.URL, seen map[string]bool) {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		if id, ok := v["$id"].(string); ok {
+			if u, err := url.Parse(id); err == nil {
+				base = base.ResolveReference(u)
+			}
+		}
+		if ref, ok := v["$ref"].(string); ok {
+			if u, err := url.Parse(ref); err == nil {
+				// References into the document itself, such as #/definitions/port, are not fetched
+				resolved, self := *base.ResolveReference(u), *base
+				resolved.Fragment, self.Fragment = "", ""
+				if (resolved.Scheme == "http" || resolved.Scheme == "https") && resolved.String() != self.String() {
+					seen[resolved.String()] = true
+				}
+			}
+		}
+		for _, child := range v {
+			remoteRefs(child, base, seen)
+		}
+	case []interface{}:
+		for _, child := range v {
+			remoteRefs(child, base, seen)
+		}
+	}
+}
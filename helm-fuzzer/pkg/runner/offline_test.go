@@ -0,0 +1,65 @@
+package runner
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+func TestRemoteSchemaRefs(t *testing.T) {
+	sub := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "sub"},
+		Schema:   []byte(`{"$id": "https://schemas.example.com/sub.json", "properties": {"a": {"$ref": "#/definitions/a"}, "b": {"$ref": "common.json#/b"}}}`),
+	}
+	c := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "app"},
+		Schema: []byte(`{"properties": {
+			"port": {"$ref": "#/definitions/port"},
+			"pod": {"$ref": "https://kubernetes.example.com/pod.json#/spec"},
+			"local": {"$ref": "file:///schemas/local.json"}
+		}}`),
+	}
+	c.AddDependency(sub)
+
+	want := []string{"https://kubernetes.example.com/pod.json", "https://schemas.example.com/common.json"}
+	if got := RemoteSchemaRefs(c); !reflect.DeepEqual(got, want) {
+		t.Errorf("RemoteSchemaRefs = %v, want %v", got, want)
+	}
+	if got := RemoteSchemaRefs(&chart.Chart{Metadata: &chart.Metadata{Name: "plain"}}); len(got) != 0 {
+		t.Errorf("expected no remote refs without a schema, got %v", got)
+	}
+
+	SetOffline(true)
+	defer SetOffline(false)
+	if err := checkSchemaRefs(c); !errors.Is(err, ErrOffline) {
+		t.Errorf("expected the remote refs to need the network, got %v", err)
+	}
+}
+
+func TestOffline(t *testing.T) {
+	SetOffline(true)
+	defer SetOffline(false)
+
+	if _, err := PullChart(t.TempDir(), "oci://registry.example.com/charts/app", "1.0.0"); !errors.Is(err, ErrOffline) {
+		t.Errorf("expected pulling to be refused offline, got %v", err)
+	}
+	if _, err := PullRepoChart(t.TempDir(), "https://charts.example.com", "app", ""); !errors.Is(err, ErrOffline) {
+		t.Errorf("expected downloading to be refused offline, got %v", err)
+	}
+	r, err := New("../../testdata/buggy-chart")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.BuildDependencies(io.Discard); !errors.Is(err, ErrOffline) {
+		t.Errorf("expected building dependencies to be refused offline, got %v", err)
+	}
+	if err := r.Validate(); err != nil {
+		t.Errorf("expected a chart without remote schemas to validate offline, got %v", err)
+	}
+	if res := r.Run(map[string]interface{}{}); res.Error != nil && errors.Is(res.Error, ErrOffline) {
+		t.Errorf("expected a chart without remote schemas to render offline, got %v", res.Error)
+	}
+}
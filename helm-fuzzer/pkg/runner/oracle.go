@@ -120,3 +120,107 @@ func (o *Oracle) IsInteresting(result *Result) bool {
 
 	return true
 }
+
+// IsInterestingReason reports whether a recorded crash reason would still be
+// reported under the oracle's current ignore and uninteresting patterns
+func (o *Oracle) IsInterestingReason(reason string) bool {
+	for _, patterns := range [][]string{o.IgnoreErrors, o.UninterestingPatterns} {
+		for _, pattern := range patterns {
+			if strings.Contains(reason, pattern) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Crash categories used to group findings
+const (
+	CategoryPanic      = "panic"
+	CategoryNilPointer = "nil pointer"
+	CategoryType       = "type mismatch"
+	CategoryParse      = "parse error"
+	CategoryTemplate   = "template error"
+	// CategoryPlugin is a finding reported by an oracle plugin
+	CategoryPlugin = "plugin"
+	// CategoryInvariant is a render breaking a scenario's invariant
+	CategoryInvariant = "invariant"
+	// CategoryBudget is a render requesting more than the configured budget
+	CategoryBudget = "budget"
+	// CategoryScanner is a check an external security scanner failed
+	CategoryScanner = "scanner"
+	// CategoryAPIServer is an object a real API server rejected
+	CategoryAPIServer = "api server"
+	// CategoryUpgrade is an object a real API server rejected as an upgrade
+	CategoryUpgrade = "upgrade"
+	// CategorySlow is a render taking far longer than the median render
+	CategorySlow = "slow render"
+	// CategoryRace is a render failing or differing when rendered concurrently
+	CategoryRace = "race"
+	// CategoryMemory is a live heap growing over a session
+	CategoryMemory = "memory growth"
+	// CategoryDefaultsDiff is a render straying far from the defaults' render
+	CategoryDefaultsDiff = "defaults diff"
+	// CategoryTimeout is a render cut short by the runner's timeout
+	CategoryTimeout = "timeout"
+	// CategoryYAMLQuirk is a string of the values rendering unquoted as another type
+	CategoryYAMLQuirk = "yaml quirk"
+	// CategoryNumber is a number of the values rendering rounded or in
+	// scientific notation
+	CategoryNumber = "number format"
+	// CategoryUnicode is invalid UTF-8, or a name or label over 63 bytes in
+	// 63 characters
+	CategoryUnicode = "unicode"
+	CategoryOther   = "other"
+)
+
+// CategorizeReason classifies a crash reason into a coarse category
+func CategorizeReason(reason string) string {
+	switch {
+	case strings.HasPrefix(reason, "Panic: "):
+		return CategoryPanic
+	case strings.HasPrefix(reason, "Plugin "):
+		return CategoryPlugin
+	case strings.HasPrefix(reason, "Invariant "):
+		return CategoryInvariant
+	case strings.HasPrefix(reason, "Budget "):
+		return CategoryBudget
+	case strings.HasPrefix(reason, "Scanner "):
+		return CategoryScanner
+	case strings.HasPrefix(reason, "API server rejected "):
+		return CategoryAPIServer
+	case strings.HasPrefix(reason, "Upgrade rejected "):
+		return CategoryUpgrade
+	case strings.HasPrefix(reason, "Slow render: "):
+		return CategorySlow
+	case strings.HasPrefix(reason, "Race: "):
+		return CategoryRace
+	case strings.HasPrefix(reason, "Memory growth: "):
+		return CategoryMemory
+	case strings.HasPrefix(reason, "Defaults diff"):
+		return CategoryDefaultsDiff
+	case strings.HasPrefix(reason, "YAML quirk: "):
+		return CategoryYAMLQuirk
+	case strings.HasPrefix(reason, "Number format: "):
+		return CategoryNumber
+	case strings.HasPrefix(reason, "Unicode: "):
+		return CategoryUnicode
+	case strings.Contains(reason, "render timed out"):
+		return CategoryTimeout
+	case strings.Contains(reason, "nil pointer"):
+		return CategoryNilPointer
+	case strings.Contains(reason, "wrong type"),
+		strings.Contains(reason, "incompatible types"),
+		strings.Contains(reason, "cannot unmarshal"):
+		return CategoryType
+	case strings.Contains(reason, "YAML parse error"),
+		strings.Contains(reason, "error converting YAML"),
+		strings.Contains(reason, "parse error"):
+		return CategoryParse
+	case strings.Contains(reason, "template:"),
+		strings.Contains(reason, "error calling"):
+		return CategoryTemplate
+	default:
+		return CategoryOther
+	}
+}
@@ -1,10 +1,42 @@
 package runner
 
 import (
+	"encoding/json"
 	"errors"
+	"reflect"
 	"testing"
+	"time"
 )
 
+func TestResult_JSON(t *testing.T) {
+	res := Result{
+		Error:     errors.New("template: app/templates/cm.yaml:3:4: nil pointer"),
+		Panic:     "boom",
+		Values:    map[string]interface{}{"name": "x"},
+		Templates: []string{"app/templates/cm.yaml"},
+		BuiltIns:  &BuiltIns{ReleaseName: "r", Namespace: "ns", Revision: 2, KubeVersion: "1.30.0"},
+		Duration:  250 * time.Millisecond,
+	}
+	data, err := json.Marshal(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"success":false,"error":"template: app/templates/cm.yaml:3:4: nil pointer","panic":"boom","values":{"name":"x"},"templates":["app/templates/cm.yaml"],` +
+		`"builtIns":{"releaseName":"r","namespace":"ns","revision":2,"appVersion":"","kubeVersion":"1.30.0"},"durationSeconds":0.25}`
+	if string(data) != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, data)
+	}
+
+	var decoded Result
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Error == nil || decoded.Error.Error() != res.Error.Error() || decoded.Panic != "boom" || decoded.Duration != res.Duration ||
+		!reflect.DeepEqual(decoded.Values, res.Values) || !reflect.DeepEqual(decoded.BuiltIns, res.BuiltIns) {
+		t.Errorf("expected the result back, got %+v", decoded)
+	}
+}
+
 func TestIsCrash(t *testing.T) {
 	oracle := NewOracle()
 
@@ -237,3 +269,39 @@ func TestDefaultUninterestingPatterns(t *testing.T) {
 		})
 	}
 }
+
+func TestCategorizeReason(t *testing.T) {
+	tests := []struct {
+		reason   string
+		expected string
+	}{
+		{"Panic: runtime error: index out of range", CategoryPanic},
+		{`Error: template: c/templates/d.yaml:25:12: executing "c/templates/d.yaml" at <.Values.a.b>: nil pointer evaluating interface {}.b`, CategoryNilPointer},
+		{`Error: template: c/templates/d.yaml:3:5: executing "c/templates/d.yaml" at <add .Values.n 1>: error calling add: wrong type for value; expected int64; got string`, CategoryType},
+		{"Error: YAML parse error on c/templates/d.yaml: error converting YAML to JSON", CategoryParse},
+		{`Error: template: c/templates/d.yaml:3:5: executing "c/templates/d.yaml" at <fail "boom">: error calling fail: boom`, CategoryTemplate},
+		{"Plugin images: container app uses the latest tag", CategoryPlugin},
+		{"Invariant violated: expected at least 1 Ingress", CategoryInvariant},
+		{"Budget exceeded: workloads run more than 50 pods", CategoryBudget},
+		{"Scanner trivy: KSV003 Default capabilities not dropped", CategoryScanner},
+		{"API server rejected Deployment: spec.replicas: Invalid value: must be greater than or equal to 0", CategoryAPIServer},
+		{"Upgrade rejected Deployment: spec.selector: Invalid value: field is immutable", CategoryUpgrade},
+		{"Slow render: took more than 10x the median render time", CategorySlow},
+		{"Memory growth: the live heap grew in 5 samples in a row, most with renders of app/templates/cm.yaml", CategoryMemory},
+		{"Defaults diff: no Deployment, which the defaults render", CategoryDefaultsDiff},
+		{`YAML quirk: the string "no" renders unquoted at data.enabled in app/templates/cm.yaml (ConfigMap), which YAML 1.1 parsers read as a boolean`, CategoryYAMLQuirk},
+		{"Number format: a whole number renders in scientific notation at spec.replicas in app/templates/deployment.yaml (Deployment)", CategoryNumber},
+		{"Unicode: invalid UTF-8 renders at name in app/templates/configmap.yaml", CategoryUnicode},
+		{"Error: render timed out after 30s in mychart/templates/configmap.yaml", CategoryTimeout},
+		{"Race: concurrent renders of the same input rendered app/templates/cm.yaml differently", CategoryRace},
+		{"Error: something else entirely", CategoryOther},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			if got := CategorizeReason(tt.reason); got != tt.expected {
+				t.Errorf("CategorizeReason() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
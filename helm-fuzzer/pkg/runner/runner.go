@@ -1,21 +1,133 @@
 package runner
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
 
 	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/chart/loader"
 	"helm.sh/helm/v3/pkg/chartutil"
 	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/engine"
+	kubefake "helm.sh/helm/v3/pkg/kube/fake"
+	"helm.sh/helm/v3/pkg/postrender"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/logging"
+)
+
+// defaultKubeVersion is the Kubernetes version used when none is configured
+const defaultKubeVersion = "1.28.0"
+
+// Release name and namespace charts are rendered into unless configured
+const (
+	DefaultReleaseName = "fuzz-test"
+	DefaultNamespace   = "default"
 )
 
-// Result represents the result of a fuzzing run
+// Result represents the result of a fuzzing run. It encodes to JSON with
+// the error and panic as their messages and the duration in seconds; decoded
+// results keep the messages as an error and a string panic.
 type Result struct {
 	Success bool
 	Error   error
 	Panic   interface{}
 	Values  map[string]interface{}
+	// Templates lists the templates that produced output (e.g. "mychart/templates/service.yaml")
+	Templates []string
+	// Manifest is the rendered release, hooks included, when rendering succeeded
+	Manifest string
+	// BuiltIns are the built-in objects rendered with, nil for the runner's own
+	BuiltIns *BuiltIns
+	// Duration is how long the render took, loading the chart included
+	Duration time.Duration
+}
+
+// resultJSON is the JSON form of a Result. It is a contract for tooling
+// consuming results, so fields are only ever added.
+type resultJSON struct {
+	Success   bool                   `json:"success"`
+	Error     string                 `json:"error,omitempty"`
+	Panic     string                 `json:"panic,omitempty"`
+	Values    map[string]interface{} `json:"values"`
+	Templates []string               `json:"templates,omitempty"`
+	Manifest  string                 `json:"manifest,omitempty"`
+	BuiltIns  *BuiltIns              `json:"builtIns,omitempty"`
+	Duration  float64                `json:"durationSeconds"`
+}
+
+// MarshalJSON encodes the result with stable field names
+func (r Result) MarshalJSON() ([]byte, error) {
+	out := resultJSON{
+		Success:   r.Success,
+		Values:    r.Values,
+		Templates: r.Templates,
+		Manifest:  r.Manifest,
+		BuiltIns:  r.BuiltIns,
+		Duration:  r.Duration.Seconds(),
+	}
+	if r.Error != nil {
+		out.Error = r.Error.Error()
+	}
+	if r.Panic != nil {
+		out.Panic = formatPanic(r.Panic)
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON decodes a result MarshalJSON encoded
+func (r *Result) UnmarshalJSON(data []byte) error {
+	var in resultJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	*r = Result{
+		Success:   in.Success,
+		Values:    in.Values,
+		Templates: in.Templates,
+		Manifest:  in.Manifest,
+		BuiltIns:  in.BuiltIns,
+		Duration:  time.Duration(in.Duration * float64(time.Second)),
+	}
+	if in.Error != "" {
+		r.Error = errors.New(in.Error)
+	}
+	if in.Panic != "" {
+		r.Panic = in.Panic
+	}
+	return nil
+}
+
+// BuiltIns are the values of Helm's built-in objects a render sees in
+// .Release, .Chart and .Capabilities, which charts depend on as much as on
+// their values
+type BuiltIns struct {
+	ReleaseName string `json:"releaseName"`
+	Namespace   string `json:"namespace"`
+	// Revision is .Release.Revision; above 1 the chart renders as an upgrade
+	// of the previous revision, with .Release.IsUpgrade set
+	Revision int `json:"revision"`
+	// AppVersion replaces the appVersion in Chart.yaml
+	AppVersion  string `json:"appVersion"`
+	KubeVersion string `json:"kubeVersion"`
+}
+
+// String describes the built-in objects in one line
+func (b *BuiltIns) String() string {
+	return fmt.Sprintf("release %s in namespace %s, revision %d, appVersion %q, kubeVersion %s",
+		b.ReleaseName, b.Namespace, b.Revision, b.AppVersion, b.KubeVersion)
 }
 
 // Runner executes Helm template rendering with fuzzing
@@ -23,31 +135,136 @@ type Runner struct {
 	chartPath   string
 	settings    *cli.EnvSettings
 	kubeVersion string
+	releaseName string
+	namespace   string
+	includeCRDs bool
+	postRender  postrender.PostRenderer
+	lookup      []map[string]interface{}
+	files       map[string][]byte
+	skip        []string
+	timeout     time.Duration
+	logger      *slog.Logger
+}
+
+// Options configures a runner
+type Options struct {
+	// KubeVersion is the Kubernetes version to render against (default 1.28.0)
+	KubeVersion string
+	// ReleaseName and Namespace are the release rendered, .Release.Name and
+	// .Release.Namespace in templates (default fuzz-test in default)
+	ReleaseName string
+	Namespace   string
+	// IncludeCRDs adds the chart's crds/ directory to the manifest, as helm
+	// template --include-crds does
+	IncludeCRDs bool
+	// PostRenderer rewrites the rendered manifest, hooks excluded, as with
+	// helm's --post-renderer; its errors fail the render
+	PostRenderer postrender.PostRenderer
+	// Lookup are the objects the lookup function finds, as if in a cluster;
+	// any other lookup finds nothing, as in client-only rendering
+	Lookup []map[string]interface{}
+	// Files replace the chart's files at their paths, which templates read
+	// with .Files; a nil content removes the file
+	Files map[string][]byte
+	// Skip are templates, named as in rendered manifests, left out of every
+	// render, such as those that fail without a cluster
+	Skip []string
+	// Timeout cuts a render short, failing it with the template that was
+	// executing; the render itself runs on until it ends (0: no timeout)
+	Timeout time.Duration
+	// Logger receives debug logging, including Helm's own; nil disables logging
+	Logger *slog.Logger
 }
 
 // New creates a new runner for the given chart path
 func New(chartPath string) (*Runner, error) {
-	return NewWithKubeVersion(chartPath, "1.28.0")
+	return NewWithOptions(chartPath, Options{})
 }
 
 // NewWithKubeVersion creates a new runner with a specific Kubernetes version
 func NewWithKubeVersion(chartPath string, kubeVersion string) (*Runner, error) {
+	return NewWithOptions(chartPath, Options{KubeVersion: kubeVersion})
+}
+
+// NewWithOptions creates a new runner with the given options
+func NewWithOptions(chartPath string, opts Options) (*Runner, error) {
 	// Verify chart path exists
 	if _, err := os.Stat(chartPath); os.IsNotExist(err) {
 		return nil, fmt.Errorf("chart path does not exist: %s", chartPath)
 	}
 
+	kubeVersion := opts.KubeVersion
+	if kubeVersion == "" {
+		kubeVersion = defaultKubeVersion
+	}
+
+	releaseName, namespace := opts.ReleaseName, opts.Namespace
+	if releaseName == "" {
+		releaseName = DefaultReleaseName
+	}
+	if namespace == "" {
+		namespace = DefaultNamespace
+	}
+
 	return &Runner{
 		chartPath:   chartPath,
 		settings:    cli.New(),
 		kubeVersion: kubeVersion,
+		releaseName: releaseName,
+		namespace:   namespace,
+		includeCRDs: opts.IncludeCRDs,
+		postRender:  opts.PostRenderer,
+		lookup:      opts.Lookup,
+		files:       opts.Files,
+		skip:        opts.Skip,
+		timeout:     opts.Timeout,
+		logger:      logging.OrDiscard(opts.Logger),
 	}, nil
 }
 
 // Run executes a single fuzzing iteration with the given values
 func (r *Runner) Run(values map[string]interface{}) *Result {
+	return r.RunWithBuiltIns(values, nil)
+}
+
+// RunWithBuiltIns executes a fuzzing iteration rendering with the given
+// built-in objects instead of the runner's release, chart and Kubernetes
+// version; nil renders as Run does
+func (r *Runner) RunWithBuiltIns(values map[string]interface{}, builtIns *BuiltIns) *Result {
+	if r.timeout <= 0 {
+		return r.render(values, builtIns)
+	}
+	// The render goes on after a timeout, so it gets values of its own
+	done := make(chan *Result, 1)
+	go func() {
+		res := r.render(copyValues(values), builtIns)
+		res.Values = values
+		done <- res
+	}()
+	timer := time.NewTimer(r.timeout)
+	defer timer.Stop()
+	select {
+	case res := <-done:
+		return res
+	case <-timer.C:
+		abandon(done)
+		return r.timedOut(values, builtIns)
+	}
+}
+
+// render executes a fuzzing iteration as RunWithBuiltIns does, however long
+// it takes
+func (r *Runner) render(values map[string]interface{}, builtIns *BuiltIns) *Result {
 	result := &Result{
-		Values: values,
+		Values:   values,
+		BuiltIns: builtIns,
+	}
+	started := time.Now()
+	defer func() { result.Duration = time.Since(started) }()
+
+	releaseName, namespace, kubeVersion := r.releaseName, r.namespace, r.kubeVersion
+	if builtIns != nil {
+		releaseName, namespace, kubeVersion = builtIns.ReleaseName, builtIns.Namespace, builtIns.KubeVersion
 	}
 
 	// Catch panics
@@ -56,6 +273,7 @@ func (r *Runner) Run(values map[string]interface{}) *Result {
 			result.Success = false
 			result.Panic = rec
 			result.Error = fmt.Errorf("PANIC: %v", rec)
+			r.logger.Debug("render panicked", "kubeVersion", kubeVersion, "panic", rec)
 		}
 	}()
 
@@ -66,43 +284,272 @@ func (r *Runner) Run(values map[string]interface{}) *Result {
 		result.Error = fmt.Errorf("failed to load chart: %w", err)
 		return result
 	}
+	if builtIns != nil {
+		chart.Metadata.AppVersion = builtIns.AppVersion
+	}
+	if len(r.files) > 0 {
+		replaceFiles(chart, r.files)
+	}
+	if len(r.skip) > 0 {
+		skipTemplates(chart, r.skip)
+	}
+	if len(r.lookup) > 0 {
+		if err := stubLookup(chart, r.lookup, namespace); err != nil {
+			result.Success = false
+			result.Error = err
+			return result
+		}
+	}
 
-	// Create action configuration
-	actionConfig := new(action.Configuration)
-	if err := actionConfig.Init(r.settings.RESTClientGetter(), r.settings.Namespace(), os.Getenv("HELM_DRIVER"), func(format string, v ...interface{}) {}); err != nil {
-		result.Success = false
-		result.Error = fmt.Errorf("failed to initialize action config: %w", err)
-		return result
+	var sources *sourceRecorder
+	var postRenderer postrender.PostRenderer
+	if r.postRender != nil {
+		sources = &sourceRecorder{next: r.postRender}
+		postRenderer = sources
 	}
 
-	// Create install action with dry-run
-	client := action.NewInstall(actionConfig)
-	client.DryRun = true
-	client.ClientOnly = true // Don't connect to cluster
-	client.ReleaseName = "fuzz-test"
-	client.Replace = true
-	client.Namespace = "default"
-	client.KubeVersion = &chartutil.KubeVersion{Version: r.kubeVersion}
-
-	// Run the installation (dry-run)
-	_, err = client.Run(chart, values)
+	var rel *release.Release
+	if builtIns != nil && builtIns.Revision > 1 {
+		rel, err = r.upgrade(chart, values, builtIns, postRenderer)
+	} else {
+		// Create action configuration
+		actionConfig := new(action.Configuration)
+		if err := actionConfig.Init(r.settings.RESTClientGetter(), r.settings.Namespace(), os.Getenv("HELM_DRIVER"), r.helmLog); err != nil {
+			result.Success = false
+			result.Error = fmt.Errorf("failed to initialize action config: %w", err)
+			return result
+		}
+
+		// Create install action with dry-run
+		client := action.NewInstall(actionConfig)
+		client.DryRun = true
+		client.ClientOnly = true // Don't connect to cluster
+		client.ReleaseName = releaseName
+		client.Replace = true
+		client.Namespace = namespace
+		kv := parseKubeVersion(kubeVersion)
+		client.KubeVersion = &kv
+		client.IncludeCRDs = r.includeCRDs
+		client.PostRenderer = postRenderer
+
+		// Run the installation (dry-run)
+		rel, err = client.Run(chart, values)
+	}
 	if err != nil {
+		r.logger.Debug("render failed", "kubeVersion", kubeVersion, "error", err)
 		result.Success = false
 		result.Error = err
 		return result
 	}
 
 	result.Success = true
+	manifest := rel.Manifest
+	if sources != nil {
+		manifest = sources.manifest
+	}
+	result.Templates = renderedTemplates(rel, manifest)
+	result.Manifest = releaseManifest(rel)
 	return result
 }
 
-// Validate performs a basic validation of the chart
+// upgrade renders the chart as a dry-run upgrade to builtIns.Revision of a
+// deployed release kept in memory, so no cluster is needed
+func (r *Runner) upgrade(c *chart.Chart, values map[string]interface{}, builtIns *BuiltIns, postRenderer postrender.PostRenderer) (*release.Release, error) {
+	mem := driver.NewMemory()
+	mem.SetNamespace(builtIns.Namespace)
+	caps := chartutil.DefaultCapabilities.Copy()
+	caps.KubeVersion = parseKubeVersion(builtIns.KubeVersion)
+	actionConfig := &action.Configuration{
+		Releases:     storage.Init(mem),
+		KubeClient:   &kubefake.PrintingKubeClient{Out: io.Discard},
+		Capabilities: caps,
+		Log:          r.helmLog,
+	}
+
+	previous := &release.Release{
+		Name:      builtIns.ReleaseName,
+		Namespace: builtIns.Namespace,
+		Version:   builtIns.Revision - 1,
+		Chart:     c,
+		Config:    map[string]interface{}{},
+		Info:      &release.Info{Status: release.StatusDeployed},
+	}
+	if err := actionConfig.Releases.Create(previous); err != nil {
+		return nil, fmt.Errorf("failed to record the previous revision: %w", err)
+	}
+
+	client := action.NewUpgrade(actionConfig)
+	client.DryRun = true
+	client.DryRunOption = "client"
+	client.Namespace = builtIns.Namespace
+	client.PostRenderer = postRenderer
+	return client.Run(builtIns.ReleaseName, c, values)
+}
+
+// parseKubeVersion parses a Kubernetes version as helm's --kube-version does,
+// so .Capabilities.KubeVersion has its major and minor versions; versions
+// that are not semver are kept as given
+func parseKubeVersion(version string) chartutil.KubeVersion {
+	if kv, err := chartutil.ParseKubeVersion(version); err == nil {
+		return *kv
+	}
+	return chartutil.KubeVersion{Version: version}
+}
+
+// Render renders the chart templates without parsing the output, so manifests
+// that fail YAML parsing during Run can still be inspected
+func (r *Runner) Render(values map[string]interface{}) (map[string]string, error) {
+	chart, err := loader.Load(r.chartPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chart: %w", err)
+	}
+	return r.RenderChart(chart, values)
+}
+
+// RenderChart renders an already loaded chart the way Render does, for
+// callers that rewrite its templates first. Rendering modifies the chart
+// while processing dependencies, so pass a freshly loaded copy each time.
+func (r *Runner) RenderChart(c *chart.Chart, values map[string]interface{}) (map[string]string, error) {
+	return r.renderChart(c, values, nil)
+}
+
+// renderChart renders as RenderChart does, with templates marked by
+// markTemplates reporting to p; nil renders them unmarked
+func (r *Runner) renderChart(c *chart.Chart, values map[string]interface{}, p *progress) (map[string]string, error) {
+	if err := checkSchemaRefs(c); err != nil {
+		return nil, err
+	}
+	if len(r.files) > 0 {
+		replaceFiles(c, r.files)
+	}
+	if len(r.skip) > 0 {
+		skipTemplates(c, r.skip)
+	}
+	if len(r.lookup) > 0 {
+		if err := stubLookup(c, r.lookup, r.namespace); err != nil {
+			return nil, err
+		}
+	}
+	if err := chartutil.ProcessDependenciesWithMerge(c, values); err != nil {
+		return nil, fmt.Errorf("failed to process dependencies: %w", err)
+	}
+
+	caps := chartutil.DefaultCapabilities.Copy()
+	caps.KubeVersion = parseKubeVersion(r.kubeVersion)
+	options := chartutil.ReleaseOptions{
+		Name:      r.releaseName,
+		Namespace: r.namespace,
+		IsInstall: true,
+	}
+
+	renderValues, err := chartutil.ToRenderValues(c, values, options, caps)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build render values: %w", err)
+	}
+	if p != nil {
+		if release, ok := renderValues["Release"].(map[string]interface{}); ok {
+			release[progressKey] = p
+		}
+	}
+
+	return engine.Render(c, renderValues)
+}
+
+// helmLog forwards Helm's debug output to the runner's logger
+func (r *Runner) helmLog(format string, v ...interface{}) {
+	r.logger.Debug(fmt.Sprintf(format, v...), "source", "helm")
+}
+
+// Validate performs a basic validation of the chart, failing too for a
+// chart that cannot render while the network is disabled
 func (r *Runner) Validate() error {
 	// Try to load the chart
-	_, err := loader.Load(r.chartPath)
+	c, err := loader.Load(r.chartPath)
 	if err != nil {
 		return fmt.Errorf("chart validation failed: %w", err)
 	}
 
-	return nil
+	return checkSchemaRefs(c)
+}
+
+// DefaultValues returns the chart's values.yaml defaults
+func (r *Runner) DefaultValues() (map[string]interface{}, error) {
+	chart, err := loader.Load(r.chartPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chart: %w", err)
+	}
+	if chart.Values == nil {
+		return map[string]interface{}{}, nil
+	}
+	return chart.Values, nil
+}
+
+// Templates lists the chart's renderable templates, including subcharts,
+// named as in rendered manifests. Partials and NOTES.txt are skipped.
+func (r *Runner) Templates() ([]string, error) {
+	root, err := loader.Load(r.chartPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chart: %w", err)
+	}
+
+	var names []string
+	var walk func(c *chart.Chart, prefix string)
+	walk = func(c *chart.Chart, prefix string) {
+		prefix = path.Join(prefix, c.Name())
+		for _, t := range c.Templates {
+			base := path.Base(t.Name)
+			if strings.HasPrefix(base, "_") || base == "NOTES.txt" {
+				continue
+			}
+			names = append(names, path.Join(prefix, t.Name))
+		}
+		for _, dep := range c.Dependencies() {
+			walk(dep, path.Join(prefix, "charts"))
+		}
+	}
+	walk(root, "")
+
+	sort.Strings(names)
+	return names, nil
+}
+
+// releaseManifest joins a release's manifest and its hooks into one YAML stream
+func releaseManifest(rel *release.Release) string {
+	if rel == nil {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString(rel.Manifest)
+	for _, hook := range rel.Hooks {
+		fmt.Fprintf(&b, "\n---\n# Source: %s\n%s", hook.Path, hook.Manifest)
+	}
+	return b.String()
+}
+
+// renderedTemplates extracts the templates that produced output from a
+// release, reading the manifest as rendered before any post-renderer
+func renderedTemplates(rel *release.Release, manifest string) []string {
+	if rel == nil {
+		return nil
+	}
+
+	names := manifestTemplates(manifest)
+	for _, hook := range rel.Hooks {
+		names = append(names, hook.Path)
+	}
+	return names
+}
+
+// sourceRecorder keeps the manifest a post-renderer is given, whose Source
+// comments name the templates that rendered; post-renderers such as kustomize
+// drop comments
+type sourceRecorder struct {
+	next     postrender.PostRenderer
+	manifest string
+}
+
+func (s *sourceRecorder) Run(rendered *bytes.Buffer) (*bytes.Buffer, error) {
+	s.manifest = rendered.String()
+	return s.next.Run(rendered)
 }
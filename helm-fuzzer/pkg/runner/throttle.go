@@ -0,0 +1,62 @@
+package runner
+
+import (
+	"sync"
+	"time"
+)
+
+// Throttle paces iterations so a worker is only busy for a share of wall time
+// and the workers together stay under a rate. It is safe for concurrent use.
+type Throttle struct {
+	// Percent is the target busy percentage (1-99); 0 or 100 disables pacing
+	Percent int
+	// Rate caps the iterations per second of every worker pacing with the
+	// throttle together; 0 disables the cap
+	Rate float64
+
+	mu sync.Mutex
+	// next is the earliest an iteration may start under the rate
+	next time.Time
+}
+
+// NewThrottle creates a new throttle targeting the given busy percentage
+func NewThrottle(percent int) *Throttle {
+	return &Throttle{
+		Percent: percent,
+	}
+}
+
+// Pace sleeps after an iteration that was busy for the given duration, until
+// both the busy percentage and the rate allow the worker's next iteration
+func (t *Throttle) Pace(busy time.Duration) {
+	now := time.Now()
+	until := t.reserve(now.Add(t.idleFor(busy)))
+	if idle := until.Sub(now); idle > 0 {
+		time.Sleep(idle)
+	}
+}
+
+// idleFor returns how long to idle so that busy/(busy+idle) matches the target percentage
+func (t *Throttle) idleFor(busy time.Duration) time.Duration {
+	if t == nil || t.Percent <= 0 || t.Percent >= 100 {
+		return 0
+	}
+	return busy * time.Duration(100-t.Percent) / time.Duration(t.Percent)
+}
+
+// reserve returns when an iteration ready at earliest may start under the
+// rate and takes that slot, so each worker waits its turn. Each worker's
+// first iteration starts unpaced.
+func (t *Throttle) reserve(earliest time.Time) time.Time {
+	if t == nil || t.Rate <= 0 {
+		return earliest
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	slot := earliest
+	if t.next.After(slot) {
+		slot = t.next
+	}
+	t.next = slot.Add(time.Duration(float64(time.Second) / t.Rate))
+	return slot
+}
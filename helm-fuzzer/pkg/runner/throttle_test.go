@@ -0,0 +1,50 @@
+package runner
+
+import (
+	"testing"
+	"time"
+)
+
+func TestThrottleIdleFor(t *testing.T) {
+	tests := []struct {
+		name     string
+		percent  int
+		busy     time.Duration
+		expected time.Duration
+	}{
+		{"disabled", 0, 100 * time.Millisecond, 0},
+		{"full speed", 100, 100 * time.Millisecond, 0},
+		{"half", 50, 100 * time.Millisecond, 100 * time.Millisecond},
+		{"quarter", 25, 100 * time.Millisecond, 300 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewThrottle(tt.percent).idleFor(tt.busy)
+			if got != tt.expected {
+				t.Errorf("idleFor(%v) = %v, want %v", tt.busy, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestThrottleReserve(t *testing.T) {
+	throttle := &Throttle{Rate: 10}
+	start := time.Now()
+
+	// Iterations ready at once are spaced by the rate, in the order they ask
+	for i := 0; i < 3; i++ {
+		if got, want := throttle.reserve(start), start.Add(time.Duration(i)*100*time.Millisecond); !got.Equal(want) {
+			t.Errorf("slot %d = %v, want %v", i, got.Sub(start), want.Sub(start))
+		}
+	}
+	// One ready after its slot starts when ready
+	late := start.Add(time.Second)
+	if got := throttle.reserve(late); !got.Equal(late) {
+		t.Errorf("expected a late iteration to start when ready, got %v", got.Sub(start))
+	}
+
+	if got := NewThrottle(50).reserve(start); !got.Equal(start) {
+		t.Errorf("expected no rate to never wait, got %v", got.Sub(start))
+	}
+}
@@ -0,0 +1,191 @@
+package runner
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+)
+
+// timeoutPattern matches the error of a render cut short by the runner's
+// timeout that names the template it was stuck in:
+// render timed out after 30s in mychart/templates/configmap.yaml
+var timeoutPattern = regexp.MustCompile(`render timed out after \S+ in (\S+)`)
+
+// progressKey is the key of .Release holding the progress marked templates
+// report to
+const progressKey = "HelmFuzzProgress"
+
+// abandoned counts the renders of every runner cut short by a timeout and
+// still running in the background
+var abandoned atomic.Int64
+
+// maxAbandoned is how many abandoned renders may run before a timed-out
+// render is no longer rendered again to find its template: two per CPU, a
+// render and the one finding its template
+var maxAbandoned = int64(2 * runtime.GOMAXPROCS(0))
+
+// stuck holds the templates, keyed by chart path and name, that renders were
+// found stuck in
+var stuck sync.Map
+
+type stuckKey struct {
+	chartPath, template string
+}
+
+// Abandoned returns how many renders cut short by a timeout are still
+// running in the background, using CPU and memory until they end
+func Abandoned() int {
+	return int(abandoned.Load())
+}
+
+// abandon counts a render left running in the background until it sends its
+// result on done
+func abandon[T any](done <-chan T) {
+	abandoned.Add(1)
+	go func() {
+		<-done
+		abandoned.Add(-1)
+	}()
+}
+
+// timedOut returns the result of a render cut short by the runner's timeout.
+// The template the render was stuck in, found by stuckTemplate, is named in
+// the error, which Attribute reads, so renders stuck in the same loop share a
+// finding. None is named when the render ends in time on its second try or
+// too many abandoned renders are running already.
+func (r *Runner) timedOut(values map[string]interface{}, builtIns *BuiltIns) *Result {
+	result := &Result{Values: values, BuiltIns: builtIns, Duration: r.timeout}
+	err := fmt.Errorf("render timed out after %s", r.timeout)
+	if name := r.stuckTemplate(values); name != "" {
+		err = fmt.Errorf("render timed out after %s in %s", r.timeout, name)
+	}
+	r.logger.Debug("render timed out", "timeout", r.timeout, "abandoned", Abandoned(), "error", err)
+	result.Error = err
+	return result
+}
+
+// stuckTemplate renders the chart's templates again, each marked to report
+// when it starts executing, and returns the template executing when the
+// runner's timeout is up. Helm executes templates one after another and text
+// templates cannot be inspected while they run, so the latest to start is the
+// one the render is stuck in. A template a render was found stuck in before
+// is returned as soon as this render reaches it. It returns an empty string
+// when the render ends in time or maxAbandoned renders already run in the
+// background; a render still running is left to finish there.
+func (r *Runner) stuckTemplate(values map[string]interface{}) string {
+	if abandoned.Load() >= maxAbandoned {
+		return ""
+	}
+	c, err := loader.Load(r.chartPath)
+	if err != nil {
+		return ""
+	}
+	markTemplates(c)
+	p := &progress{chartPath: r.chartPath, reached: make(chan struct{}, 1)}
+
+	done := make(chan struct{}, 1)
+	go func() {
+		// A render failing is done all the same
+		defer func() {
+			recover()
+			done <- struct{}{}
+		}()
+		r.renderChart(c, copyValues(values), p)
+	}()
+
+	timer := time.NewTimer(r.timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return ""
+	case <-p.reached:
+	case <-timer.C:
+	}
+	abandon(done)
+	name := p.template()
+	if name != "" {
+		stuck.Store(stuckKey{r.chartPath, name}, true)
+	}
+	return name
+}
+
+// markTemplates prefixes the templates of c and its subcharts, partials
+// aside, with a call reporting their name to the progress in .Release. The
+// call stays on the first line and renders nothing, so errors keep their
+// line numbers and manifests their content.
+func markTemplates(c *chart.Chart) {
+	forEachTemplate(c, func(name string, t *chart.File) {
+		if strings.HasPrefix(path.Base(t.Name), "_") {
+			return
+		}
+		mark := fmt.Sprintf("{{ $.Release.%s.Enter %s }}", progressKey, strconv.Quote(name))
+		t.Data = append([]byte(mark), t.Data...)
+	})
+}
+
+// progress is where a render of marked templates is
+type progress struct {
+	chartPath string
+	// reached is signalled when the render starts a template found stuck before
+	reached chan struct{}
+
+	mu      sync.Mutex
+	current string
+}
+
+// Enter records that the named template started executing. Marked
+// templates call it; it returns an empty string, so it renders nothing.
+func (p *progress) Enter(name string) string {
+	p.mu.Lock()
+	p.current = name
+	p.mu.Unlock()
+	if _, ok := stuck.Load(stuckKey{p.chartPath, name}); ok {
+		select {
+		case p.reached <- struct{}{}:
+		default:
+		}
+	}
+	return ""
+}
+
+// template returns the template executing, or the last to start
+func (p *progress) template() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.current
+}
+
+// copyValues deep-copies values, so a render left running never shares the
+// maps of the caller's input
+func copyValues(values map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		out[key] = copyValue(value)
+	}
+	return out
+}
+
+// copyValue deep-copies maps and slices
+func copyValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return copyValues(v)
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, child := range v {
+			out[i] = copyValue(child)
+		}
+		return out
+	default:
+		return v
+	}
+}
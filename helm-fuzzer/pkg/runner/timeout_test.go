@@ -0,0 +1,111 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"helm.sh/helm/v3/pkg/chart/loader"
+)
+
+func TestRunTimeout(t *testing.T) {
+	chartPath := timeoutChart(t)
+
+	r, err := NewWithOptions(chartPath, Options{Timeout: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result := r.Run(map[string]interface{}{"n": 1}); !result.Success {
+		t.Fatalf("expected a quick render to succeed, got %v", result.Error)
+	}
+
+	values := map[string]interface{}{"n": 3000}
+	result := r.Run(values)
+	if result.Success {
+		t.Fatal("expected the nested ranges to time out")
+	}
+	want := "render timed out after 100ms in app/templates/loop.yaml"
+	if result.Error.Error() != want {
+		t.Errorf("expected %q, got %q", want, result.Error)
+	}
+	if result.Values["n"] != 3000 {
+		t.Errorf("expected the result to keep the input, got %v", result.Values)
+	}
+	if attr := Attribute("Error: " + result.Error.Error()); attr == nil || attr.Template != "app/templates/loop.yaml" {
+		t.Errorf("expected the timeout attributed to the loop, got %+v", attr)
+	}
+	if Abandoned() == 0 {
+		t.Error("expected the timed-out renders counted as running in the background")
+	}
+	if _, ok := stuck.Load(stuckKey{chartPath, "app/templates/loop.yaml"}); !ok {
+		t.Error("expected the loop recorded as stuck")
+	}
+}
+
+func TestRunTimeout_TooManyAbandoned(t *testing.T) {
+	defer func(max int64) { maxAbandoned = max }(maxAbandoned)
+	maxAbandoned = 0
+
+	r, err := NewWithOptions(timeoutChart(t), Options{Timeout: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := r.Run(map[string]interface{}{"n": 3000})
+	if want := "render timed out after 100ms"; result.Error == nil || result.Error.Error() != want {
+		t.Errorf("expected %q without rendering again, got %v", want, result.Error)
+	}
+}
+
+func TestMarkTemplates(t *testing.T) {
+	chartPath := timeoutChart(t)
+	r, err := New(chartPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := map[string]interface{}{"n": 2}
+	want, err := r.Render(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := loader.Load(chartPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	markTemplates(c)
+	p := &progress{chartPath: chartPath, reached: make(chan struct{}, 1)}
+	got, err := r.renderChart(c, values, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected marked templates to render the same, got %v, want %v", got, want)
+	}
+	// Helm executes templates in reverse name order, the config map last
+	if name := p.template(); name != "app/templates/configmap.yaml" {
+		t.Errorf("expected the last template started to be the config map, got %q", name)
+	}
+}
+
+// timeoutChart writes a chart whose loop template runs for n² iterations
+func timeoutChart(t *testing.T) string {
+	t.Helper()
+	chartPath := t.TempDir()
+	files := map[string]string{
+		"Chart.yaml":               "apiVersion: v2\nname: app\nversion: 0.1.0\n",
+		"templates/configmap.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app\n",
+		"templates/loop.yaml":      "{{- range until (int .Values.n) }}{{ range until (int $.Values.n) }}{{ end }}{{ end }}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(chartPath, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return chartPath
+}
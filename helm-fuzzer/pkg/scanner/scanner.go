@@ -0,0 +1,289 @@
+// Package scanner runs existing security scanners, such as trivy config,
+// checkov or kube-score, over rendered manifests and reads their JSON output
+// into findings, so the checks a team already runs in CI ride along with
+// fuzzing. Unlike plugins, scanners need no knowledge of helmfuzz: each
+// format is parsed here.
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/config"
+)
+
+// ReasonPrefix starts the crash reason of every scanner finding
+const ReasonPrefix = "Scanner "
+
+// ManifestArg is the command argument replaced by the path of the manifest file
+const ManifestArg = "{manifest}"
+
+// Scanner is a configured scanner ready to run
+type Scanner struct {
+	Name    string
+	format  string
+	command []string
+	timeout time.Duration
+	ignore  map[string]bool
+}
+
+// check is one failed check of a scanner's output
+type check struct {
+	id    string
+	title string
+}
+
+// New prepares the scanners of cfg, resolving their commands for chartPath
+func New(cfg *config.Config, chartPath string) []*Scanner {
+	scanners := make([]*Scanner, 0, len(cfg.Scanners))
+	for _, s := range cfg.Scanners {
+		ignore := make(map[string]bool, len(s.Ignore))
+		for _, id := range s.Ignore {
+			ignore[id] = true
+		}
+		scanners = append(scanners, &Scanner{
+			Name:    s.Name,
+			format:  s.Format,
+			command: cfg.ResolveScannerCommand(chartPath, s),
+			timeout: s.Timeout,
+			ignore:  ignore,
+		})
+	}
+	return scanners
+}
+
+// Scan runs the scanner over a manifest, returning a crash reason per failed
+// check, sorted. A check failing for several resources is one reason, so
+// findings deduplicate by check rather than by the names inputs give resources.
+func (s *Scanner) Scan(ctx context.Context, manifest string) ([]string, error) {
+	output, err := s.run(ctx, manifest)
+	if err != nil {
+		return nil, err
+	}
+	var checks []check
+	switch s.format {
+	case config.ScannerTrivy:
+		checks, err = parseTrivy(output)
+	case config.ScannerCheckov:
+		checks, err = parseCheckov(output)
+	case config.ScannerKubeScore:
+		checks, err = parseKubeScore(output)
+	default:
+		err = fmt.Errorf("unknown format %q", s.format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scanner %s wrote invalid output: %w", s.Name, err)
+	}
+
+	seen := make(map[string]bool)
+	var reasons []string
+	for _, c := range checks {
+		if s.ignore[c.id] {
+			continue
+		}
+		reason := Reason(s.Name, c.id, c.title)
+		if !seen[reason] {
+			seen[reason] = true
+			reasons = append(reasons, reason)
+		}
+	}
+	sort.Strings(reasons)
+	return reasons, nil
+}
+
+// ScanAll runs each scanner over a manifest, collecting the crash reasons of their findings
+func ScanAll(ctx context.Context, scanners []*Scanner, manifest string) ([]string, error) {
+	var reasons []string
+	for _, s := range scanners {
+		found, err := s.Scan(ctx, manifest)
+		if err != nil {
+			return nil, err
+		}
+		reasons = append(reasons, found...)
+	}
+	return reasons, nil
+}
+
+// Baseline returns the set of reasons found in a render, usually of the
+// chart's defaults, which Without then leaves out of other renders' findings
+func Baseline(reasons []string) map[string]bool {
+	baseline := make(map[string]bool, len(reasons))
+	for _, reason := range reasons {
+		baseline[reason] = true
+	}
+	return baseline
+}
+
+// Without returns the reasons not in baseline
+func Without(reasons []string, baseline map[string]bool) []string {
+	var kept []string
+	for _, reason := range reasons {
+		if !baseline[reason] {
+			kept = append(kept, reason)
+		}
+	}
+	return kept
+}
+
+// Reason is the crash reason of a check the named scanner failed
+func Reason(name, id, title string) string {
+	if title == "" {
+		return ReasonPrefix + name + ": " + id
+	}
+	return ReasonPrefix + name + ": " + id + " " + title
+}
+
+// run runs the scanner over a manifest and returns its stdout. Scanners exit
+// non-zero when checks fail, so the exit status only counts when nothing was
+// written.
+func (s *Scanner) run(ctx context.Context, manifest string) ([]byte, error) {
+	args := append([]string{}, s.command[1:]...)
+	stdin := true
+	for _, arg := range args {
+		if arg == ManifestArg {
+			stdin = false
+		}
+	}
+	if !stdin {
+		dir, err := os.MkdirTemp("", "helmfuzz-scan-")
+		if err != nil {
+			return nil, fmt.Errorf("failed to write manifest for scanner %s: %w", s.Name, err)
+		}
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "manifest.yaml")
+		if err := os.WriteFile(path, []byte(manifest), 0600); err != nil {
+			return nil, fmt.Errorf("failed to write manifest for scanner %s: %w", s.Name, err)
+		}
+		for i, arg := range args {
+			if arg == ManifestArg {
+				args[i] = path
+			}
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, s.command[0], args...)
+	cmd.WaitDelay = time.Second
+	if stdin {
+		cmd.Stdin = strings.NewReader(manifest)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, fmt.Errorf("scanner %s timed out after %s", s.Name, s.timeout)
+	}
+	if err != nil && len(bytes.TrimSpace(stdout.Bytes())) == 0 {
+		if msg := lastLine(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("scanner %s failed: %w: %s", s.Name, err, msg)
+		}
+		return nil, fmt.Errorf("scanner %s failed: %w", s.Name, err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// parseTrivy reads the failed misconfigurations of trivy config --format json
+func parseTrivy(output []byte) ([]check, error) {
+	var report struct {
+		Results []struct {
+			Misconfigurations []struct {
+				ID     string `json:"ID"`
+				Title  string `json:"Title"`
+				Status string `json:"Status"`
+			} `json:"Misconfigurations"`
+		} `json:"Results"`
+	}
+	if err := json.Unmarshal(output, &report); err != nil {
+		return nil, err
+	}
+	var checks []check
+	for _, result := range report.Results {
+		for _, m := range result.Misconfigurations {
+			// Passed checks are listed too with --include-non-failures
+			if m.Status == "" || m.Status == "FAIL" {
+				checks = append(checks, check{id: m.ID, title: m.Title})
+			}
+		}
+	}
+	return checks, nil
+}
+
+// checkovReport is checkov's JSON output for one framework
+type checkovReport struct {
+	Results struct {
+		FailedChecks []struct {
+			CheckID   string `json:"check_id"`
+			CheckName string `json:"check_name"`
+		} `json:"failed_checks"`
+	} `json:"results"`
+}
+
+// parseCheckov reads the failed checks of checkov -o json, which writes one
+// report, or a list of them when several frameworks ran
+func parseCheckov(output []byte) ([]check, error) {
+	var reports []checkovReport
+	if trimmed := bytes.TrimSpace(output); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &reports); err != nil {
+			return nil, err
+		}
+	} else {
+		var report checkovReport
+		if err := json.Unmarshal(trimmed, &report); err != nil {
+			return nil, err
+		}
+		reports = []checkovReport{report}
+	}
+	var checks []check
+	for _, report := range reports {
+		for _, c := range report.Results.FailedChecks {
+			checks = append(checks, check{id: c.CheckID, title: c.CheckName})
+		}
+	}
+	return checks, nil
+}
+
+// kubeScoreWarning is kube-score's warning grade; checks graded at or below
+// it, down to critical at 1, failed
+const kubeScoreWarning = 5
+
+// parseKubeScore reads the critical and warning checks of kube-score score -o json
+func parseKubeScore(output []byte) ([]check, error) {
+	var objects []struct {
+		Checks []struct {
+			Check struct {
+				ID   string `json:"id"`
+				Name string `json:"name"`
+			} `json:"check"`
+			Grade   int  `json:"grade"`
+			Skipped bool `json:"skipped"`
+		} `json:"checks"`
+	}
+	if err := json.Unmarshal(output, &objects); err != nil {
+		return nil, err
+	}
+	var checks []check
+	for _, obj := range objects {
+		for _, c := range obj.Checks {
+			if !c.Skipped && c.Grade <= kubeScoreWarning {
+				checks = append(checks, check{id: c.Check.ID, title: c.Check.Name})
+			}
+		}
+	}
+	return checks, nil
+}
+
+// lastLine returns the last non-empty line of a scanner's stderr, usually its error
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
@@ -0,0 +1,111 @@
+package scanner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/config"
+)
+
+// script writes a shell script scanner and returns its configuration
+func script(t *testing.T, name, format, body string, args ...string) config.Scanner {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("scanners are shell scripts")
+	}
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return config.Scanner{Name: name, Format: format, Command: append([]string{path}, args...), Timeout: 5 * time.Second}
+}
+
+func TestScan(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		scanner config.Scanner
+		want    []string
+	}{
+		{
+			name: "trivy reads the manifest file",
+			scanner: script(t, "trivy", config.ScannerTrivy, `
+grep -q 'kind: Deployment' "$2" || exit 2
+echo '{"Results": [{"Misconfigurations": [
+  {"ID": "KSV001", "Title": "Process can elevate its own privileges", "Status": "FAIL"},
+  {"ID": "KSV003", "Title": "Default capabilities not dropped", "Status": "FAIL"},
+  {"ID": "KSV011", "Title": "CPU not limited", "Status": "PASS"}
+]}, {"Misconfigurations": [{"ID": "KSV003", "Title": "Default capabilities not dropped", "Status": "FAIL"}]}]}'
+exit 1
+`, "config", "{manifest}"),
+			want: []string{"Scanner trivy: KSV003 Default capabilities not dropped"},
+		},
+		{
+			name: "checkov lists frameworks",
+			scanner: script(t, "checkov", config.ScannerCheckov, `
+echo '[{"check_type": "kubernetes", "results": {"failed_checks": [{"check_id": "CKV_K8S_8", "check_name": "Liveness Probe Should be Configured"}]}},
+  {"check_type": "secrets", "results": {"failed_checks": []}}]'
+`, "-f", "{manifest}"),
+			want: []string{"Scanner checkov: CKV_K8S_8 Liveness Probe Should be Configured"},
+		},
+		{
+			name: "kube-score reads stdin",
+			scanner: script(t, "kube-score", config.ScannerKubeScore, `
+grep -q 'kind: Deployment' || exit 2
+echo '[{"object_name": "app", "checks": [
+  {"check": {"id": "container-resources", "name": "Container Resources"}, "grade": 1},
+  {"check": {"id": "pod-probes", "name": "Pod Probes"}, "grade": 5},
+  {"check": {"id": "container-image-tag", "name": "Container Image Tag"}, "grade": 10},
+  {"check": {"id": "pod-networkpolicy", "name": "Pod NetworkPolicy"}, "grade": 0, "skipped": true}
+]}]'
+`, "score", "-o", "json", "-"),
+			want: []string{"Scanner kube-score: container-resources Container Resources", "Scanner kube-score: pod-probes Pod Probes"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.scanner.Name == "trivy" {
+				tt.scanner.Ignore = []string{"KSV001"}
+			}
+			cfg := &config.Config{Scanners: []config.Scanner{tt.scanner}}
+			got, err := ScanAll(context.Background(), New(cfg, ""), "kind: Deployment\n")
+			if err != nil {
+				t.Fatalf("ScanAll failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestScan_Failures(t *testing.T) {
+	for _, tt := range []struct {
+		name, body, want string
+	}{
+		{"exit", "echo 'unknown flag' >&2\nexit 2\n", "unknown flag"},
+		{"garbage", "echo 'not json'\n", "invalid output"},
+		{"slow", "sleep 5\n", "timed out"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			s := script(t, tt.name, config.ScannerTrivy, tt.body)
+			s.Timeout = 200 * time.Millisecond
+			cfg := &config.Config{Scanners: []config.Scanner{s}}
+			_, err := New(cfg, "")[0].Scan(context.Background(), "")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestWithout(t *testing.T) {
+	baseline := Baseline([]string{"Scanner s: A", "Scanner s: B"})
+	if got := Without([]string{"Scanner s: B", "Scanner s: C"}, baseline); !reflect.DeepEqual(got, []string{"Scanner s: C"}) {
+		t.Errorf("expected only the new reason, got %v", got)
+	}
+}
@@ -27,6 +27,12 @@ func (e *Engine) InferFromValues(chartPath string) (*Schema, error) {
 	return e.inferSchema(values, "", 0), nil
 }
 
+// InferFromMap infers the schema of values found at path, such as a
+// subchart's defaults under its alias
+func (e *Engine) InferFromMap(values map[string]interface{}, path string) *Schema {
+	return e.inferSchema(values, path, 0)
+}
+
 // inferSchema recursively infers schema from a value
 func (e *Engine) inferSchema(value interface{}, path string, depth int) *Schema {
 	// Prevent infinite recursion
@@ -39,6 +45,7 @@ func (e *Engine) inferSchema(value interface{}, path string, depth int) *Schema
 		return &Schema{
 			Type:    e.inferType(value),
 			Default: value,
+			Ignored: true,
 		}
 	}
 
@@ -159,8 +166,9 @@ func (e *Engine) inferType(value interface{}) SchemaType {
 // schemaFromConstraint creates a schema from a config constraint
 func (e *Engine) schemaFromConstraint(constraint *config.Constraint, defaultValue interface{}) *Schema {
 	schema := &Schema{
-		Type:    SchemaType(constraint.Type),
-		Default: defaultValue,
+		Type:       SchemaType(constraint.Type),
+		Default:    defaultValue,
+		Constraint: constraint,
 	}
 
 	if constraint.Min != nil {
@@ -181,5 +189,7 @@ func (e *Engine) schemaFromConstraint(constraint *config.Constraint, defaultValu
 		schema.Enum = constraint.Enum
 	}
 
+	schema.Template = constraint.Template
+
 	return schema
 }
@@ -185,3 +185,51 @@ func TestSchemaDepthLimit(t *testing.T) {
 		t.Logf("level3 type: %v", level3.Type)
 	}
 }
+
+func TestInferFromMapAtPath(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Ignore = []string{"redis.auth"}
+	cfg.Constraints = []config.Constraint{{Path: "redis.port", Type: "int", Enum: []interface{}{6379, 6380}}}
+	engine := NewEngine(cfg)
+
+	s := engine.InferFromMap(map[string]interface{}{
+		"port": 6379,
+		"auth": map[string]interface{}{"password": "secret"},
+	}, "redis")
+
+	if !s.Properties["auth"].Ignored {
+		t.Error("expected redis.auth to be ignored")
+	}
+	if port := s.Properties["port"]; port.Constraint == nil || len(port.Enum) != 2 {
+		t.Errorf("expected the redis.port constraint, got %+v", port)
+	}
+
+	js, err := engine.ParseJSONSchema([]byte(`{"type":"object","properties":{"port":{"type":"integer"},"auth":{"type":"object","default":{}}}}`), "redis")
+	if err != nil {
+		t.Fatalf("ParseJSONSchema failed: %v", err)
+	}
+	if !js.Properties["auth"].Ignored || js.Properties["port"].Constraint == nil {
+		t.Errorf("expected paths under redis, got %+v", js.Properties)
+	}
+}
+
+func TestMarkRisk(t *testing.T) {
+	s := NewEngine(config.DefaultConfig()).InferFromMap(map[string]interface{}{
+		"ingress": map[string]interface{}{"tls": map[string]interface{}{"secretName": "tls"}},
+		"hosts":   []interface{}{map[string]interface{}{"name": "a"}},
+	}, "")
+
+	if !s.MarkRisk("ingress.tls", 2, true) || !s.MarkRisk("ingress.tls.secretName", 1, false) {
+		t.Fatal("expected the ingress paths to be marked")
+	}
+	ingress, tls := s.Properties["ingress"], s.Properties["ingress"].Properties["tls"]
+	if ingress.Risk != 3 || tls.Risk != 3 || tls.Properties["secretName"].Risk != 1 {
+		t.Errorf("expected risk added along the paths, got %d, %d", ingress.Risk, tls.Risk)
+	}
+	if ingress.Nilable || !tls.Nilable {
+		t.Error("expected only ingress.tls to be nilable")
+	}
+	if s.MarkRisk("hosts.name", 1, false) || s.Properties["hosts"].Risk != 0 {
+		t.Error("expected a path below an array not to be marked")
+	}
+}
@@ -3,33 +3,203 @@ package schema
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/invopop/jsonschema"
 	"github.com/kasuboski/helm-fuzzer/pkg/config"
+	"gopkg.in/yaml.v3"
 )
 
-// LoadJSONSchema loads and parses values.schema.json
-func (e *Engine) LoadJSONSchema(chartPath string) (*Schema, error) {
-	schemaPath := filepath.Join(chartPath, "values.schema.json")
+// SchemaFiles are the files a chart's values schema is loaded from, in the
+// order they are looked for. Helm itself only validates values against
+// values.schema.json; the YAML forms are common where tooling writes or
+// lints the schema.
+var SchemaFiles = []string{"values.schema.json", "values.schema.yaml", "values.schema.yml"}
 
-	// Check if file exists
-	if _, err := os.Stat(schemaPath); os.IsNotExist(err) {
-		return nil, err
+// Dialect is a JSON Schema draft, which the $schema keyword names
+type Dialect string
+
+const (
+	DialectDraft4 Dialect = "draft-04"
+	DialectDraft6 Dialect = "draft-06"
+	DialectDraft7 Dialect = "draft-07"
+	Dialect201909 Dialect = "2019-09"
+	Dialect202012 Dialect = "2020-12"
+)
+
+// DetectDialect returns the draft a $schema URI names. Schemas without one,
+// or with one naming no known draft, are draft-07, the draft Helm validates.
+func DetectDialect(uri string) Dialect {
+	for _, d := range []Dialect{DialectDraft4, DialectDraft6, DialectDraft7, Dialect201909, Dialect202012} {
+		if strings.Contains(uri, "/draft-"+strings.TrimPrefix(string(d), "draft-")+"/") ||
+			strings.Contains(uri, "/draft/"+string(d)+"/") {
+			return d
+		}
+	}
+	return DialectDraft7
+}
+
+// LoadJSONSchema loads and parses the first of SchemaFiles the chart has,
+// returning the file it came from
+func (e *Engine) LoadJSONSchema(chartPath string) (*Schema, Source, error) {
+	for _, name := range SchemaFiles {
+		data, err := os.ReadFile(filepath.Join(chartPath, name))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, "", err
+		}
+		schema, err := e.ParseJSONSchema(data, "")
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to parse %s: %w", name, err)
+		}
+		return schema, Source(name), nil
+	}
+	return nil, "", os.ErrNotExist
+}
+
+// ParseJSONSchema converts a JSON Schema document, in JSON or YAML, describing
+// the values at path, such as a subchart's values.schema.json under its
+// alias; ignore entries and constraints match the full paths. Keywords of
+// the document's dialect are read as that draft defines them.
+func (e *Engine) ParseJSONSchema(data []byte, path string) (*Schema, error) {
+	var doc interface{}
+	// JSON is YAML, but YAML rejects tabs JSON allows, so JSON is tried first
+	if err := json.Unmarshal(data, &doc); err != nil {
+		if yamlErr := yaml.Unmarshal(data, &doc); yamlErr != nil {
+			return nil, yamlErr
+		}
+	}
+	root, ok := doc.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("schema is not an object")
 	}
+	uri, _ := root["$schema"].(string)
+	dialect := DetectDialect(uri)
+	e.logger.Debug("parsing values schema", "path", path, "dialect", dialect)
 
-	data, err := os.ReadFile(schemaPath)
+	data, err := json.Marshal(normalizeSchema(root, dialect))
 	if err != nil {
 		return nil, err
 	}
-
 	var jsonSchema jsonschema.Schema
 	if err := json.Unmarshal(data, &jsonSchema); err != nil {
 		return nil, err
 	}
 
-	return e.convertJSONSchema(&jsonSchema, ""), nil
+	return e.convertJSONSchema(&jsonSchema, path), nil
+}
+
+// Keywords whose values are schemas, maps of schemas or lists of schemas
+var (
+	schemaKeywords    = []string{"not", "if", "then", "else", "additionalProperties", "items", "additionalItems", "contains", "propertyNames", "contentSchema", "unevaluatedItems", "unevaluatedProperties"}
+	schemaMapKeywords = []string{"properties", "patternProperties", "$defs", "definitions", "dependentSchemas", "dependencies"}
+	schemaListKeyword = []string{"allOf", "anyOf", "oneOf", "prefixItems", "items"}
+)
+
+// normalizeSchema rewrites a schema of an earlier dialect into the 2020-12
+// keywords the parser reads: list items become prefixItems, with an
+// additionalItems schema the items after them; definitions become $defs;
+// dependencies split into dependentRequired and dependentSchemas; and
+// draft-04's boolean exclusive bounds become the numeric bounds of later
+// drafts. Subschemas are rewritten through the keywords holding them, so a
+// property named like a keyword is left alone.
+func normalizeSchema(value interface{}, dialect Dialect) interface{} {
+	js, ok := value.(map[string]interface{})
+	if !ok {
+		// true and false are schemas too
+		return value
+	}
+	out := make(map[string]interface{}, len(js))
+	for key, v := range js {
+		out[key] = v
+	}
+
+	for _, key := range schemaKeywords {
+		if sub, ok := out[key].(map[string]interface{}); ok {
+			out[key] = normalizeSchema(sub, dialect)
+		}
+	}
+	for _, key := range schemaMapKeywords {
+		subs, ok := out[key].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		normalized := make(map[string]interface{}, len(subs))
+		for name, sub := range subs {
+			normalized[name] = normalizeSchema(sub, dialect)
+		}
+		out[key] = normalized
+	}
+	for _, key := range schemaListKeyword {
+		subs, ok := out[key].([]interface{})
+		if !ok {
+			continue
+		}
+		normalized := make([]interface{}, len(subs))
+		for i, sub := range subs {
+			normalized[i] = normalizeSchema(sub, dialect)
+		}
+		out[key] = normalized
+	}
+
+	if defs, ok := out["definitions"]; ok {
+		if _, both := out["$defs"]; !both {
+			out["$defs"] = defs
+		}
+		delete(out, "definitions")
+	}
+	if dialect == Dialect202012 {
+		return out
+	}
+
+	if tuple, ok := out["items"].([]interface{}); ok {
+		out["prefixItems"] = tuple
+		delete(out, "items")
+		// Boolean additionalItems only allow or forbid items past the tuple,
+		// which are fuzzed like its first item either way
+		if additional, ok := out["additionalItems"].(map[string]interface{}); ok {
+			out["items"] = additional
+		}
+	}
+	delete(out, "additionalItems")
+	if deps, ok := out["dependencies"].(map[string]interface{}); ok {
+		required := map[string]interface{}{}
+		schemas := map[string]interface{}{}
+		for name, dep := range deps {
+			if _, isList := dep.([]interface{}); isList {
+				required[name] = dep
+			} else {
+				schemas[name] = dep
+			}
+		}
+		if len(required) > 0 {
+			out["dependentRequired"] = required
+		}
+		if len(schemas) > 0 {
+			out["dependentSchemas"] = schemas
+		}
+		delete(out, "dependencies")
+	}
+	if dialect == DialectDraft4 {
+		for exclusive, bound := range map[string]string{"exclusiveMinimum": "minimum", "exclusiveMaximum": "maximum"} {
+			set, ok := out[exclusive].(bool)
+			if !ok {
+				continue
+			}
+			delete(out, exclusive)
+			if set {
+				if v, ok := out[bound]; ok {
+					out[exclusive] = v
+					delete(out, bound)
+				}
+			}
+		}
+	}
+	return out
 }
 
 // convertJSONSchema converts a JSON schema to our internal Schema representation
@@ -80,6 +250,31 @@ func (e *Engine) convertJSONSchema(js *jsonschema.Schema, path string) *Schema {
 			schema.Maximum = &maxVal
 		}
 	}
+	// Exclusive bounds become the nearest inclusive ones, when tighter
+	if js.ExclusiveMinimum != "" {
+		if minVal, err := js.ExclusiveMinimum.Float64(); err == nil {
+			if schema.Type == TypeInteger {
+				minVal = math.Floor(minVal) + 1
+			} else {
+				minVal = math.Nextafter(minVal, math.Inf(1))
+			}
+			if schema.Minimum == nil || minVal > *schema.Minimum {
+				schema.Minimum = &minVal
+			}
+		}
+	}
+	if js.ExclusiveMaximum != "" {
+		if maxVal, err := js.ExclusiveMaximum.Float64(); err == nil {
+			if schema.Type == TypeInteger {
+				maxVal = math.Ceil(maxVal) - 1
+			} else {
+				maxVal = math.Nextafter(maxVal, math.Inf(-1))
+			}
+			if schema.Maximum == nil || maxVal < *schema.Maximum {
+				schema.Maximum = &maxVal
+			}
+		}
+	}
 
 	// Handle default
 	if js.Default != nil {
@@ -106,17 +301,23 @@ func (e *Engine) convertJSONSchema(js *jsonschema.Schema, path string) *Schema {
 					schema.Properties[propName] = &Schema{
 						Type:    SchemaType(propSchema.Type),
 						Default: propSchema.Default,
+						Ignored: true,
 					}
 				}
 				continue
 			}
 
 			// Apply constraints from config
-			if constraint := e.config.GetConstraint(propPath); constraint != nil {
+			constraint := e.config.GetConstraint(propPath)
+			if constraint != nil {
 				propSchema = e.applyConstraint(propSchema, constraint)
 			}
 
 			schema.Properties[propName] = e.convertJSONSchema(propSchema, propPath)
+			if constraint != nil {
+				schema.Properties[propName].Template = constraint.Template
+				schema.Properties[propName].Constraint = constraint
+			}
 		}
 
 		// Handle required fields
@@ -130,6 +331,9 @@ func (e *Engine) convertJSONSchema(js *jsonschema.Schema, path string) *Schema {
 		if js.Items != nil {
 			itemPath := path + "[]"
 			schema.Items = e.convertJSONSchema(js.Items, itemPath)
+		} else if len(js.PrefixItems) > 0 {
+			// Tuples are fuzzed as lists of their first item
+			schema.Items = e.convertJSONSchema(js.PrefixItems[0], path+"[]")
 		} else {
 			// Default to any type for arrays without item schema
 			schema.Items = &Schema{Type: TypeAny}
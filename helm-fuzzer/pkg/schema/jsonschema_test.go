@@ -0,0 +1,106 @@
+package schema
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/config"
+)
+
+func TestDetectDialect(t *testing.T) {
+	for uri, want := range map[string]Dialect{
+		"http://json-schema.org/draft-04/schema#":      DialectDraft4,
+		"http://json-schema.org/draft-06/schema#":      DialectDraft6,
+		"http://json-schema.org/draft-07/schema":       DialectDraft7,
+		"https://json-schema.org/draft/2019-09/schema": Dialect201909,
+		"https://json-schema.org/draft/2020-12/schema": Dialect202012,
+		"":                            DialectDraft7,
+		"https://example.com/schema#": DialectDraft7,
+	} {
+		if got := DetectDialect(uri); got != want {
+			t.Errorf("DetectDialect(%q) = %s, want %s", uri, got, want)
+		}
+	}
+}
+
+func TestLoadJSONSchema_YAML(t *testing.T) {
+	chartPath := t.TempDir()
+	yamlSchema := `$schema: https://json-schema.org/draft/2020-12/schema
+type: object
+properties:
+  replicas:
+    type: integer
+    exclusiveMinimum: 0
+  ports:
+    type: array
+    prefixItems:
+      - type: integer
+`
+	if err := os.WriteFile(filepath.Join(chartPath, "values.schema.yaml"), []byte(yamlSchema), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	engine := NewEngine(config.DefaultConfig())
+	s, source, err := engine.Detect(chartPath)
+	if err != nil {
+		t.Fatalf("Detect failed: %v", err)
+	}
+	if source != "values.schema.yaml" {
+		t.Errorf("expected the YAML schema, got %q", source)
+	}
+	if min := s.Properties["replicas"].Minimum; min == nil || *min != 1 {
+		t.Errorf("expected the exclusive minimum as 1, got %v", min)
+	}
+	if items := s.Properties["ports"].Items; items == nil || items.Type != TypeInteger {
+		t.Errorf("expected tuple items of their first item, got %+v", items)
+	}
+
+	// values.schema.json wins over the YAML form
+	if err := os.WriteFile(filepath.Join(chartPath, "values.schema.json"), []byte(`{"type":"object","properties":{"name":{"type":"string"}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, source, err := engine.Detect(chartPath); err != nil || source != SourceJSONSchema {
+		t.Errorf("expected values.schema.json, got %q, %v", source, err)
+	}
+}
+
+func TestParseJSONSchema_Dialects(t *testing.T) {
+	engine := NewEngine(config.DefaultConfig())
+
+	// Draft-04 exclusive bounds are booleans on minimum and maximum
+	s, err := engine.ParseJSONSchema([]byte(`{
+		"$schema": "http://json-schema.org/draft-04/schema#",
+		"type": "object",
+		"properties": {
+			"port": {"type": "integer", "minimum": 0, "exclusiveMinimum": true, "maximum": 65535, "exclusiveMaximum": false}
+		}
+	}`), "")
+	if err != nil {
+		t.Fatalf("ParseJSONSchema failed: %v", err)
+	}
+	port := s.Properties["port"]
+	if port.Minimum == nil || *port.Minimum != 1 || port.Maximum == nil || *port.Maximum != 65535 {
+		t.Errorf("expected port bounds 1 and 65535, got %v and %v", port.Minimum, port.Maximum)
+	}
+
+	// Draft-07 tuples list their items, and a property may be named items
+	s, err = engine.ParseJSONSchema([]byte(`{
+		"$schema": "http://json-schema.org/draft-07/schema#",
+		"type": "object",
+		"definitions": {"name": {"type": "string"}},
+		"properties": {
+			"pair": {"type": "array", "items": [{"type": "string"}, {"type": "integer"}], "additionalItems": false},
+			"items": {"type": "array", "items": {"type": "boolean"}}
+		}
+	}`), "")
+	if err != nil {
+		t.Fatalf("ParseJSONSchema failed: %v", err)
+	}
+	if pair := s.Properties["pair"]; pair.Items == nil || pair.Items.Type != TypeString {
+		t.Errorf("expected the tuple's first item, got %+v", pair.Items)
+	}
+	if items := s.Properties["items"]; items == nil || items.Items == nil || items.Items.Type != TypeBoolean {
+		t.Errorf("expected the items property kept, got %+v", items)
+	}
+}
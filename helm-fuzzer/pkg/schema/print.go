@@ -0,0 +1,235 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/config"
+)
+
+// maxDefaultWidth caps how much of a default value the tree shows
+const maxDefaultWidth = 40
+
+// WriteTree prints the schema as an indented tree, one path per line, with
+// the constraints the generator honors and the .helmfuzz.yaml ignore and
+// constraint entries that applied
+func WriteTree(w io.Writer, s *Schema) error {
+	if s == nil {
+		return nil
+	}
+	if s.Type != TypeObject {
+		_, err := fmt.Fprintf(w, ".: %s\n", describe(s, false))
+		return err
+	}
+	return writeProperties(w, s, 0)
+}
+
+// writeProperties prints an object's properties sorted by name
+func writeProperties(w io.Writer, s *Schema, depth int) error {
+	for _, name := range sortedKeys(s.Properties) {
+		required := false
+		for _, r := range s.Required {
+			if r == name {
+				required = true
+			}
+		}
+		if err := writeNode(w, name, s.Properties[name], required, depth); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeNode prints a path and everything below it
+func writeNode(w io.Writer, name string, s *Schema, required bool, depth int) error {
+	if _, err := fmt.Fprintf(w, "%s%s: %s\n", strings.Repeat("  ", depth), name, describe(s, required)); err != nil {
+		return err
+	}
+	// Ignored paths keep their default, so their children are never generated
+	if s.Ignored {
+		return nil
+	}
+	if s.Items != nil {
+		if err := writeNode(w, "[]", s.Items, false, depth+1); err != nil {
+			return err
+		}
+	}
+	return writeProperties(w, s, depth+1)
+}
+
+// describe summarizes a schema node as its type, constraints and annotations
+func describe(s *Schema, required bool) string {
+	parts := []string{string(s.Type)}
+	if required {
+		parts = append(parts, "required")
+	}
+	if len(s.Enum) > 0 {
+		values := make([]string, len(s.Enum))
+		for i, v := range s.Enum {
+			values[i] = fmt.Sprint(v)
+		}
+		parts = append(parts, "enum=["+strings.Join(values, ", ")+"]")
+	}
+	if s.Pattern != "" {
+		parts = append(parts, "pattern="+s.Pattern)
+	}
+	if s.MinLength != nil {
+		parts = append(parts, fmt.Sprintf("minLength=%d", *s.MinLength))
+	}
+	if s.MaxLength != nil {
+		parts = append(parts, fmt.Sprintf("maxLength=%d", *s.MaxLength))
+	}
+	if s.Minimum != nil {
+		parts = append(parts, fmt.Sprintf("min=%g", *s.Minimum))
+	}
+	if s.Maximum != nil {
+		parts = append(parts, fmt.Sprintf("max=%g", *s.Maximum))
+	}
+	if s.Template != "" {
+		parts = append(parts, fmt.Sprintf("template=%q", s.Template))
+	}
+	if s.Default != nil && s.Type != TypeObject && s.Type != TypeArray {
+		parts = append(parts, "default="+formatDefault(s.Default))
+	}
+
+	line := strings.Join(parts, " ")
+	var notes []string
+	if s.Ignored {
+		notes = append(notes, "ignored: always the default")
+	}
+	if s.Constraint != nil {
+		notes = append(notes, "constraint: "+describeConstraint(s.Constraint))
+	}
+	if len(notes) > 0 {
+		line += "  # " + strings.Join(notes, "; ")
+	}
+	return line
+}
+
+// describeConstraint lists the fields set on a constraint
+func describeConstraint(c *config.Constraint) string {
+	var parts []string
+	if c.Type != "" {
+		parts = append(parts, "type="+c.Type)
+	}
+	if c.Min != nil {
+		parts = append(parts, fmt.Sprintf("min=%d", *c.Min))
+	}
+	if c.Max != nil {
+		parts = append(parts, fmt.Sprintf("max=%d", *c.Max))
+	}
+	if c.Pattern != "" {
+		parts = append(parts, "pattern="+c.Pattern)
+	}
+	if len(c.Enum) > 0 {
+		parts = append(parts, fmt.Sprintf("enum=%v", c.Enum))
+	}
+	if c.Template != "" {
+		parts = append(parts, fmt.Sprintf("template=%q", c.Template))
+	}
+	if c.Required {
+		parts = append(parts, "required")
+	}
+	if len(parts) == 0 {
+		return "(empty)"
+	}
+	return strings.Join(parts, " ")
+}
+
+// formatDefault renders a default value as compact JSON, truncated to maxDefaultWidth
+func formatDefault(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	runes := []rune(string(data))
+	if len(runes) > maxDefaultWidth {
+		return string(runes[:maxDefaultWidth-3]) + "..."
+	}
+	return string(runes)
+}
+
+// WriteJSON prints the schema as a JSON Schema document. Ignored paths and
+// applied constraints are marked with x-helmfuzz-ignored and
+// x-helmfuzz-constraint so the output can seed a values.schema.json.
+func WriteJSON(w io.Writer, s *Schema, source Source) error {
+	doc := jsonNode(s)
+	doc["$schema"] = "http://json-schema.org/draft-07/schema#"
+	doc["x-helmfuzz-source"] = string(source)
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(doc)
+}
+
+// jsonNode converts a schema node to its JSON Schema form
+func jsonNode(s *Schema) map[string]interface{} {
+	node := map[string]interface{}{}
+	if s == nil {
+		return node
+	}
+	if s.Type != TypeAny && s.Type != "" {
+		node["type"] = string(s.Type)
+	}
+	if s.Description != "" {
+		node["description"] = s.Description
+	}
+	if len(s.Enum) > 0 {
+		node["enum"] = s.Enum
+	}
+	if s.Pattern != "" {
+		node["pattern"] = s.Pattern
+	}
+	if s.MinLength != nil {
+		node["minLength"] = *s.MinLength
+	}
+	if s.MaxLength != nil {
+		node["maxLength"] = *s.MaxLength
+	}
+	if s.Minimum != nil {
+		node["minimum"] = *s.Minimum
+	}
+	if s.Maximum != nil {
+		node["maximum"] = *s.Maximum
+	}
+	if s.Default != nil {
+		node["default"] = s.Default
+	}
+	if s.Template != "" {
+		node["x-helmfuzz-template"] = s.Template
+	}
+	if s.Ignored {
+		node["x-helmfuzz-ignored"] = true
+	}
+	if c := s.Constraint; c != nil {
+		node["x-helmfuzz-constraint"] = c
+	}
+	if len(s.Properties) > 0 {
+		props := make(map[string]interface{}, len(s.Properties))
+		for name, prop := range s.Properties {
+			props[name] = jsonNode(prop)
+		}
+		node["properties"] = props
+	}
+	if len(s.Required) > 0 {
+		node["required"] = s.Required
+	}
+	if s.Items != nil {
+		node["items"] = jsonNode(s.Items)
+	}
+	return node
+}
+
+// sortedKeys returns the property names in order
+func sortedKeys(m map[string]*Schema) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
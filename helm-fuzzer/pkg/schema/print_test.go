@@ -0,0 +1,72 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/config"
+)
+
+func TestWriteTree(t *testing.T) {
+	tmpDir := t.TempDir()
+	values := `
+replicaCount: 3
+image:
+  repository: nginx
+debug:
+  enabled: false
+ports:
+  - 80
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, "values.yaml"), []byte(values), 0644); err != nil {
+		t.Fatalf("failed to write values.yaml: %v", err)
+	}
+
+	min, max := 1, 10
+	cfg := config.DefaultConfig()
+	cfg.Ignore = []string{"debug"}
+	cfg.Constraints = []config.Constraint{{Path: "replicaCount", Type: "integer", Min: &min, Max: &max}}
+
+	sch, source, err := NewEngine(cfg).Detect(tmpDir)
+	if err != nil {
+		t.Fatalf("Detect failed: %v", err)
+	}
+	if source != SourceInferred {
+		t.Errorf("expected source %q, got %q", SourceInferred, source)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteTree(&buf, sch); err != nil {
+		t.Fatalf("WriteTree failed: %v", err)
+	}
+	want := `debug: object  # ignored: always the default
+image: object
+  repository: string default="nginx"
+ports: array
+  []: integer default=80
+replicaCount: integer min=1 max=10 default=3  # constraint: type=integer min=1 max=10
+`
+	if buf.String() != want {
+		t.Errorf("unexpected tree:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := WriteJSON(&buf, sch, source); err != nil {
+		t.Fatalf("WriteJSON failed: %v", err)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	props := doc["properties"].(map[string]interface{})
+	if props["debug"].(map[string]interface{})["x-helmfuzz-ignored"] != true {
+		t.Errorf("expected debug to be marked ignored: %s", buf.String())
+	}
+	if !strings.Contains(buf.String(), `"x-helmfuzz-constraint"`) {
+		t.Errorf("expected constraint annotation: %s", buf.String())
+	}
+}
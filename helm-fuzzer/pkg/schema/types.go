@@ -1,6 +1,12 @@
 package schema
 
-import "github.com/kasuboski/helm-fuzzer/pkg/config"
+import (
+	"log/slog"
+	"strings"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/config"
+	"github.com/kasuboski/helm-fuzzer/pkg/logging"
+)
 
 // SchemaType represents the type of a schema field
 type SchemaType string
@@ -30,29 +36,89 @@ type Schema struct {
 	Maximum     *float64           // Max value for numbers
 	Default     interface{}        // Default value
 	Description string             // Description
+	Template    string             // Go template producing the value
+
+	// Ignored is set when the path matched an ignore entry in .helmfuzz.yaml
+	Ignored bool
+	// Constraint is the .helmfuzz.yaml constraint applied to the path, nil if none
+	Constraint *config.Constraint
+	// Risk counts the risky template constructs reading the path or a path
+	// below it (see MarkRisk); the generator varies risky paths more often
+	Risk int
+	// Nilable is set when a template reads keys of the path's map without
+	// checking it is set, so the generator sometimes sends null to unset it
+	Nilable bool
+}
+
+// MarkRisk adds score to the risk of the dotted path and the paths above it,
+// and marks the path nilable if asked. It reports whether the schema has the
+// path; paths below an array or an untyped value are not marked.
+func (s *Schema) MarkRisk(path string, score int, nilable bool) bool {
+	node := s
+	var along []*Schema
+	for _, key := range strings.Split(path, ".") {
+		child, ok := node.Properties[key]
+		if !ok {
+			return false
+		}
+		along = append(along, child)
+		node = child
+	}
+	for _, n := range along {
+		n.Risk += score
+	}
+	node.Nilable = node.Nilable || nilable
+	return true
 }
 
+// Source identifies where a chart's schema came from
+type Source string
+
+const (
+	// SourceJSONSchema is a schema loaded from values.schema.json; one loaded
+	// from another of SchemaFiles is named by its file
+	SourceJSONSchema Source = "values.schema.json"
+	// SourceInferred is a schema inferred from values.yaml
+	SourceInferred Source = "values.yaml"
+)
+
 // Engine handles schema detection and parsing
 type Engine struct {
 	config *config.Config
+	logger *slog.Logger
 }
 
 // NewEngine creates a new schema engine
 func NewEngine(cfg *config.Config) *Engine {
+	return NewEngineWithLogger(cfg, nil)
+}
+
+// NewEngineWithLogger creates a new schema engine that logs schema detection
+func NewEngineWithLogger(cfg *config.Config, logger *slog.Logger) *Engine {
 	return &Engine{
 		config: cfg,
+		logger: logging.OrDiscard(logger),
 	}
 }
 
-// DetectSchema attempts to load schema from values.schema.json,
-// falling back to inference from values.yaml
+// DetectSchema attempts to load schema from values.schema.json or its YAML
+// form, falling back to inference from values.yaml
 func (e *Engine) DetectSchema(chartPath string) (*Schema, error) {
+	schema, _, err := e.Detect(chartPath)
+	return schema, err
+}
+
+// Detect is DetectSchema but also reports where the schema came from
+func (e *Engine) Detect(chartPath string) (*Schema, Source, error) {
 	// First, try to load JSON schema
-	schema, err := e.LoadJSONSchema(chartPath)
+	schema, source, err := e.LoadJSONSchema(chartPath)
 	if err == nil {
-		return schema, nil
+		e.logger.Debug("loaded schema from "+string(source), "chart", chartPath)
+		return schema, source, nil
 	}
 
 	// Fall back to inference from values.yaml
-	return e.InferFromValues(chartPath)
+	e.logger.Debug("inferring schema from values.yaml", "chart", chartPath, "reason", err)
+	schema, err = e.InferFromValues(chartPath)
+	return schema, SourceInferred, err
 }
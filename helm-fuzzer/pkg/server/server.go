@@ -0,0 +1,556 @@
+// Package server runs fuzzing jobs submitted over a REST API, so many teams
+// can share one fuzzing service instead of each running the CLI.
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/config"
+	"github.com/kasuboski/helm-fuzzer/pkg/coverage"
+	"github.com/kasuboski/helm-fuzzer/pkg/fuzz"
+	"github.com/kasuboski/helm-fuzzer/pkg/logging"
+	"github.com/kasuboski/helm-fuzzer/pkg/report"
+	"github.com/kasuboski/helm-fuzzer/pkg/runner"
+)
+
+// Status is the state of a job
+type Status string
+
+const (
+	// StatusQueued means the job waits for a free slot
+	StatusQueued Status = "queued"
+	// StatusRunning means the job is fuzzing
+	StatusRunning Status = "running"
+	// StatusCompleted means the job used up its iterations or timeout
+	StatusCompleted Status = "completed"
+	// StatusFailed means the chart could not be fuzzed; see the job's error
+	StatusFailed Status = "failed"
+	// StatusCanceled means the job was canceled or the server shut down
+	StatusCanceled Status = "canceled"
+)
+
+// Job is a fuzzing job as reported by the API
+type Job struct {
+	ID    string `json:"id"`
+	Chart string `json:"chart"`
+	// Source is "upload" or the OCI reference the chart was pulled from
+	Source        string     `json:"source"`
+	Status        Status     `json:"status"`
+	Iterations    int        `json:"iterations"`
+	MaxIterations int        `json:"maxIterations"`
+	Timeout       string     `json:"timeout"`
+	Crashes       int        `json:"crashes"`
+	Findings      int        `json:"findings"`
+	Error         string     `json:"error,omitempty"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	StartedAt     *time.Time `json:"startedAt,omitempty"`
+	FinishedAt    *time.Time `json:"finishedAt,omitempty"`
+}
+
+// Request is a job submission. Charts come from an OCI reference in a JSON
+// body, or as a packaged .tgz in the "chart" field of a multipart form whose
+// other fields carry the remaining settings.
+type Request struct {
+	// Chart is an OCI reference such as oci://ghcr.io/org/charts/app
+	Chart   string `json:"chart"`
+	Version string `json:"version,omitempty"`
+	// Config is the .helmfuzz.yaml to fuzz with; empty uses the chart's own
+	Config string `json:"config,omitempty"`
+	// Iterations overrides the config's iteration target; 0 keeps it
+	Iterations int `json:"iterations,omitempty"`
+	// Timeout is a Go duration such as "10m"; empty uses the server default
+	Timeout string `json:"timeout,omitempty"`
+}
+
+// Options configures a server
+type Options struct {
+	// DataDir holds each job's chart and output
+	DataDir string
+	// MaxJobs is the number of jobs that run at once; more are queued (default: 2)
+	MaxJobs int
+	// DefaultTimeout applies to jobs that set none (default: 5m)
+	DefaultTimeout time.Duration
+	// MaxTimeout is the longest timeout a job may ask for (default: 1h)
+	MaxTimeout time.Duration
+	// MaxUploadSize limits request bodies in bytes (default: 32 MiB)
+	MaxUploadSize int64
+	// ToolVersion is recorded in job reports
+	ToolVersion string
+	Logger      *slog.Logger
+}
+
+// Server queues, runs and reports fuzzing jobs. Jobs live in memory and
+// their files in DataDir; a restarted server starts with no jobs.
+type Server struct {
+	opts   Options
+	logger *slog.Logger
+	// slots limits the jobs running at once
+	slots  chan struct{}
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu   sync.Mutex
+	jobs map[string]*job
+}
+
+// job is a submitted job and its live state
+type job struct {
+	mu       sync.Mutex
+	info     Job
+	coverage *coverage.Summary
+	dir      string
+	timeout  time.Duration
+	cancel   context.CancelFunc
+	recorder *report.Recorder
+	cfg      *config.Config
+}
+
+// New creates a server with the given options
+func New(opts Options) *Server {
+	if opts.MaxJobs <= 0 {
+		opts.MaxJobs = 2
+	}
+	if opts.DefaultTimeout <= 0 {
+		opts.DefaultTimeout = 5 * time.Minute
+	}
+	if opts.MaxTimeout <= 0 {
+		opts.MaxTimeout = time.Hour
+	}
+	if opts.MaxUploadSize <= 0 {
+		opts.MaxUploadSize = 32 << 20
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Server{
+		opts:   opts,
+		logger: logging.OrDiscard(opts.Logger),
+		slots:  make(chan struct{}, opts.MaxJobs),
+		ctx:    ctx,
+		cancel: cancel,
+		jobs:   make(map[string]*job),
+	}
+}
+
+// Handler returns the HTTP handler serving the API:
+//
+//	POST   /api/v1/jobs                    submit a job
+//	GET    /api/v1/jobs                    list jobs
+//	GET    /api/v1/jobs/{id}               job status
+//	DELETE /api/v1/jobs/{id}               cancel a job
+//	GET    /api/v1/jobs/{id}/report        report.json with the findings so far
+//	GET    /api/v1/jobs/{id}/repro/{file}  a reproduction file
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/jobs", s.handleSubmit)
+	mux.HandleFunc("GET /api/v1/jobs", s.handleList)
+	mux.HandleFunc("GET /api/v1/jobs/{id}", s.handleGet)
+	mux.HandleFunc("DELETE /api/v1/jobs/{id}", s.handleCancel)
+	mux.HandleFunc("GET /api/v1/jobs/{id}/report", s.handleReport)
+	mux.HandleFunc("GET /api/v1/jobs/{id}/findings", s.handleFindings)
+	mux.HandleFunc("GET /api/v1/jobs/{id}/repro/{file}", s.handleRepro)
+	return mux
+}
+
+// Start serves the API on addr in the background until ctx is cancelled,
+// then cancels running jobs. It returns once the listener is bound, so
+// address errors surface immediately.
+func (s *Server) Start(ctx context.Context, addr string) (net.Addr, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	server := &http.Server{Handler: s.Handler(), ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+		s.Close()
+	}()
+	go func() {
+		// Serve only fails once the listener is closed by Shutdown
+		_ = server.Serve(listener)
+	}()
+
+	return listener.Addr(), nil
+}
+
+// Close cancels every job and waits for them to stop
+func (s *Server) Close() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+func (s *Server) handleSubmit(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, s.opts.MaxUploadSize)
+
+	id, err := newID()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	dir := filepath.Join(s.opts.DataDir, id)
+	chartDir := filepath.Join(dir, "chart")
+	if err := os.MkdirAll(chartDir, 0755); err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to create job directory: %w", err))
+		return
+	}
+
+	req, chartPath, status, err := s.readRequest(r, chartDir)
+	if err == nil {
+		var j *job
+		if j, err = s.newJob(id, dir, chartPath, req); err == nil {
+			s.run(j, chartPath)
+			s.mu.Lock()
+			s.jobs[id] = j
+			s.mu.Unlock()
+
+			w.Header().Set("Location", "/api/v1/jobs/"+id)
+			writeJSON(w, http.StatusAccepted, j.snapshot())
+			return
+		}
+		status = http.StatusBadRequest
+	}
+
+	os.RemoveAll(dir)
+	writeError(w, status, err)
+}
+
+// readRequest decodes a submission and fetches its chart into chartDir,
+// returning the status code to answer with on error
+func (s *Server) readRequest(r *http.Request, chartDir string) (*Request, string, int, error) {
+	var req Request
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	if mediaType == "multipart/form-data" {
+		file, _, err := r.FormFile("chart")
+		if err != nil {
+			return nil, "", http.StatusBadRequest, fmt.Errorf("missing chart archive: %w", err)
+		}
+		defer file.Close()
+		archive, err := io.ReadAll(file)
+		if err != nil {
+			return nil, "", http.StatusBadRequest, fmt.Errorf("failed to read chart archive: %w", err)
+		}
+
+		req.Chart = "upload"
+		req.Config = r.FormValue("config")
+		req.Timeout = r.FormValue("timeout")
+		if v := r.FormValue("iterations"); v != "" {
+			if req.Iterations, err = strconv.Atoi(v); err != nil {
+				return nil, "", http.StatusBadRequest, fmt.Errorf("invalid iterations: %w", err)
+			}
+		}
+
+		chartPath, err := runner.ExpandChart(chartDir, archive)
+		if err != nil {
+			return nil, "", http.StatusBadRequest, err
+		}
+		return &req, chartPath, 0, nil
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, "", http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err)
+	}
+	if req.Chart == "" {
+		return nil, "", http.StatusBadRequest, errors.New("chart is required: an OCI reference, or a .tgz uploaded as multipart form data")
+	}
+	chartPath, err := runner.PullChart(chartDir, req.Chart, req.Version)
+	if err != nil {
+		return nil, "", http.StatusBadGateway, err
+	}
+	return &req, chartPath, 0, nil
+}
+
+// newJob validates a request's settings and creates its queued job
+func (s *Server) newJob(id, dir, chartPath string, req *Request) (*job, error) {
+	timeout := s.opts.DefaultTimeout
+	if req.Timeout != "" {
+		var err error
+		if timeout, err = time.ParseDuration(req.Timeout); err != nil {
+			return nil, fmt.Errorf("invalid timeout: %w", err)
+		}
+	}
+	if timeout <= 0 || timeout > s.opts.MaxTimeout {
+		return nil, fmt.Errorf("timeout must be positive and at most %s", s.opts.MaxTimeout)
+	}
+	if req.Iterations < 0 {
+		return nil, errors.New("iterations must not be negative")
+	}
+
+	var cfg *config.Config
+	var err error
+	if req.Config != "" {
+		configPath := filepath.Join(dir, config.FileName)
+		if err := os.WriteFile(configPath, []byte(req.Config), 0644); err != nil {
+			return nil, fmt.Errorf("failed to save config: %w", err)
+		}
+		cfg, err = config.LoadConfigFile(configPath)
+	} else {
+		cfg, err = config.LoadConfig(chartPath)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	if req.Iterations > 0 {
+		cfg.Iterations = req.Iterations
+	}
+	// A job reads nothing but its own chart, talks to nothing but the API
+	// and runs no commands a submitter chose
+	cfg.CorpusDir = ""
+	cfg.Webhooks = nil
+	cfg.OnCrash = nil
+	cfg.Tracing = nil
+	cfg.Plugins = nil
+	cfg.Scanners = nil
+
+	chartName := filepath.Base(chartPath)
+	return &job{
+		info: Job{
+			ID:            id,
+			Chart:         chartName,
+			Source:        req.Chart,
+			Status:        StatusQueued,
+			MaxIterations: cfg.Iterations,
+			Timeout:       timeout.String(),
+			CreatedAt:     time.Now(),
+		},
+		dir:      dir,
+		timeout:  timeout,
+		recorder: report.NewRecorder(chartName, cfg.Iterations),
+		cfg:      cfg,
+	}, nil
+}
+
+// run starts a job once a slot is free
+func (s *Server) run(j *job, chartPath string) {
+	ctx, cancel := context.WithCancel(s.ctx)
+	j.cancel = cancel
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer cancel()
+
+		select {
+		case s.slots <- struct{}{}:
+			defer func() { <-s.slots }()
+		case <-ctx.Done():
+			j.finish(StatusCanceled, nil)
+			return
+		}
+
+		j.mu.Lock()
+		started := time.Now()
+		j.info.Status = StatusRunning
+		j.info.StartedAt = &started
+		j.mu.Unlock()
+		logger := s.logger.With("job", j.info.ID, "chart", j.info.Chart)
+		logger.Info("job started")
+
+		session, err := fuzz.NewWithOptions(chartPath, fuzz.Options{
+			Config:    j.cfg,
+			OutputDir: filepath.Join(j.dir, "output"),
+			Timeout:   j.timeout,
+			Logger:    logger,
+			Hooks: fuzz.Hooks{
+				Iteration: func(it fuzz.Iteration) {
+					j.recorder.RecordIteration(it.Crashed)
+				},
+				Crash: j.recorder.RecordFinding,
+			},
+		})
+		if err != nil {
+			logger.Warn("job failed", "error", err)
+			j.finish(StatusFailed, err)
+			return
+		}
+
+		result, err := session.Run(ctx)
+		j.mu.Lock()
+		j.coverage = &result.Coverage
+		j.mu.Unlock()
+		switch {
+		case err != nil:
+			logger.Warn("job failed", "error", err)
+			j.finish(StatusFailed, err)
+		case result.Interrupted:
+			j.finish(StatusCanceled, nil)
+		default:
+			logger.Info("job completed", "findings", len(result.Findings))
+			j.finish(StatusCompleted, nil)
+		}
+	}()
+}
+
+// finish records the job's final status
+func (j *job) finish(status Status, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	finished := time.Now()
+	j.info.Status = status
+	j.info.FinishedAt = &finished
+	if err != nil {
+		j.info.Error = err.Error()
+	}
+}
+
+// snapshot returns the job's current state
+func (j *job) snapshot() Job {
+	j.mu.Lock()
+	info := j.info
+	j.mu.Unlock()
+
+	session := j.recorder.Session()
+	info.Iterations = session.Iterations
+	info.Crashes = session.Crashes
+	info.Findings = len(session.Findings)
+	return info
+}
+
+// reproURL returns the download URL of a reproduction file the job saved
+func (j *job) reproURL(path string) string {
+	return "/api/v1/jobs/" + j.info.ID + "/repro/" + filepath.Base(path)
+}
+
+// lookup returns the job named in the request path, answering 404 if there is none
+func (s *Server) lookup(w http.ResponseWriter, r *http.Request) *job {
+	s.mu.Lock()
+	j, ok := s.jobs[r.PathValue("id")]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("job %q not found", r.PathValue("id")))
+		return nil
+	}
+	return j
+}
+
+func (s *Server) handleList(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	jobs := make([]Job, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		jobs = append(jobs, j.snapshot())
+	}
+	s.mu.Unlock()
+
+	sort.Slice(jobs, func(i, k int) bool {
+		return jobs[i].CreatedAt.Before(jobs[k].CreatedAt)
+	})
+	writeJSON(w, http.StatusOK, jobs)
+}
+
+func (s *Server) handleGet(w http.ResponseWriter, r *http.Request) {
+	if j := s.lookup(w, r); j != nil {
+		writeJSON(w, http.StatusOK, j.snapshot())
+	}
+}
+
+func (s *Server) handleCancel(w http.ResponseWriter, r *http.Request) {
+	if j := s.lookup(w, r); j != nil {
+		j.cancel()
+		writeJSON(w, http.StatusAccepted, j.snapshot())
+	}
+}
+
+// handleReport serves report.json for the job so far, with reproduction
+// files pointing at their download URLs
+func (s *Server) handleReport(w http.ResponseWriter, r *http.Request) {
+	j := s.lookup(w, r)
+	if j == nil {
+		return
+	}
+
+	session := j.recorder.Session()
+	j.mu.Lock()
+	session.Coverage = j.coverage
+	j.mu.Unlock()
+	session.ToolVersion = s.opts.ToolVersion
+	session.Config = j.cfg
+	rep, err := report.NewJSONReport(session)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	for i, f := range rep.Findings {
+		if f.ReproFile != "" {
+			rep.Findings[i].ReproFile = j.reproURL(f.ReproFile)
+		}
+	}
+	writeJSON(w, http.StatusOK, rep)
+}
+
+// handleFindings serves the job's findings so far in their JSON form, with
+// the full attribution and reproduction files pointing at their download URLs
+func (s *Server) handleFindings(w http.ResponseWriter, r *http.Request) {
+	j := s.lookup(w, r)
+	if j == nil {
+		return
+	}
+
+	findings := append([]report.Finding{}, j.recorder.Session().Findings...)
+	for i, f := range findings {
+		if f.ReproFile != "" {
+			findings[i].ReproFile = j.reproURL(f.ReproFile)
+		}
+	}
+	writeJSON(w, http.StatusOK, findings)
+}
+
+func (s *Server) handleRepro(w http.ResponseWriter, r *http.Request) {
+	j := s.lookup(w, r)
+	if j == nil {
+		return
+	}
+
+	// Only files the job saved can be downloaded
+	name := r.PathValue("file")
+	for _, f := range j.recorder.Session().Findings {
+		if f.ReproFile != "" && filepath.Base(f.ReproFile) == name {
+			w.Header().Set("Content-Type", "application/yaml")
+			http.ServeFile(w, r, f.ReproFile)
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, fmt.Errorf("reproduction file %q not found", name))
+}
+
+// newID returns a random job ID
+func newID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate job ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
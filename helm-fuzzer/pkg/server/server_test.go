@@ -0,0 +1,197 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/report"
+)
+
+// packageChart returns the buggy test chart as a .tgz archive
+func packageChart(t *testing.T) []byte {
+	t.Helper()
+	chart, err := loader.Load("../../testdata/buggy-chart")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path, err := chartutil.Save(chart, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// upload submits a chart archive with form fields and decodes the response
+func upload(t *testing.T, url string, archive []byte, fields map[string]string) (*http.Response, Job) {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("chart", "buggy-chart.tgz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(archive)
+	for k, v := range fields {
+		form.WriteField(k, v)
+	}
+	form.Close()
+
+	resp, err := http.Post(url+"/api/v1/jobs", form.FormDataContentType(), &body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var job Job
+	json.NewDecoder(resp.Body).Decode(&job)
+	return resp, job
+}
+
+func getJSON(t *testing.T, url string, v interface{}) int {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatalf("failed to decode %s: %v", url, err)
+	}
+	return resp.StatusCode
+}
+
+func TestServer_UploadJob(t *testing.T) {
+	s := New(Options{DataDir: t.TempDir()})
+	defer s.Close()
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+
+	resp, job := upload(t, ts.URL, packageChart(t), map[string]string{
+		"iterations": "50",
+		"config":     "kubeVersions: [\"1.30.0\"]\n",
+	})
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", resp.StatusCode)
+	}
+	if resp.Header.Get("Location") != "/api/v1/jobs/"+job.ID || job.Chart != "buggy-chart" || job.MaxIterations != 50 {
+		t.Fatalf("unexpected job %+v", job)
+	}
+
+	deadline := time.Now().Add(30 * time.Second)
+	for job.Status == StatusQueued || job.Status == StatusRunning {
+		if time.Now().After(deadline) {
+			t.Fatal("job did not finish")
+		}
+		time.Sleep(50 * time.Millisecond)
+		getJSON(t, ts.URL+"/api/v1/jobs/"+job.ID, &job)
+	}
+	if job.Status != StatusCompleted || job.Iterations != 50 || job.Findings == 0 {
+		t.Fatalf("expected a completed job with findings, got %+v", job)
+	}
+
+	var rep report.JSONReport
+	if code := getJSON(t, ts.URL+"/api/v1/jobs/"+job.ID+"/report", &rep); code != http.StatusOK {
+		t.Fatalf("expected 200 for the report, got %d", code)
+	}
+	if len(rep.Findings) != job.Findings || rep.Coverage == nil {
+		t.Fatalf("expected %d findings and coverage, got %+v", job.Findings, rep)
+	}
+	if got := rep.Config["kubeVersions"]; got == nil {
+		t.Errorf("expected the submitted config in the report, got %v", rep.Config)
+	}
+
+	reproURL := rep.Findings[0].ReproFile
+	if !strings.HasPrefix(reproURL, "/api/v1/jobs/"+job.ID+"/repro/") {
+		t.Fatalf("expected a download URL, got %q", reproURL)
+	}
+	repro, err := http.Get(ts.URL + reproURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(repro.Body)
+	repro.Body.Close()
+	if repro.StatusCode != http.StatusOK || !strings.Contains(string(data), "# Helm Fuzz Reproduction Case") {
+		t.Errorf("expected the reproduction file, got %d: %s", repro.StatusCode, data)
+	}
+
+	var findings []report.Finding
+	if code := getJSON(t, ts.URL+"/api/v1/jobs/"+job.ID+"/findings", &findings); code != http.StatusOK {
+		t.Fatalf("expected 200 for the findings, got %d", code)
+	}
+	if len(findings) != job.Findings || findings[0].ID != rep.Findings[0].ID || findings[0].ReproFile != reproURL {
+		t.Errorf("expected the report's findings, got %+v", findings)
+	}
+
+	var jobs []Job
+	getJSON(t, ts.URL+"/api/v1/jobs", &jobs)
+	if len(jobs) != 1 || jobs[0].ID != job.ID {
+		t.Errorf("expected the job in the list, got %+v", jobs)
+	}
+}
+
+func TestServer_Cancel(t *testing.T) {
+	s := New(Options{DataDir: t.TempDir()})
+	defer s.Close()
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+
+	_, job := upload(t, ts.URL, packageChart(t), map[string]string{"iterations": "1000000", "timeout": "10m"})
+
+	req, _ := http.NewRequest(http.MethodDelete, ts.URL+"/api/v1/jobs/"+job.ID, nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	deadline := time.Now().Add(30 * time.Second)
+	for job.Status != StatusCanceled {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the job to be canceled, got %+v", job)
+		}
+		time.Sleep(50 * time.Millisecond)
+		getJSON(t, ts.URL+"/api/v1/jobs/"+job.ID, &job)
+	}
+}
+
+func TestServer_Rejects(t *testing.T) {
+	s := New(Options{DataDir: t.TempDir(), MaxTimeout: time.Minute})
+	defer s.Close()
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+
+	if resp, _ := upload(t, ts.URL, packageChart(t), map[string]string{"timeout": "2h"}); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected a timeout above the maximum to be rejected, got %d", resp.StatusCode)
+	}
+	if resp, _ := upload(t, ts.URL, []byte("not a chart"), nil); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected an invalid archive to be rejected, got %d", resp.StatusCode)
+	}
+
+	resp, err := http.Post(ts.URL+"/api/v1/jobs", "application/json", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected a request without a chart to be rejected, got %d", resp.StatusCode)
+	}
+
+	var body map[string]string
+	if code := getJSON(t, ts.URL+"/api/v1/jobs/missing", &body); code != http.StatusNotFound || body["error"] == "" {
+		t.Errorf("expected 404 with an error, got %d %v", code, body)
+	}
+}
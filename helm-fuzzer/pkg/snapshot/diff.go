@@ -0,0 +1,89 @@
+package snapshot
+
+import (
+	"fmt"
+	"strings"
+)
+
+// contextLines is the number of unchanged lines shown around each change
+const contextLines = 3
+
+// Diff returns a unified diff from want to got, line by line, or an empty
+// string if they are equal
+func Diff(want, got string) string {
+	if want == got {
+		return ""
+	}
+	a, b := splitLines(want), splitLines(got)
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	// Walk the table into an edit script of kept, removed and added lines
+	type edit struct {
+		op   byte
+		line string
+		// aLine and bLine are the 1-based line numbers the edit starts at on each side
+		aLine, bLine int
+	}
+	var edits []edit
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			edits = append(edits, edit{' ', a[i], i + 1, j + 1})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			edits = append(edits, edit{'-', a[i], i + 1, j + 1})
+			i++
+		default:
+			edits = append(edits, edit{'+', b[j], i + 1, j + 1})
+			j++
+		}
+	}
+
+	// Group changes into hunks with context around them
+	var out strings.Builder
+	for start := 0; start < len(edits); {
+		if edits[start].op == ' ' {
+			start++
+			continue
+		}
+		from := max(0, start-contextLines)
+		end := start
+		for k := start; k < len(edits) && k <= end+2*contextLines; k++ {
+			if edits[k].op != ' ' {
+				end = k
+			}
+		}
+		to := min(len(edits), end+contextLines+1)
+
+		fmt.Fprintf(&out, "@@ -%d +%d @@\n", edits[from].aLine, edits[from].bLine)
+		for _, e := range edits[from:to] {
+			fmt.Fprintf(&out, "%c %s\n", e.op, e.line)
+		}
+		start = to
+	}
+	return out.String()
+}
+
+// splitLines splits text into lines without the trailing empty line
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
@@ -0,0 +1,177 @@
+// Package snapshot records the manifests a chart renders for named values
+// and verifies later renders against them. Manifests are normalized first,
+// so only changes to what would be applied to a cluster show up.
+package snapshot
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/runner"
+)
+
+// checksumPrefix marks annotations that hash other templates, such as
+// checksum/config, which change whenever any of those templates does
+const checksumPrefix = "checksum/"
+
+// Statuses of a verified scenario
+const (
+	StatusMatch   = "ok"
+	StatusChanged = "changed"
+	// StatusMissing is a scenario with no recorded snapshot
+	StatusMissing = "missing"
+	// StatusFailed is a scenario that no longer renders
+	StatusFailed = "failed"
+)
+
+// Result is the outcome of verifying one scenario
+type Result struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	// Diff shows how the render differs from the snapshot, for StatusChanged
+	Diff string `json:"diff,omitempty"`
+	// Error is the render error, for StatusFailed
+	Error string `json:"error,omitempty"`
+}
+
+// Normalize renders a manifest in a canonical form: one resource per
+// document, sorted by kind, namespace and name, with map keys sorted and
+// checksum annotations and chart version labels removed
+func Normalize(manifest string) (string, error) {
+	type resource struct {
+		key string
+		doc interface{}
+	}
+	var resources []resource
+
+	dec := yaml.NewDecoder(strings.NewReader(manifest))
+	for {
+		var doc map[string]interface{}
+		if err := dec.Decode(&doc); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return "", fmt.Errorf("failed to parse manifest: %w", err)
+		}
+		if doc == nil {
+			continue
+		}
+
+		namespace, name := "", ""
+		if metadata, ok := doc["metadata"].(map[string]interface{}); ok {
+			dropChecksums(metadata)
+			namespace, _ = metadata["namespace"].(string)
+			name, _ = metadata["name"].(string)
+		}
+		if spec, ok := doc["spec"].(map[string]interface{}); ok {
+			if template, ok := spec["template"].(map[string]interface{}); ok {
+				if metadata, ok := template["metadata"].(map[string]interface{}); ok {
+					dropChecksums(metadata)
+				}
+			}
+		}
+		key := fmt.Sprintf("%v/%s/%s", doc["kind"], namespace, name)
+		resources = append(resources, resource{key: key, doc: runner.StripVersionLabels(doc)})
+	}
+	sort.SliceStable(resources, func(i, j int) bool { return resources[i].key < resources[j].key })
+
+	var b strings.Builder
+	for _, r := range resources {
+		data, err := yaml.Marshal(r.doc)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode manifest: %w", err)
+		}
+		b.WriteString("---\n")
+		b.Write(data)
+	}
+	return b.String(), nil
+}
+
+// dropChecksums removes checksum annotations from metadata
+func dropChecksums(metadata map[string]interface{}) {
+	annotations, ok := metadata["annotations"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	for key := range annotations {
+		if strings.HasPrefix(key, checksumPrefix) {
+			delete(annotations, key)
+		}
+	}
+	if len(annotations) == 0 {
+		delete(metadata, "annotations")
+	}
+}
+
+// Path returns the file a scenario's snapshot is kept in
+func Path(dir, name string) string {
+	return filepath.Join(dir, name+".yaml")
+}
+
+// Save writes a normalized manifest as the scenario's snapshot, creating dir if needed
+func Save(dir, name, normalized string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+	if err := os.WriteFile(Path(dir, name), []byte(normalized), 0644); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	return nil
+}
+
+// Verify compares a render of a scenario with its snapshot. renderErr is
+// the error of a render that failed, in which case manifest is ignored.
+func Verify(dir, name, manifest string, renderErr error) Result {
+	result := Result{Name: name}
+	want, err := os.ReadFile(Path(dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		result.Status = StatusMissing
+		return result
+	} else if err != nil {
+		result.Status, result.Error = StatusFailed, err.Error()
+		return result
+	}
+	if renderErr != nil {
+		result.Status, result.Error = StatusFailed, renderErr.Error()
+		return result
+	}
+
+	got, err := Normalize(manifest)
+	if err != nil {
+		result.Status, result.Error = StatusFailed, err.Error()
+		return result
+	}
+	if diff := Diff(string(want), got); diff != "" {
+		result.Status, result.Diff = StatusChanged, diff
+		return result
+	}
+	result.Status = StatusMatch
+	return result
+}
+
+// Obsolete lists the snapshots in dir that belong to none of the named scenarios
+func Obsolete(dir string, names []string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot directory: %w", err)
+	}
+	known := make(map[string]bool, len(names))
+	for _, name := range names {
+		known[name+".yaml"] = true
+	}
+	var obsolete []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".yaml") && !known[entry.Name()] {
+			obsolete = append(obsolete, strings.TrimSuffix(entry.Name(), ".yaml"))
+		}
+	}
+	return obsolete, nil
+}
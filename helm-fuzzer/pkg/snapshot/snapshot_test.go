@@ -0,0 +1,124 @@
+package snapshot
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const manifest = `---
+# Source: app/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: app
+  labels:
+    helm.sh/chart: app-0.1.0
+spec:
+  ports:
+    - port: 80
+---
+# Source: app/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  replicas: 1
+  template:
+    metadata:
+      annotations:
+        checksum/config: 1c2f
+        team: core
+`
+
+func TestNormalize(t *testing.T) {
+	got, err := Normalize(manifest)
+	if err != nil {
+		t.Fatalf("Normalize failed: %v", err)
+	}
+	want := `---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+    name: app
+spec:
+    replicas: 1
+    template:
+        metadata:
+            annotations:
+                team: core
+---
+apiVersion: v1
+kind: Service
+metadata:
+    labels: {}
+    name: app
+spec:
+    ports:
+        - port: 80
+`
+	if got != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, got)
+	}
+
+	// Reordered resources and keys, a new chart version and checksum normalize alike
+	reordered := strings.Replace(strings.Replace(manifest, "app-0.1.0", "app-0.2.0", 1), "1c2f", "9e8d", 1)
+	parts := strings.SplitN(reordered, "---\n# Source: app/templates/deployment.yaml", 2)
+	again, err := Normalize("---\n# Source: app/templates/deployment.yaml" + parts[1] + parts[0])
+	if err != nil {
+		t.Fatalf("Normalize failed: %v", err)
+	}
+	if again != got {
+		t.Errorf("expected reordering and volatile fields to be ignored, got\n%s", Diff(got, again))
+	}
+
+	if _, err := Normalize("kind: [unclosed"); err == nil {
+		t.Error("expected an error for a manifest that does not parse")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	if diff := Diff("a\nb\n", "a\nb\n"); diff != "" {
+		t.Errorf("expected no diff for equal text, got %q", diff)
+	}
+
+	want := strings.Join([]string{"l1", "l2", "l3", "l4", "l5", "l6", "l7", "l8", "l9", "l10"}, "\n") + "\n"
+	got := strings.Replace(want, "l5\n", "l5 changed\n", 1)
+	expected := "@@ -2 +2 @@\n  l2\n  l3\n  l4\n- l5\n+ l5 changed\n  l6\n  l7\n  l8\n"
+	if diff := Diff(want, got); diff != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, diff)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	dir := t.TempDir()
+	if r := Verify(dir, "default", manifest, nil); r.Status != StatusMissing {
+		t.Errorf("expected %s before recording, got %+v", StatusMissing, r)
+	}
+
+	normalized, err := Normalize(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Save(dir, "default", normalized); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if r := Verify(dir, "default", manifest, nil); r.Status != StatusMatch {
+		t.Errorf("expected %s, got %+v", StatusMatch, r)
+	}
+
+	r := Verify(dir, "default", strings.Replace(manifest, "replicas: 1", "replicas: 2", 1), nil)
+	if r.Status != StatusChanged || !strings.Contains(r.Diff, "-     replicas: 1\n+     replicas: 2") {
+		t.Errorf("expected the changed replicas in the diff, got %+v", r)
+	}
+
+	if r := Verify(dir, "default", "", errors.New("template: boom")); r.Status != StatusFailed || r.Error != "template: boom" {
+		t.Errorf("expected the render error, got %+v", r)
+	}
+
+	obsolete, err := Obsolete(dir, []string{"ingress"})
+	if err != nil || len(obsolete) != 1 || obsolete[0] != "default" {
+		t.Errorf("expected default to be obsolete, got %v, %v", obsolete, err)
+	}
+}
@@ -0,0 +1,369 @@
+// Package tracing exports spans of fuzzing sessions to an OpenTelemetry
+// collector, so teams running the fuzzer as a service can see where a
+// session's time goes: schema detection, sampled iterations with their
+// renders and oracles, minimization of findings and report writing.
+//
+// Spans are sent over OTLP/HTTP with the JSON encoding, which every
+// collector accepts on its HTTP port, without the OpenTelemetry SDK. A nil
+// Tracer, and the nil spans it starts, do nothing, so callers trace
+// unconditionally.
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/logging"
+)
+
+const (
+	// exportInterval is how often finished spans are exported
+	exportInterval = 5 * time.Second
+	// maxBatch is how many finished spans are exported at once; a batch
+	// filling up is exported without waiting for the interval
+	maxBatch = 512
+	// requestTimeout bounds each export so a slow collector cannot stall the session
+	requestTimeout = 10 * time.Second
+	// scopeName is the instrumentation scope of every span
+	scopeName = "github.com/kasuboski/helm-fuzzer"
+)
+
+// Options configure a tracer
+type Options struct {
+	// Endpoint is the collector's base URL, to which /v1/traces is added
+	Endpoint string
+	// Headers are sent with each export
+	Headers map[string]string
+	// ServiceName is the service.name resource attribute of the spans
+	ServiceName string
+	// Logger receives export failures; nil discards them
+	Logger *slog.Logger
+}
+
+// Tracer batches finished spans and exports them in the background
+type Tracer struct {
+	url     string
+	headers map[string]string
+	service string
+	client  *http.Client
+	logger  *slog.Logger
+
+	mu      sync.Mutex
+	pending []*Span
+	// failed is set once an export failed, so later failures are not warned of again
+	failed bool
+	closed bool
+
+	full chan struct{}
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New creates a tracer exporting to the endpoint until it is closed
+func New(opts Options) *Tracer {
+	t := &Tracer{
+		url:     strings.TrimSuffix(opts.Endpoint, "/") + "/v1/traces",
+		headers: opts.Headers,
+		service: opts.ServiceName,
+		client:  &http.Client{Timeout: requestTimeout},
+		logger:  logging.OrDiscard(opts.Logger),
+		full:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+	}
+	t.wg.Add(1)
+	go t.loop()
+	return t
+}
+
+// Close exports the spans finished so far and stops the tracer; spans
+// ending afterwards are dropped
+func (t *Tracer) Close() {
+	if t == nil {
+		return
+	}
+	close(t.stop)
+	t.wg.Wait()
+	t.mu.Lock()
+	t.closed, t.pending = true, nil
+	t.mu.Unlock()
+}
+
+// loop exports finished spans every interval, when a batch fills up, and
+// once more when the tracer is closed
+func (t *Tracer) loop() {
+	defer t.wg.Done()
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-t.full:
+		case <-t.stop:
+			t.export()
+			return
+		}
+		t.export()
+	}
+}
+
+// export sends the pending spans in batches, dropping those that fail
+func (t *Tracer) export() {
+	t.mu.Lock()
+	spans := t.pending
+	t.pending = nil
+	t.mu.Unlock()
+
+	for len(spans) > 0 {
+		batch := spans[:min(len(spans), maxBatch)]
+		spans = spans[len(batch):]
+		if err := t.send(batch); err != nil {
+			t.mu.Lock()
+			failed := t.failed
+			t.failed = true
+			t.mu.Unlock()
+			if !failed {
+				t.logger.Warn("span export failed; dropping spans", "endpoint", t.url, "error", err)
+			} else {
+				t.logger.Debug("span export failed", "error", err)
+			}
+		}
+	}
+}
+
+// send posts one batch of spans
+func (t *Tracer) send(batch []*Span) error {
+	body, err := json.Marshal(t.payload(batch))
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create export request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range t.headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to export spans: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("collector returned %s", resp.Status)
+	}
+	return nil
+}
+
+// finish queues an ended span for export
+func (t *Tracer) finish(s *Span) {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return
+	}
+	t.pending = append(t.pending, s)
+	full := len(t.pending) >= maxBatch
+	t.mu.Unlock()
+	if full {
+		select {
+		case t.full <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Start starts a span at the root of a new trace
+func (t *Tracer) Start(name string, attrs ...Attribute) *Span {
+	if t == nil {
+		return nil
+	}
+	var traceID [16]byte
+	rand.Read(traceID[:])
+	return t.start(name, traceID, [8]byte{}, attrs)
+}
+
+// start starts a span of a trace under a parent, the zero ID for none
+func (t *Tracer) start(name string, traceID [16]byte, parent [8]byte, attrs []Attribute) *Span {
+	s := &Span{tracer: t, name: name, traceID: traceID, parent: parent, start: time.Now(), attrs: attrs}
+	rand.Read(s.id[:])
+	return s
+}
+
+// Span is a timed operation of a session. Its methods are not safe for
+// concurrent use, except Child, which workers call on the session's span.
+type Span struct {
+	tracer  *Tracer
+	name    string
+	traceID [16]byte
+	id      [8]byte
+	parent  [8]byte
+	start   time.Time
+	end     time.Time
+	attrs   []Attribute
+	err     string
+	ended   bool
+}
+
+// Child starts a span under s
+func (s *Span) Child(name string, attrs ...Attribute) *Span {
+	if s == nil {
+		return nil
+	}
+	return s.tracer.start(name, s.traceID, s.id, attrs)
+}
+
+// SetAttributes adds attributes to the span
+func (s *Span) SetAttributes(attrs ...Attribute) {
+	if s == nil {
+		return
+	}
+	s.attrs = append(s.attrs, attrs...)
+}
+
+// SetError marks the span failed with err; a nil error leaves it as it is
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.err = err.Error()
+}
+
+// End ends the span and queues it for export; ending it again does nothing
+func (s *Span) End() {
+	if s == nil || s.ended {
+		return
+	}
+	s.ended, s.end = true, time.Now()
+	s.tracer.finish(s)
+}
+
+// Attribute is a key and a string, integer or boolean value
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// String returns a string attribute
+func String(key, value string) Attribute { return Attribute{key, value} }
+
+// Int returns an integer attribute
+func Int(key string, value int) Attribute { return Attribute{key, int64(value)} }
+
+// Bool returns a boolean attribute
+func Bool(key string, value bool) Attribute { return Attribute{key, value} }
+
+// The OTLP JSON encoding of spans: IDs are hex, 64-bit integers are strings
+
+type exportRequest struct {
+	ResourceSpans []resourceSpans `json:"resourceSpans"`
+}
+
+type resourceSpans struct {
+	Resource   resource     `json:"resource"`
+	ScopeSpans []scopeSpans `json:"scopeSpans"`
+}
+
+type resource struct {
+	Attributes []keyValue `json:"attributes"`
+}
+
+type scopeSpans struct {
+	Scope scope      `json:"scope"`
+	Spans []spanJSON `json:"spans"`
+}
+
+type scope struct {
+	Name string `json:"name"`
+}
+
+type spanJSON struct {
+	TraceID           string     `json:"traceId"`
+	SpanID            string     `json:"spanId"`
+	ParentSpanID      string     `json:"parentSpanId,omitempty"`
+	Name              string     `json:"name"`
+	Kind              int        `json:"kind"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+	Attributes        []keyValue `json:"attributes,omitempty"`
+	Status            *status    `json:"status,omitempty"`
+}
+
+type status struct {
+	// Code 2 is an error
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    *string `json:"intValue,omitempty"`
+	BoolValue   *bool   `json:"boolValue,omitempty"`
+}
+
+// payload encodes a batch of spans as an OTLP export request
+func (t *Tracer) payload(batch []*Span) exportRequest {
+	spans := make([]spanJSON, 0, len(batch))
+	for _, s := range batch {
+		span := spanJSON{
+			TraceID:           hex.EncodeToString(s.traceID[:]),
+			SpanID:            hex.EncodeToString(s.id[:]),
+			Name:              s.name,
+			Kind:              1, // internal
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+			Attributes:        keyValues(s.attrs),
+		}
+		if s.parent != [8]byte{} {
+			span.ParentSpanID = hex.EncodeToString(s.parent[:])
+		}
+		if s.err != "" {
+			span.Status = &status{Code: 2, Message: s.err}
+		}
+		spans = append(spans, span)
+	}
+	return exportRequest{ResourceSpans: []resourceSpans{{
+		Resource:   resource{Attributes: keyValues([]Attribute{String("service.name", t.service)})},
+		ScopeSpans: []scopeSpans{{Scope: scope{Name: scopeName}, Spans: spans}},
+	}}}
+}
+
+// keyValues encodes attributes, those of other types as strings
+func keyValues(attrs []Attribute) []keyValue {
+	out := make([]keyValue, 0, len(attrs))
+	for _, attr := range attrs {
+		var v anyValue
+		switch value := attr.Value.(type) {
+		case string:
+			v.StringValue = &value
+		case int64:
+			s := strconv.FormatInt(value, 10)
+			v.IntValue = &s
+		case bool:
+			v.BoolValue = &value
+		default:
+			s := fmt.Sprint(value)
+			v.StringValue = &s
+		}
+		out = append(out, keyValue{Key: attr.Key, Value: v})
+	}
+	return out
+}
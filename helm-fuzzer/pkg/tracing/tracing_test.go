@@ -0,0 +1,83 @@
+package tracing
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestTracer(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests []exportRequest
+		apiKey   string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" || r.Header.Get("Content-Type") != "application/json" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		var req exportRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		requests = append(requests, req)
+		apiKey = r.Header.Get("X-Api-Key")
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	tracer := New(Options{Endpoint: server.URL + "/", Headers: map[string]string{"x-api-key": "secret"}, ServiceName: "helm-fuzz"})
+	session := tracer.Start("fuzz", String("chart", "my-chart"))
+	iteration := session.Child("iteration", Int("iteration", 7), Bool("crashed", true))
+	iteration.SetError(errors.New("boom"))
+	iteration.End()
+	iteration.End()
+	session.End()
+	tracer.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(requests) != 1 || apiKey != "secret" {
+		t.Fatalf("expected one export with the header, got %d with %q", len(requests), apiKey)
+	}
+	rs := requests[0].ResourceSpans[0]
+	if service := rs.Resource.Attributes[0]; service.Key != "service.name" || *service.Value.StringValue != "helm-fuzz" {
+		t.Errorf("expected the service name, got %+v", service)
+	}
+	spans := rs.ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, each ended once, got %d", len(spans))
+	}
+	child, root := spans[0], spans[1]
+	if root.ParentSpanID != "" || child.ParentSpanID != root.SpanID || child.TraceID != root.TraceID || len(root.TraceID) != 32 {
+		t.Errorf("expected the iteration under the session in one trace, got %+v and %+v", child, root)
+	}
+	if child.Status == nil || child.Status.Code != 2 || child.Status.Message != "boom" {
+		t.Errorf("expected the iteration failed, got %+v", child.Status)
+	}
+	if a := child.Attributes; len(a) != 2 || *a[0].Value.IntValue != "7" || !*a[1].Value.BoolValue {
+		t.Errorf("expected the iteration's attributes, got %+v", a)
+	}
+}
+
+func TestTracer_Nil(t *testing.T) {
+	var tracer *Tracer
+	span := tracer.Start("fuzz")
+	child := span.Child("iteration")
+	child.SetAttributes(String("key", "value"))
+	child.SetError(errors.New("boom"))
+	child.End()
+	span.End()
+	tracer.Close()
+	if span != nil || child != nil {
+		t.Error("expected a nil tracer to start nil spans")
+	}
+}
@@ -0,0 +1,144 @@
+package triage
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/runner"
+)
+
+// Repro is a saved reproduction file
+type Repro struct {
+	File string
+	// Reason is the crash reason recorded in the file, empty for a plain values file
+	Reason string
+	Values map[string]interface{}
+}
+
+// LoadRepros reads the reproduction files at paths. A directory contributes
+// the files in it matching runner.ReproPattern, in name order.
+func LoadRepros(paths []string) ([]Repro, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read reproductions: %w", err)
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		matches, err := filepath.Glob(filepath.Join(path, runner.ReproPattern))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list reproductions in %s: %w", path, err)
+		}
+		sort.Strings(matches)
+		files = append(files, matches...)
+	}
+
+	repros := make([]Repro, 0, len(files))
+	for _, file := range files {
+		values, reason, err := runner.LoadReproduction(file)
+		if err != nil {
+			return nil, err
+		}
+		repros = append(repros, Repro{File: file, Reason: reason, Values: values})
+	}
+	return repros, nil
+}
+
+// Verification is a reproduction replayed against the current chart. It
+// passes only if the values now render cleanly.
+type Verification struct {
+	File string `json:"file"`
+	// Fingerprint and Reason identify the crash recorded in the file
+	Fingerprint string `json:"fingerprint,omitempty"`
+	Reason      string `json:"reason,omitempty"`
+	Status      Status `json:"status"`
+	// NewReason is the current crash reason when it differs from the recorded one
+	NewReason string `json:"newReason,omitempty"`
+	Passed    bool   `json:"passed"`
+}
+
+// VerifyReport is the outcome of verifying a set of reproductions
+type VerifyReport struct {
+	Chart   string         `json:"chart"`
+	Passed  int            `json:"passed"`
+	Failed  int            `json:"failed"`
+	Results []Verification `json:"results"`
+}
+
+// Verify replays each reproduction. A file without a recorded reason that
+// still crashes is open, with the crash as its new reason.
+func Verify(chart string, repros []Repro, replay ReplayFunc) *VerifyReport {
+	report := &VerifyReport{Chart: chart, Results: make([]Verification, 0, len(repros))}
+	for _, r := range repros {
+		v := Verification{File: r.File, Reason: r.Reason, Status: StatusFixed}
+		if r.Reason != "" {
+			v.Fingerprint = runner.Fingerprint(r.Reason)
+		}
+		if reason := replay(r.Values); reason != "" {
+			v.Status = StatusOpen
+			if runner.Fingerprint(reason) != v.Fingerprint {
+				if r.Reason != "" {
+					v.Status = StatusChanged
+				}
+				v.NewReason = reason
+			}
+		}
+		v.Passed = v.Status == StatusFixed
+		if v.Passed {
+			report.Passed++
+		} else {
+			report.Failed++
+		}
+		report.Results = append(report.Results, v)
+	}
+	return report
+}
+
+// VerifyFormats lists the supported verification report formats
+var VerifyFormats = []string{"text", "json"}
+
+// WriteVerify renders a verification report in the given format
+func WriteVerify(w io.Writer, format string, report *VerifyReport) error {
+	switch format {
+	case "text":
+		return WriteVerifyText(w, report)
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	default:
+		return fmt.Errorf("unsupported format %q (supported: %s)", format, strings.Join(VerifyFormats, ", "))
+	}
+}
+
+// WriteVerifyText prints one line per reproduction and the totals
+func WriteVerifyText(w io.Writer, report *VerifyReport) error {
+	var b strings.Builder
+	for _, v := range report.Results {
+		result := "PASS"
+		if !v.Passed {
+			result = "FAIL"
+		}
+		fmt.Fprintf(&b, "%s  %-8s %s\n", result, v.Status, v.File)
+		if !v.Passed {
+			if v.Reason != "" {
+				fmt.Fprintf(&b, "      was: %s\n", firstLine(v.Reason))
+			}
+			if v.NewReason != "" {
+				fmt.Fprintf(&b, "      now: %s\n", firstLine(v.NewReason))
+			}
+		}
+	}
+	fmt.Fprintf(&b, "\n%d reproduction(s): %d passed, %d failed\n", len(report.Results), report.Passed, report.Failed)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,207 @@
+package triage
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/report"
+	"github.com/kasuboski/helm-fuzzer/pkg/runner"
+)
+
+// Status is a saved crash's state against the current chart
+type Status string
+
+const (
+	// StatusOpen means the input still crashes the same way
+	StatusOpen Status = "open"
+	// StatusChanged means the input still crashes, with a different error
+	StatusChanged Status = "changed"
+	// StatusFixed means the input no longer crashes
+	StatusFixed Status = "fixed"
+)
+
+// statusOrder lists statuses in the order reports show them
+var statusOrder = []Status{StatusOpen, StatusChanged, StatusFixed}
+
+// Cluster is saved findings that share a fingerprint under the current rules
+type Cluster struct {
+	// ID is the finding ID of the cluster (see runner.FindingID)
+	ID          string                 `json:"id"`
+	Fingerprint string                 `json:"fingerprint"`
+	Category    string                 `json:"category"`
+	Reason      string                 `json:"reason"`
+	Location    string                 `json:"location,omitempty"`
+	Values      map[string]interface{} `json:"values"`
+	ReproFile   string                 `json:"reproFile,omitempty"`
+	// Runs lists the runs that saved a finding in this cluster
+	Runs []string `json:"runs"`
+	// Occurrences is the number of saved findings in the cluster
+	Occurrences int `json:"occurrences"`
+}
+
+// Result is a cluster replayed against the current chart
+type Result struct {
+	Cluster
+	Status Status `json:"status"`
+	// NewReason is the current crash reason when the status is changed
+	NewReason string `json:"newReason,omitempty"`
+}
+
+// ReplayFunc renders values against the current chart, returning the crash
+// reason or an empty string if the values render cleanly
+type ReplayFunc func(values map[string]interface{}) string
+
+// ClusterFindings regroups the findings of runs by their fingerprint under the
+// current deduplication rules, which may merge findings that older versions
+// kept apart. The first finding seen represents each cluster. Findings the
+// oracle now ignores are dropped and counted.
+func ClusterFindings(runs []report.Run, oracle *runner.Oracle) ([]Cluster, int) {
+	var clusters []Cluster
+	dropped := 0
+	index := make(map[string]int)
+	for _, run := range runs {
+		for _, f := range run.Report.Findings {
+			if !oracle.IsInterestingReason(f.Reason) {
+				dropped++
+				continue
+			}
+			fingerprint := runner.Fingerprint(f.Reason)
+			if i, ok := index[fingerprint]; ok {
+				clusters[i].Occurrences++
+				if last := clusters[i].Runs[len(clusters[i].Runs)-1]; last != run.Name {
+					clusters[i].Runs = append(clusters[i].Runs, run.Name)
+				}
+				continue
+			}
+			index[fingerprint] = len(clusters)
+			clusters = append(clusters, Cluster{
+				ID:          runner.FindingID(run.Report.Chart, fingerprint),
+				Fingerprint: fingerprint,
+				Category:    runner.CategorizeReason(f.Reason),
+				Reason:      f.Reason,
+				Location:    f.Location(),
+				Values:      f.Values,
+				ReproFile:   f.ReproFile,
+				Runs:        []string{run.Name},
+				Occurrences: 1,
+			})
+		}
+	}
+	return clusters, dropped
+}
+
+// Replay renders each cluster's representative input and classifies it
+func Replay(clusters []Cluster, replay ReplayFunc) []Result {
+	results := make([]Result, 0, len(clusters))
+	for _, c := range clusters {
+		result := Result{Cluster: c, Status: StatusFixed}
+		if reason := replay(c.Values); reason != "" {
+			result.Status = StatusOpen
+			if runner.Fingerprint(reason) != c.Fingerprint {
+				result.Status = StatusChanged
+				result.NewReason = reason
+			}
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// Counts returns the number of results with each status
+func Counts(results []Result) map[Status]int {
+	counts := make(map[Status]int)
+	for _, r := range results {
+		counts[r.Status]++
+	}
+	return counts
+}
+
+// Formats lists the supported triage report formats
+var Formats = []string{"text", "markdown", "json"}
+
+// Write renders results in the given format
+func Write(w io.Writer, format string, results []Result) error {
+	switch format {
+	case "text":
+		return WriteText(w, results)
+	case "markdown":
+		return WriteMarkdown(w, results)
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(results)
+	default:
+		return fmt.Errorf("unsupported format %q (supported: %s)", format, strings.Join(Formats, ", "))
+	}
+}
+
+// WriteText prints results grouped by status
+func WriteText(w io.Writer, results []Result) error {
+	var b strings.Builder
+	counts := Counts(results)
+	for _, status := range statusOrder {
+		if counts[status] == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "%s (%d)\n", strings.ToUpper(string(status)), counts[status])
+		for _, r := range results {
+			if r.Status != status {
+				continue
+			}
+			fmt.Fprintf(&b, "  %s  %s  %s\n", r.ID, r.Category, firstLine(r.Reason))
+			if r.NewReason != "" {
+				fmt.Fprintf(&b, "      now: %s\n", firstLine(r.NewReason))
+			}
+			fmt.Fprintf(&b, "      %d finding(s) in %d run(s)", r.Occurrences, len(r.Runs))
+			if r.ReproFile != "" {
+				fmt.Fprintf(&b, ", repro %s", r.ReproFile)
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "%d crash cluster(s): %d open, %d changed, %d fixed\n",
+		len(results), counts[StatusOpen], counts[StatusChanged], counts[StatusFixed])
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteMarkdown renders results as a table for issues or job summaries
+func WriteMarkdown(w io.Writer, results []Result) error {
+	var b strings.Builder
+	counts := Counts(results)
+	fmt.Fprintf(&b, "## 🩺 Helm Fuzz Triage\n\n%d open, %d changed, %d fixed\n\n",
+		counts[StatusOpen], counts[StatusChanged], counts[StatusFixed])
+	b.WriteString("| Status | ID | Category | Location | Reason | Findings |\n")
+	b.WriteString("|--------|----|----------|----------|--------|---------:|\n")
+	for _, status := range statusOrder {
+		for _, r := range results {
+			if r.Status != status {
+				continue
+			}
+			reason := firstLine(r.Reason)
+			if r.NewReason != "" {
+				reason += " → " + firstLine(r.NewReason)
+			}
+			fmt.Fprintf(&b, "| %s | `%s` | %s | %s | %s | %d |\n", r.Status, r.ID,
+				cell(r.Category), cell(r.Location), cell(reason), r.Occurrences)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// cell makes text safe to place in a markdown table cell
+func cell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
+
+// firstLine returns the first line of a multi-line string
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
@@ -0,0 +1,130 @@
+package triage
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/report"
+	"github.com/kasuboski/helm-fuzzer/pkg/runner"
+)
+
+func TestTriage(t *testing.T) {
+	nilPointer := "Error: template: app/templates/a.yaml:3:4: nil pointer evaluating interface {}.port"
+	runs := []report.Run{
+		{Name: "run1", Report: &report.JSONReport{Chart: "app", Findings: []report.JSONFinding{
+			{Reason: nilPointer, Values: map[string]interface{}{"case": "nil"}},
+			{Reason: "Error: template: app/templates/b.yaml:1:2: wrong type for value; expected string; got int", Values: map[string]interface{}{"case": "type"}},
+			{Reason: "Error: parse error at line 7", Values: map[string]interface{}{"case": "parse"}},
+		}}},
+		{Name: "run2", Report: &report.JSONReport{Chart: "app", Findings: []report.JSONFinding{
+			{Reason: strings.Replace(nilPointer, "3:4", "9:4", 1), Values: map[string]interface{}{"case": "nil-again"}},
+			{Reason: "Error: ignored by now", Values: map[string]interface{}{"case": "ignored"}},
+		}}},
+	}
+
+	oracle := runner.NewOracleWithConfig([]string{"ignored by now"}, nil)
+	clusters, dropped := ClusterFindings(runs, oracle)
+	if dropped != 1 {
+		t.Errorf("expected 1 dropped finding, got %d", dropped)
+	}
+	if len(clusters) != 3 {
+		t.Fatalf("expected 3 clusters, got %d: %+v", len(clusters), clusters)
+	}
+	if c := clusters[0]; c.Occurrences != 2 || len(c.Runs) != 2 || c.Values["case"] != "nil" {
+		t.Errorf("expected nil pointer cluster from both runs represented by the first, got %+v", c)
+	}
+	if want := runner.FindingID("app", runner.Fingerprint(nilPointer)); clusters[0].ID != want {
+		t.Errorf("expected cluster ID %s, got %s", want, clusters[0].ID)
+	}
+
+	results := Replay(clusters, func(values map[string]interface{}) string {
+		switch values["case"] {
+		case "nil":
+			return nilPointer
+		case "type":
+			return "Error: template: app/templates/b.yaml:1:2: nil pointer evaluating interface {}.name"
+		default:
+			return ""
+		}
+	})
+	want := []Status{StatusOpen, StatusChanged, StatusFixed}
+	for i, r := range results {
+		if r.Status != want[i] {
+			t.Errorf("result %d: status = %s, want %s", i, r.Status, want[i])
+		}
+	}
+	if results[1].NewReason == "" {
+		t.Error("expected changed result to carry the new reason")
+	}
+
+	var buf bytes.Buffer
+	if err := WriteText(&buf, results); err != nil {
+		t.Fatalf("WriteText failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "3 crash cluster(s): 1 open, 1 changed, 1 fixed") {
+		t.Errorf("unexpected summary:\n%s", buf.String())
+	}
+}
+
+func TestVerify(t *testing.T) {
+	dir := t.TempDir()
+	nilPointer := "Error: template: app/templates/a.yaml:3:4: nil pointer evaluating interface {}.port"
+	minimizer := runner.NewMinimizer(dir)
+	for _, values := range []map[string]interface{}{{"fixed": true}, {"open": true}, {"changed": true}} {
+		if _, err := minimizer.SaveReproduction(&runner.Result{Values: values}, nilPointer); err != nil {
+			t.Fatal(err)
+		}
+	}
+	plain := filepath.Join(dir, "hand-written.yaml")
+	if err := os.WriteFile(plain, []byte("plain: true\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	repros, err := LoadRepros([]string{dir, plain})
+	if err != nil {
+		t.Fatalf("LoadRepros failed: %v", err)
+	}
+	if len(repros) != 4 || repros[3].File != plain || repros[3].Reason != "" {
+		t.Fatalf("expected three saved reproductions and the plain file, got %+v", repros)
+	}
+
+	report := Verify("app", repros, func(values map[string]interface{}) string {
+		switch {
+		case values["open"] == true:
+			return "Error: template: app/templates/a.yaml:9:4: nil pointer evaluating interface {}.port"
+		case values["changed"] == true, values["plain"] == true:
+			return "Error: parse error at line 7"
+		}
+		return ""
+	})
+
+	// Each file's only value names the outcome the replay gives it
+	statuses := make(map[string]Status)
+	for i, v := range report.Results {
+		for key := range repros[i].Values {
+			statuses[key] = v.Status
+		}
+		if v.Passed != (v.Status == StatusFixed) {
+			t.Errorf("expected only fixed reproductions to pass, got %+v", v)
+		}
+	}
+	want := map[string]Status{"fixed": StatusFixed, "open": StatusOpen, "changed": StatusChanged, "plain": StatusOpen}
+	if !reflect.DeepEqual(statuses, want) {
+		t.Errorf("expected statuses %v, got %v", want, statuses)
+	}
+	if report.Passed != 1 || report.Failed != 3 {
+		t.Errorf("expected 1 passed and 3 failed, got %d and %d", report.Passed, report.Failed)
+	}
+
+	var b strings.Builder
+	if err := WriteVerify(&b, "json", report); err != nil {
+		t.Fatalf("WriteVerify failed: %v", err)
+	}
+	if !strings.Contains(b.String(), `"passed": false`) {
+		t.Errorf("expected per-file pass/fail in JSON, got %s", b.String())
+	}
+}
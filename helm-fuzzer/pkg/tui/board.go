@@ -0,0 +1,359 @@
+package tui
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/coverage"
+)
+
+// boardRedrawInterval limits how often progress rows are redrawn
+const boardRedrawInterval = 100 * time.Millisecond
+
+// Board shows one progress row per chart when several charts are fuzzed in
+// one invocation, with an aggregate summary at the end. Crashes and log
+// messages are printed above the rows, prefixed with the chart name.
+type Board struct {
+	mu        sync.Mutex
+	writer    io.Writer
+	ciMode    bool
+	verbosity Verbosity
+	// label names what each row is, "chart" unless set with SetLabel
+	label     string
+	startTime time.Time
+	rows      []*BoardRow
+	// drawn is the number of rows currently on screen below the cursor's line
+	drawn    int
+	lastDraw time.Time
+}
+
+// NewBoard creates a board; in CI mode rows are not redrawn and only events
+// and the summary are printed
+func NewBoard(ciMode bool) *Board {
+	return &Board{
+		writer:    os.Stdout,
+		ciMode:    ciMode,
+		verbosity: VerbosityNormal,
+		label:     "chart",
+		startTime: time.Now(),
+	}
+}
+
+// SetWriter sets a custom writer (useful for testing)
+func (b *Board) SetWriter(w io.Writer) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.writer = w
+}
+
+// SetVerbosity sets which messages are printed for every chart
+func (b *Board) SetVerbosity(v Verbosity) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.verbosity = v
+}
+
+// SetLabel names what each row is (e.g. "cell"), used in the header and summary
+func (b *Board) SetLabel(label string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.label = label
+}
+
+// Chart adds a row for a chart and returns the UI that drives it
+func (b *Board) Chart(name string) *BoardRow {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	row := &BoardRow{board: b, name: name, progress: newProgress(0, 0), hotspots: newHotspots()}
+	b.rows = append(b.rows, row)
+	return row
+}
+
+// Start prints the header for a run over the given number of rows
+func (b *Board) Start(rows int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.startTime = time.Now()
+	if !b.verbosity.allows(VerbosityNormal) {
+		return
+	}
+	fmt.Fprintf(b.writer, "%sHelm Fuzz - Fuzzing %d %ss\n", sym.start, rows, b.label)
+	fmt.Fprintf(b.writer, "%sStarted at: %s\n\n", sym.clock, b.startTime.Format("15:04:05"))
+}
+
+// Finish draws the final rows and prints the aggregate summary
+func (b *Board) Finish() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.redraw(true)
+	if b.drawn > 0 {
+		fmt.Fprintln(b.writer)
+	}
+	b.drawn = 0
+
+	heading := strings.ToUpper(b.label[:1]) + b.label[1:]
+	width := max(len("Total"), len(heading))
+	for _, row := range b.rows {
+		if len(row.name) > width {
+			width = len(row.name)
+		}
+	}
+
+	var iterations, crashes, unique int
+	combined := newHotspots()
+	fmt.Fprintf(b.writer, "%sFuzzed %d %ss in %s\n", sym.completed, len(b.rows), b.label, formatDuration(time.Since(b.startTime)))
+	fmt.Fprintf(b.writer, "   %-*s  %10s  %7s  %6s  %5s  %9s  %s\n", width, heading, "Iterations", "Crashes", "Unique", "Paths", "Templates", "Status")
+	for _, row := range b.rows {
+		paths, templates := "-", "-"
+		if c := row.coverage; c != nil {
+			paths = fmt.Sprintf("%.0f%%", coverage.Percent(c.PathsSet, c.Paths))
+			templates = fmt.Sprintf("%.0f%%", coverage.Percent(c.TemplatesRendered, c.Templates))
+		}
+		fmt.Fprintf(b.writer, "   %-*s  %10d  %7d  %6d  %5s  %9s  %s\n", width, row.name, row.iterations, row.crashes, row.unique, paths, templates, row.status())
+		iterations += row.iterations
+		crashes += row.crashes
+		unique += row.unique
+		combined.merge(row.hotspots)
+	}
+	fmt.Fprintf(b.writer, "   %-*s  %10d  %7d  %6d\n", width, "Total", iterations, crashes, unique)
+
+	if b.verbosity.allows(VerbosityNormal) {
+		combined.write(b.writer)
+	}
+
+	if crashes == 0 {
+		fmt.Fprintf(b.writer, "\n%sNo crashes found! Your charts are robust.\n", sym.success)
+	} else {
+		fmt.Fprintf(b.writer, "\n%sFound %d crash(es). Please review the reproduction files.\n", sym.warning, crashes)
+	}
+}
+
+// interactive reports whether rows are drawn; callers hold b.mu
+func (b *Board) interactive() bool {
+	return !b.ciMode && b.verbosity.allows(VerbosityNormal)
+}
+
+// redraw repaints every row in place, at most every boardRedrawInterval
+// unless forced; callers hold b.mu
+func (b *Board) redraw(force bool) {
+	if !b.interactive() {
+		return
+	}
+	now := time.Now()
+	if !force && now.Sub(b.lastDraw) < boardRedrawInterval {
+		return
+	}
+	b.lastDraw = now
+
+	b.clearRows()
+	width := 0
+	for _, row := range b.rows {
+		if len(row.name) > width {
+			width = len(row.name)
+		}
+	}
+	for _, row := range b.rows {
+		fmt.Fprintf(b.writer, "  %-*s  %s\n", width, row.name, row.line(now))
+	}
+	b.drawn = len(b.rows)
+}
+
+// clearRows moves the cursor back over the drawn rows and erases them;
+// callers hold b.mu
+func (b *Board) clearRows() {
+	if b.drawn > 0 {
+		fmt.Fprintf(b.writer, "\x1b[%dA\x1b[J", b.drawn)
+		b.drawn = 0
+	}
+}
+
+// print writes a message above the rows and redraws them; callers hold b.mu
+func (b *Board) print(message string) {
+	b.clearRows()
+	fmt.Fprint(b.writer, message)
+	b.redraw(true)
+}
+
+// BoardRow is the UI for one chart on a Board
+type BoardRow struct {
+	board      *Board
+	name       string
+	started    bool
+	finished   bool
+	err        error
+	startTime  time.Time
+	duration   time.Duration
+	iterations int
+	crashes    int
+	unique     int
+	progress   *progress
+	hotspots   *hotspots
+	coverage   *coverage.Summary
+}
+
+// line renders the row's progress; callers hold the board lock
+func (r *BoardRow) line(now time.Time) string {
+	switch {
+	case r.err != nil:
+		return fmt.Sprintf("%sfailed: %s", sym.error, firstLine(r.err.Error()))
+	case !r.started:
+		return "waiting"
+	}
+
+	fraction := r.progress.Fraction(r.iterations, now)
+	status := fmt.Sprintf("%sRate: %.1f/s%sETA: --", sym.rate, r.progress.Rate(r.iterations, now), sym.separator)
+	if r.finished {
+		fraction = 1
+		status = fmt.Sprintf("%sdone in %s", sym.completed, formatDuration(r.duration))
+	} else if d, ok := r.progress.ETA(r.iterations, now); ok {
+		status = fmt.Sprintf("%sRate: %.1f/s%sETA: %s", sym.rate, r.progress.Rate(r.iterations, now), sym.separator, formatDuration(d))
+	}
+
+	return fmt.Sprintf("[%s] %3.0f%% %s%s%sCrashes: %d (%d unique)%s%s",
+		bar(fraction), fraction*100, r.progress.Count(r.iterations), sym.separator,
+		sym.crash, r.crashes, r.unique, sym.separator, status)
+}
+
+// status describes the row's outcome in the summary table
+func (r *BoardRow) status() string {
+	switch {
+	case r.err != nil:
+		return "failed: " + firstLine(r.err.Error())
+	case !r.finished:
+		return "incomplete"
+	case r.unique > 0:
+		return "crashes found"
+	default:
+		return "clean"
+	}
+}
+
+// Start starts the chart's row
+func (r *BoardRow) Start(chartName string, maxIterations int, timeout time.Duration) {
+	b := r.board
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	r.started = true
+	r.startTime = time.Now()
+	r.progress = newProgress(maxIterations, timeout)
+	if b.interactive() {
+		b.redraw(true)
+	} else if b.verbosity.allows(VerbosityNormal) {
+		fmt.Fprintf(b.writer, "[%s] %sstarted: %s\n", r.name, sym.start, budget(maxIterations, timeout))
+	}
+}
+
+// Update records a completed iteration
+func (r *BoardRow) Update(iteration int, crashed bool) {
+	b := r.board
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	r.iterations = iteration
+	if crashed {
+		r.crashes++
+	}
+	r.progress.observe(iteration, time.Now())
+	b.redraw(false)
+}
+
+// ReportCrash prints the crash above the rows
+func (r *BoardRow) ReportCrash(c Crash) {
+	b := r.board
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	r.unique++
+	r.hotspots.add(c)
+	if !b.verbosity.allows(VerbosityNormal) {
+		return
+	}
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "[%s] %sCRASH DETECTED at iteration %d\n", r.name, sym.crash, c.Iteration)
+	writeCrashDetail(&msg, c)
+	b.print(msg.String())
+}
+
+// ReportCoverage records the chart's coverage for the summary table
+func (r *BoardRow) ReportCoverage(c coverage.Summary) {
+	b := r.board
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	r.coverage = &c
+}
+
+// Finish marks the chart's row as done
+func (r *BoardRow) Finish() {
+	b := r.board
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	r.finished = true
+	r.duration = time.Since(r.startTime)
+	if b.interactive() {
+		b.redraw(true)
+	} else if b.verbosity.allows(VerbosityNormal) {
+		fmt.Fprintf(b.writer, "[%s] %scompleted: %d iterations, %d crashes (%d unique) in %s\n",
+			r.name, sym.completed, r.iterations, r.crashes, r.unique, formatDuration(r.duration))
+	}
+}
+
+// Fail marks the chart as failed with err, which is shown in its row and the summary
+func (r *BoardRow) Fail(err error) {
+	b := r.board
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	r.err = err
+	if b.interactive() {
+		b.redraw(true)
+	} else {
+		fmt.Fprintf(b.writer, "[%s] %s%v\n", r.name, sym.error, err)
+	}
+}
+
+// Wait never blocks since the board cannot be paused
+func (r *BoardRow) Wait() bool {
+	return true
+}
+
+// SetVerbosity sets the verbosity for the whole board
+func (r *BoardRow) SetVerbosity(v Verbosity) {
+	r.board.SetVerbosity(v)
+}
+
+// log prints a message for this chart if the level is enabled
+func (r *BoardRow) log(level Verbosity, prefix, format string, args ...interface{}) {
+	b := r.board
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.verbosity.allows(level) {
+		return
+	}
+	b.print(fmt.Sprintf("[%s] %s%s\n", r.name, prefix, fmt.Sprintf(format, args...)))
+}
+
+// LogDebug logs debug information (only when verbose)
+func (r *BoardRow) LogDebug(format string, args ...interface{}) {
+	r.log(VerbosityVerbose, sym.debug, format, args...)
+}
+
+// LogWarning logs a warning message (hidden when quiet)
+func (r *BoardRow) LogWarning(format string, args ...interface{}) {
+	r.log(VerbosityNormal, sym.warning, format, args...)
+}
+
+// LogError logs an error message
+func (r *BoardRow) LogError(format string, args ...interface{}) {
+	r.log(VerbosityQuiet, sym.error, format, args...)
+}
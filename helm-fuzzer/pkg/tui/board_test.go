@@ -0,0 +1,78 @@
+package tui
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/coverage"
+)
+
+func TestBoard_CI(t *testing.T) {
+	var buf bytes.Buffer
+	board := NewBoard(true)
+	board.SetWriter(&buf)
+	board.Start(2)
+
+	app := board.Chart("app")
+	db := board.Chart("db")
+
+	app.Start("app", 10, time.Minute)
+	app.Update(1, true)
+	app.ReportCrash(Crash{Iteration: 1, Reason: "template: app/templates/deployment.yaml:3:4: executing at <.Values.image>: nil pointer"})
+	app.Update(2, false)
+	app.ReportCoverage(coverage.Summary{Paths: 4, PathsSet: 3, Templates: 2, TemplatesRendered: 2})
+	app.Finish()
+	db.Fail(errors.New("failed to load config: bad yaml"))
+	board.Finish()
+
+	out := buf.String()
+	if strings.Contains(out, "\x1b[") {
+		t.Errorf("expected no cursor movement in CI mode:\n%q", out)
+	}
+	for _, want := range []string{
+		"Fuzzing 2 charts",
+		"[app] 🔍 started: 10 iterations",
+		"[app] 💥 CRASH DETECTED at iteration 1",
+		"[app] ✅ completed: 2 iterations, 1 crashes (1 unique)",
+		"[db] ❌ failed to load config: bad yaml",
+		"Fuzzed 2 charts",
+		"   app             2        1       1    75%       100%  crashes found\n",
+		"   db              0        0       0      -          -  failed: failed to load config: bad yaml\n",
+		"   Total           2        1       1\n",
+		"templates/deployment.yaml",
+		"Found 1 crash(es)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected output to contain %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestBoard_Interactive(t *testing.T) {
+	var buf bytes.Buffer
+	board := NewBoard(false)
+	board.SetWriter(&buf)
+
+	app := board.Chart("app")
+	board.Chart("database")
+	app.Start("app", 4, time.Minute)
+	app.LogWarning("careful")
+	app.Update(4, false)
+	app.Finish()
+	board.Finish()
+
+	out := buf.String()
+	for _, want := range []string{
+		"  app       [",
+		"  database  waiting\n",
+		"\x1b[2A\x1b[J[app] ⚠️  careful\n",
+		"100% 4/4",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected output to contain %q:\n%q", want, out)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package tui
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/coverage"
+)
+
+// maxCoverageList is the number of never-exercised entries listed per kind
+const maxCoverageList = 5
+
+// writeCoverage prints how much of the schema and templates the session exercised
+func writeCoverage(w io.Writer, c *coverage.Summary) {
+	if c == nil {
+		return
+	}
+
+	fmt.Fprintf(w, "\n%sCoverage:\n", sym.coverage)
+	fmt.Fprintf(w, "   Value paths set:     %s\n", ratio(c.PathsSet, c.Paths))
+	fmt.Fprintf(w, "   Enum values chosen:  %s\n", ratio(c.EnumValuesChosen, c.EnumValues))
+	fmt.Fprintf(w, "   Templates rendered:  %s\n", ratio(c.TemplatesRendered, c.Templates))
+	if c.Regions > 0 {
+		fmt.Fprintf(w, "   Regions executed:    %s\n", ratio(c.RegionsExecuted, c.Regions))
+	}
+	writeCoverageList(w, "Never set:     ", c.UnsetPaths)
+	writeCoverageList(w, "Never chosen:  ", c.UnchosenEnumValues)
+	writeCoverageList(w, "Never rendered:", c.UnrenderedTemplates)
+}
+
+// ratio formats part/total with a percentage
+func ratio(part, total int) string {
+	return fmt.Sprintf("%d/%d (%.0f%%)", part, total, coverage.Percent(part, total))
+}
+
+// writeCoverageList prints the first few entries of a never-exercised list
+func writeCoverageList(w io.Writer, label string, entries []string) {
+	if len(entries) == 0 {
+		return
+	}
+	shown := entries
+	if len(shown) > maxCoverageList {
+		shown = shown[:maxCoverageList]
+	}
+	line := strings.Join(shown, ", ")
+	if more := len(entries) - len(shown); more > 0 {
+		line += fmt.Sprintf(", ... (%d more)", more)
+	}
+	fmt.Fprintf(w, "   %s %s\n", label, line)
+}
@@ -0,0 +1,466 @@
+package tui
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/coverage"
+	"github.com/kasuboski/helm-fuzzer/pkg/runner"
+)
+
+// Dashboard is a full-screen interactive UI built on bubbletea
+type Dashboard struct {
+	writer    io.Writer
+	control   *control
+	model     *model
+	program   *tea.Program
+	launch    sync.Once
+	done      chan struct{}
+	mu        sync.Mutex
+	running   bool
+	verbosity Verbosity
+	coverage  *coverage.Summary
+}
+
+// NewDashboard creates a new full-screen dashboard
+func NewDashboard() *Dashboard {
+	ctrl := newControl()
+	return &Dashboard{
+		writer:    os.Stdout,
+		control:   ctrl,
+		model:     newModel(ctrl),
+		done:      make(chan struct{}),
+		verbosity: VerbosityNormal,
+	}
+}
+
+// Start records session details; the screen is taken over once fuzzing begins
+func (d *Dashboard) Start(chartName string, maxIterations int, timeout time.Duration) {
+	d.model.chartName = chartName
+	d.model.startTime = time.Now()
+	d.model.progress = newProgress(maxIterations, timeout)
+}
+
+// ensureRunning launches the bubbletea program on first use
+func (d *Dashboard) ensureRunning() {
+	d.launch.Do(func() {
+		d.mu.Lock()
+		d.program = tea.NewProgram(d.model, tea.WithAltScreen())
+		d.running = true
+		d.mu.Unlock()
+
+		go func() {
+			defer close(d.done)
+			if _, err := d.program.Run(); err != nil {
+				fmt.Fprintf(os.Stderr, "dashboard error: %v\n", err)
+			}
+			// Closing the dashboard stops the session
+			d.control.stop()
+		}()
+	})
+}
+
+// send delivers a message to the running program, or applies it directly before launch
+func (d *Dashboard) send(msg tea.Msg) {
+	d.mu.Lock()
+	running := d.running
+	d.mu.Unlock()
+
+	if running {
+		d.program.Send(msg)
+		return
+	}
+	d.model.Update(msg)
+}
+
+// Update records a completed iteration
+func (d *Dashboard) Update(iteration int, crashed bool) {
+	d.ensureRunning()
+	d.send(updateMsg{iteration: iteration, crashed: crashed})
+}
+
+// ReportCrash adds a finding to the crash table
+func (d *Dashboard) ReportCrash(c Crash) {
+	d.ensureRunning()
+	d.send(crashMsg(c))
+}
+
+// ReportCoverage records the coverage printed with the final summary
+func (d *Dashboard) ReportCoverage(c coverage.Summary) {
+	d.coverage = &c
+}
+
+// Wait blocks while paused and reports whether fuzzing should continue
+func (d *Dashboard) Wait() bool {
+	d.ensureRunning()
+	return d.control.wait()
+}
+
+// Finish closes the dashboard and prints a plain summary to the terminal
+func (d *Dashboard) Finish() {
+	d.mu.Lock()
+	running := d.running
+	d.mu.Unlock()
+
+	if running {
+		d.program.Send(finishMsg{})
+		<-d.done
+	}
+
+	m := d.model
+	summary := &TUI{writer: d.writer, startTime: m.startTime, iterations: m.iterations, crashes: m.crashes, noProgress: true, verbosity: d.verbosity, hotspots: newHotspots(), coverage: d.coverage}
+	for _, f := range m.findings {
+		summary.hotspots.add(f.Crash)
+	}
+	if !d.verbosity.allows(VerbosityNormal) {
+		summary.Finish()
+		return
+	}
+	for _, f := range m.findings {
+		fmt.Fprintf(d.writer, "%sCRASH at iteration %d [%s]\n", sym.crash, f.Iteration, f.category)
+		writeCrashDetail(d.writer, f.Crash)
+	}
+	if len(m.findings) > 0 {
+		fmt.Fprintln(d.writer)
+	}
+	summary.Finish()
+}
+
+// SetVerbosity sets which messages reach the log pane
+func (d *Dashboard) SetVerbosity(v Verbosity) {
+	d.verbosity = v
+}
+
+// LogDebug adds a debug line to the log pane (only when verbose)
+func (d *Dashboard) LogDebug(format string, args ...interface{}) {
+	if !d.verbosity.allows(VerbosityVerbose) {
+		return
+	}
+	d.send(logMsg(sym.debug + fmt.Sprintf(format, args...)))
+}
+
+// LogWarning adds a warning line to the log pane (hidden when quiet)
+func (d *Dashboard) LogWarning(format string, args ...interface{}) {
+	if !d.verbosity.allows(VerbosityNormal) {
+		return
+	}
+	d.send(logMsg(sym.warning + fmt.Sprintf(format, args...)))
+}
+
+// LogError adds an error line to the log pane
+func (d *Dashboard) LogError(format string, args ...interface{}) {
+	d.send(logMsg(sym.error + fmt.Sprintf(format, args...)))
+}
+
+// control lets the dashboard pause, resume and stop the fuzzing workers
+type control struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	paused  bool
+	stopped bool
+}
+
+func newControl() *control {
+	c := &control{}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+// wait blocks while paused and returns false once stopped
+func (c *control) wait() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for c.paused && !c.stopped {
+		c.cond.Wait()
+	}
+	return !c.stopped
+}
+
+// togglePause flips the paused state and returns the new state
+func (c *control) togglePause() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.paused = !c.paused
+	c.cond.Broadcast()
+	return c.paused
+}
+
+// stop releases any paused workers and ends the session
+func (c *control) stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stopped = true
+	c.cond.Broadcast()
+}
+
+// Messages delivered to the bubbletea model
+type (
+	updateMsg struct {
+		iteration int
+		crashed   bool
+	}
+	crashMsg  Crash
+	logMsg    string
+	tickMsg   time.Time
+	finishMsg struct{}
+)
+
+// finding is a crash shown in the crash table
+type finding struct {
+	Crash
+	category string
+}
+
+// sparkWidth is the number of rate samples kept for the sparkline
+const sparkWidth = 40
+
+// maxLogLines is the number of log lines kept in the log pane
+const maxLogLines = 5
+
+// model is the bubbletea state of the dashboard
+type model struct {
+	control    *control
+	chartName  string
+	startTime  time.Time
+	progress   *progress
+	iterations int
+	crashes    int
+	categories map[string]int
+	findings   []finding
+	rates      []float64
+	lastSample int
+	selected   int
+	showDetail bool
+	paused     bool
+	finished   bool
+	logs       []string
+	width      int
+}
+
+func newModel(ctrl *control) *model {
+	return &model{
+		control:    ctrl,
+		startTime:  time.Now(),
+		progress:   newProgress(0, 0),
+		categories: make(map[string]int),
+		width:      100,
+	}
+}
+
+func tick() tea.Cmd {
+	return tea.Tick(time.Second, func(t time.Time) tea.Msg {
+		return tickMsg(t)
+	})
+}
+
+// Init starts the rate sampler
+func (m *model) Init() tea.Cmd {
+	return tick()
+}
+
+// Update applies a message to the model
+func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "q", "ctrl+c":
+			m.control.stop()
+			return m, tea.Quit
+		case "p", " ":
+			m.paused = m.control.togglePause()
+		case "up", "k":
+			if m.selected > 0 {
+				m.selected--
+			}
+		case "down", "j":
+			if m.selected < len(m.findings)-1 {
+				m.selected++
+			}
+		case "enter":
+			m.showDetail = !m.showDetail && len(m.findings) > 0
+		case "esc":
+			m.showDetail = false
+		}
+	case updateMsg:
+		m.iterations = msg.iteration
+		m.progress.observe(msg.iteration, time.Now())
+		if msg.crashed {
+			m.crashes++
+		}
+	case crashMsg:
+		category := runner.CategorizeReason(msg.Reason)
+		m.categories[category]++
+		m.findings = append(m.findings, finding{Crash: Crash(msg), category: category})
+	case logMsg:
+		m.logs = append(m.logs, string(msg))
+		if len(m.logs) > maxLogLines {
+			m.logs = m.logs[len(m.logs)-maxLogLines:]
+		}
+	case tickMsg:
+		m.rates = append(m.rates, float64(m.iterations-m.lastSample))
+		m.lastSample = m.iterations
+		if len(m.rates) > sparkWidth {
+			m.rates = m.rates[len(m.rates)-sparkWidth:]
+		}
+		if !m.finished {
+			return m, tick()
+		}
+	case finishMsg:
+		m.finished = true
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+var (
+	titleStyle    = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("12"))
+	labelStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	crashStyle    = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("9"))
+	selectedStyle = lipgloss.NewStyle().Reverse(true)
+	paneStyle     = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).Padding(0, 1)
+)
+
+// View renders the dashboard
+func (m *model) View() string {
+	var b strings.Builder
+
+	state := "RUNNING"
+	if m.paused {
+		state = "PAUSED"
+	}
+	if m.finished {
+		state = "DONE"
+	}
+	fmt.Fprintf(&b, "%s  %s  [%s]\n\n", titleStyle.Render(sym.start+"Helm Fuzz"), m.chartName, state)
+
+	now := time.Now()
+	elapsed := now.Sub(m.startTime)
+	rate := m.progress.Rate(m.iterations, now)
+	fraction := m.progress.Fraction(m.iterations, now)
+	eta := "--"
+	if d, ok := m.progress.ETA(m.iterations, now); ok {
+		eta = formatDuration(d)
+	}
+
+	fmt.Fprintf(&b, "%s [%s] %3.0f%%   %s %s\n", labelStyle.Render("Progress  "), bar(fraction), fraction*100, labelStyle.Render("ETA"), eta)
+	fmt.Fprintf(&b, "%s %s   %s %s   %s %d   %s %.1f/s   %s %s\n",
+		labelStyle.Render("Iterations"), m.progress.Count(m.iterations),
+		labelStyle.Render("Crashes"), crashStyle.Render(fmt.Sprint(m.crashes)),
+		labelStyle.Render("Unique"), len(m.findings),
+		labelStyle.Render("Rate"), rate,
+		labelStyle.Render("Elapsed"), formatDuration(elapsed))
+	fmt.Fprintf(&b, "%s %s\n", labelStyle.Render("Rate  "), sparkline(m.rates))
+	fmt.Fprintf(&b, "%s %s\n\n", labelStyle.Render("Categories"), m.categorySummary())
+
+	b.WriteString(paneStyle.Width(m.paneWidth()).Render(m.crashTable()))
+	b.WriteString("\n")
+
+	if m.showDetail && m.selected < len(m.findings) {
+		f := m.findings[m.selected]
+		detail := fmt.Sprintf("Iteration: %d\nCategory:  %s\nRepro:     %s\n\n%s", f.Iteration, f.category, f.ReproFile, f.Reason)
+		if len(f.Overrides) > 0 {
+			detail += "\n\nOverrides vs defaults:"
+			for _, change := range f.Overrides {
+				detail += "\n  " + change.String()
+			}
+		}
+		b.WriteString(paneStyle.Width(m.paneWidth()).Render(detail))
+		b.WriteString("\n")
+	}
+
+	for _, line := range m.logs {
+		b.WriteString(labelStyle.Render(line))
+		b.WriteString("\n")
+	}
+
+	help := []string{"p pause/resume", sym.upDown + " select", "enter details", "q quit"}
+	b.WriteString(labelStyle.Render("\n" + strings.Join(help, sym.separator)))
+	return b.String()
+}
+
+// paneWidth returns the inner width for bordered panes
+func (m *model) paneWidth() int {
+	if m.width > 4 {
+		return m.width - 4
+	}
+	return m.width
+}
+
+// crashTable renders the list of unique findings
+func (m *model) crashTable() string {
+	if len(m.findings) == 0 {
+		return "No crashes yet"
+	}
+
+	var lines []string
+	for i, f := range m.findings {
+		line := fmt.Sprintf("#%-6d %-15s %s", f.Iteration, f.category, firstLine(f.Reason))
+		if max := m.paneWidth() - 2; max > 0 && len(line) > max {
+			line = line[:max]
+		}
+		if i == m.selected {
+			line = selectedStyle.Render(line)
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// categorySummary renders per-category crash counters
+func (m *model) categorySummary() string {
+	if len(m.categories) == 0 {
+		return "-"
+	}
+
+	names := make([]string, 0, len(m.categories))
+	for name := range m.categories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s %d", name, m.categories[name])
+	}
+	return strings.Join(parts, sym.separator)
+}
+
+// sparkline renders samples as a row of block characters
+func sparkline(samples []float64) string {
+	blocks := sym.spark
+
+	max := 0.0
+	for _, s := range samples {
+		if s > max {
+			max = s
+		}
+	}
+
+	var b strings.Builder
+	for _, s := range samples {
+		idx := 0
+		if max > 0 {
+			idx = int(s / max * float64(len(blocks)-1))
+		}
+		b.WriteRune(blocks[idx])
+	}
+	return b.String()
+}
+
+// firstLine returns the first line of a multi-line string
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
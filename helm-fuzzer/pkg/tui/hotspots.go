@@ -0,0 +1,109 @@
+package tui
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/runner"
+)
+
+// topHotspots is the number of templates and value paths listed at the end of a run
+const topHotspots = 5
+
+// hotspots counts the templates and value paths implicated in findings
+type hotspots struct {
+	templates map[string]int
+	paths     map[string]int
+}
+
+// hotspot is a template or value path with the number of findings implicating it
+type hotspot struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func newHotspots() *hotspots {
+	return &hotspots{
+		templates: make(map[string]int),
+		paths:     make(map[string]int),
+	}
+}
+
+// add attributes a crash to its template and counts the value paths
+// implicated in it: the attributed one and the culprits ablation found, each
+// once, culprits named as .Values expressions like attributed paths
+func (h *hotspots) add(c Crash) {
+	paths := make(map[string]bool)
+	if attr := runner.Attribute(c.Reason); attr != nil {
+		h.templates[attr.File()]++
+		if attr.ValuePath != "" {
+			paths[attr.ValuePath] = true
+		}
+	}
+	for _, culprit := range c.Culprits {
+		paths[".Values."+culprit] = true
+	}
+	for path := range paths {
+		h.paths[path]++
+	}
+}
+
+// merge adds the counts from other
+func (h *hotspots) merge(other *hotspots) {
+	for name, count := range other.templates {
+		h.templates[name] += count
+	}
+	for name, count := range other.paths {
+		h.paths[name] += count
+	}
+}
+
+// top returns the n most frequent entries, ties broken by name
+func top(counts map[string]int, n int) []hotspot {
+	result := make([]hotspot, 0, len(counts))
+	for name, count := range counts {
+		result = append(result, hotspot{Name: name, Count: count})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Name < result[j].Name
+	})
+	if len(result) > n {
+		result = result[:n]
+	}
+	return result
+}
+
+// write prints histograms of the top templates and value paths
+func (h *hotspots) write(w io.Writer) {
+	writeHistogram(w, "Top failing templates:", top(h.templates, topHotspots))
+	writeHistogram(w, "Top value paths:", top(h.paths, topHotspots))
+}
+
+// writeHistogram prints one histogram, scaling bars to the largest count
+func writeHistogram(w io.Writer, title string, entries []hotspot) {
+	if len(entries) == 0 {
+		return
+	}
+
+	width := 0
+	for _, e := range entries {
+		if len(e.Name) > width {
+			width = len(e.Name)
+		}
+	}
+
+	fmt.Fprintf(w, "\n%s%s\n", sym.chart, title)
+	max := entries[0].Count
+	for _, e := range entries {
+		cells := e.Count * barWidth / max
+		if cells == 0 {
+			cells = 1
+		}
+		fmt.Fprintf(w, "   %-*s  %s %d\n", width, e.Name, strings.Repeat(sym.barFull, cells), e.Count)
+	}
+}
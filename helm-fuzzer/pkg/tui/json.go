@@ -0,0 +1,198 @@
+package tui
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/coverage"
+	"github.com/kasuboski/helm-fuzzer/pkg/runner"
+)
+
+// milestoneInterval is how often (in iterations) a progress event is emitted
+const milestoneInterval = 100
+
+// JSONLogger emits one JSON object per session event for log pipelines
+type JSONLogger struct {
+	mu         sync.Mutex
+	encoder    *json.Encoder
+	chart      string
+	startTime  time.Time
+	iterations int
+	crashes    int
+	unique     int
+	progress   *progress
+	verbosity  Verbosity
+	hotspots   *hotspots
+	coverage   *coverage.Summary
+}
+
+// NewJSON creates a new JSON event logger writing to w
+func NewJSON(w io.Writer) *JSONLogger {
+	return &JSONLogger{
+		encoder:   json.NewEncoder(w),
+		startTime: time.Now(),
+		progress:  newProgress(0, 0),
+		verbosity: VerbosityNormal,
+		hotspots:  newHotspots(),
+	}
+}
+
+// emit writes a single event
+func (j *JSONLogger) emit(event string, fields map[string]interface{}) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	record := map[string]interface{}{
+		"time":  time.Now().UTC().Format(time.RFC3339Nano),
+		"event": event,
+	}
+	// Every event names its chart so multi-chart streams can be told apart
+	if j.chart != "" {
+		record["chart"] = j.chart
+	}
+	for k, v := range fields {
+		record[k] = v
+	}
+	// Encoding errors are dropped, there is nowhere better to report them
+	_ = j.encoder.Encode(record)
+}
+
+// Start emits a session_start event
+func (j *JSONLogger) Start(chartName string, maxIterations int, timeout time.Duration) {
+	j.mu.Lock()
+	j.chart = chartName
+	j.startTime = time.Now()
+	j.progress = newProgress(maxIterations, timeout)
+	j.mu.Unlock()
+
+	j.emit("session_start", map[string]interface{}{
+		"maxIterations":  maxIterations,
+		"timeoutSeconds": timeout.Seconds(),
+	})
+}
+
+// Update emits an iteration event at every milestone
+func (j *JSONLogger) Update(iteration int, crashed bool) {
+	now := time.Now()
+
+	j.mu.Lock()
+	j.iterations = iteration
+	if crashed {
+		j.crashes++
+	}
+	j.progress.observe(iteration, now)
+	crashes := j.crashes
+	fields := map[string]interface{}{
+		"iteration": iteration,
+		"crashes":   crashes,
+		"rate":      j.progress.Rate(iteration, now),
+		"progress":  j.progress.Fraction(iteration, now),
+	}
+	if eta, ok := j.progress.ETA(iteration, now); ok {
+		fields["etaSeconds"] = eta.Seconds()
+	}
+	j.mu.Unlock()
+
+	if iteration%milestoneInterval != 0 {
+		return
+	}
+
+	j.emit("iteration", fields)
+}
+
+// ReportCrash emits crash_found and, when a file was written, repro_saved
+func (j *JSONLogger) ReportCrash(c Crash) {
+	j.mu.Lock()
+	j.unique++
+	j.hotspots.add(c)
+	j.mu.Unlock()
+
+	overrides := make([]string, len(c.Overrides))
+	for i, change := range c.Overrides {
+		overrides[i] = change.String()
+	}
+
+	j.emit("crash_found", map[string]interface{}{
+		"iteration": c.Iteration,
+		"reason":    c.Reason,
+		"category":  runner.CategorizeReason(c.Reason),
+		"overrides": overrides,
+		"flaky":     c.Flaky,
+	})
+
+	if c.ReproFile != "" {
+		j.emit("repro_saved", map[string]interface{}{
+			"iteration": c.Iteration,
+			"file":      c.ReproFile,
+		})
+	}
+}
+
+// ReportCoverage records the coverage included in session_summary
+func (j *JSONLogger) ReportCoverage(c coverage.Summary) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.coverage = &c
+}
+
+// Finish emits the session_summary event
+func (j *JSONLogger) Finish() {
+	j.mu.Lock()
+	iterations, crashes, unique := j.iterations, j.crashes, j.unique
+	templates, paths := top(j.hotspots.templates, topHotspots), top(j.hotspots.paths, topHotspots)
+	fields := map[string]interface{}{
+		"iterations":      iterations,
+		"crashes":         crashes,
+		"uniqueCrashes":   unique,
+		"durationSeconds": time.Since(j.startTime).Seconds(),
+		"topTemplates":    templates,
+		"topValuePaths":   paths,
+	}
+	if j.coverage != nil {
+		fields["coverage"] = j.coverage
+	}
+	j.mu.Unlock()
+
+	j.emit("session_summary", fields)
+}
+
+// Wait never blocks since JSON output cannot be paused
+func (j *JSONLogger) Wait() bool {
+	return true
+}
+
+// SetVerbosity sets which log events are emitted; session events are always emitted
+func (j *JSONLogger) SetVerbosity(v Verbosity) {
+	j.verbosity = v
+}
+
+// LogDebug emits a debug log event (only when verbose)
+func (j *JSONLogger) LogDebug(format string, args ...interface{}) {
+	if !j.verbosity.allows(VerbosityVerbose) {
+		return
+	}
+	j.log("debug", format, args...)
+}
+
+// LogWarning emits a warning log event (hidden when quiet)
+func (j *JSONLogger) LogWarning(format string, args ...interface{}) {
+	if !j.verbosity.allows(VerbosityNormal) {
+		return
+	}
+	j.log("warning", format, args...)
+}
+
+// LogError emits an error log event
+func (j *JSONLogger) LogError(format string, args ...interface{}) {
+	j.log("error", format, args...)
+}
+
+func (j *JSONLogger) log(level string, format string, args ...interface{}) {
+	j.emit("log", map[string]interface{}{
+		"level":   level,
+		"message": fmt.Sprintf(format, args...),
+	})
+}
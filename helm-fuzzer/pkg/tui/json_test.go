@@ -0,0 +1,48 @@
+package tui
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJSONLogger_Events(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewJSON(&buf)
+
+	logger.Start("my-chart", 200, time.Minute)
+	for i := 1; i <= 100; i++ {
+		logger.Update(i, i == 50)
+	}
+	logger.ReportCrash(Crash{Iteration: 50, Reason: "nil pointer evaluating interface {}.name", ReproFile: "fuzzer-repro-abc.yaml"})
+	logger.Finish()
+
+	var events []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var event map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("line is not valid JSON: %q: %v", line, err)
+		}
+		events = append(events, event)
+	}
+
+	expected := []string{"session_start", "iteration", "crash_found", "repro_saved", "session_summary"}
+	if len(events) != len(expected) {
+		t.Fatalf("expected %d events, got %d: %s", len(expected), len(events), buf.String())
+	}
+	for i, name := range expected {
+		if events[i]["event"] != name {
+			t.Errorf("event %d: expected %s, got %v", i, name, events[i]["event"])
+		}
+		if events[i]["chart"] != "my-chart" {
+			t.Errorf("event %d: expected chart my-chart, got %v", i, events[i]["chart"])
+		}
+	}
+
+	summary := events[len(events)-1]
+	if summary["iterations"] != float64(100) || summary["crashes"] != float64(1) || summary["uniqueCrashes"] != float64(1) {
+		t.Errorf("unexpected summary: %v", summary)
+	}
+}
@@ -0,0 +1,89 @@
+package tui
+
+import (
+	"time"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/coverage"
+)
+
+// multi forwards every call to several UIs
+type multi []UI
+
+// Multi combines UIs so each receives every session event
+func Multi(uis ...UI) UI {
+	return multi(uis)
+}
+
+// Start starts every UI
+func (m multi) Start(chartName string, maxIterations int, timeout time.Duration) {
+	for _, ui := range m {
+		ui.Start(chartName, maxIterations, timeout)
+	}
+}
+
+// Update forwards a completed iteration
+func (m multi) Update(iteration int, crashed bool) {
+	for _, ui := range m {
+		ui.Update(iteration, crashed)
+	}
+}
+
+// ReportCrash forwards a crash finding
+func (m multi) ReportCrash(c Crash) {
+	for _, ui := range m {
+		ui.ReportCrash(c)
+	}
+}
+
+// ReportCoverage forwards the session coverage
+func (m multi) ReportCoverage(c coverage.Summary) {
+	for _, ui := range m {
+		ui.ReportCoverage(c)
+	}
+}
+
+// Finish finishes every UI
+func (m multi) Finish() {
+	for _, ui := range m {
+		ui.Finish()
+	}
+}
+
+// Wait blocks on every UI and continues only if all of them do
+func (m multi) Wait() bool {
+	ok := true
+	for _, ui := range m {
+		if !ui.Wait() {
+			ok = false
+		}
+	}
+	return ok
+}
+
+// SetVerbosity forwards the verbosity to every UI
+func (m multi) SetVerbosity(v Verbosity) {
+	for _, ui := range m {
+		ui.SetVerbosity(v)
+	}
+}
+
+// LogDebug forwards a debug message
+func (m multi) LogDebug(format string, args ...interface{}) {
+	for _, ui := range m {
+		ui.LogDebug(format, args...)
+	}
+}
+
+// LogWarning forwards a warning message
+func (m multi) LogWarning(format string, args ...interface{}) {
+	for _, ui := range m {
+		ui.LogWarning(format, args...)
+	}
+}
+
+// LogError forwards an error message
+func (m multi) LogError(format string, args ...interface{}) {
+	for _, ui := range m {
+		ui.LogError(format, args...)
+	}
+}
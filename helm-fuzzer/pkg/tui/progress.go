@@ -0,0 +1,142 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// rateSampleInterval is the minimum time between rate samples
+	rateSampleInterval = 250 * time.Millisecond
+	// rateSmoothing is the weight of the newest sample in the moving average
+	rateSmoothing = 0.3
+	// barWidth is the number of cells in the progress bar
+	barWidth = 20
+)
+
+// progress tracks completion toward the iteration target or timeout,
+// whichever comes first, with an exponentially smoothed rate
+type progress struct {
+	startTime     time.Time
+	maxIterations int
+	timeout       time.Duration
+
+	rate         float64
+	lastSample   time.Time
+	lastIterated int
+}
+
+func newProgress(maxIterations int, timeout time.Duration) *progress {
+	now := time.Now()
+	return &progress{
+		startTime:     now,
+		maxIterations: maxIterations,
+		timeout:       timeout,
+		lastSample:    now,
+	}
+}
+
+// observe folds the iteration count into the moving average rate
+func (p *progress) observe(iteration int, now time.Time) {
+	dt := now.Sub(p.lastSample)
+	if dt < rateSampleInterval {
+		return
+	}
+
+	sample := float64(iteration-p.lastIterated) / dt.Seconds()
+	if p.rate == 0 {
+		p.rate = sample
+	} else {
+		p.rate = rateSmoothing*sample + (1-rateSmoothing)*p.rate
+	}
+	p.lastSample = now
+	p.lastIterated = iteration
+}
+
+// Rate returns the smoothed iterations per second, falling back to the
+// overall average until the first sample is taken
+func (p *progress) Rate(iteration int, now time.Time) float64 {
+	if p.rate > 0 {
+		return p.rate
+	}
+	elapsed := now.Sub(p.startTime).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(iteration) / elapsed
+}
+
+// Fraction returns completion between 0 and 1
+func (p *progress) Fraction(iteration int, now time.Time) float64 {
+	fraction := 0.0
+	if p.maxIterations > 0 {
+		fraction = float64(iteration) / float64(p.maxIterations)
+	}
+	if p.timeout > 0 {
+		if byTime := float64(now.Sub(p.startTime)) / float64(p.timeout); byTime > fraction {
+			fraction = byTime
+		}
+	}
+	if fraction > 1 {
+		fraction = 1
+	}
+	return fraction
+}
+
+// ETA estimates the time remaining until the iteration target or the timeout
+func (p *progress) ETA(iteration int, now time.Time) (time.Duration, bool) {
+	var eta time.Duration
+	known := false
+
+	if rate := p.Rate(iteration, now); rate > 0 && p.maxIterations > 0 {
+		remaining := p.maxIterations - iteration
+		if remaining < 0 {
+			remaining = 0
+		}
+		eta = time.Duration(float64(remaining) / rate * float64(time.Second))
+		known = true
+	}
+
+	if p.timeout > 0 {
+		left := p.timeout - now.Sub(p.startTime)
+		if left < 0 {
+			left = 0
+		}
+		if !known || left < eta {
+			eta = left
+			known = true
+		}
+	}
+
+	return eta, known
+}
+
+// Count formats the iteration count against the target, or alone when the
+// session is bounded only by its timeout
+func (p *progress) Count(iteration int) string {
+	if p.maxIterations <= 0 {
+		return fmt.Sprint(iteration)
+	}
+	return fmt.Sprintf("%d/%d", iteration, p.maxIterations)
+}
+
+// budget describes what ends a session, for start messages
+func budget(maxIterations int, timeout time.Duration) string {
+	if maxIterations <= 0 && timeout <= 0 {
+		return "continuous, until interrupted"
+	}
+	if maxIterations <= 0 {
+		return fmt.Sprintf("%s time budget, no iteration limit", timeout)
+	}
+	return fmt.Sprintf("%d iterations (timeout %s)", maxIterations, timeout)
+}
+
+// bar renders a progress bar for the given completion fraction
+func bar(fraction float64) string {
+	filled := int(fraction * barWidth)
+	if filled > barWidth {
+		filled = barWidth
+	}
+	return strings.Repeat(sym.barFull, filled) + strings.Repeat(sym.barEmpty, barWidth-filled)
+}
@@ -0,0 +1,88 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProgress_SmoothedRate(t *testing.T) {
+	p := newProgress(1000, 0)
+	start := p.startTime
+
+	// Samples closer together than the sample interval are ignored
+	p.observe(10, start.Add(100*time.Millisecond))
+	if p.rate != 0 {
+		t.Fatalf("expected no sample yet, got rate %.1f", p.rate)
+	}
+
+	p.observe(100, start.Add(time.Second))
+	if p.rate != 100 {
+		t.Fatalf("expected first sample to seed the rate, got %.1f", p.rate)
+	}
+
+	// A burst moves the average only part of the way
+	p.observe(1100, start.Add(2*time.Second))
+	if p.rate != 370 {
+		t.Errorf("expected smoothed rate 370, got %.1f", p.rate)
+	}
+}
+
+func TestProgress_ETA(t *testing.T) {
+	p := newProgress(1000, time.Minute)
+	start := p.startTime
+	p.observe(100, start.Add(time.Second))
+
+	eta, ok := p.ETA(100, start.Add(time.Second))
+	if !ok || eta != 9*time.Second {
+		t.Errorf("expected 9s ETA from the iteration target, got %s (%v)", eta, ok)
+	}
+
+	// A slow run is bounded by the timeout instead
+	p = newProgress(1000000, time.Minute)
+	p.observe(100, start.Add(time.Second))
+	eta, ok = p.ETA(100, p.startTime.Add(10*time.Second))
+	if !ok || eta != 50*time.Second {
+		t.Errorf("expected 50s ETA from the timeout, got %s (%v)", eta, ok)
+	}
+}
+
+func TestProgress_Fraction(t *testing.T) {
+	p := newProgress(100, time.Minute)
+
+	if f := p.Fraction(25, p.startTime.Add(time.Second)); f != 0.25 {
+		t.Errorf("expected fraction from iterations, got %.2f", f)
+	}
+	if f := p.Fraction(25, p.startTime.Add(30*time.Second)); f != 0.5 {
+		t.Errorf("expected fraction from elapsed time, got %.2f", f)
+	}
+	if f := p.Fraction(500, p.startTime); f != 1 {
+		t.Errorf("expected fraction capped at 1, got %.2f", f)
+	}
+}
+
+func TestProgress_TimeBudget(t *testing.T) {
+	p := newProgress(0, time.Minute)
+
+	if f := p.Fraction(5000, p.startTime.Add(15*time.Second)); f != 0.25 {
+		t.Errorf("expected fraction from elapsed time only, got %.2f", f)
+	}
+	if eta, ok := p.ETA(5000, p.startTime.Add(15*time.Second)); !ok || eta != 45*time.Second {
+		t.Errorf("expected time remaining as ETA, got %s (%v)", eta, ok)
+	}
+	if got := p.Count(5000); got != "5000" {
+		t.Errorf("expected bare count without a target, got %q", got)
+	}
+	if got := newProgress(100, 0).Count(5); got != "5/100" {
+		t.Errorf("expected count against the target, got %q", got)
+	}
+}
+
+func TestBar(t *testing.T) {
+	if got := bar(0.5); got != strings.Repeat("█", 10)+strings.Repeat("░", 10) {
+		t.Errorf("unexpected half bar: %s", got)
+	}
+	if got := bar(1); got != strings.Repeat("█", barWidth) {
+		t.Errorf("unexpected full bar: %s", got)
+	}
+}
@@ -0,0 +1,157 @@
+package tui
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/coverage"
+)
+
+// SessionLog writes every session event with a timestamp and full error text,
+// so a run can be debugged after the terminal scrollback is gone. It logs at
+// all levels regardless of verbosity.
+type SessionLog struct {
+	mu         sync.Mutex
+	writer     io.Writer
+	file       *os.File
+	startTime  time.Time
+	iterations int
+	crashes    int
+}
+
+// NewSessionLog creates a session log writing to w
+func NewSessionLog(w io.Writer) *SessionLog {
+	return &SessionLog{
+		writer:    w,
+		startTime: time.Now(),
+	}
+}
+
+// SessionLogPattern matches the names of the session log files CreateSessionLog creates
+const SessionLogPattern = "helm-fuzz-*.log"
+
+// CreateSessionLog creates a timestamped session log file in dir
+func CreateSessionLog(dir, chartName string) (*SessionLog, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	name := fmt.Sprintf("helm-fuzz-%s-%s.log", chartName, time.Now().Format("20060102-150405"))
+	f, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session log: %w", err)
+	}
+
+	l := NewSessionLog(f)
+	l.file = f
+	return l, nil
+}
+
+// Path returns the session log file path, empty if not writing to a file
+func (l *SessionLog) Path() string {
+	if l.file == nil {
+		return ""
+	}
+	return l.file.Name()
+}
+
+// Close closes the session log file
+func (l *SessionLog) Close() error {
+	if l.file == nil {
+		return nil
+	}
+	return l.file.Close()
+}
+
+// write adds a timestamped entry; continuation lines are indented
+func (l *SessionLog) write(level string, message string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	message = strings.ReplaceAll(strings.TrimRight(message, "\n"), "\n", "\n    ")
+	fmt.Fprintf(l.writer, "%s %-5s %s\n", time.Now().Format(time.RFC3339Nano), level, message)
+}
+
+// Start logs the session parameters
+func (l *SessionLog) Start(chartName string, maxIterations int, timeout time.Duration) {
+	l.mu.Lock()
+	l.startTime = time.Now()
+	l.mu.Unlock()
+
+	l.write("INFO", fmt.Sprintf("session started: chart=%s maxIterations=%d timeout=%s", chartName, maxIterations, timeout))
+}
+
+// Update logs a progress entry at every milestone
+func (l *SessionLog) Update(iteration int, crashed bool) {
+	l.mu.Lock()
+	l.iterations = iteration
+	if crashed {
+		l.crashes++
+	}
+	crashes := l.crashes
+	l.mu.Unlock()
+
+	if iteration%milestoneInterval == 0 {
+		l.write("INFO", fmt.Sprintf("progress: iterations=%d crashes=%d", iteration, crashes))
+	}
+}
+
+// ReportCrash logs a finding with its full reason
+func (l *SessionLog) ReportCrash(c Crash) {
+	message := fmt.Sprintf("crash at iteration %d", c.Iteration)
+	if c.Flaky {
+		message = "flaky " + message
+	}
+	if c.ReproFile != "" {
+		message += fmt.Sprintf(" (repro: %s)", c.ReproFile)
+	}
+	message += "\n" + c.Reason
+	for _, change := range c.Overrides {
+		message += "\n" + change.String()
+	}
+	l.write("CRASH", message)
+}
+
+// ReportCoverage logs the session coverage
+func (l *SessionLog) ReportCoverage(c coverage.Summary) {
+	l.write("INFO", fmt.Sprintf("coverage: paths=%d/%d enumValues=%d/%d templates=%d/%d\nunset paths: %s\nunchosen enum values: %s\nunrendered templates: %s",
+		c.PathsSet, c.Paths, c.EnumValuesChosen, c.EnumValues, c.TemplatesRendered, c.Templates,
+		strings.Join(c.UnsetPaths, ", "), strings.Join(c.UnchosenEnumValues, ", "), strings.Join(c.UnrenderedTemplates, ", ")))
+}
+
+// Finish logs the session summary
+func (l *SessionLog) Finish() {
+	l.mu.Lock()
+	iterations, crashes, elapsed := l.iterations, l.crashes, time.Since(l.startTime)
+	l.mu.Unlock()
+
+	l.write("INFO", fmt.Sprintf("session completed: iterations=%d crashes=%d duration=%s", iterations, crashes, elapsed.Round(time.Millisecond)))
+}
+
+// Wait never blocks since the log cannot be paused
+func (l *SessionLog) Wait() bool {
+	return true
+}
+
+// SetVerbosity is a no-op; the session log always records every level
+func (l *SessionLog) SetVerbosity(v Verbosity) {}
+
+// LogDebug logs debug information
+func (l *SessionLog) LogDebug(format string, args ...interface{}) {
+	l.write("DEBUG", fmt.Sprintf(format, args...))
+}
+
+// LogWarning logs a warning message
+func (l *SessionLog) LogWarning(format string, args ...interface{}) {
+	l.write("WARN", fmt.Sprintf(format, args...))
+}
+
+// LogError logs an error message
+func (l *SessionLog) LogError(format string, args ...interface{}) {
+	l.write("ERROR", fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,82 @@
+package tui
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSessionLog_RecordsAllEvents(t *testing.T) {
+	var buf bytes.Buffer
+	log := NewSessionLog(&buf)
+	log.SetVerbosity(VerbosityQuiet)
+
+	log.Start("my-chart", 100, time.Minute)
+	log.LogDebug("debug %d", 1)
+	for i := 1; i <= 100; i++ {
+		log.Update(i, false)
+	}
+	log.ReportCrash(Crash{Iteration: 42, Reason: "first line\nsecond line", ReproFile: "fuzzer-repro-abc.yaml"})
+	log.Finish()
+
+	out := buf.String()
+	for _, want := range []string{
+		"INFO  session started: chart=my-chart maxIterations=100 timeout=1m0s",
+		"DEBUG debug 1",
+		"INFO  progress: iterations=100 crashes=0",
+		"CRASH crash at iteration 42 (repro: fuzzer-repro-abc.yaml)\n    first line\n    second line\n",
+		"INFO  session completed: iterations=100 crashes=0",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected session log to contain %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestCreateSessionLog(t *testing.T) {
+	dir := t.TempDir()
+	log, err := CreateSessionLog(dir, "my-chart")
+	if err != nil {
+		t.Fatalf("CreateSessionLog failed: %v", err)
+	}
+	log.LogError("boom")
+	if err := log.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	if !strings.HasPrefix(log.Path(), dir) || !strings.HasSuffix(log.Path(), ".log") {
+		t.Errorf("unexpected session log path: %s", log.Path())
+	}
+	data, err := os.ReadFile(log.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "ERROR boom") {
+		t.Errorf("expected error entry, got: %s", data)
+	}
+}
+
+func TestMulti(t *testing.T) {
+	var plain, log bytes.Buffer
+	display := New(true)
+	display.SetWriter(&plain)
+
+	ui := Multi(display, NewSessionLog(&log))
+	ui.Start("my-chart", 10, time.Minute)
+	ui.LogDebug("debug line")
+	ui.ReportCrash(Crash{Iteration: 1, Reason: "boom"})
+
+	if strings.Contains(plain.String(), "debug line") {
+		t.Error("expected display to respect its verbosity")
+	}
+	for _, out := range []string{plain.String(), log.String()} {
+		if !strings.Contains(out, "boom") {
+			t.Errorf("expected every UI to receive the crash, got %q", out)
+		}
+	}
+	if !ui.Wait() {
+		t.Error("expected Wait to continue")
+	}
+}
@@ -0,0 +1,92 @@
+package tui
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"strings"
+)
+
+// logHandler is a slog.Handler that routes records to a UI, so library
+// logging follows the UI's verbosity and reaches the session log
+type logHandler struct {
+	ui     UI
+	attrs  []slog.Attr
+	prefix string
+}
+
+// NewLogHandler creates a slog handler that writes to ui. Errors go to
+// LogError, warnings to LogWarning and everything else to LogDebug.
+func NewLogHandler(ui UI) slog.Handler {
+	return &logHandler{ui: ui}
+}
+
+// Enabled accepts every level; the UI applies its own verbosity
+func (h *logHandler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+// Handle formats the record as "message key=value ..." and sends it to the UI
+func (h *logHandler) Handle(_ context.Context, r slog.Record) error {
+	var b strings.Builder
+	b.WriteString(r.Message)
+	for _, a := range h.attrs {
+		writeAttr(&b, "", a)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		writeAttr(&b, h.prefix, a)
+		return true
+	})
+
+	switch {
+	case r.Level >= slog.LevelError:
+		h.ui.LogError("%s", b.String())
+	case r.Level >= slog.LevelWarn:
+		h.ui.LogWarning("%s", b.String())
+	default:
+		h.ui.LogDebug("%s", b.String())
+	}
+	return nil
+}
+
+// WithAttrs returns a handler that adds attrs to every record
+func (h *logHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	next := *h
+	next.attrs = append([]slog.Attr(nil), h.attrs...)
+	for _, a := range attrs {
+		a.Key = h.prefix + a.Key
+		next.attrs = append(next.attrs, a)
+	}
+	return &next
+}
+
+// WithGroup returns a handler that qualifies later keys with name
+func (h *logHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	next := *h
+	next.prefix = h.prefix + name + "."
+	return &next
+}
+
+// writeAttr appends " key=value", quoting values that contain spaces
+func writeAttr(b *strings.Builder, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		for _, ga := range a.Value.Group() {
+			writeAttr(b, prefix+a.Key+".", ga)
+		}
+		return
+	}
+
+	value := a.Value.String()
+	if strings.ContainsAny(value, " \t\n\"=") {
+		value = strconv.Quote(value)
+	}
+	b.WriteString(" " + prefix + a.Key + "=" + value)
+}
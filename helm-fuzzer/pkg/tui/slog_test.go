@@ -0,0 +1,29 @@
+package tui
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestLogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewLogHandler(NewSessionLog(&buf)))
+
+	logger.With("worker", 2).WithGroup("render").Debug("render failed", "error", errors.New("bad value"), "kubeVersion", "1.28.0")
+	logger.Warn("slow render", slog.Group("timing", "ms", 1500))
+	logger.Error("100% broken")
+
+	out := buf.String()
+	for _, want := range []string{
+		`DEBUG render failed worker=2 render.error="bad value" render.kubeVersion=1.28.0`,
+		"WARN  slow render timing.ms=1500",
+		"ERROR 100% broken",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected log to contain %q:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package tui
+
+import (
+	"os"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// symbols holds the glyphs used in terminal output. Prefixes include their
+// trailing spacing so they can be empty in ASCII mode.
+type symbols struct {
+	start     string
+	chart     string
+	coverage  string
+	target    string
+	clock     string
+	progress  string
+	crash     string
+	rate      string
+	elapsed   string
+	eta       string
+	completed string
+	success   string
+	warning   string
+	debug     string
+	error     string
+	barFull   string
+	barEmpty  string
+	separator string
+	upDown    string
+	spark     []rune
+}
+
+var emojiSymbols = symbols{
+	start:     "🔍 ",
+	chart:     "📊 ",
+	coverage:  "📈 ",
+	target:    "🎯 ",
+	clock:     "⏰ ",
+	progress:  "⏳ ",
+	crash:     "💥 ",
+	rate:      "⚡ ",
+	elapsed:   "⏱️  ",
+	eta:       "⌛ ",
+	completed: "✅ ",
+	success:   "🎉 ",
+	warning:   "⚠️  ",
+	debug:     "🔧 ",
+	error:     "❌ ",
+	barFull:   "█",
+	barEmpty:  "░",
+	separator: " · ",
+	upDown:    "↑/↓",
+	spark:     []rune("▁▂▃▄▅▆▇█"),
+}
+
+var asciiSymbols = symbols{
+	crash:     "[CRASH] ",
+	completed: "[DONE] ",
+	success:   "[OK] ",
+	warning:   "[WARN] ",
+	debug:     "[DEBUG] ",
+	error:     "[ERROR] ",
+	barFull:   "#",
+	barEmpty:  ".",
+	separator: " | ",
+	upDown:    "up/down",
+	spark:     []rune("_.-=+*#@"),
+}
+
+// sym is the active symbol set
+var sym = emojiSymbols
+
+// UseASCII switches all terminal output to ASCII symbols without color,
+// for terminals and log collectors that mangle emoji and ANSI sequences.
+// Call it before creating a UI.
+func UseASCII(enabled bool) {
+	if enabled {
+		sym = asciiSymbols
+		lipgloss.SetColorProfile(termenv.Ascii)
+		return
+	}
+	sym = emojiSymbols
+	lipgloss.SetColorProfile(termenv.EnvColorProfile())
+}
+
+// NoColorRequested checks the NO_COLOR convention (https://no-color.org):
+// any non-empty value disables color
+func NoColorRequested() bool {
+	return os.Getenv("NO_COLOR") != ""
+}
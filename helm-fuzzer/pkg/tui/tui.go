@@ -4,17 +4,29 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
+	"sync"
 	"time"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/coverage"
 )
 
-// TUI handles the text user interface for fuzzing progress
+// TUI handles the plain text user interface for fuzzing progress
 type TUI struct {
+	// mu serializes output since workers may log concurrently
+	mu         sync.Mutex
 	writer     io.Writer
 	startTime  time.Time
 	iterations int
 	crashes    int
 	ciMode     bool
-	quiet      bool
+	noProgress bool
+	verbosity  Verbosity
+	progress   *progress
+	hotspots   *hotspots
+	coverage   *coverage.Summary
+	// midLine is set while the cursor sits at the end of the progress line
+	midLine bool
 }
 
 // New creates a new TUI
@@ -25,84 +37,135 @@ func New(ciMode bool) *TUI {
 		iterations: 0,
 		crashes:    0,
 		ciMode:     ciMode,
-		quiet:      ciMode,
+		noProgress: ciMode,
+		verbosity:  VerbosityNormal,
+		progress:   newProgress(0, 0),
+		hotspots:   newHotspots(),
 	}
 }
 
 // Start initializes the TUI display
-func (t *TUI) Start(chartName string, maxIterations int) {
-	if t.quiet {
+func (t *TUI) Start(chartName string, maxIterations int, timeout time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.startTime = time.Now()
+	t.progress = newProgress(maxIterations, timeout)
+
+	if t.noProgress || !t.verbosity.allows(VerbosityNormal) {
 		return
 	}
 
-	fmt.Fprintf(t.writer, "🔍 Helm Fuzz - Starting fuzzing session\n")
-	fmt.Fprintf(t.writer, "📊 Chart: %s\n", chartName)
-	fmt.Fprintf(t.writer, "🎯 Target iterations: %d\n", maxIterations)
-	fmt.Fprintf(t.writer, "⏰ Started at: %s\n\n", t.startTime.Format("15:04:05"))
+	fmt.Fprintf(t.writer, "%sHelm Fuzz - Starting fuzzing session\n", sym.start)
+	fmt.Fprintf(t.writer, "%sChart: %s\n", sym.chart, chartName)
+	fmt.Fprintf(t.writer, "%sTarget: %s\n", sym.target, budget(maxIterations, timeout))
+	fmt.Fprintf(t.writer, "%sStarted at: %s\n\n", sym.clock, t.startTime.Format("15:04:05"))
 }
 
 // Update updates the progress display
 func (t *TUI) Update(iteration int, crashed bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
 	t.iterations = iteration
 	if crashed {
 		t.crashes++
 	}
 
-	if t.quiet {
+	if t.noProgress || !t.verbosity.allows(VerbosityNormal) {
 		return
 	}
 
-	// Clear line and print progress
-	elapsed := time.Since(t.startTime)
-	rate := float64(iteration) / elapsed.Seconds()
+	now := time.Now()
+	t.progress.observe(iteration, now)
+	fraction := t.progress.Fraction(iteration, now)
 
-	fmt.Fprintf(t.writer, "\r⏳ Iterations: %d | 💥 Crashes: %d | ⚡ Rate: %.1f/s | ⏱️  Elapsed: %s",
-		iteration, t.crashes, rate, formatDuration(elapsed))
+	eta := "--"
+	if d, ok := t.progress.ETA(iteration, now); ok {
+		eta = formatDuration(d)
+	}
+
+	// Return to line start and redraw; ETA is padded so a shorter value overwrites a longer one
+	fmt.Fprintf(t.writer, "\r%s[%s] %3.0f%% %s | %sCrashes: %d | %sRate: %.1f/s | %sElapsed: %s | %sETA: %-6s",
+		sym.progress, bar(fraction), fraction*100, t.progress.Count(iteration), sym.crash, t.crashes, sym.rate, t.progress.Rate(iteration, now),
+		sym.elapsed, formatDuration(now.Sub(t.startTime)), sym.eta, eta)
+	t.midLine = true
 }
 
 // ReportCrash reports a crash finding
-func (t *TUI) ReportCrash(iteration int, reason string, reproFile string) {
-	if !t.quiet {
-		fmt.Fprintf(t.writer, "\n\n")
+func (t *TUI) ReportCrash(c Crash) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.hotspots.add(c)
+	if !t.verbosity.allows(VerbosityNormal) {
+		return
 	}
 
-	fmt.Fprintf(t.writer, "💥 CRASH DETECTED at iteration %d\n", iteration)
-	fmt.Fprintf(t.writer, "   Reason: %s\n", reason)
-	if reproFile != "" {
-		fmt.Fprintf(t.writer, "   Reproduction file: %s\n", reproFile)
+	if !t.noProgress {
+		fmt.Fprintf(t.writer, "\n\n")
 	}
 
-	if !t.quiet {
+	fmt.Fprintf(t.writer, "%sCRASH DETECTED at iteration %d\n", sym.crash, c.Iteration)
+	writeCrashDetail(t.writer, c)
+
+	if !t.noProgress {
 		fmt.Fprintf(t.writer, "\n")
 	}
+	t.midLine = false
+}
+
+// ReportCoverage records the coverage printed with the summary
+func (t *TUI) ReportCoverage(c coverage.Summary) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.coverage = &c
 }
 
 // Finish completes the TUI display
 func (t *TUI) Finish() {
-	if !t.quiet {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.noProgress && t.verbosity.allows(VerbosityNormal) {
 		fmt.Fprintf(t.writer, "\n\n")
 	}
+	t.midLine = false
 
 	elapsed := time.Since(t.startTime)
-	fmt.Fprintf(t.writer, "✅ Fuzzing session completed\n")
+	fmt.Fprintf(t.writer, "%sFuzzing session completed\n", sym.completed)
 	fmt.Fprintf(t.writer, "   Total iterations: %d\n", t.iterations)
 	fmt.Fprintf(t.writer, "   Total crashes: %d\n", t.crashes)
 	fmt.Fprintf(t.writer, "   Duration: %s\n", formatDuration(elapsed))
 
+	if t.verbosity.allows(VerbosityNormal) {
+		t.hotspots.write(t.writer)
+		writeCoverage(t.writer, t.coverage)
+	}
+
 	if t.crashes == 0 {
-		fmt.Fprintf(t.writer, "\n🎉 No crashes found! Your chart is robust.\n")
+		fmt.Fprintf(t.writer, "\n%sNo crashes found! Your chart is robust.\n", sym.success)
 	} else {
-		fmt.Fprintf(t.writer, "\n⚠️  Found %d crash(es). Please review the reproduction files.\n", t.crashes)
+		fmt.Fprintf(t.writer, "\n%sFound %d crash(es). Please review the reproduction files.\n", sym.warning, t.crashes)
 	}
 }
 
+// Wait never blocks since the plain display cannot be paused
+func (t *TUI) Wait() bool {
+	return true
+}
+
 // SetWriter sets a custom writer (useful for testing)
 func (t *TUI) SetWriter(w io.Writer) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	t.writer = w
 }
 
 // GetCrashCount returns the number of crashes found
 func (t *TUI) GetCrashCount() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	return t.crashes
 }
 
@@ -117,20 +180,88 @@ func formatDuration(d time.Duration) string {
 	return fmt.Sprintf("%.1fh", d.Hours())
 }
 
-// LogDebug logs debug information (only in non-CI mode)
+// SetVerbosity sets which messages are printed
+func (t *TUI) SetVerbosity(v Verbosity) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.verbosity = v
+}
+
+// LogDebug logs debug information (only when verbose)
 func (t *TUI) LogDebug(format string, args ...interface{}) {
-	if t.ciMode || t.quiet {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.verbosity.allows(VerbosityVerbose) {
 		return
 	}
-	fmt.Fprintf(t.writer, "🔧 "+format+"\n", args...)
+	t.breakLine()
+	fmt.Fprintf(t.writer, sym.debug+format+"\n", args...)
 }
 
-// LogWarning logs a warning message
+// LogWarning logs a warning message (hidden when quiet)
 func (t *TUI) LogWarning(format string, args ...interface{}) {
-	fmt.Fprintf(t.writer, "⚠️  "+format+"\n", args...)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.verbosity.allows(VerbosityNormal) {
+		return
+	}
+	t.breakLine()
+	fmt.Fprintf(t.writer, sym.warning+format+"\n", args...)
 }
 
 // LogError logs an error message
 func (t *TUI) LogError(format string, args ...interface{}) {
-	fmt.Fprintf(t.writer, "❌ "+format+"\n", args...)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.breakLine()
+	fmt.Fprintf(t.writer, sym.error+format+"\n", args...)
+}
+
+// breakLine ends the progress line so a message starts on its own line
+func (t *TUI) breakLine() {
+	if t.midLine {
+		fmt.Fprintln(t.writer)
+		t.midLine = false
+	}
+}
+
+const (
+	// maxReasonLines is the number of reason lines shown inline for a crash
+	maxReasonLines = 3
+	// maxOverrideLines is the number of value overrides shown inline for a crash
+	maxOverrideLines = 10
+)
+
+// writeCrashDetail prints the start of the crash reason, the values that
+// differ from the chart defaults and the reproduction file
+func writeCrashDetail(w io.Writer, c Crash) {
+	lines := strings.Split(strings.TrimRight(c.Reason, "\n"), "\n")
+	fmt.Fprintf(w, "   Reason: %s\n", lines[0])
+	for i := 1; i < len(lines) && i < maxReasonLines; i++ {
+		fmt.Fprintf(w, "           %s\n", lines[i])
+	}
+	if len(lines) > maxReasonLines {
+		fmt.Fprintf(w, "           ... (%d more lines)\n", len(lines)-maxReasonLines)
+	}
+	if c.Flaky {
+		fmt.Fprintf(w, "   Flaky: not every replay reproduced it; it does not fail the session\n")
+	}
+
+	if len(c.Overrides) > 0 {
+		fmt.Fprintf(w, "   Overrides vs defaults:\n")
+		for i, change := range c.Overrides {
+			if i == maxOverrideLines {
+				fmt.Fprintf(w, "     ... (%d more)\n", len(c.Overrides)-maxOverrideLines)
+				break
+			}
+			fmt.Fprintf(w, "     %s\n", change)
+		}
+	}
+
+	if c.ReproFile != "" {
+		fmt.Fprintf(w, "   Reproduction file: %s\n", c.ReproFile)
+	}
 }
@@ -0,0 +1,223 @@
+package tui
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/coverage"
+	"github.com/kasuboski/helm-fuzzer/pkg/runner"
+)
+
+func TestTUI_Verbosity(t *testing.T) {
+	tests := []struct {
+		name      string
+		verbosity Verbosity
+		present   []string
+		absent    []string
+	}{
+		{
+			name:      "quiet",
+			verbosity: VerbosityQuiet,
+			present:   []string{"error line", "Fuzzing session completed"},
+			absent:    []string{"debug line", "warning line", "CRASH DETECTED", "Starting fuzzing session"},
+		},
+		{
+			name:      "normal",
+			verbosity: VerbosityNormal,
+			present:   []string{"warning line", "error line", "CRASH DETECTED", "Starting fuzzing session"},
+			absent:    []string{"debug line"},
+		},
+		{
+			name:      "verbose",
+			verbosity: VerbosityVerbose,
+			present:   []string{"debug line", "warning line", "error line", "CRASH DETECTED"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			ui := New(false)
+			ui.SetWriter(&buf)
+			ui.SetVerbosity(tt.verbosity)
+
+			ui.Start("my-chart", 10, time.Minute)
+			ui.LogDebug("debug line")
+			ui.Update(1, true)
+			ui.LogWarning("warning line")
+			ui.LogError("error line")
+			ui.ReportCrash(Crash{Iteration: 1, Reason: "boom"})
+			ui.Finish()
+
+			out := buf.String()
+			for _, want := range tt.present {
+				if !strings.Contains(out, want) {
+					t.Errorf("expected output to contain %q:\n%s", want, out)
+				}
+			}
+			for _, unwanted := range tt.absent {
+				if strings.Contains(out, unwanted) {
+					t.Errorf("expected output not to contain %q:\n%s", unwanted, out)
+				}
+			}
+		})
+	}
+}
+
+func TestTUI_LogBreaksProgressLine(t *testing.T) {
+	var buf bytes.Buffer
+	ui := New(false)
+	ui.SetWriter(&buf)
+	ui.Start("my-chart", 10, time.Minute)
+	ui.Update(1, false)
+	ui.LogWarning("warning line")
+
+	if !strings.Contains(buf.String(), "\n⚠️  warning line\n") {
+		t.Errorf("expected warning on its own line, got %q", buf.String())
+	}
+}
+
+func TestTUI_ASCII(t *testing.T) {
+	UseASCII(true)
+	defer UseASCII(false)
+
+	var buf bytes.Buffer
+	ui := New(false)
+	ui.SetWriter(&buf)
+	ui.Start("my-chart", 10, time.Minute)
+	ui.Update(5, true)
+	ui.ReportCrash(Crash{Iteration: 5, Reason: "boom"})
+	ui.LogWarning("careful")
+	ui.Finish()
+
+	out := buf.String()
+	for _, r := range out {
+		if r > 127 {
+			t.Fatalf("expected ASCII-only output, found %q in:\n%s", r, out)
+		}
+	}
+	for _, want := range []string{"[##########..........]  50% 5/10", "[CRASH] CRASH DETECTED", "[WARN] careful", "[DONE] Fuzzing session completed"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected output to contain %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestTUI_FinishHotspots(t *testing.T) {
+	var buf bytes.Buffer
+	ui := New(true)
+	ui.SetWriter(&buf)
+	ui.Start("my-chart", 10, time.Minute)
+
+	reasons := []string{
+		`Error: template: my-chart/templates/deployment.yaml:25:12: executing "my-chart/templates/deployment.yaml" at <.Values.resources.limits>: nil pointer`,
+		`Error: template: my-chart/templates/deployment.yaml:30:8: executing "my-chart/templates/deployment.yaml" at <.Values.image.tag>: wrong type`,
+		`Error: template: my-chart/templates/service.yaml:4:10: executing "my-chart/templates/service.yaml" at <.Values.resources.limits>: nil pointer`,
+		`Panic: boom`,
+	}
+	for i, reason := range reasons {
+		ui.Update(i+1, true)
+		ui.ReportCrash(Crash{Iteration: i + 1, Reason: reason})
+	}
+	ui.Finish()
+
+	out := buf.String()
+	for _, want := range []string{
+		"Top failing templates:\n   templates/deployment.yaml  " + strings.Repeat("█", barWidth) + " 2\n   templates/service.yaml     " + strings.Repeat("█", barWidth/2) + " 1\n",
+		"Top value paths:\n   .Values.resources.limits  " + strings.Repeat("█", barWidth) + " 2\n   .Values.image.tag         ",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected output to contain %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestTUI_FinishHotspotsCountsCulprits(t *testing.T) {
+	var buf bytes.Buffer
+	ui := New(true)
+	ui.SetWriter(&buf)
+	ui.Start("my-chart", 10, time.Minute)
+
+	// The attributed path is counted once even when it is also a culprit,
+	// and culprits of crashes without an attribution still count
+	ui.ReportCrash(Crash{
+		Iteration: 1,
+		Reason:    `Error: template: my-chart/templates/deployment.yaml:25:12: executing "my-chart/templates/deployment.yaml" at <.Values.resources.limits>: nil pointer`,
+		Culprits:  []string{"resources.limits", "replicas"},
+	})
+	ui.ReportCrash(Crash{Iteration: 2, Reason: "Panic: boom", Culprits: []string{"replicas"}})
+	ui.Finish()
+
+	out := buf.String()
+	want := "Top value paths:\n   .Values.replicas          " + strings.Repeat("█", barWidth) + " 2\n   .Values.resources.limits  " + strings.Repeat("█", barWidth/2) + " 1\n"
+	if !strings.Contains(out, want) {
+		t.Errorf("expected output to contain %q:\n%s", want, out)
+	}
+}
+
+func TestTUI_CrashDetail(t *testing.T) {
+	var buf bytes.Buffer
+	ui := New(true)
+	ui.SetWriter(&buf)
+	ui.Start("my-chart", 10, time.Minute)
+	ui.ReportCrash(Crash{
+		Iteration: 3,
+		Reason:    "line one\nline two\nline three\nline four\nline five",
+		ReproFile: "fuzzer-repro-abc.yaml",
+		Overrides: []runner.ValueChange{
+			{Path: "image.tag", Default: "1.19", Value: nil},
+			{Path: "extra", Value: true, Added: true},
+		},
+	})
+
+	out := buf.String()
+	for _, want := range []string{
+		"   Reason: line one\n           line two\n           line three\n           ... (2 more lines)\n",
+		"   Overrides vs defaults:\n     ~ image.tag: null (default: \"1.19\")\n     + extra: true\n",
+		"   Reproduction file: fuzzer-repro-abc.yaml\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected output to contain %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "line four") {
+		t.Errorf("expected reason to be truncated:\n%s", out)
+	}
+}
+
+func TestTUI_Coverage(t *testing.T) {
+	var buf bytes.Buffer
+	ui := New(true)
+	ui.SetWriter(&buf)
+	ui.Start("my-chart", 10, time.Minute)
+	ui.ReportCoverage(coverage.Summary{
+		Paths:               8,
+		PathsSet:            6,
+		EnumValues:          2,
+		EnumValuesChosen:    2,
+		Templates:           3,
+		TemplatesRendered:   2,
+		UnsetPaths:          []string{"a", "b"},
+		UnrenderedTemplates: []string{"my-chart/templates/ingress.yaml"},
+	})
+	ui.Finish()
+
+	out := buf.String()
+	for _, want := range []string{
+		"Coverage:\n",
+		"   Value paths set:     6/8 (75%)\n",
+		"   Enum values chosen:  2/2 (100%)\n",
+		"   Templates rendered:  2/3 (67%)\n",
+		"   Never set:      a, b\n",
+		"   Never rendered: my-chart/templates/ingress.yaml\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected output to contain %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "Never chosen") {
+		t.Errorf("expected no empty list:\n%s", out)
+	}
+}
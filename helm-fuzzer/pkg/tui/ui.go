@@ -0,0 +1,46 @@
+package tui
+
+import (
+	"time"
+
+	"github.com/kasuboski/helm-fuzzer/pkg/coverage"
+	"github.com/kasuboski/helm-fuzzer/pkg/runner"
+)
+
+// UI is implemented by every output mode of a fuzzing session
+type UI interface {
+	// Start initializes the display for a session that ends after maxIterations or timeout
+	Start(chartName string, maxIterations int, timeout time.Duration)
+	// Update records a completed iteration
+	Update(iteration int, crashed bool)
+	// ReportCrash reports a new unique crash finding
+	ReportCrash(c Crash)
+	// ReportCoverage records what the session exercised, shown with the summary
+	ReportCoverage(c coverage.Summary)
+	// Finish completes the display and prints the session summary
+	Finish()
+	// Wait blocks while the session is paused and reports whether fuzzing should continue
+	Wait() bool
+	// SetVerbosity sets which messages are printed
+	SetVerbosity(v Verbosity)
+	// LogDebug logs debug information
+	LogDebug(format string, args ...interface{})
+	// LogWarning logs a warning message
+	LogWarning(format string, args ...interface{})
+	// LogError logs an error message
+	LogError(format string, args ...interface{})
+}
+
+// Crash is a new unique crash finding
+type Crash struct {
+	Iteration int
+	Reason    string
+	ReproFile string
+	// Overrides lists how the crashing values differ from the chart defaults
+	Overrides []runner.ValueChange
+	// Flaky is set when replays of the input did not all reproduce the crash
+	Flaky bool
+	// Culprits are the value paths ablation found the crash needs, nil when
+	// it was not ablated
+	Culprits []string
+}
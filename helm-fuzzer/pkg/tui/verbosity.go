@@ -0,0 +1,18 @@
+package tui
+
+// Verbosity controls which messages a UI prints
+type Verbosity int
+
+const (
+	// VerbosityQuiet prints errors and the final summary only
+	VerbosityQuiet Verbosity = iota
+	// VerbosityNormal adds crash reports and warnings
+	VerbosityNormal
+	// VerbosityVerbose adds debug logging
+	VerbosityVerbose
+)
+
+// allows checks if a message of the given level should be printed
+func (v Verbosity) allows(level Verbosity) bool {
+	return v >= level
+}
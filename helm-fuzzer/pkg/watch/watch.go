@@ -0,0 +1,155 @@
+package watch
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// fileState is what a poll compares to notice a changed file
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// Watcher polls chart directories for changes to the files that affect
+// rendering. Polling keeps the tool free of platform file notification APIs
+// and copes with editors that replace files on save.
+type Watcher struct {
+	charts   []string
+	interval time.Duration
+	state    map[string]fileState
+}
+
+// New creates a watcher for the given chart directories, taking the initial
+// snapshot that later changes are compared against
+func New(charts []string, interval time.Duration) (*Watcher, error) {
+	w := &Watcher{
+		charts:   charts,
+		interval: interval,
+	}
+	state, err := w.snapshot()
+	if err != nil {
+		return nil, err
+	}
+	w.state = state
+	return w, nil
+}
+
+// Wait blocks until relevant files change and returns their paths, sorted.
+// Changes arriving within one interval of each other are reported together,
+// so saving several files at once triggers a single run.
+func (w *Watcher) Wait(ctx context.Context) ([]string, error) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	var changed map[string]bool
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+
+		state, err := w.snapshot()
+		if err != nil {
+			return nil, err
+		}
+		diff := compare(w.state, state)
+		w.state = state
+		if len(diff) > 0 {
+			if changed == nil {
+				changed = make(map[string]bool)
+			}
+			for _, path := range diff {
+				changed[path] = true
+			}
+			continue
+		}
+		if changed != nil {
+			paths := make([]string, 0, len(changed))
+			for path := range changed {
+				paths = append(paths, path)
+			}
+			sort.Strings(paths)
+			return paths, nil
+		}
+	}
+}
+
+// snapshot records the state of every relevant file in the watched charts
+func (w *Watcher) snapshot() (map[string]fileState, error) {
+	state := make(map[string]fileState)
+	for _, chart := range w.charts {
+		err := filepath.WalkDir(chart, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				// Files may disappear mid-walk while an editor saves
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			rel, err := filepath.Rel(chart, path)
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if rel != "." && strings.HasPrefix(d.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !Relevant(rel) {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			state[path] = fileState{modTime: info.ModTime(), size: info.Size()}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return state, nil
+}
+
+// Relevant reports whether a path relative to a chart directory affects how
+// the chart renders or is fuzzed. Reproduction files and reports written into
+// the chart directory are not, so a session never retriggers itself.
+func Relevant(rel string) bool {
+	rel = filepath.ToSlash(rel)
+	if dir, _, ok := strings.Cut(rel, "/"); ok {
+		return dir == "templates" || dir == "charts" || dir == "crds"
+	}
+	switch rel {
+	case "Chart.yaml", "Chart.lock", "values.schema.json", ".helmfuzz.yaml":
+		return true
+	}
+	return strings.HasPrefix(rel, "values") && (strings.HasSuffix(rel, ".yaml") || strings.HasSuffix(rel, ".yml"))
+}
+
+// compare returns the paths added, removed or modified between two snapshots
+func compare(old, new map[string]fileState) []string {
+	var changed []string
+	for path, state := range new {
+		if prev, ok := old[path]; !ok || prev != state {
+			changed = append(changed, path)
+		}
+	}
+	for path := range old {
+		if _, ok := new[path]; !ok {
+			changed = append(changed, path)
+		}
+	}
+	return changed
+}
@@ -0,0 +1,68 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRelevant(t *testing.T) {
+	tests := map[string]bool{
+		"templates/deployment.yaml": true,
+		"templates/_helpers.tpl":    true,
+		"charts/sub/values.yaml":    true,
+		"values.yaml":               true,
+		"values-prod.yaml":          true,
+		"values.schema.json":        true,
+		".helmfuzz.yaml":            true,
+		"Chart.yaml":                true,
+		"fuzzer-repro-abc123.yaml":  false,
+		"report.json":               false,
+		"README.md":                 false,
+	}
+	for path, want := range tests {
+		if got := Relevant(path); got != want {
+			t.Errorf("Relevant(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestWait(t *testing.T) {
+	dir := t.TempDir()
+	template := filepath.Join(dir, "templates", "cm.yaml")
+	if err := os.MkdirAll(filepath.Dir(template), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(template, []byte("a: 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := New([]string{dir}, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		os.WriteFile(filepath.Join(dir, "fuzzer-repro-1.yaml"), []byte("x: 1\n"), 0644)
+		os.WriteFile(template, []byte("a: 22\n"), 0644)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	changed, err := w.Wait(ctx)
+	if err != nil {
+		t.Fatalf("Wait failed: %v", err)
+	}
+	if len(changed) != 1 || changed[0] != template {
+		t.Errorf("expected only the template to change, got %v", changed)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := w.Wait(ctx); err != context.DeadlineExceeded {
+		t.Errorf("expected no further changes, got %v", err)
+	}
+}